@@ -9,7 +9,7 @@ import (
 )
 
 func main() {
-	con := connection.GetMySqlConnection()
+	con := connection.GetConnectionManager()
 	getenv := os.Getenv(constants.ENV_PANEL_MYSQL_CONNECTION_STRING)
 	log.Printf("<%v>", getenv)
 	mySqlConfig := connection.DBConfig{
@@ -19,9 +19,11 @@ func main() {
 		Lifetime:       5 * time.Minute,
 		IdleTime:       1 * time.Minute,
 	}
-	err := con.InitDataSourceConnection("mysql", mySqlConfig)
+	conn, err := con.Connect("mysql", mySqlConfig)
 	if err != nil {
 		log.Printf("Failed during duplicate initialization: %v", err)
+	} else {
+		log.Println(conn)
 	}
 
 	db, err := con.GetDB("mysql")
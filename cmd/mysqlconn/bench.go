@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func runBench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	dsn := dsnFlag(fs)
+	query := fs.String("query", "SELECT 1", "statement to run; separate several statements with ';'")
+	concurrency := fs.Int("concurrency", 32, "number of concurrent workers")
+	duration := fs.Duration("duration", 10*time.Second, "measurement time per pool size")
+	pools := fs.String("pools", "2,4,8,16,32,64", "comma separated MaxOpen values to measure (MaxIdle = MaxOpen)")
+	_ = fs.Parse(args)
+
+	profile := connection.WorkloadProfile{Concurrency: *concurrency, Duration: *duration}
+	for _, q := range strings.Split(*query, ";") {
+		if q = strings.TrimSpace(q); q != "" {
+			profile.Queries = append(profile.Queries, q)
+		}
+	}
+	for _, size := range strings.Split(*pools, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid pool size %q", size)
+		}
+		profile.Pools = append(profile.Pools, connection.PoolSetting{MaxOpen: n, MaxIdle: n})
+	}
+
+	factory, err := openFactory(*dsn)
+	if err != nil {
+		return err
+	}
+	defer factory.CloseAllConnections()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report, err := factory.Benchmark(ctx, cliConnection, profile)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "max_open\tmax_idle\tops/s\tp50\tp95\tp99\terrors\t")
+	for _, step := range report.Steps {
+		fmt.Fprintf(w, "%d\t%d\t%.0f\t%v\t%v\t%v\t%d\t\n", step.Pool.MaxOpen, step.Pool.MaxIdle, step.Throughput,
+			step.P50.Round(time.Microsecond), step.P95.Round(time.Microsecond), step.P99.Round(time.Microsecond), step.Errors)
+	}
+	_ = w.Flush()
+	fmt.Printf("\nRecommended: MaxOpen=%d MaxIdle=%d\n", report.Recommended.MaxOpen, report.Recommended.MaxIdle)
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"github.com/hemant-dhiman/MySQL-connection/constants"
+	"os"
+)
+
+func runLint(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	dsn := dsnFlag(fs)
+	_ = fs.Parse(args)
+
+	source := *dsn
+	if source == "" {
+		source = os.Getenv(constants.ENV_PANEL_MYSQL_CONNECTION_STRING)
+	}
+	if source == "" {
+		return errors.New("no data source: pass -dsn or set " + constants.ENV_PANEL_MYSQL_CONNECTION_STRING)
+	}
+
+	warnings := connection.LintDSN(source)
+	for _, warning := range warnings {
+		fmt.Println(warning)
+	}
+	if len(warnings) > 0 {
+		return fmt.Errorf("%d dangerous setting(s) in the data source name", len(warnings))
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/hemant-dhiman/MySQL-connection/constants"
+	"testing"
+)
+
+func TestRunLint(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     string
+		wantErr bool
+	}{
+		{"no data source", nil, "", true},
+		{"clean flag", []string{"-dsn", "app:secret@tcp(db:3306)/orders?timeout=5s&readTimeout=30s&writeTimeout=30s"}, "", false},
+		{"missing timeouts", []string{"-dsn", "app:secret@tcp(db:3306)/orders"}, "", true},
+		{"allow all files", []string{"-dsn", "app:secret@tcp(db:3306)/orders?timeout=5s&readTimeout=30s&writeTimeout=30s&allowAllFiles=true"}, "", true},
+		{"clean environment", nil, "app:secret@tcp(db:3306)/orders?timeout=5s&readTimeout=30s&writeTimeout=30s", false},
+		{"unparsable", []string{"-dsn", "app:secret@db/orders"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(constants.ENV_PANEL_MYSQL_CONNECTION_STRING, tt.env)
+			if err := runLint(tt.args); (err != nil) != tt.wantErr {
+				t.Fatalf("runLint(%v) = %v, want error %v", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Command mysqlconn provides operational tooling on top of the connection package.
+//
+// Usage:
+//
+//	mysqlconn <command> [flags]
+//
+// Commands:
+//
+//	bench      Run a synthetic workload with several pool sizes and recommend a pool configuration.
+//	lint       Report dangerous settings of the data source name without connecting; exit with 1 on any.
+//	rawcheck   Report Raw and Exec calls in Go sources that format values into SQL literals.
+//	sqlcheck   Check captured statements against a schema dump; exit with 1 on unknown tables, columns or bad types.
+//	status     Print the health report of the connection as JSON; exit with 1 when it is down.
+//	validate   Check the connections of a YAML file against their servers; exit with 1 on errors.
+//
+// Except for validate, which reads its connections from -config, and sqlcheck, which only connects to
+// the scratch database of -scratch-dsn, the data source is taken from -dsn, the
+// MYSQL_PANEL_CONNECTION_STRING environment variable or MYSQLCONN_DSN_MYSQLCONN, in that order.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"github.com/hemant-dhiman/MySQL-connection/constants"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "bench":
+		err = runBench(os.Args[2:])
+	case "lint":
+		err = runLint(os.Args[2:])
+	case "rawcheck":
+		err = runRawCheck(os.Args[2:])
+	case "sqlcheck":
+		err = runSQLCheck(os.Args[2:])
+	case "status":
+		err = runStatus(os.Args[2:])
+	case "validate":
+		err = runValidate(os.Args[2:])
+	case "help", "-h", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mysqlconn %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: mysqlconn <command> [flags]
+
+Commands:
+  bench      Run a synthetic workload with several pool sizes and recommend a pool configuration.
+  lint       Report dangerous settings of the data source name without connecting; exit with 1 on any.
+  rawcheck   Report Raw and Exec calls in Go sources that format values into SQL literals.
+  sqlcheck   Check captured statements against a schema dump; exit with 1 on unknown tables, columns or bad types.
+  status     Print the health report of the connection as JSON; exit with 1 when it is down.
+  validate   Check the connections of a YAML file against their servers; exit with 1 on errors.
+
+Run "mysqlconn <command> -h" for the flags of a command.`)
+}
+
+// dsnFlag registers the -dsn flag shared by all commands, defaulting to the environment.
+func dsnFlag(fs *flag.FlagSet) *string {
+	return fs.String("dsn", "", "data source name (default $MYSQL_PANEL_CONNECTION_STRING)")
+}
+
+// cliConnection is the name of the managed connection opened by the CLI.
+const cliConnection = "mysqlconn"
+
+// openFactory initializes the CLI's connection from dsn or, when empty, the environment.
+func openFactory(dsn string) (*connection.ConnectionManager, error) {
+	source := connection.NewDSNChain(0,
+		connection.StaticDSN(map[string]string{cliConnection: dsn}),
+		connection.StaticDSN(map[string]string{cliConnection: os.Getenv(constants.ENV_PANEL_MYSQL_CONNECTION_STRING)}),
+		connection.EnvDSN(""),
+	)
+	factory := connection.GetConnectionManager()
+	_, err := factory.ConnectFromSource(context.Background(), cliConnection, source, connection.DBConfig{
+		MaxOpen:     4,
+		MaxIdle:     2,
+		ProgramName: "mysqlconn",
+	})
+	if errors.Is(err, connection.ErrDSNNotFound) {
+		return nil, errors.New("no data source: pass -dsn or set " + constants.ENV_PANEL_MYSQL_CONNECTION_STRING)
+	}
+	return factory, err
+}
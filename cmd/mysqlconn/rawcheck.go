@@ -0,0 +1,152 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// quotedSegment matches a quoted section of a format string.
+	quotedSegment = regexp.MustCompile(`'[^']*'|"[^"]*"`)
+
+	// formatVerb matches a fmt verb once escaped percent signs are removed.
+	formatVerb = regexp.MustCompile(`%[-+# 0-9.*\[\]]*[a-zA-Z]`)
+)
+
+// rawMethods are the methods whose first argument is SQL text.
+var rawMethods = map[string]bool{"Raw": true, "Exec": true}
+
+func runRawCheck(args []string) error {
+	flagSet := flag.NewFlagSet("rawcheck", flag.ExitOnError)
+	flagSet.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: mysqlconn rawcheck [dir ...]\n\nReports Raw and Exec calls whose SQL is built by formatting or concatenating values into quoted literals.")
+	}
+	_ = flagSet.Parse(args)
+	dirs := flagSet.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	var findings int
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if name := entry.Name(); path != dir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.HasSuffix(path, ".go") {
+				return nil
+			}
+			n, err := rawCheckFile(path)
+			findings += n
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if findings > 0 {
+		return fmt.Errorf("%d suspicious Raw/Exec call(s)", findings)
+	}
+	return nil
+}
+
+// rawCheckFile prints the suspicious Raw and Exec calls of one Go file and returns their number.
+func rawCheckFile(path string) (int, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return 0, err
+	}
+
+	var findings int
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		selector, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || !rawMethods[selector.Sel.Name] {
+			return true
+		}
+		if reason := unsafeSQL(call.Args[0]); reason != "" {
+			fmt.Printf("%s: %s: %s\n", fset.Position(call.Pos()), selector.Sel.Name, reason)
+			findings++
+		}
+		return true
+	})
+	return findings, nil
+}
+
+// unsafeSQL returns why the SQL argument of a Raw or Exec call is suspicious, or "".
+func unsafeSQL(arg ast.Expr) string {
+	switch expr := arg.(type) {
+	case *ast.CallExpr:
+		fn, ok := expr.Fun.(*ast.SelectorExpr)
+		if !ok || len(expr.Args) == 0 {
+			return ""
+		}
+		if pkg, ok := fn.X.(*ast.Ident); !ok || pkg.Name != "fmt" || fn.Sel.Name != "Sprintf" {
+			return ""
+		}
+		format, ok := stringLiteral(expr.Args[0])
+		if !ok {
+			return ""
+		}
+		for _, segment := range quotedSegment.FindAllString(strings.ReplaceAll(format, "%%", ""), -1) {
+			if formatVerb.MatchString(segment) {
+				return "value formatted into a quoted literal with fmt.Sprintf; pass it as an argument"
+			}
+		}
+	case *ast.BinaryExpr:
+		if expr.Op != token.ADD {
+			return ""
+		}
+		var quoted, variable bool
+		var walk func(ast.Expr)
+		walk = func(e ast.Expr) {
+			if bin, ok := e.(*ast.BinaryExpr); ok && bin.Op == token.ADD {
+				walk(bin.X)
+				walk(bin.Y)
+				return
+			}
+			if s, ok := stringLiteral(e); ok {
+				quoted = quoted || strings.ContainsAny(s, `'"`)
+				return
+			}
+			variable = true
+		}
+		walk(expr)
+		if quoted && variable {
+			return "value concatenated into a quoted literal; pass it as an argument"
+		}
+	}
+	return ""
+}
+
+// stringLiteral returns the value of a string literal expression.
+func stringLiteral(e ast.Expr) (string, bool) {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", false
+	}
+	return s, true
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"os"
+	"strings"
+)
+
+func runSQLCheck(args []string) error {
+	fs := flag.NewFlagSet("sqlcheck", flag.ExitOnError)
+	schema := fs.String("schema", "", "schema dump, e.g. the output of mysqldump --no-data (required)")
+	scratch := fs.String("scratch-dsn", "", "data source name of an empty scratch database (refused when it has tables) to load the dump into and prepare the statements on")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: mysqlconn sqlcheck -schema dump.sql [-scratch-dsn dsn] statements.jsonl ...\n\n"+
+			"Checks statements saved with StatementCapture.Save against a schema dump.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if *schema == "" {
+		return errors.New("no schema: pass -schema")
+	}
+
+	dump, err := os.ReadFile(*schema)
+	if err != nil {
+		return err
+	}
+	info, err := connection.ParseSchemaDump(bytes.NewReader(dump))
+	if err != nil {
+		return err
+	}
+	var statements []connection.CapturedStatement
+	for _, path := range fs.Args() {
+		loaded, err := connection.LoadStatements(path)
+		if err != nil {
+			return err
+		}
+		statements = append(statements, loaded...)
+	}
+
+	problems := info.CheckSQL(statements)
+	if *scratch != "" {
+		factory, err := openFactory(*scratch)
+		if err != nil {
+			return err
+		}
+		defer factory.CloseAllConnections()
+		ctx := context.Background()
+		db, err := factory.GetDBContext(ctx, cliConnection)
+		if err != nil {
+			return err
+		}
+		var tables []string
+		if err := db.Raw("SHOW TABLES").Scan(&tables).Error; err != nil {
+			return fmt.Errorf("failed to list the tables of the scratch database: %w", err)
+		}
+		if err := requireEmptyScratch(tables); err != nil {
+			return err
+		}
+		if err := factory.ExecScript(ctx, cliConnection, bytes.NewReader(dump)); err != nil {
+			return fmt.Errorf("failed to load %s: %w", *schema, err)
+		}
+		serverProblems, err := factory.CheckSQLOnServer(ctx, cliConnection, statements)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, serverProblems...)
+	}
+
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problem(s) in %d statement(s)", len(problems), len(statements))
+	}
+	return nil
+}
+
+// requireEmptyScratch refuses a scratch database holding tables: the dump is run as is, and the
+// DROP TABLE IF EXISTS statements of mysqldump would drop them.
+func requireEmptyScratch(tables []string) error {
+	if len(tables) == 0 {
+		return nil
+	}
+	shown := tables
+	if len(shown) > 3 {
+		shown = shown[:3]
+	}
+	return fmt.Errorf("the scratch database is not empty (%d table(s): %s); refusing to load the dump into it",
+		len(tables), strings.Join(shown, ", "))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRequireEmptyScratch(t *testing.T) {
+	tests := []struct {
+		name   string
+		tables []string
+		want   string
+	}{
+		{"empty", nil, ""},
+		{"one table", []string{"users"}, "(1 table(s): users)"},
+		{"many tables", []string{"a", "b", "c", "d"}, "(4 table(s): a, b, c)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := requireEmptyScratch(tt.tables)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRunSQLCheck(t *testing.T) {
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "schema.sql")
+	dump := "CREATE TABLE `users` (\n  `id` bigint NOT NULL AUTO_INCREMENT,\n  `name` varchar(64) NOT NULL,\n  PRIMARY KEY (`id`)\n);\n"
+	if err := os.WriteFile(schema, []byte(dump), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	valid := write("valid.jsonl", `{"sql":"SELECT `+"`id`, `name`"+` FROM `+"`users`"+` WHERE `+"`id`"+` = ?","vars":[1]}`+"\n")
+	unknown := write("unknown.jsonl", `{"sql":"SELECT * FROM `+"`orders`"+`"}`+"\n")
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"no schema", []string{valid}, "no schema"},
+		{"missing schema", []string{"-schema", filepath.Join(dir, "missing.sql"), valid}, "no such file"},
+		{"valid statements", []string{"-schema", schema, valid}, ""},
+		{"unknown table", []string{"-schema", schema, valid, unknown}, "1 problem(s) in 2 statement(s)"},
+		{"missing statements", []string{"-schema", schema, filepath.Join(dir, "missing.jsonl")}, "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runSQLCheck(tt.args)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Expected no problems, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+)
+
+func runStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	dsn := dsnFlag(fs)
+	timeout := fs.Duration("timeout", 5*time.Second, "maximum time to wait for the server")
+	_ = fs.Parse(args)
+
+	factory, err := openFactory(*dsn)
+	if err != nil {
+		return err
+	}
+	defer factory.CloseAllConnections()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	report, err := factory.HealthReport(ctx)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, report, "", "  "); err != nil {
+		return err
+	}
+	fmt.Println(out.String())
+
+	var status struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(report, &status); err != nil {
+		return err
+	}
+	if status.Status != "up" {
+		return errors.New("connection is down")
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"gopkg.in/yaml.v3"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Levels of a validation check.
+const (
+	levelOK      = "ok"
+	levelWarning = "warning"
+	levelError   = "error"
+)
+
+// maxConnectionsShare is the share of the server's max_connections above which the pools of a
+// configuration are reported: other clients and administrators need connections too.
+const maxConnectionsShare = 0.8
+
+// validateFile is the configuration read by "mysqlconn validate". Data source names may reference
+// environment variables as ${NAME}.
+//
+//	connections:
+//	  primary_db:
+//	    dsn: ${PRIMARY_DSN}
+//	    max_open: 20
+//	    max_idle: 10
+//	    lifetime: 5m
+//	    lifetime_jitter: 0.1
+//	    idle_time: 1m
+//	    require_tls: true
+type validateFile struct {
+	Connections map[string]validateEntry `yaml:"connections"`
+}
+
+type validateEntry struct {
+	DSN            string        `yaml:"dsn"`
+	MaxOpen        int           `yaml:"max_open"`
+	MaxIdle        int           `yaml:"max_idle"`
+	Lifetime       time.Duration `yaml:"lifetime"`
+	LifetimeJitter float64       `yaml:"lifetime_jitter"`
+	IdleTime       time.Duration `yaml:"idle_time"`
+	PoolShards     int           `yaml:"pool_shards"`
+	RequireTLS     bool          `yaml:"require_tls"`
+}
+
+func (c validateEntry) dbConfig() connection.DBConfig {
+	return connection.DBConfig{
+		DataSourceName: os.ExpandEnv(c.DSN),
+		MaxOpen:        c.MaxOpen,
+		MaxIdle:        c.MaxIdle,
+		Lifetime:       c.Lifetime,
+		LifetimeJitter: c.LifetimeJitter,
+		IdleTime:       c.IdleTime,
+		PoolShards:     c.PoolShards,
+		ProgramName:    "mysqlconn",
+	}
+}
+
+// validateReport is the JSON report of "mysqlconn validate".
+type validateReport struct {
+	Status      string               `json:"status"`
+	Connections []connectionValidity `json:"connections"`
+}
+
+type connectionValidity struct {
+	Name   string       `json:"name"`
+	Status string       `json:"status"`
+	Checks []checkEntry `json:"checks"`
+}
+
+type checkEntry struct {
+	Check   string `json:"check"`
+	Level   string `json:"level"`
+	Message string `json:"message,omitempty"`
+}
+
+func (v *connectionValidity) add(check, level, message string) {
+	v.Checks = append(v.Checks, checkEntry{Check: check, Level: level, Message: message})
+	if level == levelError || (level == levelWarning && v.Status == levelOK) {
+		v.Status = level
+	}
+}
+
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	path := fs.String("config", "", "YAML file listing the connections to validate (required)")
+	timeout := fs.Duration("timeout", 10*time.Second, "maximum time to wait for each server")
+	strict := fs.Bool("strict", false, "exit with 1 on warnings as well as errors")
+	_ = fs.Parse(args)
+	if *path == "" {
+		return errors.New("-config is required")
+	}
+
+	raw, err := os.ReadFile(*path)
+	if err != nil {
+		return err
+	}
+	var file validateFile
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", *path, err)
+	}
+	if len(file.Connections) == 0 {
+		return fmt.Errorf("%s lists no connections", *path)
+	}
+
+	factory := connection.GetConnectionManager()
+	defer factory.CloseAllConnections()
+	report := validateConnections(factory, file, *timeout)
+
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	if report.Status == levelError || (*strict && report.Status == levelWarning) {
+		return fmt.Errorf("configuration is not valid (%s)", report.Status)
+	}
+	return nil
+}
+
+// validateConnections checks every connection of file and the pools of each server against its max_connections.
+func validateConnections(factory *connection.ConnectionManager, file validateFile, timeout time.Duration) *validateReport {
+	names := make([]string, 0, len(file.Connections))
+	for name := range file.Connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	report := &validateReport{Status: levelOK}
+	poolsByServer := make(map[string]int)
+	maxConnections := make(map[string]int)
+	validities := make(map[string]*connectionValidity)
+	addrs := make(map[string]string)
+	for _, name := range names {
+		conn := file.Connections[name]
+		validity := &connectionValidity{Name: name, Status: levelOK}
+		validities[name] = validity
+		config := conn.dbConfig()
+		if cfg, err := mysqldriver.ParseDSN(config.DataSourceName); err == nil {
+			addrs[name] = cfg.Addr
+		}
+		if max, ok := validateConnection(factory, name, conn, config, timeout, validity); ok {
+			poolsByServer[addrs[name]] += config.MaxOpen
+			maxConnections[addrs[name]] = max
+		}
+	}
+
+	for _, name := range names {
+		validity := validities[name]
+		addr := addrs[name]
+		if max, ok := maxConnections[addr]; ok && max > 0 {
+			total := poolsByServer[addr]
+			switch {
+			case total > max:
+				validity.add("max_connections", levelError, fmt.Sprintf("the pools to %s open up to %d connections, over max_connections (%d)", addr, total, max))
+			case float64(total) > maxConnectionsShare*float64(max):
+				validity.add("max_connections", levelWarning, fmt.Sprintf("the pools to %s open up to %d of the %d max_connections", addr, total, max))
+			default:
+				validity.add("max_connections", levelOK, "")
+			}
+		}
+		report.Connections = append(report.Connections, *validity)
+		if validity.Status == levelError || (validity.Status == levelWarning && report.Status == levelOK) {
+			report.Status = validity.Status
+		}
+	}
+	return report
+}
+
+// validateConnection connects to one data source and records its checks. It returns the server's
+// max_connections, and false when the server could not be checked. An unlimited MaxOpen counts as 0.
+func validateConnection(factory *connection.ConnectionManager, name string, conn validateEntry, config connection.DBConfig, timeout time.Duration, validity *connectionValidity) (int, bool) {
+	if config.DataSourceName == "" {
+		validity.add("config", levelError, "dsn is empty")
+		return 0, false
+	}
+	if config.MaxOpen <= 0 {
+		validity.add("config", levelWarning, "max_open is unlimited")
+	}
+	for _, warning := range config.Validate() {
+		validity.add("config", levelWarning, warning.String())
+	}
+	for _, warning := range connection.LintDSN(config.DataSourceName) {
+		validity.add("dsn", levelWarning, warning.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	info, err := factory.ConnectContext(ctx, name, config)
+	if err != nil {
+		validity.add("connect", levelError, err.Error())
+		return 0, false
+	}
+	validity.add("connect", levelOK, info.String())
+
+	warnings, _ := factory.ConfigWarnings(name)
+	for _, warning := range warnings {
+		if warning.Field == connection.ConfigFieldLifetime {
+			validity.add("wait_timeout", levelWarning, warning.String())
+		}
+	}
+
+	switch {
+	case info.TLS:
+		validity.add("tls", levelOK, info.TLSVersion)
+	case conn.RequireTLS:
+		validity.add("tls", levelError, "sessions are not encrypted")
+	default:
+		validity.add("tls", levelWarning, "sessions are not encrypted")
+	}
+
+	db, err := factory.GetDBContext(ctx, name)
+	if err != nil {
+		validity.add("connect", levelError, err.Error())
+		return 0, false
+	}
+	audit, err := factory.AuditPrivileges(ctx, name)
+	if err != nil {
+		validity.add("privileges", levelError, err.Error())
+	} else {
+		database := ""
+		if cfg, err := mysqldriver.ParseDSN(config.DataSourceName); err == nil {
+			database = cfg.DBName
+		}
+		level, message := checkGrants(audit.Grants, database)
+		if level == levelOK && !audit.Compliant {
+			level, message = levelWarning, fmt.Sprintf("%s holds privileges beyond the policy: %v", audit.User, audit.Excess)
+		}
+		validity.add("privileges", level, message)
+	}
+
+	var maxConnections int
+	if err := db.Raw("SELECT @@GLOBAL.max_connections").Scan(&maxConnections).Error; err != nil {
+		validity.add("max_connections", levelError, fmt.Sprintf("failed to read max_connections: %v", err))
+		return 0, false
+	}
+	return maxConnections, true
+}
+
+// checkGrants reports a user without privileges on database.
+func checkGrants(grants []string, database string) (string, string) {
+	covered := database == ""
+	for _, grant := range grants {
+		upper := strings.ToUpper(grant)
+		if strings.Contains(upper, " ON *.* ") && !strings.HasPrefix(upper, "GRANT USAGE ") {
+			covered = true
+		}
+		if database != "" && (strings.Contains(grant, " ON `"+database+"`.") || strings.Contains(grant, " ON "+database+".")) {
+			covered = true
+		}
+	}
+	if !covered {
+		return levelError, fmt.Sprintf("the user has no privileges on database %q", database)
+	}
+	return levelOK, ""
+}
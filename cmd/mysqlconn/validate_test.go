@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"testing"
+	"time"
+)
+
+func TestCheckGrants(t *testing.T) {
+	tests := []struct {
+		name     string
+		grants   []string
+		database string
+		level    string
+	}{
+		{"no database", nil, "", levelOK},
+		{"global grant", []string{"GRANT SELECT, INSERT ON *.* TO `app`@`%`"}, "orders", levelOK},
+		{"usage only", []string{"GRANT USAGE ON *.* TO `app`@`%`"}, "orders", levelError},
+		{"quoted database", []string{"GRANT USAGE ON *.* TO `app`@`%`", "GRANT SELECT ON `orders`.* TO `app`@`%`"}, "orders", levelOK},
+		{"unquoted database", []string{"GRANT SELECT ON orders.* TO app@'%'"}, "orders", levelOK},
+		{"other database", []string{"GRANT ALL PRIVILEGES ON `billing`.* TO `app`@`%`"}, "orders", levelError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, message := checkGrants(tt.grants, tt.database)
+			if level != tt.level {
+				t.Fatalf("checkGrants(%v, %q) = %s (%s), want %s", tt.grants, tt.database, level, message, tt.level)
+			}
+			if (level == levelOK) != (message == "") {
+				t.Fatalf("Expected a message with errors only, got %q", message)
+			}
+		})
+	}
+}
+
+func TestValidateConnections(t *testing.T) {
+	factory := connection.GetConnectionManager()
+	defer factory.CloseAllConnections()
+	file := validateFile{Connections: map[string]validateEntry{
+		"reports_db": {DSN: "app:secret@tcp(127.0.0.1:1)/reports?timeout=1s&readTimeout=1s&writeTimeout=1s", MaxOpen: 10},
+		"empty_db":   {},
+		"orders_db":  {DSN: "app:secret@tcp(127.0.0.1:1)/orders"},
+	}}
+
+	report := validateConnections(factory, file, time.Second)
+	if report.Status != levelError || len(report.Connections) != 3 {
+		t.Fatalf("Expected an error report for 3 connections, got %+v", report)
+	}
+	tests := []struct {
+		name   string
+		checks map[string]string
+	}{
+		{"empty_db", map[string]string{"config": levelError}},
+		{"orders_db", map[string]string{"config": levelWarning, "dsn": levelWarning, "connect": levelError}},
+		{"reports_db", map[string]string{"connect": levelError}},
+	}
+	for i, tt := range tests {
+		validity := report.Connections[i]
+		if validity.Name != tt.name || validity.Status != levelError {
+			t.Fatalf("Expected %s to fail at position %d, got %+v", tt.name, i, validity)
+		}
+		levels := make(map[string]string)
+		for _, check := range validity.Checks {
+			if levels[check.Check] != levelError {
+				levels[check.Check] = check.Level
+			}
+		}
+		for check, level := range tt.checks {
+			if levels[check] != level {
+				t.Errorf("%s: expected check %s at %s, got %+v", tt.name, check, level, validity.Checks)
+			}
+		}
+		if tt.name == "reports_db" && (levels["dsn"] != "" || levels["config"] != "") {
+			t.Errorf("reports_db: expected no config or dsn warnings, got %+v", validity.Checks)
+		}
+	}
+}
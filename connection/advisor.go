@@ -0,0 +1,449 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// adviseDigestLimit is the number of statement digests examined by Advise, most expensive first.
+const adviseDigestLimit = 50
+
+// IndexSuggestion is a candidate index proposed by Advise.
+type IndexSuggestion struct {
+	Table   string
+	Columns []string
+
+	// Statement is the DDL creating the suggested index.
+	Statement string
+
+	// Reason summarizes the plan problem, e.g. "full table scan".
+	Reason string
+
+	// Fingerprints are the statements that would benefit, with their execution counts and rows examined.
+	Fingerprints []string
+	Executions   int64
+	RowsExamined int64
+}
+
+// Advise suggests candidate indexes for the most expensive statements of a managed connection's database.
+//
+// Parameters:
+// - ctx: Context bounding the analysis.
+// - name: The name of the managed connection.
+//
+// Returns:
+// - []IndexSuggestion: Suggested indexes, most rows examined first.
+// - error: An error if the connection does not exist or performance_schema cannot be read.
+//
+// Behavior:
+// 1. Slow statements are taken from performance_schema.events_statements_summary_by_digest, the server's
+// own per-fingerprint statement collector: the adviseDigestLimit most expensive SELECT, UPDATE and DELETE
+// digests that ran without a (good) index or examined far more rows than they returned.
+// 2. Each digest's sample statement is EXPLAINed. Tables accessed by full scan, full index scan, or with a
+// filesort get a candidate index built from the statement: equality columns from WHERE and ON, then one
+// range column, or the ORDER BY columns when there is no range.
+// 3. Candidates already covered by the leading columns of an existing index are dropped; identical
+// candidates from several statements are merged.
+//
+// Notes:
+//   - Requires performance_schema (enabled by default) and MySQL 8.0.3+ for sample statements; samples
+//     truncated by performance_schema_max_sql_text_length are skipped.
+//   - Suggestions are heuristics for a human to review, not DDL to apply blindly.
+//
+// Example Usage:
+//
+//	suggestions, err := connection.GetConnectionManager().Advise(ctx, "primary_db")
+//	for _, s := range suggestions {
+//		fmt.Printf("%s -- %s, %d executions\n", s.Statement, s.Reason, s.Executions)
+//	}
+func (f *ConnectionManager) Advise(ctx context.Context, name string) ([]IndexSuggestion, error) {
+	db, err := f.GetDB(name)
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+
+	var digests []struct {
+		DigestText   string
+		Sample       string
+		Executions   int64
+		RowsExamined int64
+	}
+	err = db.Raw(`SELECT DIGEST_TEXT AS digest_text, QUERY_SAMPLE_TEXT AS sample, COUNT_STAR AS executions,
+		SUM_ROWS_EXAMINED AS rows_examined
+		FROM performance_schema.events_statements_summary_by_digest
+		WHERE SCHEMA_NAME = DATABASE() AND QUERY_SAMPLE_TEXT IS NOT NULL
+			AND (SUM_NO_INDEX_USED > 0 OR SUM_NO_GOOD_INDEX_USED > 0 OR SUM_ROWS_EXAMINED > 10 * GREATEST(SUM_ROWS_SENT, 1))
+		ORDER BY SUM_TIMER_WAIT DESC LIMIT ?`, adviseDigestLimit).Scan(&digests).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read statement digests of %q: %w", name, err)
+	}
+
+	info, err := f.Inspect(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := make(map[string]*IndexSuggestion)
+	for _, digest := range digests {
+		switch firstKeyword(digest.Sample) {
+		case "SELECT", "UPDATE", "DELETE":
+		default:
+			continue
+		}
+		plan, err := explainQuery(db, digest.Sample)
+		if err != nil {
+			// Truncated samples and statements on temporary tables cannot be explained.
+			continue
+		}
+		for _, s := range adviseStatement(digest.Sample, plan, info) {
+			key := s.Table + "(" + strings.Join(s.Columns, ",") + ")"
+			existing := merged[key]
+			if existing == nil {
+				existing = &IndexSuggestion{Table: s.Table, Columns: s.Columns, Reason: s.Reason,
+					Statement: indexStatement(s.Table, s.Columns)}
+				merged[key] = existing
+			}
+			existing.Fingerprints = append(existing.Fingerprints, Fingerprint(digest.Sample))
+			existing.Executions += digest.Executions
+			existing.RowsExamined += digest.RowsExamined
+		}
+	}
+
+	suggestions := make([]IndexSuggestion, 0, len(merged))
+	for _, s := range merged {
+		suggestions = append(suggestions, *s)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].RowsExamined != suggestions[j].RowsExamined {
+			return suggestions[i].RowsExamined > suggestions[j].RowsExamined
+		}
+		return suggestions[i].Statement < suggestions[j].Statement
+	})
+	return suggestions, nil
+}
+
+// adviceReport is an IndexSuggestion as served by AdviseHandler.
+type adviceReport struct {
+	Table        string   `json:"table"`
+	Columns      []string `json:"columns"`
+	Statement    string   `json:"statement"`
+	Reason       string   `json:"reason"`
+	Fingerprints []string `json:"fingerprints"`
+	Executions   int64    `json:"executions"`
+	RowsExamined int64    `json:"rows_examined"`
+}
+
+// AdviseHandler returns an http.Handler serving the index suggestions of Advise for the connection
+// named by the connection query parameter, as a JSON array. It answers 400 without the parameter, 404
+// for an unknown connection and 500 when the analysis fails. The analysis EXPLAINs up to
+// adviseDigestLimit statements, so the handler belongs on an admin listener, not a public one.
+//
+// Example Usage:
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/admin/db/forecast", factory.ForecastHandler())
+//	mux.Handle("/admin/db/advise", factory.AdviseHandler())
+func (f *ConnectionManager) AdviseHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("connection")
+		if name == "" {
+			http.Error(w, "missing connection parameter", http.StatusBadRequest)
+			return
+		}
+		suggestions, err := f.Advise(r.Context(), name)
+		if errors.Is(err, ErrConnectionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		reports := make([]adviceReport, len(suggestions))
+		for i, s := range suggestions {
+			reports[i] = adviceReport{Table: s.Table, Columns: s.Columns, Statement: s.Statement, Reason: s.Reason,
+				Fingerprints: s.Fingerprints, Executions: s.Executions, RowsExamined: s.RowsExamined}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(reports)
+	})
+}
+
+// adviseStatement returns the index candidates for the badly accessed tables in the plan of query.
+func adviseStatement(query string, plan []PlanStep, info *SchemaInfo) []IndexSuggestion {
+	refs := parseTableRefs(sqlTokens(query))
+	candidates := indexCandidates(sqlTokens(query), refs)
+
+	var suggestions []IndexSuggestion
+	seen := make(map[string]bool)
+	for _, row := range plan {
+		reason := planProblem(row)
+		if reason == "" {
+			continue
+		}
+		table, ok := refs.aliases[strings.ToLower(row.Table)]
+		if !ok || seen[table] {
+			continue
+		}
+		seen[table] = true
+
+		columns := candidates[table]
+		if len(columns) == 0 {
+			continue
+		}
+		if t := info.Table(table); t != nil {
+			if t.indexCovers(columns) {
+				continue
+			}
+			table = t.Name
+		}
+		suggestions = append(suggestions, IndexSuggestion{Table: table, Columns: columns, Reason: reason})
+	}
+	return suggestions
+}
+
+// planProblem describes why a plan row suggests a missing index, or returns "".
+func planProblem(row PlanStep) string {
+	switch {
+	case row.Type == "ALL":
+		return "full table scan"
+	case row.Type == "index":
+		return "full index scan"
+	case strings.Contains(row.Extra, "Using filesort"):
+		return "filesort"
+	}
+	return ""
+}
+
+// indexCovers reports whether an existing index starts with exactly the given columns.
+func (t *TableInfo) indexCovers(columns []string) bool {
+	for _, idx := range t.Indexes {
+		if len(idx.Columns) < len(columns) {
+			continue
+		}
+		covered := true
+		for i := range columns {
+			if !strings.EqualFold(idx.Columns[i], columns[i]) {
+				covered = false
+				break
+			}
+		}
+		if covered {
+			return true
+		}
+	}
+	return false
+}
+
+// indexStatement returns the CREATE INDEX statement for a suggestion.
+func indexStatement(table string, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+	}
+	name := "idx_" + strings.Join(columns, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return fmt.Sprintf("CREATE INDEX %s ON %s (%s)", quoteIdentifier(name), quoteIdentifier(table), strings.Join(quoted, ", "))
+}
+
+// tableRefs maps the names and aliases used in a statement to table names.
+type tableRefs struct {
+	aliases map[string]string
+	tables  []string
+}
+
+// tableRefStop are the keywords that end a table reference.
+var tableRefStop = map[string]bool{
+	"where": true, "join": true, "inner": true, "left": true, "right": true, "cross": true, "straight_join": true,
+	"natural": true, "on": true, "using": true, "group": true, "order": true, "limit": true, "having": true,
+	"for": true, "union": true, "set": true, "force": true, "use": true, "ignore": true, "window": true, "lock": true,
+}
+
+// parseTableRefs collects the tables following FROM, JOIN and UPDATE, with their aliases.
+func parseTableRefs(tokens []string) tableRefs {
+	refs := tableRefs{aliases: make(map[string]string)}
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "from", "join", "update":
+		default:
+			continue
+		}
+		for j := i + 1; j < len(tokens); {
+			if tokens[j] == "(" {
+				break // Derived table.
+			}
+			// Take the last part of a possibly qualified name (db.table).
+			for j+2 < len(tokens) && tokens[j+1] == "." {
+				j += 2
+			}
+			table := unquoteToken(tokens[j])
+			if !isIdentifierToken(tokens[j]) {
+				break
+			}
+			refs.tables = append(refs.tables, table)
+			refs.aliases[table] = table
+			j++
+			if j < len(tokens) && tokens[j] == "as" {
+				j++
+			}
+			if j < len(tokens) && isIdentifierToken(tokens[j]) && !tableRefStop[tokens[j]] {
+				refs.aliases[unquoteToken(tokens[j])] = table
+				j++
+			}
+			// Comma joins list several tables.
+			if j < len(tokens) && tokens[j] == "," && tokens[i] == "from" {
+				j++
+				continue
+			}
+			break
+		}
+	}
+	return refs
+}
+
+// predicateKinds classifies comparison operators: true for equality, false for range.
+var predicateKinds = map[string]bool{
+	"=": true, "<=>": true, "in": true, "is": true,
+	"<": false, ">": false, "<=": false, ">=": false, "between": false, "like": false,
+}
+
+// sectionEnd are the keywords that end a WHERE, ON or ORDER BY section.
+var sectionEnd = map[string]bool{
+	"group": true, "having": true, "limit": true, "union": true, "for": true, "window": true,
+	"join": true, "inner": true, "left": true, "right": true, "cross": true, "straight_join": true, "where": true,
+}
+
+// indexCandidates builds a candidate column list per table from the WHERE, ON and ORDER BY clauses:
+// equality columns first, then the first range column, or the ORDER BY columns when there is no range.
+func indexCandidates(tokens []string, refs tableRefs) map[string][]string {
+	eq := make(map[string][]string)
+	rng := make(map[string][]string)
+	order := make(map[string][]string)
+	add := func(m map[string][]string, table, column string) {
+		for _, existing := range m[table] {
+			if existing == column {
+				return
+			}
+		}
+		m[table] = append(m[table], column)
+	}
+
+	// columnAt resolves a (possibly qualified) column reference ending at index end.
+	columnAt := func(end int) (table, column string, ok bool) {
+		if end < 0 || !isIdentifierToken(tokens[end]) || fingerprintKeywords[tokens[end]] {
+			return "", "", false
+		}
+		column = unquoteToken(tokens[end])
+		if end >= 2 && tokens[end-1] == "." {
+			table, ok = refs.aliases[unquoteToken(tokens[end-2])]
+			return table, column, ok
+		}
+		if len(refs.tables) != 1 {
+			return "", "", false // Ambiguous without a qualifier.
+		}
+		return refs.tables[0], column, true
+	}
+
+	inConditions, inOrder := false, false
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case tok == "where" || tok == "on":
+			inConditions, inOrder = true, false
+			continue
+		case tok == "order" && i+1 < len(tokens) && tokens[i+1] == "by":
+			inConditions, inOrder = false, true
+			i++
+			continue
+		case sectionEnd[tok]:
+			inConditions, inOrder = false, false
+			continue
+		}
+
+		if inOrder {
+			if tok == "," || tok == "asc" || tok == "desc" {
+				continue
+			}
+			if i+1 < len(tokens) && tokens[i+1] == "." {
+				continue
+			}
+			if table, column, ok := columnAt(i); ok {
+				add(order, table, column)
+			}
+			continue
+		}
+		if !inConditions {
+			continue
+		}
+
+		isEq, isPredicate := predicateKinds[tok]
+		if !isPredicate {
+			continue
+		}
+		if table, column, ok := columnAt(i - 1); ok {
+			if isEq {
+				add(eq, table, column)
+			} else {
+				add(rng, table, column)
+			}
+		}
+		// Join conditions compare two columns: "a.x = b.y" is an equality on both sides.
+		if tok == "=" && i+1 < len(tokens) {
+			end := i + 1
+			if end+2 < len(tokens) && tokens[end+1] == "." {
+				end += 2
+			}
+			if table, column, ok := columnAt(end); ok {
+				add(eq, table, column)
+			}
+		}
+	}
+
+	candidates := make(map[string][]string)
+	for _, table := range refs.tables {
+		columns := append([]string(nil), eq[table]...)
+		if len(rng[table]) > 0 {
+			if !containsString(columns, rng[table][0]) {
+				columns = append(columns, rng[table][0])
+			}
+		} else {
+			for _, column := range order[table] {
+				if !containsString(columns, column) {
+					columns = append(columns, column)
+				}
+			}
+		}
+		if len(columns) > 0 {
+			candidates[table] = columns
+		}
+	}
+	return candidates
+}
+
+// isIdentifierToken reports whether a token from sqlTokens is an identifier or keyword.
+func isIdentifierToken(tok string) bool {
+	return isWordToken(tok) || (strings.HasPrefix(tok, "`") && len(tok) > 1)
+}
+
+// unquoteToken removes the backticks of a quoted identifier token.
+func unquoteToken(tok string) string {
+	return strings.Trim(tok, "`")
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package connection
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestIndexCandidates(t *testing.T) {
+	cases := []struct {
+		query string
+		want  map[string][]string
+	}{
+		{
+			query: "SELECT * FROM orders WHERE customer_id = 7 AND status IN ('a', 'b') AND created_at > '2024-01-01' ORDER BY id",
+			want:  map[string][]string{"orders": {"customer_id", "status", "created_at"}},
+		},
+		{
+			query: "SELECT * FROM `orders` WHERE status = 'open' ORDER BY created_at DESC",
+			want:  map[string][]string{"orders": {"status", "created_at"}},
+		},
+		{
+			query: "SELECT o.id FROM orders AS o JOIN customers c ON c.id = o.customer_id WHERE c.country = 'NL' ORDER BY o.total",
+			want:  map[string][]string{"orders": {"customer_id", "total"}, "customers": {"id", "country"}},
+		},
+		{
+			// Unqualified columns are ambiguous in joins.
+			query: "SELECT * FROM a, b WHERE x = 1",
+			want:  map[string][]string{},
+		},
+	}
+	for _, c := range cases {
+		tokens := sqlTokens(c.query)
+		got := indexCandidates(tokens, parseTableRefs(tokens))
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("indexCandidates(%q) = %v, want %v", c.query, got, c.want)
+		}
+	}
+}
+
+func TestAdviseStatement(t *testing.T) {
+	query := "SELECT * FROM orders o WHERE o.customer_id = 7 ORDER BY o.created_at"
+	plan := []PlanStep{{ID: 1, SelectType: "SIMPLE", Table: "o", Type: "ALL", Rows: 100000}}
+	info := &SchemaInfo{Tables: []TableInfo{{
+		Name:    "orders",
+		Indexes: []IndexInfo{{Name: "PRIMARY", Columns: []string{"id"}, Unique: true}},
+	}}}
+
+	suggestions := adviseStatement(query, plan, info)
+	if len(suggestions) != 1 {
+		t.Fatalf("Expected 1 suggestion, got %v", suggestions)
+	}
+	if s := suggestions[0]; s.Table != "orders" || !reflect.DeepEqual(s.Columns, []string{"customer_id", "created_at"}) || s.Reason != "full table scan" {
+		t.Fatalf("Unexpected suggestion %+v", s)
+	}
+	if got := indexStatement("orders", suggestions[0].Columns); got != "CREATE INDEX `idx_customer_id_created_at` ON `orders` (`customer_id`, `created_at`)" {
+		t.Fatalf("Unexpected statement %q", got)
+	}
+
+	info.Tables[0].Indexes = append(info.Tables[0].Indexes, IndexInfo{Name: "idx", Columns: []string{"customer_id", "created_at", "id"}})
+	if suggestions := adviseStatement(query, plan, info); len(suggestions) != 0 {
+		t.Fatalf("Expected no suggestion when an index covers the candidate, got %v", suggestions)
+	}
+}
+
+func TestAdviseHandler(t *testing.T) {
+	factory := newFakeFactory(t, FakeData{})
+	cases := map[string]int{
+		"/advise":                       http.StatusBadRequest,
+		"/advise?connection=missing_db": http.StatusNotFound,
+		// The fake database has no performance_schema.
+		"/advise?connection=primary_db": http.StatusInternalServerError,
+	}
+	for target, want := range cases {
+		recorder := httptest.NewRecorder()
+		factory.AdviseHandler().ServeHTTP(recorder, httptest.NewRequest("GET", target, nil))
+		if recorder.Code != want {
+			t.Errorf("GET %s = %d %q, want %d", target, recorder.Code, recorder.Body.String(), want)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultBackfillBatch    = 1000
+	defaultBackfillMaxPause = 30 * time.Second
+	minBackfillThrottle     = 250 * time.Millisecond
+	backfillCheckpointTable = "mysqlconn_backfill_checkpoints"
+)
+
+// BatchSpec describes a backfill run by Backfill.
+type BatchSpec struct {
+	// Name identifies the backfill for checkpointing. Defaults to Table.
+	Name string
+
+	// Table is the table walked in primary key order. It must have a single-column primary key.
+	Table string
+
+	// Statement is executed once per chunk with the first and last primary key of the chunk as
+	// arguments, e.g. "UPDATE orders SET total_cents = total * 100 WHERE id BETWEEN ? AND ?".
+	// Exactly one of Statement and Func must be set.
+	Statement string
+
+	// Func is called once per chunk inside a transaction with the inclusive primary key bounds of
+	// the chunk and returns the number of rows it changed.
+	Func func(ctx context.Context, tx *gorm.DB, from, to interface{}) (int64, error)
+
+	// BatchSize is the number of rows per chunk. Defaults to 1000.
+	BatchSize int
+
+	// Pause is the delay between chunks while the servers are healthy. Defaults to no delay.
+	Pause time.Duration
+
+	// MaxPause caps the delay while throttled. Defaults to 30 seconds.
+	MaxPause time.Duration
+
+	// Replicas are the names of managed connections to replicas whose lag is checked before each chunk.
+	Replicas []string
+
+	// MaxReplicaLag throttles the backfill while any replica lags more than this. Zero disables the check.
+	MaxReplicaLag time.Duration
+
+	// MaxThreadsRunning throttles the backfill while the primary's Threads_running is above this.
+	// Zero disables the check.
+	MaxThreadsRunning int
+
+	// Restart ignores an existing checkpoint and starts from the beginning of the table.
+	Restart bool
+
+	// OnChunk, if set, is called after every committed chunk.
+	OnChunk func(BackfillResult)
+}
+
+// BackfillResult reports the progress of a backfill.
+type BackfillResult struct {
+	Name string
+
+	// Chunks and Rows count the work done by this call; Rows is the sum reported per chunk.
+	Chunks int
+	Rows   int64
+
+	// LastKey is the last primary key processed, including progress restored from a checkpoint.
+	LastKey interface{}
+
+	// Resumed is true when the run continued from a checkpoint.
+	Resumed bool
+
+	// Throttled is the total time spent waiting for replicas or server load.
+	Throttled time.Duration
+}
+
+// Backfill walks a table in primary key ordered chunks and applies spec.Statement or spec.Func to
+// each chunk, for data migrations that must not overload the primary or its replicas.
+//
+// Parameters:
+// - ctx: Context bounding the backfill. When it is cancelled the current chunk is rolled back and
+// the backfill can be resumed later from the last checkpoint.
+// - name: The name of the managed connection to run on.
+// - spec: The table, the per-chunk work, and the throttling limits.
+//
+// Returns:
+// - BackfillResult: The work done by this call.
+// - error: An error if the spec is invalid, a connection does not exist, or a chunk fails.
+//
+// Behavior:
+// 1. Progress is checkpointed in the table mysqlconn_backfill_checkpoints, created on demand, in the
+// same transaction as each chunk, so a restarted backfill resumes after the last committed chunk.
+// A completed backfill is a no-op until it is run with Restart.
+// 2. Before each chunk, replica lag (SHOW REPLICA STATUS on spec.Replicas) and Threads_running on
+// the primary are compared with the limits. While a limit is exceeded the backfill sleeps, doubling
+// the delay up to MaxPause, and checks again; once healthy the delay returns to Pause.
+//
+// Example Usage:
+//
+//	result, err := connection.GetConnectionManager().Backfill(ctx, "primary_db", connection.BatchSpec{
+//		Table:         "orders",
+//		Statement:     "UPDATE orders SET total_cents = total * 100 WHERE id BETWEEN ? AND ?",
+//		Replicas:      []string{"replica_db"},
+//		MaxReplicaLag: 5 * time.Second,
+//	})
+func (f *ConnectionManager) Backfill(ctx context.Context, name string, spec BatchSpec) (BackfillResult, error) {
+	if spec.Table == "" || (spec.Statement == "") == (spec.Func == nil) {
+		return BackfillResult{}, errors.New("backfill requires a table and exactly one of Statement and Func")
+	}
+	if spec.Name == "" {
+		spec.Name = spec.Table
+	}
+	if spec.BatchSize <= 0 {
+		spec.BatchSize = defaultBackfillBatch
+	}
+	if spec.MaxPause <= 0 {
+		spec.MaxPause = defaultBackfillMaxPause
+	}
+	result := BackfillResult{Name: spec.Name}
+
+	db, err := f.GetDB(name)
+	if err != nil {
+		return result, err
+	}
+	db = db.WithContext(ctx)
+
+	pk, err := singlePrimaryKey(db, spec.Table)
+	if err != nil {
+		return result, fmt.Errorf("failed to backfill %q: %w", spec.Table, err)
+	}
+	lower, completed, err := loadBackfillCheckpoint(db, spec)
+	if err != nil {
+		return result, fmt.Errorf("failed to load checkpoint of backfill %q: %w", spec.Name, err)
+	}
+	if lower != nil {
+		result.Resumed, result.LastKey = true, lower
+	}
+	if completed {
+		return result, nil
+	}
+
+	quotedTable, quotedPK := quoteIdentifier(spec.Table), quoteIdentifier(pk)
+	pause := spec.Pause
+	for {
+		pause, err = f.throttleBackfill(ctx, db, spec, pause, &result)
+		if err != nil {
+			return result, err
+		}
+
+		from, to, err := backfillChunk(db, quotedTable, quotedPK, lower, spec.BatchSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to find next chunk of %q: %w", spec.Table, err)
+		}
+		if from == nil {
+			break
+		}
+
+		var rows int64
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if spec.Func != nil {
+				rows, err = spec.Func(ctx, tx, from, to)
+			} else {
+				exec := tx.Exec(spec.Statement, from, to)
+				rows, err = exec.RowsAffected, exec.Error
+			}
+			if err != nil {
+				return err
+			}
+			return saveBackfillCheckpoint(tx, spec.Name, to, rows, false)
+		})
+		if err != nil {
+			return result, fmt.Errorf("backfill %q failed on chunk [%v, %v]: %w", spec.Name, from, to, err)
+		}
+
+		result.Chunks++
+		result.Rows += rows
+		result.LastKey, lower = to, to
+		if spec.OnChunk != nil {
+			spec.OnChunk(result)
+		}
+	}
+
+	if err := saveBackfillCheckpoint(db, spec.Name, lower, 0, true); err != nil {
+		return result, fmt.Errorf("failed to mark backfill %q completed: %w", spec.Name, err)
+	}
+	return result, nil
+}
+
+// throttleBackfill waits until replica lag and server load are within the spec's limits and returns
+// the pause to use before the next check.
+func (f *ConnectionManager) throttleBackfill(ctx context.Context, db *gorm.DB, spec BatchSpec, pause time.Duration, result *BackfillResult) (time.Duration, error) {
+	for {
+		reason, err := f.backfillPressure(ctx, db, spec)
+		if err != nil {
+			return pause, err
+		}
+		pause = nextBackfillPause(pause, spec.Pause, spec.MaxPause, reason != "")
+		if reason != "" {
+			log.Printf("Backfill %q throttled for %v: %s", spec.Name, pause, reason)
+			result.Throttled += pause
+		}
+		if err := sleep(ctx, f.clock(), pause); err != nil {
+			return pause, err
+		}
+		if reason == "" {
+			return pause, nil
+		}
+	}
+}
+
+// backfillPressure returns why the backfill should slow down, or "" when it may proceed.
+func (f *ConnectionManager) backfillPressure(ctx context.Context, db *gorm.DB, spec BatchSpec) (string, error) {
+	if spec.MaxReplicaLag > 0 {
+		for _, replica := range spec.Replicas {
+			replicaDB, err := f.GetDB(replica)
+			if err != nil {
+				return "", err
+			}
+			lag, ok, err := replicaLag(replicaDB.WithContext(ctx))
+			if err != nil {
+				return "", fmt.Errorf("failed to read replication lag of %q: %w", replica, err)
+			}
+			if !ok {
+				return fmt.Sprintf("replication on %q is not running", replica), nil
+			}
+			if lag > spec.MaxReplicaLag {
+				return fmt.Sprintf("replica %q lags %v", replica, lag), nil
+			}
+		}
+	}
+	if spec.MaxThreadsRunning > 0 {
+		running, err := threadsRunning(db)
+		if err != nil {
+			return "", err
+		}
+		if running > spec.MaxThreadsRunning {
+			return fmt.Sprintf("Threads_running is %d", running), nil
+		}
+	}
+	return "", nil
+}
+
+// nextBackfillPause doubles the pause while throttled, starting at minBackfillThrottle and capped at
+// max, and returns to base once the servers are healthy.
+func nextBackfillPause(current, base, max time.Duration, throttled bool) time.Duration {
+	if !throttled {
+		return base
+	}
+	next := current * 2
+	if next < minBackfillThrottle {
+		next = minBackfillThrottle
+	}
+	if next > max {
+		next = max
+	}
+	return next
+}
+
+// replicaLag returns the replication delay of the server behind db. ok is false when the server is
+// not a replica or its SQL thread is not running (Seconds_Behind_Source is NULL).
+func replicaLag(db *gorm.DB) (lag time.Duration, ok bool, err error) {
+	rows, err := db.Raw("SHOW REPLICA STATUS").Rows()
+	if err != nil {
+		// Servers before 8.0.22 only know the old syntax.
+		if rows, err = db.Raw("SHOW SLAVE STATUS").Rows(); err != nil {
+			return 0, false, err
+		}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, false, err
+	}
+	if !rows.Next() {
+		return 0, false, rows.Err()
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return 0, false, err
+	}
+	for i, column := range columns {
+		if column != "Seconds_Behind_Source" && column != "Seconds_Behind_Master" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, false, nil
+		}
+		seconds, err := strconv.ParseInt(string(values[i]), 10, 64)
+		if err != nil {
+			return 0, false, err
+		}
+		return time.Duration(seconds) * time.Second, true, nil
+	}
+	return 0, false, errors.New("replica status has no Seconds_Behind_Source column")
+}
+
+// backfillChunk returns the first and last primary key of the next chunk after lower (exclusive;
+// nil starts at the beginning of the table). from is nil when no rows are left.
+func backfillChunk(db *gorm.DB, quotedTable, quotedPK string, lower interface{}, size int) (from, to interface{}, err error) {
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s LIMIT 1", quotedPK, quotedTable, quotedPK)
+	var args []interface{}
+	if lower != nil {
+		query = fmt.Sprintf("SELECT %s FROM %s WHERE %s > ? ORDER BY %s LIMIT 1", quotedPK, quotedTable, quotedPK, quotedPK)
+		args = append(args, lower)
+	}
+	if from, err = scanKey(db, query, args...); err != nil || from == nil {
+		return nil, nil, err
+	}
+
+	to, err = scanKey(db, fmt.Sprintf("SELECT %s FROM %s WHERE %s >= ? ORDER BY %s LIMIT 1 OFFSET ?", quotedPK, quotedTable, quotedPK, quotedPK), from, size-1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if to == nil {
+		// Fewer than size rows left: the last chunk ends at the highest key.
+		to, err = scanKey(db, fmt.Sprintf("SELECT MAX(%s) FROM %s WHERE %s >= ?", quotedPK, quotedTable, quotedPK), from)
+	}
+	return from, to, err
+}
+
+// scanKey returns the single value selected by query, or nil when no row (or NULL) is returned.
+func scanKey(db *gorm.DB, query string, args ...interface{}) (interface{}, error) {
+	var value interface{}
+	err := db.Raw(query, args...).Row().Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return keyValue(value), nil
+}
+
+// loadBackfillCheckpoint creates the checkpoint table if needed and returns the last committed key
+// of the backfill. The key is returned as a string; MySQL converts it back when comparing.
+func loadBackfillCheckpoint(db *gorm.DB, spec BatchSpec) (lastKey interface{}, completed bool, err error) {
+	err = db.Exec("CREATE TABLE IF NOT EXISTS `" + backfillCheckpointTable + "` (" +
+		"`name` VARCHAR(191) NOT NULL PRIMARY KEY," +
+		"`last_key` VARCHAR(255) NULL," +
+		"`rows_done` BIGINT NOT NULL DEFAULT 0," +
+		"`completed` BOOL NOT NULL DEFAULT FALSE," +
+		"`updated_at` DATETIME(6) NOT NULL)").Error
+	if err != nil || spec.Restart {
+		if err == nil {
+			err = db.Exec("DELETE FROM `"+backfillCheckpointTable+"` WHERE `name` = ?", spec.Name).Error
+		}
+		return nil, false, err
+	}
+
+	var checkpoint struct {
+		LastKey   *string
+		Completed bool
+	}
+	result := db.Raw("SELECT `last_key`, `completed` FROM `"+backfillCheckpointTable+"` WHERE `name` = ?", spec.Name).Scan(&checkpoint)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, false, result.Error
+	}
+	if checkpoint.LastKey != nil {
+		lastKey = *checkpoint.LastKey
+	}
+	return lastKey, checkpoint.Completed, nil
+}
+
+// saveBackfillCheckpoint records lastKey as the backfill's progress and adds rows to its total.
+func saveBackfillCheckpoint(db *gorm.DB, name string, lastKey interface{}, rows int64, completed bool) error {
+	var key *string
+	if lastKey != nil {
+		s := fmt.Sprint(lastKey)
+		key = &s
+	}
+	return db.Exec("INSERT INTO `"+backfillCheckpointTable+"` (`name`, `last_key`, `rows_done`, `completed`, `updated_at`) "+
+		"VALUES (?, ?, ?, ?, NOW(6)) ON DUPLICATE KEY UPDATE `last_key` = VALUES(`last_key`), "+
+		"`rows_done` = `rows_done` + VALUES(`rows_done`), `completed` = VALUES(`completed`), `updated_at` = NOW(6)",
+		name, key, rows, completed).Error
+}
@@ -0,0 +1,37 @@
+package connection
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackfillValidatesSpec(t *testing.T) {
+	factory := newTestFactory()
+	ctx := context.Background()
+
+	if _, err := factory.Backfill(ctx, "missing_db", BatchSpec{Table: "orders"}); err == nil {
+		t.Fatal("Expected an error for a spec without Statement or Func, got nil")
+	}
+	if _, err := factory.Backfill(ctx, "missing_db", BatchSpec{Table: "orders", Statement: "UPDATE orders SET x = 1 WHERE id BETWEEN ? AND ?"}); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
+
+func TestNextBackfillPause(t *testing.T) {
+	base, max := 10*time.Millisecond, time.Second
+
+	pause := nextBackfillPause(base, base, max, true)
+	if pause != minBackfillThrottle {
+		t.Fatalf("Expected the first throttle to wait %v, got %v", minBackfillThrottle, pause)
+	}
+	for i := 0; i < 5; i++ {
+		pause = nextBackfillPause(pause, base, max, true)
+	}
+	if pause != max {
+		t.Fatalf("Expected the pause to be capped at %v, got %v", max, pause)
+	}
+	if pause = nextBackfillPause(pause, base, max, false); pause != base {
+		t.Fatalf("Expected the pause to return to %v once healthy, got %v", base, pause)
+	}
+}
@@ -0,0 +1,245 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults of WorkloadProfile.
+const (
+	defaultBenchConcurrency = 32
+	defaultBenchDuration    = 10 * time.Second
+)
+
+var defaultBenchPools = []int{2, 4, 8, 16, 32, 64}
+
+// WorkloadProfile describes the synthetic workload run by Benchmark.
+type WorkloadProfile struct {
+	// Queries are run round-robin by every worker. Defaults to "SELECT 1". Use read-only statements
+	// or statements on scratch tables: they really run on the server.
+	Queries []string
+
+	// Concurrency is the number of concurrent workers. Defaults to 32.
+	Concurrency int
+
+	// Duration is how long each pool setting is measured. Defaults to 10 seconds.
+	Duration time.Duration
+
+	// Pools are the pool settings measured, in order. Defaults to MaxOpen 2, 4, 8, 16, 32 and 64 with
+	// MaxIdle equal to MaxOpen.
+	Pools []PoolSetting
+}
+
+// PoolSetting is one pool configuration measured by Benchmark.
+type PoolSetting struct {
+	MaxOpen int
+	MaxIdle int
+}
+
+// BenchmarkStep is the measurement of one pool setting.
+type BenchmarkStep struct {
+	Pool       PoolSetting
+	Operations int64
+	Errors     int64
+
+	// Throughput is in successful operations per second.
+	Throughput float64
+
+	// Latency percentiles of successful operations, including the wait for a pooled connection.
+	P50, P95, P99 time.Duration
+}
+
+// BenchmarkReport is the result of Benchmark: a throughput/latency curve over the pool settings
+// and the recommended setting.
+type BenchmarkReport struct {
+	Profile     WorkloadProfile
+	Steps       []BenchmarkStep
+	Recommended PoolSetting
+}
+
+// Benchmark runs a synthetic workload against the server of a managed connection with different pool
+// sizes and recommends the smallest pool that achieves close to the best throughput.
+//
+// Parameters:
+// - ctx: Context bounding the benchmark. Cancelling it stops the run and returns the steps measured so far.
+// - name: The name of the managed connection whose data source is benchmarked.
+// - profile: The workload and the pool settings to measure.
+//
+// Returns:
+// - *BenchmarkReport: One step per pool setting and the recommendation.
+// - error: An error if the connection does not exist or the benchmark pool cannot be opened.
+//
+// Behavior:
+// 1. Each pool setting is measured on a separate, freshly opened pool using the connection's data source,
+// so the live pool and its settings are not touched.
+// 2. Concurrency workers run the profile's queries for Duration; throughput and latency percentiles are
+// computed from the successful operations.
+// 3. The recommendation is the smallest MaxOpen whose throughput is within 5% of the best measured one,
+// with the lowest p99 latency breaking ties.
+//
+// Example Usage:
+//
+//	report, err := connection.GetConnectionManager().Benchmark(ctx, "primary_db", connection.WorkloadProfile{
+//		Queries:  []string{"SELECT * FROM products WHERE id = 42"},
+//		Duration: 5 * time.Second,
+//	})
+//	if err == nil {
+//		log.Printf("Recommended pool: %+v", report.Recommended)
+//	}
+func (f *ConnectionManager) Benchmark(ctx context.Context, name string, profile WorkloadProfile) (*BenchmarkReport, error) {
+	f.mutex.Lock()
+	config, exists := f.configs[name]
+	f.mutex.Unlock()
+	if !exists {
+		return nil, errNotFound(name, OpBenchmark)
+	}
+
+	if len(profile.Queries) == 0 {
+		profile.Queries = []string{"SELECT 1"}
+	}
+	if profile.Concurrency <= 0 {
+		profile.Concurrency = defaultBenchConcurrency
+	}
+	if profile.Duration <= 0 {
+		profile.Duration = defaultBenchDuration
+	}
+	if len(profile.Pools) == 0 {
+		for _, size := range defaultBenchPools {
+			profile.Pools = append(profile.Pools, PoolSetting{MaxOpen: size, MaxIdle: size})
+		}
+	}
+
+	dsnConfig, err := config.driverConfig(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data source name for %q: %w", name, err)
+	}
+	dsnConfig.ConnectionAttributes = connectionAttributes(dsnConfig.ConnectionAttributes, name, config)
+	connector, err := mysqldriver.NewConnector(dsnConfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data source name for %q: %w", name, err)
+	}
+
+	report := &BenchmarkReport{Profile: profile}
+	for _, pool := range profile.Pools {
+		if pool.MaxOpen <= 0 {
+			return nil, fmt.Errorf("benchmark pool MaxOpen must be positive, got %d", pool.MaxOpen)
+		}
+		step, err := benchmarkPool(ctx, connector, pool, profile)
+		if err != nil {
+			return nil, fmt.Errorf("benchmark of %q with %+v failed: %w", name, pool, err)
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		report.Steps = append(report.Steps, step)
+	}
+	report.Recommended = recommendPool(report.Steps)
+	return report, nil
+}
+
+// benchmarkPool measures the workload on a fresh pool with the given setting.
+func benchmarkPool(ctx context.Context, connector driver.Connector, pool PoolSetting, profile WorkloadProfile) (BenchmarkStep, error) {
+	step := BenchmarkStep{Pool: pool}
+
+	sqlDB := sql.OpenDB(connector)
+	defer sqlDB.Close()
+	sqlDB.SetMaxOpenConns(pool.MaxOpen)
+	sqlDB.SetMaxIdleConns(pool.MaxIdle)
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return step, err
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, profile.Duration)
+	defer cancel()
+
+	var mutex sync.Mutex
+	var latencies []time.Duration
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < profile.Concurrency; w++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			var local []time.Duration
+			var failures int64
+			for i := worker; runCtx.Err() == nil; i++ {
+				began := time.Now()
+				rows, err := sqlDB.QueryContext(runCtx, profile.Queries[i%len(profile.Queries)])
+				if err == nil {
+					for rows.Next() {
+					}
+					err = rows.Close()
+				}
+				if err != nil {
+					// Statements cut off by the end of the measurement are not failures.
+					if runCtx.Err() == nil {
+						failures++
+					}
+					continue
+				}
+				local = append(local, time.Since(began))
+			}
+			mutex.Lock()
+			latencies = append(latencies, local...)
+			step.Errors += failures
+			mutex.Unlock()
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	step.Operations = int64(len(latencies))
+	step.Throughput = float64(step.Operations) / elapsed.Seconds()
+	step.P50, step.P95, step.P99 = percentile(latencies, 50), percentile(latencies, 95), percentile(latencies, 99)
+	if step.Operations == 0 && step.Errors > 0 {
+		return step, errors.New("every operation failed")
+	}
+	return step, nil
+}
+
+// percentile returns the p-th percentile of sorted latencies (nearest rank), or 0 for no samples.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (len(sorted)*p + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// recommendPool returns the smallest pool whose throughput is within 5% of the best step, preferring
+// the lower p99 between pools of the same size.
+func recommendPool(steps []BenchmarkStep) PoolSetting {
+	var best float64
+	for _, step := range steps {
+		if step.Throughput > best {
+			best = step.Throughput
+		}
+	}
+
+	var chosen *BenchmarkStep
+	for i := range steps {
+		step := &steps[i]
+		if step.Throughput < best*0.95 {
+			continue
+		}
+		if chosen == nil || step.Pool.MaxOpen < chosen.Pool.MaxOpen ||
+			(step.Pool.MaxOpen == chosen.Pool.MaxOpen && step.P99 < chosen.P99) {
+			chosen = step
+		}
+	}
+	if chosen == nil {
+		return PoolSetting{}
+	}
+	return chosen.Pool
+}
@@ -0,0 +1,41 @@
+package connection
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecommendPool(t *testing.T) {
+	steps := []BenchmarkStep{
+		{Pool: PoolSetting{MaxOpen: 2, MaxIdle: 2}, Throughput: 4000, P99: 9 * time.Millisecond},
+		{Pool: PoolSetting{MaxOpen: 8, MaxIdle: 8}, Throughput: 9700, P99: 4 * time.Millisecond},
+		{Pool: PoolSetting{MaxOpen: 16, MaxIdle: 16}, Throughput: 10000, P99: 3 * time.Millisecond},
+		{Pool: PoolSetting{MaxOpen: 32, MaxIdle: 32}, Throughput: 9000, P99: 6 * time.Millisecond},
+	}
+	if got := recommendPool(steps); got != (PoolSetting{MaxOpen: 8, MaxIdle: 8}) {
+		t.Fatalf("Expected MaxOpen 8 to be recommended, got %+v", got)
+	}
+	if got := recommendPool(nil); got != (PoolSetting{}) {
+		t.Fatalf("Expected no recommendation without steps, got %+v", got)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 100; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	if p := percentile(sorted, 50); p != 50*time.Millisecond {
+		t.Fatalf("p50 = %v", p)
+	}
+	if p := percentile(sorted, 99); p != 99*time.Millisecond {
+		t.Fatalf("p99 = %v", p)
+	}
+	if p := percentile(nil, 99); p != 0 {
+		t.Fatalf("p99 of no samples = %v", p)
+	}
+	if _, err := newTestFactory().Benchmark(context.Background(), "missing_db", WorkloadProfile{}); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
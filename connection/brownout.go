@@ -0,0 +1,327 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultBrownoutSampleInterval is the default interval of the Threads_running samples of SetBrownoutThresholds.
+const defaultBrownoutSampleInterval = time.Second
+
+// ErrBrownout matches every *BrownoutError with errors.Is.
+var ErrBrownout = errors.New("statement shed by brownout")
+
+// QueryClass tells how much the statements of a context matter, see WithQueryClass. The zero value
+// is QueryInteractive, the class of untagged statements.
+type QueryClass int
+
+const (
+	// QueryInteractive is the class of statements serving a user waiting for the response.
+	QueryInteractive QueryClass = iota
+
+	// QueryCritical statements, e.g. health checks and payments, are never shed.
+	QueryCritical
+
+	// QueryBackground statements come from batch jobs and workers that can retry later.
+	QueryBackground
+
+	// QueryBestEffort statements feed optional features, e.g. recommendations or view counters.
+	QueryBestEffort
+)
+
+func (c QueryClass) String() string {
+	switch c {
+	case QueryInteractive:
+		return "interactive"
+	case QueryCritical:
+		return "critical"
+	case QueryBackground:
+		return "background"
+	case QueryBestEffort:
+		return "best-effort"
+	}
+	return fmt.Sprintf("QueryClass(%d)", int(c))
+}
+
+// shedAt returns the lowest brownout level shedding the class.
+func (c QueryClass) shedAt() BrownoutLevel {
+	switch c {
+	case QueryBestEffort:
+		return BrownoutShedBestEffort
+	case QueryBackground:
+		return BrownoutShedBackground
+	case QueryCritical:
+		return BrownoutShedInteractive + 1
+	}
+	return BrownoutShedInteractive
+}
+
+type queryClassKey struct{}
+
+// WithQueryClass returns a context whose statements belong to class, for SetBrownout.
+//
+// Example Usage:
+//
+//	ctx := connection.WithQueryClass(r.Context(), connection.QueryBestEffort)
+//	err := db.WithContext(ctx).Find(&recommendations).Error
+//	if errors.Is(err, connection.ErrBrownout) {
+//		recommendations = nil // render the page without them
+//	}
+func WithQueryClass(ctx context.Context, class QueryClass) context.Context {
+	return context.WithValue(ctx, queryClassKey{}, class)
+}
+
+// QueryClassOf returns the class of the statements of ctx.
+func QueryClassOf(ctx context.Context) QueryClass {
+	class, _ := ctx.Value(queryClassKey{}).(QueryClass)
+	return class
+}
+
+// BrownoutLevel is the set of query classes shed. Each level sheds the classes of the levels below it.
+type BrownoutLevel int
+
+const (
+	// BrownoutOff sheds nothing.
+	BrownoutOff BrownoutLevel = iota
+
+	// BrownoutShedBestEffort sheds QueryBestEffort statements.
+	BrownoutShedBestEffort
+
+	// BrownoutShedBackground also sheds QueryBackground statements.
+	BrownoutShedBackground
+
+	// BrownoutShedInteractive also sheds QueryInteractive statements, leaving only QueryCritical ones.
+	BrownoutShedInteractive
+)
+
+// BrownoutError is returned for a statement shed by a brownout.
+type BrownoutError struct {
+	Connection string
+	Class      QueryClass
+	Level      BrownoutLevel
+
+	// Cause tells what set the level: "manual" (SetBrownout), or the pool saturation or the
+	// Threads_running of the server crossing a threshold of SetBrownoutThresholds.
+	Cause string
+}
+
+func (e *BrownoutError) Error() string {
+	return fmt.Sprintf("%s statement shed on %q by brownout level %d (%s)", e.Class, e.Connection, e.Level, e.Cause)
+}
+
+// Is makes errors.Is(err, ErrBrownout) true for brownout errors.
+func (e *BrownoutError) Is(target error) bool {
+	return target == ErrBrownout
+}
+
+// BrownoutThresholds raise the brownout level of a connection automatically. Entry i of each array is
+// the threshold of level i+1; a zero entry disables the level for that signal. The highest level
+// reached by either signal, or set with SetBrownout, applies.
+type BrownoutThresholds struct {
+	// PoolSaturation is the share of MaxOpen in use, e.g. {0.8, 0.9, 0.98}. It needs a bounded pool.
+	PoolSaturation [3]float64
+
+	// ThreadsRunning is the Threads_running of the server, sampled every SampleInterval.
+	ThreadsRunning [3]int64
+
+	// SampleInterval defaults to one second.
+	SampleInterval time.Duration
+}
+
+// brownoutState holds the brownout settings of a ConnectionManager.
+type brownoutState struct {
+	level      atomic.Int32
+	thresholds atomic.Pointer[BrownoutThresholds]
+	shed       atomic.Int64
+
+	mutex   sync.Mutex
+	threads map[string]int64
+	stop    context.CancelFunc
+	done    chan struct{}
+}
+
+// SetBrownout sheds the statements of the query classes of level on every connection, e.g. from an
+// incident switch, so that callers get ErrBrownout at once and serve a degraded response instead of
+// timing out. BrownoutOff ends the manual brownout; levels reached through SetBrownoutThresholds still
+// apply.
+//
+// Parameters:
+// - level: The classes to shed. Statements tagged with WithQueryClass(ctx, QueryCritical) are never shed.
+//
+// Notes:
+//   - Statements are shed before they are sent, in the statement hooks of the connections, with a
+//     *BrownoutError matching ErrBrownout. Statements running already are not interrupted.
+//   - Untagged statements are QueryInteractive and are shed from BrownoutShedInteractive only.
+//
+// Example Usage:
+//
+//	factory := connection.GetConnectionManager()
+//	factory.SetBrownout(connection.BrownoutShedBackground) // batch jobs and optional features off
+//	...
+//	factory.SetBrownout(connection.BrownoutOff)
+func (f *ConnectionManager) SetBrownout(level BrownoutLevel) {
+	level = max(BrownoutOff, min(level, BrownoutShedInteractive))
+	if previous := BrownoutLevel(f.brownout.level.Swap(int32(level))); previous != level {
+		log.Printf("Brownout level set to %d (was %d).", level, previous)
+	}
+}
+
+// SetBrownoutThresholds raises the brownout level of each connection progressively with the saturation
+// of its pool and the load of its server. The zero value turns the automatic levels off.
+//
+// Example Usage:
+//
+//	factory.SetBrownoutThresholds(connection.BrownoutThresholds{
+//		PoolSaturation: [3]float64{0.8, 0.9, 0.98},
+//		ThreadsRunning: [3]int64{64, 128, 256},
+//	})
+func (f *ConnectionManager) SetBrownoutThresholds(thresholds BrownoutThresholds) {
+	s := &f.brownout
+	s.mutex.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done, s.threads = nil, nil, nil
+	if thresholds == (BrownoutThresholds{}) {
+		s.thresholds.Store(nil)
+	} else {
+		if thresholds.SampleInterval <= 0 {
+			thresholds.SampleInterval = defaultBrownoutSampleInterval
+		}
+		s.thresholds.Store(&thresholds)
+		if thresholds.ThreadsRunning != ([3]int64{}) {
+			ctx, cancel := context.WithCancel(context.Background())
+			s.stop, s.done = cancel, make(chan struct{})
+			go f.sampleThreadsRunning(ctx, thresholds.SampleInterval, s.done)
+		}
+	}
+	s.mutex.Unlock()
+	if stop != nil {
+		stop()
+		<-done
+	}
+}
+
+// EffectiveBrownout returns the brownout level applying to the statements of a connection and its cause.
+func (f *ConnectionManager) EffectiveBrownout(name string) (BrownoutLevel, string) {
+	f.mutex.Lock()
+	db := f.connections[name]
+	f.mutex.Unlock()
+	var pools []*sql.DB
+	if db != nil {
+		pools, _ = poolShards(db)
+	}
+	return f.brownoutLevel(name, pools)
+}
+
+// BrownoutShed returns the number of statements shed by brownouts since the process started.
+func (f *ConnectionManager) BrownoutShed() int64 {
+	return f.brownout.shed.Load()
+}
+
+// brownoutLevel computes the level of a connection from the manual level and the thresholds.
+func (f *ConnectionManager) brownoutLevel(name string, pools []*sql.DB) (BrownoutLevel, string) {
+	s := &f.brownout
+	level, cause := BrownoutLevel(s.level.Load()), "manual"
+	thresholds := s.thresholds.Load()
+	if thresholds == nil {
+		return level, cause
+	}
+
+	if thresholds.PoolSaturation != ([3]float64{}) {
+		var inUse, maxOpen int
+		for _, pool := range pools {
+			stats := pool.Stats()
+			inUse += stats.InUse
+			maxOpen += stats.MaxOpenConnections
+		}
+		if maxOpen > 0 {
+			saturation := float64(inUse) / float64(maxOpen)
+			for i, threshold := range thresholds.PoolSaturation {
+				if threshold > 0 && saturation >= threshold && BrownoutLevel(i+1) > level {
+					level, cause = BrownoutLevel(i+1), fmt.Sprintf("pool saturation %.0f%%", 100*saturation)
+				}
+			}
+		}
+	}
+	if thresholds.ThreadsRunning != ([3]int64{}) {
+		s.mutex.Lock()
+		running, sampled := s.threads[name]
+		s.mutex.Unlock()
+		for i, threshold := range thresholds.ThreadsRunning {
+			if sampled && threshold > 0 && running >= threshold && BrownoutLevel(i+1) > level {
+				level, cause = BrownoutLevel(i+1), fmt.Sprintf("Threads_running %d", running)
+			}
+		}
+	}
+	return level, cause
+}
+
+// brownoutHook returns the statement hook shedding the statements of a connection whose class the
+// brownout level sheds. pools are the pools of the connection, for its saturation.
+func (f *ConnectionManager) brownoutHook(pools []*sql.DB) statementHook {
+	return func(ctx context.Context, stmt *hookedStatement) error {
+		if f.brownout.level.Load() == 0 && f.brownout.thresholds.Load() == nil {
+			return nil
+		}
+		class := QueryClassOf(ctx)
+		level, cause := f.brownoutLevel(stmt.Conn, pools)
+		if level < class.shedAt() {
+			return nil
+		}
+		f.brownout.shed.Add(1)
+		return &BrownoutError{Connection: stmt.Conn, Class: class, Level: level, Cause: cause}
+	}
+}
+
+// sampleThreadsRunning samples the Threads_running of the server of every connection until ctx ends.
+func (f *ConnectionManager) sampleThreadsRunning(ctx context.Context, interval time.Duration, done chan struct{}) {
+	defer close(done)
+	ticker := f.clock().NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		f.mutex.Lock()
+		connections := make(map[string]*gorm.DB, len(f.connections))
+		for name, db := range f.connections {
+			connections[name] = db
+		}
+		f.mutex.Unlock()
+
+		threads := make(map[string]int64, len(connections))
+		for name, db := range connections {
+			sampleCtx, cancel := context.WithTimeout(WithQueryClass(ctx, QueryCritical), interval)
+			running, err := threadsRunning(db.WithContext(sampleCtx))
+			cancel()
+			if err == nil {
+				threads[name] = int64(running)
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		f.brownout.mutex.Lock()
+		f.brownout.threads = threads
+		f.brownout.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// threadsRunning reads the Threads_running status variable of the server.
+func threadsRunning(db *gorm.DB) (int, error) {
+	var variable string
+	var running int
+	if err := db.Raw("SHOW GLOBAL STATUS LIKE 'Threads_running'").Row().Scan(&variable, &running); err != nil {
+		return 0, fmt.Errorf("failed to read Threads_running: %w", err)
+	}
+	return running, nil
+}
@@ -0,0 +1,74 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestBrownoutShedsByClass(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	db, err := factory.GetDB("primary_db")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	query := func(class QueryClass) error {
+		var users []fakeTestUser
+		return db.WithContext(WithQueryClass(context.Background(), class)).Find(&users).Error
+	}
+
+	factory.SetBrownout(BrownoutShedBackground)
+	defer factory.SetBrownout(BrownoutOff)
+	for class, shed := range map[QueryClass]bool{QueryCritical: false, QueryInteractive: false, QueryBackground: true, QueryBestEffort: true} {
+		err := query(class)
+		if shed != errors.Is(err, ErrBrownout) {
+			t.Fatalf("Class %s: expected shed=%v, got %v", class, shed, err)
+		}
+		var brownout *BrownoutError
+		if shed && (!errors.As(err, &brownout) || brownout.Level != BrownoutShedBackground || brownout.Cause != "manual") {
+			t.Fatalf("Unexpected brownout error %#v", err)
+		}
+	}
+	if shed := factory.BrownoutShed(); shed != 2 {
+		t.Fatalf("Expected 2 statements shed, got %d", shed)
+	}
+
+	factory.SetBrownout(BrownoutOff)
+	if err := query(QueryBestEffort); err != nil {
+		t.Fatalf("Expected statements to run after the brownout, got %v", err)
+	}
+}
+
+func TestBrownoutThresholds(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	db, _ := factory.GetDB("primary_db")
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(2)
+	conn, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to hold a connection: %v", err)
+	}
+	defer conn.Close()
+
+	factory.SetBrownoutThresholds(BrownoutThresholds{PoolSaturation: [3]float64{0.5, 0.9, 1}})
+	defer factory.SetBrownoutThresholds(BrownoutThresholds{})
+	if level, cause := factory.EffectiveBrownout("primary_db"); level != BrownoutShedBestEffort || cause != "pool saturation 50%" {
+		t.Fatalf("Expected level 1 from the pool saturation, got %d (%s)", level, cause)
+	}
+	var users []fakeTestUser
+	ctx := WithQueryClass(context.Background(), QueryBestEffort)
+	if err := db.WithContext(ctx).Find(&users).Error; !errors.Is(err, ErrBrownout) {
+		t.Fatalf("Expected the best-effort statement to be shed, got %v", err)
+	}
+	if err := db.WithContext(WithQueryClass(context.Background(), QueryBackground)).Find(&users).Error; err != nil {
+		t.Fatalf("Expected the background statement to run, got %v", err)
+	}
+
+	factory.brownout.thresholds.Store(&BrownoutThresholds{ThreadsRunning: [3]int64{10, 20, 40}})
+	factory.brownout.mutex.Lock()
+	factory.brownout.threads = map[string]int64{"primary_db": 25}
+	factory.brownout.mutex.Unlock()
+	if level, cause := factory.EffectiveBrownout("primary_db"); level != BrownoutShedBackground || cause != "Threads_running 25" {
+		t.Fatalf("Expected level 2 from the server load, got %d (%s)", level, cause)
+	}
+}
@@ -0,0 +1,75 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+)
+
+// ErrQueryBudgetExceeded is returned for statements issued after the query budget of their context is spent.
+var ErrQueryBudgetExceeded = errors.New("query budget exceeded")
+
+type queryBudgetKey struct{}
+
+// queryBudget counts the statements issued with one request context.
+type queryBudget struct {
+	limit  int64
+	used   atomic.Int64
+	logged atomic.Bool
+}
+
+// WithQueryBudget returns a context allowing at most n statements on managed connections. Every statement
+// issued with the context (db.WithContext(ctx)), on any connection, counts against the same budget,
+// which makes accidental N+1 patterns visible at runtime.
+//
+// Once the budget is spent, further statements fail with ErrQueryBudgetExceeded, or only log a warning
+// on connections configured with DBConfig.QueryBudgetLogOnly. A budget set on a parent context is
+// replaced, not nested. n <= 0 removes the budget.
+//
+// Example Usage:
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		ctx := connection.WithQueryBudget(r.Context(), 20)
+//		db, _ := connection.GetConnectionManager().GetDBContext(ctx, "primary_db")
+//		...
+//	}
+func WithQueryBudget(ctx context.Context, n int) context.Context {
+	if n <= 0 {
+		return context.WithValue(ctx, queryBudgetKey{}, (*queryBudget)(nil))
+	}
+	return context.WithValue(ctx, queryBudgetKey{}, &queryBudget{limit: int64(n)})
+}
+
+// QueryBudgetUsage returns the number of statements counted against the budget of ctx and the budget.
+// ok is false when ctx carries no budget.
+func QueryBudgetUsage(ctx context.Context) (used, limit int, ok bool) {
+	budget, _ := ctx.Value(queryBudgetKey{}).(*queryBudget)
+	if budget == nil {
+		return 0, 0, false
+	}
+	return int(budget.used.Load()), int(budget.limit), true
+}
+
+// queryBudgetHook returns the statement hook counting statements against the budget of their context.
+// With logOnly, the first statement over budget is logged and all statements are let through.
+func queryBudgetHook(logOnly bool) statementHook {
+	return func(ctx context.Context, stmt *hookedStatement) error {
+		budget, _ := ctx.Value(queryBudgetKey{}).(*queryBudget)
+		if budget == nil {
+			return nil
+		}
+		used := budget.used.Add(1)
+		if used <= budget.limit {
+			return nil
+		}
+		if logOnly {
+			if budget.logged.CompareAndSwap(false, true) {
+				log.Printf("Query budget of %d statements exceeded on %q by: %s", budget.limit, stmt.Conn, stmt.SQL)
+			}
+			return nil
+		}
+		return fmt.Errorf("%w: statement %d of %d allowed on %q", ErrQueryBudgetExceeded, used, budget.limit, stmt.Conn)
+	}
+}
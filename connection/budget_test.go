@@ -0,0 +1,51 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestQueryBudget(t *testing.T) {
+	db, hooks, pool := newRecordingDB(t, "budget_db")
+	hooks.set("query_budget", queryBudgetHook(false))
+
+	ctx := WithQueryBudget(context.Background(), 2)
+	for i := 0; i < 2; i++ {
+		if err := db.WithContext(ctx).Exec("UPDATE t SET a = 1").Error; err != nil {
+			t.Fatalf("Unexpected error within budget: %v", err)
+		}
+	}
+	if err := db.WithContext(ctx).Exec("UPDATE t SET a = 1").Error; !errors.Is(err, ErrQueryBudgetExceeded) {
+		t.Fatalf("Expected ErrQueryBudgetExceeded, got %v", err)
+	}
+	if used, limit, ok := QueryBudgetUsage(ctx); !ok || used != 3 || limit != 2 {
+		t.Fatalf("Unexpected usage %d/%d (%v)", used, limit, ok)
+	}
+	if len(pool.recorded()) != 2 {
+		t.Fatalf("Expected the statement over budget to be rejected, got %q", pool.recorded())
+	}
+
+	// Statements without a budget, or with the budget removed, are not counted.
+	if err := db.Exec("UPDATE t SET a = 1").Error; err != nil {
+		t.Fatalf("Unexpected error without budget: %v", err)
+	}
+	if _, _, ok := QueryBudgetUsage(WithQueryBudget(ctx, 0)); ok {
+		t.Fatal("Expected WithQueryBudget(ctx, 0) to remove the budget")
+	}
+}
+
+func TestQueryBudgetLogOnly(t *testing.T) {
+	db, hooks, pool := newRecordingDB(t, "budget_db")
+	hooks.set("query_budget", queryBudgetHook(true))
+
+	ctx := WithQueryBudget(context.Background(), 1)
+	for i := 0; i < 3; i++ {
+		if err := db.WithContext(ctx).Exec("UPDATE t SET a = 1").Error; err != nil {
+			t.Fatalf("Unexpected error in log-only mode: %v", err)
+		}
+	}
+	if len(pool.recorded()) != 3 {
+		t.Fatalf("Expected every statement to run, got %q", pool.recorded())
+	}
+}
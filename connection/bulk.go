@@ -0,0 +1,251 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// defaultBulkChunkSize is the default of BulkOptions.ChunkSize.
+const defaultBulkChunkSize = 100
+
+// Savepoints of BulkExec, for a chunk and for a statement of a failed chunk re-run by BulkCollect.
+const (
+	bulkChunkSavepoint     = "mysqlconn_bulk_chunk"
+	bulkStatementSavepoint = "mysqlconn_bulk_statement"
+)
+
+// ErrBulkFailureLimit is returned by BulkExec when more statements failed than BulkOptions.MaxFailures.
+var ErrBulkFailureLimit = errors.New("too many failed statements")
+
+// Stmt is a statement with its placeholder arguments.
+type Stmt struct {
+	SQL  string
+	Args []interface{}
+}
+
+// BulkFailurePolicy tells BulkExec what to do with a chunk whose statement fails.
+type BulkFailurePolicy int
+
+const (
+	// BulkAbort rolls back the whole transaction at the first failing statement.
+	BulkAbort BulkFailurePolicy = iota
+
+	// BulkSkip rolls back the failing chunk and goes on with the next one.
+	BulkSkip
+
+	// BulkCollect rolls back the failing chunk and re-runs its statements one by one, keeping those
+	// that succeed and collecting those that fail.
+	BulkCollect
+)
+
+func (p BulkFailurePolicy) String() string {
+	switch p {
+	case BulkAbort:
+		return "abort"
+	case BulkSkip:
+		return "skip"
+	case BulkCollect:
+		return "collect"
+	}
+	return fmt.Sprintf("BulkFailurePolicy(%d)", int(p))
+}
+
+// BulkOptions configure BulkExec.
+type BulkOptions struct {
+	// ChunkSize is the number of statements per savepoint. Defaults to 100.
+	ChunkSize int
+
+	// OnFailure is the policy for failing chunks. Defaults to BulkAbort.
+	OnFailure BulkFailurePolicy
+
+	// MaxFailures rolls back the whole transaction once more failures are reported, as with BulkAbort.
+	// Zero allows any number.
+	MaxFailures int
+}
+
+// BulkFailure is a failed statement of BulkExec. With BulkAbort it is also the error returned.
+type BulkFailure struct {
+	// Chunk is the 0-based index of the chunk of the statement.
+	Chunk int
+
+	// Statement is the 0-based index of the failed statement in the statements of BulkExec, and SQL its text.
+	Statement int
+	SQL       string
+
+	// Skipped is the number of statements rolled back because of the failure: every statement with
+	// BulkAbort, the whole chunk with BulkSkip, the statement itself with BulkCollect.
+	Skipped int
+
+	// Err is the error of the statement.
+	Err error
+}
+
+func (e *BulkFailure) Error() string {
+	return fmt.Sprintf("bulk statement %d (chunk %d) failed: %v", e.Statement, e.Chunk, e.Err)
+}
+
+func (e *BulkFailure) Unwrap() error {
+	return e.Err
+}
+
+// BulkReport describes the outcome of BulkExec.
+type BulkReport struct {
+	// Statements is the number of statements given, and Chunks the number of chunks they were split into.
+	Statements int
+	Chunks     int
+
+	// Applied is the number of statements kept in the transaction, and RowsAffected the rows they
+	// affected. They count nothing when the transaction was rolled back.
+	Applied      int
+	RowsAffected int64
+
+	// Failures lists the failed statements in order.
+	Failures []BulkFailure
+
+	// Committed reports whether the transaction was committed.
+	Committed bool
+}
+
+// BulkExec executes many statements in one transaction, setting a savepoint before each chunk so that a
+// failing chunk can be undone without losing the others, e.g. to import a file with a few dirty rows.
+//
+// Parameters:
+// - ctx: Context bounding the whole transaction.
+// - name: The name of the managed connection.
+// - stmts: The statements, run in order.
+// - opts: The chunk size, the failure policy and the maximum number of failures.
+//
+// Returns:
+// - *BulkReport: The statements applied, the rows affected, the failures and whether the transaction
+// was committed. It is nil only when the connection does not exist.
+// - error: The *BulkFailure of the failing statement with BulkAbort, an error wrapping
+// ErrBulkFailureLimit when MaxFailures is exceeded, or the error of the transaction or of ctx.
+//
+// Behavior:
+//  1. Statements are split into chunks of ChunkSize. Each chunk runs after a savepoint, released when
+//     the chunk succeeds.
+//  2. When a statement fails, BulkAbort rolls back the transaction; BulkSkip rolls back to the
+//     savepoint and reports the chunk as one failure; BulkCollect rolls back to the savepoint and re-runs
+//     the statements of the chunk under a savepoint each, reporting those that fail again.
+//  3. The statements pass through the hooks of the connection as statements of a transaction.
+//
+// Notes:
+//   - Statements with implicit commits (DDL, LOCK TABLES) end the transaction and its savepoints; do not
+//     mix them in.
+//   - A deadlock rolls back the whole transaction on the server, so BulkExec fails whatever the policy.
+//
+// Example Usage:
+//
+//	stmts := make([]connection.Stmt, 0, len(records))
+//	for _, r := range records {
+//		stmts = append(stmts, connection.Stmt{SQL: "INSERT INTO customers (id, email) VALUES (?, ?)", Args: []interface{}{r.ID, r.Email}})
+//	}
+//	report, err := connection.GetConnectionManager().BulkExec(ctx, "primary_db", stmts,
+//		connection.BulkOptions{ChunkSize: 500, OnFailure: connection.BulkCollect, MaxFailures: 100})
+//	for _, failure := range report.Failures {
+//		log.Printf("record %d rejected: %v", failure.Statement, failure.Err)
+//	}
+func (f *ConnectionManager) BulkExec(ctx context.Context, name string, stmts []Stmt, opts BulkOptions) (*BulkReport, error) {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = defaultBulkChunkSize
+	}
+	db, err := f.GetDBContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	report := &BulkReport{Statements: len(stmts), Chunks: (len(stmts) + opts.ChunkSize - 1) / opts.ChunkSize}
+	if len(stmts) == 0 {
+		return report, nil
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for chunk := 0; chunk < report.Chunks; chunk++ {
+			start := chunk * opts.ChunkSize
+			end := min(start+opts.ChunkSize, len(stmts))
+			if err := f.bulkChunk(tx, report, stmts, chunk, start, end, opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		report.Applied, report.RowsAffected = 0, 0
+		var failure *BulkFailure
+		if !errors.As(err, &failure) && !errors.Is(err, ErrBulkFailureLimit) {
+			err = connError(name, OpBulkExec, err)
+		}
+		return report, err
+	}
+	report.Committed = true
+	return report, nil
+}
+
+// bulkChunk runs the statements of one chunk, stmts[start:end], under a savepoint and applies the
+// failure policy.
+func (f *ConnectionManager) bulkChunk(tx *gorm.DB, report *BulkReport, stmts []Stmt, chunk, start, end int, opts BulkOptions) error {
+	if err := tx.SavePoint(bulkChunkSavepoint).Error; err != nil {
+		return err
+	}
+	var rows int64
+	failed, failErr := -1, error(nil)
+	for i := start; i < end; i++ {
+		result := tx.Exec(stmts[i].SQL, stmts[i].Args...)
+		if result.Error != nil {
+			failed, failErr = i, result.Error
+			break
+		}
+		rows += result.RowsAffected
+	}
+	if failed < 0 {
+		report.Applied += end - start
+		report.RowsAffected += rows
+		return tx.Exec("RELEASE SAVEPOINT " + bulkChunkSavepoint).Error
+	}
+
+	failure := BulkFailure{Chunk: chunk, Statement: failed, SQL: stmts[failed].SQL, Skipped: end - start, Err: failErr}
+	if (opts.OnFailure != BulkSkip && opts.OnFailure != BulkCollect) || tx.Statement.Context.Err() != nil {
+		failure.Skipped = len(stmts)
+		report.Failures = append(report.Failures, failure)
+		return &failure
+	}
+	if err := tx.RollbackTo(bulkChunkSavepoint).Error; err != nil {
+		return err
+	}
+	if opts.OnFailure == BulkSkip {
+		return report.fail(failure, opts)
+	}
+
+	for i := start; i < end; i++ {
+		if err := tx.SavePoint(bulkStatementSavepoint).Error; err != nil {
+			return err
+		}
+		result := tx.Exec(stmts[i].SQL, stmts[i].Args...)
+		if result.Error == nil {
+			report.Applied++
+			report.RowsAffected += result.RowsAffected
+			continue
+		}
+		if err := tx.Statement.Context.Err(); err != nil {
+			return err
+		}
+		if err := tx.RollbackTo(bulkStatementSavepoint).Error; err != nil {
+			return err
+		}
+		failure := BulkFailure{Chunk: chunk, Statement: i, SQL: stmts[i].SQL, Skipped: 1, Err: result.Error}
+		if err := report.fail(failure, opts); err != nil {
+			return err
+		}
+	}
+	return tx.Exec("RELEASE SAVEPOINT " + bulkChunkSavepoint).Error
+}
+
+// fail records a failure and returns an error once there are more than opts.MaxFailures.
+func (r *BulkReport) fail(failure BulkFailure, opts BulkOptions) error {
+	r.Failures = append(r.Failures, failure)
+	if opts.MaxFailures > 0 && len(r.Failures) > opts.MaxFailures {
+		return fmt.Errorf("%w: %d over the limit of %d, last: %w", ErrBulkFailureLimit, len(r.Failures), opts.MaxFailures, &failure)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// bulkImport returns ten inserts into accounts, the third and the seventh duplicating a seeded key.
+func bulkImport() []Stmt {
+	var stmts []Stmt
+	for i := 1; i <= 10; i++ {
+		id := i + 100
+		if i == 3 || i == 7 {
+			id = 1
+		}
+		stmts = append(stmts, Stmt{SQL: "INSERT INTO accounts (id, email) VALUES (?, ?)", Args: []interface{}{id, fmt.Sprintf("user%d@example.com", i)}})
+	}
+	return stmts
+}
+
+func newBulkFactory(t *testing.T) *ConnectionManager {
+	t.Helper()
+	factory := newTestFactory()
+	err := factory.InitFake("ledger", FakeData{"accounts": {Rows: []map[string]any{{"id": 1, "email": "seed@example.com"}}}})
+	if err != nil {
+		t.Fatalf("InitFake failed: %v", err)
+	}
+	return factory
+}
+
+func countAccounts(t *testing.T, factory *ConnectionManager) int64 {
+	t.Helper()
+	var n int64
+	if err := factory.connections["ledger"].Raw("SELECT COUNT(*) FROM accounts").Scan(&n).Error; err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	return n
+}
+
+func TestBulkExecPolicies(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		policy   BulkFailurePolicy
+		applied  int
+		failures []int
+		skipped  int
+	}{
+		{BulkSkip, 2, []int{2, 6}, 8},
+		{BulkCollect, 8, []int{2, 6}, 2},
+	} {
+		factory := newBulkFactory(t)
+		report, err := factory.BulkExec(ctx, "ledger", bulkImport(), BulkOptions{ChunkSize: 4, OnFailure: tc.policy})
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tc.policy, err)
+		}
+		if !report.Committed || report.Chunks != 3 || report.Applied != tc.applied || report.RowsAffected != int64(tc.applied) {
+			t.Errorf("%v: unexpected report %+v", tc.policy, report)
+		}
+		skipped := 0
+		for i, failure := range report.Failures {
+			if i >= len(tc.failures) || failure.Statement != tc.failures[i] || failure.Err == nil {
+				t.Errorf("%v: unexpected failure %+v", tc.policy, failure)
+			}
+			skipped += failure.Skipped
+		}
+		if len(report.Failures) != len(tc.failures) || skipped != tc.skipped {
+			t.Errorf("%v: expected failures %v skipping %d statements, got %+v", tc.policy, tc.failures, tc.skipped, report.Failures)
+		}
+		if n := countAccounts(t, factory); n != int64(tc.applied)+1 {
+			t.Errorf("%v: expected %d committed rows, got %d", tc.policy, tc.applied+1, n)
+		}
+	}
+}
+
+func TestBulkExecAbortsAndLimitsFailures(t *testing.T) {
+	ctx := context.Background()
+	factory := newBulkFactory(t)
+	report, err := factory.BulkExec(ctx, "ledger", bulkImport(), BulkOptions{ChunkSize: 4})
+	var failure *BulkFailure
+	if !errors.As(err, &failure) || failure.Statement != 2 || failure.Chunk != 0 {
+		t.Fatalf("Expected the failure of the third statement, got %v", err)
+	}
+	if report.Committed || report.Applied != 0 || countAccounts(t, factory) != 1 {
+		t.Fatalf("Expected the transaction to be rolled back, got %+v", report)
+	}
+
+	report, err = factory.BulkExec(ctx, "ledger", bulkImport(), BulkOptions{ChunkSize: 4, OnFailure: BulkCollect, MaxFailures: 1})
+	if !errors.Is(err, ErrBulkFailureLimit) || report.Committed || len(report.Failures) != 2 {
+		t.Fatalf("Expected the failure limit to roll back the transaction, got %v, %+v", err, report)
+	}
+	if countAccounts(t, factory) != 1 {
+		t.Fatal("Expected no committed rows")
+	}
+
+	if _, err := factory.BulkExec(ctx, "missing", bulkImport(), BulkOptions{}); !errors.Is(err, ErrConnectionNotFound) {
+		t.Fatalf("Expected ErrConnectionNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// canaryLatencyFloor is the latency under which a canary query on the new pool is never too slow,
+// so that the jitter of fast queries does not fail CanaryCheck.MaxLatencyRatio.
+const canaryLatencyFloor = 5 * time.Millisecond
+
+var (
+	// ErrCanaryMismatch is the error of a canary check whose query returned different rows on the new pool.
+	ErrCanaryMismatch = errors.New("new pool returned different results than the current pool")
+
+	// ErrCanaryTooSlow is the error of a canary check whose query was too slow on the new pool.
+	ErrCanaryTooSlow = errors.New("new pool is slower than allowed")
+)
+
+// CanaryCheck is a smoke query RotateDSN runs against the new pool of a connection, and against its
+// current pool for comparison, before swapping them.
+type CanaryCheck struct {
+	// Name identifies the check in CanaryResult. It defaults to the query.
+	Name string
+
+	// Query and Args are the smoke query. It must be read-only and cheap: the connection cannot be
+	// retrieved while the checks run.
+	Query string
+	Args  []interface{}
+
+	// CompareResults fails the check when the rows returned by the new pool differ from the rows
+	// returned by the current pool, e.g. SELECT DATABASE(), @@read_only or the last applied migration.
+	CompareResults bool
+
+	// MaxLatencyRatio fails the check when the query takes more than this many times longer on the new
+	// pool than on the current one. Zero disables the comparison.
+	MaxLatencyRatio float64
+}
+
+// CanaryResult is the outcome of one CanaryCheck.
+type CanaryResult struct {
+	Check string
+
+	// NewLatency and OldLatency are the durations of the query on the new and the current pool.
+	NewLatency time.Duration
+	OldLatency time.Duration
+
+	// OldErr is the error of the query on the current pool, if any. The comparisons are then skipped:
+	// the current endpoint is typically down when the data source is rotated away from it.
+	OldErr error
+
+	// Err is why the check failed, nil when it passed.
+	Err error
+}
+
+// CanaryError is returned by RotateDSN when a canary check failed. The new pool was closed and the
+// connection keeps its current pool.
+type CanaryError struct {
+	Connection string
+	Results    []CanaryResult
+}
+
+func (e *CanaryError) Error() string {
+	var failed []string
+	for _, result := range e.Results {
+		if result.Err != nil {
+			failed = append(failed, fmt.Sprintf("%q: %v", result.Check, result.Err))
+		}
+	}
+	return fmt.Sprintf("canary verification of the new pool of %q failed (%d of %d checks): %s",
+		e.Connection, len(failed), len(e.Results), strings.Join(failed, "; "))
+}
+
+// Unwrap returns the errors of the failed checks, for errors.Is(err, ErrCanaryMismatch).
+func (e *CanaryError) Unwrap() []error {
+	var errs []error
+	for _, result := range e.Results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	return errs
+}
+
+// AddCanaryCheck registers a smoke query that RotateDSN runs on the new pool of a connection before
+// swapping it in, protecting against pointing the connection at the wrong database. The checks of a
+// connection are kept until it is closed.
+//
+// Example Usage:
+//
+//	factory := connection.GetConnectionManager()
+//	factory.AddCanaryCheck("primary_db", connection.CanaryCheck{
+//		Name:           "same schema",
+//		Query:          "SELECT DATABASE(), MAX(version) FROM schema_migrations",
+//		CompareResults: true,
+//	})
+//	factory.AddCanaryCheck("primary_db", connection.CanaryCheck{
+//		Query:           "SELECT id FROM orders ORDER BY id DESC LIMIT 1",
+//		MaxLatencyRatio: 3,
+//	})
+func (f *ConnectionManager) AddCanaryCheck(name string, check CanaryCheck) {
+	if check.Name == "" {
+		check.Name = check.Query
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.canaries == nil {
+		f.canaries = make(map[string][]CanaryCheck)
+	}
+	f.canaries[name] = append(f.canaries[name], check)
+}
+
+// canaryVerifier returns the verifier running the canary checks of name, or nil when it has none.
+// The caller holds the mutex.
+func (f *ConnectionManager) canaryVerifier(name string) poolVerifier {
+	checks := append([]CanaryCheck(nil), f.canaries[name]...)
+	if len(checks) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, pool, replaced *sql.DB) error {
+		results := make([]CanaryResult, len(checks))
+		failed := false
+		for i, check := range checks {
+			results[i] = runCanaryCheck(ctx, check, pool, replaced)
+			failed = failed || results[i].Err != nil
+		}
+		if failed {
+			return &CanaryError{Connection: name, Results: results}
+		}
+		for _, result := range results {
+			log.Printf("Canary check %q passed on the new pool of %q in %v (current pool: %v).",
+				result.Check, name, result.NewLatency, result.OldLatency)
+		}
+		return nil
+	}
+}
+
+// runCanaryCheck runs check on the new pool and the current pool and compares them.
+func runCanaryCheck(ctx context.Context, check CanaryCheck, pool, replaced *sql.DB) CanaryResult {
+	result := CanaryResult{Check: check.Name}
+	newRows, newLatency, err := canaryQuery(ctx, pool, check)
+	result.NewLatency = newLatency
+	if err != nil {
+		result.Err = fmt.Errorf("query failed on the new pool: %w", err)
+		return result
+	}
+	oldRows, oldLatency, err := canaryQuery(ctx, replaced, check)
+	result.OldLatency, result.OldErr = oldLatency, err
+	if err != nil {
+		return result
+	}
+
+	if check.CompareResults && !reflect.DeepEqual(newRows, oldRows) {
+		result.Err = fmt.Errorf("%w: %v instead of %v", ErrCanaryMismatch, newRows, oldRows)
+		return result
+	}
+	if check.MaxLatencyRatio > 0 && newLatency > canaryLatencyFloor &&
+		float64(newLatency) > check.MaxLatencyRatio*float64(oldLatency) {
+		result.Err = fmt.Errorf("%w: %v instead of %v", ErrCanaryTooSlow, newLatency, oldLatency)
+	}
+	return result
+}
+
+// canaryQuery runs the query of check on pool and returns its rows, with []byte values as strings.
+func canaryQuery(ctx context.Context, pool *sql.DB, check CanaryCheck) ([][]interface{}, time.Duration, error) {
+	start := time.Now()
+	rows, err := pool.QueryContext(ctx, check.Query, check.Args...)
+	if err != nil {
+		return nil, time.Since(start), err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, time.Since(start), err
+	}
+	var values [][]interface{}
+	for rows.Next() {
+		row := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, time.Since(start), err
+		}
+		for i, value := range row {
+			if b, ok := value.([]byte); ok {
+				row[i] = string(b)
+			}
+		}
+		values = append(values, row)
+	}
+	return values, time.Since(start), rows.Err()
+}
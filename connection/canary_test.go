@@ -0,0 +1,45 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+func TestCanaryVerifier(t *testing.T) {
+	database := func(name string) *sql.DB {
+		db := sql.OpenDB(&scriptedConnector{rows: func(query string) ([]string, [][]driver.Value) {
+			return []string{"DATABASE()"}, [][]driver.Value{{[]byte(name)}}
+		}})
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	factory := newTestFactory()
+	if factory.canaryVerifier("primary_db") != nil {
+		t.Fatal("Expected no verifier without canary checks")
+	}
+	factory.AddCanaryCheck("primary_db", CanaryCheck{Query: "SELECT DATABASE()", CompareResults: true})
+	verify := factory.canaryVerifier("primary_db")
+	ctx := context.Background()
+
+	if err := verify(ctx, database("app"), database("app")); err != nil {
+		t.Fatalf("Expected the same database to pass, got %v", err)
+	}
+	err := verify(ctx, database("app_staging"), database("app"))
+	var canaryErr *CanaryError
+	if !errors.As(err, &canaryErr) || !errors.Is(err, ErrCanaryMismatch) || len(canaryErr.Results) != 1 ||
+		canaryErr.Results[0].Check != "SELECT DATABASE()" {
+		t.Fatalf("Expected a canary mismatch, got %v", err)
+	}
+
+	closed := database("app")
+	closed.Close()
+	if err := verify(ctx, database("app_staging"), closed); err != nil {
+		t.Fatalf("Expected the comparison to be skipped when the current pool fails, got %v", err)
+	}
+	if err := verify(ctx, closed, database("app")); !errors.As(err, &canaryErr) {
+		t.Fatalf("Expected a failure when the new pool fails, got %v", err)
+	}
+}
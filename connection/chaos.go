@@ -0,0 +1,139 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/hemant-dhiman/MySQL-connection/constants"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrChaosDisabled is returned by InjectChaos when chaos mode is not enabled.
+var ErrChaosDisabled = errors.New("chaos mode is disabled")
+
+// chaosEnabled is set by EnableChaos or the MYSQLCONN_CHAOS environment variable.
+var chaosEnabled atomic.Bool
+
+func init() {
+	switch os.Getenv(constants.ENV_MYSQLCONN_CHAOS) {
+	case "1", "true":
+		chaosEnabled.Store(true)
+	}
+}
+
+// EnableChaos turns chaos mode on or off for the process. It must be enabled before connections are
+// initialized, since only connections initialized in chaos mode carry the fault injection hook; with
+// chaos mode off (the default) fault injection has no cost at all.
+func EnableChaos(enabled bool) {
+	chaosEnabled.Store(enabled)
+}
+
+// ChaosConfig describes the faults injected into a managed connection for resilience testing.
+type ChaosConfig struct {
+	// FailPing makes the health check in GetDB fail, which drives callers into the reconnect path.
+	FailPing bool
+
+	// Latency is added before every statement, simulating slow queries.
+	Latency time.Duration
+
+	// DropRate is the fraction (0 to 1) of statements that fail with driver.ErrBadConn, as if the
+	// connection had been dropped by the server or the network.
+	DropRate float64
+
+	// ReconnectError, when set, is returned by reconnect attempts instead of reconnecting.
+	ReconnectError error
+}
+
+// chaosRegistry holds the injected faults per connection name. The zero value is ready to use.
+type chaosRegistry struct {
+	mutex  sync.RWMutex
+	faults map[string]ChaosConfig
+}
+
+func (r *chaosRegistry) get(name string) (ChaosConfig, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	config, ok := r.faults[name]
+	return config, ok
+}
+
+// InjectChaos sets the faults injected into the named connection, replacing earlier ones. Faults are
+// kept by name, so they survive reconnects until ClearChaos is called.
+//
+// Parameters:
+// - name: The name of the managed connection. It does not need to exist yet.
+// - config: The faults to inject.
+//
+// Returns:
+// - error: ErrChaosDisabled unless chaos mode was enabled with EnableChaos or MYSQLCONN_CHAOS=1.
+//
+// Example Usage:
+//
+//	connection.EnableChaos(true)
+//	factory := connection.GetConnectionManager()
+//	_ = factory.InitDataSourceConnection("primary_db", config)
+//	_ = factory.InjectChaos("primary_db", connection.ChaosConfig{Latency: 2 * time.Second, DropRate: 0.1})
+//	defer factory.ClearChaos("primary_db")
+func (f *ConnectionManager) InjectChaos(name string, config ChaosConfig) error {
+	if !chaosEnabled.Load() {
+		return ErrChaosDisabled
+	}
+	if config.DropRate < 0 || config.DropRate > 1 {
+		return fmt.Errorf("chaos drop rate must be between 0 and 1, got %v", config.DropRate)
+	}
+
+	f.chaos.mutex.Lock()
+	defer f.chaos.mutex.Unlock()
+	if f.chaos.faults == nil {
+		f.chaos.faults = make(map[string]ChaosConfig)
+	}
+	f.chaos.faults[name] = config
+	log.Printf("Chaos injected into connection %q: %+v", name, config)
+	return nil
+}
+
+// ClearChaos removes all faults injected into the named connection.
+func (f *ConnectionManager) ClearChaos(name string) {
+	f.chaos.mutex.Lock()
+	defer f.chaos.mutex.Unlock()
+	delete(f.chaos.faults, name)
+}
+
+// chaosPingFailed reports whether a ping failure is injected into the named connection.
+func (f *ConnectionManager) chaosPingFailed(name string) bool {
+	config, ok := f.chaos.get(name)
+	return ok && config.FailPing
+}
+
+// chaosReconnectError returns the reconnect error injected into the named connection, if any.
+func (f *ConnectionManager) chaosReconnectError(name string) error {
+	config, _ := f.chaos.get(name)
+	return config.ReconnectError
+}
+
+// chaosHook returns the statement hook applying the latency and drop faults of the named connection.
+func (f *ConnectionManager) chaosHook(name string) statementHook {
+	return func(ctx context.Context, stmt *hookedStatement) error {
+		config, ok := f.chaos.get(name)
+		if !ok {
+			return nil
+		}
+		if config.Latency > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(config.Latency):
+			}
+		}
+		if config.DropRate > 0 && rand.Float64() < config.DropRate {
+			return driver.ErrBadConn
+		}
+		return nil
+	}
+}
@@ -0,0 +1,50 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChaosInjection(t *testing.T) {
+	factory := newTestFactory()
+
+	EnableChaos(false)
+	if err := factory.InjectChaos("primary_db", ChaosConfig{FailPing: true}); !errors.Is(err, ErrChaosDisabled) {
+		t.Fatalf("Expected ErrChaosDisabled, got %v", err)
+	}
+
+	EnableChaos(true)
+	defer EnableChaos(false)
+	if err := factory.InjectChaos("primary_db", ChaosConfig{DropRate: 2}); err == nil {
+		t.Fatal("Expected an error for an invalid drop rate, got nil")
+	}
+
+	reconnectErr := errors.New("injected reconnect failure")
+	err := factory.InjectChaos("primary_db", ChaosConfig{FailPing: true, Latency: 20 * time.Millisecond, DropRate: 1, ReconnectError: reconnectErr})
+	if err != nil {
+		t.Fatalf("InjectChaos failed: %v", err)
+	}
+	if !factory.chaosPingFailed("primary_db") || factory.chaosPingFailed("other_db") {
+		t.Fatal("Expected the ping failure only on primary_db")
+	}
+	if _, err := factory.reconnect(context.Background(), "primary_db", DBConfig{}); !errors.Is(err, reconnectErr) {
+		t.Fatalf("Expected the injected reconnect error, got %v", err)
+	}
+
+	hook := factory.chaosHook("primary_db")
+	start := time.Now()
+	if err := hook(context.Background(), &hookedStatement{SQL: "SELECT 1"}); !errors.Is(err, driver.ErrBadConn) {
+		t.Fatalf("Expected driver.ErrBadConn, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("Expected the injected latency, statement took %v", elapsed)
+	}
+
+	factory.ClearChaos("primary_db")
+	if err := hook(context.Background(), &hookedStatement{SQL: "SELECT 1"}); err != nil {
+		t.Fatalf("Expected no fault after ClearChaos, got %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package connection
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time and randomness of the background work of the manager: the health checks
+// of standbys and replicas, the timestamps of their statistics and of the reconnect tracker, the random
+// choice of a replica, the waits of drains, quiesces, elections and scheduled jobs, and the pauses of
+// upserts and backfills. Replace it with a FakeClock through WithClock to drive that work
+// deterministically in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTicker returns a ticker delivering the time on its channel every d.
+	NewTicker(d time.Duration) Ticker
+
+	// Float64 returns a pseudo-random number in [0, 1), the jitter source.
+	Float64() float64
+}
+
+// Ticker delivers ticks of a Clock.
+type Ticker interface {
+	// C returns the channel the ticks are delivered on.
+	C() <-chan time.Time
+
+	// Stop turns the ticker off.
+	Stop()
+}
+
+// realClock is the Clock of the system.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+func (realClock) Float64() float64 { return rand.Float64() }
+
+type realTicker struct{ ticker *time.Ticker }
+
+func (t realTicker) C() <-chan time.Time { return t.ticker.C }
+
+func (t realTicker) Stop() { t.ticker.Stop() }
+
+// WithClock sets the clock of the manager and returns the manager, so the call chains after its
+// construction. A nil clock restores the system clock. Set the clock before adding standbys or
+// replicas: running health checks keep the ticker of the clock they started with.
+//
+// Example Usage:
+//
+//	clock := connection.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1)
+//	factory := connection.GetConnectionManager().WithClock(clock)
+//	_ = factory.AddStandby(ctx, "primary_db", "standby_db", config, connection.StandbyOptions{ValidateInterval: time.Minute})
+//	clock.Advance(time.Minute) // runs one validation of standby_db
+func (f *ConnectionManager) WithClock(clock Clock) *ConnectionManager {
+	if clock == nil {
+		f.clockSource.Store(nil)
+	} else {
+		f.clockSource.Store(&clock)
+	}
+	return f
+}
+
+// clock returns the clock of the manager.
+func (f *ConnectionManager) clock() Clock {
+	if clock := f.clockSource.Load(); clock != nil {
+		return *clock
+	}
+	return realClock{}
+}
+
+// sleep waits d on clock, or returns the error of ctx when it ends first.
+func sleep(ctx context.Context, clock Clock, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	ticker := clock.NewTicker(d)
+	defer ticker.Stop()
+	select {
+	case <-ticker.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// randIntn returns a pseudo-random int in [0, n) from the jitter source of clock.
+func randIntn(clock Clock, n int) int {
+	return min(int(clock.Float64()*float64(n)), n-1)
+}
+
+// FakeClock is a Clock whose time only moves with Advance, with a seeded jitter source.
+type FakeClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	rand    *rand.Rand
+	tickers []*fakeTicker
+}
+
+// NewFakeClock returns a FakeClock set to start, whose jitter source is seeded with seed.
+func NewFakeClock(start time.Time, seed int64) *FakeClock {
+	return &FakeClock{now: start, rand: rand.New(rand.NewSource(seed))}
+}
+
+// Now returns the time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// NewTicker returns a ticker firing when Advance reaches each multiple of d from now.
+func (c *FakeClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("connection: non-positive interval for FakeClock.NewTicker")
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &fakeTicker{clock: c, c: make(chan time.Time, 1), period: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Float64 returns the next number of the seeded jitter source.
+func (c *FakeClock) Float64() float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.rand.Float64()
+}
+
+// Advance moves the clock forward by d and fires the tickers due in the meantime. Like the tickers of
+// the time package, a ticker whose previous tick was not received yet drops the tick.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		if t.next.After(c.now) {
+			continue
+		}
+		for !t.next.After(c.now) {
+			t.next = t.next.Add(t.period)
+		}
+		select {
+		case t.c <- c.now:
+		default:
+		}
+	}
+}
+
+// Tickers returns the number of running tickers, for tests waiting for background work to start.
+func (c *FakeClock) Tickers() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.tickers)
+}
+
+type fakeTicker struct {
+	clock  *FakeClock
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Stop() {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	for i, other := range t.clock.tickers {
+		if other == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}
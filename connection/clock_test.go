@@ -0,0 +1,79 @@
+package connection
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFakeClockTicker(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start, 1)
+	ticker := clock.NewTicker(time.Minute)
+
+	clock.Advance(59 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("Expected no tick before the interval elapsed")
+	default:
+	}
+	clock.Advance(3 * time.Minute)
+	if tick := <-ticker.C(); !tick.Equal(start.Add(239 * time.Second)) {
+		t.Fatalf("Expected a tick at the advanced time, got %v", tick)
+	}
+	select {
+	case <-ticker.C():
+		t.Fatal("Expected the missed ticks to be dropped")
+	default:
+	}
+
+	ticker.Stop()
+	if clock.Tickers() != 0 {
+		t.Fatalf("Expected no running ticker after Stop, got %d", clock.Tickers())
+	}
+
+	other := NewFakeClock(start, 1)
+	for i := 0; i < 5; i++ {
+		if a, b := clock.Float64(), other.Float64(); a != b {
+			t.Fatalf("Expected equally seeded clocks to jitter alike, got %v and %v", a, b)
+		}
+	}
+}
+
+func TestWithClockDrivesStandbyValidation(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start, 1)
+	factory := newStandbyTestFactory(t).WithClock(clock)
+
+	var validations atomic.Int32
+	factory.startStandby("orders", "orders_standby", DBConfig{}, StandbyOptions{ValidateInterval: time.Hour},
+		func(ctx context.Context) error {
+			validations.Add(1)
+			return nil
+		})
+	defer func() { _ = factory.RemoveStandby("orders", "orders_standby") }()
+
+	if stats := factory.StandbyStats("orders"); len(stats) != 1 || !stats[0].LastValidated.Equal(start) {
+		t.Fatalf("Expected the first validation at the fake time, got %+v", stats)
+	}
+	for clock.Tickers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.Advance(time.Hour)
+	deadline := time.Now().Add(5 * time.Second)
+	for validations.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := validations.Load(); n != 2 {
+		t.Fatalf("Expected 2 validations after advancing one interval, got %d", n)
+	}
+	for time.Now().Before(deadline) {
+		if stats := factory.StandbyStats("orders"); stats[0].LastValidated.Equal(start.Add(time.Hour)) {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Expected the last validation at the advanced time, got %+v", factory.StandbyStats("orders"))
+}
@@ -0,0 +1,125 @@
+package connection
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"gorm.io/gorm/logger"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Roles of ClusterMetadata.Role.
+const (
+	ClusterRolePrimary = "primary"
+	ClusterRoleReplica = "replica"
+)
+
+// Attribute keys of the cluster identity on metrics and spans.
+const (
+	otelClusterName   = attribute.Key("db.cluster.name")
+	otelClusterRegion = attribute.Key("db.cluster.region")
+	otelClusterRole   = attribute.Key("db.cluster.role")
+)
+
+// ClusterMetadata identifies the database cluster behind a connection, so the telemetry of several
+// services can be grouped by cluster. Empty fields are not reported.
+type ClusterMetadata struct {
+	// Cluster is the name of the cluster, e.g. "orders-eu".
+	Cluster string
+
+	// Region is the region of the server. It defaults to the region of connections initialized with
+	// InitRegionalConnection.
+	Region string
+
+	// Role is the role of the server in the cluster, typically ClusterRolePrimary or ClusterRoleReplica.
+	Role string
+}
+
+// IsZero reports whether no field is set.
+func (m ClusterMetadata) IsZero() bool {
+	return m == ClusterMetadata{}
+}
+
+// String renders the metadata for logs, e.g. "cluster=orders-eu region=eu-west-1 role=primary".
+func (m ClusterMetadata) String() string {
+	var parts []string
+	for _, field := range []struct{ key, value string }{{"cluster", m.Cluster}, {"region", m.Region}, {"role", m.Role}} {
+		if field.value != "" {
+			parts = append(parts, field.key+"="+field.value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// attributes returns the metadata as the db.cluster.name, db.cluster.region and db.cluster.role attributes.
+func (m ClusterMetadata) attributes() []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if m.Cluster != "" {
+		attrs = append(attrs, otelClusterName.String(m.Cluster))
+	}
+	if m.Region != "" {
+		attrs = append(attrs, otelClusterRegion.String(m.Region))
+	}
+	if m.Role != "" {
+		attrs = append(attrs, otelClusterRole.String(m.Role))
+	}
+	return attrs
+}
+
+// otelAttributes returns the attributes every measurement and span of a connection carries: db.system,
+// the pool name and the cluster identity.
+func otelAttributes(name string, cluster ClusterMetadata) []attribute.KeyValue {
+	return append([]attribute.KeyValue{otelSystem, otelPoolName.String(name)}, cluster.attributes()...)
+}
+
+// ClusterMetadata returns the cluster identity of a managed connection, set with DBConfig.Cluster. It
+// is zero for an unknown connection.
+//
+// Example Usage:
+//
+//	meta := connection.GetConnectionManager().ClusterMetadata("primary_db")
+//	slog.Info("checkout failed", "db_cluster", meta.Cluster, "db_role", meta.Role)
+func (f *ConnectionManager) ClusterMetadata(name string) ClusterMetadata {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.configs[name].Cluster
+}
+
+// clusters returns the cluster identity of every managed connection.
+func (f *ConnectionManager) clusters() map[string]ClusterMetadata {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	clusters := make(map[string]ClusterMetadata, len(f.configs))
+	for name, config := range f.configs {
+		clusters[name] = config.Cluster
+	}
+	return clusters
+}
+
+// defaultSlowThreshold is the default of DBConfig.SlowThreshold, the threshold of the default GORM logger.
+const defaultSlowThreshold = 200 * time.Millisecond
+
+// gormLogger returns the GORM logger of a connection: the default one configured with the logging
+// fields of config, with the cluster identity in front of every line when it is set.
+func gormLogger(config DBConfig) logger.Interface {
+	prefix := "\r\n"
+	if !config.Cluster.IsZero() {
+		prefix += "[" + config.Cluster.String() + "] "
+	}
+	level := config.LogLevel
+	if level == 0 {
+		level = logger.Info
+	}
+	slow := config.SlowThreshold
+	if slow == 0 {
+		slow = defaultSlowThreshold
+	}
+	return logger.New(log.New(os.Stdout, prefix, log.LstdFlags), logger.Config{
+		SlowThreshold:             max(slow, 0),
+		LogLevel:                  level,
+		IgnoreRecordNotFoundError: config.IgnoreRecordNotFoundError,
+		ParameterizedQueries:      config.ParameterizedQueries,
+		Colorful:                  true,
+	})
+}
@@ -0,0 +1,94 @@
+package connection
+
+import (
+	"bytes"
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClusterMetadataTagging(t *testing.T) {
+	meta := ClusterMetadata{Cluster: "orders-eu", Region: "eu-west-1", Role: ClusterRolePrimary}
+	if meta.String() != "cluster=orders-eu region=eu-west-1 role=primary" || (ClusterMetadata{Role: ClusterRoleReplica}).String() != "role=replica" {
+		t.Fatalf("Unexpected rendering %q", meta.String())
+	}
+	if !(ClusterMetadata{}).IsZero() || meta.IsZero() {
+		t.Fatal("Unexpected IsZero")
+	}
+
+	factory := newTestFactory()
+	db := newDryRunDB(t)
+	if err := db.Use(otelPlugin{factory: factory, name: "primary_db", cluster: meta}); err != nil {
+		t.Fatalf("Failed to install metrics callbacks: %v", err)
+	}
+	factory.connections["primary_db"] = db
+	factory.configs["primary_db"] = DBConfig{Cluster: meta}
+	if factory.ClusterMetadata("primary_db") != meta || !factory.ClusterMetadata("missing_db").IsZero() {
+		t.Fatalf("Unexpected metadata %+v", factory.ClusterMetadata("primary_db"))
+	}
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	stop, err := factory.RegisterOTelMetrics(provider.Meter("test"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer stop()
+	db.Find(&[]repoTestUser{})
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("Failed to collect metrics: %v", err)
+	}
+	checked := 0
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			switch points := m.Data.(type) {
+			case metricdata.Histogram[float64]:
+				for _, point := range points.DataPoints {
+					checkClusterAttributes(t, m.Name, point.Attributes.Value)
+					checked++
+				}
+			case metricdata.Sum[int64]:
+				for _, point := range points.DataPoints {
+					checkClusterAttributes(t, m.Name, point.Attributes.Value)
+					checked++
+				}
+			}
+		}
+	}
+	if checked == 0 {
+		t.Fatal("Expected data points to check")
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	var events []LifecycleEvent
+	factory.SetLifecycleHook(func(e LifecycleEvent) { events = append(events, e) })
+	_, op := startOperation(context.Background(), OpClose)
+	op = op.tagged(meta)
+	op.logf("Database connection %q closed.", "primary_db")
+	_ = factory.endStep(op, "primary_db", "close", time.Now(), nil)
+	if !strings.Contains(buf.String(), "[op "+op.id+" cluster=orders-eu region=eu-west-1 role=primary] Database connection") {
+		t.Fatalf("Expected the log line to carry the cluster, got %q", buf.String())
+	}
+	if len(events) != 1 || events[0].Cluster != meta {
+		t.Fatalf("Expected the event to carry the cluster, got %+v", events)
+	}
+}
+
+func checkClusterAttributes(t *testing.T, metric string, value func(key attribute.Key) (attribute.Value, bool)) {
+	t.Helper()
+	for key, expected := range map[attribute.Key]string{otelClusterName: "orders-eu", otelClusterRegion: "eu-west-1", otelClusterRole: "primary"} {
+		if got, ok := value(key); !ok || got.AsString() != expected {
+			t.Errorf("Expected %s=%s on %s, got %q", key, expected, metric, got.AsString())
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package connection
+
+import (
+	"hash/crc32"
+	"testing"
+)
+
+func TestHashRowBoundaries(t *testing.T) {
+	sum := func(values ...interface{}) uint32 {
+		h := crc32.NewIEEE()
+		hashRow(h, values)
+		return h.Sum32()
+	}
+
+	if sum([]byte("ab"), []byte("c")) == sum([]byte("a"), []byte("bc")) {
+		t.Fatal("Expected different checksums when column boundaries move")
+	}
+	if sum(nil) == sum([]byte("")) {
+		t.Fatal("Expected NULL and empty string to hash differently")
+	}
+	if sum([]byte("1"), int64(2)) != sum([]byte("1"), []byte("2")) {
+		t.Fatal("Expected text and binary protocol values to hash identically")
+	}
+}
@@ -21,8 +21,10 @@ Types:
     - Lifetime: Maximum lifetime of a connection.
     - IdleTime: Maximum idle time for a connection.
 
- 2. MySqlConnection:
+ 2. ConnectionManager:
     A struct managing a map of database connections and their configurations.
+    MySqlConnection and GetMySqlConnection remain as deprecated aliases of
+    ConnectionManager and GetConnectionManager.
     Features:
     - Methods for initializing connections (`InitDataSourceConnection`).
     - Methods for reconnecting unhealthy connections.
@@ -33,7 +35,7 @@ Usage Example:
 
 		func main() {
 			// Initialize a singleton MySQL connection factory
-			dbFactory := connection.GetMySqlConnection()
+			dbFactory := connection.GetConnectionManager()
 
 			// Define the database configuration
 			config := connection.DBConfig{
@@ -77,17 +79,23 @@ Usage Example:
 package connection
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
-	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // DBConfig represents the configuration settings for a database connection.
 // It includes parameters for connection pooling and resource management.
+// Zero pool fields are replaced with the defaults of DefaultConfig when the connection is initialized.
 type DBConfig struct {
 	// DataSourceName specifies the connection string for the database.
 	// This typically includes the username, password, database name,
@@ -96,25 +104,211 @@ type DBConfig struct {
 
 	// MaxOpen defines the maximum number of open connections allowed in the connection pool.
 	// A higher value supports higher concurrency but consumes more resources.
+	// Zero selects the default (25); a negative value means unlimited.
 	MaxOpen int
 
 	// MaxIdle defines the maximum number of idle connections maintained in the connection pool.
 	// Idle connections are ready for immediate use without creating a new connection.
+	// Zero selects MaxOpen; a negative value keeps no idle connections.
 	MaxIdle int
 
 	// Lifetime specifies the maximum amount of time a connection can remain open before being closed.
 	// Use this to prevent stale connections or comply with database server limits.
+	// Zero selects the default (5 minutes); a negative value means unlimited.
 	Lifetime time.Duration
 
+	// LifetimeJitter spreads the lifetime of each physical connection uniformly over Lifetime ± this
+	// fraction of it, e.g. 0.1 for ±10%, so the connections opened together at startup do not expire
+	// together and reconnect in a stampede. A connection past its own lifetime is closed when it is
+	// returned to the pool or next taken from it. Capped at 0.9; zero or negative disables the jitter.
+	// A reconfigured value applies to the connections opened afterwards.
+	LifetimeJitter float64
+
+	// ConnectRampRate opens the pool gradually: its open connection limit starts at one connection
+	// and rises to MaxOpen by this many connections per second, so a restarting fleet does not open
+	// all its connections at once. Callers over the current limit wait for a connection as they would
+	// on a full pool. It needs MaxOpen; zero opens up to MaxOpen at once. See also SetConnectRate.
+	ConnectRampRate float64
+
 	// IdleTime specifies the maximum duration an idle connection can remain in the pool
 	// before being closed. Helps manage resource usage by closing unused connections.
 	IdleTime time.Duration
+
+	// Policy optionally restricts the statements allowed on this connection (deny DDL,
+	// deny SELECT * on large tables, fingerprint allowlist, writes only in transactions). Nil allows everything.
+	Policy *QueryPolicy
+
+	// TenantGuard installs the row-level tenancy plugin: statements on models with a
+	// `tenant:"true"` field are scoped to the tenant set with WithTenant on the context,
+	// and blocked when the context carries no tenant.
+	TenantGuard bool
+
+	// MaxExecutionTime, when positive, adds a /*+ MAX_EXECUTION_TIME(n) */ hint to every SELECT on this
+	// connection (MySQL 5.7+), so the server itself aborts long reads. Context timeouts only stop the
+	// client from waiting and leave the query running on the server.
+	MaxExecutionTime time.Duration
+
+	// StrictConfig makes InitDataSourceConnection fail with a *ConfigError when the pool settings are
+	// inconsistent (see DBConfig.Validate and ConfigWarnings) instead of logging warnings.
+	StrictConfig bool
+
+	// ConnectTimeout bounds establishing a new physical connection. Zero selects the default (5 seconds)
+	// unless the data source name sets a timeout parameter.
+	ConnectTimeout time.Duration
+
+	// ProgramName is sent as the program_name connection attribute of every session, next to
+	// mysqlconn_pool (the connection name). Defaults to the executable name.
+	ProgramName string
+
+	// ServiceName, PodName and ServiceVersion are sent as the service, pod and version connection
+	// attributes. They default to the MYSQLCONN_SERVICE and MYSQLCONN_POD environment variables (the host
+	// name for the pod) and the module version of the binary; empty values are not sent.
+	ServiceName    string
+	PodName        string
+	ServiceVersion string
+
+	// QueryBudgetLogOnly makes statements over the budget of their context (see WithQueryBudget) log a
+	// warning instead of failing with ErrQueryBudgetExceeded.
+	QueryBudgetLogOnly bool
+
+	// DetectNPlusOne enables the development-mode N+1 detector: statements run with a context from
+	// WithNPlusOneScope are fingerprinted, and a statement repeated with NPlusOneThreshold (default 5)
+	// different argument lists is logged with the call stack of its first execution.
+	DetectNPlusOne bool
+
+	// NPlusOneThreshold is the number of distinct executions reported by the N+1 detector.
+	NPlusOneThreshold int
+
+	// PrepareStmt caches prepared statements (gorm.Config.PrepareStmt). Statements invalidated by DDL on
+	// their tables (MySQL error 1615) are flushed from the cache and retried once.
+	PrepareStmt bool
+
+	// Compress enables protocol compression (compress=true), worthwhile on WAN links to the server, e.g.
+	// for analytics queries returning large result sets (zlib). A server not allowing zlib is reported
+	// as a configuration warning (an error with StrictConfig).
+	Compress bool
+
+	// MaxAllowedPacket is the largest packet sent to the server, in bytes (maxAllowedPacket). Zero keeps
+	// the value of DataSourceName. A value above the server's max_allowed_packet is reported as a
+	// configuration warning.
+	MaxAllowedPacket int
+
+	// DisableReadRetry turns off the transparent retry of SELECT statements, and of statements marked
+	// with WithIdempotent, whose connection was closed by the server mid-query (errors 2006/2013, broken
+	// pipe), e.g. after wait_timeout expired.
+	DisableReadRetry bool
+
+	// PasswordProvider, when set, supplies the password for every new physical connection, so the
+	// credential is not stored in DataSourceName (which must then omit it), in GetDbConfig or in logs,
+	// and reconnects always use fresh credentials. Each Secret is wiped right after the handshake
+	// configuration is built; the driver keeps a copy only for the life of that physical connection.
+	PasswordProvider SecretProvider
+
+	// Proxy routes the connections through an egress proxy: socks5://[user:password@]host:port or
+	// http://[user:password@]host:port (HTTP CONNECT).
+	Proxy string
+
+	// Dialer opens the network connections to the server, or to Proxy when it is set, instead of a
+	// plain net.Dialer.
+	Dialer ContextDialer
+
+	// ReconfigureOnMismatch makes InitDataSourceConnection apply this configuration to an existing
+	// connection of the same name instead of failing with ErrConfigMismatch: pool settings are applied to
+	// the live pool, other changes (e.g. a new DataSourceName) reconnect.
+	ReconfigureOnMismatch bool
+
+	// QueryFields makes queries select every field of the model by name instead of SELECT *
+	// (gorm.Session.QueryFields) on the handles returned by GetDB and GetDBContext.
+	QueryFields bool
+
+	// FullSaveAssociations makes Save and Create update the associations of a record instead of
+	// only inserting missing ones (gorm.Session.FullSaveAssociations).
+	FullSaveAssociations bool
+
+	// DryRun makes the handles build statements without executing them (gorm.Session.DryRun), e.g.
+	// to check the generated SQL in CI. Health checks and reconnects still use the server.
+	DryRun bool
+
+	// RawSQLGuard installs the SQL injection guard: Raw and Exec statements whose text looks like values
+	// were formatted into it (see CheckRawSQL) are logged with their caller and rejected with
+	// ErrSuspectedInjection. Statements GORM builds from models and conditions are not inspected.
+	RawSQLGuard bool
+
+	// RawSQLGuardLogOnly makes the SQL injection guard only log suspicious statements.
+	RawSQLGuardLogOnly bool
+
+	// AllowLoadData enables LoadData (LOAD DATA LOCAL INFILE from a reader) on this connection. It is off
+	// by default because the server then accepts client data for any table the user can insert into.
+	AllowLoadData bool
+
+	// PoolShards splits the connection into that many pools of the same data source, used round-robin,
+	// to reduce lock contention inside database/sql for very hot workloads. MaxOpen and MaxIdle are
+	// divided between the shards. GetDB still returns one handle; its DB() is the first shard.
+	// Zero or one keeps a single pool.
+	PoolShards int
+
+	// TimeZoneCheck checks that times are read and written in UTC: parseTime=true and loc=UTC in the data
+	// source name and a UTC session time_zone. TimeZoneCheckWarn logs warnings, TimeZoneCheckEnforce fails.
+	TimeZoneCheck TimeZoneCheck
+
+	// PrivilegePolicy declares the privileges the user may hold, see AuditPrivileges. Nil audits with
+	// DefaultPrivilegePolicy.
+	PrivilegePolicy *PrivilegePolicy
+
+	// InstrumentDriver records statements at the driver level instead of with GORM callbacks, so that
+	// statements run without GORM (GetSQLDB, ExecScript, CallProc, ...) are also measured by
+	// RegisterOTelMetrics and traced by RegisterOTelTracing.
+	InstrumentDriver bool
+
+	// TrackRowsAffected accounts the rows affected by the write statements of the connection, by table
+	// and by statement, see RowsAffectedStats.
+	TrackRowsAffected bool
+
+	// RowsAffectedAlert logs the write statements affecting more rows than this, e.g. an UPDATE missing
+	// its WHERE clause. Zero logs none. It needs TrackRowsAffected.
+	RowsAffectedAlert int64
+
+	// PriorityAcquisition queues the callers waiting for a connection of a saturated pool by the class
+	// of their context (see WithQueryClass) instead of in arrival order: critical first, then interactive,
+	// background and best-effort. It needs MaxOpen and is not supported with PoolShards or PrepareStmt.
+	// Statements run without GORM (GetSQLDB) bypass the queue.
+	PriorityAcquisition bool
+
+	// Cluster identifies the database cluster of the connection. It is added to the log lines of the
+	// connection (lifecycle operations and GORM statement logs), to its LifecycleEvents and to the
+	// attributes of its metrics and spans.
+	Cluster ClusterMetadata
+
+	// LogLevel is the level of the GORM statement log of the connection: logger.Info (the default) logs
+	// every statement, logger.Warn only slow and failed statements, logger.Error only failed statements
+	// and logger.Silent nothing.
+	LogLevel logger.LogLevel
+
+	// SlowThreshold is the duration from which statements are logged as slow, at logger.Warn. Zero
+	// selects the default (200 milliseconds); a negative value logs no statement as slow.
+	SlowThreshold time.Duration
+
+	// IgnoreRecordNotFoundError leaves gorm.ErrRecordNotFound (First, Take and Last finding no row) out
+	// of the error log.
+	IgnoreRecordNotFoundError bool
+
+	// ParameterizedQueries logs statements with their placeholders instead of their arguments, keeping
+	// the values, e.g. personal data, out of the log.
+	ParameterizedQueries bool
+
+	// LabelErrors wraps the errors of the statements run through GORM in a *ConnError with Op OpQuery,
+	// naming the connection, the statement type and the table, e.g. `query "billing" (UPDATE invoices):
+	// Error 1205: Lock wait timeout exceeded`, so an error logged far from its query tells which database
+	// it came from. errors.Is and errors.As still match the underlying error, e.g. gorm.ErrRecordNotFound;
+	// comparisons with == no longer do.
+	LabelErrors bool
 }
 
-// MySqlConnection is a thread-safe singleton structure for managing multiple
+// ConnectionManager is a thread-safe singleton structure for managing multiple
 // database connections. It provides functionality to initialize, retrieve,
-// and close database connections dynamically.
-type MySqlConnection struct {
+// and close database connections dynamically. Its name and method set do not
+// depend on the database driver, so the same API can serve other dialects.
+type ConnectionManager struct {
 	// connections stores active database connections, keyed by a unique connection name.
 	// Each connection is a pointer to a gorm.DB object, representing the GORM abstraction
 	// of a database connection.
@@ -125,18 +319,110 @@ type MySqlConnection struct {
 	// that require access to the original configuration.
 	configs map[string]DBConfig
 
+	// sessions tracks the server-side connection ids of each connection's pool.
+	sessions map[string]*sessionTracker
+
+	// warnings holds the configuration warnings found when each connection was initialized.
+	warnings map[string][]ConfigWarning
+
+	// hooks holds the statement hook chain of each connection, for hooks added at runtime.
+	hooks map[string]*hookChain
+
+	// regions lists the regions of each logical database with regional connections, in registration order.
+	regions map[string][]string
+
+	// localRegion is the region of the process set with SetLocalRegion.
+	localRegion string
+
+	// shardGroups lists the connections of each shard group, see SetShardGroup and FanOut.
+	shardGroups map[string][]string
+
+	// tenants maps each tenant to its shard, see AssignTenant and MoveTenant.
+	tenants map[string]*tenantRoute
+
+	// replicas holds the read replicas of each primary connection, see SetReplicas.
+	replicas map[string]*replicaSet
+
+	// lagMonitors holds the running lag monitor of each primary connection, see StartLagMonitor.
+	lagMonitors map[string]*LagMonitor
+
+	// standbys holds the warm standbys of each primary connection, see AddStandby and Failover.
+	standbys map[string][]*standby
+
+	// refs counts the modules retaining each connection, see Retain and Release.
+	refs map[string]int
+
+	// rowsAffected accounts the rows written by each connection with DBConfig.TrackRowsAffected.
+	rowsAffected map[string]*rowsAffectedTracker
+
+	// canaries holds the canary checks of each connection run by RotateDSN, see AddCanaryCheck.
+	canaries map[string][]CanaryCheck
+
+	// plugins tracks the GORM plugins of each connection across reconnects, see UsePlugin and RemovePlugin.
+	plugins map[string]*pluginRegistry
+
+	// info describes each connection as established, see Connect.
+	info map[string]*Connection
+
+	// lifecycle is the hook of SetLifecycleHook, or nil.
+	lifecycle atomic.Pointer[func(LifecycleEvent)]
+
+	// tracer holds the tracer of RegisterOTelTracing, or nil when statements are not traced.
+	tracer atomic.Pointer[otelTracer]
+
+	// quiesce is the quiesce in progress, or nil when queries are admitted. See Quiesce and Resume.
+	quiesce atomic.Pointer[quiesceState]
+
+	// quiesceGrace is the grace period of SetQuiesceGrace, or nil for the default.
+	quiesceGrace atomic.Pointer[time.Duration]
+
+	// events fans lifecycle events out to the streams of EventStreamHandler.
+	events eventBus
+
+	// reconnects aggregates the reconnect attempts of GetDB. See ReconnectStats.
+	reconnects reconnectTracker
+
+	// history keeps the pool statistics samples of StartStatsHistory for Forecast.
+	history statsHistory
+
+	// brownout holds the levels of SetBrownout and SetBrownoutThresholds.
+	brownout brownoutState
+
+	// scalars caches the results of CachedScalar.
+	scalars scalarCache
+
+	// chaos holds the faults injected for resilience testing, keyed by connection name.
+	chaos chaosRegistry
+
+	// otel holds the instruments of RegisterOTelMetrics, or nil when metrics are not exported.
+	otel atomic.Pointer[otelInstruments]
+
+	// connectLimit limits the rate of physical connects of all pools, see SetConnectRate.
+	connectLimit connectLimiter
+
+	// clockSource is the clock of WithClock, or nil for the system clock.
+	clockSource atomic.Pointer[Clock]
+
+	// connecting holds a slot per connection name, taken while the name is connected. See connectLock.
+	connecting sync.Map
+
 	// mutex ensures thread-safe access to the connections and configs maps,
 	// preventing race conditions when multiple goroutines access or modify these resources.
 	mutex sync.Mutex
 }
 
-var instance *MySqlConnection
+var instance *ConnectionManager
 var once sync.Once
 
-// GetMySqlConnection Singleton connection
-func GetMySqlConnection() *MySqlConnection {
+// MySqlConnection is the previous name of ConnectionManager.
+//
+// Deprecated: Use ConnectionManager.
+type MySqlConnection = ConnectionManager
+
+// GetConnectionManager returns the singleton connection manager.
+func GetConnectionManager() *ConnectionManager {
 	once.Do(func() {
-		instance = &MySqlConnection{
+		instance = &ConnectionManager{
 			connections: make(map[string]*gorm.DB),
 			configs:     make(map[string]DBConfig),
 		}
@@ -144,43 +430,298 @@ func GetMySqlConnection() *MySqlConnection {
 	return instance
 }
 
+// GetMySqlConnection Singleton connection
+//
+// Deprecated: Use GetConnectionManager, which returns the same instance.
+func GetMySqlConnection() *MySqlConnection {
+	return GetConnectionManager()
+}
+
 // InitDataSourceConnection initializes a database connection
-func (f *MySqlConnection) InitDataSourceConnection(name string, config DBConfig) error {
+func (f *ConnectionManager) InitDataSourceConnection(name string, config DBConfig) error {
+	_, err := f.Connect(name, config)
+	return err
+}
+
+// Connect initializes a database connection like InitDataSourceConnection and returns its description.
+//
+// Parameters:
+// - name: The name under which the connection is registered.
+// - config: The configuration of the connection.
+//
+// Returns:
+// - *Connection: The name, server version, TLS state, address and pool of the connection. When the
+// connection already exists and is kept, its current description.
+// - error: An error if the configuration is invalid or the connection cannot be established.
+//
+// Example Usage:
+//
+//	conn, err := connection.GetConnectionManager().Connect("primary_db", config)
+//	if err != nil {
+//		log.Fatalf("Failed to connect: %v", err)
+//	}
+//	log.Println(conn) // primary_db: connected to db-1:3306 (MySQL 8.0.36) over TLS
+func (f *ConnectionManager) Connect(name string, config DBConfig) (*Connection, error) {
+	return f.ConnectContext(context.Background(), name, config)
+}
+
+// ConnectContext is Connect with a context: dialing, the handshake and the queries made while
+// connecting are bounded by ctx.
+func (f *ConnectionManager) ConnectContext(ctx context.Context, name string, config DBConfig) (*Connection, error) {
+	return f.connect(ctx, name, config, false)
+}
+
+// connect establishes the named connection. An existing connection is kept when its configuration is
+// compatible, unless replace is set; a replaced pool is closed only once the new one is established.
+func (f *ConnectionManager) connect(ctx context.Context, name string, config DBConfig, replace bool) (*Connection, error) {
+	return f.connectVerified(ctx, name, config, replace, nil)
+}
+
+// connectLock waits within ctx until no other connect of name runs, and returns the function ending
+// its own.
+func (f *ConnectionManager) connectLock(ctx context.Context, name string) (func(), error) {
+	value, _ := f.connecting.LoadOrStore(name, make(chan struct{}, 1))
+	slot := value.(chan struct{})
+	select {
+	case slot <- struct{}{}:
+		return func() { <-slot }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for another connect of %q: %w", name, ctx.Err())
+	}
+}
+
+// poolVerifier checks a new pool against the pool it replaces before the swap, see RotateDSN.
+type poolVerifier func(ctx context.Context, pool, replaced *sql.DB) error
+
+// connectVerified is connect with verify run on the new pool before it replaces an existing one.
+func (f *ConnectionManager) connectVerified(ctx context.Context, name string, config DBConfig, replace bool, verify poolVerifier) (*Connection, error) {
+	ctx, op := startOperation(ctx, OpInit)
+	op = op.tagged(config.Cluster)
+	start := time.Now()
+	info, err := f.establish(ctx, op, name, config, replace, verify)
+	return info, connError(name, op.op, f.endStep(op, name, "connect", start, err))
+}
+
+// establish is connect within the lifecycle operation op. The connects of one name run one at a time;
+// the new pool is opened, checked and verified without holding the mutex, so a slow server does not
+// block the other connections, and is swapped in only if the connection did not change meanwhile.
+func (f *ConnectionManager) establish(ctx context.Context, op operation, name string, config DBConfig, replace bool, verify poolVerifier) (*Connection, error) {
+	unlock, err := f.connectLock(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	config = config.withDefaults()
 	f.mutex.Lock()
-	defer f.mutex.Unlock()
+	replaced, exists := f.connections[name]
+	if exists && !replace {
+		replace, err := f.reconfigure(name, config)
+		info := f.info[name]
+		f.mutex.Unlock()
+		if err != nil {
+			op.logf("Database connection %q already exists.", name)
+			return nil, err
+		}
+		if !replace {
+			op.logf("Database connection %q already exists.", name)
+			return info, nil
+		}
+		op.logf("Database connection %q exists with a different data source, reconnecting.", name)
+	} else {
+		f.mutex.Unlock()
+	}
+	if isFake(config) {
+		return nil, ErrFakeReconnect
+	}
 
-	if _, exists := f.connections[name]; exists {
-		log.Printf("Database connection '%s' already exists.", name)
-		return nil
+	warnings := config.Validate()
+	if (config.StrictConfig && len(warnings) > 0) || (config.TimeZoneCheck == TimeZoneCheckEnforce && hasTimeZoneWarning(warnings)) {
+		return nil, &ConfigError{Connection: name, Warnings: warnings}
+	}
+
+	// Driver connector tagging and tracking every session of the pool
+	dsnConfig, err := config.driverConfig(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data source name: %w", err)
+	}
+	if dsnConfig.Timeout == 0 {
+		dsnConfig.Timeout = config.ConnectTimeout
+	}
+	dsnConfig.ConnectionAttributes = connectionAttributes(dsnConfig.ConnectionAttributes, name, config)
+	connector, err := mysqldriver.NewConnector(dsnConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database connection: %w", err)
+	}
+	sessions := &sessionTracker{}
+	control := newPoolControl(config)
+	var poolConnector driver.Connector = &trackedConnector{connector: connector, sessions: sessions,
+		control: control, clock: f.clock(), limiter: &f.connectLimit}
+	if config.InstrumentDriver {
+		poolConnector = &instrumentedConnector{connector: poolConnector, factory: f, name: name, cluster: config.Cluster}
+	}
+	pool := sql.OpenDB(poolConnector)
+	shards := []*sql.DB{pool}
+	for len(shards) < config.PoolShards {
+		shards = append(shards, sql.OpenDB(poolConnector))
+	}
+	closeShards := func() {
+		for _, shard := range shards {
+			_ = shard.Close()
+		}
+	}
+	var connPool gorm.ConnPool = pool
+	if len(shards) > 1 {
+		connPool = &shardedPool{shards: shards}
+	}
+
+	// The first session is established within ctx; GORM would otherwise ping and read the version without it.
+	if err := pool.PingContext(ctx); err != nil {
+		closeShards()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+	var version string
+	if err := pool.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		closeShards()
+		return nil, fmt.Errorf("failed to read server version: %w", err)
 	}
 
 	// GORM connection
-	db, err := gorm.Open(mysql.Open(config.DataSourceName), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: connPool, DSNConfig: dsnConfig, ServerVersion: version}), &gorm.Config{
+		Logger:               gormLogger(config),
+		PrepareStmt:          config.PrepareStmt,
+		DisableAutomaticPing: true,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to initialize database connection %q: %w", name, err)
+		closeShards()
+		return nil, fmt.Errorf("failed to initialize database connection: %w", err)
 	}
 
 	// connection pool setup
-	sqlDB, err := db.DB()
+	control.shards = shards
+	control.apply(config)
+	if config.ConnectRampRate > 0 && config.MaxOpen > 0 {
+		control.ramp(f.clock())
+	}
+
+	serverWarnings, err := config.validateAgainstServer(db.WithContext(ctx))
 	if err != nil {
-		return fmt.Errorf("failed to retrieve database handle for '%q': %w", name, err)
+		op.logf("Could not validate configuration of %q against the server: %v", name, err)
+	}
+	warnings = append(warnings, serverWarnings...)
+	if (config.StrictConfig && len(warnings) > 0) || (config.TimeZoneCheck == TimeZoneCheckEnforce && hasTimeZoneWarning(warnings)) {
+		closeShards()
+		return nil, &ConfigError{Connection: name, Warnings: warnings}
+	}
+	for _, warning := range warnings {
+		op.logf("Configuration warning for %q: %v", name, warning)
+	}
+	if verify != nil && replaced != nil {
+		replacedDB, err := replaced.DB()
+		if err == nil {
+			err = verify(ctx, pool, replacedDB)
+		}
+		if err != nil {
+			closeShards()
+			return nil, err
+		}
+	}
+	info, err := describeConnection(name, db.WithContext(ctx), dsnConfig.Addr)
+	if err != nil {
+		op.logf("Could not describe connection %q: %v", name, err)
+	}
+
+	// Route every statement through the connection's hook chain
+	hooks, err := installStatementHooks(name, db, !config.DisableReadRetry)
+	if err != nil {
+		closeShards()
+		return nil, fmt.Errorf("failed to install statement hooks: %w", err)
+	}
+	db.ConnPool.(*hookedConnPool).control = control
+	db.ConnPool.(*hookedConnPool).downtime.Store(f.downtimeProbe(name))
+	if config.Policy != nil {
+		hooks.set("policy", config.Policy.hook())
+		if hooked, ok := db.ConnPool.(*hookedConnPool); ok {
+			hooked.isolation = config.Policy.TransactionIsolation
+		}
+	}
+	hooks.set("quiesce", f.quiesceHook())
+	hooks.set("tenant_write", f.tenantWriteHook())
+	hooks.set("query_budget", queryBudgetHook(config.QueryBudgetLogOnly))
+	hooks.set("brownout", f.brownoutHook(shards))
+	installPriorityAcquisition(db, pool, config)
+	if config.MaxExecutionTime > 0 {
+		hooks.set("max_execution_time", maxExecutionTimeHook(config.MaxExecutionTime))
+	}
+	if config.DetectNPlusOne {
+		hooks.set("n_plus_one", nPlusOneHook(config.NPlusOneThreshold))
+	}
+	if chaosEnabled.Load() {
+		hooks.set("chaos", f.chaosHook(name))
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.connections[name] != replaced {
+		closeShards()
+		return nil, errors.New("the connection was closed or replaced while connecting")
 	}
-	sqlDB.SetMaxOpenConns(config.MaxOpen)
-	sqlDB.SetMaxIdleConns(config.MaxIdle)
-	sqlDB.SetConnMaxLifetime(config.Lifetime)
-	sqlDB.SetConnMaxIdleTime(config.IdleTime)
 
-	if err := sqlDB.Ping(); err != nil {
-		return fmt.Errorf("failed to ping database '%q': %w", name, err)
+	// GORM plugins: metrics, the tenancy and SQL injection guards and the plugins added with UsePlugin
+	builtins := []gorm.Plugin{txStatsPlugin{}, requestStatsPlugin{name: name}, statementCapturePlugin{}}
+	if !config.InstrumentDriver {
+		builtins = append(builtins, otelPlugin{factory: f, name: name, cluster: config.Cluster})
+	}
+	if config.TenantGuard {
+		builtins = append(builtins, tenancyPlugin{})
+	}
+	if config.RawSQLGuard {
+		builtins = append(builtins, rawGuardPlugin{logOnly: config.RawSQLGuardLogOnly})
+	}
+	if config.LabelErrors {
+		builtins = append(builtins, errorLabelPlugin{name: name})
+	}
+	if config.TrackRowsAffected {
+		builtins = append(builtins, rowsAffectedPlugin{name: name, tracker: f.rowsAffectedTracker(name), alert: config.RowsAffectedAlert})
+	}
+	plugins, err := f.installPlugins(name, db, builtins...)
+	if err != nil {
+		closeShards()
+		return nil, fmt.Errorf("failed to install plugins: %w", err)
 	}
 
 	// Store the connection and configuration
 	f.connections[name] = db
 	f.configs[name] = config
-	fmt.Printf("Database connection '%q' initialized successfully.\n", name)
-	return nil
+	if f.warnings == nil {
+		f.warnings = make(map[string][]ConfigWarning)
+	}
+	f.warnings[name] = warnings
+	if f.sessions == nil {
+		f.sessions = make(map[string]*sessionTracker)
+	}
+	f.sessions[name] = sessions
+	if f.hooks == nil {
+		f.hooks = make(map[string]*hookChain)
+	}
+	f.hooks[name] = hooks
+	if f.plugins == nil {
+		f.plugins = make(map[string]*pluginRegistry)
+	}
+	f.plugins[name] = plugins
+	if f.info == nil {
+		f.info = make(map[string]*Connection)
+	}
+	f.info[name] = info
+	if replaced != nil {
+		// Statements already running on the replaced pool finish; it closes when they return.
+		if replacedDB, err := replaced.DB(); err == nil {
+			_ = replacedDB.Close()
+		}
+		closeExtraShards(replaced)
+	}
+	fmt.Printf("[op %s] Database connection %q initialized successfully.\n", op.id, name)
+	return info, nil
 }
 
 // GetDB retrieves an existing database connection by its name.
@@ -209,7 +750,7 @@ func (f *MySqlConnection) InitDataSourceConnection(name string, config DBConfig)
 // - Health checks enhance the reliability of the database connection pool.
 //
 // Example Usage:
-// db, err := connection.GetMySqlConnection().GetDB("example_db")
+// db, err := connection.GetConnectionManager().GetDB("example_db")
 //
 //	if err != nil {
 //	    log.Fatalf("Failed to retrieve database connection: %v", err)
@@ -218,45 +759,144 @@ func (f *MySqlConnection) InitDataSourceConnection(name string, config DBConfig)
 //	if db != nil {
 //	    log.Println("Database connection retrieved successfully.")
 //	}
-func (f *MySqlConnection) GetDB(name string) (*gorm.DB, error) {
+func (f *ConnectionManager) GetDB(name string) (*gorm.DB, error) {
+	return f.getDB(context.Background(), name)
+}
+
+// GetDBContext is GetDB with a context: the health check ping and a reconnect, including the wait for
+// a reconnect already in progress, are bounded by ctx, and the returned connection is bound to ctx, so
+// every statement run on it is cancelled with ctx.
+//
+// Example Usage:
+//
+//	db, err := connection.GetConnectionManager().GetDBContext(r.Context(), "example_db")
+//	if err != nil {
+//	    return err
+//	}
+//	return db.Find(&users).Error
+func (f *ConnectionManager) GetDBContext(ctx context.Context, name string) (*gorm.DB, error) {
+	db, err := f.getDB(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return db.WithContext(ctx), nil
+}
+
+// GetSQLDB returns the database/sql pool of the named connection after the health check of GetDB,
+// for code that does not use GORM. With DBConfig.PoolShards it is the first shard. Statements run on it
+// bypass the statement hooks; they are measured and traced with DBConfig.InstrumentDriver.
+//
+// Notes:
+//   - The pool is the one under GetDB and GetDBContext, not a copy: each name has one *sql.DB, so
+//     MaxOpen bounds the server connections of GORM and raw SQL together, and pool statistics cover both.
+//   - Other access layers should wrap this pool rather than open their own, e.g. sqlx.NewDb(sqlDB, "mysql")
+//     instead of sqlx.Open, which would open a second pool with its own limits.
+//   - Do not close the pool; use CloseConnection. Do not keep it across requests either: a reconnect
+//     replaces it, so fetch it again for each unit of work like GetDB.
+//
+// Example Usage:
+//
+//	sqlDB, err := connection.GetConnectionManager().GetSQLDB("primary_db")
+//	if err != nil {
+//		return err
+//	}
+//	row := sqlDB.QueryRowContext(ctx, "SELECT name FROM users WHERE id = ?", id)
+//
+//	// sqlx on the same pool
+//	users := sqlx.NewDb(sqlDB, "mysql")
+func (f *ConnectionManager) GetSQLDB(name string) (*sql.DB, error) {
+	db, err := f.getDB(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, connError(name, OpGet, fmt.Errorf("failed to retrieve database handle: %w", err))
+	}
+	return sqlDB, nil
+}
+
+// getDB returns the named connection after a health check bounded by ctx.
+func (f *ConnectionManager) getDB(ctx context.Context, name string) (*gorm.DB, error) {
+	if err := f.admit(name, OpGet); err != nil {
+		return nil, err
+	}
 	f.mutex.Lock()
 	db, exists := f.connections[name]
 	config, configExists := f.configs[name]
+	regions, regional := f.regions[name]
 	f.mutex.Unlock()
 
+	if !exists && regional {
+		return f.regionalDB(ctx, name, regions)
+	}
 	if !exists {
-		return nil, fmt.Errorf("database connection '%q' does not exist", name)
+		return nil, errNotFound(name, OpGet)
 	}
 
 	// Health check
 	sqlDB, err := db.DB()
-	if err != nil || sqlDB.Ping() != nil {
-		log.Printf("Database connection '%s' is not healthy. Attempting to reconnect...", name)
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	// A cancelled caller says nothing about the health of the connection.
+	if ctx.Err() != nil {
+		return nil, connError(name, OpGet, fmt.Errorf("health check interrupted: %w", ctx.Err()))
+	}
+	if err != nil || f.chaosPingFailed(name) {
+		ctx, op := startOperation(ctx, OpReconnect)
+		op = op.tagged(config.Cluster)
+		// During an outage every call attempts a reconnect: only the first attempt and every Nth one are logged.
+		if logged, suppressed := f.reconnects.begin(name, f.clock().Now()); logged && suppressed > 0 {
+			op.logf("Database connection %q is not healthy. Attempting to reconnect (%d similar messages suppressed)...", name, suppressed)
+		} else if logged {
+			op.logf("Database connection %q is not healthy. Attempting to reconnect...", name)
+		}
 
 		if !configExists {
-			return nil, fmt.Errorf("no configuration found to reconnect database '%q'", name)
+			err := connError(name, OpReconnect, f.endStep(op, name, "reconnect", time.Now(), errors.New("no configuration found to reconnect")))
+			f.reconnects.end(name, err, f.clock().Now())
+			return nil, err
 		}
 
 		// Attempt to reconnect
-		return f.reconnect(name, config)
+		started := f.clock().Now()
+		db, err = f.reconnect(ctx, name, config)
+		ended := f.clock().Now()
+		f.reconnects.timed(name, ended.Sub(started))
+		f.recordReconnect(ctx, name, ended.Sub(started), err)
+		if failed, outage := f.reconnects.end(name, err, ended); failed > 0 {
+			op.logf("Database connection %q reconnected after %d failed attempts over %v.", name, failed, outage.Round(time.Millisecond))
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		f.reconnects.recovered(name, f.clock().Now())
 	}
 
-	return db, nil
+	return config.session(db), nil
 }
 
-func (f *MySqlConnection) reconnect(name string, config DBConfig) (*gorm.DB, error) {
-
-	// Close the unhealthy connection which needs to be reconnected
-	err := f.CloseConnection(name)
-	if err != nil {
-		return nil, fmt.Errorf("failed to remove connection '%q': %w", name, err)
+// reconnect replaces the pool of the named connection by a new one established within ctx. The
+// unhealthy pool stays registered until the new one is established, so a caller running out of time
+// leaves the connection to be reconnected by the next caller.
+func (f *ConnectionManager) reconnect(ctx context.Context, name string, config DBConfig) (*gorm.DB, error) {
+	ctx, op := startOperation(ctx, OpReconnect)
+	op = op.tagged(config.Cluster)
+	start := time.Now()
+	if err := f.chaosReconnectError(name); err != nil {
+		return nil, connError(name, OpReconnect, f.endStep(op, name, "reconnect", start, fmt.Errorf("failed to reconnect: %w", err)))
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, connError(name, OpReconnect, f.endStep(op, name, "reconnect", start, fmt.Errorf("reconnect interrupted: %w", err)))
 	}
 
-	// Reinitialize the connection
-	err = f.InitDataSourceConnection(name, config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to reconnect to database '%q': %w", name, err)
+	// The error of connect already carries the connection, the reconnect operation and its ID.
+	if _, err := f.connect(ctx, name, config, true); err != nil {
+		return nil, f.endStep(op, name, "reconnect", start, err)
 	}
+	_ = f.endStep(op, name, "reconnect", start, nil)
 
 	// Return the reinitialized connection
 	f.mutex.Lock()
@@ -264,57 +904,114 @@ func (f *MySqlConnection) reconnect(name string, config DBConfig) (*gorm.DB, err
 	return f.connections[name], nil
 }
 
-// CloseAllConnections closes all database connections and remove configs
-func (f *MySqlConnection) CloseAllConnections() {
+// CloseAllConnections closes all database connections and remove configs.
+//
+// Returns:
+// - []error: One error per connection that could not be closed cleanly, or nil when all were closed.
+//
+// Behavior:
+// 1. Each pool is closed once; the error of the close is reported, not retried.
+// 2. Each pool is then verified to have no open connection left, waiting up to a second for
+// connections still in use to be returned.
+// 3. All connections and configurations are removed first, whether or not they close cleanly, so
+// the manager does not hand them out while they close.
+func (f *ConnectionManager) CloseAllConnections() []error {
 	f.mutex.Lock()
-	defer f.mutex.Unlock()
-
-	for name, db := range f.connections {
-		sqlDB, err := db.DB()
-		if err != nil {
-			fmt.Printf("Error retrieving database handle for %q: %v\n", name, err)
-			continue
+	connections := f.connections
+	f.connections = make(map[string]*gorm.DB)
+	f.configs = make(map[string]DBConfig)
+	f.sessions = make(map[string]*sessionTracker)
+	f.warnings = make(map[string][]ConfigWarning)
+	f.hooks = make(map[string]*hookChain)
+	f.regions = make(map[string][]string)
+	for _, set := range f.replicas {
+		set.cancel()
+	}
+	f.replicas = make(map[string]*replicaSet)
+	monitors := f.lagMonitors
+	f.lagMonitors = make(map[string]*LagMonitor)
+	standbys := f.standbys
+	f.standbys = make(map[string][]*standby)
+	f.refs = make(map[string]int)
+	f.canaries = make(map[string][]CanaryCheck)
+	f.rowsAffected = make(map[string]*rowsAffectedTracker)
+	f.plugins = make(map[string]*pluginRegistry)
+	f.info = make(map[string]*Connection)
+	f.mutex.Unlock()
+	for name := range connections {
+		f.scalars.invalidate(name)
+	}
+	for _, list := range standbys {
+		for _, s := range list {
+			s.stop()
 		}
+	}
+	for _, m := range monitors {
+		m.Stop()
+	}
 
-		if err := sqlDB.Close(); err != nil {
-			fmt.Printf("Error closing database connection %q: %v\n", name, err)
-		} else {
-			fmt.Printf("Database connection %q closed successfully and config remved.\n", name)
+	var errs []error
+	for name, db := range connections {
+		if err := closePool(db); err != nil {
+			errs = append(errs, &ConnError{Name: name, Op: OpClose, Err: err})
+			continue
 		}
+		fmt.Printf("Database connection %q closed successfully and config removed.\n", name)
 	}
+	return errs
+}
 
-	f.connections = make(map[string]*gorm.DB)
-	f.configs = make(map[string]DBConfig)
+// CloseConnection closes a specific database connection and removes its config.
+// Statements running on the connection fail; use DrainConnection to let them finish first.
+// The connection is closed even if modules still retain it (see Retain); shared connections
+// should be given up with Release instead. The lag monitor of the connection (StartLagMonitor) is stopped.
+// The validation of a standby closed by name stops, and so does the validation of the standbys of a closed
+// primary (AddStandby); their connections stay open until they are closed by name.
+func (f *ConnectionManager) CloseConnection(name string) error {
+	_, op := startOperation(context.Background(), OpClose)
+	op = op.tagged(f.ClusterMetadata(name))
+	start := time.Now()
+	err := f.closeWithin(op, name)
+	return connError(name, OpClose, f.endStep(op, name, "close", start, err))
 }
 
-// CloseConnection closes a specific database connection and removes its config
-func (f *MySqlConnection) CloseConnection(name string) error {
+// closeWithin is CloseConnection within the lifecycle operation op.
+func (f *ConnectionManager) closeWithin(op operation, name string) error {
 	f.mutex.Lock()
-	defer f.mutex.Unlock()
+	stop, err := f.closeLocked(op, name)
+	f.mutex.Unlock()
+	if err == nil {
+		stop()
+	}
+	return err
+}
 
+// closeLocked is closeWithin with the mutex held. The caller calls stop once it has released the
+// mutex, see retire.
+func (f *ConnectionManager) closeLocked(op operation, name string) (stop func(), err error) {
 	// Check if the connection exists
 	db, exists := f.connections[name]
 	if !exists {
-		return fmt.Errorf("database connection '%q' does not exist", name)
+		return nil, errNotFound(name, OpClose)
 	}
 
 	// Retrieve the SQL DB handle
 	sqlDB, err := db.DB()
 	if err != nil {
-		return fmt.Errorf("error retrieving database handle for '%q': %v", name, err)
+		return nil, fmt.Errorf("error retrieving database handle: %w", err)
 	}
 
 	// Close the connection
 	if err := sqlDB.Close(); err != nil {
-		return fmt.Errorf("error closing database connection '%q': %v", name, err)
+		return nil, fmt.Errorf("error closing database connection: %w", err)
 	}
+	closeExtraShards(db)
 
 	// Remove connection and config
-	delete(f.connections, name)
-	delete(f.configs, name)
+	stop = f.retire(name)
 
-	fmt.Printf("Database connection '%q' closed successfully and config removed.\n", name)
-	return nil
+	fmt.Printf("[op %s] Database connection %q closed successfully and config removed.\n", op.id, name)
+	return stop, nil
 }
 
 // PrintAllExistingDb prints the names of all currently active database connections.
@@ -338,11 +1035,11 @@ func (f *MySqlConnection) CloseConnection(name string) error {
 // Database connections: [db1 db2]
 //
 // Example Usage:
-// connection.GetMySqlConnection().PrintAllExistingDb()
+// connection.GetConnectionManager().PrintAllExistingDb()
 //
 // Limitations:
 // - The method only checks the presence of connections in the `connections` map. It does not verify the health of each connection.
-func (f *MySqlConnection) PrintAllExistingDb() {
+func (f *ConnectionManager) PrintAllExistingDb() {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
 
@@ -378,7 +1075,7 @@ func (f *MySqlConnection) PrintAllExistingDb() {
 // 4. If the configuration exists, it is returned to the caller.
 //
 // Example Usage:
-// dbConfig := connection.GetMySqlConnection().GetDbConfig("my_database")
+// dbConfig := connection.GetConnectionManager().GetDbConfig("my_database")
 //
 //	if dbConfig == (DBConfig{}) {
 //	    fmt.Println("Configuration for the database does not exist.")
@@ -393,14 +1090,50 @@ func (f *MySqlConnection) PrintAllExistingDb() {
 //
 // Limitations:
 // - Returns an empty `DBConfig` when the connection does not exist, which may require additional checks by the caller.
-func (f *MySqlConnection) GetDbConfig(conName string) DBConfig {
+func (f *ConnectionManager) GetDbConfig(conName string) DBConfig {
 	f.mutex.Lock()
 	defer f.mutex.Unlock()
 
 	config := f.configs[conName]
 	if config == (DBConfig{}) {
-		fmt.Printf("database connection '%s' does not exist", conName)
+		fmt.Printf("database connection %q does not exist\n", conName)
 		return DBConfig{}
 	}
 	return config
 }
+
+// forget removes the registry entries of the named connection, leaving what retire tears down; the
+// standby failover uses it alone to move a standby under the name of its primary. The caller holds the
+// mutex.
+func (f *ConnectionManager) forget(name string) {
+	delete(f.connections, name)
+	delete(f.configs, name)
+	delete(f.sessions, name)
+	delete(f.warnings, name)
+	delete(f.hooks, name)
+	delete(f.info, name)
+	f.forgetRegion(name)
+}
+
+// retire forgets a closed connection and detaches what runs for it: its holders, canary checks,
+// rows-affected tracker, plugins, lag monitor and standbys. The caller holds the mutex and calls the
+// returned function once it has released it, to stop the lag monitor and the standby validation and
+// drop the cached scalars of the connection.
+func (f *ConnectionManager) retire(name string) func() {
+	f.forget(name)
+	delete(f.refs, name)
+	delete(f.canaries, name)
+	delete(f.rowsAffected, name)
+	delete(f.plugins, name)
+	monitor := f.lagMonitors[name]
+	standbys := f.detachStandbys(name)
+	return func() {
+		f.scalars.invalidate(name)
+		if monitor != nil {
+			monitor.Stop()
+		}
+		for _, s := range standbys {
+			s.stop()
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package connection
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/hemant-dhiman/MySQL-connection/constants"
 	"gorm.io/gorm"
 	"log"
+	"net"
 	"os"
 	"testing"
 	"time"
@@ -182,3 +185,84 @@ func TestReconnect(t *testing.T) {
 	dbFactory.PrintAllExistingDb()
 	dbFactory.CloseAllConnections()
 }
+
+func TestReconnectHonorsDeadline(t *testing.T) {
+	factory := newTestFactory()
+	unhealthy := newConnectorDB(t, &fakeConnector{})
+	factory.connections["slow_db"] = unhealthy
+	// A non-routable address: connecting never completes on its own within the deadline.
+	factory.configs["slow_db"] = DBConfig{DataSourceName: "user:secret@tcp(10.255.255.1:3306)/app", ConnectTimeout: 10 * time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := factory.reconnect(ctx, "slow_db", factory.configs["slow_db"]); err == nil {
+		t.Fatal("Expected the reconnect to fail, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Reconnect ignored the deadline, took %v", elapsed)
+	}
+	if factory.connections["slow_db"] != unhealthy {
+		t.Fatal("Expected the connection to stay registered for the next reconnect")
+	}
+
+	if _, err := factory.reconnect(ctx, "slow_db", factory.configs["slow_db"]); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected an expired context to fail fast, got %v", err)
+	}
+}
+
+// blockingDialer signals each dial on dialing and fails it once release is closed.
+type blockingDialer struct {
+	dialing chan struct{}
+	release chan struct{}
+}
+
+func (d *blockingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.dialing <- struct{}{}
+	<-d.release
+	return nil, errors.New("connection refused")
+}
+
+func TestConnectOutsideMutex(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["other_db"] = newConnectorDB(t, &fakeConnector{})
+	dialer := &blockingDialer{dialing: make(chan struct{}, 1), release: make(chan struct{})}
+	config := DBConfig{DataSourceName: "user:secret@tcp(db-1:3306)/app", Dialer: dialer}
+
+	connected := make(chan error, 1)
+	go func() {
+		_, err := factory.ConnectContext(context.Background(), "slow_db", config)
+		connected <- err
+	}()
+	<-dialer.dialing
+
+	// The other connections are served while slow_db dials.
+	got := make(chan error, 1)
+	go func() {
+		_, err := factory.GetSQLDB("other_db")
+		got <- err
+	}()
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Fatalf("GetSQLDB failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the manager not to be locked while connecting")
+	}
+
+	// A second connect of slow_db waits for the first one, within its deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := factory.ConnectContext(ctx, "slow_db", config); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the second connect to give up at its deadline, got %v", err)
+	}
+
+	close(dialer.release)
+	if err := <-connected; err == nil {
+		t.Fatal("Expected the connect to fail, got nil")
+	}
+	if _, exists := factory.connections["slow_db"]; exists {
+		t.Fatal("Expected no connection after the failed connect")
+	}
+}
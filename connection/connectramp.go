@@ -0,0 +1,157 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// minRampInterval bounds how often the connection ramp raises the open connection limit.
+const minRampInterval = 50 * time.Millisecond
+
+// ConnectRateStats reports the waits of the connect-rate limiter of SetConnectRate.
+type ConnectRateStats struct {
+	// Connects counts the physical connections opened by the pools of the manager.
+	Connects int64
+
+	// Waits counts the connects delayed by the limiter, and WaitTime their total delay.
+	Waits    int64
+	WaitTime time.Duration
+}
+
+// connectLimiter is a token bucket shared by the pools of a manager, limiting the rate of physical
+// connects. Its zero value lets every connect through.
+type connectLimiter struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	stats  ConnectRateStats
+}
+
+// SetConnectRate limits the rate at which all the pools of the manager open physical connections
+// together, so a fleet restarting at once does not hit the servers with thousands of simultaneous
+// handshakes. Connects over the rate wait for their turn, within the deadline of the caller.
+//
+// Parameters:
+// - perSecond: The sustained rate of connects, across all pools. Zero or negative removes the limit.
+// - burst: The number of connects allowed at once after a quiet period. Values below 1 are read as 1.
+//
+// Notes:
+//   - The limit applies to the pools established after the call and to those already running.
+//   - Connections of the fake (InitFake) are not limited.
+//
+// Example Usage:
+//
+//	factory := connection.GetConnectionManager()
+//	factory.SetConnectRate(20, 5)
+//	err := factory.InitDataSourceConnection("primary_db", connection.DBConfig{
+//		DataSourceName:  dsn,
+//		MaxOpen:         50,
+//		ConnectRampRate: 5, // 10 seconds to reach 50 connections
+//	})
+func (f *ConnectionManager) SetConnectRate(perSecond float64, burst int) {
+	l := &f.connectLimit
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.rate, l.burst = max(perSecond, 0), float64(max(burst, 1))
+	l.tokens, l.last = l.burst, time.Time{}
+}
+
+// ConnectRateStats returns the connects of the pools of the manager and the waits of the limiter of
+// SetConnectRate.
+func (f *ConnectionManager) ConnectRateStats() ConnectRateStats {
+	f.connectLimit.mutex.Lock()
+	defer f.connectLimit.mutex.Unlock()
+	return f.connectLimit.stats
+}
+
+// wait blocks until a connect is allowed or ctx ends.
+func (l *connectLimiter) wait(ctx context.Context, clock Clock) error {
+	var waited time.Duration
+	for {
+		delay := l.reserve(clock.Now(), waited)
+		if delay == 0 {
+			return nil
+		}
+		ticker := clock.NewTicker(delay)
+		select {
+		case <-ticker.C():
+			ticker.Stop()
+			waited += delay
+		case <-ctx.Done():
+			ticker.Stop()
+			return fmt.Errorf("waiting for the connect rate limit: %w", ctx.Err())
+		}
+	}
+}
+
+// reserve takes a token and returns 0, or returns how long to wait for one. waited is the time the
+// caller already waited, recorded in the stats once it gets its token.
+func (l *connectLimiter) reserve(now time.Time, waited time.Duration) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.rate <= 0 {
+		l.stats.Connects++
+		return 0
+	}
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	if l.tokens >= 1 {
+		l.tokens--
+		l.stats.Connects++
+		if waited > 0 {
+			l.stats.Waits++
+			l.stats.WaitTime += waited
+		}
+		return 0
+	}
+	return max(time.Duration((1-l.tokens)/l.rate*float64(time.Second)), time.Millisecond)
+}
+
+// ramp raises the open connection limit of the shards of a new pool from one connection each to
+// their share of MaxOpen, by ConnectRampRate connections per second in total. Each step reads the
+// current settings, so a reconfigured MaxOpen is the new target and is never exceeded. The ramp runs
+// in the background until the limit is reached, also when the pool is closed meanwhile.
+func (c *poolControl) ramp(clock Clock) {
+	c.mutex.Lock()
+	c.rampOpen = 1
+	c.applyLocked()
+	interval := max(time.Duration(float64(time.Second)/c.config.ConnectRampRate), minRampInterval)
+	step := c.config.ConnectRampRate * interval.Seconds() / float64(len(c.shards))
+	c.mutex.Unlock()
+
+	ticker := clock.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		open := 1.0
+		for range ticker.C() {
+			open += step
+			c.mutex.Lock()
+			target := shardShare(c.config.MaxOpen, len(c.shards))
+			done := target <= 0 || int(open) >= target
+			c.rampOpen = int(open)
+			if done {
+				c.rampOpen = 0
+			}
+			c.applyLocked()
+			c.mutex.Unlock()
+			if done {
+				return
+			}
+		}
+	}()
+}
+
+// connectRampWarnings reports a ConnectRampRate without effect.
+func connectRampWarnings(c DBConfig) []ConfigWarning {
+	if c.ConnectRampRate > 0 && c.MaxOpen <= 0 {
+		return []ConfigWarning{{Field: ConfigFieldConnectRampRate,
+			Message: "ConnectRampRate has no effect on a pool without MaxOpen"}}
+	}
+	return nil
+}
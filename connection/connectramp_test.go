@@ -0,0 +1,158 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds or a second passes.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConnectRateLimit(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1)
+	factory := newTestFactory()
+	factory.SetConnectRate(10, 2)
+	pool := sql.OpenDB(&trackedConnector{connector: &fakeConnector{}, sessions: &sessionTracker{}, clock: clock, limiter: &factory.connectLimit})
+	defer pool.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := pool.Conn(ctx); err != nil {
+			t.Fatalf("Conn failed: %v", err)
+		}
+	}
+	opened := make(chan error, 1)
+	go func() {
+		_, err := pool.Conn(ctx)
+		opened <- err
+	}()
+	waitFor(t, "the connect to wait", func() bool { return clock.Tickers() == 1 })
+	select {
+	case err := <-opened:
+		t.Fatalf("Expected the third connect to wait for the rate limit, got %v", err)
+	default:
+	}
+	clock.Advance(100 * time.Millisecond)
+	if err := <-opened; err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	if stats := factory.ConnectRateStats(); stats.Connects != 3 || stats.Waits != 1 || stats.WaitTime != 100*time.Millisecond {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Conn(timeout); err == nil {
+		t.Fatal("Expected the connect to give up at the deadline of the caller")
+	}
+
+	factory.SetConnectRate(0, 0)
+	if _, err := pool.Conn(ctx); err != nil {
+		t.Fatalf("Expected no limit after removing it, got %v", err)
+	}
+}
+
+func TestConnectRamp(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1)
+	shards := []*sql.DB{sql.OpenDB(&fakeConnector{}), sql.OpenDB(&fakeConnector{})}
+	for _, shard := range shards {
+		defer shard.Close()
+	}
+	config := DBConfig{MaxOpen: 8, MaxIdle: 2, ConnectRampRate: 10}
+	control := &poolControl{shards: shards}
+	control.apply(config)
+	control.ramp(clock)
+	if got := shards[0].Stats().MaxOpenConnections; got != 1 {
+		t.Fatalf("Expected the ramp to start at one connection, got %d", got)
+	}
+
+	// 10 connections per second over 2 shards: +0.5 per shard every 100ms, until 4 per shard.
+	var limits []int
+	for i := 0; i < 100 && clock.Tickers() > 0; i++ {
+		clock.Advance(100 * time.Millisecond)
+		time.Sleep(time.Millisecond)
+		if limit := shards[1].Stats().MaxOpenConnections; len(limits) == 0 || limits[len(limits)-1] != limit {
+			limits = append(limits, limit)
+		}
+	}
+	if len(limits) < 3 || limits[len(limits)-1] != 4 || shards[0].Stats().MaxOpenConnections != 4 {
+		t.Fatalf("Expected the limit to rise gradually to the share of MaxOpen, got %v", limits)
+	}
+
+	if len(connectRampWarnings(DBConfig{ConnectRampRate: 1})) != 1 || len(connectRampWarnings(config)) != 0 {
+		t.Fatal("Unexpected ramp warnings")
+	}
+}
+
+func TestConnectRampFollowsReconfigure(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1)
+	shard := sql.OpenDB(&fakeConnector{})
+	defer shard.Close()
+	config := DBConfig{MaxOpen: 20, ConnectRampRate: 10}
+	control := &poolControl{shards: []*sql.DB{shard}}
+	control.apply(config)
+	control.ramp(clock)
+
+	// A lower MaxOpen during the ramp is kept; the ramp ends at it.
+	lowered := config
+	lowered.MaxOpen = 3
+	control.apply(lowered)
+	if got := shard.Stats().MaxOpenConnections; got != 1 {
+		t.Fatalf("Expected the ramp to keep its limit, got %d", got)
+	}
+	for i := 0; i < 100 && clock.Tickers() > 0; i++ {
+		clock.Advance(100 * time.Millisecond)
+		time.Sleep(time.Millisecond)
+		if got := shard.Stats().MaxOpenConnections; got > 3 {
+			t.Fatalf("Expected the ramp never to exceed the reconfigured MaxOpen, got %d", got)
+		}
+	}
+	waitFor(t, "the end of the ramp", func() bool { return clock.Tickers() == 0 })
+	if got := shard.Stats().MaxOpenConnections; got != 3 {
+		t.Fatalf("Expected the reconfigured MaxOpen once the ramp ended, got %d", got)
+	}
+}
+
+func TestConnectRampGateCapacity(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1)
+	shard := sql.OpenDB(&fakeConnector{})
+	defer shard.Close()
+	config := DBConfig{MaxOpen: 4, ConnectRampRate: 10, PriorityAcquisition: true}
+	control := &poolControl{shards: []*sql.DB{shard}}
+	control.apply(config)
+	control.ramp(clock)
+	gate := newPriorityGate(config.MaxOpen)
+	control.setGate(gate)
+	if got := gate.stats().Capacity; got != 1 {
+		t.Fatalf("Expected the gate to admit the ramp limit, got %d", got)
+	}
+
+	ctx := context.Background()
+	if err := gate.acquire(ctx, QueryInteractive); err != nil {
+		t.Fatalf("Unexpected error acquiring a free slot: %v", err)
+	}
+	admitted := make(chan error, 1)
+	go func() { admitted <- gate.acquire(ctx, QueryInteractive) }()
+	waitForWaiting(t, gate, 1)
+	for i := 0; i < 100 && clock.Tickers() > 0; i++ {
+		clock.Advance(100 * time.Millisecond)
+		time.Sleep(time.Millisecond)
+	}
+	if err := <-admitted; err != nil {
+		t.Fatalf("Expected the waiter to be admitted as the ramp raised the limit, got %v", err)
+	}
+	if stats := gate.stats(); stats.Capacity != 4 || stats.InUse != 2 {
+		t.Fatalf("Expected the gate to reach MaxOpen, got %+v", stats)
+	}
+}
@@ -0,0 +1,73 @@
+package connection
+
+import (
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Production defaults applied by DefaultConfig and to zero DBConfig fields.
+const (
+	DefaultMaxOpen        = 25
+	DefaultLifetime       = 5 * time.Minute
+	DefaultConnectTimeout = 5 * time.Second
+)
+
+// DefaultConfig returns a configuration with production-sensible defaults for dsn: MaxOpen and MaxIdle 25,
+// a Lifetime of 5 minutes, a ConnectTimeout of 5 seconds, and parseTime enabled in the data source name
+// so DATETIME and TIMESTAMP columns scan into time.Time.
+//
+// Example Usage:
+//
+//	config := connection.DefaultConfig("user:password@tcp(localhost:3306)/dbname")
+//	config.MaxOpen = 50
+//	err := connection.GetConnectionManager().InitDataSourceConnection("primary_db", config)
+func DefaultConfig(dsn string) DBConfig {
+	// An unparsable DSN is kept as is; InitDataSourceConnection reports it.
+	if cfg, err := mysqldriver.ParseDSN(dsn); err == nil && !cfg.ParseTime {
+		cfg.ParseTime = true
+		dsn = cfg.FormatDSN()
+	}
+	return DBConfig{
+		DataSourceName: dsn,
+		MaxOpen:        DefaultMaxOpen,
+		MaxIdle:        DefaultMaxOpen,
+		Lifetime:       DefaultLifetime,
+		ConnectTimeout: DefaultConnectTimeout,
+	}
+}
+
+// withDefaults replaces zero pool settings with the defaults, so a zero MaxOpen does not silently mean
+// an unlimited pool. Negative values are kept and mean "unlimited" (MaxOpen, Lifetime) or "none" (MaxIdle).
+func (c DBConfig) withDefaults() DBConfig {
+	if c.MaxOpen == 0 {
+		c.MaxOpen = DefaultMaxOpen
+	}
+	if c.MaxIdle == 0 {
+		c.MaxIdle = c.MaxOpen
+		if c.MaxIdle < 0 {
+			c.MaxIdle = DefaultMaxOpen
+		}
+	}
+	if c.Lifetime == 0 {
+		c.Lifetime = DefaultLifetime
+	}
+	if c.ConnectTimeout == 0 {
+		c.ConnectTimeout = DefaultConnectTimeout
+	}
+	return c
+}
+
+// session applies the session defaults of the configuration (QueryFields, FullSaveAssociations,
+// DryRun) to a handle being returned to a caller. Without defaults the handle is returned as is.
+func (c DBConfig) session(db *gorm.DB) *gorm.DB {
+	if !c.QueryFields && !c.FullSaveAssociations && !c.DryRun {
+		return db
+	}
+	return db.Session(&gorm.Session{
+		NewDB:                true,
+		QueryFields:          c.QueryFields,
+		FullSaveAssociations: c.FullSaveAssociations,
+		DryRun:               c.DryRun,
+	})
+}
@@ -0,0 +1,68 @@
+package connection
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	config := DefaultConfig("user:password@tcp(localhost:3306)/dbname")
+	if config.MaxOpen != 25 || config.MaxIdle != 25 || config.Lifetime != 5*time.Minute || config.ConnectTimeout != 5*time.Second {
+		t.Fatalf("Unexpected defaults %+v", config)
+	}
+	if !strings.Contains(config.DataSourceName, "parseTime=true") {
+		t.Fatalf("Expected parseTime in the data source name, got %q", config.DataSourceName)
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	config := DBConfig{}.withDefaults()
+	if config.MaxOpen != DefaultMaxOpen || config.MaxIdle != DefaultMaxOpen || config.Lifetime != DefaultLifetime {
+		t.Fatalf("Expected zero fields to get defaults, got %+v", config)
+	}
+
+	config = DBConfig{MaxOpen: 8, Lifetime: -1}.withDefaults()
+	if config.MaxOpen != 8 || config.MaxIdle != 8 || config.Lifetime != -1 {
+		t.Fatalf("Expected explicit values to be kept, got %+v", config)
+	}
+	if config = (DBConfig{MaxOpen: -1}).withDefaults(); config.MaxOpen != -1 || config.MaxIdle != DefaultMaxOpen {
+		t.Fatalf("Expected an unlimited pool to keep %d idle connections, got %+v", DefaultMaxOpen, config)
+	}
+}
+
+type sessionTestUser struct {
+	ID   int
+	Name string
+}
+
+func TestSessionDefaults(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["session_db"] = newConnectorDB(t, &fakeConnector{})
+	factory.configs["session_db"] = DBConfig{QueryFields: true, DryRun: true}
+
+	db, err := factory.GetDBContext(context.Background(), "session_db")
+	if err != nil {
+		t.Fatalf("GetDBContext failed: %v", err)
+	}
+	if !db.DryRun || !db.QueryFields || db.FullSaveAssociations {
+		t.Fatalf("Expected the configured session defaults, got DryRun=%v QueryFields=%v FullSaveAssociations=%v",
+			db.DryRun, db.QueryFields, db.FullSaveAssociations)
+	}
+
+	var users []sessionTestUser
+	stmt := db.Where("id > ?", 1).Find(&users).Statement
+	if sql := stmt.SQL.String(); !strings.Contains(sql, "`session_test_users`.`name`") {
+		t.Errorf("Expected the fields to be selected by name, got %q", sql)
+	}
+	// The defaults do not leak between handles.
+	if again := db.Find(&users).Statement.SQL.String(); strings.Contains(again, "id >") {
+		t.Errorf("Expected a fresh statement, got %q", again)
+	}
+
+	factory.configs["session_db"] = DBConfig{}
+	if db, _ := factory.GetDB("session_db"); db != factory.connections["session_db"] {
+		t.Error("Expected the handle to be returned as is without session defaults")
+	}
+}
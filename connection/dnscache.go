@@ -0,0 +1,229 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Defaults of DNSCacheOptions.
+const (
+	defaultDNSTTL         = 30 * time.Second
+	defaultDNSMaxTTL      = 5 * time.Minute
+	defaultDNSNegativeTTL = 5 * time.Second
+)
+
+// DNSLookupFunc resolves a host name into addresses, with the time they may be cached. A ttl of zero
+// or less selects DNSCacheOptions.TTL.
+type DNSLookupFunc func(ctx context.Context, host string) (addrs []string, ttl time.Duration, err error)
+
+// DNSCacheOptions configures NewDNSCache.
+type DNSCacheOptions struct {
+	// TTL is how long addresses are cached when the lookup does not report a TTL. The default lookup
+	// never does, since the Go resolver does not expose record TTLs. Defaults to 30 seconds.
+	TTL time.Duration
+
+	// MinTTL and MaxTTL bound the TTLs reported by the lookup. MaxTTL defaults to five minutes.
+	MinTTL time.Duration
+	MaxTTL time.Duration
+
+	// NegativeTTL is how long a failed lookup is cached, so a missing host does not cause a lookup per
+	// connection attempt. Defaults to five seconds.
+	NegativeTTL time.Duration
+
+	// Lookup resolves the host names. Defaults to net.DefaultResolver, with TTL as the TTL.
+	Lookup DNSLookupFunc
+
+	// Dialer dials the resolved addresses. Defaults to a net.Dialer.
+	Dialer ContextDialer
+}
+
+// DNSCacheStats counts the lookups of a DNSCache.
+type DNSCacheStats struct {
+	// Hits and NegativeHits count the dials served from cached addresses and cached failures.
+	Hits         int64
+	NegativeHits int64
+
+	// Lookups counts the lookups performed. Concurrent dials of a host missing from the cache share one.
+	Lookups int64
+
+	// Entries is the number of host names cached.
+	Entries int
+}
+
+// DNSCache is a ContextDialer resolving host names through a cache honoring TTLs, with negative
+// caching. Set it as DBConfig.Dialer of the connections to a host name, sharing one DNSCache between
+// them, so that pools opening connections do not storm the resolver.
+type DNSCache struct {
+	opts DNSCacheOptions
+
+	mutex    sync.Mutex
+	entries  map[string]*dnsEntry
+	inflight map[string]*dnsLookup
+	stats    DNSCacheStats
+}
+
+// dnsEntry is the cached outcome of a lookup.
+type dnsEntry struct {
+	addrs   []string
+	err     error
+	expires time.Time
+}
+
+// dnsLookup is a lookup shared by the dials of a host missing from the cache.
+type dnsLookup struct {
+	done  chan struct{}
+	entry *dnsEntry
+}
+
+// NewDNSCache returns an empty DNS cache.
+//
+// Example Usage:
+//
+//	resolver := connection.NewDNSCache(connection.DNSCacheOptions{TTL: time.Minute})
+//	for _, name := range []string{"orders_db", "billing_db", "audit_db"} { // same cluster endpoint
+//		config := configs[name]
+//		config.Dialer = resolver
+//		factory.InitDataSourceConnection(name, config)
+//	}
+//	...
+//	resolver.Flush("cluster.internal") // the cluster endpoint was moved
+func NewDNSCache(opts DNSCacheOptions) *DNSCache {
+	if opts.TTL <= 0 {
+		opts.TTL = defaultDNSTTL
+	}
+	if opts.MaxTTL <= 0 {
+		opts.MaxTTL = defaultDNSMaxTTL
+	}
+	if opts.NegativeTTL <= 0 {
+		opts.NegativeTTL = defaultDNSNegativeTTL
+	}
+	if opts.Lookup == nil {
+		opts.Lookup = func(ctx context.Context, host string) ([]string, time.Duration, error) {
+			addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+			return addrs, 0, err
+		}
+	}
+	if opts.Dialer == nil {
+		opts.Dialer = &net.Dialer{}
+	}
+	return &DNSCache{opts: opts}
+}
+
+// DialContext resolves the host of address through the cache and dials its addresses in turn until
+// one accepts the connection. When none does, the host is dropped from the cache, so that the next
+// dial resolves it again, e.g. after a failover moved the endpoint.
+func (c *DNSCache) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return c.opts.Dialer.DialContext(ctx, network, address)
+	}
+	addrs, err := c.Resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, addr := range addrs {
+		conn, err := c.opts.Dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	c.Flush(host)
+	return nil, errors.Join(errs...)
+}
+
+// Resolve returns the addresses of host, from the cache while they are fresh.
+func (c *DNSCache) Resolve(ctx context.Context, host string) ([]string, error) {
+	c.mutex.Lock()
+	if entry, ok := c.entries[host]; ok && time.Now().Before(entry.expires) {
+		if entry.err != nil {
+			c.stats.NegativeHits++
+		} else {
+			c.stats.Hits++
+		}
+		c.mutex.Unlock()
+		return entry.addrs, entry.err
+	}
+	lookup, ok := c.inflight[host]
+	if !ok {
+		lookup = &dnsLookup{done: make(chan struct{})}
+		if c.inflight == nil {
+			c.inflight = make(map[string]*dnsLookup)
+		}
+		c.inflight[host] = lookup
+		c.stats.Lookups++
+	}
+	c.mutex.Unlock()
+
+	if ok {
+		select {
+		case <-lookup.done:
+			return lookup.entry.addrs, lookup.entry.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	addrs, ttl, err := c.opts.Lookup(ctx, host)
+	entry := &dnsEntry{addrs: addrs, err: err}
+	switch {
+	case err == nil && len(addrs) == 0:
+		entry.err = &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}
+		ttl = c.opts.NegativeTTL
+	case err != nil:
+		ttl = c.opts.NegativeTTL
+	case ttl <= 0:
+		ttl = c.opts.TTL
+	}
+	if entry.err == nil {
+		ttl = max(c.opts.MinTTL, min(ttl, c.opts.MaxTTL))
+	}
+	entry.expires = time.Now().Add(ttl)
+
+	c.mutex.Lock()
+	delete(c.inflight, host)
+	// A lookup cut short by the context of its dial says nothing about the host.
+	if ctx.Err() == nil || entry.err == nil {
+		if c.entries == nil {
+			c.entries = make(map[string]*dnsEntry)
+		}
+		c.entries[host] = entry
+	}
+	c.mutex.Unlock()
+	lookup.entry = entry
+	close(lookup.done)
+	return entry.addrs, entry.err
+}
+
+// Flush drops hosts from the cache, or every host when none is given, so their next dial resolves
+// them again.
+func (c *DNSCache) Flush(hosts ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(hosts) == 0 {
+		c.entries = nil
+		return
+	}
+	for _, host := range hosts {
+		delete(c.entries, host)
+	}
+}
+
+// Stats returns the counters of the cache.
+func (c *DNSCache) Stats() DNSCacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	return stats
+}
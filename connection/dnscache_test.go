@@ -0,0 +1,97 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// addrDialer records the addresses dialed and accepts only those in accept.
+type addrDialer struct {
+	mutex  sync.Mutex
+	dialed []string
+	accept map[string]bool
+}
+
+func (d *addrDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.dialed = append(d.dialed, address)
+	if !d.accept[address] {
+		return nil, errors.New("connection refused")
+	}
+	client, server := net.Pipe()
+	server.Close()
+	return client, nil
+}
+
+func TestDNSCache(t *testing.T) {
+	var lookups atomic.Int64
+	addrs := []string{"10.0.0.1", "10.0.0.2"}
+	dialer := &addrDialer{accept: map[string]bool{"10.0.0.2:3306": true}}
+	cache := NewDNSCache(DNSCacheOptions{
+		Lookup: func(ctx context.Context, host string) ([]string, time.Duration, error) {
+			lookups.Add(1)
+			time.Sleep(20 * time.Millisecond)
+			if host == "missing.internal" {
+				return nil, 0, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+			}
+			return addrs, time.Minute, nil
+		},
+		Dialer: dialer,
+	})
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := cache.DialContext(ctx, "tcp", "db.internal:3306")
+			if err != nil {
+				t.Errorf("Dial failed: %v", err)
+				return
+			}
+			conn.Close()
+		}()
+	}
+	wg.Wait()
+	if stats := cache.Stats(); lookups.Load() != 1 || stats.Lookups != 1 || stats.Entries != 1 {
+		t.Fatalf("Expected concurrent dials to share one lookup, got %d lookups, %+v", lookups.Load(), stats)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.DialContext(ctx, "tcp", "missing.internal:3306"); err == nil {
+			t.Fatal("Expected an error for a missing host, got nil")
+		}
+	}
+	if stats := cache.Stats(); lookups.Load() != 2 || stats.NegativeHits != 1 {
+		t.Fatalf("Expected the failed lookup to be cached, got %d lookups, %+v", lookups.Load(), stats)
+	}
+
+	// Every address refusing the connection drops the host, so the next dial resolves it again.
+	dialer.accept = nil
+	if _, err := cache.DialContext(ctx, "tcp", "db.internal:3306"); err == nil {
+		t.Fatal("Expected an error when every address refuses, got nil")
+	}
+	dialer.accept = map[string]bool{"10.0.0.2:3306": true}
+	if conn, err := cache.DialContext(ctx, "tcp", "db.internal:3306"); err != nil || lookups.Load() != 3 {
+		t.Fatalf("Expected a new lookup after the failed dial, got %d lookups: %v", lookups.Load(), err)
+	} else {
+		conn.Close()
+	}
+
+	cache.Flush()
+	if stats := cache.Stats(); stats.Entries != 0 {
+		t.Fatalf("Expected Flush to empty the cache, got %+v", stats)
+	}
+	if conn, err := cache.DialContext(ctx, "tcp", "10.0.0.2:3306"); err != nil || lookups.Load() != 3 {
+		t.Fatalf("Expected IP addresses to be dialed without lookup, got %d lookups: %v", lookups.Load(), err)
+	} else {
+		conn.Close()
+	}
+}
@@ -0,0 +1,167 @@
+package connection
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"sync/atomic"
+	"time"
+)
+
+// maxDowntimeHistory is the number of closed downtime windows kept per connection.
+const maxDowntimeHistory = 100
+
+// DowntimeWindow is a period during which a connection was down: from the first failed health check of
+// GetDB to the first statement that succeeded after the connection recovered. A window of a connection
+// that recovered but ran no statement yet stays open.
+type DowntimeWindow struct {
+	Start time.Time
+	// End is zero for the window of a connection still down.
+	End      time.Time
+	Duration time.Duration
+
+	// FailedAttempts is the number of reconnect attempts that failed during the window.
+	FailedAttempts int64
+
+	// LastError is the error of the last failed attempt, nil when none failed.
+	LastError error
+}
+
+// DowntimeHistory returns the downtime windows of a connection, oldest first, for SLO reports
+// attributing error budgets to databases. The last 100 closed windows are kept; when the connection is
+// down, the current window comes last with a zero End and its duration so far.
+//
+// Parameters:
+// - name: The name of the connection.
+// - since: Windows that ended before since are left out. The zero time returns them all.
+//
+// Returns:
+// - []DowntimeWindow: The windows, none for a connection never found down.
+//
+// Notes:
+//   - Downtime is observed by GetDB: a connection nobody asks for is not found down, and a window
+//     starts with the first request finding the connection unhealthy. It ends with the first statement
+//     succeeding after a reconnect or health check succeeded, not with the recovery itself.
+//   - ReconnectStats reports the total downtime, and RegisterOTelMetrics exports it as the
+//     db.client.connection.downtime counter along with the reconnect durations.
+//
+// Example Usage:
+//
+//	var budget time.Duration
+//	for _, window := range factory.DowntimeHistory("primary_db", monthStart) {
+//		budget += window.Duration
+//	}
+//	log.Printf("primary_db was down %v this month", budget)
+func (f *ConnectionManager) DowntimeHistory(name string, since time.Time) []DowntimeWindow {
+	now := f.clock().Now()
+	f.reconnects.mutex.Lock()
+	defer f.reconnects.mutex.Unlock()
+	st := f.reconnects.states[name]
+	if st == nil {
+		return nil
+	}
+	var windows []DowntimeWindow
+	for _, window := range st.windows {
+		if !window.End.Before(since) {
+			windows = append(windows, window)
+		}
+	}
+	if !st.downSince.IsZero() {
+		current := st.window(now)
+		current.End = time.Time{}
+		windows = append(windows, current)
+	}
+	return windows
+}
+
+// window returns the current downtime window of st ending at now.
+func (st *reconnectState) window(now time.Time) DowntimeWindow {
+	window := DowntimeWindow{Start: st.downSince, End: now, Duration: now.Sub(st.downSince), FailedAttempts: st.downFailures}
+	if st.downFailures > 0 {
+		window.LastError = st.lastErr
+	}
+	return window
+}
+
+// closeWindow closes the current downtime window of st at now.
+func (st *reconnectState) closeWindow(now time.Time) {
+	if len(st.windows) == maxDowntimeHistory {
+		st.windows = append(st.windows[:0], st.windows[1:]...)
+	}
+	window := st.window(now)
+	st.windows = append(st.windows, window)
+	st.downtime += window.Duration
+	st.downSince, st.downFailures = time.Time{}, 0
+	if st.probe != nil {
+		st.probe.down.Store(false)
+	}
+}
+
+// downtimeProbe closes the downtime window of a connection at its first successful statement. down is
+// set from the recovery of the connection to that statement, so the statements of a connection that is
+// up skip the reconnect tracker and its mutex.
+type downtimeProbe struct {
+	down    atomic.Bool
+	name    string
+	factory *ConnectionManager
+}
+
+// downtimeProbe returns the probe of the named connection, shared by its successive pools.
+func (f *ConnectionManager) downtimeProbe(name string) *downtimeProbe {
+	f.reconnects.mutex.Lock()
+	defer f.reconnects.mutex.Unlock()
+	st := f.reconnects.state(name)
+	if st.probe == nil {
+		st.probe = &downtimeProbe{name: name, factory: f}
+	}
+	return st.probe
+}
+
+// served records a successful statement. It is safe to call on a nil probe.
+func (p *downtimeProbe) served() {
+	if p == nil || !p.down.Load() {
+		return
+	}
+	now := p.factory.clock().Now()
+	t := &p.factory.reconnects
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if st := t.states[p.name]; st != nil && p.down.Load() && !st.downSince.IsZero() {
+		st.closeWindow(now)
+	}
+}
+
+// downtimeUntil returns the total downtime of the state at now, including the current window.
+func (st *reconnectState) downtimeUntil(now time.Time) time.Duration {
+	if st.downSince.IsZero() {
+		return st.downtime
+	}
+	return st.downtime + now.Sub(st.downSince)
+}
+
+// downtimes returns the total downtime of every connection found down at least once.
+func (t *reconnectTracker) downtimes(now time.Time) map[string]time.Duration {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	downtimes := make(map[string]time.Duration)
+	for name, st := range t.states {
+		if total := st.downtimeUntil(now); total > 0 || !st.downSince.IsZero() {
+			downtimes[name] = total
+		}
+	}
+	return downtimes
+}
+
+// recordReconnect records the duration of a reconnect attempt for RegisterOTelMetrics.
+func (f *ConnectionManager) recordReconnect(ctx context.Context, name string, took time.Duration, err error) {
+	instruments := f.otel.Load()
+	if instruments == nil {
+		return
+	}
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	instruments.reconnect.Record(ctx, took.Seconds(),
+		metric.WithAttributes(append(otelAttributes(name, f.ClusterMetadata(name)), attribute.String("outcome", outcome))...))
+}
@@ -0,0 +1,70 @@
+package connection
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDowntimeHistory(t *testing.T) {
+	EnableChaos(true)
+	defer EnableChaos(false)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start, 1)
+	factory := newFakeFactory(t, FakeData{}).WithClock(clock)
+	outage := errors.New("connection refused")
+	if err := factory.InjectChaos("primary_db", ChaosConfig{FailPing: true, ReconnectError: outage}); err != nil {
+		t.Fatalf("InjectChaos failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := factory.GetDB("primary_db"); !errors.Is(err, outage) {
+			t.Fatalf("Expected the injected reconnect error, got %v", err)
+		}
+		clock.Advance(10 * time.Second)
+	}
+	history := factory.DowntimeHistory("primary_db", time.Time{})
+	if len(history) != 1 || !history[0].End.IsZero() || history[0].Duration != 30*time.Second || history[0].FailedAttempts != 3 {
+		t.Fatalf("Expected an ongoing window of 30s and 3 failures, got %+v", history)
+	}
+
+	factory.ClearChaos("primary_db")
+	db, err := factory.GetDB("primary_db")
+	if err != nil {
+		t.Fatalf("Expected the connection to be healthy again, got %v", err)
+	}
+	if history = factory.DowntimeHistory("primary_db", time.Time{}); len(history) != 1 || !history[0].End.IsZero() {
+		t.Fatalf("Expected the window to stay open until a statement succeeds, got %+v", history)
+	}
+	if stats := factory.ReconnectStats("primary_db"); stats.ConsecutiveFailures != 0 {
+		t.Fatalf("Expected the outage to end at the recovery: %+v", stats)
+	}
+
+	clock.Advance(5 * time.Second)
+	var one int
+	if err := db.Raw("SELECT 1").Scan(&one).Error; err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	history = factory.DowntimeHistory("primary_db", time.Time{})
+	if len(history) != 1 || !history[0].End.Equal(start.Add(35*time.Second)) || history[0].FailedAttempts != 3 ||
+		!errors.Is(history[0].LastError, outage) {
+		t.Fatalf("Expected a closed window ending at the first statement, got %+v", history)
+	}
+	if stats := factory.ReconnectStats("primary_db"); stats.Downtime != 35*time.Second {
+		t.Fatalf("Unexpected stats after the first statement: %+v", stats)
+	}
+	if windows := factory.DowntimeHistory("primary_db", start.Add(time.Minute)); len(windows) != 0 {
+		t.Fatalf("Expected windows ended before since to be left out, got %+v", windows)
+	}
+
+	clock.Advance(time.Hour)
+	if stats := factory.ReconnectStats("primary_db"); stats.Downtime != 35*time.Second {
+		t.Fatalf("Expected no downtime while up, got %v", stats.Downtime)
+	}
+	if err := db.Raw("SELECT 1").Scan(&one).Error; err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if history := factory.DowntimeHistory("primary_db", time.Time{}); len(history) != 1 {
+		t.Fatalf("Expected statements of a connection that is up to open no window, got %+v", history)
+	}
+}
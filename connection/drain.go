@@ -0,0 +1,128 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"time"
+)
+
+// drainPollInterval is how often DrainConnection checks whether in-use connections were returned.
+const drainPollInterval = 10 * time.Millisecond
+
+// closeVerifyTimeout is how long closePool waits for the connections of a closed pool to be closed.
+const closeVerifyTimeout = time.Second
+
+// DrainReport describes how a connection was drained by DrainConnection.
+type DrainReport struct {
+	// InUse is the number of pooled connections in use when draining started.
+	InUse int
+
+	// Drained is the number of them returned to the pool before the deadline.
+	Drained int
+
+	// ForceClosed is the number still in use at the deadline. The pool is closed under them, so they are
+	// closed as soon as their statement returns and cannot be used again.
+	ForceClosed int
+
+	// Waited is how long draining took.
+	Waited time.Duration
+}
+
+// DrainConnection closes a connection without failing the statements running on it: it stops handing out
+// the connection, waits for the pooled connections in use to be returned, then closes the pool.
+//
+// Parameters:
+// - ctx: Bounds the wait; its deadline is the drain deadline. Use context.WithTimeout.
+// - name: The name of the database connection to drain and close.
+//
+// Returns:
+// - *DrainReport: How many connections were in use, drained and force-closed.
+// - error: An error if the connection does not exist or cannot be closed.
+//
+// Behavior:
+// 1. The connection and its configuration are removed right away, so GetDB stops returning it and a new
+// connection with the same name can be initialized while the old one drains. Its lag monitor and the
+// validation of its standbys are stopped, as by CloseConnection.
+// 2. The pool is polled until no connection is in use or ctx is done.
+// 3. The pool is closed; connections still in use at that point are reported as force-closed.
+//
+// Example Usage:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	report, err := connection.GetConnectionManager().DrainConnection(ctx, "primary_db")
+//	if err == nil && report.ForceClosed > 0 {
+//		log.Printf("%d connections were still busy at shutdown", report.ForceClosed)
+//	}
+func (f *ConnectionManager) DrainConnection(ctx context.Context, name string) (*DrainReport, error) {
+	f.mutex.Lock()
+	db, exists := f.connections[name]
+	if !exists {
+		f.mutex.Unlock()
+		return nil, errNotFound(name, OpDrain)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		f.mutex.Unlock()
+		return nil, connError(name, OpDrain, fmt.Errorf("error retrieving database handle: %w", err))
+	}
+	stop := f.retire(name)
+	f.mutex.Unlock()
+	stop()
+
+	clock := f.clock()
+	start := clock.Now()
+	report := &DrainReport{InUse: poolStats(db).InUse}
+	ticker := clock.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	inUse := report.InUse
+	for inUse > 0 && ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case <-ticker.C():
+			inUse = poolStats(db).InUse
+		}
+	}
+	report.ForceClosed = inUse
+	report.Drained = report.InUse - report.ForceClosed
+	report.Waited = clock.Now().Sub(start)
+
+	if err := sqlDB.Close(); err != nil {
+		return report, connError(name, OpDrain, fmt.Errorf("error closing database connection: %w", err))
+	}
+	closeExtraShards(db)
+	log.Printf("Database connection %q drained (%d drained, %d force-closed) and closed.", name, report.Drained, report.ForceClosed)
+	return report, nil
+}
+
+// closePool closes the pool of db and verifies that no connection is left open. sql.DB.Close is not
+// retried: the pool is closed by the first call whatever its error, and later calls return nil. The
+// verification waits on the system clock, not the clock of the manager: it waits for sockets to close,
+// which a fake clock does not speed up.
+func closePool(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("error retrieving database handle: %w", err)
+	}
+
+	err = sqlDB.Close()
+	closeExtraShards(db)
+	if err != nil {
+		return fmt.Errorf("error closing database connection: %w", err)
+	}
+
+	// Connections in use are closed when they are returned to the closed pool.
+	deadline := time.Now().Add(closeVerifyTimeout)
+	for {
+		open := poolStats(db).OpenConnections
+		if open == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d connections still open after close", open)
+		}
+		time.Sleep(drainPollInterval)
+	}
+}
@@ -0,0 +1,139 @@
+package connection
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDrainConnection(t *testing.T) {
+	factory := newTestFactory()
+	db := newConnectorDB(t, &fakeConnector{})
+	factory.connections["primary_db"] = db
+	sqlDB, _ := db.DB()
+
+	busy, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = busy.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	report, err := factory.DrainConnection(ctx, "primary_db")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.InUse != 1 || report.Drained != 1 || report.ForceClosed != 0 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if _, err := factory.GetDB("primary_db"); err == nil {
+		t.Fatal("Expected the drained connection to be gone, got nil error")
+	}
+}
+
+func TestDrainConnectionDeadline(t *testing.T) {
+	factory := newTestFactory()
+	db := newConnectorDB(t, &fakeConnector{})
+	factory.connections["primary_db"] = db
+	sqlDB, _ := db.DB()
+
+	busy, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	defer busy.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	report, err := factory.DrainConnection(ctx, "primary_db")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.ForceClosed != 1 || report.Drained != 0 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if _, err := factory.DrainConnection(ctx, "primary_db"); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
+
+func TestCloseAllConnectionsReportsErrors(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["idle_db"] = newConnectorDB(t, &fakeConnector{})
+	busyDB := newConnectorDB(t, &fakeConnector{})
+	factory.connections["busy_db"] = busyDB
+
+	sqlDB, _ := busyDB.DB()
+	busy, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	defer busy.Close()
+
+	errs := factory.CloseAllConnections()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "busy_db") {
+		t.Fatalf("Expected one error for busy_db, got %v", errs)
+	}
+	if len(factory.connections) != 0 {
+		t.Fatalf("Expected all connections to be removed, got %d", len(factory.connections))
+	}
+	if errs := factory.CloseAllConnections(); errs != nil {
+		t.Fatalf("Expected no errors closing nothing, got %v", errs)
+	}
+}
+
+func TestDrainConnectionFollowsClock(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1)
+	factory := newTestFactory().WithClock(clock)
+	db := newConnectorDB(t, &fakeConnector{})
+	factory.connections["primary_db"] = db
+	sqlDB, _ := db.DB()
+	busy, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+
+	done := make(chan *DrainReport, 1)
+	go func() {
+		report, _ := factory.DrainConnection(context.Background(), "primary_db")
+		done <- report
+	}()
+	waitFor(t, "the drain to poll", func() bool { return clock.Tickers() == 1 })
+	_ = busy.Close()
+	clock.Advance(drainPollInterval)
+	if report := <-done; report.Drained != 1 || report.Waited != drainPollInterval {
+		t.Fatalf("Expected the drain to wait one poll of the clock, got %+v", report)
+	}
+}
+
+func TestDrainConnectionStopsLagMonitor(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1)
+	factory := newTestFactory().WithClock(clock)
+	for _, name := range []string{"orders", "replica"} {
+		if err := factory.InitFake(name, heartbeatTable()); err != nil {
+			t.Fatalf("InitFake failed: %v", err)
+		}
+	}
+	defer factory.CloseAllConnections()
+	monitor, err := factory.StartLagMonitor("orders", []string{"replica"}, LagMonitorOptions{ServerID: 7})
+	if err != nil {
+		t.Fatalf("StartLagMonitor failed: %v", err)
+	}
+
+	if _, err := factory.DrainConnection(context.Background(), "orders"); err != nil {
+		t.Fatalf("DrainConnection failed: %v", err)
+	}
+	select {
+	case <-monitor.done:
+	default:
+		t.Fatal("Expected the lag monitor to stop with the drained connection")
+	}
+	if clock.Tickers() != 0 || len(factory.lagMonitors) != 0 {
+		t.Fatalf("Expected no running monitor, got %d tickers and %v", clock.Tickers(), factory.lagMonitors)
+	}
+}
@@ -0,0 +1,296 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/hemant-dhiman/MySQL-connection/constants"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Connection attributes sent with every session of a managed connection. They are visible in
+// performance_schema.session_connect_attrs.
+const (
+	attrProgramName = "program_name"
+	attrService     = "service"
+	attrPod         = "pod"
+	attrVersion     = "version"
+	attrPoolName    = "mysqlconn_pool"
+)
+
+// sessionTracker records the server-side CONNECTION_ID() of the open physical connections of a pool.
+type sessionTracker struct {
+	mutex sync.Mutex
+	ids   map[int64]struct{}
+}
+
+func (t *sessionTracker) add(id int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.ids == nil {
+		t.ids = make(map[int64]struct{})
+	}
+	t.ids[id] = struct{}{}
+}
+
+func (t *sessionTracker) remove(id int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.ids, id)
+}
+
+// list returns the tracked ids in ascending order.
+func (t *sessionTracker) list() []int64 {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	ids := make([]int64, 0, len(t.ids))
+	for id := range t.ids {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// OwnedConnectionIDs returns the server-side connection ids (CONNECTION_ID(), the Id column of
+// SHOW PROCESSLIST) of the physical connections currently open in the pool of the named connection.
+//
+// Parameters:
+// - name: The name of the managed connection.
+//
+// Returns:
+// - []int64: The connection ids in ascending order.
+// - error: An error if the connection does not exist.
+//
+// Notes:
+//   - Every session also carries the connection attributes program_name (DBConfig.ProgramName), service,
+//     pod, version and mysqlconn_pool (the connection name), so DBAs can attribute sessions without calling
+//     into the service:
+//     SELECT * FROM performance_schema.session_connect_attrs WHERE ATTR_NAME = 'mysqlconn_pool'
+//
+// Example Usage:
+//
+//	ids, err := connection.GetConnectionManager().OwnedConnectionIDs("primary_db")
+//	if err == nil {
+//		log.Printf("primary_db owns sessions %v", ids)
+//	}
+func (f *ConnectionManager) OwnedConnectionIDs(name string) ([]int64, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, exists := f.connections[name]; !exists {
+		return nil, errNotFound(name, OpOwnedConnectionIDs)
+	}
+	tracker := f.sessions[name]
+	if tracker == nil {
+		return nil, nil
+	}
+	return tracker.list(), nil
+}
+
+// connectionAttributes appends the attribution attributes of a managed connection to the
+// attributes already present in the DSN, in the driver's "key:value,key:value" format. Empty
+// service, pod and version attributes are left out.
+func connectionAttributes(existing, pool string, config DBConfig) string {
+	program := config.ProgramName
+	if program == "" {
+		program = filepath.Base(os.Args[0])
+	}
+	service := config.ServiceName
+	if service == "" {
+		service = os.Getenv(constants.ENV_MYSQLCONN_SERVICE)
+	}
+	pod := config.PodName
+	if pod == "" {
+		pod = os.Getenv(constants.ENV_MYSQLCONN_POD)
+	}
+	if pod == "" {
+		pod, _ = os.Hostname()
+	}
+	version := config.ServiceVersion
+	if version == "" {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+	}
+
+	var attrs []string
+	if existing != "" {
+		attrs = append(attrs, existing)
+	}
+	attrs = append(attrs, attrProgramName+":"+attributeValue(program))
+	for _, attr := range [][2]string{{attrService, service}, {attrPod, pod}, {attrVersion, version}} {
+		if attr[1] != "" {
+			attrs = append(attrs, attr[0]+":"+attributeValue(attr[1]))
+		}
+	}
+	return strings.Join(append(attrs, attrPoolName+":"+attributeValue(pool)), ",")
+}
+
+// attributeValue replaces the characters the driver uses as attribute separators.
+func attributeValue(value string) string {
+	return strings.NewReplacer(",", "_", ":", "_").Replace(value)
+}
+
+// trackedConnector wraps the driver connector of a managed connection and records the connection id
+// of every physical connection it opens. With a lifetime jitter, it also gives each connection its own
+// jittered lifetime (DBConfig.LifetimeJitter), and with a limiter it waits for the connect rate limit.
+type trackedConnector struct {
+	connector driver.Connector
+	sessions  *sessionTracker
+
+	// control holds the current lifetime settings of the pool, so a reconfigured Lifetime applies to the
+	// connections opened afterwards. Nil disables the jitter.
+	control *poolControl
+	clock   Clock
+
+	// limiter is the connect-rate limiter of the manager, or nil.
+	limiter *connectLimiter
+}
+
+func (c *trackedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.limiter != nil {
+		if err := c.limiter.wait(ctx, c.clock); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := sessionConnectionID(ctx, conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to read connection id: %w", err)
+	}
+	c.sessions.add(id)
+	tracked := &trackedConn{Conn: conn, id: id, sessions: c.sessions}
+	if lifetime, jitter := c.control.lifetime(); jitter > 0 && lifetime > 0 {
+		tracked.clock = c.clock
+		tracked.expires = c.clock.Now().Add(jitteredLifetime(lifetime, jitter, c.clock))
+	}
+	return tracked, nil
+}
+
+func (c *trackedConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// sessionConnectionID runs SELECT CONNECTION_ID() directly on a driver connection.
+func sessionConnectionID(ctx context.Context, conn driver.Conn) (int64, error) {
+	queryer, ok := conn.(driver.QueryerContext)
+	if !ok {
+		return 0, errors.New("driver connection does not support queries")
+	}
+	rows, err := queryer.QueryContext(ctx, "SELECT CONNECTION_ID()", nil)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		return 0, err
+	}
+	switch v := dest[0].(type) {
+	case int64:
+		return v, nil
+	case uint64:
+		return int64(v), nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	}
+	return 0, fmt.Errorf("unexpected connection id type %T", dest[0])
+}
+
+// trackedConn forwards to the driver connection and removes its id from the tracker when closed.
+// It implements the optional database/sql driver interfaces of the MySQL driver, so wrapping
+// does not change how database/sql uses the connection.
+type trackedConn struct {
+	driver.Conn
+	id       int64
+	sessions *sessionTracker
+
+	// expires is the end of the jittered lifetime of the connection, zero without jitter.
+	expires time.Time
+	clock   Clock
+}
+
+// expired reports whether the connection outlived its jittered lifetime.
+func (c *trackedConn) expired() bool {
+	return !c.expires.IsZero() && !c.clock.Now().Before(c.expires)
+}
+
+func (c *trackedConn) Close() error {
+	c.sessions.remove(c.id)
+	return c.Conn.Close()
+}
+
+func (c *trackedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *trackedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *trackedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := c.Conn.(driver.ExecerContext); ok {
+		return e.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *trackedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *trackedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *trackedConn) ResetSession(ctx context.Context) error {
+	if c.expired() {
+		return driver.ErrBadConn
+	}
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *trackedConn) IsValid() bool {
+	if c.expired() {
+		return false
+	}
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *trackedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
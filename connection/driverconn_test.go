@@ -0,0 +1,116 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/hemant-dhiman/MySQL-connection/constants"
+	"io"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeConnector opens fakeConns with increasing connection ids.
+type fakeConnector struct {
+	next atomic.Int64
+}
+
+func (c *fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{id: c.next.Add(1)}, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+type fakeConn struct {
+	id int64
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &fakeRows{values: []driver.Value{c.id}}, nil
+}
+
+type fakeRows struct {
+	values []driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"CONNECTION_ID()"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func TestTrackedConnectorRecordsSessions(t *testing.T) {
+	sessions := &sessionTracker{}
+	pool := sql.OpenDB(&trackedConnector{connector: &fakeConnector{}, sessions: sessions})
+	defer pool.Close()
+	ctx := context.Background()
+
+	first, err := pool.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	second, err := pool.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	if got := sessions.list(); !reflect.DeepEqual(got, []int64{1, 2}) {
+		t.Fatalf("Expected sessions [1 2], got %v", got)
+	}
+
+	// Returning a connection to the pool keeps the session; closing the pool ends it.
+	_ = first.Close()
+	_ = second.Close()
+	if got := sessions.list(); len(got) != 2 {
+		t.Fatalf("Expected idle sessions to stay tracked, got %v", got)
+	}
+	_ = pool.Close()
+	if got := sessions.list(); len(got) != 0 {
+		t.Fatalf("Expected no sessions after closing the pool, got %v", got)
+	}
+}
+
+func TestConnectionAttributes(t *testing.T) {
+	if got := connectionAttributes("", "primary_db", DBConfig{ProgramName: "billing,api:v2", PodName: "pod-1"}); got != "program_name:billing_api_v2,pod:pod-1,mysqlconn_pool:primary_db" {
+		t.Fatalf("Unexpected attributes %q", got)
+	}
+	if got := connectionAttributes("team:payments", "replica", DBConfig{ProgramName: "svc", PodName: "pod-1"}); got != "team:payments,program_name:svc,pod:pod-1,mysqlconn_pool:replica" {
+		t.Fatalf("Unexpected attributes %q", got)
+	}
+	config := DBConfig{ProgramName: "api", ServiceName: "billing", PodName: "billing-7f9c", ServiceVersion: "v1.4.2"}
+	if got := connectionAttributes("", "primary_db", config); got != "program_name:api,service:billing,pod:billing-7f9c,version:v1.4.2,mysqlconn_pool:primary_db" {
+		t.Fatalf("Unexpected attributes %q", got)
+	}
+	t.Setenv(constants.ENV_MYSQLCONN_SERVICE, "env-service")
+	t.Setenv(constants.ENV_MYSQLCONN_POD, "env-pod")
+	if got := connectionAttributes("", "db", DBConfig{ProgramName: "api", ServiceVersion: "v1"}); got != "program_name:api,service:env-service,pod:env-pod,version:v1,mysqlconn_pool:db" {
+		t.Fatalf("Unexpected attributes from the environment %q", got)
+	}
+	if _, err := newTestFactory().OwnedConnectionIDs("missing_db"); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
@@ -0,0 +1,188 @@
+package connection
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"os"
+	"sync"
+)
+
+// Names under which the statement capture of DryRunDB is registered.
+const (
+	statementCapturePluginName = "mysqlconn:statement_capture"
+	statementCaptureSetting    = "mysqlconn:statement_capture"
+)
+
+// CapturedStatement is a statement built by a DryRunDB handle or registered with RegisterQuery.
+type CapturedStatement struct {
+	// Name is the name of a query registered with RegisterQuery, empty for DryRunDB captures.
+	Name string `json:"name,omitempty"`
+
+	// SQL is the statement text with placeholders, and Vars its arguments.
+	SQL  string        `json:"sql"`
+	Vars []interface{} `json:"vars,omitempty"`
+
+	// Table is the table of the model of the statement, empty for Raw and Exec.
+	Table string `json:"table,omitempty"`
+}
+
+// StatementCapture collects the statements built by the handle of DryRunDB, in order.
+type StatementCapture struct {
+	mutex      sync.Mutex
+	statements []CapturedStatement
+}
+
+// Statements returns the statements captured so far.
+func (c *StatementCapture) Statements() []CapturedStatement {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]CapturedStatement(nil), c.statements...)
+}
+
+// SQL returns the text of the statements captured so far.
+func (c *StatementCapture) SQL() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	queries := make([]string, len(c.statements))
+	for i, statement := range c.statements {
+		queries[i] = statement.SQL
+	}
+	return queries
+}
+
+// Reset drops the statements captured so far.
+func (c *StatementCapture) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.statements = nil
+}
+
+// Save writes the statements captured so far to path as JSON lines, for checking them against a schema
+// outside the test run, e.g. with "mysqlconn sqlcheck".
+func (c *StatementCapture) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, statement := range c.Statements() {
+		if err := encoder.Encode(statement); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("failed to write statements %q: %w", path, err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write statements %q: %w", path, err)
+	}
+	return file.Close()
+}
+
+// LoadStatements reads statements written by StatementCapture.Save. Like LoadRecording, numeric
+// arguments are read back as float64 and times as strings.
+func LoadStatements(path string) ([]CapturedStatement, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var statements []CapturedStatement
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var statement CapturedStatement
+		if err := decoder.Decode(&statement); err != nil {
+			return nil, fmt.Errorf("failed to read statements %q: %w", path, err)
+		}
+		statements = append(statements, statement)
+	}
+	return statements, nil
+}
+
+// DryRunDB returns a handle on a managed connection in GORM DryRun mode: statements are built, with the
+// callbacks and scopes of the connection, and captured instead of being sent to the server.
+//
+// Parameters:
+// - name: The name of the managed connection. Its schema is not read; only its dialect and plugins are used.
+//
+// Returns:
+// - *gorm.DB: The handle. Every handle derived from it (Where, WithContext, Session, ...) builds and
+// captures statements the same way.
+// - *StatementCapture: The statements built through the handle, in order.
+// - error: An error if the connection does not exist.
+//
+// Notes:
+//   - Queries return no rows, so code reading a result and issuing statements based on it follows the
+//     path of an empty result.
+//   - Transactions started on the handle do run BEGIN and COMMIT on the server; build statements outside
+//     transactions.
+//
+// Example Usage:
+//
+//	db, capture, err := connection.GetConnectionManager().DryRunDB("primary_db")
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	NewOrderRepo(db).ArchiveBefore(ctx, cutoff)
+//	for _, statement := range capture.Statements() {
+//		t.Log(statement.SQL, statement.Vars)
+//	}
+func (f *ConnectionManager) DryRunDB(name string) (*gorm.DB, *StatementCapture, error) {
+	f.mutex.Lock()
+	db, exists := f.connections[name]
+	f.mutex.Unlock()
+	if !exists {
+		return nil, nil, errNotFound(name, OpDryRun)
+	}
+	capture := &StatementCapture{}
+	return db.Session(&gorm.Session{NewDB: true, DryRun: true}).Set(statementCaptureSetting, capture), capture, nil
+}
+
+// statementCapturePlugin adds the statements built by DryRunDB handles to their StatementCapture. It is
+// installed on every connection and does nothing for other handles.
+type statementCapturePlugin struct{}
+
+func (statementCapturePlugin) Name() string {
+	return statementCapturePluginName
+}
+
+func (p statementCapturePlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for operation, register := range map[string]func(name string, fn func(*gorm.DB)) error{
+		"create": cb.Create().After("*").Register,
+		"query":  cb.Query().After("*").Register,
+		"update": cb.Update().After("*").Register,
+		"delete": cb.Delete().After("*").Register,
+		"row":    cb.Row().After("*").Register,
+		"raw":    cb.Raw().After("*").Register,
+	} {
+		if err := register(statementCapturePluginName+"_"+operation, p.capture); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (statementCapturePlugin) capture(db *gorm.DB) {
+	if !db.DryRun {
+		return
+	}
+	value, ok := db.Get(statementCaptureSetting)
+	if !ok {
+		return
+	}
+	capture, _ := value.(*StatementCapture)
+	if capture == nil || db.Statement.SQL.Len() == 0 {
+		return
+	}
+	capture.mutex.Lock()
+	defer capture.mutex.Unlock()
+	capture.statements = append(capture.statements, CapturedStatement{
+		SQL:   db.Statement.SQL.String(),
+		Vars:  append([]interface{}(nil), db.Statement.Vars...),
+		Table: db.Statement.Table,
+	})
+}
@@ -0,0 +1,49 @@
+package connection
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDryRunDB(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	if _, _, err := factory.DryRunDB("missing_db"); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+	db, capture, err := factory.DryRunDB("primary_db")
+	if err != nil {
+		t.Fatalf("DryRunDB failed: %v", err)
+	}
+
+	var users []fakeTestUser
+	db.WithContext(context.Background()).Where("active = ?", true).Find(&users)
+	db.Create(&fakeTestUser{Name: "dave", Email: "dave@example.com"})
+	db.Exec("DELETE FROM fake_test_users WHERE id = ?", 2)
+
+	statements := capture.Statements()
+	if len(statements) != 3 {
+		t.Fatalf("Expected 3 captured statements, got %+v", statements)
+	}
+	if !strings.HasPrefix(statements[0].SQL, "SELECT * FROM `fake_test_users` WHERE active = ?") || statements[0].Table != "fake_test_users" ||
+		len(statements[0].Vars) != 1 || statements[0].Vars[0] != true {
+		t.Fatalf("Unexpected query capture %+v", statements[0])
+	}
+	if !strings.HasPrefix(statements[1].SQL, "INSERT INTO `fake_test_users`") || statements[2].SQL != "DELETE FROM fake_test_users WHERE id = ?" {
+		t.Fatalf("Unexpected captures %v", capture.SQL())
+	}
+
+	live, _ := factory.GetDB("primary_db")
+	var count int64
+	live.Model(&fakeTestUser{}).Count(&count)
+	if count != 3 {
+		t.Fatalf("Expected the dry run to leave the 3 users, got %d", count)
+	}
+	if len(capture.Statements()) != 3 {
+		t.Fatal("Expected statements of other handles not to be captured")
+	}
+	capture.Reset()
+	if len(capture.SQL()) != 0 {
+		t.Fatal("Expected no statement after Reset")
+	}
+}
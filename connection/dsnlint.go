@@ -0,0 +1,93 @@
+package connection
+
+import (
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// DSNWarning describes a dangerous setting of a data source name, as reported by LintDSN.
+type DSNWarning struct {
+	// Param is the DSN parameter at fault, e.g. "allowAllFiles", or "" when the DSN does not parse.
+	Param   string
+	Message string
+}
+
+func (w DSNWarning) String() string {
+	if w.Param == "" {
+		return w.Message
+	}
+	return w.Param + ": " + w.Message
+}
+
+// parseDSN parses dsn like mysqldriver.ParseDSN, turning a panic of the driver on malformed input
+// into an error.
+func parseDSN(dsn string) (cfg *mysqldriver.Config, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			cfg, err = nil, fmt.Errorf("malformed data source name: %v", r)
+		}
+	}()
+	return mysqldriver.ParseDSN(dsn)
+}
+
+// LintDSN checks a data source name for settings that are dangerous in production.
+//
+// Parameters:
+// - dsn: The data source name, in the format of the MySQL driver. It is never included in the warnings,
+// so the password it may hold does not leak into logs.
+//
+// Returns:
+// - []DSNWarning: The dangerous settings found, none for a safe DSN. A DSN that does not parse yields
+// a single warning with an empty Param.
+//
+// Behavior:
+//   - allowAllFiles=true lets the server read any file of the client through LOAD DATA LOCAL INFILE.
+//   - A missing timeout, readTimeout or writeTimeout lets a dead network hang dials or statements
+//     until the operating system gives up, usually many minutes.
+//   - allowCleartextPasswords=true sends the password in clear text unless the connection is TLS
+//     without fallback to plain text (tls=preferred falls back) or goes through a unix socket.
+//   - interpolateParams=true with multiStatements=true turns an escaping flaw into stacked statements.
+//
+// Example Usage:
+//
+//	for _, warning := range connection.LintDSN(os.Getenv("ORDERS_DSN")) {
+//		log.Printf("orders DSN: %s", warning)
+//	}
+func LintDSN(dsn string) []DSNWarning {
+	cfg, err := parseDSN(dsn)
+	if err != nil {
+		return []DSNWarning{{Message: fmt.Sprintf("the data source name does not parse: %v", err)}}
+	}
+
+	var warnings []DSNWarning
+	if cfg.AllowAllFiles {
+		warnings = append(warnings, DSNWarning{Param: "allowAllFiles",
+			Message: "the server may read any file of the client with LOAD DATA LOCAL INFILE; register the allowed files with mysql.RegisterLocalFile instead"})
+	}
+	for _, timeout := range []struct {
+		param string
+		set   bool
+		what  string
+	}{
+		{"timeout", cfg.Timeout > 0, "dials"},
+		{"readTimeout", cfg.ReadTimeout > 0, "reads"},
+		{"writeTimeout", cfg.WriteTimeout > 0, "writes"},
+	} {
+		if !timeout.set {
+			warnings = append(warnings, DSNWarning{Param: timeout.param,
+				Message: fmt.Sprintf("not set; %s hang when the server or the network stops responding, until the operating system gives up", timeout.what)})
+		}
+	}
+	if cfg.AllowCleartextPasswords && cfg.Net != "unix" && (cfg.TLS == nil || cfg.AllowFallbackToPlaintext) {
+		message := "the password is sent in clear text over a connection without TLS"
+		if cfg.AllowFallbackToPlaintext {
+			message = fmt.Sprintf("the password is sent in clear text when tls=%s falls back to an unencrypted connection", cfg.TLSConfig)
+		}
+		warnings = append(warnings, DSNWarning{Param: "allowCleartextPasswords", Message: message})
+	}
+	if cfg.InterpolateParams && cfg.MultiStatements {
+		warnings = append(warnings, DSNWarning{Param: "multiStatements",
+			Message: "with interpolateParams, arguments are escaped into the SQL text and a flaw in the escaping lets them add statements"})
+	}
+	return warnings
+}
@@ -0,0 +1,67 @@
+package connection
+
+import (
+	"strings"
+	"testing"
+)
+
+func dsnParams(warnings []DSNWarning) string {
+	params := make([]string, len(warnings))
+	for i, w := range warnings {
+		params[i] = w.Param
+	}
+	return strings.Join(params, ",")
+}
+
+func TestLintDSN(t *testing.T) {
+	const timeouts = "timeout=5s&readTimeout=30s&writeTimeout=30s"
+	cases := []struct {
+		dsn    string
+		params string
+	}{
+		{"app:secret@tcp(db:3306)/orders?" + timeouts, ""},
+		{"app:secret@tcp(db:3306)/orders", "timeout,readTimeout,writeTimeout"},
+		{"app:secret@tcp(db:3306)/orders?allowAllFiles=true&" + timeouts, "allowAllFiles"},
+		{"app:secret@tcp(db:3306)/orders?allowCleartextPasswords=true&" + timeouts, "allowCleartextPasswords"},
+		{"app:secret@tcp(db:3306)/orders?allowCleartextPasswords=true&tls=preferred&" + timeouts, "allowCleartextPasswords"},
+		{"app:secret@tcp(db:3306)/orders?allowCleartextPasswords=true&tls=true&" + timeouts, ""},
+		{"app:secret@unix(/run/mysqld.sock)/orders?allowCleartextPasswords=true&" + timeouts, ""},
+		{"app:secret@tcp(db:3306)/orders?interpolateParams=true&" + timeouts, ""},
+		{"app:secret@tcp(db:3306)/orders?interpolateParams=true&multiStatements=true&" + timeouts, "multiStatements"},
+	}
+	for _, c := range cases {
+		if params := dsnParams(LintDSN(c.dsn)); params != c.params {
+			t.Errorf("LintDSN(%q): expected warnings for %q, got %q", c.dsn, c.params, params)
+		}
+	}
+
+	warnings := LintDSN("app:secret@tcp(db:3306)orders")
+	if len(warnings) != 1 || warnings[0].Param != "" {
+		t.Fatalf("Expected a single parse warning, got %v", warnings)
+	}
+	for _, w := range LintDSN("app:secret@tcp(db:3306)/orders?allowAllFiles=true") {
+		if strings.Contains(w.String(), "secret") {
+			t.Fatalf("Expected the password not to be reported, got %q", w)
+		}
+	}
+}
+
+func FuzzLintDSN(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"app:secret@tcp(db:3306)/orders?timeout=5s",
+		"app@unix(/tmp/mysql.sock)/orders?allowCleartextPasswords=true",
+		"/?interpolateParams=true&multiStatements=true",
+		"a@b(c)/d?e=f&g",
+		"@tcp([::1]:3306)/?tls=preferred",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, dsn string) {
+		for _, w := range LintDSN(dsn) {
+			if w.Message == "" {
+				t.Fatalf("Expected a message for every warning of %q", dsn)
+			}
+		}
+	})
+}
@@ -0,0 +1,242 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/hemant-dhiman/MySQL-connection/constants"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDSNNotFound is returned by a DSNSource that has no data source name for a connection. A DSNChain
+// then asks the next source.
+var ErrDSNNotFound = errors.New("no data source name")
+
+// DSNSource supplies the data source names of connections, e.g. from flags, the environment, files or a
+// secret store. Sources return ErrDSNNotFound for connections they know nothing about.
+type DSNSource interface {
+	DSN(ctx context.Context, name string) (string, error)
+}
+
+// DSNSourceFunc adapts a function to DSNSource.
+type DSNSourceFunc func(ctx context.Context, name string) (string, error)
+
+func (fn DSNSourceFunc) DSN(ctx context.Context, name string) (string, error) {
+	return fn(ctx, name)
+}
+
+// StaticDSN serves fixed data source names. Empty values count as missing.
+func StaticDSN(dsns map[string]string) DSNSource {
+	return DSNSourceFunc(func(_ context.Context, name string) (string, error) {
+		if dsn := dsns[name]; dsn != "" {
+			return dsn, nil
+		}
+		return "", ErrDSNNotFound
+	})
+}
+
+// FlagDSN serves the value of the flag prefix+name of fs, e.g. -dsn-orders with the prefix "dsn-", or of
+// flag.CommandLine when fs is nil. Only flags set on the command line count, so that defaults do not hide
+// the sources after it. The flags must be defined and parsed by the caller.
+func FlagDSN(fs *flag.FlagSet, prefix string) DSNSource {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	return DSNSourceFunc(func(_ context.Context, name string) (string, error) {
+		dsn := ""
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == prefix+name {
+				dsn = f.Value.String()
+			}
+		})
+		if dsn == "" {
+			return "", ErrDSNNotFound
+		}
+		return dsn, nil
+	})
+}
+
+// EnvDSN serves the environment variable prefix+NAME, where NAME is the connection name in upper case with
+// characters other than letters and digits replaced by "_". An empty prefix stands for MYSQLCONN_DSN_, so
+// the connection "orders-ro" is read from MYSQLCONN_DSN_ORDERS_RO.
+func EnvDSN(prefix string) DSNSource {
+	if prefix == "" {
+		prefix = constants.ENV_MYSQLCONN_DSN_PREFIX
+	}
+	return DSNSourceFunc(func(_ context.Context, name string) (string, error) {
+		if dsn := os.Getenv(prefix + envName(name)); dsn != "" {
+			return dsn, nil
+		}
+		return "", ErrDSNNotFound
+	})
+}
+
+// envName is the environment variable suffix of a connection name.
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// FileDSN serves the content of the file named after the connection in dir, without surrounding white
+// space, as laid out by Kubernetes and Docker secret mounts.
+func FileDSN(dir string) DSNSource {
+	return DSNSourceFunc(func(_ context.Context, name string) (string, error) {
+		if name == "" || name != filepath.Base(name) {
+			return "", fmt.Errorf("invalid connection name %q for a file data source", name)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			return "", ErrDSNNotFound
+		}
+		if err != nil {
+			return "", err
+		}
+		if dsn := strings.TrimSpace(string(data)); dsn != "" {
+			return dsn, nil
+		}
+		return "", ErrDSNNotFound
+	})
+}
+
+// SecretStore looks secrets up by key, e.g. in Vault or a cloud secret manager. It returns ErrDSNNotFound
+// when the key does not exist.
+type SecretStore interface {
+	Lookup(ctx context.Context, key string) (*Secret, error)
+}
+
+// SecretStoreFunc adapts a function to SecretStore.
+type SecretStoreFunc func(ctx context.Context, key string) (*Secret, error)
+
+func (fn SecretStoreFunc) Lookup(ctx context.Context, key string) (*Secret, error) {
+	return fn(ctx, key)
+}
+
+// SecretStoreDSN serves the secret prefix+name of store, e.g. "database/orders" with the prefix "database/".
+func SecretStoreDSN(store SecretStore, prefix string) DSNSource {
+	return DSNSourceFunc(func(ctx context.Context, name string) (string, error) {
+		secret, err := store.Lookup(ctx, prefix+name)
+		if err != nil {
+			return "", err
+		}
+		dsn := ""
+		if err := secret.Use(func(value []byte) error {
+			dsn = string(value)
+			return nil
+		}); err != nil {
+			return "", err
+		}
+		if dsn == "" {
+			return "", ErrDSNNotFound
+		}
+		return dsn, nil
+	})
+}
+
+// DSNChain resolves data source names from sources in precedence order and caches them.
+type DSNChain struct {
+	sources []DSNSource
+	ttl     time.Duration
+
+	mutex sync.Mutex
+	cache map[string]cachedDSN
+}
+
+type cachedDSN struct {
+	dsn     string
+	expires time.Time
+}
+
+// NewDSNChain returns a chain asking sources in order, the first one having a data source name for a
+// connection winning.
+//
+// Parameters:
+// - ttl: How long resolved names are cached. Zero or less caches them until Invalidate.
+// - sources: The sources, highest precedence first.
+//
+// Returns:
+// - *DSNChain: The chain. It is itself a DSNSource.
+//
+// Behavior:
+// 1. A source returning ErrDSNNotFound passes the connection on to the next source.
+// 2. Any other error stops the resolution: a failing secret store must not silently fall back to a
+// development data source further down the chain.
+// 3. Errors are not cached.
+//
+// Example Usage:
+//
+//	// -dsn-orders in local runs, MYSQLCONN_DSN_ORDERS in dev, /etc/mysqlconn/orders in staging, Vault in prod.
+//	chain := connection.NewDSNChain(10*time.Minute,
+//		connection.FlagDSN(nil, "dsn-"),
+//		connection.EnvDSN(""),
+//		connection.FileDSN("/etc/mysqlconn"),
+//		connection.SecretStoreDSN(vaultStore, "database/"),
+//	)
+//	conn, err := connection.GetConnectionManager().ConnectFromSource(ctx, "orders", chain, config)
+func NewDSNChain(ttl time.Duration, sources ...DSNSource) *DSNChain {
+	return &DSNChain{sources: sources, ttl: ttl, cache: make(map[string]cachedDSN)}
+}
+
+// DSN returns the data source name of a connection from the cache or the first source that has one.
+func (c *DSNChain) DSN(ctx context.Context, name string) (string, error) {
+	c.mutex.Lock()
+	cached, ok := c.cache[name]
+	c.mutex.Unlock()
+	if ok && (cached.expires.IsZero() || time.Now().Before(cached.expires)) {
+		return cached.dsn, nil
+	}
+
+	for i, source := range c.sources {
+		dsn, err := source.DSN(ctx, name)
+		if errors.Is(err, ErrDSNNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("data source name source %d of %q: %w", i, name, err)
+		}
+		entry := cachedDSN{dsn: dsn}
+		if c.ttl > 0 {
+			entry.expires = time.Now().Add(c.ttl)
+		}
+		c.mutex.Lock()
+		c.cache[name] = entry
+		c.mutex.Unlock()
+		return dsn, nil
+	}
+	return "", fmt.Errorf("%w for %q in %d sources", ErrDSNNotFound, name, len(c.sources))
+}
+
+// Invalidate drops the cached data source names of names, or of all connections when none is given.
+func (c *DSNChain) Invalidate(names ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(names) == 0 {
+		c.cache = make(map[string]cachedDSN)
+		return
+	}
+	for _, name := range names {
+		delete(c.cache, name)
+	}
+}
+
+// ConnectFromSource is ConnectContext with the data source name of config resolved from source, so the
+// same binary connects in every environment without code changes.
+func (f *ConnectionManager) ConnectFromSource(ctx context.Context, name string, source DSNSource, config DBConfig) (*Connection, error) {
+	dsn, err := source.DSN(ctx, name)
+	if err != nil {
+		return nil, connError(name, OpInit, fmt.Errorf("failed to resolve data source name: %w", err))
+	}
+	config.DataSourceName = dsn
+	return f.ConnectContext(ctx, name, config)
+}
@@ -0,0 +1,83 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDSNChainPrecedence(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("dsn-orders", "flag-default", "")
+	fs.String("dsn-users", "", "")
+	if err := fs.Parse([]string{"-dsn-users=flag-users"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	t.Setenv("TEST_DSN_ORDERS", "env-orders")
+	t.Setenv("TEST_DSN_ORDERS_RO", "env-orders-ro")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "reports"), []byte("file-reports\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	store := SecretStoreFunc(func(ctx context.Context, key string) (*Secret, error) {
+		if key == "db/audit" {
+			return NewSecret([]byte("vault-audit")), nil
+		}
+		return nil, ErrDSNNotFound
+	})
+
+	chain := NewDSNChain(0, FlagDSN(fs, "dsn-"), EnvDSN("TEST_DSN_"), FileDSN(dir), SecretStoreDSN(store, "db/"))
+	for name, want := range map[string]string{
+		"users":     "flag-users",
+		"orders":    "env-orders",
+		"orders-ro": "env-orders-ro",
+		"reports":   "file-reports",
+		"audit":     "vault-audit",
+	} {
+		if got, err := chain.DSN(context.Background(), name); err != nil || got != want {
+			t.Errorf("DSN(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := chain.DSN(context.Background(), "missing"); !errors.Is(err, ErrDSNNotFound) {
+		t.Fatalf("Expected ErrDSNNotFound, got %v", err)
+	}
+}
+
+func TestDSNChainCacheAndErrors(t *testing.T) {
+	calls := 0
+	value := "first"
+	source := DSNSourceFunc(func(ctx context.Context, name string) (string, error) {
+		calls++
+		return value, nil
+	})
+	chain := NewDSNChain(0, source)
+	_, _ = chain.DSN(context.Background(), "orders")
+	value = "second"
+	if got, _ := chain.DSN(context.Background(), "orders"); got != "first" || calls != 1 {
+		t.Fatalf("Expected the cached name after one call, got %q after %d calls", got, calls)
+	}
+	chain.Invalidate("orders")
+	if got, _ := chain.DSN(context.Background(), "orders"); got != "second" {
+		t.Fatalf("Expected the name to be resolved again after Invalidate, got %q", got)
+	}
+
+	unavailable := errors.New("vault sealed")
+	failing := DSNSourceFunc(func(ctx context.Context, name string) (string, error) { return "", unavailable })
+	chain = NewDSNChain(0, failing, StaticDSN(map[string]string{"orders": "fallback"}))
+	if _, err := chain.DSN(context.Background(), "orders"); !errors.Is(err, unavailable) {
+		t.Fatalf("Expected the source error to stop the chain, got %v", err)
+	}
+}
+
+func TestConnectFromSourceMissing(t *testing.T) {
+	factory := newTestFactory()
+	_, err := factory.ConnectFromSource(context.Background(), "orders", NewDSNChain(0), DBConfig{})
+	var connErr *ConnError
+	if !errors.Is(err, ErrDSNNotFound) || !errors.As(err, &connErr) || connErr.Name != "orders" {
+		t.Fatalf("Expected a ConnError wrapping ErrDSNNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// electionInterval is how often a candidate retries the election lock, and how often a leader
+// verifies that it still holds it.
+const electionInterval = 2 * time.Second
+
+// ErrResigned is returned by Leadership.Await once the campaign was stopped by Resign or by
+// cancellation of the context passed to Elect.
+var ErrResigned = errors.New("leadership campaign stopped")
+
+// Leadership is a running campaign for an election key. It is returned by Elect.
+type Leadership interface {
+	// IsLeader reports whether this process currently holds the election.
+	IsLeader() bool
+
+	// Await blocks until this process is leader, the campaign stops (ErrResigned) or ctx is done.
+	Await(ctx context.Context) error
+
+	// Resign stops the campaign and releases the leadership if it is held.
+	Resign() error
+}
+
+// Elect starts campaigning for electionKey on the named connection, so singleton background workers
+// running on several replicas of a service can coordinate through the database they already use.
+//
+// Parameters:
+//   - ctx: Bounds the campaign. Cancelling it resigns.
+//   - name: The name of the managed connection used for the election.
+//   - electionKey: Identifies the election. All candidates of one election use the same key.
+//
+// Returns:
+//   - Leadership: The running campaign. Check IsLeader before doing leader-only work, or block with Await.
+//   - error: Returns an error if the connection does not exist or the first attempt fails.
+//
+// Behavior:
+// 1. Leadership is a MySQL named lock (GET_LOCK) held by a dedicated session taken from the pool.
+// The server releases it when that session ends, so a crashed leader cannot hold the election forever.
+// 2. Candidates retry every electionInterval; the leader checks its session at the same interval with
+// IS_USED_LOCK and steps down as soon as the lock or the session is lost.
+// 3. The first attempt is made before Elect returns.
+//
+// Notes:
+//   - The leader keeps one connection of the pool busy for as long as it leads.
+//   - Leadership can be lost at any time (network partition, server restart); long running leader work
+//     should check IsLeader regularly.
+//
+// Example Usage:
+//
+//	leadership, err := connection.GetConnectionManager().Elect(ctx, "primary_db", "billing-worker")
+//	if err != nil {
+//		log.Fatalf("Failed to start election: %v", err)
+//	}
+//	defer leadership.Resign()
+//	for leadership.Await(ctx) == nil {
+//		runBillingCycle(ctx, leadership.IsLeader)
+//	}
+func (f *ConnectionManager) Elect(ctx context.Context, name, electionKey string) (Leadership, error) {
+	if electionKey == "" {
+		return nil, errors.New("election key must not be empty")
+	}
+	db, err := f.GetDB(name)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve database handle for %q: %w", name, err)
+	}
+
+	l := &lockLeadership{
+		sqlDB:   sqlDB,
+		clock:   f.clock(),
+		name:    name,
+		lock:    namedLock("mysqlconn:leader:", electionKey),
+		elected: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := l.step(ctx); err != nil {
+		return nil, err
+	}
+
+	ctx, l.cancel = context.WithCancel(ctx)
+	go l.campaign(ctx)
+	return l, nil
+}
+
+// lockLeadership implements Leadership with a GET_LOCK held by a dedicated session.
+type lockLeadership struct {
+	sqlDB  *sql.DB
+	clock  Clock
+	name   string
+	lock   string
+	leader atomic.Bool
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mutex   sync.Mutex
+	session *sql.Conn
+	// elected is closed while this process leads and replaced by a fresh channel when leadership is lost.
+	elected chan struct{}
+}
+
+func (l *lockLeadership) IsLeader() bool {
+	return l.leader.Load()
+}
+
+func (l *lockLeadership) Await(ctx context.Context) error {
+	l.mutex.Lock()
+	elected := l.elected
+	l.mutex.Unlock()
+
+	select {
+	case <-elected:
+		return nil
+	case <-l.done:
+		return ErrResigned
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *lockLeadership) Resign() error {
+	l.cancel()
+	<-l.done
+	return nil
+}
+
+// campaign runs step every electionInterval until ctx is cancelled, then releases the lock.
+func (l *lockLeadership) campaign(ctx context.Context) {
+	defer close(l.done)
+	defer l.stepDown()
+
+	ticker := l.clock.NewTicker(electionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+		if err := l.step(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Election %q on %q: %v", l.lock, l.name, err)
+		}
+	}
+}
+
+// step tries to acquire the lock when not leading, and verifies it when leading.
+func (l *lockLeadership) step(ctx context.Context) error {
+	l.mutex.Lock()
+	session := l.session
+	l.mutex.Unlock()
+
+	if session != nil {
+		var held sql.NullInt64
+		err := session.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?) = CONNECTION_ID()", l.lock).Scan(&held)
+		if err == nil && held.Valid && held.Int64 == 1 {
+			return nil
+		}
+		log.Printf("Lost leadership of %q on %q.", l.lock, l.name)
+		l.stepDown()
+		return err
+	}
+
+	session, err := l.sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	var acquired sql.NullInt64
+	if err := session.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", l.lock).Scan(&acquired); err != nil {
+		session.Close()
+		return fmt.Errorf("failed to acquire lock %q: %w", l.lock, err)
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		return session.Close()
+	}
+
+	l.mutex.Lock()
+	l.session = session
+	l.leader.Store(true)
+	close(l.elected)
+	l.mutex.Unlock()
+	log.Printf("Acquired leadership of %q on %q.", l.lock, l.name)
+	return nil
+}
+
+// stepDown gives up the lock and returns the session to the pool.
+func (l *lockLeadership) stepDown() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.session == nil {
+		return
+	}
+	// Release explicitly so a session returned to the pool never carries the lock. A fresh context is
+	// used because the campaign context is already cancelled when resigning.
+	ctx, cancel := context.WithTimeout(context.Background(), electionInterval)
+	defer cancel()
+	_, _ = l.session.ExecContext(ctx, "DO RELEASE_LOCK(?)", l.lock)
+	_ = l.session.Close()
+	l.session = nil
+	l.leader.Store(false)
+	l.elected = make(chan struct{})
+}
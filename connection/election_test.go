@@ -0,0 +1,194 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockServer is a connector whose sessions share the named locks of one server: GET_LOCK,
+// IS_USED_LOCK and RELEASE_LOCK, released when the session holding them ends.
+type lockServer struct {
+	mutex    sync.Mutex
+	sessions int
+	holder   int
+}
+
+func (s *lockServer) Connect(ctx context.Context) (driver.Conn, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sessions++
+	return &lockSession{server: s, id: s.sessions}, nil
+}
+
+func (s *lockServer) Driver() driver.Driver {
+	return nil
+}
+
+// steal gives the lock to a session of another client, e.g. after the leader's session was killed.
+func (s *lockServer) steal() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.holder = -1
+}
+
+func (s *lockServer) free() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.holder = 0
+}
+
+func (s *lockServer) lockHolder() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.holder
+}
+
+type lockSession struct {
+	server *lockServer
+	id     int
+}
+
+func (c *lockSession) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *lockSession) Close() error {
+	c.release()
+	return nil
+}
+
+func (c *lockSession) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+func (c *lockSession) release() {
+	c.server.mutex.Lock()
+	defer c.server.mutex.Unlock()
+	if c.server.holder == c.id {
+		c.server.holder = 0
+	}
+}
+
+func (c *lockSession) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if strings.Contains(query, "RELEASE_LOCK") {
+		c.release()
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (c *lockSession) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.server.mutex.Lock()
+	defer c.server.mutex.Unlock()
+	var value int64
+	switch {
+	case strings.Contains(query, "GET_LOCK"):
+		if c.server.holder == 0 {
+			c.server.holder = c.id
+		}
+		if c.server.holder == c.id {
+			value = 1
+		}
+	case strings.Contains(query, "IS_USED_LOCK"):
+		if c.server.holder == c.id {
+			value = 1
+		}
+	}
+	return &lockRows{values: []driver.Value{value}}, nil
+}
+
+type lockRows struct {
+	values []driver.Value
+}
+
+func (r *lockRows) Columns() []string {
+	return []string{"result"}
+}
+
+func (r *lockRows) Close() error {
+	return nil
+}
+
+func (r *lockRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], nil
+	return nil
+}
+
+func TestElectAcquireLoseResign(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1)
+	factory := newTestFactory().WithClock(clock)
+	server := &lockServer{}
+	factory.connections["primary_db"] = newConnectorDB(t, server)
+	ctx := context.Background()
+
+	first, err := factory.Elect(ctx, "primary_db", "billing-worker")
+	if err != nil {
+		t.Fatalf("Elect failed: %v", err)
+	}
+	if !first.IsLeader() || first.Await(ctx) != nil {
+		t.Fatal("Expected the first candidate to acquire the leadership before Elect returns")
+	}
+	second, err := factory.Elect(ctx, "primary_db", "billing-worker")
+	if err != nil {
+		t.Fatalf("Elect failed: %v", err)
+	}
+	if second.IsLeader() {
+		t.Fatal("Expected a single leader")
+	}
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := second.Await(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected Await of a candidate to wait, got %v", err)
+	}
+
+	// The lock is lost, e.g. the session was killed: the leader steps down at its next check.
+	server.steal()
+	waitFor(t, "the leader to step down", func() bool {
+		clock.Advance(electionInterval)
+		return !first.IsLeader()
+	})
+	if second.IsLeader() {
+		t.Fatal("Expected no leader while another client holds the lock")
+	}
+
+	if err := first.Resign(); err != nil {
+		t.Fatalf("Resign failed: %v", err)
+	}
+	if err := first.Await(ctx); !errors.Is(err, ErrResigned) {
+		t.Fatalf("Expected ErrResigned after Resign, got %v", err)
+	}
+
+	server.free()
+	waitFor(t, "the candidate to take over", func() bool {
+		clock.Advance(electionInterval)
+		return second.IsLeader()
+	})
+	if err := second.Await(ctx); err != nil {
+		t.Fatalf("Expected Await of the new leader to return, got %v", err)
+	}
+	if err := second.Resign(); err != nil {
+		t.Fatalf("Resign failed: %v", err)
+	}
+	if second.IsLeader() || server.lockHolder() != 0 {
+		t.Fatalf("Expected the lock to be released on Resign, held by session %d", server.lockHolder())
+	}
+}
+
+func TestElectEmptyKey(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["primary_db"] = newConnectorDB(t, &lockServer{})
+	if _, err := factory.Elect(context.Background(), "primary_db", ""); err == nil {
+		t.Fatal("Expected an error for an empty election key, got nil")
+	}
+	if _, err := factory.Elect(context.Background(), "missing_db", "billing-worker"); !errors.Is(err, ErrConnectionNotFound) {
+		t.Fatalf("Expected ErrConnectionNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package connection
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+)
+
+const errorLabelPluginName = "mysqlconn:error_labels"
+
+// errorLabelPlugin wraps the statement errors of one connection in a *ConnError naming the connection,
+// the statement type and the table (DBConfig.LabelErrors).
+type errorLabelPlugin struct {
+	name string
+}
+
+func (errorLabelPlugin) Name() string {
+	return errorLabelPluginName
+}
+
+func (p errorLabelPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	processors := []struct {
+		operation string
+		register  func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().After("*").Register},
+		{"query", cb.Query().After("*").Register},
+		{"update", cb.Update().After("*").Register},
+		{"delete", cb.Delete().After("*").Register},
+		{"row", cb.Row().After("*").Register},
+		{"raw", cb.Raw().After("*").Register},
+	}
+	for _, proc := range processors {
+		if err := proc.register(errorLabelPluginName+"_"+proc.operation, p.label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// label wraps the error of the statement, unless it is already labeled with the connection.
+func (p errorLabelPlugin) label(db *gorm.DB) {
+	var labeled *ConnError
+	if db.Error == nil || (errors.As(db.Error, &labeled) && labeled.Name == p.name) {
+		return
+	}
+	query := db.Statement.SQL.String()
+	kind := firstKeyword(query)
+	table := db.Statement.Table
+	if table == "" {
+		table = writtenTable(kind, query)
+	}
+	db.Error = &ConnError{Name: p.name, Op: OpQuery, Statement: kind, Table: strings.Trim(table, "`"), Err: db.Error}
+}
@@ -0,0 +1,43 @@
+package connection
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+)
+
+func TestLabelErrors(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	db, err := factory.GetDB("primary_db")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	if err := db.Use(errorLabelPlugin{name: "primary_db"}); err != nil {
+		t.Fatalf("Failed to install the plugin: %v", err)
+	}
+
+	err = db.First(&fakeTestUser{}, 99).Error
+	var labeled *ConnError
+	if !errors.As(err, &labeled) || labeled.Name != "primary_db" || labeled.Op != OpQuery {
+		t.Fatalf("Expected a labeled error, got %v", err)
+	}
+	if labeled.Statement != "SELECT" || labeled.Table != "fake_test_users" {
+		t.Fatalf("Unexpected label %+v", labeled)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected the label to keep gorm.ErrRecordNotFound, got %v", err)
+	}
+
+	err = db.Exec("INSERT INTO fake_test_users (id, name, email) VALUES (?, ?, ?)", 4, "dave", "alice@example.com").Error
+	if !errors.As(err, &labeled) || labeled.Statement != "INSERT" || labeled.Table != "fake_test_users" {
+		t.Fatalf("Expected a labeled INSERT error, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), `query "primary_db" (INSERT fake_test_users): `) {
+		t.Fatalf("Unexpected message %q", err.Error())
+	}
+
+	if err := db.Create(&fakeTestUser{Name: "erin", Email: "erin@example.com"}).Error; err != nil {
+		t.Fatalf("Expected no error for a successful statement, got %v", err)
+	}
+}
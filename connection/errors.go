@@ -0,0 +1,110 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Operations of ConnError.Op besides the lifecycle operations OpInit, OpReconnect and OpClose.
+const (
+	OpGet   = "get"
+	OpDrain = "drain"
+
+	// OpQuery is the operation of statement errors labeled with DBConfig.LabelErrors.
+	OpQuery = "query"
+)
+
+// Operations of ConnError.Op named after the method that failed.
+const (
+	OpAddStandby         = "add_standby"
+	OpAuditPrivileges    = "audit_privileges"
+	OpBeginTx            = "begin_tx"
+	OpBenchmark          = "benchmark"
+	OpBulkExec           = "bulk_exec"
+	OpCachedScalar       = "cached_scalar"
+	OpCallbacks          = "callbacks"
+	OpConfigWarnings     = "config_warnings"
+	OpDryRun             = "dry_run"
+	OpExecScript         = "exec_script"
+	OpFailover           = "failover"
+	OpFanOut             = "fan_out"
+	OpForecast           = "forecast"
+	OpGetConnection      = "get_connection"
+	OpHandle             = "handle"
+	OpHeartbeat          = "heartbeat"
+	OpLoadData           = "load_data"
+	OpMoveTenant         = "move_tenant"
+	OpOwnedConnectionIDs = "owned_connection_ids"
+	OpPipeline           = "pipeline"
+	OpProbeReplica       = "probe_replica"
+	OpReadHeartbeat      = "read_heartbeat"
+	OpReaderDB           = "reader_db"
+	OpRecord             = "record"
+	OpRemovePlugin       = "remove_plugin"
+	OpRemoveStandby      = "remove_standby"
+	OpRetain             = "retain"
+	OpRotateDSN          = "rotate_dsn"
+	OpRowsAffectedStats  = "rows_affected_stats"
+	OpSetReplicas        = "set_replicas"
+	OpSnapshot           = "snapshot"
+	OpSnapshotSession    = "snapshot_session"
+	OpStartLagMonitor    = "start_lag_monitor"
+	OpUsePlugin          = "use_plugin"
+	OpValidateStandby    = "validate_standby"
+)
+
+// ErrConnectionNotFound is the error of operations on a connection name that is not managed.
+var ErrConnectionNotFound = errors.New("database connection does not exist")
+
+// ConnError reports the connection and the operation an error of the package comes from, so that
+// consumers can log and alert on them uniformly:
+//
+//	var connErr *connection.ConnError
+//	if errors.As(err, &connErr) {
+//		slog.Error("db error", "connection", connErr.Name, "op", connErr.Op, "class", connection.ErrorClass(err))
+//	}
+type ConnError struct {
+	// Name is the name of the connection.
+	Name string
+
+	// Op is the operation that failed: OpInit, OpGet, OpClose, or the method, e.g. OpLoadData.
+	Op string
+
+	// Statement is the type of the failed statement, e.g. "SELECT" or "UPDATE", and Table the table it
+	// reads or writes, when Op is OpQuery. Either may be empty when the SQL does not tell.
+	Statement string
+	Table     string
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *ConnError) Error() string {
+	if e.Statement != "" || e.Table != "" {
+		return fmt.Sprintf("%s %q (%s): %v", e.Op, e.Name, strings.TrimSpace(e.Statement+" "+e.Table), e.Err)
+	}
+	return fmt.Sprintf("%s %q: %v", e.Op, e.Name, e.Err)
+}
+
+func (e *ConnError) Unwrap() error {
+	return e.Err
+}
+
+// connError wraps err in a ConnError, unless it already carries one for the same connection. Errors
+// returned by the methods of ConnectionManager taking a connection name go through it.
+func connError(name, op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var existing *ConnError
+	if errors.As(err, &existing) && existing.Name == name {
+		return err
+	}
+	return &ConnError{Name: name, Op: op, Err: err}
+}
+
+// errNotFound is the error of op on a connection name that is not managed.
+func errNotFound(name, op string) error {
+	return &ConnError{Name: name, Op: op, Err: ErrConnectionNotFound}
+}
@@ -0,0 +1,60 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestConnErrorNotFound(t *testing.T) {
+	factory := newTestFactory()
+
+	_, err := factory.GetDB("missing_db")
+	var connErr *ConnError
+	if !errors.As(err, &connErr) {
+		t.Fatalf("Expected a ConnError, got %v", err)
+	}
+	if connErr.Name != "missing_db" || connErr.Op != OpGet {
+		t.Fatalf("Unexpected ConnError: %+v", connErr)
+	}
+	if !errors.Is(err, ErrConnectionNotFound) {
+		t.Fatalf("Expected ErrConnectionNotFound, got %v", err)
+	}
+	if got, want := err.Error(), `get "missing_db": database connection does not exist`; got != want {
+		t.Fatalf("Expected %q, got %q", want, got)
+	}
+
+	if _, err := factory.DrainConnection(context.Background(), "missing_db"); !errors.As(err, &connErr) || connErr.Op != OpDrain {
+		t.Fatalf("Expected a drain ConnError, got %v", err)
+	}
+	if err := factory.CloseConnection("missing_db"); !errors.As(err, &connErr) || connErr.Op != OpClose || OperationID(err) == "" {
+		t.Fatalf("Expected a close ConnError carrying an operation ID, got %v", err)
+	}
+
+	ctx := context.Background()
+	for op, err := range map[string]error{
+		OpFailover:   func() error { _, err := factory.Failover("missing_db"); return err }(),
+		OpSnapshot:   func() error { _, err := factory.Snapshot(ctx, "missing_db", SnapshotOptions{}); return err }(),
+		OpExecScript: factory.ExecScript(ctx, "missing_db", strings.NewReader("SELECT 1;")),
+	} {
+		if !errors.As(err, &connErr) || connErr.Name != "missing_db" || connErr.Op != op {
+			t.Errorf("Expected a %s ConnError, got %v", op, err)
+		}
+	}
+}
+
+func TestConnErrorNotWrappedTwice(t *testing.T) {
+	inner := errNotFound("orders", OpGet)
+	if err := connError("orders", OpLoadData, inner); err != inner {
+		t.Fatalf("Expected the ConnError to be returned as is, got %v", err)
+	}
+	if err := connError("orders", OpGet, nil); err != nil {
+		t.Fatalf("Expected nil, got %v", err)
+	}
+
+	outer := connError("reports", "compare", inner)
+	if strings.Count(outer.Error(), "orders") != 1 || !strings.HasPrefix(outer.Error(), `compare "reports"`) {
+		t.Fatalf("Unexpected message: %q", outer.Error())
+	}
+}
@@ -0,0 +1,244 @@
+package connection
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Event stream defaults of EventStreamHandler.
+const (
+	defaultStatsInterval = time.Second
+	eventBufferSize      = 64
+)
+
+// Types of streamEvent.
+const (
+	eventLifecycle = "lifecycle"
+	eventStats     = "stats"
+	eventDropped   = "dropped"
+)
+
+// streamEvent is the JSON document of one event of EventStreamHandler.
+type streamEvent struct {
+	Type       string          `json:"type"`
+	Time       time.Time       `json:"time"`
+	Connection string          `json:"connection,omitempty"`
+	Lifecycle  *lifecycleEvent `json:"lifecycle,omitempty"`
+	Stats      *statsDelta     `json:"stats,omitempty"`
+	Dropped    int             `json:"dropped,omitempty"`
+}
+
+type lifecycleEvent struct {
+	OperationID string  `json:"operation_id"`
+	Op          string  `json:"op"`
+	Step        string  `json:"step"`
+	Error       string  `json:"error,omitempty"`
+	DurationMs  float64 `json:"duration_ms"`
+}
+
+// statsDelta holds the pool gauges and the change of the pool counters since the previous stats event.
+type statsDelta struct {
+	Open              int     `json:"open"`
+	InUse             int     `json:"in_use"`
+	Idle              int     `json:"idle"`
+	WaitCount         int64   `json:"wait_count_delta"`
+	WaitDurationMs    float64 `json:"wait_duration_ms_delta"`
+	MaxIdleClosed     int64   `json:"max_idle_closed_delta"`
+	MaxIdleTimeClosed int64   `json:"max_idle_time_closed_delta"`
+	MaxLifetimeClosed int64   `json:"max_lifetime_closed_delta"`
+}
+
+// eventBus fans lifecycle events out to the subscribers of EventStreamHandler.
+type eventBus struct {
+	mutex sync.Mutex
+	subs  map[*eventSub]struct{}
+}
+
+// eventSub is one subscriber. Events are dropped, and counted, when its buffer is full.
+type eventSub struct {
+	events  chan LifecycleEvent
+	mutex   sync.Mutex
+	dropped int
+}
+
+func (b *eventBus) subscribe() *eventSub {
+	sub := &eventSub{events: make(chan LifecycleEvent, eventBufferSize)}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.subs == nil {
+		b.subs = make(map[*eventSub]struct{})
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+func (b *eventBus) unsubscribe(sub *eventSub) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	delete(b.subs, sub)
+}
+
+func (b *eventBus) publish(event LifecycleEvent) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for sub := range b.subs {
+		select {
+		case sub.events <- event:
+		default:
+			sub.mutex.Lock()
+			sub.dropped++
+			sub.mutex.Unlock()
+		}
+	}
+}
+
+// takeDropped returns and resets the number of events dropped for sub.
+func (s *eventSub) takeDropped() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	dropped := s.dropped
+	s.dropped = 0
+	return dropped
+}
+
+// EventStreamHandler returns an http.Handler streaming connection events as Server-Sent Events, for
+// dashboards watching the pools live, e.g. during an incident.
+//
+// Parameters:
+// - interval: How often pool statistics are sent. Zero or less selects one second.
+//
+// Returns:
+// - http.Handler: A handler sending one JSON document per "data:" line until the client disconnects.
+//
+// Behavior:
+// 1. A "lifecycle" event is sent after each step of the init, reconnect and close operations (see
+// LifecycleEvent), as it happens.
+// 2. A "stats" event is sent for each connection every interval with its open, in-use and idle
+// connections and the change of its wait and close counters since the previous stats event.
+// 3. The connection query parameter restricts the stream to one connection: /events?connection=primary_db.
+// 4. A slow client does not block the connections: lifecycle events it cannot keep up with are dropped
+// and reported in a "dropped" event.
+//
+// Notes:
+// - The handler exposes connection names and pool statistics but no credentials. Mount it on an
+// internal listener or behind authentication.
+//
+// Example Usage:
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/admin/db/events", connection.GetConnectionManager().EventStreamHandler(time.Second))
+//	go http.ListenAndServe("127.0.0.1:9090", mux)
+func (f *ConnectionManager) EventStreamHandler(interval time.Duration) http.Handler {
+	if interval <= 0 {
+		interval = defaultStatsInterval
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		only := r.URL.Query().Get("connection")
+		sub := f.events.subscribe()
+		defer f.events.unsubscribe(sub)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		send := func(event streamEvent) bool {
+			payload, err := json.Marshal(event)
+			if err != nil {
+				return false
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, payload); err != nil {
+				return false
+			}
+			flusher.Flush()
+			return true
+		}
+
+		previous := make(map[string]sql.DBStats)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case event := <-sub.events:
+				if only != "" && event.Connection != only {
+					continue
+				}
+				if !send(newLifecycleStreamEvent(event)) {
+					return
+				}
+			case now := <-ticker.C:
+				if dropped := sub.takeDropped(); dropped > 0 {
+					if !send(streamEvent{Type: eventDropped, Time: now, Dropped: dropped}) {
+						return
+					}
+				}
+				for _, event := range f.statsEvents(now, only, previous) {
+					if !send(event) {
+						return
+					}
+				}
+			}
+		}
+	})
+}
+
+func newLifecycleStreamEvent(e LifecycleEvent) streamEvent {
+	event := &lifecycleEvent{OperationID: e.OperationID, Op: e.Op, Step: e.Step,
+		DurationMs: float64(e.Duration) / float64(time.Millisecond)}
+	if e.Err != nil {
+		event.Error = e.Err.Error()
+	}
+	return streamEvent{Type: eventLifecycle, Time: time.Now(), Connection: e.Connection, Lifecycle: event}
+}
+
+// statsEvents returns the stats events of the connections (or of only) and updates previous.
+func (f *ConnectionManager) statsEvents(now time.Time, only string, previous map[string]sql.DBStats) []streamEvent {
+	current := make(map[string]sql.DBStats)
+	f.mutex.Lock()
+	for name, db := range f.connections {
+		if only == "" || name == only {
+			current[name] = poolStats(db)
+		}
+	}
+	f.mutex.Unlock()
+
+	names := make([]string, 0, len(current))
+	for name := range current {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	events := make([]streamEvent, 0, len(current))
+	for _, name := range names {
+		stats, last := current[name], previous[name]
+		events = append(events, streamEvent{Type: eventStats, Time: now, Connection: name, Stats: &statsDelta{
+			Open:              stats.OpenConnections,
+			InUse:             stats.InUse,
+			Idle:              stats.Idle,
+			WaitCount:         stats.WaitCount - last.WaitCount,
+			WaitDurationMs:    float64(stats.WaitDuration-last.WaitDuration) / float64(time.Millisecond),
+			MaxIdleClosed:     stats.MaxIdleClosed - last.MaxIdleClosed,
+			MaxIdleTimeClosed: stats.MaxIdleTimeClosed - last.MaxIdleTimeClosed,
+			MaxLifetimeClosed: stats.MaxLifetimeClosed - last.MaxLifetimeClosed,
+		}})
+	}
+	for name := range previous {
+		delete(previous, name)
+	}
+	for name, stats := range current {
+		previous[name] = stats
+	}
+	return events
+}
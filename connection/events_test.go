@@ -0,0 +1,81 @@
+package connection
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventStreamHandler(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["primary_db"] = newConnectorDB(t, &fakeConnector{})
+	factory.connections["replica_db"] = newConnectorDB(t, &fakeConnector{})
+	server := httptest.NewServer(factory.EventStreamHandler(20 * time.Millisecond))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?connection=primary_db", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	_, op := startOperation(context.Background(), OpReconnect)
+	_ = factory.endStep(op, "replica_db", "reconnect", time.Now(), nil)
+	_ = factory.endStep(op, "primary_db", "reconnect", time.Now(), errors.New("refused"))
+
+	var lifecycle, stats *streamEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for (lifecycle == nil || stats == nil) && scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event streamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			t.Fatalf("Invalid event %q: %v", data, err)
+		}
+		if event.Connection != "primary_db" {
+			t.Fatalf("Expected only primary_db events, got %+v", event)
+		}
+		switch event.Type {
+		case eventLifecycle:
+			lifecycle = &event
+		case eventStats:
+			stats = &event
+		}
+	}
+	if lifecycle == nil || lifecycle.Lifecycle.Op != OpReconnect || lifecycle.Lifecycle.Error == "" {
+		t.Fatalf("Unexpected lifecycle event %+v", lifecycle)
+	}
+	if stats == nil || stats.Stats == nil {
+		t.Fatalf("Unexpected stats event %+v", stats)
+	}
+}
+
+func TestEventBusDropsWhenFull(t *testing.T) {
+	bus := &eventBus{}
+	sub := bus.subscribe()
+	for i := 0; i < eventBufferSize+3; i++ {
+		bus.publish(LifecycleEvent{Connection: "primary_db"})
+	}
+	if dropped := sub.takeDropped(); dropped != 3 {
+		t.Fatalf("Expected 3 dropped events, got %d", dropped)
+	}
+	bus.unsubscribe(sub)
+	bus.publish(LifecycleEvent{})
+	if len(sub.events) != eventBufferSize {
+		t.Fatalf("Expected no delivery after unsubscribe, got %d events", len(sub.events))
+	}
+}
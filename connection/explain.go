@@ -0,0 +1,128 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+// PlanStep is one row of traditional (tabular) EXPLAIN output: the access to one table.
+type PlanStep struct {
+	ID         int64
+	SelectType string
+
+	// Table is the table name or alias as written in the statement.
+	Table string
+
+	// Type is the access type, e.g. "const", "ref", "range", "index" (full index scan) or "ALL" (full table scan).
+	Type         string
+	PossibleKeys string
+
+	// Key is the index used, or "" when none is.
+	Key      string
+	Rows     int64
+	Filtered float64
+	Extra    string
+}
+
+// QueryPlan is the structured execution plan of a statement, as returned by Explain.
+type QueryPlan struct {
+	Query string
+	Steps []PlanStep
+}
+
+// FullScans returns the tables (as written in the statement) read by full table scan.
+func (p *QueryPlan) FullScans() []string {
+	var tables []string
+	for _, step := range p.Steps {
+		if step.Type == "ALL" {
+			tables = append(tables, step.Table)
+		}
+	}
+	return tables
+}
+
+// Explain returns the execution plan the server chooses for query, without executing it.
+//
+// Parameters:
+// - ctx: Context bounding the EXPLAIN.
+// - name: The name of the managed connection.
+// - query: A SELECT, INSERT, UPDATE, DELETE or REPLACE statement.
+// - args: Arguments for the placeholders in query.
+//
+// Returns:
+// - *QueryPlan: One step per table access, in the order of the EXPLAIN output.
+// - error: An error if the connection does not exist or the statement cannot be explained.
+//
+// Example Usage:
+//
+//	plan, err := connection.GetConnectionManager().Explain(ctx, "primary_db",
+//		"SELECT * FROM orders WHERE customer_id = ?", 42)
+//	if err == nil && len(plan.FullScans()) > 0 {
+//		log.Printf("Query scans %v", plan.FullScans())
+//	}
+func (f *ConnectionManager) Explain(ctx context.Context, name, query string, args ...interface{}) (*QueryPlan, error) {
+	db, err := f.GetDB(name)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := explainQuery(db.WithContext(ctx), query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain query on %q: %w", name, err)
+	}
+	return &QueryPlan{Query: query, Steps: steps}, nil
+}
+
+// explainQuery runs EXPLAIN on query and returns the plan rows. The statement itself is not executed.
+func explainQuery(db *gorm.DB, query string, args ...interface{}) ([]PlanStep, error) {
+	rows, err := db.Raw("EXPLAIN "+query, args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var plan []PlanStep
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		var row PlanStep
+		for i, column := range columns {
+			value := values[i].String
+			switch column {
+			case "id":
+				row.ID, _ = strconv.ParseInt(value, 10, 64)
+			case "select_type":
+				row.SelectType = value
+			case "table":
+				row.Table = value
+			case "type":
+				row.Type = value
+			case "possible_keys":
+				row.PossibleKeys = value
+			case "key":
+				row.Key = value
+			case "rows":
+				row.Rows, _ = strconv.ParseInt(value, 10, 64)
+			case "filtered":
+				row.Filtered, _ = strconv.ParseFloat(value, 64)
+			case "Extra":
+				row.Extra = value
+			}
+		}
+		plan = append(plan, row)
+	}
+	return plan, rows.Err()
+}
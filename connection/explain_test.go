@@ -0,0 +1,48 @@
+package connection
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestPlanRegressions(t *testing.T) {
+	before := &QueryPlan{Steps: []PlanStep{
+		{Table: "o", Type: "ref", Key: "idx_customer"},
+		{Table: "c", Type: "eq_ref", Key: "PRIMARY"},
+		{Table: "i", Type: "ref", Key: "idx_order"},
+	}}
+	after := &QueryPlan{Steps: []PlanStep{
+		{Table: "o", Type: "ALL"},
+		{Table: "c", Type: "eq_ref", Key: "PRIMARY"},
+		{Table: "i", Type: "ref", Key: "idx_order_item"},
+	}}
+
+	want := []string{
+		`full table scan on "o" (was ref access)`,
+		`index "idx_order" on "i" replaced by "idx_order_item"`,
+	}
+	if got := planRegressions(before, after); !reflect.DeepEqual(got, want) {
+		t.Fatalf("planRegressions() = %v, want %v", got, want)
+	}
+	if got := planRegressions(after, after); len(got) != 0 {
+		t.Fatalf("Expected no regressions for identical plans, got %v", got)
+	}
+	if got := after.FullScans(); !reflect.DeepEqual(got, []string{"o"}) {
+		t.Fatalf("FullScans() = %v", got)
+	}
+}
+
+func TestPlanMonitorWatch(t *testing.T) {
+	monitor := NewPlanMonitor(newTestFactory(), 0, nil)
+	if err := monitor.Watch("missing_db", "orders", "SELECT 1"); err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	if err := monitor.Watch("missing_db", "orders", "SELECT 2"); err == nil {
+		t.Fatal("Expected an error for a duplicate label, got nil")
+	}
+	// Unknown connections are logged and skipped rather than reported as plan changes.
+	if changes := monitor.Check(context.Background()); len(changes) != 0 {
+		t.Fatalf("Expected no changes, got %v", changes)
+	}
+}
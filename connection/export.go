@@ -0,0 +1,316 @@
+package connection
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format is the output format of Export.
+type Format int
+
+const (
+	// FormatCSV writes RFC 4180 CSV with a header row. NULL is written as an empty field.
+	FormatCSV Format = iota
+
+	// FormatJSONL writes one JSON object per row, keyed by column name.
+	FormatJSONL
+
+	// FormatParquet writes an Apache Parquet file with one optional column per result column. It is
+	// provided by the github.com/hemant-dhiman/MySQL-connection/parquetexport module, which registers it
+	// when imported, so applications that do not export Parquet do not depend on a Parquet library.
+	FormatParquet
+)
+
+// ErrUnsupportedFormat is returned by Export for formats it cannot produce, including FormatParquet
+// when the parquetexport module is not imported.
+var ErrUnsupportedFormat = errors.New("unsupported export format")
+
+// ExportColumn describes a column of the result of an Export query.
+type ExportColumn struct {
+	Name string
+
+	// DatabaseType is the MySQL type name of the column, e.g. "BIGINT", "DECIMAL" or "VARCHAR".
+	DatabaseType string
+}
+
+// ExportWriter renders the rows of Export in an output format registered with RegisterExportFormat.
+type ExportWriter interface {
+	// Header is called once with the columns of the result, before the first row.
+	Header(columns []ExportColumn) error
+
+	// Row writes one row. Values are nil, int64, uint64, float64, json.Number (DECIMAL), string or []byte
+	// (binary types), mapped from the column types like for the built-in formats. The slice is reused
+	// for the next row.
+	Row(values []interface{}) error
+
+	// Flush is called every exportChunkRows rows.
+	Flush() error
+
+	// Close is called once after the last row, also when the export fails.
+	Close() error
+}
+
+var (
+	exportFormatsMutex sync.RWMutex
+	exportFormats      = make(map[Format]func(w io.Writer) ExportWriter)
+)
+
+// RegisterExportFormat makes format available to Export, writing through the ExportWriter returned by
+// newWriter. It is called by the modules providing formats, e.g. parquetexport for FormatParquet. The
+// built-in formats cannot be replaced.
+func RegisterExportFormat(format Format, newWriter func(w io.Writer) ExportWriter) {
+	exportFormatsMutex.Lock()
+	defer exportFormatsMutex.Unlock()
+	exportFormats[format] = newWriter
+}
+
+// exportChunkRows is the number of rows written between two flushes of the output.
+const exportChunkRows = 1000
+
+// Export streams the result of a read-only query on the named connection to w.
+//
+// Parameters:
+// - ctx: Context bounding the whole export; cancelling it aborts the query.
+// - name: The name of the managed connection, e.g. the analytics connection.
+// - query: The SELECT statement to export. Optional args are bound to its placeholders.
+// - w: Destination of the export.
+// - format: FormatCSV, FormatJSONL, or FormatParquet when the parquetexport module is imported.
+//
+// Returns:
+// - int64: The number of rows written.
+// - error: An error if the connection does not exist, the query fails or writing fails.
+//
+// Behavior:
+// 1. Runs the query inside a READ ONLY transaction, so the export can never modify data.
+// 2. Streams rows from the server instead of loading the whole result, flushing w every exportChunkRows rows.
+// 3. Maps column values by their MySQL type: integers and floats become numbers, DECIMAL keeps its exact text,
+// temporal types are rendered as text, binary types are base64 encoded in JSONL and NULL becomes null/empty.
+// In Parquet, the columns get the matching Parquet types.
+//
+// Example Usage:
+//
+//	f, _ := os.Create("audience.csv")
+//	defer f.Close()
+//	n, err := connection.GetConnectionManager().Export(ctx, "analytics", "SELECT * FROM audience WHERE day = ?", f, connection.FormatCSV, day)
+func (f *ConnectionManager) Export(ctx context.Context, name, query string, w io.Writer, format Format, args ...interface{}) (count int64, err error) {
+	out, err := newExportWriter(w, format)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	db, err := f.GetDBContext(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve database handle for %q: %w", name, err)
+	}
+
+	tx, err := sqlDB.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to start read-only transaction on %q: %w", name, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("export query on %q failed: %w", name, err)
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read export columns on %q: %w", name, err)
+	}
+	columns := make([]ExportColumn, len(columnTypes))
+	for i, ct := range columnTypes {
+		columns[i] = ExportColumn{Name: ct.Name(), DatabaseType: ct.DatabaseTypeName()}
+	}
+	if err := out.Header(columns); err != nil {
+		return 0, err
+	}
+
+	raw := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range raw {
+		dest[i] = &raw[i]
+	}
+	values := make([]interface{}, len(columns))
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return count, fmt.Errorf("failed to scan export row %d on %q: %w", count+1, name, err)
+		}
+		for i, v := range raw {
+			values[i] = exportValue(columns[i].DatabaseType, v)
+		}
+		if err := out.Row(values); err != nil {
+			return count, err
+		}
+		count++
+		if count%exportChunkRows == 0 {
+			if err := out.Flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return count, fmt.Errorf("export of %q interrupted after %d rows: %w", name, count, err)
+	}
+	return count, nil
+}
+
+// exportValue converts a scanned driver value into a typed value based on the column's MySQL type.
+// The text protocol returns everything as []byte, so the type name drives the conversion.
+func exportValue(typeName string, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	switch t := v.(type) {
+	case time.Time:
+		return t.Format("2006-01-02 15:04:05.999999")
+	case []byte:
+		s := string(t)
+		switch typeName {
+		case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR":
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return n
+			}
+		case "UNSIGNED TINYINT", "UNSIGNED SMALLINT", "UNSIGNED MEDIUMINT", "UNSIGNED INT", "UNSIGNED BIGINT":
+			if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+				return n
+			}
+		case "FLOAT", "DOUBLE":
+			if n, err := strconv.ParseFloat(s, 64); err == nil {
+				return n
+			}
+		case "DECIMAL":
+			return json.Number(s)
+		case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "GEOMETRY":
+			return t
+		}
+		return s
+	}
+	return v
+}
+
+func newExportWriter(w io.Writer, format Format) (ExportWriter, error) {
+	switch format {
+	case FormatCSV:
+		return &csvExportWriter{w: csv.NewWriter(w)}, nil
+	case FormatJSONL:
+		return &jsonlExportWriter{w: bufio.NewWriter(w)}, nil
+	}
+	exportFormatsMutex.RLock()
+	newWriter, ok := exportFormats[format]
+	exportFormatsMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedFormat, format)
+	}
+	return newWriter(w), nil
+}
+
+type csvExportWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func (c *csvExportWriter) Header(columns []ExportColumn) error {
+	c.record = make([]string, len(columns))
+	for i, column := range columns {
+		c.record[i] = column.Name
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvExportWriter) Row(values []interface{}) error {
+	for i, v := range values {
+		switch t := v.(type) {
+		case nil:
+			c.record[i] = ""
+		case []byte:
+			c.record[i] = string(t)
+		default:
+			c.record[i] = fmt.Sprint(t)
+		}
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvExportWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvExportWriter) Close() error {
+	return c.Flush()
+}
+
+type jsonlExportWriter struct {
+	w       *bufio.Writer
+	columns []ExportColumn
+	keys    []string
+}
+
+func (j *jsonlExportWriter) Header(columns []ExportColumn) error {
+	j.columns = columns
+	j.keys = make([]string, len(columns))
+	for i, column := range columns {
+		key, err := json.Marshal(column.Name)
+		if err != nil {
+			return err
+		}
+		j.keys[i] = string(key)
+	}
+	return nil
+}
+
+func (j *jsonlExportWriter) Row(values []interface{}) error {
+	// The object is assembled by hand to keep the column order of the result set.
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(j.keys[i])
+		b.WriteByte(':')
+		if raw, ok := v.([]byte); ok {
+			v = base64.StdEncoding.EncodeToString(raw)
+		}
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode column %q: %w", j.columns[i].Name, err)
+		}
+		b.Write(encoded)
+	}
+	b.WriteString("}\n")
+	_, err := j.w.WriteString(b.String())
+	return err
+}
+
+func (j *jsonlExportWriter) Flush() error {
+	return j.w.Flush()
+}
+
+func (j *jsonlExportWriter) Close() error {
+	return j.Flush()
+}
@@ -0,0 +1,51 @@
+package connection
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestExportWriters(t *testing.T) {
+	columns := []ExportColumn{{"id", "BIGINT"}, {"amount", "DECIMAL"}, {"name", "VARCHAR"}, {"payload", "BLOB"}, {"deleted_at", "DATETIME"}}
+	raw := []interface{}{[]byte("7"), []byte("10.50"), []byte(`a "b"`), []byte{0x01, 0x02}, nil}
+
+	values := make([]interface{}, len(raw))
+	for i, v := range raw {
+		values[i] = exportValue(columns[i].DatabaseType, v)
+	}
+
+	var jsonl bytes.Buffer
+	writer, err := newExportWriter(&jsonl, FormatJSONL)
+	if err != nil {
+		t.Fatalf("Failed to create JSONL writer: %v", err)
+	}
+	_ = writer.Header(columns)
+	_ = writer.Row(values)
+	_ = writer.Close()
+
+	want := `{"id":7,"amount":10.50,"name":"a \"b\"","payload":"AQI=","deleted_at":null}` + "\n"
+	if jsonl.String() != want {
+		t.Fatalf("Unexpected JSONL output:\n got: %s\nwant: %s", jsonl.String(), want)
+	}
+
+	var csvOut bytes.Buffer
+	writer, _ = newExportWriter(&csvOut, FormatCSV)
+	_ = writer.Header(columns)
+	_ = writer.Row(values)
+	_ = writer.Close()
+
+	want = "id,amount,name,payload,deleted_at\n7,10.50,\"a \"\"b\"\"\",\x01\x02,\n"
+	if csvOut.String() != want {
+		t.Fatalf("Unexpected CSV output:\n got: %q\nwant: %q", csvOut.String(), want)
+	}
+}
+
+func TestExportUnsupportedFormat(t *testing.T) {
+	if _, err := newExportWriter(&bytes.Buffer{}, FormatParquet); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("Expected ErrUnsupportedFormat for an unregistered format, got %v", err)
+	}
+	if _, err := newExportWriter(&bytes.Buffer{}, FormatParquet+1); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("Expected ErrUnsupportedFormat for an unknown format, got %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package connection
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/hemant-dhiman/MySQL-connection/internal/fakesql"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"strings"
+	"time"
+)
+
+// ErrFakeUnsupported is returned by fake connections for statements outside the SQL subset they
+// implement (see InitFake).
+var ErrFakeUnsupported = fakesql.ErrUnsupported
+
+// ErrFakeReconnect is returned when a fake connection would be reconnected: it only exists in memory.
+var ErrFakeReconnect = errors.New("fake connections cannot be reconnected, call InitFake again")
+
+// fakeDSNPrefix prefixes the data source name of fake connections, see isFake.
+const fakeDSNPrefix = "fake@memory/"
+
+// FakeData seeds the tables of a fake connection, keyed by table name.
+type FakeData map[string]FakeTable
+
+// FakeTable is a table of a fake connection.
+type FakeTable struct {
+	// Rows are the initial rows, as column names to values. Values are converted like query arguments.
+	Rows []map[string]any
+
+	// Columns lists the columns returned by SELECT * besides those of Rows, for tables seeded empty.
+	Columns []string
+
+	// PrimaryKey is the primary key column, "id" when empty. Integer keys missing from inserted rows are
+	// generated like AUTO_INCREMENT.
+	PrimaryKey string
+
+	// Unique lists the unique keys besides the primary key, as column lists.
+	Unique [][]string
+}
+
+// InitFake registers a connection to an in-memory fake of MySQL seeded with seed, so unit tests of
+// repository code run without any server or container.
+//
+// Parameters:
+// - name: The name under which the connection is registered. An existing connection of that name is replaced.
+// - seed: The tables and their initial rows. Only these tables exist.
+//
+// Returns:
+// - error: An error if the seed is invalid, e.g. has duplicate keys.
+//
+// Behavior:
+//  1. The fake implements the SQL GORM and the helpers of this package emit on a single table: SELECT
+//     with WHERE, ORDER BY, LIMIT/OFFSET and COUNT/SUM/MIN/MAX/AVG, INSERT with ON DUPLICATE KEY UPDATE,
+//     UPDATE, DELETE, transactions and savepoints. Joins, GROUP BY, subqueries and DDL fail with ErrFakeUnsupported.
+//  2. Results are deterministic: rows are returned in insertion order unless ordered, and ORDER BY is stable.
+//  3. Strings compare case-insensitively, as with the default MySQL collations, and duplicate keys fail
+//     with MySQL error 1062, so ErrorClass classifies them as against a server.
+//  4. Statement hooks and the builtin plugins are installed as on real connections.
+//
+// Notes:
+//   - Transactions are not isolated from each other: a rollback restores every table as it was when the
+//     transaction began. Tests should not run concurrent transactions on one fake.
+//   - A fake connection is never reconnected: a reconnect, e.g. after a failed health check injected with
+//     chaos, fails with ErrFakeReconnect instead of dialing its placeholder data source name.
+//
+// Example Usage:
+//
+//	factory := connection.GetConnectionManager()
+//	err := factory.InitFake("primary_db", connection.FakeData{
+//		"users": {Rows: []map[string]any{{"id": 1, "name": "alice", "active": true}}},
+//	})
+//	users := connection.NewRepo[User](factory, "primary_db")
+//	user, err := users.Get(ctx, 1)
+func (f *ConnectionManager) InitFake(name string, seed FakeData) error {
+	tables := make(fakesql.Data, len(seed))
+	for table, spec := range seed {
+		tables[table] = fakesql.Table(spec)
+	}
+	connector, err := fakesql.NewConnector(name, tables)
+	if err != nil {
+		return connError(name, OpInit, fmt.Errorf("invalid fake data: %w", err))
+	}
+	pool := sql.OpenDB(connector)
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: pool, ServerVersion: fakesql.ServerVersion}), &gorm.Config{
+		Logger:               logger.Default.LogMode(logger.Silent),
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		_ = pool.Close()
+		return connError(name, OpInit, fmt.Errorf("failed to initialize database connection: %w", err))
+	}
+	config := DBConfig{DataSourceName: fakeDSNPrefix + name}.withDefaults()
+	applyPoolSettings([]*sql.DB{pool}, config)
+
+	hooks, err := installStatementHooks(name, db, false)
+	if err != nil {
+		_ = pool.Close()
+		return connError(name, OpInit, fmt.Errorf("failed to install statement hooks: %w", err))
+	}
+	db.ConnPool.(*hookedConnPool).downtime.Store(f.downtimeProbe(name))
+	hooks.set("quiesce", f.quiesceHook())
+	hooks.set("tenant_write", f.tenantWriteHook())
+	hooks.set("query_budget", queryBudgetHook(false))
+	hooks.set("brownout", f.brownoutHook([]*sql.DB{pool}))
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	plugins, err := f.installPlugins(name, db, txStatsPlugin{}, requestStatsPlugin{name: name}, otelPlugin{factory: f, name: name}, statementCapturePlugin{})
+	if err != nil {
+		_ = pool.Close()
+		return connError(name, OpInit, fmt.Errorf("failed to install plugins: %w", err))
+	}
+
+	replaced := f.connections[name]
+	f.connections[name] = db
+	f.configs[name] = config
+	if f.hooks == nil {
+		f.hooks = make(map[string]*hookChain)
+	}
+	f.hooks[name] = hooks
+	if f.plugins == nil {
+		f.plugins = make(map[string]*pluginRegistry)
+	}
+	f.plugins[name] = plugins
+	if f.info == nil {
+		f.info = make(map[string]*Connection)
+	}
+	f.info[name] = &Connection{Name: name, Addr: "memory", ServerVersion: fakesql.ServerVersion, Pool: pool, ConnectedAt: time.Now()}
+	delete(f.sessions, name)
+	delete(f.warnings, name)
+	if replaced != nil {
+		if replacedDB, err := replaced.DB(); err == nil {
+			_ = replacedDB.Close()
+		}
+		closeExtraShards(replaced)
+	}
+	return nil
+}
+
+// isFake reports whether config is the configuration of a fake connection.
+func isFake(config DBConfig) bool {
+	return strings.HasPrefix(config.DataSourceName, fakeDSNPrefix)
+}
@@ -0,0 +1,204 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"testing"
+	"time"
+)
+
+type fakeTestUser struct {
+	ID        uint
+	Name      string
+	Email     string
+	Active    bool
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+func newFakeFactory(t *testing.T, seed FakeData) *ConnectionManager {
+	t.Helper()
+	factory := newTestFactory()
+	if err := factory.InitFake("primary_db", seed); err != nil {
+		t.Fatalf("InitFake failed: %v", err)
+	}
+	t.Cleanup(func() { factory.CloseAllConnections() })
+	return factory
+}
+
+func fakeUsers() FakeData {
+	return FakeData{"fake_test_users": {
+		Columns: []string{"created_at", "deleted_at"},
+		Unique:  [][]string{{"email"}},
+		Rows: []map[string]any{
+			{"id": 1, "name": "alice", "email": "alice@example.com", "active": true},
+			{"id": 2, "name": "bob", "email": "bob@example.com", "active": false},
+			{"id": 3, "name": "Carol", "email": "carol@example.com", "active": true},
+		},
+	}}
+}
+
+func TestFakeRepoCRUD(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	repo := NewRepo[fakeTestUser](factory, "primary_db")
+	ctx := context.Background()
+
+	user, err := repo.Get(ctx, 3)
+	if err != nil || user.Name != "Carol" || !user.Active {
+		t.Fatalf("Unexpected Get result %+v: %v", user, err)
+	}
+	if _, err := repo.Get(ctx, 42); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected gorm.ErrRecordNotFound, got %v", err)
+	}
+
+	created := &fakeTestUser{Name: "dave", Email: "dave@example.com"}
+	if err := repo.Create(ctx, created); err != nil || created.ID != 4 {
+		t.Fatalf("Expected the generated ID 4, got %d: %v", created.ID, err)
+	}
+	if err := repo.Create(ctx, &fakeTestUser{Name: "eve", Email: "ALICE@example.com"}); ErrorClass(err) != "duplicate_key" {
+		t.Fatalf("Expected a duplicate key error, got %v", err)
+	}
+
+	created.Name = "david"
+	if err := repo.Update(ctx, created); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := repo.Delete(ctx, 2); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	users, err := repo.List(ctx, 0, 10)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	var names []string
+	for _, u := range users {
+		names = append(names, u.Name)
+	}
+	if got := names; len(got) != 3 || got[0] != "alice" || got[1] != "Carol" || got[2] != "david" {
+		t.Fatalf("Unexpected users after the changes: %v", got)
+	}
+
+	db, _ := factory.GetDB("primary_db")
+	var deleted int64
+	if err := db.Unscoped().Model(&fakeTestUser{}).Where("deleted_at IS NOT NULL").Count(&deleted).Error; err != nil || deleted != 1 {
+		t.Fatalf("Expected one soft-deleted user, got %d: %v", deleted, err)
+	}
+}
+
+func TestFakeQueries(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	db, _ := factory.GetDB("primary_db")
+
+	var names []string
+	if err := db.Model(&fakeTestUser{}).Where("active = ? AND name LIKE ?", true, "%a%").
+		Order("name DESC").Pluck("name", &names).Error; err != nil {
+		t.Fatalf("Pluck failed: %v", err)
+	}
+	if len(names) != 2 || names[0] != "Carol" || names[1] != "alice" {
+		t.Fatalf("Unexpected names: %v", names)
+	}
+
+	var count int64
+	if err := db.Model(&fakeTestUser{}).Where("id IN ?", []int{1, 2, 9}).Count(&count).Error; err != nil || count != 2 {
+		t.Fatalf("Expected 2, got %d: %v", count, err)
+	}
+
+	page, err := Paginate[fakeTestUser](context.Background(), db, PageRequest{Mode: PageKeyset, Size: 2, OrderBy: []string{"id"}})
+	if err != nil || len(page.Items) != 2 || !page.HasMore {
+		t.Fatalf("Unexpected first page %+v: %v", page, err)
+	}
+	page, err = Paginate[fakeTestUser](context.Background(), db, PageRequest{Mode: PageKeyset, Size: 2, OrderBy: []string{"id"}, Token: page.NextToken})
+	if err != nil || len(page.Items) != 1 || page.Items[0].ID != 3 || page.HasMore {
+		t.Fatalf("Unexpected last page %+v: %v", page, err)
+	}
+
+	err = db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&fakeTestUser{ID: 2, Name: "robert", Email: "bob@example.com"}).Error
+	var user fakeTestUser
+	if err != nil || db.First(&user, 2).Error != nil || user.Name != "robert" {
+		t.Fatalf("Expected the upsert to update bob, got %+v: %v", user, err)
+	}
+
+	var computed struct {
+		Later time.Time
+		Label string
+	}
+	err = db.Raw("SELECT NOW(6) + INTERVAL 90 MINUTE AS later, CONCAT(name, '#', id) AS label FROM fake_test_users WHERE id = ?", 1).Scan(&computed).Error
+	if err != nil || time.Until(computed.Later) < 89*time.Minute || computed.Label != "alice#1" {
+		t.Fatalf("Unexpected computed columns %+v: %v", computed, err)
+	}
+	var lastID int64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&fakeTestUser{Name: "frank", Email: "frank@example.com"}).Error; err != nil {
+			return err
+		}
+		return tx.Raw("SELECT LAST_INSERT_ID()").Scan(&lastID).Error
+	})
+	if err != nil || lastID != 4 {
+		t.Fatalf("Expected LAST_INSERT_ID() 4, got %d: %v", lastID, err)
+	}
+
+	if err := db.Exec("SELECT a.id FROM fake_test_users a JOIN other b ON a.id = b.id").Error; !errors.Is(err, ErrFakeUnsupported) {
+		t.Fatalf("Expected ErrFakeUnsupported for a join, got %v", err)
+	}
+	if err := db.Table("missing").Count(&count).Error; ErrorClass(err) != "no_such_table" {
+		t.Fatalf("Expected a missing table error, got %v", err)
+	}
+}
+
+func TestFakeTransactions(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	db, _ := factory.GetDB("primary_db")
+
+	failed := errors.New("rollback")
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&fakeTestUser{Name: "kept", Email: "kept@example.com"}).Error; err != nil {
+			return err
+		}
+		_ = tx.Transaction(func(nested *gorm.DB) error {
+			nested.Create(&fakeTestUser{Name: "nested", Email: "nested@example.com"})
+			return failed
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	_ = db.Transaction(func(tx *gorm.DB) error {
+		tx.Where("id = ?", 1).Delete(&fakeTestUser{})
+		return failed
+	})
+
+	var names []string
+	db.Model(&fakeTestUser{}).Order("id").Pluck("name", &names)
+	if len(names) != 4 || names[0] != "alice" || names[3] != "kept" {
+		t.Fatalf("Expected the savepoint and the transaction to be rolled back, got %v", names)
+	}
+}
+
+func TestFakeInvalidSeed(t *testing.T) {
+	err := newTestFactory().InitFake("primary_db", FakeData{"users": {Rows: []map[string]any{{"id": 1}, {"id": 1}}}})
+	if ErrorClass(err) != "duplicate_key" {
+		t.Fatalf("Expected a duplicate key error, got %v", err)
+	}
+}
+
+func TestFakeNotReconnected(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	EnableChaos(true)
+	defer EnableChaos(false)
+	if err := factory.InjectChaos("primary_db", ChaosConfig{FailPing: true}); err != nil {
+		t.Fatalf("InjectChaos failed: %v", err)
+	}
+
+	if _, err := factory.GetDB("primary_db"); !errors.Is(err, ErrFakeReconnect) {
+		t.Fatalf("Expected ErrFakeReconnect, got %v", err)
+	}
+	factory.ClearChaos("primary_db")
+	var users []fakeTestUser
+	if err := factory.connections["primary_db"].Find(&users).Error; err != nil || len(users) != 3 {
+		t.Fatalf("Expected the fake to keep its data, got %d users: %v", len(users), err)
+	}
+}
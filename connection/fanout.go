@@ -0,0 +1,180 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultFanOutParallelism is the number of shards FanOut queries at once by default.
+const defaultFanOutParallelism = 8
+
+// FanOutOptions configures FanOut.
+type FanOutOptions struct {
+	// Parallelism is the number of shards queried at once. Defaults to 8.
+	Parallelism int
+
+	// ShardTimeout bounds the query on each shard, on top of the deadline of the context. Zero means
+	// the context only.
+	ShardTimeout time.Duration
+}
+
+// FanOutError is returned by FanOut when the query failed on some shards. The results of the other
+// shards are returned along with it.
+type FanOutError struct {
+	Group string
+
+	// Errors holds the error of each failed shard, by connection name.
+	Errors map[string]error
+
+	// Shards is the number of shards of the group.
+	Shards int
+}
+
+func (e *FanOutError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	messages := make([]string, len(names))
+	for i, name := range names {
+		messages[i] = fmt.Sprintf("%s: %v", name, e.Errors[name])
+	}
+	return fmt.Sprintf("query failed on %d of %d shards of %q: %s", len(e.Errors), e.Shards, e.Group, strings.Join(messages, "; "))
+}
+
+// Unwrap returns the errors of the failed shards.
+func (e *FanOutError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// SetShardGroup defines a group of managed connections holding the same schema for different tenants
+// or key ranges, for FanOut. Setting a group again replaces its shards; no shard removes the group.
+// The connections do not need to exist yet.
+//
+// Example Usage:
+//
+//	factory := connection.GetConnectionManager()
+//	factory.SetShardGroup("tenants", "tenants_eu_1", "tenants_eu_2", "tenants_us_1")
+func (f *ConnectionManager) SetShardGroup(group string, shards ...string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if len(shards) == 0 {
+		delete(f.shardGroups, group)
+		return
+	}
+	if f.shardGroups == nil {
+		f.shardGroups = make(map[string][]string)
+	}
+	f.shardGroups[group] = append([]string(nil), shards...)
+}
+
+// ShardGroup returns the connections of a shard group, nil for an unknown group.
+func (f *ConnectionManager) ShardGroup(group string) []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.shardGroups[group]...)
+}
+
+// FanOut runs the same query concurrently on every shard of a group and scans the rows of each shard
+// into a slice of T, e.g. for admin views across all tenants.
+//
+// Parameters:
+// - ctx: Context bounding the whole fan-out.
+// - f: The connection manager holding the group (see SetShardGroup).
+// - group: The shard group.
+// - query, args: The query and its placeholder arguments, run as is on every shard.
+// - opts: The parallelism and the timeout of each shard.
+//
+// Returns:
+// - map[string][]T: The rows of each shard the query succeeded on, by connection name.
+// - error: An error if the group is unknown, or a *FanOutError listing the shards the query failed on
+// (missing connection, timeout, query error), with the results of the others returned anyway.
+//
+// Example Usage:
+//
+//	type tenantUsage struct {
+//		TenantID int64
+//		Rows     int64
+//	}
+//	usage, err := connection.FanOut[tenantUsage](ctx, factory, "tenants",
+//		"SELECT tenant_id, COUNT(*) AS rows FROM events WHERE day = ? GROUP BY tenant_id", []any{day},
+//		connection.FanOutOptions{Parallelism: 4, ShardTimeout: 5 * time.Second})
+//	var partial *connection.FanOutError
+//	if errors.As(err, &partial) {
+//		log.Printf("usage incomplete: %v", partial) // usage holds the shards that answered
+//	} else if err != nil {
+//		return err
+//	}
+func FanOut[T any](ctx context.Context, f *ConnectionManager, group, query string, args []interface{}, opts FanOutOptions) (map[string][]T, error) {
+	shards := f.ShardGroup(group)
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("unknown shard group %q", group)
+	}
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = defaultFanOutParallelism
+	}
+
+	results := make(map[string][]T, len(shards))
+	failures := make(map[string]error)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, parallelism)
+	for _, shard := range shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				mutex.Lock()
+				failures[shard] = ctx.Err()
+				mutex.Unlock()
+				return
+			}
+
+			rows, err := fanOutShard[T](ctx, f, shard, query, args, opts.ShardTimeout)
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				failures[shard] = err
+				return
+			}
+			results[shard] = rows
+		}()
+	}
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return results, &FanOutError{Group: group, Errors: failures, Shards: len(shards)}
+	}
+	return results, nil
+}
+
+// fanOutShard runs the query of FanOut on one shard.
+func fanOutShard[T any](ctx context.Context, f *ConnectionManager, shard, query string, args []interface{}, timeout time.Duration) ([]T, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	db, err := f.GetDBContext(ctx, shard)
+	if err != nil {
+		return nil, err
+	}
+	var rows []T
+	if err := db.Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, connError(shard, OpFanOut, err)
+	}
+	return rows, nil
+}
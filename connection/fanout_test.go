@@ -0,0 +1,38 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFanOut(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	if err := factory.InitFake("tenants_2", FakeData{"fake_test_users": {Rows: []map[string]any{
+		{"id": 1, "name": "zoe", "email": "zoe@example.com", "active": true},
+	}}}); err != nil {
+		t.Fatalf("InitFake failed: %v", err)
+	}
+	factory.SetShardGroup("tenants", "primary_db", "tenants_2", "tenants_3")
+	ctx := context.Background()
+
+	results, err := FanOut[fakeTestUser](ctx, factory, "tenants", "SELECT * FROM fake_test_users WHERE active = ? ORDER BY id",
+		[]interface{}{true}, FanOutOptions{Parallelism: 1})
+	var fanOutErr *FanOutError
+	if !errors.As(err, &fanOutErr) || len(fanOutErr.Errors) != 1 || !errors.Is(fanOutErr.Errors["tenants_3"], ErrConnectionNotFound) {
+		t.Fatalf("Expected tenants_3 to fail, got %v", err)
+	}
+	if len(results) != 2 || len(results["primary_db"]) != 2 || results["primary_db"][1].Name != "Carol" ||
+		len(results["tenants_2"]) != 1 || results["tenants_2"][0].Name != "zoe" {
+		t.Fatalf("Unexpected partial results %+v", results)
+	}
+
+	factory.SetShardGroup("tenants", "primary_db", "tenants_2")
+	if results, err := FanOut[fakeTestUser](ctx, factory, "tenants", "SELECT * FROM fake_test_users", nil, FanOutOptions{}); err != nil || len(results) != 2 {
+		t.Fatalf("Unexpected results %+v: %v", results, err)
+	}
+	factory.SetShardGroup("tenants")
+	if _, err := FanOut[fakeTestUser](ctx, factory, "tenants", "SELECT 1", nil, FanOutOptions{}); err == nil {
+		t.Fatal("Expected an error for an unknown group, got nil")
+	}
+}
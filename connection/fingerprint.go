@@ -0,0 +1,182 @@
+package connection
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Fingerprint normalizes a SQL statement so that statements differing only in literal values
+// map to the same string. Comments are removed, whitespace is canonicalized, keywords and
+// identifiers are lower-cased, string and numeric literals become "?" and lists of
+// placeholders such as "IN (?, ?, ?)" collapse to "(?+)".
+//
+// Example:
+//
+//	Fingerprint("SELECT * FROM users WHERE id IN (1, 2, 3) AND name = 'bob'")
+//	// select * from users where id in (?+) and name = ?
+func Fingerprint(query string) string {
+	tokens := collapsePlaceholderLists(sqlTokens(query))
+
+	var b strings.Builder
+	b.Grow(len(query))
+	for i, tok := range tokens {
+		if i > 0 && spaceBetween(tokens[i-1], tok) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(tok)
+	}
+	return b.String()
+}
+
+// fingerprintKeywords are keywords that keep a space before an opening parenthesis, unlike
+// function names ("in (?+)" but "count(*)").
+var fingerprintKeywords = map[string]bool{
+	"in": true, "values": true, "value": true, "from": true, "join": true, "exists": true, "using": true,
+	"on": true, "as": true, "and": true, "or": true, "not": true, "where": true, "select": true,
+	"into": true, "set": true, "union": true, "all": true, "by": true, "having": true, "table": true,
+}
+
+// spaceBetween decides whether two consecutive tokens are separated by a space in a fingerprint.
+func spaceBetween(prev, tok string) bool {
+	switch {
+	case tok == "," || tok == ")" || tok == "." || prev == "(" || prev == ".":
+		return false
+	case tok == "(":
+		return !isWordToken(prev) || fingerprintKeywords[prev]
+	}
+	return true
+}
+
+// sqlTokens splits a statement into lower-cased tokens with literals replaced by "?" and comments dropped.
+func sqlTokens(query string) []string {
+	var tokens []string
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+		case c == '#' || (c == '-' && strings.HasPrefix(query[i:], "-- ")) || (c == '-' && strings.HasPrefix(query[i:], "--\n")):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 3
+			}
+		case c == '\'' || c == '"':
+			i = skipQuoted(query, i, c)
+			tokens = append(tokens, "?")
+		case c == '`':
+			end := skipQuoted(query, i, c)
+			tokens = append(tokens, strings.ToLower(query[i:end+1]))
+			i = end
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			for i+1 < len(query) && (isNumberChar(query[i+1]) ||
+				((query[i+1] == '-' || query[i+1] == '+') && (query[i] == 'e' || query[i] == 'E'))) {
+				i++
+			}
+			// A sign directly in front of a literal is part of it, unless the sign is a binary operator.
+			if n := len(tokens); n > 0 && (tokens[n-1] == "-" || tokens[n-1] == "+") &&
+				(n == 1 || !isOperandToken(tokens[n-2])) {
+				tokens = tokens[:n-1]
+			}
+			tokens = append(tokens, "?")
+		case isWordChar(c):
+			start := i
+			for i+1 < len(query) && (isWordChar(query[i+1]) || isDigit(query[i+1])) {
+				i++
+			}
+			tokens = append(tokens, strings.ToLower(query[start:i+1]))
+		case strings.ContainsRune("=<>!|&:", rune(c)):
+			start := i
+			for i+1 < len(query) && strings.ContainsRune("=<>!|&:", rune(query[i+1])) {
+				i++
+			}
+			tokens = append(tokens, query[start:i+1])
+		case c == ';' && strings.TrimSpace(query[i+1:]) == "":
+			// A trailing statement terminator does not change the statement.
+		default:
+			tokens = append(tokens, string(c))
+		}
+	}
+	return tokens
+}
+
+// collapsePlaceholderLists rewrites "( ? , ? , ... )" as a single "(?+)" token so IN lists and
+// multi-row VALUES of any length share a fingerprint.
+func collapsePlaceholderLists(tokens []string) []string {
+	out := tokens[:0:0]
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i] == "(" {
+			j := i + 1
+			for j+1 < len(tokens) && tokens[j] == "?" && tokens[j+1] == "," {
+				j += 2
+			}
+			if j > i+1 && j+1 < len(tokens) && tokens[j] == "?" && tokens[j+1] == ")" {
+				out = append(out, "(?+)")
+				i = j + 1
+				continue
+			}
+		}
+		out = append(out, tokens[i])
+	}
+	return out
+}
+
+// skipQuoted returns the index of the closing quote for the quoted section starting at start.
+func skipQuoted(s string, start int, quote byte) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i
+		}
+	}
+	return len(s) - 1
+}
+
+// isOperandToken reports whether tok can end an operand, making a following sign a binary operator.
+func isOperandToken(tok string) bool {
+	return tok == "?" || tok == ")" || tok == "(?+)" || strings.HasPrefix(tok, "`") ||
+		(isWordToken(tok) && !fingerprintKeywords[tok])
+}
+
+func isWordToken(tok string) bool {
+	return tok != "" && isWordChar(tok[0])
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || c == '@' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isNumberChar(c byte) bool {
+	return isDigit(c) || c == '.' || c == 'x' || c == 'X' || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// firstKeyword returns the first keyword of a statement, upper-cased, ignoring leading comments,
+// whitespace and parentheses.
+func firstKeyword(query string) string {
+	for _, tok := range sqlTokens(query) {
+		if tok == "(" {
+			continue
+		}
+		if isWordToken(tok) {
+			return strings.ToUpper(tok)
+		}
+		return ""
+	}
+	return ""
+}
@@ -0,0 +1,29 @@
+package connection
+
+import "testing"
+
+func TestFingerprint(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM users WHERE id IN (1, 2, 3) AND name = 'bob'":    "select * from users where id in (?+) and name = ?",
+		"select *\n  from users\twhere id in (7,8) and name='o''brien'": "select * from users where id in (?+) and name = ?",
+		"SELECT `a`, b FROM t1 /* hint */ WHERE x = -- trailing\n 42":   "select `a`, b from t1 where x = ?",
+		"INSERT INTO `t` (`a`,`b`) VALUES (?,?),(?,?)":                  "insert into `t` (`a`, `b`) values (?+), (?+)",
+		"SELECT COUNT(*) FROM izooto.audience":                          "select count(*) from izooto.audience",
+		"UPDATE t SET v = 1.5e3 WHERE id = 0x1F":                        "update t set v = ? where id = ?",
+		"SELECT id FROM t WHERE a IN (?, ?, col)":                       "select id from t where a in (?, ?, col)",
+		"SELECT a-5, b FROM t WHERE x = -5 AND y > 1.5e-3":              "select a - ?, b from t where x = ? and y > ?",
+		"  # comment\nDELETE FROM t WHERE name = \"x\\\"y\"":            "delete from t where name = ?",
+	}
+	for in, want := range cases {
+		if got := Fingerprint(in); got != want {
+			t.Errorf("Fingerprint(%q)\n got: %q\nwant: %q", in, got, want)
+		}
+	}
+
+	if got := firstKeyword("/* x */ (SELECT 1) UNION (SELECT 2)"); got != "SELECT" {
+		t.Errorf("Unexpected first keyword %q", got)
+	}
+	if got := firstKeyword("  drop table t"); got != "DROP" {
+		t.Errorf("Unexpected first keyword %q", got)
+	}
+}
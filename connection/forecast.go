@@ -0,0 +1,323 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults of StartStatsHistory and the parameters of Forecast.
+const (
+	defaultHistoryInterval = 10 * time.Second
+	defaultHistorySize     = 360
+
+	// forecastSmoothing is the weight of a new sample in the moving average of connections in use.
+	forecastSmoothing = 0.3
+
+	// forecastWarningHorizon is how close a projected saturation must be for PoolForecast.Warning.
+	forecastWarningHorizon = time.Hour
+
+	// forecastMinSamples is the number of samples Forecast needs to project a trend.
+	forecastMinSamples = 3
+)
+
+// ErrInsufficientHistory is returned by Forecast when too few statistics samples were recorded for the
+// connection, e.g. because StartStatsHistory was not called or has just been.
+var ErrInsufficientHistory = errors.New("not enough pool statistics history")
+
+// StatsSample is one sample of the pool statistics of a connection.
+type StatsSample struct {
+	At           time.Time
+	InUse        int
+	Idle         int
+	MaxOpen      int
+	WaitCount    int64
+	WaitDuration time.Duration
+}
+
+// statsHistory keeps the last samples of every connection. The zero value is ready to use.
+type statsHistory struct {
+	mutex   sync.Mutex
+	size    int
+	samples map[string][]StatsSample
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// StartStatsHistory samples the pool statistics of all connections every interval and keeps the last
+// size samples of each, for StatsHistory and Forecast. Calling it again restarts sampling with the new
+// settings and keeps the samples already recorded.
+//
+// Parameters:
+// - interval: The sampling interval. Zero or less selects ten seconds.
+// - size: The number of samples kept per connection. Zero or less selects 360, an hour at the default interval.
+//
+// Returns:
+// - func(): Stops sampling. The recorded samples are kept.
+//
+// Example Usage:
+//
+//	factory := connection.GetConnectionManager()
+//	stop := factory.StartStatsHistory(10*time.Second, 360)
+//	defer stop()
+func (f *ConnectionManager) StartStatsHistory(interval time.Duration, size int) func() {
+	if interval <= 0 {
+		interval = defaultHistoryInterval
+	}
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+	f.stopStatsHistory()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	f.history.mutex.Lock()
+	f.history.size, f.history.cancel, f.history.done = size, cancel, done
+	f.history.mutex.Unlock()
+
+	f.history.record(time.Now(), f.poolStats())
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				f.history.record(now, f.poolStats())
+			}
+		}
+	}()
+	return f.stopStatsHistory
+}
+
+// stopStatsHistory stops the sampling of StartStatsHistory, if running.
+func (f *ConnectionManager) stopStatsHistory() {
+	f.history.mutex.Lock()
+	cancel, done := f.history.cancel, f.history.done
+	f.history.cancel, f.history.done = nil, nil
+	f.history.mutex.Unlock()
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// StatsHistory returns the recorded pool statistics samples of a connection, oldest first.
+func (f *ConnectionManager) StatsHistory(name string) []StatsSample {
+	f.history.mutex.Lock()
+	defer f.history.mutex.Unlock()
+	return append([]StatsSample(nil), f.history.samples[name]...)
+}
+
+// record appends a sample of every connection of stats, dropping the samples of connections that are gone.
+func (h *statsHistory) record(now time.Time, stats map[string]sql.DBStats) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.samples == nil {
+		h.samples = make(map[string][]StatsSample)
+	}
+	size := h.size
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+	for name := range h.samples {
+		if _, exists := stats[name]; !exists {
+			delete(h.samples, name)
+		}
+	}
+	for name, s := range stats {
+		samples := append(h.samples[name], StatsSample{At: now, InUse: s.InUse, Idle: s.Idle,
+			MaxOpen: s.MaxOpenConnections, WaitCount: s.WaitCount, WaitDuration: s.WaitDuration})
+		if len(samples) > size {
+			samples = append(samples[:0], samples[len(samples)-size:]...)
+		}
+		h.samples[name] = samples
+	}
+}
+
+// PoolForecast projects when a connection pool will run out of connections.
+type PoolForecast struct {
+	Connection string
+
+	// Samples is the number of samples the forecast is based on, spanning Span.
+	Samples int
+	Span    time.Duration
+
+	// MaxOpen is the pool limit, 0 when unlimited.
+	MaxOpen int
+
+	// InUse is the moving average of connections in use at the last sample, and Peak the most in use in
+	// any sample. Utilization is InUse over MaxOpen.
+	InUse       float64
+	Peak        int
+	Utilization float64
+
+	// GrowthPerHour is the trend of InUse, in connections per hour, fitted over the samples.
+	GrowthPerHour float64
+
+	// Waits is the number of times a statement waited for a free connection during Span.
+	Waits int64
+
+	// Saturated reports that the pool hit MaxOpen during Span or statements had to wait for a connection.
+	Saturated bool
+
+	// TimeToSaturation is when InUse reaches MaxOpen at the current trend, counted from the last sample.
+	// It is zero when the pool is saturated, and negative when no saturation is projected.
+	TimeToSaturation time.Duration
+
+	// Warning reports a saturated pool or a saturation projected within the next hour.
+	Warning bool
+}
+
+// Forecast projects the time to saturation of a connection pool from the trend of its recorded
+// statistics, giving an early warning before MaxOpen is hit in peak hours.
+//
+// Parameters:
+// - name: The name of the connection.
+//
+// Returns:
+// - *PoolForecast: The projection.
+// - error: ErrInsufficientHistory when fewer than three samples were recorded (see StartStatsHistory).
+//
+// Behavior:
+// 1. The connections in use are smoothed with an exponentially weighted moving average, so a single
+// burst does not dominate the trend.
+// 2. A least squares line fitted over the smoothed series gives the growth per hour, and its intersection
+// with MaxOpen the time to saturation.
+// 3. Waits for a free connection during the recorded span mark the pool as saturated already.
+//
+// Example Usage:
+//
+//	forecast, err := connection.GetConnectionManager().Forecast("primary_db")
+//	if err == nil && forecast.Warning {
+//		log.Printf("primary_db pool saturates in %v (%.0f%% used, +%.1f connections/h)",
+//			forecast.TimeToSaturation, 100*forecast.Utilization, forecast.GrowthPerHour)
+//	}
+func (f *ConnectionManager) Forecast(name string) (*PoolForecast, error) {
+	samples := f.StatsHistory(name)
+	if len(samples) < forecastMinSamples {
+		return nil, connError(name, OpForecast, ErrInsufficientHistory)
+	}
+	return forecastPool(name, samples), nil
+}
+
+// forecastPool computes the forecast of samples, oldest first.
+func forecastPool(name string, samples []StatsSample) *PoolForecast {
+	first, last := samples[0], samples[len(samples)-1]
+	forecast := &PoolForecast{
+		Connection: name,
+		Samples:    len(samples),
+		Span:       last.At.Sub(first.At),
+		MaxOpen:    last.MaxOpen,
+		Waits:      last.WaitCount - first.WaitCount,
+	}
+
+	// Exponentially weighted moving average, then least squares over (seconds, average)
+	var smoothed float64
+	var sumT, sumY, sumTT, sumTY float64
+	for i, sample := range samples {
+		if i == 0 {
+			smoothed = float64(sample.InUse)
+		} else {
+			smoothed = forecastSmoothing*float64(sample.InUse) + (1-forecastSmoothing)*smoothed
+		}
+		if sample.InUse > forecast.Peak {
+			forecast.Peak = sample.InUse
+		}
+		t := sample.At.Sub(first.At).Seconds()
+		sumT += t
+		sumY += smoothed
+		sumTT += t * t
+		sumTY += t * smoothed
+	}
+	n := float64(len(samples))
+	slope := 0.0
+	if d := n*sumTT - sumT*sumT; d > 0 {
+		slope = (n*sumTY - sumT*sumY) / d
+	}
+	forecast.InUse = smoothed
+	forecast.GrowthPerHour = slope * 3600
+
+	forecast.TimeToSaturation = -1
+	if forecast.MaxOpen <= 0 {
+		return forecast
+	}
+	forecast.Utilization = forecast.InUse / float64(forecast.MaxOpen)
+	forecast.Saturated = forecast.Peak >= forecast.MaxOpen || forecast.Waits > 0
+	switch {
+	case forecast.Saturated:
+		forecast.TimeToSaturation = 0
+	case slope > 0:
+		seconds := (float64(forecast.MaxOpen) - forecast.InUse) / slope
+		forecast.TimeToSaturation = time.Duration(math.Min(seconds, math.MaxInt64/float64(time.Second)) * float64(time.Second))
+	}
+	forecast.Warning = forecast.Saturated || forecast.TimeToSaturation > 0 && forecast.TimeToSaturation <= forecastWarningHorizon
+	return forecast
+}
+
+// forecastReport is the JSON form of a PoolForecast served by ForecastHandler.
+type forecastReport struct {
+	Connection              string   `json:"connection"`
+	Samples                 int      `json:"samples"`
+	SpanSeconds             float64  `json:"span_seconds"`
+	MaxOpen                 int      `json:"max_open"`
+	InUse                   float64  `json:"in_use"`
+	Peak                    int      `json:"peak"`
+	Utilization             float64  `json:"utilization"`
+	GrowthPerHour           float64  `json:"growth_per_hour"`
+	Waits                   int64    `json:"waits"`
+	Saturated               bool     `json:"saturated"`
+	TimeToSaturationSeconds *float64 `json:"time_to_saturation_seconds"`
+	Warning                 bool     `json:"warning"`
+}
+
+// ForecastHandler returns an http.Handler serving the forecasts of all connections with enough
+// history as a JSON array, ordered by connection name. The connection query parameter restricts it to
+// one connection. time_to_saturation_seconds is null when no saturation is projected.
+//
+// Example Usage:
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/admin/db/events", factory.EventStreamHandler(time.Second))
+//	mux.Handle("/admin/db/forecast", factory.ForecastHandler())
+func (f *ConnectionManager) ForecastHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		only := r.URL.Query().Get("connection")
+		f.history.mutex.Lock()
+		names := make([]string, 0, len(f.history.samples))
+		for name := range f.history.samples {
+			if only == "" || name == only {
+				names = append(names, name)
+			}
+		}
+		f.history.mutex.Unlock()
+		sort.Strings(names)
+
+		reports := make([]forecastReport, 0, len(names))
+		for _, name := range names {
+			forecast, err := f.Forecast(name)
+			if err != nil {
+				continue
+			}
+			report := forecastReport{Connection: name, Samples: forecast.Samples, SpanSeconds: forecast.Span.Seconds(),
+				MaxOpen: forecast.MaxOpen, InUse: forecast.InUse, Peak: forecast.Peak, Utilization: forecast.Utilization,
+				GrowthPerHour: forecast.GrowthPerHour, Waits: forecast.Waits, Saturated: forecast.Saturated, Warning: forecast.Warning}
+			if forecast.TimeToSaturation >= 0 {
+				seconds := forecast.TimeToSaturation.Seconds()
+				report.TimeToSaturationSeconds = &seconds
+			}
+			reports = append(reports, report)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(reports)
+	})
+}
@@ -0,0 +1,86 @@
+package connection
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// risingSamples returns one sample a minute with one more connection in use each time.
+func risingSamples(start time.Time, n, maxOpen int) []StatsSample {
+	samples := make([]StatsSample, n)
+	for i := range samples {
+		samples[i] = StatsSample{At: start.Add(time.Duration(i) * time.Minute), InUse: 10 + i, MaxOpen: maxOpen}
+	}
+	return samples
+}
+
+func TestForecastRisingTrend(t *testing.T) {
+	forecast := forecastPool("primary_db", risingSamples(time.Now(), 30, 100))
+	if forecast.GrowthPerHour < 50 || forecast.GrowthPerHour > 61 {
+		t.Fatalf("Expected a growth close to 60 connections per hour, got %v", forecast.GrowthPerHour)
+	}
+	if forecast.Saturated || forecast.TimeToSaturation < 50*time.Minute || forecast.TimeToSaturation > 90*time.Minute {
+		t.Fatalf("Expected a saturation in about an hour, got %+v", forecast)
+	}
+
+	flat := risingSamples(time.Now(), 10, 100)
+	for i := range flat {
+		flat[i].InUse = 20
+	}
+	if forecast := forecastPool("primary_db", flat); forecast.TimeToSaturation >= 0 || forecast.Warning || forecast.Utilization != 0.2 {
+		t.Fatalf("Expected no saturation for a flat pool at 20%%, got %+v", forecast)
+	}
+
+	flat[len(flat)-1].WaitCount = 3
+	if forecast := forecastPool("primary_db", flat); !forecast.Saturated || forecast.TimeToSaturation != 0 || !forecast.Warning {
+		t.Fatalf("Expected waits to mark the pool saturated, got %+v", forecast)
+	}
+}
+
+func TestForecastHandler(t *testing.T) {
+	factory := newTestFactory()
+	if _, err := factory.Forecast("primary_db"); !errors.Is(err, ErrInsufficientHistory) {
+		t.Fatalf("Expected ErrInsufficientHistory, got %v", err)
+	}
+
+	start := time.Now()
+	for _, sample := range risingSamples(start, 5, 20) {
+		factory.history.record(sample.At, map[string]sql.DBStats{
+			"primary_db": {InUse: sample.InUse, MaxOpenConnections: sample.MaxOpen},
+			"reports_db": {InUse: 1, MaxOpenConnections: 0},
+		})
+	}
+
+	recorder := httptest.NewRecorder()
+	factory.ForecastHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/forecast?connection=primary_db", nil))
+	var reports []map[string]any
+	if err := json.Unmarshal(recorder.Body.Bytes(), &reports); err != nil {
+		t.Fatalf("Invalid JSON %q: %v", recorder.Body.String(), err)
+	}
+	if len(reports) != 1 || reports[0]["connection"] != "primary_db" || reports[0]["warning"] != true ||
+		reports[0]["time_to_saturation_seconds"] == nil {
+		t.Fatalf("Unexpected reports: %v", reports)
+	}
+
+	recorder = httptest.NewRecorder()
+	factory.ForecastHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/forecast", nil))
+	reports = nil
+	_ = json.Unmarshal(recorder.Body.Bytes(), &reports)
+	if len(reports) != 2 || reports[1]["time_to_saturation_seconds"] != nil {
+		t.Fatalf("Expected the unlimited pool to have no saturation, got %v", reports)
+	}
+}
+
+func TestStartStatsHistory(t *testing.T) {
+	factory := newFakeFactory(t, FakeData{})
+	stop := factory.StartStatsHistory(10*time.Millisecond, 3)
+	time.Sleep(80 * time.Millisecond)
+	stop()
+	if samples := factory.StatsHistory("primary_db"); len(samples) != 3 {
+		t.Fatalf("Expected the history to keep 3 samples, got %d", len(samples))
+	}
+}
@@ -0,0 +1,126 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"io"
+	"log"
+	"syscall"
+)
+
+// MySQL client errors for a connection closed by the server.
+const (
+	crServerGone = 2006 // CR_SERVER_GONE_ERROR: "MySQL server has gone away".
+	crServerLost = 2013 // CR_SERVER_LOST: "Lost connection to MySQL server during query".
+)
+
+// isServerGone reports whether err means the server or the network closed the connection, as
+// happens when wait_timeout expires on an idle pooled connection.
+func isServerGone(err error) bool {
+	if err == nil {
+		return false
+	}
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == crServerGone || mysqlErr.Number == crServerLost
+	}
+	return errors.Is(err, mysqldriver.ErrInvalidConn) || errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// ErrNotRetried is wrapped around the connection errors of statements that were not retried because
+// the server may have applied them before the connection broke.
+var ErrNotRetried = errors.New("statement not retried after a connection error because it may have been applied; " +
+	"check its effect, or mark it with WithIdempotent if running it twice is safe")
+
+type idempotentKey struct{}
+
+// WithIdempotent marks the statements run with ctx as safe to run twice, e.g. upserts or updates setting
+// absolute values, so they are retried once after a connection error like reads. Other writes fail
+// with ErrNotRetried instead.
+//
+// Example Usage:
+//
+//	ctx := connection.WithIdempotent(r.Context())
+//	err := db.WithContext(ctx).Model(&user).Update("status", "active").Error
+func WithIdempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// isIdempotent reports whether ctx was marked with WithIdempotent.
+func isIdempotent(ctx context.Context) bool {
+	idempotent, _ := ctx.Value(idempotentKey{}).(bool)
+	return idempotent
+}
+
+type noStatementRetryKey struct{}
+
+// withoutStatementRetry disables the retry of single statements run with ctx, for callers retrying
+// the whole operation themselves.
+func withoutStatementRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noStatementRetryKey{}, true)
+}
+
+// statementRetryDisabled reports whether ctx was marked with withoutStatementRetry.
+func statementRetryDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noStatementRetryKey{}).(bool)
+	return disabled
+}
+
+// isIdempotentRead reports whether query only reads, so running it twice is safe.
+func isIdempotentRead(query string) bool {
+	switch firstKeyword(query) {
+	case "SELECT", "SHOW":
+		return true
+	}
+	return false
+}
+
+// shouldRetry reports whether a statement that failed with err is re-run: reads, and statements run
+// with WithIdempotent. The pool discards the broken connection and the driver checks the liveness of
+// idle connections before reuse, so the retry runs on a working connection.
+func (p *hookedConnPool) shouldRetry(ctx context.Context, query string, err error) bool {
+	if !p.retryReads || ctx.Err() != nil || statementRetryDisabled(ctx) || !isServerGone(err) || !(isIdempotentRead(query) || isIdempotent(ctx)) {
+		return false
+	}
+	log.Printf("Connection of %q closed by the server during a statement (%v), retrying once", p.name, err)
+	return true
+}
+
+// notRetried wraps the connection error of a write that was not retried with ErrNotRetried.
+func notRetried(query string, err error) error {
+	if !isServerGone(err) || isIdempotentRead(query) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrNotRetried, err)
+}
+
+// RunIdempotent runs fn on the named connection, and runs it once more after a connection error:
+// fn must be safe to run twice, e.g. a transaction whose effects are not applied unless it commits.
+// The statements of fn are not retried one by one, reads included, so that each runs at most twice.
+//
+// Example Usage:
+//
+//	err := connection.GetConnectionManager().RunIdempotent(ctx, "primary_db", func(db *gorm.DB) error {
+//		return db.Transaction(func(tx *gorm.DB) error {
+//			return tx.Model(&Account{}).Where("id = ?", id).Update("tier", "gold").Error
+//		})
+//	})
+func (f *ConnectionManager) RunIdempotent(ctx context.Context, name string, fn func(db *gorm.DB) error) error {
+	ctx = withoutStatementRetry(ctx)
+	for attempt := 1; ; attempt++ {
+		db, err := f.GetDBContext(ctx, name)
+		if err != nil {
+			return err
+		}
+		err = fn(db)
+		if attempt == 2 || ctx.Err() != nil || !isServerGone(err) {
+			return err
+		}
+		log.Printf("Connection of %q closed by the server during an idempotent operation (%v), retrying once", name, err)
+	}
+}
@@ -0,0 +1,161 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"sync/atomic"
+	"syscall"
+	"testing"
+)
+
+// goneAwayPool fails the first query with failure and records how many queries were attempted.
+type goneAwayPool struct {
+	recordingPool
+	failure  error
+	attempts atomic.Int64
+}
+
+func (p *goneAwayPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if p.attempts.Add(1) == 1 {
+		return nil, p.failure
+	}
+	return nil, errRecordingPoolQuery
+}
+
+func TestIsServerGone(t *testing.T) {
+	gone := []error{
+		&mysqldriver.MySQLError{Number: crServerGone},
+		fmt.Errorf("query: %w", &mysqldriver.MySQLError{Number: crServerLost}),
+		mysqldriver.ErrInvalidConn,
+		driver.ErrBadConn,
+		fmt.Errorf("write: %w", syscall.EPIPE),
+	}
+	for _, err := range gone {
+		if !isServerGone(err) {
+			t.Errorf("Expected %v to be classified as server gone", err)
+		}
+	}
+	for _, err := range []error{nil, errors.New("syntax"), &mysqldriver.MySQLError{Number: 1062}} {
+		if isServerGone(err) {
+			t.Errorf("Expected %v not to be classified as server gone", err)
+		}
+	}
+}
+
+func TestReadRetriedAfterServerGone(t *testing.T) {
+	cases := []struct {
+		query      string
+		retryReads bool
+		attempts   int64
+	}{
+		{"SELECT * FROM users", true, 2},
+		{"/* report */ SELECT 1", true, 2},
+		{"SELECT * FROM users", false, 1},
+		{"DELETE FROM users", true, 1},
+	}
+	for _, c := range cases {
+		pool := &goneAwayPool{failure: mysqldriver.ErrInvalidConn}
+		hooked := &hookedConnPool{pool: pool, name: "primary_db", hooks: &hookChain{}, retryReads: c.retryReads}
+
+		_, err := hooked.QueryContext(context.Background(), c.query)
+		if got := pool.attempts.Load(); got != c.attempts {
+			t.Errorf("%q (retry %v): %d attempts, want %d", c.query, c.retryReads, got, c.attempts)
+		}
+		if c.attempts == 1 && !errors.Is(err, mysqldriver.ErrInvalidConn) {
+			t.Errorf("%q: expected the original error, got %v", c.query, err)
+		}
+	}
+}
+
+// goneAwayExecPool fails the first write with failure and records how many writes were attempted.
+type goneAwayExecPool struct {
+	recordingPool
+	failure  error
+	attempts atomic.Int64
+}
+
+func (p *goneAwayExecPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if p.attempts.Add(1) == 1 {
+		return nil, p.failure
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func TestWritesRetriedOnlyWhenIdempotent(t *testing.T) {
+	pool := &goneAwayExecPool{failure: mysqldriver.ErrInvalidConn}
+	hooked := &hookedConnPool{pool: pool, name: "primary_db", hooks: &hookChain{}, retryReads: true}
+	_, err := hooked.ExecContext(context.Background(), "UPDATE accounts SET balance = balance - 10")
+	if pool.attempts.Load() != 1 || !errors.Is(err, ErrNotRetried) || !errors.Is(err, mysqldriver.ErrInvalidConn) {
+		t.Fatalf("Expected a single attempt failing with ErrNotRetried, got %d attempts and %v", pool.attempts.Load(), err)
+	}
+
+	pool = &goneAwayExecPool{failure: mysqldriver.ErrInvalidConn}
+	hooked.pool = pool
+	if _, err := hooked.ExecContext(WithIdempotent(context.Background()), "UPDATE accounts SET tier = 'gold'"); err != nil || pool.attempts.Load() != 2 {
+		t.Fatalf("Expected the idempotent write retried, got %d attempts and %v", pool.attempts.Load(), err)
+	}
+
+	pool = &goneAwayExecPool{failure: errors.New("syntax error")}
+	hooked.pool = pool
+	if _, err := hooked.ExecContext(context.Background(), "UPDAT accounts"); errors.Is(err, ErrNotRetried) {
+		t.Fatalf("Expected other errors unchanged, got %v", err)
+	}
+}
+
+func TestRunIdempotent(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["primary_db"] = newConnectorDB(t, &fakeConnector{})
+	ctx := context.Background()
+
+	calls := 0
+	err := factory.RunIdempotent(ctx, "primary_db", func(db *gorm.DB) error {
+		calls++
+		if isIdempotent(db.Statement.Context) || !statementRetryDisabled(db.Statement.Context) {
+			t.Error("Expected the statements of fn not to be retried one by one")
+		}
+		return mysqldriver.ErrInvalidConn
+	})
+	if calls != 2 || !errors.Is(err, mysqldriver.ErrInvalidConn) {
+		t.Fatalf("Expected two calls, got %d and %v", calls, err)
+	}
+
+	calls = 0
+	_ = factory.RunIdempotent(ctx, "primary_db", func(db *gorm.DB) error {
+		calls++
+		return errors.New("constraint violated")
+	})
+	if calls != 1 {
+		t.Fatalf("Expected no retry for other errors, got %d calls", calls)
+	}
+}
+
+// alwaysGonePool fails every query with a connection error and records how many were attempted.
+type alwaysGonePool struct {
+	recordingPool
+	attempts atomic.Int64
+}
+
+func (p *alwaysGonePool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	p.attempts.Add(1)
+	return nil, mysqldriver.ErrInvalidConn
+}
+
+func TestRunIdempotentSingleRetryLayer(t *testing.T) {
+	pool := &alwaysGonePool{}
+	hooked := &hookedConnPool{pool: pool, name: "primary_db", hooks: &hookChain{}, retryReads: true}
+	factory := newTestFactory()
+	factory.connections["primary_db"] = newConnectorDB(t, &fakeConnector{})
+
+	err := factory.RunIdempotent(context.Background(), "primary_db", func(db *gorm.DB) error {
+		_, err := hooked.QueryContext(db.Statement.Context, "SELECT balance FROM accounts")
+		return err
+	})
+	if got := pool.attempts.Load(); got != 2 || !errors.Is(err, mysqldriver.ErrInvalidConn) {
+		t.Fatalf("Expected the read run twice in all, got %d attempts and %v", got, err)
+	}
+}
@@ -0,0 +1,72 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureGormLog builds the GORM logger of config, runs fn with it and returns what it logged.
+func captureGormLog(t *testing.T, config DBConfig, fn func(l logger.Interface)) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	l := gormLogger(config)
+	os.Stdout = stdout
+
+	fn(l)
+	_ = w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestGormLoggerSettings(t *testing.T) {
+	ctx := context.Background()
+	statement := func(sql string) func() (string, int64) { return func() (string, int64) { return sql, 1 } }
+
+	config := DBConfig{
+		LogLevel:                  logger.Warn,
+		SlowThreshold:             50 * time.Millisecond,
+		IgnoreRecordNotFoundError: true,
+		ParameterizedQueries:      true,
+		Cluster:                   ClusterMetadata{Cluster: "orders"},
+	}
+	out := captureGormLog(t, config, func(l logger.Interface) {
+		l.Trace(ctx, time.Now(), statement("SELECT fast"), nil)
+		l.Trace(ctx, time.Now(), statement("SELECT missing"), gorm.ErrRecordNotFound)
+		l.Trace(ctx, time.Now(), statement("SELECT broken"), errors.New("boom"))
+		l.Trace(ctx, time.Now().Add(-100*time.Millisecond), statement("SELECT slow"), nil)
+
+		if sql, params := l.(gorm.ParamsFilter).ParamsFilter(ctx, "SELECT ?", 1); sql != "SELECT ?" || params != nil {
+			t.Errorf("Expected the arguments to be left out, got %v", params)
+		}
+	})
+	for _, unexpected := range []string{"SELECT fast", "SELECT missing"} {
+		if strings.Contains(out, unexpected) {
+			t.Errorf("Expected %q not to be logged, got %q", unexpected, out)
+		}
+	}
+	for _, expected := range []string{"[cluster=orders]", "SELECT broken", "SLOW SQL >= 50ms", "SELECT slow"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in the log, got %q", expected, out)
+		}
+	}
+
+	out = captureGormLog(t, DBConfig{}, func(l logger.Interface) {
+		l.Trace(ctx, time.Now(), statement("SELECT fast"), nil)
+		l.Trace(ctx, time.Now(), statement("SELECT missing"), gorm.ErrRecordNotFound)
+	})
+	if !strings.Contains(out, "SELECT fast") || !strings.Contains(out, "record not found") {
+		t.Errorf("Expected the default logger to log every statement, got %q", out)
+	}
+}
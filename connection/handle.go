@@ -0,0 +1,84 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// Handle is a typed reference to a managed connection, obtained once at wiring time with
+// ConnectionManager.Handle so that code using it no longer passes connection names around.
+type Handle struct {
+	factory *ConnectionManager
+	name    string
+}
+
+// Handle returns a handle to the named connection, or to the logical database of regional connections.
+//
+// Parameters:
+// - name: The name of an initialized connection or logical database.
+//
+// Returns:
+// - *Handle: The handle. It stays valid across reconnects and reconfigurations of the connection.
+// - error: An error wrapping ErrConnectionNotFound when no such connection is initialized.
+//
+// Notes:
+// - Call it while wiring the application, after InitDataSourceConnection, so a misspelled name fails at
+// startup rather than deep in a request. MustHandle panics instead, for package-level wiring.
+//
+// Example Usage:
+//
+//	orders, err := connection.GetConnectionManager().Handle("orders")
+//	if err != nil {
+//		log.Fatalf("Wiring failed: %v", err)
+//	}
+//	repo := NewOrderRepository(orders)
+//	...
+//	db, err := orders.DB(ctx)
+func (f *ConnectionManager) Handle(name string) (*Handle, error) {
+	f.mutex.Lock()
+	_, exists := f.connections[name]
+	_, regional := f.regions[name]
+	f.mutex.Unlock()
+	if !exists && !regional {
+		return nil, errNotFound(name, OpHandle)
+	}
+	return &Handle{factory: f, name: name}, nil
+}
+
+// MustHandle is Handle panicking when no such connection is initialized, like regexp.MustCompile.
+func (f *ConnectionManager) MustHandle(name string) *Handle {
+	handle, err := f.Handle(name)
+	if err != nil {
+		panic(fmt.Sprintf("connection: MustHandle(%q): %v", name, err))
+	}
+	return handle
+}
+
+// Name returns the name of the connection.
+func (h *Handle) Name() string {
+	return h.name
+}
+
+// DB returns the connection bound to ctx, like GetDBContext.
+func (h *Handle) DB(ctx context.Context) (*gorm.DB, error) {
+	return h.factory.GetDBContext(ctx, h.name)
+}
+
+// Stats returns the statistics of the connection pool, summed over its shards. They are zero when the connection is closed
+// or is a logical database of regional connections.
+func (h *Handle) Stats() sql.DBStats {
+	h.factory.mutex.Lock()
+	db, exists := h.factory.connections[h.name]
+	h.factory.mutex.Unlock()
+	if !exists {
+		return sql.DBStats{}
+	}
+	return poolStats(db)
+}
+
+// Close closes the connection, like CloseConnection. The handle fails afterwards.
+func (h *Handle) Close() error {
+	return h.factory.CloseConnection(h.name)
+}
@@ -0,0 +1,52 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestHandle(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["orders"] = newConnectorDB(t, &fakeConnector{})
+	ctx := context.Background()
+
+	orders, err := factory.Handle("orders")
+	if err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	if orders.Name() != "orders" {
+		t.Errorf("Name() = %q", orders.Name())
+	}
+	db, err := orders.DB(ctx)
+	if err != nil {
+		t.Fatalf("DB failed: %v", err)
+	}
+	var id int64
+	if err := db.Raw("SELECT CONNECTION_ID()").Scan(&id).Error; err != nil || id != 1 {
+		t.Fatalf("Unexpected query result %d, %v", id, err)
+	}
+	if stats := orders.Stats(); stats.OpenConnections != 1 {
+		t.Errorf("Expected one open connection, got %+v", stats)
+	}
+
+	if err := orders.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := orders.DB(ctx); err == nil {
+		t.Fatal("Expected an error for a closed connection, got nil")
+	}
+	if stats := orders.Stats(); stats.OpenConnections != 0 {
+		t.Errorf("Expected zero stats after Close, got %+v", stats)
+	}
+
+	if _, err := factory.Handle("ordres"); !errors.Is(err, ErrConnectionNotFound) {
+		t.Fatalf("Expected ErrConnectionNotFound for a misspelled connection, got %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected MustHandle to panic for a misspelled connection")
+		}
+	}()
+	factory.MustHandle("ordres")
+}
@@ -0,0 +1,41 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestHealthReport(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["primary_db"] = newConnectorDB(t, &fakeConnector{})
+	factory.configs["primary_db"] = DBConfig{DataSourceName: "app:hunter2@tcp(db-1:3306)/orders?tls=true", MaxOpen: 8}
+	closed := newConnectorDB(t, &fakeConnector{})
+	sqlDB, _ := closed.DB()
+	_ = sqlDB.Close()
+	factory.connections["closed_db"] = closed
+
+	document, err := factory.HealthReport(context.Background())
+	if err != nil {
+		t.Fatalf("HealthReport failed: %v", err)
+	}
+	if strings.Contains(string(document), "hunter2") {
+		t.Fatalf("Password leaked into the health report: %s", document)
+	}
+	var report healthReport
+	if err := json.Unmarshal(document, &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if report.Status != healthDown || len(report.Connections) != 2 {
+		t.Fatalf("Unexpected report %s", document)
+	}
+	closedHealth, primary := report.Connections[0], report.Connections[1]
+	if closedHealth.Name != "closed_db" || closedHealth.Status != healthDown || closedHealth.Error == "" {
+		t.Errorf("Unexpected closed connection health %+v", closedHealth)
+	}
+	want := configSummary{Addr: "db-1:3306", User: "app", Database: "orders", TLS: "true", MaxOpen: 8, Lifetime: "0s", IdleTime: "0s"}
+	if primary.Status != healthUp || primary.Config != want || primary.Pool.Open != 1 {
+		t.Errorf("Unexpected primary connection health %+v", primary)
+	}
+}
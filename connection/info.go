@@ -0,0 +1,90 @@
+package connection
+
+import (
+	"database/sql"
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// Connection describes a managed connection as established by Connect. A reconnect establishes a new
+// pool and description; GetConnection returns the current one.
+type Connection struct {
+	// Name is the name under which the connection is registered.
+	Name string
+
+	// Addr is the address of the data source name, e.g. "db-1:3306".
+	Addr string
+
+	// ServerHost is the host name reported by the server (@@hostname), which differs from Addr behind
+	// a proxy or a load balancer.
+	ServerHost string
+
+	// ServerVersion is the version reported by the server, e.g. "8.0.36".
+	ServerVersion string
+
+	// TLS reports whether sessions are encrypted, and TLSVersion with which protocol, e.g. "TLSv1.3".
+	TLS        bool
+	TLSVersion string
+
+	// Pool is the connection pool.
+	Pool *sql.DB
+
+	// ConnectedAt is when the connection was established.
+	ConnectedAt time.Time
+}
+
+// String renders the connection for logs, e.g. "primary_db: connected to db-1:3306 (MySQL 8.0.36) over TLS".
+func (c *Connection) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: connected to %s", c.Name, c.Addr)
+	if c.ServerHost != "" && !strings.HasPrefix(c.Addr, c.ServerHost) {
+		fmt.Fprintf(&b, " (host %s)", c.ServerHost)
+	}
+	if c.ServerVersion != "" {
+		fmt.Fprintf(&b, " (MySQL %s)", c.ServerVersion)
+	}
+	if c.TLS {
+		b.WriteString(" over TLS")
+	} else {
+		b.WriteString(" without TLS")
+	}
+	return b.String()
+}
+
+// GetConnection returns the description of the named connection.
+func (f *ConnectionManager) GetConnection(name string) (*Connection, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	info, exists := f.info[name]
+	if !exists {
+		return nil, errNotFound(name, OpGetConnection)
+	}
+	return info, nil
+}
+
+// describeConnection reads the server version, host name and TLS state of db. On error, the returned
+// description holds what is known without the server.
+func describeConnection(name string, db *gorm.DB, addr string) (*Connection, error) {
+	info := &Connection{Name: name, Addr: addr, ConnectedAt: time.Now()}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return info, err
+	}
+	info.Pool = sqlDB
+
+	var version, host sql.NullString
+	if err := db.Raw("SELECT VERSION(), @@hostname").Row().Scan(&version, &host); err != nil {
+		return info, fmt.Errorf("failed to read server version: %w", err)
+	}
+	info.ServerVersion, info.ServerHost = version.String, host.String
+
+	var variable, tlsVersion string
+	if err := db.Raw("SHOW SESSION STATUS LIKE 'Ssl_version'").Row().Scan(&variable, &tlsVersion); err != nil {
+		return info, fmt.Errorf("failed to read TLS state: %w", err)
+	}
+	info.TLS, info.TLSVersion = tlsVersion != "", tlsVersion
+	return info, nil
+}
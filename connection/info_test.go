@@ -0,0 +1,51 @@
+package connection
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConnectionString(t *testing.T) {
+	conn := &Connection{Name: "primary_db", Addr: "db-1:3306", ServerHost: "db-1", ServerVersion: "8.0.36", TLS: true}
+	if got, want := conn.String(), "primary_db: connected to db-1:3306 (MySQL 8.0.36) over TLS"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	conn = &Connection{Name: "proxy_db", Addr: "proxy:6033", ServerHost: "db-2"}
+	if got, want := conn.String(), "proxy_db: connected to proxy:6033 (host db-2) without TLS"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestDescribeConnection(t *testing.T) {
+	// fakeConn answers every query with one column, so the version scan fails but the pool is known.
+	db := newConnectorDB(t, &fakeConnector{})
+	info, err := describeConnection("primary_db", db, "db-1:3306")
+	if err == nil {
+		t.Fatal("Expected an error reading the server version, got nil")
+	}
+	if info.Name != "primary_db" || info.Addr != "db-1:3306" || info.Pool == nil || info.ConnectedAt.IsZero() {
+		t.Fatalf("Unexpected partial description %+v", info)
+	}
+
+	factory := newTestFactory()
+	factory.info = map[string]*Connection{"primary_db": info}
+	if got, err := factory.GetConnection("primary_db"); err != nil || got != info {
+		t.Fatalf("GetConnection() = %v, %v", got, err)
+	}
+	if _, err := factory.GetConnection("missing_db"); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
+
+func TestCloseAllConnectionsForgetsDescriptions(t *testing.T) {
+	factory := newFakeFactory(t, FakeData{})
+	if _, err := factory.GetConnection("primary_db"); err != nil {
+		t.Fatalf("GetConnection failed: %v", err)
+	}
+	if errs := factory.CloseAllConnections(); len(errs) != 0 {
+		t.Fatalf("CloseAllConnections failed: %v", errs)
+	}
+	if _, err := factory.GetConnection("primary_db"); !errors.Is(err, ErrConnectionNotFound) {
+		t.Fatalf("Expected the description to be removed with the connection, got %v", err)
+	}
+}
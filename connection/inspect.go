@@ -0,0 +1,429 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Drift kinds reported in DriftIssue.Kind.
+const (
+	DriftMissingTable        = "missing_table"
+	DriftMissingColumn       = "missing_column"
+	DriftExtraColumn         = "extra_column"
+	DriftTypeMismatch        = "type_mismatch"
+	DriftNullabilityMismatch = "nullability_mismatch"
+	DriftMissingIndex        = "missing_index"
+)
+
+// SchemaInfo describes the tables of the current database of a connection, as returned by Inspect.
+type SchemaInfo struct {
+	Database string
+	Tables   []TableInfo
+}
+
+// TableInfo describes one base table.
+type TableInfo struct {
+	Name   string
+	Engine string
+
+	// Rows, DataBytes and IndexBytes are the estimates maintained by the storage engine.
+	Rows       int64
+	DataBytes  int64
+	IndexBytes int64
+
+	Columns     []ColumnInfo
+	Indexes     []IndexInfo
+	ForeignKeys []ForeignKeyInfo
+}
+
+// ColumnInfo describes a table column.
+type ColumnInfo struct {
+	Name string
+
+	// Type is the full column type, e.g. "varchar(191)" or "bigint unsigned".
+	Type     string
+	Nullable bool
+	Default  *string
+
+	// Extra holds attributes such as "auto_increment".
+	Extra string
+}
+
+// IndexInfo describes an index. The primary key is reported as the index named "PRIMARY".
+type IndexInfo struct {
+	Name    string
+	Columns []string
+	Unique  bool
+}
+
+// ForeignKeyInfo describes a foreign key constraint.
+type ForeignKeyInfo struct {
+	Name              string
+	Columns           []string
+	ReferencedTable   string
+	ReferencedColumns []string
+	OnUpdate          string
+	OnDelete          string
+}
+
+// Table returns the table with the given name, or nil.
+func (s *SchemaInfo) Table(name string) *TableInfo {
+	for i := range s.Tables {
+		if strings.EqualFold(s.Tables[i].Name, name) {
+			return &s.Tables[i]
+		}
+	}
+	return nil
+}
+
+// Column returns the column with the given name, or nil.
+func (t *TableInfo) Column(name string) *ColumnInfo {
+	for i := range t.Columns {
+		if strings.EqualFold(t.Columns[i].Name, name) {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// Inspect reads the schema of the current database of a managed connection from information_schema.
+//
+// Parameters:
+// - ctx: Context bounding the queries.
+// - name: The name of the managed connection.
+//
+// Returns:
+// - *SchemaInfo: Base tables with their columns, indexes, foreign keys and size estimates, sorted by name.
+// - error: An error if the connection does not exist or a query fails.
+//
+// Example Usage:
+//
+//	info, err := connection.GetConnectionManager().Inspect(ctx, "primary_db")
+//	if err != nil {
+//		log.Fatalf("Failed to inspect schema: %v", err)
+//	}
+//	for _, table := range info.Tables {
+//		fmt.Printf("%s: ~%d rows, %d bytes\n", table.Name, table.Rows, table.DataBytes+table.IndexBytes)
+//	}
+func (f *ConnectionManager) Inspect(ctx context.Context, name string) (*SchemaInfo, error) {
+	db, err := f.GetDB(name)
+	if err != nil {
+		return nil, err
+	}
+	db = db.WithContext(ctx)
+
+	info := &SchemaInfo{}
+	if err := db.Raw("SELECT DATABASE()").Row().Scan(&info.Database); err != nil {
+		return nil, fmt.Errorf("failed to inspect %q: %w", name, err)
+	}
+	if err := inspectTables(db, info); err != nil {
+		return nil, fmt.Errorf("failed to inspect %q: %w", name, err)
+	}
+	return info, nil
+}
+
+func inspectTables(db *gorm.DB, info *SchemaInfo) error {
+	var tables []struct {
+		TableName   string
+		Engine      *string
+		TableRows   *int64
+		DataLength  *int64
+		IndexLength *int64
+	}
+	err := db.Raw(`SELECT TABLE_NAME AS table_name, ENGINE AS engine, TABLE_ROWS AS table_rows,
+		DATA_LENGTH AS data_length, INDEX_LENGTH AS index_length
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME`).Scan(&tables).Error
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]*TableInfo, len(tables))
+	info.Tables = make([]TableInfo, len(tables))
+	for i, t := range tables {
+		info.Tables[i] = TableInfo{Name: t.TableName, Engine: derefString(t.Engine),
+			Rows: derefInt64(t.TableRows), DataBytes: derefInt64(t.DataLength), IndexBytes: derefInt64(t.IndexLength)}
+		byName[t.TableName] = &info.Tables[i]
+	}
+
+	var columns []struct {
+		TableName     string
+		ColumnName    string
+		ColumnType    string
+		IsNullable    string
+		ColumnDefault *string
+		Extra         string
+	}
+	err = db.Raw(`SELECT TABLE_NAME AS table_name, COLUMN_NAME AS column_name, COLUMN_TYPE AS column_type,
+		IS_NULLABLE AS is_nullable, COLUMN_DEFAULT AS column_default, EXTRA AS extra
+		FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE()
+		ORDER BY TABLE_NAME, ORDINAL_POSITION`).Scan(&columns).Error
+	if err != nil {
+		return err
+	}
+	for _, c := range columns {
+		if t := byName[c.TableName]; t != nil {
+			t.Columns = append(t.Columns, ColumnInfo{Name: c.ColumnName, Type: c.ColumnType,
+				Nullable: c.IsNullable == "YES", Default: c.ColumnDefault, Extra: c.Extra})
+		}
+	}
+
+	var indexColumns []struct {
+		TableName  string
+		IndexName  string
+		NonUnique  int
+		ColumnName *string
+	}
+	err = db.Raw(`SELECT TABLE_NAME AS table_name, INDEX_NAME AS index_name, NON_UNIQUE AS non_unique,
+		COLUMN_NAME AS column_name
+		FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE()
+		ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`).Scan(&indexColumns).Error
+	if err != nil {
+		return err
+	}
+	for _, ic := range indexColumns {
+		t := byName[ic.TableName]
+		if t == nil {
+			continue
+		}
+		if n := len(t.Indexes); n == 0 || t.Indexes[n-1].Name != ic.IndexName {
+			t.Indexes = append(t.Indexes, IndexInfo{Name: ic.IndexName, Unique: ic.NonUnique == 0})
+		}
+		// Functional index parts have no column name.
+		if ic.ColumnName != nil {
+			idx := &t.Indexes[len(t.Indexes)-1]
+			idx.Columns = append(idx.Columns, *ic.ColumnName)
+		}
+	}
+
+	var fkColumns []struct {
+		TableName            string
+		ConstraintName       string
+		ColumnName           string
+		ReferencedTableName  string
+		ReferencedColumnName string
+		UpdateRule           string
+		DeleteRule           string
+	}
+	err = db.Raw(`SELECT k.TABLE_NAME AS table_name, k.CONSTRAINT_NAME AS constraint_name, k.COLUMN_NAME AS column_name,
+		k.REFERENCED_TABLE_NAME AS referenced_table_name, k.REFERENCED_COLUMN_NAME AS referenced_column_name,
+		r.UPDATE_RULE AS update_rule, r.DELETE_RULE AS delete_rule
+		FROM information_schema.KEY_COLUMN_USAGE k
+		JOIN information_schema.REFERENTIAL_CONSTRAINTS r
+			ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME AND r.TABLE_NAME = k.TABLE_NAME
+		WHERE k.TABLE_SCHEMA = DATABASE() AND k.REFERENCED_TABLE_NAME IS NOT NULL
+		ORDER BY k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION`).Scan(&fkColumns).Error
+	if err != nil {
+		return err
+	}
+	for _, fc := range fkColumns {
+		t := byName[fc.TableName]
+		if t == nil {
+			continue
+		}
+		if n := len(t.ForeignKeys); n == 0 || t.ForeignKeys[n-1].Name != fc.ConstraintName {
+			t.ForeignKeys = append(t.ForeignKeys, ForeignKeyInfo{Name: fc.ConstraintName, ReferencedTable: fc.ReferencedTableName,
+				OnUpdate: fc.UpdateRule, OnDelete: fc.DeleteRule})
+		}
+		fk := &t.ForeignKeys[len(t.ForeignKeys)-1]
+		fk.Columns = append(fk.Columns, fc.ColumnName)
+		fk.ReferencedColumns = append(fk.ReferencedColumns, fc.ReferencedColumnName)
+	}
+	return nil
+}
+
+// DriftIssue is one difference between a GORM model and the live schema.
+type DriftIssue struct {
+	// Kind is one of the Drift constants.
+	Kind   string
+	Table  string
+	Column string
+
+	// Expected and Actual describe the model's and the database's definition.
+	Expected string
+	Actual   string
+}
+
+func (i DriftIssue) String() string {
+	target := i.Table
+	if i.Column != "" {
+		target += "." + i.Column
+	}
+	if i.Expected == "" && i.Actual == "" {
+		return fmt.Sprintf("%s: %s", i.Kind, target)
+	}
+	return fmt.Sprintf("%s: %s (model %q, database %q)", i.Kind, target, i.Expected, i.Actual)
+}
+
+// DriftReport compares GORM models with the live schema of a managed connection, e.g. as a CI check
+// that migrations and models agree.
+//
+// Parameters:
+// - ctx: Context bounding the inspection.
+// - name: The name of the managed connection.
+// - models: Pointers to model structs, as passed to AutoMigrate.
+//
+// Returns:
+// - []DriftIssue: The differences, sorted by table; empty when the schema matches.
+// - error: An error if the connection does not exist, a model cannot be parsed, or a query fails.
+//
+// Behavior:
+// 1. Missing tables, missing columns and columns that exist only in the database are reported.
+// 2. Column types are compared with the type GORM would create, ignoring integer display widths;
+// nullability is compared for fields tagged "not null" and primary keys.
+// 3. Indexes and unique indexes declared on the model are matched by their column list, so an index
+// created under a different name is not reported.
+func (f *ConnectionManager) DriftReport(ctx context.Context, name string, models ...interface{}) ([]DriftIssue, error) {
+	info, err := f.Inspect(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	db, err := f.GetDB(name)
+	if err != nil {
+		return nil, err
+	}
+	return schemaDrift(db, info, models...)
+}
+
+// schemaDrift compares models, parsed with db's naming strategy and dialector, with info.
+func schemaDrift(db *gorm.DB, info *SchemaInfo, models ...interface{}) ([]DriftIssue, error) {
+	var issues []DriftIssue
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		sch := stmt.Schema
+
+		table := info.Table(stmt.Table)
+		if table == nil {
+			issues = append(issues, DriftIssue{Kind: DriftMissingTable, Table: stmt.Table})
+			continue
+		}
+
+		modelColumns := make(map[string]bool)
+		for _, field := range sch.Fields {
+			if field.DBName == "" || field.IgnoreMigration {
+				continue
+			}
+			modelColumns[strings.ToLower(field.DBName)] = true
+
+			column := table.Column(field.DBName)
+			if column == nil {
+				issues = append(issues, DriftIssue{Kind: DriftMissingColumn, Table: table.Name, Column: field.DBName})
+				continue
+			}
+			expected := normalizeColumnType(db.Dialector.DataTypeOf(field))
+			if expected != "" && expected != normalizeColumnType(column.Type) {
+				issues = append(issues, DriftIssue{Kind: DriftTypeMismatch, Table: table.Name, Column: column.Name,
+					Expected: expected, Actual: column.Type})
+			}
+			if (field.NotNull || field.PrimaryKey) && column.Nullable {
+				issues = append(issues, DriftIssue{Kind: DriftNullabilityMismatch, Table: table.Name, Column: column.Name,
+					Expected: "NOT NULL", Actual: "NULL"})
+			}
+		}
+		for _, column := range table.Columns {
+			if !modelColumns[strings.ToLower(column.Name)] {
+				issues = append(issues, DriftIssue{Kind: DriftExtraColumn, Table: table.Name, Column: column.Name, Actual: column.Type})
+			}
+		}
+
+		for _, idx := range modelIndexes(sch.ParseIndexes()) {
+			if !table.hasIndexOn(idx.columns) {
+				issues = append(issues, DriftIssue{Kind: DriftMissingIndex, Table: table.Name,
+					Expected: fmt.Sprintf("%s(%s)", idx.name, strings.Join(idx.columns, ","))})
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Table < issues[j].Table })
+	return issues, nil
+}
+
+// declaredIndex is an index declared on a model.
+type declaredIndex struct {
+	name    string
+	columns []string
+}
+
+// modelIndexes flattens GORM's parsed indexes, sorted by name for stable reports.
+func modelIndexes(parsed map[string]schema.Index) []declaredIndex {
+	indexes := make([]declaredIndex, 0, len(parsed))
+	for name, idx := range parsed {
+		d := declaredIndex{name: name}
+		for _, option := range idx.Fields {
+			if option.Field != nil {
+				d.columns = append(d.columns, option.Field.DBName)
+			}
+		}
+		if len(d.columns) > 0 {
+			indexes = append(indexes, d)
+		}
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].name < indexes[j].name })
+	return indexes
+}
+
+// hasIndexOn reports whether the table has an index on exactly the given columns, in order.
+func (t *TableInfo) hasIndexOn(columns []string) bool {
+	for _, idx := range t.Indexes {
+		if len(idx.Columns) != len(columns) {
+			continue
+		}
+		match := true
+		for i := range columns {
+			if !strings.EqualFold(idx.Columns[i], columns[i]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	intDisplayWidth = regexp.MustCompile(`\b(tinyint|smallint|mediumint|int|integer|bigint)\(\d+\)`)
+	columnTypeTail  = regexp.MustCompile(`\s+(auto_increment|not null|null|default\b.*|comment\b.*|primary key)$`)
+)
+
+// normalizeColumnType reduces a column type to the form compared by DriftReport: lower case, without
+// integer display widths or column attributes, with "boolean" spelled as MySQL stores it.
+func normalizeColumnType(t string) string {
+	t = strings.ToLower(strings.TrimSpace(t))
+	for {
+		trimmed := columnTypeTail.ReplaceAllString(t, "")
+		if trimmed == t {
+			break
+		}
+		t = trimmed
+	}
+	switch t {
+	case "boolean", "bool", "tinyint(1)":
+		return "tinyint(1)"
+	case "integer":
+		t = "int"
+	}
+	return intDisplayWidth.ReplaceAllString(t, "$1")
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func derefInt64(n *int64) int64 {
+	if n == nil {
+		return 0
+	}
+	return *n
+}
@@ -0,0 +1,64 @@
+package connection
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type driftTestOrder struct {
+	ID        uint64 `gorm:"primaryKey"`
+	Customer  string `gorm:"size:64;not null;index:idx_customer"`
+	Paid      bool
+	Total     int64
+	CreatedAt time.Time
+}
+
+func TestSchemaDrift(t *testing.T) {
+	db := newDryRunDB(t)
+	info := &SchemaInfo{Tables: []TableInfo{{
+		Name: "drift_test_orders",
+		Columns: []ColumnInfo{
+			{Name: "id", Type: "bigint(20) unsigned", Extra: "auto_increment"},
+			{Name: "customer", Type: "varchar(64)", Nullable: true},
+			{Name: "paid", Type: "tinyint(1)", Nullable: true},
+			{Name: "total", Type: "int", Nullable: true},
+			{Name: "legacy_flag", Type: "char(1)", Nullable: true},
+		},
+		Indexes: []IndexInfo{{Name: "PRIMARY", Columns: []string{"id"}, Unique: true}},
+	}}}
+
+	issues, err := schemaDrift(db, info, &driftTestOrder{}, &repoTestUser{})
+	if err != nil {
+		t.Fatalf("schemaDrift failed: %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, issue := range issues {
+		got[issue.Kind+" "+issue.Table+"."+issue.Column] = true
+	}
+	want := []string{
+		DriftNullabilityMismatch + " drift_test_orders.customer",
+		DriftTypeMismatch + " drift_test_orders.total",
+		DriftMissingColumn + " drift_test_orders.created_at",
+		DriftExtraColumn + " drift_test_orders.legacy_flag",
+		DriftMissingIndex + " drift_test_orders.",
+		DriftMissingTable + " repo_test_users.",
+	}
+	for _, w := range want {
+		if !got[w] {
+			t.Errorf("Expected issue %q, got %v", w, issues)
+		}
+	}
+	if len(issues) != len(want) {
+		t.Errorf("Expected %d issues, got %d: %v", len(want), len(issues), issues)
+	}
+	for _, issue := range issues {
+		if issue.Column == "id" || issue.Column == "paid" {
+			t.Errorf("Unexpected issue for a matching column: %v", issue)
+		}
+		if issue.Kind == DriftMissingIndex && !strings.Contains(issue.Expected, "idx_customer(customer)") {
+			t.Errorf("Unexpected missing index description %q", issue.Expected)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// MySQL errors classified by ErrorClass.
+const (
+	erDupEntry          = 1062 // ER_DUP_ENTRY
+	erParseError        = 1064 // ER_PARSE_ERROR
+	erAccessDenied      = 1045 // ER_ACCESS_DENIED_ERROR
+	erTableAccessDenied = 1142 // ER_TABLEACCESS_DENIED_ERROR
+	erNoSuchTable       = 1146 // ER_NO_SUCH_TABLE
+	erQueryTimeout      = 3024 // ER_QUERY_TIMEOUT: max_execution_time exceeded
+)
+
+// ErrorClass returns a low-cardinality class of a database error, as recorded in the error.type
+// attribute of the metrics and spans: "canceled", "timeout", "connection", "deadlock", "lock_timeout",
+// "duplicate_key", "syntax", "access_denied", "no_such_table" or "error".
+func ErrorClass(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case isServerGone(err):
+		return "connection"
+	}
+	var mysqlErr *mysqldriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case erLockDeadlock:
+			return "deadlock"
+		case erLockWaitTimeout:
+			return "lock_timeout"
+		case erQueryTimeout:
+			return "timeout"
+		case erDupEntry:
+			return "duplicate_key"
+		case erParseError:
+			return "syntax"
+		case erAccessDenied, erTableAccessDenied:
+			return "access_denied"
+		case erNoSuchTable:
+			return "no_such_table"
+		}
+	}
+	return "error"
+}
+
+// otelTracer holds the tracer of RegisterOTelTracing.
+type otelTracer struct {
+	tracer trace.Tracer
+}
+
+// RegisterOTelTracing creates a client span for every statement run on the connections with
+// DBConfig.InstrumentDriver, including statements run through GetSQLDB without GORM.
+//
+// Parameters:
+// - tracer: The tracer the spans are created with, typically otel.Tracer(...).
+//
+// Returns:
+// - func(): Stops the tracing.
+//
+// Behavior:
+// 1. Spans are named after the statement keyword (SELECT, INSERT, ...) and carry db.system,
+// db.client.connection.pool.name, db.operation.name, db.query.text (the statement with its placeholders,
+// without the arguments) and the cluster identity of the connection (DBConfig.Cluster).
+// 2. Failed statements record the error and carry its ErrorClass in error.type.
+// 3. A query span ends when the driver returns the rows, before they are read.
+//
+// Example Usage:
+//
+//	stop := connection.GetConnectionManager().RegisterOTelTracing(otel.Tracer("orders-service"))
+//	defer stop()
+func (f *ConnectionManager) RegisterOTelTracing(tracer trace.Tracer) func() {
+	holder := &otelTracer{tracer: tracer}
+	f.tracer.Store(holder)
+	return func() {
+		f.tracer.CompareAndSwap(holder, nil)
+	}
+}
+
+// instrumentedConnector records the statements of its connections in the metrics of RegisterOTelMetrics
+// and the spans of RegisterOTelTracing, below GORM (DBConfig.InstrumentDriver).
+type instrumentedConnector struct {
+	connector driver.Connector
+	factory   *ConnectionManager
+	name      string
+	cluster   ClusterMetadata
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn, connector: c}, nil
+}
+
+func (c *instrumentedConnector) Driver() driver.Driver {
+	return c.connector.Driver()
+}
+
+// observe records a statement that started at started and failed with err, if any.
+func (c *instrumentedConnector) observe(ctx context.Context, query string, started time.Time, err error) {
+	instruments, tracer := c.factory.otel.Load(), c.factory.tracer.Load()
+	if instruments == nil && tracer == nil {
+		return
+	}
+	operation := firstKeyword(query)
+	attrs := append(otelAttributes(c.name, c.cluster), attribute.String("db.operation.name", operation))
+	if err != nil {
+		attrs = append(attrs, attribute.String("error.type", ErrorClass(err)))
+	}
+	if instruments != nil {
+		instruments.duration.Record(ctx, time.Since(started).Seconds(), metric.WithAttributes(attrs...))
+	}
+	if tracer != nil {
+		if operation == "" {
+			operation = "statement"
+		}
+		_, span := tracer.tracer.Start(ctx, operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithTimestamp(started),
+			trace.WithAttributes(append(attrs, attribute.String("db.query.text", query))...))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, ErrorClass(err))
+		}
+		span.End()
+	}
+}
+
+// instrumentedConn observes the statements run on a driver connection. Statements the driver skips
+// (driver.ErrSkip) are observed when database/sql runs them as prepared statements.
+type instrumentedConn struct {
+	driver.Conn
+	connector *instrumentedConnector
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{Stmt: stmt, connector: c.connector, query: query}, nil
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	started := time.Now()
+	result, err := e.ExecContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.connector.observe(ctx, query, started, err)
+	}
+	return result, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	started := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.connector.observe(ctx, query, started, err)
+	}
+	return rows, err
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *instrumentedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// instrumentedStmt observes the executions of a prepared statement.
+type instrumentedStmt struct {
+	driver.Stmt
+	connector *instrumentedConnector
+	query     string
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	started := time.Now()
+	var result driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = e.ExecContext(ctx, args)
+	} else {
+		result, err = s.Stmt.Exec(namedValues(args))
+	}
+	s.connector.observe(ctx, s.query, started, err)
+	return result, err
+}
+
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	started := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(namedValues(args))
+	}
+	s.connector.observe(ctx, s.query, started, err)
+	return rows, err
+}
+
+// ColumnConverter keeps the argument conversions of the driver statement.
+func (s *instrumentedStmt) ColumnConverter(idx int) driver.ValueConverter {
+	if c, ok := s.Stmt.(driver.ColumnConverter); ok {
+		return c.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
+
+// namedValues drops the names of args for the driver.Stmt methods without context.
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
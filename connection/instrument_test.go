@@ -0,0 +1,130 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestInstrumentedDriver(t *testing.T) {
+	factory := newTestFactory()
+	reader := sdkmetric.NewManualReader()
+	stopMetrics, err := factory.RegisterOTelMetrics(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter("test"))
+	if err != nil {
+		t.Fatalf("RegisterOTelMetrics failed: %v", err)
+	}
+	defer stopMetrics()
+	spans := tracetest.NewSpanRecorder()
+	stopTracing := factory.RegisterOTelTracing(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)).Tracer("test"))
+
+	sqlDB := sql.OpenDB(&instrumentedConnector{connector: &fakeConnector{}, factory: factory, name: "primary_db"})
+	defer sqlDB.Close()
+	var id int64
+	if err := sqlDB.QueryRowContext(context.Background(), "SELECT CONNECTION_ID()").Scan(&id); err != nil || id != 1 {
+		t.Fatalf("Query failed: %d, %v", id, err)
+	}
+
+	ended := spans.Ended()
+	if len(ended) != 1 || ended[0].Name() != "SELECT" {
+		t.Fatalf("Expected one SELECT span, got %v", ended)
+	}
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("Failed to collect metrics: %v", err)
+	}
+	var recorded uint64
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name == otelOperationDuration {
+				for _, point := range m.Data.(metricdata.Histogram[float64]).DataPoints {
+					recorded += point.Count
+				}
+			}
+		}
+	}
+	if recorded != 1 {
+		t.Fatalf("Expected one recorded statement, got %d", recorded)
+	}
+
+	stopTracing()
+	_ = sqlDB.QueryRowContext(context.Background(), "SELECT CONNECTION_ID()").Scan(&id)
+	if len(spans.Ended()) != 1 {
+		t.Fatal("Expected no span after tracing stopped")
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	for err, want := range map[error]string{
+		context.DeadlineExceeded:                             "timeout",
+		fmt.Errorf("query: %w", context.Canceled):            "canceled",
+		&mysqldriver.MySQLError{Number: erLockDeadlock}:      "deadlock",
+		&mysqldriver.MySQLError{Number: erDupEntry}:          "duplicate_key",
+		&mysqldriver.MySQLError{Number: crServerGone}:        "connection",
+		&mysqldriver.MySQLError{Number: erTableAccessDenied}: "access_denied",
+		errors.New("boom"):                                   "error",
+	} {
+		if got := ErrorClass(err); got != want {
+			t.Errorf("ErrorClass(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestGetSQLDB(t *testing.T) {
+	factory := newTestFactory()
+	db := newConnectorDB(t, &fakeConnector{})
+	factory.connections["primary_db"] = db
+	sqlDB, err := factory.GetSQLDB("primary_db")
+	if want, _ := db.DB(); err != nil || sqlDB != want {
+		t.Fatalf("GetSQLDB() = %v, %v", sqlDB, err)
+	}
+	if _, err := factory.GetSQLDB("missing_db"); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
+
+func TestPoolSharedAcrossAccessLayers(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	ctx := context.Background()
+	sqlDB, err := factory.GetSQLDB("primary_db")
+	if err != nil {
+		t.Fatalf("GetSQLDB failed: %v", err)
+	}
+
+	db, _ := factory.GetDB("primary_db")
+	dbContext, _ := factory.GetDBContext(ctx, "primary_db")
+	current, _ := factory.CurrentDB(ctx, "primary_db")
+	for i, handle := range []*gorm.DB{db, dbContext, current, db.Session(&gorm.Session{QueryFields: true})} {
+		if pool, err := handle.DB(); err != nil || pool != sqlDB {
+			t.Fatalf("Expected handle %d to share the pool of GetSQLDB, got %p: %v", i, pool, err)
+		}
+	}
+
+	// One limit for both layers: with the only connection held through database/sql, GORM has to wait.
+	sqlDB.SetMaxOpenConns(1)
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	var count int64
+	if err := db.WithContext(timeout).Model(&fakeTestUser{}).Count(&count).Error; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected GORM to wait for the connection held through GetSQLDB, got %v", err)
+	}
+	if stats := poolStats(db); stats.InUse != 1 || stats.OpenConnections != 1 {
+		t.Fatalf("Expected one connection in use for both layers, got %+v", stats)
+	}
+	conn.Close()
+	if err := db.Model(&fakeTestUser{}).Count(&count).Error; err != nil || count != 3 {
+		t.Fatalf("Expected GORM to use the released connection, got %d: %v", count, err)
+	}
+}
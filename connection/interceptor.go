@@ -0,0 +1,360 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"gorm.io/gorm"
+	"sync"
+	"sync/atomic"
+)
+
+// hookedStatement is a statement about to be sent to the server on a managed connection.
+// Hooks may rewrite SQL; Args are the bound arguments.
+type hookedStatement struct {
+	// Conn is the name of the managed connection the statement runs on.
+	Conn string
+
+	// SQL is the statement text. Hooks may rewrite it.
+	SQL string
+
+	// Args are the placeholder arguments of the statement.
+	Args []interface{}
+
+	// InTx reports a statement of an explicit transaction; other statements run in autocommit mode.
+	InTx bool
+}
+
+// statementHook inspects a statement before it is sent to the server.
+// Returning an error rejects the statement; the error is returned to the caller unchanged.
+type statementHook func(ctx context.Context, stmt *hookedStatement) error
+
+// namedHook is a statementHook registered under a unique name.
+type namedHook struct {
+	name string
+	fn   statementHook
+}
+
+// hookChain is the ordered, mutable list of statement hooks of one connection.
+// It is shared by the pool and all transactions started from it, so hooks added or removed
+// at runtime apply to statements issued afterwards.
+type hookChain struct {
+	mutex sync.RWMutex
+	hooks []namedHook
+}
+
+// set adds the hook under name, replacing an existing hook with the same name in place.
+func (c *hookChain) set(name string, fn statementHook) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i := range c.hooks {
+		if c.hooks[i].name == name {
+			c.hooks[i].fn = fn
+			return
+		}
+	}
+	c.hooks = append(c.hooks, namedHook{name: name, fn: fn})
+}
+
+// has reports whether a hook is registered under name.
+func (c *hookChain) has(name string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	for _, hook := range c.hooks {
+		if hook.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// remove deletes the hook registered under name and reports whether it existed.
+func (c *hookChain) remove(name string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for i := range c.hooks {
+		if c.hooks[i].name == name {
+			c.hooks = append(c.hooks[:i:i], c.hooks[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// run passes the statement through every hook in order and stops at the first error.
+func (c *hookChain) run(ctx context.Context, conn, query string, args []interface{}) (*hookedStatement, error) {
+	return c.apply(ctx, &hookedStatement{Conn: conn, SQL: query, Args: args})
+}
+
+// apply passes stmt through every hook in order and stops at the first error.
+func (c *hookChain) apply(ctx context.Context, stmt *hookedStatement) (*hookedStatement, error) {
+	c.mutex.RLock()
+	hooks := c.hooks
+	c.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook.fn(ctx, stmt); err != nil {
+			return stmt, err
+		}
+	}
+	return stmt, nil
+}
+
+// hookedConnPool wraps the connection pool of a managed *gorm.DB so every statement passes through the
+// connection's hook chain, whatever GORM API issued it (Create, Find, Raw, Exec, migrations, ...).
+type hookedConnPool struct {
+	pool  gorm.ConnPool
+	sqlDB *sql.DB
+	name  string
+	hooks *hookChain
+
+	// retryReads re-runs read-only statements once after the server closed their connection.
+	retryReads bool
+
+	// isolation is the isolation level of the transactions begun without one, see
+	// QueryPolicy.TransactionIsolation.
+	isolation sql.IsolationLevel
+
+	// control applies the pool settings of the connection, or is nil for pools not established by
+	// the manager.
+	control *poolControl
+
+	// downtime closes the downtime window of the connection at its first successful statement. It is
+	// nil for pools not established by the manager, and moves to the primary on a failover.
+	downtime atomic.Pointer[downtimeProbe]
+}
+
+// hookedTx is the transaction counterpart of hookedConnPool. It implements gorm.Tx, so GORM
+// treats it as a transaction (commit, rollback, savepoints, prepared statements).
+type hookedTx struct {
+	tx    *sql.Tx
+	sqlDB *sql.DB
+	name  string
+	hooks *hookChain
+
+	// release gives the slot of the transaction back to the priority gate of the pool, or is nil.
+	release func()
+
+	// outcome holds the OnCommit and OnRollback functions of the transaction, or is nil.
+	outcome *txHooks
+}
+
+// installStatementHooks routes all statements of db through a new hook chain for name and returns it.
+// With retryReads, read-only statements failing because the server closed the connection are re-run
+// once (see isServerGone).
+func installStatementHooks(name string, db *gorm.DB, retryReads bool) (*hookChain, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	chain := &hookChain{}
+	pool := &hookedConnPool{pool: db.ConnPool, sqlDB: sqlDB, name: name, hooks: chain, retryReads: retryReads}
+	db.ConnPool = pool
+	db.Statement.ConnPool = pool
+	return chain, nil
+}
+
+func (p *hookedConnPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := p.hooks.run(ctx, p.name, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return p.pool.PrepareContext(ctx, stmt.SQL)
+}
+
+// ExecContext, QueryContext and QueryRowContext retry a statement once when a cached prepared
+// statement was invalidated by DDL (error 1615), after flushing the affected statements. Reads and
+// statements marked with WithIdempotent are also retried once when the server closed their connection
+// mid-query (see retryReads); other writes then fail with ErrNotRetried. A successful statement ends the
+// downtime window of a recovered connection.
+func (p *hookedConnPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := p.exec(ctx, query, args)
+	p.served(err)
+	return result, err
+}
+
+func (p *hookedConnPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := p.query(ctx, query, args)
+	p.served(err)
+	return rows, err
+}
+
+func (p *hookedConnPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := p.queryRow(ctx, query, args)
+	p.served(row.Err())
+	return row
+}
+
+// served records a successful statement for the downtime probe of the connection.
+func (p *hookedConnPool) served(err error) {
+	if err == nil {
+		p.downtime.Load().served()
+	}
+}
+
+func (p *hookedConnPool) exec(ctx context.Context, query string, args []interface{}) (sql.Result, error) {
+	stmt, err := p.hooks.run(ctx, p.name, query, args)
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.pool.ExecContext(ctx, stmt.SQL, stmt.Args...)
+	if isNeedReprepare(err) && flushPreparedStatements(p.pool, stmt.SQL) {
+		return p.pool.ExecContext(ctx, stmt.SQL, stmt.Args...)
+	}
+	if p.shouldRetry(ctx, stmt.SQL, err) {
+		return p.pool.ExecContext(ctx, stmt.SQL, stmt.Args...)
+	}
+	if err != nil {
+		return nil, notRetried(stmt.SQL, err)
+	}
+	return result, nil
+}
+
+func (p *hookedConnPool) query(ctx context.Context, query string, args []interface{}) (*sql.Rows, error) {
+	stmt, err := p.hooks.run(ctx, p.name, query, args)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.QueryContext(ctx, stmt.SQL, stmt.Args...)
+	if isNeedReprepare(err) && flushPreparedStatements(p.pool, stmt.SQL) {
+		return p.pool.QueryContext(ctx, stmt.SQL, stmt.Args...)
+	}
+	if p.shouldRetry(ctx, stmt.SQL, err) {
+		return p.pool.QueryContext(ctx, stmt.SQL, stmt.Args...)
+	}
+	if err != nil {
+		return nil, notRetried(stmt.SQL, err)
+	}
+	return rows, nil
+}
+
+func (p *hookedConnPool) queryRow(ctx context.Context, query string, args []interface{}) *sql.Row {
+	stmt, err := p.hooks.run(ctx, p.name, query, args)
+	if err != nil {
+		return rejectedRow(ctx, p.sqlDB)
+	}
+	row := p.pool.QueryRowContext(ctx, stmt.SQL, stmt.Args...)
+	if isNeedReprepare(row.Err()) && flushPreparedStatements(p.pool, stmt.SQL) {
+		return p.pool.QueryRowContext(ctx, stmt.SQL, stmt.Args...)
+	}
+	if p.shouldRetry(ctx, stmt.SQL, row.Err()) {
+		return p.pool.QueryRowContext(ctx, stmt.SQL, stmt.Args...)
+	}
+	return row
+}
+
+// BeginTx implements gorm.ConnPoolBeginner so transactions keep running through the hooks.
+func (p *hookedConnPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	var beginner gorm.TxBeginner = p.sqlDB
+	if sharded := findShardedPool(p.pool); sharded != nil {
+		beginner = sharded
+	}
+	// A transaction holds its connection until it ends, so it holds a slot of the priority gate as long.
+	var release func()
+	if gated, ok := p.pool.(*priorityPool); ok {
+		if err := gated.gate.acquire(ctx, QueryClassOf(ctx)); err != nil {
+			return nil, err
+		}
+		release = sync.OnceFunc(gated.gate.release)
+	}
+	if p.isolation != sql.LevelDefault && (opts == nil || opts.Isolation == sql.LevelDefault) {
+		withIsolation := sql.TxOptions{Isolation: p.isolation}
+		if opts != nil {
+			withIsolation.ReadOnly = opts.ReadOnly
+		}
+		opts = &withIsolation
+	}
+	tx, err := beginner.BeginTx(ctx, opts)
+	if err != nil {
+		if release != nil {
+			release()
+		}
+		return nil, err
+	}
+	p.served(nil)
+	return &hookedTx{tx: tx, sqlDB: p.sqlDB, name: p.name, hooks: p.hooks, release: release, outcome: txHooksOf(ctx)}, nil
+}
+
+// GetDBConn implements gorm.GetDBConnector so (*gorm.DB).DB() keeps returning the underlying pool.
+func (p *hookedConnPool) GetDBConn() (*sql.DB, error) {
+	return p.sqlDB, nil
+}
+
+func (t *hookedTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := t.hooks.apply(ctx, &hookedStatement{Conn: t.name, SQL: query, InTx: true})
+	if err != nil {
+		return nil, err
+	}
+	return t.tx.PrepareContext(ctx, stmt.SQL)
+}
+
+func (t *hookedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := t.hooks.apply(ctx, &hookedStatement{Conn: t.name, SQL: query, Args: args, InTx: true})
+	if err != nil {
+		return nil, err
+	}
+	result, err := t.tx.ExecContext(ctx, stmt.SQL, stmt.Args...)
+	if err == nil && t.outcome != nil {
+		t.outcome.statement(stmt.SQL)
+	}
+	return result, err
+}
+
+func (t *hookedTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := t.hooks.apply(ctx, &hookedStatement{Conn: t.name, SQL: query, Args: args, InTx: true})
+	if err != nil {
+		return nil, err
+	}
+	return t.tx.QueryContext(ctx, stmt.SQL, stmt.Args...)
+}
+
+func (t *hookedTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	stmt, err := t.hooks.apply(ctx, &hookedStatement{Conn: t.name, SQL: query, Args: args, InTx: true})
+	if err != nil {
+		return rejectedRow(ctx, t.sqlDB)
+	}
+	return t.tx.QueryRowContext(ctx, stmt.SQL, stmt.Args...)
+}
+
+func (t *hookedTx) StmtContext(ctx context.Context, stmt *sql.Stmt) *sql.Stmt {
+	return t.tx.StmtContext(ctx, stmt)
+}
+
+func (t *hookedTx) Commit() error {
+	err := t.tx.Commit()
+	t.end(err == nil)
+	return err
+}
+
+func (t *hookedTx) Rollback() error {
+	err := t.tx.Rollback()
+	t.end(false)
+	return err
+}
+
+// end releases the slot of the transaction and runs its OnCommit or OnRollback functions.
+func (t *hookedTx) end(committed bool) {
+	if t.release != nil {
+		t.release()
+	}
+	if t.outcome != nil {
+		t.outcome.run(committed)
+	}
+}
+
+// GetDBConn implements gorm.GetDBConnector for transactions.
+func (t *hookedTx) GetDBConn() (*sql.DB, error) {
+	return t.sqlDB, nil
+}
+
+// rejectedRow returns a *sql.Row that fails on Scan. database/sql offers no way to build a Row
+// carrying a custom error, so a rejected single-row query surfaces as context.Canceled;
+// the hook that rejected it has already logged the reason.
+func rejectedRow(ctx context.Context, sqlDB *sql.DB) *sql.Row {
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	return sqlDB.QueryRowContext(canceled, "SELECT 1")
+}
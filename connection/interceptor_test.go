@@ -0,0 +1,123 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"sync"
+	"testing"
+)
+
+// recordingPool is a gorm.ConnPool that records executed statements instead of talking to a server.
+// Queries fail with errRecordingPoolQuery since *sql.Rows cannot be fabricated.
+type recordingPool struct {
+	sqlDB *sql.DB
+
+	mutex      sync.Mutex
+	statements []string
+}
+
+var errRecordingPoolQuery = errors.New("recording pool does not return rows")
+
+func (p *recordingPool) record(query string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.statements = append(p.statements, query)
+}
+
+func (p *recordingPool) recorded() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]string(nil), p.statements...)
+}
+
+func (p *recordingPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	p.record(query)
+	return nil, errRecordingPoolQuery
+}
+
+func (p *recordingPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	p.record(query)
+	return driver.RowsAffected(1), nil
+}
+
+func (p *recordingPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	p.record(query)
+	return nil, errRecordingPoolQuery
+}
+
+func (p *recordingPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	p.record(query)
+	return rejectedRow(ctx, p.sqlDB)
+}
+
+func (p *recordingPool) GetDBConn() (*sql.DB, error) {
+	return p.sqlDB, nil
+}
+
+// newRecordingDB returns a GORM handle whose statements pass through a fresh hook chain into a recordingPool.
+func newRecordingDB(t *testing.T, name string) (*gorm.DB, *hookChain, *recordingPool) {
+	t.Helper()
+
+	// sql.Open does not connect; the handle only backs GetDBConn.
+	sqlDB, err := sql.Open("mysql", "user:password@tcp(127.0.0.1:3306)/dbname")
+	if err != nil {
+		t.Fatalf("Failed to create sql.DB: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	pool := &recordingPool{sqlDB: sqlDB}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: pool, SkipInitializeWithVersion: true}), &gorm.Config{
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open recording database: %v", err)
+	}
+
+	hooks, err := installStatementHooks(name, db, false)
+	if err != nil {
+		t.Fatalf("Failed to install statement hooks: %v", err)
+	}
+	return db, hooks, pool
+}
+
+func TestStatementHooksRewriteAndReject(t *testing.T) {
+	db, hooks, pool := newRecordingDB(t, "hooked_db")
+
+	hooks.set("rewrite", func(ctx context.Context, stmt *hookedStatement) error {
+		stmt.SQL = "/* rewritten */ " + stmt.SQL
+		return nil
+	})
+	if err := db.Exec("UPDATE t SET a = 1").Error; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rejected := errors.New("rejected")
+	hooks.set("reject", func(ctx context.Context, stmt *hookedStatement) error { return rejected })
+	if err := db.Exec("UPDATE t SET a = 2").Error; !errors.Is(err, rejected) {
+		t.Fatalf("Expected the hook error, got %v", err)
+	}
+
+	if !hooks.remove("reject") || hooks.remove("reject") {
+		t.Fatal("Expected remove to report whether the hook existed")
+	}
+	if err := db.Exec("UPDATE t SET a = 3").Error; err != nil {
+		t.Fatalf("Unexpected error after removing hook: %v", err)
+	}
+
+	got := pool.recorded()
+	want := []string{"/* rewritten */ UPDATE t SET a = 1", "/* rewritten */ UPDATE t SET a = 3"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("Unexpected statements reaching the pool: %q", got)
+	}
+
+	if sqlDB, err := db.DB(); err != nil || sqlDB != pool.sqlDB {
+		t.Fatalf("Expected DB() to return the underlying pool, got %v, %v", sqlDB, err)
+	}
+}
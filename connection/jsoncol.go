@@ -0,0 +1,143 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strings"
+)
+
+// ErrInvalidJSON is returned when a JSON column value is rejected by its JSONSerializer.
+var ErrInvalidJSON = errors.New("invalid JSON column value")
+
+// JSONSerializer is a GORM serializer for JSON columns that validates payloads before they are written.
+// Register it under a name with RegisterJSONSerializer and select it with the serializer tag:
+//
+//	Attributes map[string]any `gorm:"type:json;serializer:checked_json"`
+//
+// Reads are decoded like GORM's json serializer, without validation.
+type JSONSerializer struct {
+	// MaxBytes rejects encoded payloads larger than this. Zero means no limit.
+	MaxBytes int
+
+	// RequiredKeys rejects payloads that are not JSON objects with all these top-level keys.
+	RequiredKeys []string
+
+	// Validate is called with the field name and the encoded payload, for schema checks. A non-nil error rejects it.
+	Validate func(field string, payload []byte) error
+}
+
+// RegisterJSONSerializer registers s as the GORM serializer name, for every connection.
+//
+// Example Usage:
+//
+//	connection.RegisterJSONSerializer("checked_json", connection.JSONSerializer{
+//		MaxBytes:     64 << 10,
+//		RequiredKeys: []string{"version"},
+//	})
+func RegisterJSONSerializer(name string, s JSONSerializer) {
+	schema.RegisterSerializer(name, s)
+}
+
+// Scan implements schema.SerializerInterface.
+func (s JSONSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	return schema.JSONSerializer{}.Scan(ctx, field, dst, dbValue)
+}
+
+// Value implements schema.SerializerValuerInterface, validating the encoded payload.
+func (s JSONSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, err := schema.JSONSerializer{}.Value(ctx, field, dst, fieldValue)
+	if err != nil {
+		return nil, err
+	}
+	payload, ok := value.(string)
+	if !ok || payload == "" {
+		return value, nil
+	}
+	if err := s.check(field.Name, []byte(payload)); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// check validates an encoded payload of field.
+func (s JSONSerializer) check(field string, payload []byte) error {
+	if s.MaxBytes > 0 && len(payload) > s.MaxBytes {
+		return fmt.Errorf("%w: %s is %d bytes, more than %d", ErrInvalidJSON, field, len(payload), s.MaxBytes)
+	}
+	if len(s.RequiredKeys) > 0 {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(payload, &object); err != nil || object == nil {
+			return fmt.Errorf("%w: %s is not a JSON object", ErrInvalidJSON, field)
+		}
+		for _, key := range s.RequiredKeys {
+			if _, ok := object[key]; !ok {
+				return fmt.Errorf("%w: %s has no %q key", ErrInvalidJSON, field, key)
+			}
+		}
+	}
+	if s.Validate != nil {
+		if err := s.Validate(field, payload); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidJSON, field, err)
+		}
+	}
+	return nil
+}
+
+// JSONValue returns the unquoted value at path in a JSON column, JSON_UNQUOTE(JSON_EXTRACT(column, path)),
+// for Select, Order or Group clauses. path is a MySQL JSON path such as "$.address.city".
+//
+// Example Usage:
+//
+//	db.Model(&Order{}).Select("id, ?", connection.JSONValue("attributes", "$.channel")).Rows()
+func JSONValue(column, path string) clause.Expr {
+	return clause.Expr{SQL: "JSON_UNQUOTE(JSON_EXTRACT(" + quoteIdentifier(column) + ", ?))", Vars: []interface{}{jsonPath(path)}}
+}
+
+// JSONEquals is a condition comparing the value at path in a JSON column with value, as a string.
+//
+// Example Usage:
+//
+//	db.Where(connection.JSONEquals("attributes", "$.channel", "email")).Find(&orders)
+func JSONEquals(column, path string, value interface{}) clause.Expr {
+	expr := JSONValue(column, path)
+	expr.SQL += " = ?"
+	expr.Vars = append(expr.Vars, value)
+	return expr
+}
+
+// JSONContains is a condition matching rows whose JSON column contains value (encoded as JSON) at path,
+// JSON_CONTAINS(column, value, path). For arrays, value matches an element. It returns an error when
+// value cannot be encoded as JSON.
+//
+// Example Usage:
+//
+//	vip, err := connection.JSONContains("attributes", "$.tags", "vip")
+//	if err != nil {
+//		return err
+//	}
+//	db.Where(vip).Find(&customers)
+func JSONContains(column, path string, value interface{}) (clause.Expr, error) {
+	candidate, err := json.Marshal(value)
+	if err != nil {
+		return clause.Expr{}, fmt.Errorf("failed to encode JSON_CONTAINS value: %w", err)
+	}
+	return clause.Expr{SQL: "JSON_CONTAINS(" + quoteIdentifier(column) + ", ?, ?)", Vars: []interface{}{string(candidate), jsonPath(path)}}, nil
+}
+
+// JSONHasPath is a condition matching rows whose JSON column has a value at path, JSON_CONTAINS_PATH.
+func JSONHasPath(column, path string) clause.Expr {
+	return clause.Expr{SQL: "JSON_CONTAINS_PATH(" + quoteIdentifier(column) + ", 'one', ?)", Vars: []interface{}{jsonPath(path)}}
+}
+
+// jsonPath prefixes path with the document root "$" when it is missing, so "address.city" works too.
+func jsonPath(path string) string {
+	if strings.HasPrefix(path, "$") {
+		return path
+	}
+	return "$." + strings.TrimPrefix(path, ".")
+}
@@ -0,0 +1,62 @@
+package connection
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type jsonTestOrder struct {
+	ID         uint
+	Attributes map[string]interface{} `gorm:"type:json;serializer:test_checked_json"`
+}
+
+func TestJSONSerializer(t *testing.T) {
+	RegisterJSONSerializer("test_checked_json", JSONSerializer{
+		MaxBytes:     64,
+		RequiredKeys: []string{"version"},
+		Validate: func(field string, payload []byte) error {
+			if strings.Contains(string(payload), "forbidden") {
+				return errors.New("forbidden value")
+			}
+			return nil
+		},
+	})
+
+	// The serializer runs when the driver converts the arguments.
+	value := func(attributes map[string]interface{}) (driver.Value, error) {
+		stmt := newDryRunDB(t).Create(&jsonTestOrder{Attributes: attributes}).Statement
+		return stmt.Vars[0].(driver.Valuer).Value()
+	}
+	if got, err := value(map[string]interface{}{"version": 1}); err != nil || got != `{"version":1}` {
+		t.Fatalf("Unexpected value %v, %v", got, err)
+	}
+	for _, attributes := range []map[string]interface{}{
+		{"channel": "email"},
+		{"version": 1, "padding": strings.Repeat("x", 64)},
+		{"version": 1, "channel": "forbidden"},
+	} {
+		if _, err := value(attributes); !errors.Is(err, ErrInvalidJSON) {
+			t.Errorf("Expected ErrInvalidJSON for %v, got %v", attributes, err)
+		}
+	}
+}
+
+func TestJSONConditions(t *testing.T) {
+	db := newDryRunDB(t)
+	stmt := db.Where(JSONEquals("attributes", "channel", "email")).Find(&[]jsonTestOrder{}).Statement
+	if sql := stmt.SQL.String(); !strings.Contains(sql, "JSON_UNQUOTE(JSON_EXTRACT(`attributes`, ?)) = ?") || stmt.Vars[0] != "$.channel" {
+		t.Fatalf("Unexpected statement %s %v", sql, stmt.Vars)
+	}
+
+	contains, err := JSONContains("attributes", "$.tags", "vip")
+	if err != nil {
+		t.Fatalf("JSONContains failed: %v", err)
+	}
+	stmt = newDryRunDB(t).Where(contains).Where(JSONHasPath("attributes", "$.tags")).Find(&[]jsonTestOrder{}).Statement
+	if sql := stmt.SQL.String(); !strings.Contains(sql, "JSON_CONTAINS(`attributes`, ?, ?) AND JSON_CONTAINS_PATH(`attributes`, 'one', ?)") ||
+		stmt.Vars[0] != `"vip"` {
+		t.Fatalf("Unexpected statement %s %v", sql, stmt.Vars)
+	}
+}
@@ -0,0 +1,362 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults of LagMonitorOptions.
+const (
+	defaultHeartbeatTable    = "heartbeat"
+	defaultHeartbeatInterval = time.Second
+)
+
+// heartbeatLayout is the format of the ts column, as written by pt-heartbeat --utc.
+const heartbeatLayout = "2006-01-02T15:04:05.000000"
+
+// LagMonitorOptions configure StartLagMonitor.
+type LagMonitorOptions struct {
+	// Table is the heartbeat table, optionally qualified by its database ("percona.heartbeat"). Defaults
+	// to "heartbeat" in the default database of the connections.
+	Table string
+
+	// Interval is how often the heartbeat is written on the primary and read on the replicas. Defaults
+	// to one second.
+	Interval time.Duration
+
+	// ServerID identifies the heartbeat row of the primary. Defaults to the @@server_id of the primary.
+	ServerID int64
+
+	// CreateTable creates the heartbeat table on the primary when it does not exist.
+	CreateTable bool
+}
+
+// ReplicaLag is the replication delay of one replica measured by a LagMonitor.
+type ReplicaLag struct {
+	Name string
+
+	// Lag is the replication delay, valid when Measured is true.
+	Lag      time.Duration
+	Measured bool
+
+	// Err is the error of the last read of the heartbeat on the replica, or nil.
+	Err error
+}
+
+// LagMonitor measures the replication delay of the replicas of a primary connection from a heartbeat
+// table, see StartLagMonitor.
+type LagMonitor struct {
+	factory  *ConnectionManager
+	primary  string
+	replicas []string
+	table    string
+	serverID int64
+	interval time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mutex sync.Mutex
+	// first and last are the first and the latest heartbeats written by the monitor.
+	first, last time.Time
+	lags        map[string]ReplicaLag
+}
+
+// StartLagMonitor measures the replication delay of the replicas of a primary connection the way
+// pt-heartbeat does: a heartbeat row on the primary is updated with the current time every interval,
+// and the replicas are read back to see how old the heartbeat they applied is. Unlike
+// Seconds_Behind_Source, the delay is measured end to end, also through intermediate replicas, and
+// needs no SUPER or REPLICATION CLIENT privilege: the primary connection only needs to write the
+// heartbeat table and the replicas to read it.
+//
+// Parameters:
+// - primary: The name of the managed primary connection, where the heartbeat is written.
+// - replicas: The names of the managed replica connections, where the heartbeat is read. When empty,
+// the replicas declared with SetReplicas are monitored.
+// - opts: The heartbeat table, the interval, the server id of the heartbeat row and whether to create the table.
+//
+// Returns:
+// - *LagMonitor: The running monitor. Stop ends it.
+// - error: An error if a connection does not exist, no replica is given, or the first heartbeat cannot
+// be written.
+//
+// Behavior:
+//  1. The heartbeat table has the columns of pt-heartbeat: ts (VARCHAR(26), UTC with microseconds) and
+//     server_id (the primary key). A table maintained by pt-heartbeat --utc can be shared.
+//  2. Every Interval the replicas are read, then a new heartbeat is written. A replica that applied the
+//     latest heartbeat has no lag; otherwise its lag is the age of the last heartbeat it applied, and a
+//     replica without any heartbeat lags since the monitor started.
+//  3. The lag of each replica feeds the routing of ReaderDB: with ReplicaOptions.MaxLag, replicas
+//     lagging more are skipped. It is reported by ReplicaStats and exported by RegisterOTelMetrics as
+//     the db.client.replica.lag gauge.
+//  4. Starting a monitor for a primary stops the previous monitor of that primary.
+//
+// Notes:
+//   - Lag is measured with the resolution of Interval.
+//   - Timestamps come from the clock of the process (see WithClock), so monitors of several processes
+//     sharing a heartbeat row need synchronized clocks.
+//
+// Example Usage:
+//
+//	factory := connection.GetConnectionManager()
+//	_ = factory.SetReplicas("orders", []string{"orders_replica_a", "orders_replica_b"}, connection.ReplicaOptions{MaxLag: 5 * time.Second})
+//	monitor, err := factory.StartLagMonitor("orders", nil, connection.LagMonitorOptions{Table: "percona.heartbeat"})
+//	if err != nil {
+//		log.Fatalf("Failed to start the lag monitor: %v", err)
+//	}
+//	defer monitor.Stop()
+func (f *ConnectionManager) StartLagMonitor(primary string, replicas []string, opts LagMonitorOptions) (*LagMonitor, error) {
+	if opts.Table == "" {
+		opts.Table = defaultHeartbeatTable
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = defaultHeartbeatInterval
+	}
+
+	f.mutex.Lock()
+	if len(replicas) == 0 && f.replicas[primary] != nil {
+		for _, replica := range f.replicas[primary].replicas {
+			replicas = append(replicas, replica.name)
+		}
+	}
+	db, exists := f.connections[primary]
+	missing := primary
+	for _, name := range replicas {
+		if _, ok := f.connections[name]; !ok && exists {
+			exists, missing = false, name
+		}
+	}
+	previous := f.lagMonitors[primary]
+	f.mutex.Unlock()
+	if !exists {
+		return nil, errNotFound(missing, OpStartLagMonitor)
+	}
+	if len(replicas) == 0 {
+		return nil, connError(primary, OpStartLagMonitor, errors.New("no replica to monitor"))
+	}
+	if previous != nil {
+		previous.Stop()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &LagMonitor{
+		factory:  f,
+		primary:  primary,
+		replicas: append([]string(nil), replicas...),
+		table:    quoteIdentifier(opts.Table),
+		serverID: opts.ServerID,
+		interval: opts.Interval,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		lags:     make(map[string]ReplicaLag, len(replicas)),
+	}
+	if err := m.prepare(ctx, db, opts.CreateTable); err != nil {
+		cancel()
+		return nil, connError(primary, OpStartLagMonitor, err)
+	}
+	if err := m.beat(ctx); err != nil {
+		cancel()
+		return nil, connError(primary, OpStartLagMonitor, fmt.Errorf("failed to write the heartbeat: %w", err))
+	}
+
+	f.mutex.Lock()
+	if f.lagMonitors == nil {
+		f.lagMonitors = make(map[string]*LagMonitor)
+	}
+	f.lagMonitors[primary] = m
+	f.mutex.Unlock()
+
+	ticker := f.clock().NewTicker(opts.Interval)
+	go func() {
+		defer close(m.done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				m.measure(ctx)
+				if err := m.beat(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("Failed to write the heartbeat of %q: %v", primary, err)
+				}
+			}
+		}
+	}()
+	return m, nil
+}
+
+// Lag returns the replication delay of a monitored replica, and false while it is not measured.
+func (m *LagMonitor) Lag(replica string) (time.Duration, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	lag := m.lags[replica]
+	return lag.Lag, lag.Measured
+}
+
+// Stats returns the replication delay of every monitored replica, sorted by name.
+func (m *LagMonitor) Stats() []ReplicaLag {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	stats := make([]ReplicaLag, 0, len(m.replicas))
+	for _, name := range m.replicas {
+		lag := m.lags[name]
+		lag.Name = name
+		stats = append(stats, lag)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// Stop ends the monitor. The replicas of the primary are no longer skipped for their lag.
+func (m *LagMonitor) Stop() {
+	m.cancel()
+	<-m.done
+
+	f := m.factory
+	f.mutex.Lock()
+	if f.lagMonitors[m.primary] == m {
+		delete(f.lagMonitors, m.primary)
+	}
+	set := f.replicas[m.primary]
+	f.mutex.Unlock()
+	if set != nil {
+		set.setLags(nil)
+	}
+}
+
+// prepare creates the heartbeat table when asked to and reads the server id of the primary when it is
+// not configured.
+func (m *LagMonitor) prepare(ctx context.Context, db *gorm.DB, create bool) error {
+	ctx, cancel := context.WithTimeout(ctx, m.interval)
+	defer cancel()
+	if create {
+		err := db.WithContext(ctx).Exec("CREATE TABLE IF NOT EXISTS " + m.table +
+			" (ts VARCHAR(26) NOT NULL, server_id INT UNSIGNED NOT NULL PRIMARY KEY)").Error
+		if err != nil {
+			return fmt.Errorf("failed to create the heartbeat table: %w", err)
+		}
+	}
+	if m.serverID == 0 {
+		if err := db.WithContext(ctx).Raw("SELECT @@server_id").Row().Scan(&m.serverID); err != nil {
+			return fmt.Errorf("failed to read the server id: %w", err)
+		}
+	}
+	return nil
+}
+
+// beat writes a heartbeat on the primary.
+func (m *LagMonitor) beat(ctx context.Context) error {
+	f := m.factory
+	f.mutex.Lock()
+	db, exists := f.connections[m.primary]
+	f.mutex.Unlock()
+	if !exists {
+		return errNotFound(m.primary, OpHeartbeat)
+	}
+
+	now := f.clock().Now().UTC()
+	ctx, cancel := context.WithTimeout(ctx, m.interval)
+	defer cancel()
+	err := db.WithContext(ctx).Exec("INSERT INTO "+m.table+" (ts, server_id) VALUES (?, ?) ON DUPLICATE KEY UPDATE ts = VALUES(ts)",
+		now.Format(heartbeatLayout), m.serverID).Error
+	if err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	if m.first.IsZero() {
+		m.first = now
+	}
+	m.last = now
+	m.mutex.Unlock()
+	return nil
+}
+
+// measure reads the heartbeat on every replica and passes the lags to the routing of the primary.
+func (m *LagMonitor) measure(ctx context.Context) {
+	f := m.factory
+	lags := make(map[string]ReplicaLag, len(m.replicas))
+	for _, name := range m.replicas {
+		f.mutex.Lock()
+		db, exists := f.connections[name]
+		f.mutex.Unlock()
+
+		ts, found, err := time.Time{}, false, errNotFound(name, OpReadHeartbeat)
+		if exists {
+			ts, found, err = m.read(ctx, db)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		now := f.clock().Now()
+		m.mutex.Lock()
+		lag := ReplicaLag{Name: name, Err: err}
+		switch {
+		case err != nil:
+			if m.lags[name].Err == nil {
+				log.Printf("Failed to read the heartbeat of %q on replica %q: %v", m.primary, name, err)
+			}
+		case found && !ts.Before(m.last):
+			lag.Measured = true
+		case found:
+			lag.Lag, lag.Measured = now.Sub(ts), true
+		default:
+			lag.Lag, lag.Measured = now.Sub(m.first), true
+		}
+		m.lags[name] = lag
+		m.mutex.Unlock()
+		lags[name] = lag
+	}
+
+	f.mutex.Lock()
+	set := f.replicas[m.primary]
+	f.mutex.Unlock()
+	if set != nil {
+		set.setLags(lags)
+	}
+}
+
+// read returns the heartbeat of the primary applied by a replica. found is false when the replica has
+// not applied any.
+func (m *LagMonitor) read(ctx context.Context, db *gorm.DB) (ts time.Time, found bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, m.interval)
+	defer cancel()
+	var value string
+	err = db.WithContext(ctx).Raw("SELECT ts FROM "+m.table+" WHERE server_id = ?", m.serverID).Row().Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	// Parsing accepts any number of fractional digits, and pt-heartbeat writes a space instead of the T
+	// in some versions.
+	ts, err = time.Parse("2006-01-02T15:04:05", strings.Replace(value, " ", "T", 1))
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid heartbeat %q: %w", value, err)
+	}
+	return ts, true, nil
+}
+
+// lagStats returns the lags of the replicas of every running lag monitor.
+func (f *ConnectionManager) lagStats() []ReplicaLag {
+	f.mutex.Lock()
+	monitors := make([]*LagMonitor, 0, len(f.lagMonitors))
+	for _, m := range f.lagMonitors {
+		monitors = append(monitors, m)
+	}
+	f.mutex.Unlock()
+
+	var stats []ReplicaLag
+	for _, m := range monitors {
+		stats = append(stats, m.Stats()...)
+	}
+	return stats
+}
@@ -0,0 +1,138 @@
+package connection
+
+import (
+	"context"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"testing"
+	"time"
+)
+
+func heartbeatTable(rows ...map[string]any) FakeData {
+	return FakeData{"heartbeat": {Rows: rows, Columns: []string{"ts", "server_id"}, PrimaryKey: "server_id"}}
+}
+
+func TestLagMonitorMeasuresAndRoutes(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start, 1)
+	factory := newTestFactory().WithClock(clock)
+	if err := factory.InitFake("orders", heartbeatTable()); err != nil {
+		t.Fatalf("InitFake failed: %v", err)
+	}
+	// replica_live reads the store of the primary, so it applies every heartbeat at once.
+	factory.connections["replica_live"] = factory.connections["orders"]
+	if err := factory.InitFake("replica_behind", heartbeatTable(map[string]any{"server_id": 7, "ts": "2023-12-31T23:59:55.000000"})); err != nil {
+		t.Fatalf("InitFake failed: %v", err)
+	}
+	if err := factory.InitFake("replica_empty", heartbeatTable()); err != nil {
+		t.Fatalf("InitFake failed: %v", err)
+	}
+	replicas := []string{"replica_live", "replica_behind", "replica_empty"}
+	if err := factory.SetReplicas("orders", replicas, ReplicaOptions{ProbeInterval: time.Hour, Fastest: 3, MaxLag: 3 * time.Second}); err != nil {
+		t.Fatalf("SetReplicas failed: %v", err)
+	}
+	defer factory.SetReplicas("orders", nil, ReplicaOptions{})
+
+	if _, err := factory.StartLagMonitor("orders", []string{"missing"}, LagMonitorOptions{ServerID: 7}); err == nil {
+		t.Fatal("Expected an error for a non-existent replica, got nil")
+	}
+	monitor, err := factory.StartLagMonitor("orders", nil, LagMonitorOptions{ServerID: 7})
+	if err != nil {
+		t.Fatalf("StartLagMonitor failed: %v", err)
+	}
+	defer monitor.Stop()
+	if _, measured := monitor.Lag("replica_live"); measured {
+		t.Fatal("Expected no lag before the first read")
+	}
+
+	clock.Advance(time.Second)
+	waitFor(t, "the heartbeat reads", func() bool {
+		for _, lag := range monitor.Stats() {
+			if !lag.Measured {
+				return false
+			}
+		}
+		return true
+	})
+	expected := map[string]time.Duration{"replica_live": 0, "replica_behind": 6 * time.Second, "replica_empty": time.Second}
+	for _, lag := range monitor.Stats() {
+		if lag.Lag != expected[lag.Name] || lag.Err != nil {
+			t.Errorf("Unexpected lag %+v, expected %v", lag, expected[lag.Name])
+		}
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 30; i++ {
+		if _, err := factory.ReaderDB(ctx, "orders"); err != nil {
+			t.Fatalf("ReaderDB failed: %v", err)
+		}
+	}
+	for _, stat := range factory.ReplicaStats("orders") {
+		if !stat.LagMeasured || stat.Lag != expected[stat.Name] {
+			t.Errorf("Expected the measured lag in the stats, got %+v", stat)
+		}
+		if stat.Name == "replica_behind" && stat.Selections > 0 {
+			t.Errorf("Expected the lagging replica to be skipped, got %+v", stat)
+		}
+	}
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	stop, err := factory.RegisterOTelMetrics(provider.Meter("test"))
+	if err != nil {
+		t.Fatalf("RegisterOTelMetrics failed: %v", err)
+	}
+	defer stop()
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &data); err != nil {
+		t.Fatalf("Failed to collect metrics: %v", err)
+	}
+	observed := map[string]float64{}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if gauge, ok := m.Data.(metricdata.Gauge[float64]); ok && m.Name == otelReplicaLag {
+				for _, point := range gauge.DataPoints {
+					name, _ := point.Attributes.Value(otelPoolName)
+					observed[name.AsString()] = point.Value
+				}
+			}
+		}
+	}
+	if len(observed) != 3 || observed["replica_behind"] != 6 {
+		t.Fatalf("Unexpected lag gauge %v", observed)
+	}
+
+	monitor.Stop()
+	for _, stat := range factory.ReplicaStats("orders") {
+		if stat.LagMeasured {
+			t.Fatalf("Expected no lag once the monitor stopped, got %+v", stat)
+		}
+	}
+}
+
+func TestLagMonitorStoppedOnClose(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1)
+	factory := newTestFactory().WithClock(clock)
+	for _, name := range []string{"orders", "replica"} {
+		if err := factory.InitFake(name, heartbeatTable()); err != nil {
+			t.Fatalf("InitFake failed: %v", err)
+		}
+	}
+	defer factory.CloseAllConnections()
+	monitor, err := factory.StartLagMonitor("orders", []string{"replica"}, LagMonitorOptions{ServerID: 7})
+	if err != nil {
+		t.Fatalf("StartLagMonitor failed: %v", err)
+	}
+
+	if err := factory.CloseConnection("orders"); err != nil {
+		t.Fatalf("CloseConnection failed: %v", err)
+	}
+	select {
+	case <-monitor.done:
+	default:
+		t.Fatal("Expected the lag monitor to stop with its primary")
+	}
+	if clock.Tickers() != 0 || len(factory.lagMonitors) != 0 {
+		t.Fatalf("Expected no running monitor, got %d tickers and %v", clock.Tickers(), factory.lagMonitors)
+	}
+}
@@ -0,0 +1,131 @@
+package connection
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Lifecycle operations of LifecycleEvent.Op.
+const (
+	OpInit      = "init"
+	OpReconnect = "reconnect"
+	OpClose     = "close"
+)
+
+// LifecycleEvent reports one step of a lifecycle operation on a connection. The steps of one operation,
+// e.g. the connect step of a reconnect and the reconnect itself, share its OperationID.
+type LifecycleEvent struct {
+	// OperationID identifies the operation. It also prefixes the log lines of the operation and is
+	// carried by its errors (see OperationID).
+	OperationID string
+
+	// Op is the operation: OpInit, OpReconnect or OpClose.
+	Op string
+
+	// Step is the step of the operation that ended: "connect", "reconnect" or "close".
+	Step string
+
+	// Connection is the name of the connection, and Cluster its cluster identity (DBConfig.Cluster).
+	Connection string
+	Cluster    ClusterMetadata
+
+	// Err is the error of the step, or nil.
+	Err error
+
+	// Duration is the time taken by the step.
+	Duration time.Duration
+}
+
+// SetLifecycleHook sets a function called after every step of the init, reconnect and close operations,
+// or removes it when hook is nil. It is called without locks held, from the goroutine running the operation.
+//
+// Example Usage:
+//
+//	connection.GetConnectionManager().SetLifecycleHook(func(e connection.LifecycleEvent) {
+//		slog.Info("db lifecycle", "op_id", e.OperationID, "op", e.Op, "step", e.Step,
+//			"connection", e.Connection, "err", e.Err, "duration", e.Duration)
+//	})
+func (f *ConnectionManager) SetLifecycleHook(hook func(LifecycleEvent)) {
+	if hook == nil {
+		f.lifecycle.Store(nil)
+		return
+	}
+	f.lifecycle.Store(&hook)
+}
+
+// OperationID returns the ID of the lifecycle operation that produced err, or "" if err did not come
+// from a lifecycle operation.
+func OperationID(err error) string {
+	var opErr *operationError
+	if errors.As(err, &opErr) {
+		return opErr.id
+	}
+	return ""
+}
+
+// operationError carries the ID of the lifecycle operation that failed.
+type operationError struct {
+	id  string
+	err error
+}
+
+func (e *operationError) Error() string {
+	return fmt.Sprintf("%v (operation %s)", e.err, e.id)
+}
+
+func (e *operationError) Unwrap() error {
+	return e.err
+}
+
+type operationKey struct{}
+
+// operation is a lifecycle operation in progress.
+type operation struct {
+	id      string
+	op      string
+	cluster ClusterMetadata
+}
+
+// startOperation returns the operation carried by ctx, or starts a new op operation and returns ctx carrying it.
+func startOperation(ctx context.Context, op string) (context.Context, operation) {
+	if current, ok := ctx.Value(operationKey{}).(operation); ok {
+		return ctx, current
+	}
+	var b [6]byte
+	_, _ = rand.Read(b[:])
+	current := operation{id: hex.EncodeToString(b[:]), op: op}
+	return context.WithValue(ctx, operationKey{}, current), current
+}
+
+// tagged returns the operation with the cluster identity of its connection, for its log lines and events.
+func (o operation) tagged(cluster ClusterMetadata) operation {
+	o.cluster = cluster
+	return o
+}
+
+// logf logs a line prefixed with the operation ID and the cluster identity of its connection.
+func (o operation) logf(format string, args ...interface{}) {
+	if o.cluster.IsZero() {
+		log.Printf("[op %s] "+format, append([]interface{}{o.id}, args...)...)
+		return
+	}
+	log.Printf("[op %s %s] "+format, append([]interface{}{o.id, o.cluster.String()}, args...)...)
+}
+
+// endStep reports the end of step to the lifecycle hook and the event streams of f and returns err carrying the operation ID.
+func (f *ConnectionManager) endStep(o operation, name, step string, start time.Time, err error) error {
+	if err != nil && OperationID(err) != o.id {
+		err = &operationError{id: o.id, err: err}
+	}
+	event := LifecycleEvent{OperationID: o.id, Op: o.op, Step: step, Connection: name, Cluster: o.cluster, Err: err, Duration: time.Since(start)}
+	if hook := f.lifecycle.Load(); hook != nil {
+		(*hook)(event)
+	}
+	f.events.publish(event)
+	return err
+}
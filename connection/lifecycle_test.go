@@ -0,0 +1,57 @@
+package connection
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLifecycleOperationIDs(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["slow_db"] = newConnectorDB(t, &fakeConnector{})
+	config := DBConfig{DataSourceName: "user:secret@tcp(10.255.255.1:3306)/app"}
+	var mutex sync.Mutex
+	var events []LifecycleEvent
+	factory.SetLifecycleHook(func(e LifecycleEvent) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		events = append(events, e)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := factory.reconnect(ctx, "slow_db", config)
+	if err == nil {
+		t.Fatal("Expected the reconnect to fail, got nil")
+	}
+	id := OperationID(err)
+	if id == "" || strings.Count(err.Error(), id) != 1 {
+		t.Fatalf("Expected the error to carry the operation ID once, got %v", err)
+	}
+	if len(events) != 2 || events[0].Step != "connect" || events[1].Step != "reconnect" {
+		t.Fatalf("Unexpected events %+v", events)
+	}
+	for _, e := range events {
+		if e.OperationID != id || e.Op != OpReconnect || e.Connection != "slow_db" || e.Err == nil {
+			t.Errorf("Unexpected event %+v", e)
+		}
+	}
+
+	events = nil
+	if err := factory.CloseConnection("slow_db"); err != nil {
+		t.Fatalf("CloseConnection failed: %v", err)
+	}
+	if len(events) != 1 || events[0].Op != OpClose || events[0].OperationID == id || events[0].Err != nil {
+		t.Fatalf("Unexpected close events %+v", events)
+	}
+
+	factory.SetLifecycleHook(nil)
+	if err := factory.CloseConnection("slow_db"); OperationID(err) == "" {
+		t.Fatalf("Expected the close error to carry an operation ID, got %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected no event after removing the hook, got %+v", events)
+	}
+}
@@ -0,0 +1,46 @@
+package connection
+
+import (
+	"fmt"
+	"time"
+)
+
+// jitteredLifetime returns the lifetime of a new physical connection: lifetime spread uniformly over
+// ±jitter of itself, from the jitter source of clock. It returns lifetime when either is not positive.
+func jitteredLifetime(lifetime time.Duration, jitter float64, clock Clock) time.Duration {
+	if lifetime <= 0 || jitter <= 0 {
+		return lifetime
+	}
+	jitter = min(jitter, maxLifetimeJitter)
+	return time.Duration(float64(lifetime) * (1 + jitter*(2*clock.Float64()-1)))
+}
+
+// maxLifetimeJitter caps DBConfig.LifetimeJitter, so every connection lives a little.
+const maxLifetimeJitter = 0.9
+
+// poolLifetime returns the maximum lifetime set on the pool: the longest jittered lifetime, closing the
+// idle connections past their own lifetime, or Lifetime without jitter.
+func poolLifetime(c DBConfig) time.Duration {
+	if c.Lifetime <= 0 || c.LifetimeJitter <= 0 {
+		return c.Lifetime
+	}
+	return time.Duration(float64(c.Lifetime) * (1 + min(c.LifetimeJitter, maxLifetimeJitter)))
+}
+
+// lifetimeJitterWarnings reports a LifetimeJitter without effect or out of range.
+func lifetimeJitterWarnings(c DBConfig) []ConfigWarning {
+	switch {
+	case c.LifetimeJitter == 0:
+		return nil
+	case c.LifetimeJitter < 0:
+		return []ConfigWarning{{Field: ConfigFieldLifetimeJitter,
+			Message: fmt.Sprintf("LifetimeJitter (%v) is negative; jitter is disabled", c.LifetimeJitter)}}
+	case c.LifetimeJitter > maxLifetimeJitter:
+		return []ConfigWarning{{Field: ConfigFieldLifetimeJitter,
+			Message: fmt.Sprintf("LifetimeJitter (%v) is above %v; it is capped at %v", c.LifetimeJitter, maxLifetimeJitter, maxLifetimeJitter)}}
+	case c.Lifetime <= 0:
+		return []ConfigWarning{{Field: ConfigFieldLifetimeJitter,
+			Message: "LifetimeJitter has no effect without a Lifetime"}}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifetimeJitterSpreadsExpiry(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1)
+	sessions := &sessionTracker{}
+	pool := sql.OpenDB(&trackedConnector{connector: &fakeConnector{}, sessions: sessions,
+		control: &poolControl{config: DBConfig{Lifetime: time.Minute, LifetimeJitter: 0.5}}, clock: clock})
+	defer pool.Close()
+	pool.SetMaxIdleConns(10)
+	ctx := context.Background()
+
+	var conns []*sql.Conn
+	expiries := make(map[time.Time]bool)
+	survivors := 0
+	for i := 0; i < 10; i++ {
+		conn, err := pool.Conn(ctx)
+		if err != nil {
+			t.Fatalf("Conn failed: %v", err)
+		}
+		_ = conn.Raw(func(driverConn interface{}) error {
+			expires := driverConn.(*trackedConn).expires
+			if lifetime := expires.Sub(clock.Now()); lifetime < 30*time.Second || lifetime >= 90*time.Second {
+				t.Errorf("Expected a lifetime within 1m ± 50%%, got %v", lifetime)
+			}
+			expiries[expires] = true
+			if expires.After(clock.Now().Add(time.Minute)) {
+				survivors++
+			}
+			return nil
+		})
+		conns = append(conns, conn)
+	}
+	if len(expiries) < 9 {
+		t.Fatalf("Expected spread expiries, got %v", expiries)
+	}
+
+	// Connections returned past their own lifetime are closed; the others stay idle in the pool.
+	clock.Advance(time.Minute)
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	if got := len(sessions.list()); got != survivors || survivors == 0 || survivors == 10 {
+		t.Fatalf("Expected %d connections to survive, got %d", survivors, got)
+	}
+
+	if poolLifetime(DBConfig{Lifetime: time.Minute, LifetimeJitter: 0.5}) != 90*time.Second ||
+		poolLifetime(DBConfig{Lifetime: time.Minute}) != time.Minute {
+		t.Fatal("Unexpected pool lifetime")
+	}
+	if len(lifetimeJitterWarnings(DBConfig{Lifetime: -1, LifetimeJitter: 0.1})) != 1 ||
+		len(lifetimeJitterWarnings(DBConfig{Lifetime: time.Minute, LifetimeJitter: 2})) != 1 ||
+		len(lifetimeJitterWarnings(DBConfig{Lifetime: time.Minute, LifetimeJitter: 0.1})) != 0 {
+		t.Fatal("Unexpected jitter warnings")
+	}
+	if warnings := lifetimeJitterWarnings(DBConfig{Lifetime: time.Minute, LifetimeJitter: -0.1}); len(warnings) != 1 ||
+		!strings.Contains(warnings[0].Message, "disabled") {
+		t.Fatalf("Expected a negative jitter to be reported as disabled, got %v", warnings)
+	}
+	if warnings := lifetimeWarnings(DBConfig{Lifetime: time.Minute, LifetimeJitter: 0.5}, 80*time.Second); len(warnings) != 1 ||
+		warnings[0].Field != ConfigFieldLifetime {
+		t.Fatalf("Expected the jittered lifetime to be checked against wait_timeout, got %v", warnings)
+	}
+}
+
+func TestLifetimeFollowsReconfigure(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1)
+	control := newPoolControl(DBConfig{Lifetime: time.Minute, LifetimeJitter: 0.5})
+	pool := sql.OpenDB(&trackedConnector{connector: &fakeConnector{}, sessions: &sessionTracker{}, control: control, clock: clock})
+	defer pool.Close()
+	ctx := context.Background()
+
+	control.apply(DBConfig{Lifetime: time.Hour, LifetimeJitter: 0.1})
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	defer conn.Close()
+	_ = conn.Raw(func(driverConn interface{}) error {
+		if lifetime := driverConn.(*trackedConn).expires.Sub(clock.Now()); lifetime < 54*time.Minute || lifetime >= 66*time.Minute {
+			t.Errorf("Expected the reconfigured lifetime of 1h ± 10%%, got %v", lifetime)
+		}
+		return nil
+	})
+}
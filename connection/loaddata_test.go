@@ -0,0 +1,46 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadDataStatement(t *testing.T) {
+	statement, err := loadDataStatement("mysqlconn_1", "analytics.audience", LoadDataOptions{
+		Columns:     []string{"id", "email"},
+		IgnoreLines: 1,
+		Duplicates:  LoadDuplicatesReplace,
+	})
+	if err != nil {
+		t.Fatalf("loadDataStatement failed: %v", err)
+	}
+	want := "LOAD DATA LOCAL INFILE 'Reader::mysqlconn_1' REPLACE INTO TABLE `analytics`.`audience` CHARACTER SET utf8mb4" +
+		` FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"' ESCAPED BY '' LINES TERMINATED BY '\n'` +
+		" IGNORE 1 LINES (`id`, `email`)"
+	if statement != want {
+		t.Errorf("statement =\n%s\nwant\n%s", statement, want)
+	}
+
+	if _, err := loadDataStatement("r", "t", LoadDataOptions{CharacterSet: "utf8; DROP TABLE t"}); err == nil {
+		t.Error("Expected an error for an invalid character set, got nil")
+	}
+	if _, err := loadDataStatement("r", "t", LoadDataOptions{Duplicates: 7}); err == nil {
+		t.Error("Expected an error for an invalid duplicate handling, got nil")
+	}
+}
+
+func TestLoadDataDisabled(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["ingest_db"] = newConnectorDB(t, &fakeConnector{})
+	factory.configs["ingest_db"] = DBConfig{}
+
+	_, err := factory.LoadData(context.Background(), "ingest_db", "audience", strings.NewReader("1,a\n"), LoadDataOptions{})
+	if !errors.Is(err, ErrLoadDataDisabled) {
+		t.Fatalf("Expected ErrLoadDataDisabled, got %v", err)
+	}
+	if _, err := factory.LoadData(context.Background(), "missing_db", "t", strings.NewReader(""), LoadDataOptions{}); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
@@ -0,0 +1,118 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+)
+
+// defaultNPlusOneThreshold is the number of executions with distinct arguments reported as N+1
+// unless DBConfig.NPlusOneThreshold is set.
+const defaultNPlusOneThreshold = 5
+
+// NPlusOneReport describes a statement executed repeatedly with different arguments within one
+// request scope: usually a query issued per row of a previous result (the N+1 pattern).
+type NPlusOneReport struct {
+	Connection  string
+	Fingerprint string
+
+	// Count is the number of executions with distinct arguments in the scope so far.
+	Count int
+
+	// FirstStack is the call stack of the first execution.
+	FirstStack string
+}
+
+func (r NPlusOneReport) String() string {
+	return fmt.Sprintf("possible N+1 on %q: %d executions of %s\nfirst executed at:\n%s", r.Connection, r.Count, r.Fingerprint, r.FirstStack)
+}
+
+type nPlusOneScopeKey struct{}
+
+// nPlusOneScope collects the statements of one request context.
+type nPlusOneScope struct {
+	mutex      sync.Mutex
+	statements map[string]*nPlusOneStatement
+}
+
+type nPlusOneStatement struct {
+	report   NPlusOneReport
+	args     map[string]struct{}
+	reported bool
+}
+
+// WithNPlusOneScope returns a context whose statements are analyzed together by the N+1 detector of
+// connections configured with DBConfig.DetectNPlusOne. Use one scope per request or job.
+//
+// Example Usage:
+//
+//	ctx := connection.WithNPlusOneScope(r.Context())
+//	handle(ctx)
+//	for _, report := range connection.NPlusOneReports(ctx) {
+//		log.Print(report)
+//	}
+func WithNPlusOneScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, nPlusOneScopeKey{}, &nPlusOneScope{statements: make(map[string]*nPlusOneStatement)})
+}
+
+// NPlusOneReports returns the N+1 patterns detected in the scope of ctx, ordered by fingerprint.
+func NPlusOneReports(ctx context.Context) []NPlusOneReport {
+	scope, _ := ctx.Value(nPlusOneScopeKey{}).(*nPlusOneScope)
+	if scope == nil {
+		return nil
+	}
+	scope.mutex.Lock()
+	defer scope.mutex.Unlock()
+
+	var reports []NPlusOneReport
+	for _, stmt := range scope.statements {
+		if stmt.reported {
+			reports = append(reports, stmt.report)
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Fingerprint < reports[j].Fingerprint })
+	return reports
+}
+
+// nPlusOneHook returns the statement hook of the N+1 detector. Statements are grouped by fingerprint
+// within their context's scope; a group is reported, once, when it has run with threshold distinct
+// argument lists. Reports are logged and kept for NPlusOneReports. Statements are never rejected.
+func nPlusOneHook(threshold int) statementHook {
+	if threshold <= 0 {
+		threshold = defaultNPlusOneThreshold
+	}
+	return func(ctx context.Context, stmt *hookedStatement) error {
+		scope, _ := ctx.Value(nPlusOneScopeKey{}).(*nPlusOneScope)
+		if scope == nil {
+			return nil
+		}
+		fingerprint := Fingerprint(stmt.SQL)
+		args := fmt.Sprint(stmt.Args...)
+
+		scope.mutex.Lock()
+		defer scope.mutex.Unlock()
+
+		key := stmt.Conn + "\x00" + fingerprint
+		entry, seen := scope.statements[key]
+		if !seen {
+			entry = &nPlusOneStatement{
+				report: NPlusOneReport{Connection: stmt.Conn, Fingerprint: fingerprint, FirstStack: string(debug.Stack())},
+				args:   make(map[string]struct{}),
+			}
+			scope.statements[key] = entry
+		}
+		if _, repeated := entry.args[args]; repeated {
+			return nil
+		}
+		entry.args[args] = struct{}{}
+		entry.report.Count = len(entry.args)
+		if entry.report.Count >= threshold && !entry.reported {
+			entry.reported = true
+			log.Print(entry.report)
+		}
+		return nil
+	}
+}
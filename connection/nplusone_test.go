@@ -0,0 +1,37 @@
+package connection
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNPlusOneDetector(t *testing.T) {
+	db, hooks, _ := newRecordingDB(t, "orders_db")
+	hooks.set("n_plus_one", nPlusOneHook(3))
+
+	ctx := WithNPlusOneScope(context.Background())
+	for id := 1; id <= 4; id++ {
+		db.WithContext(ctx).Exec("UPDATE items SET seen = 1 WHERE order_id = ?", id)
+	}
+	// The same arguments again are a repeat, not an N+1.
+	for i := 0; i < 4; i++ {
+		db.WithContext(ctx).Exec("UPDATE orders SET seen = 1 WHERE id = ?", 7)
+	}
+
+	reports := NPlusOneReports(ctx)
+	if len(reports) != 1 {
+		t.Fatalf("Expected one report, got %+v", reports)
+	}
+	report := reports[0]
+	if report.Connection != "orders_db" || report.Count != 4 || !strings.Contains(report.Fingerprint, "items") {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if !strings.Contains(report.FirstStack, "TestNPlusOneDetector") {
+		t.Fatalf("Expected the first stack to include the caller, got %s", report.FirstStack)
+	}
+
+	if NPlusOneReports(context.Background()) != nil {
+		t.Fatal("Expected no reports without a scope")
+	}
+}
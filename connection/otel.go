@@ -0,0 +1,255 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"gorm.io/gorm"
+	"time"
+)
+
+// OpenTelemetry instrument and attribute names. They follow the database client semantic conventions.
+const (
+	otelOperationDuration  = "db.client.operation.duration"
+	otelConnectionCount    = "db.client.connection.count"
+	otelConnectionMax      = "db.client.connection.max"
+	otelConnectionWaits    = "db.client.connection.wait_count"
+	otelConnectionWaitTime = "db.client.connection.wait_time"
+	otelConnectionClosed   = "db.client.connection.closed"
+	otelReconnectDuration  = "db.client.connection.reconnect.duration"
+	otelConnectionDowntime = "db.client.connection.downtime"
+	otelReplicaLag         = "db.client.replica.lag"
+	otelReplicaShare       = "db.client.replica.selection_share"
+
+	otelPluginName   = "mysqlconn:otel"
+	otelStartSetting = "mysqlconn:otel_start"
+)
+
+var (
+	otelSystem   = attribute.String("db.system", "mysql")
+	otelPoolName = attribute.Key("db.client.connection.pool.name")
+)
+
+// otelInstruments are the synchronous instruments recorded by the otel plugin of every connection.
+type otelInstruments struct {
+	duration  metric.Float64Histogram
+	reconnect metric.Float64Histogram
+}
+
+// RegisterOTelMetrics exports the pool statistics and the statement latencies of all managed connections
+// through an OpenTelemetry meter, so services on an OTLP pipeline get the data without scraping.
+//
+// Parameters:
+// - meter: The meter the instruments are created on, typically otel.Meter(...) or provider.Meter(...).
+//
+// Returns:
+// - func() error: Stops the export. Statement latencies are no longer recorded and the pool callback is unregistered.
+// - error: An error if an instrument or the callback cannot be created.
+//
+// Behavior:
+// 1. Pool statistics (connections in use and idle, pool limit, waits, wait time and connections closed by
+// reason) are observed from database/sql on every collection, for every connection managed at that time.
+// 2. Statement latencies are recorded in the db.client.operation.duration histogram, in seconds, by the
+// GORM callbacks every connection is initialized with. Failed statements carry an error.type attribute.
+// 3. Reconnect attempts are recorded in the db.client.connection.reconnect.duration histogram, in seconds,
+// with an outcome attribute (success or failure), and the total downtime of each connection (see
+// DowntimeHistory) is observed as the db.client.connection.downtime counter, in seconds.
+// 4. The replication delay of the replicas of running lag monitors (see StartLagMonitor) is observed as
+// the db.client.replica.lag gauge, in seconds, with the replica as the pool name.
+// 5. The fraction of the reads of ReaderDB routed to each replica (see ReplicaStats) is observed as the
+// db.client.replica.selection_share gauge, between 0 and 1, with the replica as the pool name.
+// 6. Every measurement carries db.system=mysql, db.client.connection.pool.name (the connection name) and
+// the cluster identity of the connection (DBConfig.Cluster) as db.cluster.name, db.cluster.region and
+// db.cluster.role.
+//
+// Example Usage:
+//
+//	stop, err := connection.GetConnectionManager().RegisterOTelMetrics(otel.Meter("orders-service"))
+//	if err != nil {
+//		log.Fatalf("Failed to export database metrics: %v", err)
+//	}
+//	defer stop()
+func (f *ConnectionManager) RegisterOTelMetrics(meter metric.Meter) (func() error, error) {
+	duration, err := meter.Float64Histogram(otelOperationDuration,
+		metric.WithUnit("s"), metric.WithDescription("Duration of database client operations."))
+	if err != nil {
+		return nil, err
+	}
+	count, err := meter.Int64ObservableUpDownCounter(otelConnectionCount,
+		metric.WithUnit("{connection}"), metric.WithDescription("Connections currently in use or idle, by state."))
+	if err != nil {
+		return nil, err
+	}
+	limit, err := meter.Int64ObservableUpDownCounter(otelConnectionMax,
+		metric.WithUnit("{connection}"), metric.WithDescription("Maximum number of open connections allowed."))
+	if err != nil {
+		return nil, err
+	}
+	waits, err := meter.Int64ObservableCounter(otelConnectionWaits,
+		metric.WithUnit("{wait}"), metric.WithDescription("Times a statement waited for a free connection."))
+	if err != nil {
+		return nil, err
+	}
+	waitTime, err := meter.Float64ObservableCounter(otelConnectionWaitTime,
+		metric.WithUnit("s"), metric.WithDescription("Total time spent waiting for a free connection."))
+	if err != nil {
+		return nil, err
+	}
+	closed, err := meter.Int64ObservableCounter(otelConnectionClosed,
+		metric.WithUnit("{connection}"), metric.WithDescription("Connections closed by the pool, by reason."))
+	if err != nil {
+		return nil, err
+	}
+	reconnect, err := meter.Float64Histogram(otelReconnectDuration,
+		metric.WithUnit("s"), metric.WithDescription("Duration of reconnect attempts, by outcome."))
+	if err != nil {
+		return nil, err
+	}
+	downtime, err := meter.Float64ObservableCounter(otelConnectionDowntime,
+		metric.WithUnit("s"), metric.WithDescription("Total time the connection was down, from a failed health check to the next successful statement."))
+	if err != nil {
+		return nil, err
+	}
+
+	lag, err := meter.Float64ObservableGauge(otelReplicaLag,
+		metric.WithUnit("s"), metric.WithDescription("Replication delay of the replica, measured from a heartbeat table."))
+	if err != nil {
+		return nil, err
+	}
+	share, err := meter.Float64ObservableGauge(otelReplicaShare,
+		metric.WithUnit("1"), metric.WithDescription("Fraction of the reads of the primary routed to the replica."))
+	if err != nil {
+		return nil, err
+	}
+
+	registration, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		clusters := f.clusters()
+		for name, stats := range f.poolStats() {
+			pool := metric.WithAttributes(otelAttributes(name, clusters[name])...)
+			with := func(key, value string) metric.ObserveOption {
+				return metric.WithAttributes(append(otelAttributes(name, clusters[name]), attribute.String(key, value))...)
+			}
+			o.ObserveInt64(count, int64(stats.InUse), with("db.client.connection.state", "used"))
+			o.ObserveInt64(count, int64(stats.Idle), with("db.client.connection.state", "idle"))
+			o.ObserveInt64(limit, int64(stats.MaxOpenConnections), pool)
+			o.ObserveInt64(waits, stats.WaitCount, pool)
+			o.ObserveFloat64(waitTime, stats.WaitDuration.Seconds(), pool)
+			for reason, n := range map[string]int64{
+				"max_idle":      stats.MaxIdleClosed,
+				"max_idle_time": stats.MaxIdleTimeClosed,
+				"max_lifetime":  stats.MaxLifetimeClosed,
+			} {
+				o.ObserveInt64(closed, n, with("reason", reason))
+			}
+		}
+		for name, total := range f.reconnects.downtimes(f.clock().Now()) {
+			o.ObserveFloat64(downtime, total.Seconds(), metric.WithAttributes(otelAttributes(name, clusters[name])...))
+		}
+		for _, replica := range f.lagStats() {
+			if replica.Measured {
+				o.ObserveFloat64(lag, replica.Lag.Seconds(), metric.WithAttributes(otelAttributes(replica.Name, clusters[replica.Name])...))
+			}
+		}
+		for _, replica := range f.replicaStats() {
+			o.ObserveFloat64(share, replica.Share, metric.WithAttributes(otelAttributes(replica.Name, clusters[replica.Name])...))
+		}
+		return nil
+	}, count, limit, waits, waitTime, closed, downtime, lag, share)
+	if err != nil {
+		return nil, err
+	}
+
+	instruments := &otelInstruments{duration: duration, reconnect: reconnect}
+	f.otel.Store(instruments)
+	return func() error {
+		f.otel.CompareAndSwap(instruments, nil)
+		return registration.Unregister()
+	}, nil
+}
+
+// poolStats returns the database/sql statistics of every managed connection.
+func (f *ConnectionManager) poolStats() map[string]sql.DBStats {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	stats := make(map[string]sql.DBStats, len(f.connections))
+	for name, db := range f.connections {
+		if _, err := db.DB(); err == nil {
+			stats[name] = poolStats(db)
+		}
+	}
+	return stats
+}
+
+// otelPlugin times the statements of one connection for RegisterOTelMetrics. It is installed on every
+// connection and records nothing until metrics are registered.
+type otelPlugin struct {
+	factory *ConnectionManager
+	name    string
+	cluster ClusterMetadata
+}
+
+func (p otelPlugin) Name() string {
+	return otelPluginName
+}
+
+func (p otelPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	processors := []struct {
+		operation     string
+		before, after func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("*").Register, cb.Create().After("*").Register},
+		{"query", cb.Query().Before("*").Register, cb.Query().After("*").Register},
+		{"update", cb.Update().Before("*").Register, cb.Update().After("*").Register},
+		{"delete", cb.Delete().Before("*").Register, cb.Delete().After("*").Register},
+		{"row", cb.Row().Before("*").Register, cb.Row().After("*").Register},
+		{"raw", cb.Raw().Before("*").Register, cb.Raw().After("*").Register},
+	}
+	for _, proc := range processors {
+		if err := proc.before(otelPluginName+"_before_"+proc.operation, p.start); err != nil {
+			return err
+		}
+		if err := proc.after(otelPluginName+"_after_"+proc.operation, p.record(proc.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p otelPlugin) start(db *gorm.DB) {
+	if p.factory.otel.Load() != nil {
+		db.InstanceSet(otelStartSetting, time.Now())
+	}
+}
+
+func (p otelPlugin) record(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		instruments := p.factory.otel.Load()
+		if instruments == nil {
+			return
+		}
+		value, ok := db.InstanceGet(otelStartSetting)
+		if !ok {
+			return
+		}
+		started, _ := value.(time.Time)
+
+		attrs := append(otelAttributes(p.name, p.cluster), attribute.String("db.operation.name", operation))
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			attrs = append(attrs, attribute.String("error.type", errorType(db.Error)))
+		}
+		ctx := db.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		instruments.duration.Record(ctx, time.Since(started).Seconds(), metric.WithAttributes(attrs...))
+	}
+}
+
+// errorType is the low-cardinality error.type attribute of a failed statement.
+func errorType(err error) string {
+	return ErrorClass(err)
+}
@@ -0,0 +1,70 @@
+package connection
+
+import (
+	"context"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"testing"
+	"time"
+)
+
+func TestRegisterOTelMetrics(t *testing.T) {
+	db := newDryRunDB(t)
+	factory := newTestFactory()
+	if err := db.Use(otelPlugin{factory: factory, name: "primary_db"}); err != nil {
+		t.Fatalf("Failed to install metrics callbacks: %v", err)
+	}
+	factory.connections["primary_db"] = db
+	factory.connections["replica_db"] = newConnectorDB(t, &fakeConnector{})
+	if err := factory.SetReplicas("primary_db", []string{"replica_db"}, ReplicaOptions{ProbeInterval: time.Hour}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer factory.SetReplicas("primary_db", nil, ReplicaOptions{})
+	if _, err := factory.ReaderDB(context.Background(), "primary_db"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	stop, err := factory.RegisterOTelMetrics(provider.Meter("test"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	db.Find(&[]repoTestUser{})
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("Failed to collect metrics: %v", err)
+	}
+	found := make(map[string]metricdata.Aggregation)
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			found[m.Name] = m.Data
+		}
+	}
+	for _, name := range []string{otelOperationDuration, otelConnectionCount, otelConnectionMax, otelConnectionWaits, otelConnectionClosed, otelReplicaShare} {
+		if _, ok := found[name]; !ok {
+			t.Fatalf("Metric %q not exported, got %v", name, found)
+		}
+	}
+	histogram := found[otelOperationDuration].(metricdata.Histogram[float64])
+	if len(histogram.DataPoints) != 1 || histogram.DataPoints[0].Count != 1 {
+		t.Fatalf("Expected one recorded query, got %+v", histogram.DataPoints)
+	}
+	if op, _ := histogram.DataPoints[0].Attributes.Value("db.operation.name"); op.AsString() != "query" {
+		t.Fatalf("Unexpected operation attribute %q", op.AsString())
+	}
+
+	gauge := found[otelReplicaShare].(metricdata.Gauge[float64])
+	if len(gauge.DataPoints) != 1 || gauge.DataPoints[0].Value != 1 {
+		t.Fatalf("Expected the replica to serve every read, got %+v", gauge.DataPoints)
+	}
+
+	if err := stop(); err != nil {
+		t.Fatalf("Unexpected error stopping export: %v", err)
+	}
+	if factory.otel.Load() != nil {
+		t.Fatal("Expected statement recording to stop")
+	}
+}
@@ -0,0 +1,292 @@
+package connection
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// PageMode selects the pagination strategy used by Paginate.
+type PageMode int
+
+const (
+	// PageOffset paginates with LIMIT/OFFSET. Simple, supports jumping, but gets slower
+	// for deep pages and may skip or repeat rows when data changes between requests.
+	PageOffset PageMode = iota
+
+	// PageKeyset paginates by seeking past the ordering values of the last returned row
+	// ("WHERE (a, b) > (?, ?)"). Cost is independent of page depth.
+	PageKeyset
+)
+
+// ErrInvalidPageToken is returned when a page token cannot be decoded or was issued
+// for a different mode or ordering than the current request.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// PageRequest describes the page to fetch.
+type PageRequest struct {
+	// Mode selects offset or keyset pagination.
+	Mode PageMode
+
+	// Size is the maximum number of items to return; it must be positive.
+	Size int
+
+	// Token is the NextToken of the previous response. Empty means the first page.
+	Token string
+
+	// OrderBy lists the column names to order by. The ordering must be total, so it has
+	// to include every primary key column of the model; this is validated.
+	OrderBy []string
+
+	// Desc orders all OrderBy columns descending instead of ascending.
+	Desc bool
+}
+
+// PageResponse is a page of items plus the token to request the next page.
+type PageResponse[T any] struct {
+	// Items holds at most PageRequest.Size records.
+	Items []T
+
+	// NextToken is an opaque token for the following page; empty when HasMore is false.
+	NextToken string
+
+	// HasMore reports whether another page exists.
+	HasMore bool
+}
+
+// pageToken is the decoded form of PageResponse.NextToken.
+type pageToken struct {
+	Mode     PageMode      `json:"m"`
+	Ordering string        `json:"o"`
+	Offset   int           `json:"n,omitempty"`
+	Values   []interface{} `json:"k,omitempty"`
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// Paginate fetches one page of T from db according to req.
+//
+// Parameters:
+// - ctx: Context bound to the query.
+// - db: The database handle, typically obtained from GetDB. Existing conditions (Where, Joins) on it are kept.
+// - req: The page request; see PageRequest.
+//
+// Returns:
+// - PageResponse[T]: The items and the next page token.
+// - error: A validation error, ErrInvalidPageToken, or the query error.
+//
+// Behavior:
+// 1. Validates that the ordering is made of plain column identifiers of T and contains every primary key column,
+// so that rows have a stable, unique position across requests.
+// 2. Decodes req.Token and rejects it when it was issued for another mode or ordering.
+// 3. Queries Size+1 rows to find out whether a further page exists without a COUNT(*).
+// 4. Builds the next token from the offset (offset mode) or from the ordering values of the last row (keyset mode).
+//
+// Example Usage:
+//
+//	db, _ := connection.GetConnectionManager().GetDB("primary_db")
+//	page, err := connection.Paginate[User](ctx, db.Where("active = ?", true), connection.PageRequest{
+//		Mode:    connection.PageKeyset,
+//		Size:    50,
+//		OrderBy: []string{"created_at", "id"},
+//		Token:   r.URL.Query().Get("page_token"),
+//	})
+func Paginate[T any](ctx context.Context, db *gorm.DB, req PageRequest) (PageResponse[T], error) {
+	var resp PageResponse[T]
+
+	query, token, fields, err := buildPageQuery[T](db.WithContext(ctx), req)
+	if err != nil {
+		return resp, err
+	}
+
+	var items []T
+	if err := query.Find(&items).Error; err != nil {
+		return resp, err
+	}
+
+	if len(items) <= req.Size {
+		resp.Items = items
+		return resp, nil
+	}
+
+	resp.Items = items[:req.Size]
+	resp.HasMore = true
+
+	next := pageToken{Mode: req.Mode, Ordering: token.Ordering}
+	switch req.Mode {
+	case PageOffset:
+		next.Offset = token.Offset + req.Size
+	case PageKeyset:
+		last := reflect.ValueOf(&resp.Items[req.Size-1]).Elem()
+		for _, field := range fields {
+			value, _ := field.ValueOf(ctx, last)
+			next.Values = append(next.Values, keysetValue(value))
+		}
+	}
+
+	if resp.NextToken, err = encodePageToken(next); err != nil {
+		return PageResponse[T]{}, err
+	}
+	return resp, nil
+}
+
+// buildPageQuery validates req and returns the query for the page (fetching Size+1 rows),
+// the decoded current token and the schema fields of the ordering columns.
+func buildPageQuery[T any](db *gorm.DB, req PageRequest) (*gorm.DB, pageToken, []*schema.Field, error) {
+	var token pageToken
+
+	if req.Size <= 0 {
+		return nil, token, nil, fmt.Errorf("page size must be positive, got %d", req.Size)
+	}
+	if req.Mode != PageOffset && req.Mode != PageKeyset {
+		return nil, token, nil, fmt.Errorf("unknown page mode %d", req.Mode)
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, token, nil, fmt.Errorf("failed to parse model for pagination: %w", err)
+	}
+	fields, err := orderingFields(stmt.Schema, req.OrderBy)
+	if err != nil {
+		return nil, token, nil, err
+	}
+
+	ordering := strings.Join(req.OrderBy, ",")
+	if req.Desc {
+		ordering += ":desc"
+	}
+	token.Mode, token.Ordering = req.Mode, ordering
+	if req.Token != "" {
+		if token, err = decodePageToken(req.Token); err != nil {
+			return nil, token, nil, err
+		}
+		if token.Mode != req.Mode || token.Ordering != ordering {
+			return nil, token, nil, fmt.Errorf("%w: issued for a different mode or ordering", ErrInvalidPageToken)
+		}
+	}
+
+	orderBy := clause.OrderBy{}
+	for _, field := range fields {
+		orderBy.Columns = append(orderBy.Columns, clause.OrderByColumn{
+			Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName},
+			Desc:   req.Desc,
+		})
+	}
+	query := db.Model(new(T)).Clauses(orderBy).Limit(req.Size + 1)
+
+	switch req.Mode {
+	case PageOffset:
+		if token.Offset < 0 {
+			return nil, token, nil, fmt.Errorf("%w: negative offset", ErrInvalidPageToken)
+		}
+		query = query.Offset(token.Offset)
+	case PageKeyset:
+		if req.Token == "" {
+			break
+		}
+		if len(token.Values) != len(fields) {
+			return nil, token, nil, fmt.Errorf("%w: expected %d keyset values, got %d", ErrInvalidPageToken, len(fields), len(token.Values))
+		}
+		// Columns are passed as clause.Column vars so they are quoted once the table is known.
+		vars := make([]interface{}, 0, 2*len(fields))
+		for _, field := range fields {
+			vars = append(vars, clause.Column{Table: clause.CurrentTable, Name: field.DBName})
+		}
+		vars = append(vars, token.Values...)
+		operator := ">"
+		if req.Desc {
+			operator = "<"
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(fields)), ", ")
+		query = query.Where(clause.Expr{
+			SQL:  fmt.Sprintf("(%s) %s (%s)", placeholders, operator, placeholders),
+			Vars: vars,
+		})
+	}
+
+	return query, token, fields, nil
+}
+
+// orderingFields resolves the ordering columns against the model schema and checks that the
+// ordering is total, i.e. it contains all primary key columns.
+func orderingFields(s *schema.Schema, orderBy []string) ([]*schema.Field, error) {
+	if len(orderBy) == 0 {
+		return nil, errors.New("pagination requires at least one order by column")
+	}
+
+	fields := make([]*schema.Field, 0, len(orderBy))
+	seen := make(map[string]bool, len(orderBy))
+	for _, column := range orderBy {
+		if !identifierPattern.MatchString(column) {
+			return nil, fmt.Errorf("invalid order by column %q", column)
+		}
+		field := s.LookUpField(column)
+		if field == nil || field.DBName == "" {
+			return nil, fmt.Errorf("order by column %q is not a column of %s", column, s.Name)
+		}
+		if seen[field.DBName] {
+			return nil, fmt.Errorf("order by column %q is listed twice", column)
+		}
+		seen[field.DBName] = true
+		fields = append(fields, field)
+	}
+
+	if len(s.PrimaryFields) == 0 {
+		return nil, fmt.Errorf("model %s has no primary key; pagination order would not be stable", s.Name)
+	}
+	for _, pk := range s.PrimaryFields {
+		if !seen[pk.DBName] {
+			return nil, fmt.Errorf("order by must include primary key column %q for a stable ordering", pk.DBName)
+		}
+	}
+	return fields, nil
+}
+
+// keysetValue converts a column value into a form that survives the JSON round trip of a token
+// and still compares correctly on the server. Times are rendered in MySQL DATETIME format.
+func keysetValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999")
+	case *time.Time:
+		if v != nil {
+			return v.Format("2006-01-02 15:04:05.999999")
+		}
+	}
+	return value
+}
+
+func encodePageToken(token pageToken) (string, error) {
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode page token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodePageToken(s string) (pageToken, error) {
+	var token pageToken
+
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return token, fmt.Errorf("%w: %v", ErrInvalidPageToken, err)
+	}
+
+	// UseNumber keeps large integer keys exact instead of rounding them through float64.
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&token); err != nil {
+		return token, fmt.Errorf("%w: %v", ErrInvalidPageToken, err)
+	}
+	return token, nil
+}
@@ -0,0 +1,108 @@
+package connection
+
+import (
+	"errors"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"strings"
+	"testing"
+	"time"
+)
+
+type pageTestEvent struct {
+	ID        uint
+	CreatedAt time.Time
+	Title     string
+}
+
+// newDryRunDB returns a GORM handle that renders SQL without ever contacting a server.
+func newDryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "user:password@tcp(127.0.0.1:3306)/dbname",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		// Default transactions would dial the server even in dry run mode.
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open dry run database: %v", err)
+	}
+	return db
+}
+
+func TestPaginateOrderingValidation(t *testing.T) {
+	db := newDryRunDB(t)
+
+	cases := map[string]PageRequest{
+		"no order":          {Size: 10},
+		"missing pk":        {Size: 10, OrderBy: []string{"created_at"}},
+		"unknown column":    {Size: 10, OrderBy: []string{"nope", "id"}},
+		"injection attempt": {Size: 10, OrderBy: []string{"id; DROP TABLE x"}},
+		"zero size":         {Size: 0, OrderBy: []string{"id"}},
+	}
+	for name, req := range cases {
+		if _, _, _, err := buildPageQuery[pageTestEvent](db, req); err == nil {
+			t.Errorf("%s: expected a validation error, got nil", name)
+		}
+	}
+}
+
+func TestPaginateKeysetQuery(t *testing.T) {
+	db := newDryRunDB(t)
+
+	token, err := encodePageToken(pageToken{
+		Mode:     PageKeyset,
+		Ordering: "created_at,id:desc",
+		Values:   []interface{}{"2024-01-01 00:00:00", 42},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode token: %v", err)
+	}
+
+	query, _, _, err := buildPageQuery[pageTestEvent](db, PageRequest{
+		Mode:    PageKeyset,
+		Size:    20,
+		Token:   token,
+		OrderBy: []string{"created_at", "id"},
+		Desc:    true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to build keyset query: %v", err)
+	}
+
+	var events []pageTestEvent
+	sql := query.Find(&events).Statement.SQL.String()
+	for _, want := range []string{
+		"(`page_test_events`.`created_at`, `page_test_events`.`id`) < (?, ?)",
+		"ORDER BY `page_test_events`.`created_at` DESC,`page_test_events`.`id` DESC",
+		"LIMIT ?",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("Expected SQL to contain %q, got %s", want, sql)
+		}
+	}
+}
+
+func TestPaginateRejectsForeignToken(t *testing.T) {
+	db := newDryRunDB(t)
+
+	token, _ := encodePageToken(pageToken{Mode: PageOffset, Ordering: "id", Offset: 20})
+	_, _, _, err := buildPageQuery[pageTestEvent](db, PageRequest{
+		Mode:    PageOffset,
+		Size:    20,
+		Token:   token,
+		OrderBy: []string{"created_at", "id"},
+	})
+	if !errors.Is(err, ErrInvalidPageToken) {
+		t.Fatalf("Expected ErrInvalidPageToken, got %v", err)
+	}
+
+	if _, err := decodePageToken("%%%"); !errors.Is(err, ErrInvalidPageToken) {
+		t.Fatalf("Expected ErrInvalidPageToken for garbage, got %v", err)
+	}
+}
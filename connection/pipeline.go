@@ -0,0 +1,207 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"strings"
+	"time"
+)
+
+// Pipeline queues independent statements and executes them together over one connection, with as
+// few round trips as the connection allows. Create it with ConnectionManager.Pipeline. A Pipeline is
+// not safe for concurrent use.
+type Pipeline struct {
+	factory    *ConnectionManager
+	ctx        context.Context
+	name       string
+	statements []pipelineStatement
+}
+
+type pipelineStatement struct {
+	SQL  string
+	Args []interface{}
+}
+
+// PipelineResult describes an executed pipeline.
+type PipelineResult struct {
+	// RowsAffected holds the rows affected by each statement, in queue order. It is -1 for a statement
+	// whose count the driver did not report.
+	RowsAffected []int64
+
+	// RoundTrips is the number of requests sent to the server: 1 when the statements were sent as one
+	// multi-statement, the number of statements otherwise.
+	RoundTrips int
+
+	// Duration is the latency of the whole pipeline, shared by its statements.
+	Duration time.Duration
+}
+
+// PipelineError is returned by Pipeline.Run for the statement that failed.
+type PipelineError struct {
+	// Statement is the 1-based position of the statement in the queue. It is 0 when the statements were
+	// sent as one multi-statement and the server did not tell which one failed.
+	Statement int
+
+	// SQL is the statement text, or the whole multi-statement when Statement is 0.
+	SQL string
+
+	// Err is the error of the statement.
+	Err error
+}
+
+func (e *PipelineError) Error() string {
+	if e.Statement == 0 {
+		return fmt.Sprintf("pipeline failed: %v", e.Err)
+	}
+	return fmt.Sprintf("pipeline statement %d failed: %v", e.Statement, e.Err)
+}
+
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+// Pipeline returns an empty pipeline on a managed connection, to queue statements with Exec and run
+// them with Run, e.g. the chatty SET and INSERT sequences of a startup.
+//
+// Parameters:
+// - ctx: Context bounding the execution of the pipeline.
+// - name: The name of the managed connection. It is resolved by Run.
+//
+// Returns:
+// - *Pipeline: The pipeline.
+//
+// Example Usage:
+//
+//	result, err := connection.GetConnectionManager().Pipeline(ctx, "primary_db").
+//		Exec("INSERT INTO boot_log (host) VALUES ('api-1')").
+//		Exec("UPDATE features SET seen = seen + 1 WHERE name = 'v2'").
+//		Exec("DELETE FROM sessions WHERE expires_at < NOW()").
+//		Run()
+//	if err == nil {
+//		log.Printf("startup sequence: %d round trips in %v", result.RoundTrips, result.Duration)
+//	}
+func (f *ConnectionManager) Pipeline(ctx context.Context, name string) *Pipeline {
+	return &Pipeline{factory: f, ctx: ctx, name: name}
+}
+
+// Exec queues a statement and returns the pipeline, for chaining. Statements must not depend on the
+// results of one another.
+func (p *Pipeline) Exec(query string, args ...interface{}) *Pipeline {
+	p.statements = append(p.statements, pipelineStatement{SQL: query, Args: args})
+	return p
+}
+
+// Len returns the number of statements queued.
+func (p *Pipeline) Len() int {
+	return len(p.statements)
+}
+
+// Run executes the queued statements in order on one connection of the pool and empties the queue.
+//
+// Returns:
+// - *PipelineResult: The rows affected by each statement, the round trips and the latency.
+// - error: A *PipelineError for the first failing statement, or an error if the connection does not
+// exist or no connection can be obtained. Statements that ran before the failure are not rolled back.
+//
+// Behavior:
+//  1. Each statement passes through the hooks of the connection (query policy, chaos faults, ...) before
+//     any is sent, so a rejected statement fails the pipeline without side effects.
+//  2. When the data source name enables multiStatements and no statement has placeholder arguments,
+//     the statements are sent as one multi-statement: a single round trip.
+//  3. Otherwise they run one after the other on the same connection, saving the pool checkouts.
+func (p *Pipeline) Run() (*PipelineResult, error) {
+	statements := p.statements
+	p.statements = nil
+	result := &PipelineResult{RowsAffected: make([]int64, len(statements))}
+	if len(statements) == 0 {
+		return result, nil
+	}
+
+	db, err := p.factory.GetDBContext(p.ctx, p.name)
+	if err != nil {
+		return nil, err
+	}
+	p.factory.mutex.Lock()
+	config := p.factory.configs[p.name]
+	hooks := p.factory.hooks[p.name]
+	p.factory.mutex.Unlock()
+
+	multi := true
+	queries := make([]string, len(statements))
+	args := make([][]interface{}, len(statements))
+	for i, stmt := range statements {
+		queries[i], args[i] = stmt.SQL, stmt.Args
+		if hooks != nil {
+			hooked, err := hooks.run(p.ctx, p.name, stmt.SQL, stmt.Args)
+			if err != nil {
+				return nil, &PipelineError{Statement: i + 1, SQL: stmt.SQL, Err: err}
+			}
+			queries[i], args[i] = hooked.SQL, hooked.Args
+		}
+		if len(args[i]) > 0 {
+			multi = false
+		}
+	}
+	if dsnConfig, err := mysqldriver.ParseDSN(config.DataSourceName); err != nil || !dsnConfig.MultiStatements {
+		multi = false
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, connError(p.name, OpPipeline, err)
+	}
+	start := time.Now()
+	conn, err := sqlDB.Conn(p.ctx)
+	if err != nil {
+		return nil, connError(p.name, OpPipeline, err)
+	}
+	defer conn.Close()
+
+	if multi {
+		joined := strings.Join(queries, ";\n")
+		result.RoundTrips = 1
+		err = conn.Raw(func(driverConn interface{}) error {
+			execer, ok := driverConn.(driver.ExecerContext)
+			if !ok {
+				return fmt.Errorf("driver connection %T cannot execute statements", driverConn)
+			}
+			res, err := execer.ExecContext(p.ctx, joined, nil)
+			if err != nil {
+				return err
+			}
+			counts := []int64(nil)
+			if mysqlResult, ok := res.(mysqldriver.Result); ok {
+				counts = mysqlResult.AllRowsAffected()
+			}
+			for i := range result.RowsAffected {
+				result.RowsAffected[i] = -1
+				if i < len(counts) {
+					result.RowsAffected[i] = counts[i]
+				}
+			}
+			return nil
+		})
+		result.Duration = time.Since(start)
+		if err != nil {
+			return result, &PipelineError{SQL: joined, Err: err}
+		}
+		return result, nil
+	}
+
+	for i, query := range queries {
+		result.RoundTrips++
+		res, err := conn.ExecContext(p.ctx, query, args[i]...)
+		if err != nil {
+			result.Duration = time.Since(start)
+			return result, &PipelineError{Statement: i + 1, SQL: statements[i].SQL, Err: err}
+		}
+		result.RowsAffected[i] = -1
+		if rows, err := res.RowsAffected(); err == nil {
+			result.RowsAffected[i] = rows
+		}
+	}
+	result.Duration = time.Since(start)
+	return result, nil
+}
@@ -0,0 +1,70 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPipelineSequential(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	ctx := context.Background()
+
+	result, err := factory.Pipeline(ctx, "primary_db").
+		Exec("INSERT INTO fake_test_users (name, email) VALUES (?, ?)", "dave", "dave@example.com").
+		Exec("UPDATE fake_test_users SET active = ? WHERE active = ?", true, false).
+		Exec("DELETE FROM fake_test_users WHERE id = 42").
+		Run()
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if result.RoundTrips != 3 || len(result.RowsAffected) != 3 || result.RowsAffected[0] != 1 ||
+		result.RowsAffected[1] != 1 || result.RowsAffected[2] != 0 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+
+	pipeline := factory.Pipeline(ctx, "primary_db").
+		Exec("DELETE FROM fake_test_users WHERE id = 4").
+		Exec("INSERT INTO fake_test_users (name, email) VALUES ('eve', 'alice@example.com')").
+		Exec("DELETE FROM fake_test_users WHERE id = 1")
+	_, err = pipeline.Run()
+	var pipelineErr *PipelineError
+	if !errors.As(err, &pipelineErr) || pipelineErr.Statement != 2 || ErrorClass(err) != "duplicate_key" {
+		t.Fatalf("Expected statement 2 to fail with a duplicate key, got %v", err)
+	}
+	if pipeline.Len() != 0 {
+		t.Fatalf("Expected Run to empty the queue, got %d statements", pipeline.Len())
+	}
+
+	db, _ := factory.GetDB("primary_db")
+	var count int64
+	if db.Model(&fakeTestUser{}).Count(&count); count != 3 {
+		t.Fatalf("Expected the statements before the failure to run and the ones after not to, got %d users", count)
+	}
+	if _, err := factory.Pipeline(ctx, "missing_db").Exec("SELECT 1").Run(); !errors.Is(err, ErrConnectionNotFound) {
+		t.Fatalf("Expected ErrConnectionNotFound, got %v", err)
+	}
+}
+
+func TestPipelineMultiStatements(t *testing.T) {
+	var commits, rollbacks int
+	factory := newTestFactory()
+	factory.connections["multi_db"] = newConnectorDB(t, txConnector{commits: &commits, rollbacks: &rollbacks})
+	factory.configs["multi_db"] = DBConfig{DataSourceName: "app@tcp(db:3306)/app?multiStatements=true"}
+
+	result, err := factory.Pipeline(context.Background(), "multi_db").
+		Exec("SET @a = 1").
+		Exec("UPDATE counters SET n = n + 1").
+		Run()
+	if err != nil || result.RoundTrips != 1 || len(result.RowsAffected) != 2 || result.RowsAffected[0] != -1 {
+		t.Fatalf("Expected one round trip, got %+v: %v", result, err)
+	}
+
+	result, err = factory.Pipeline(context.Background(), "multi_db").
+		Exec("SET @a = ?", 1).
+		Exec("UPDATE counters SET n = n + 1").
+		Run()
+	if err != nil || result.RoundTrips != 2 || result.RowsAffected[1] != 2 {
+		t.Fatalf("Expected statements with arguments to run one by one, got %+v: %v", result, err)
+	}
+}
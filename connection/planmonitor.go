@@ -0,0 +1,191 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultPlanCheckInterval is how often a PlanMonitor re-explains its queries unless configured otherwise.
+const defaultPlanCheckInterval = 24 * time.Hour
+
+// PlanChange reports a critical query whose plan regressed since the previous check.
+type PlanChange struct {
+	Connection string
+	Label      string
+	Before     *QueryPlan
+	After      *QueryPlan
+
+	// Reasons describe the regressions, e.g. `index "idx_customer" on "orders" no longer used`.
+	Reasons []string
+}
+
+// PlanMonitor periodically re-EXPLAINs registered critical queries and reports plan regressions:
+// an index that is no longer used, or a full table scan that was not there before.
+//
+// Behavior:
+// 1. The first successful EXPLAIN of a query is its baseline. Later plans are compared with the baseline,
+// and the baseline is replaced after every change so a regression is reported once.
+// 2. Check runs all queries immediately; Start runs Check every interval until Stop or ctx cancellation.
+// 3. Regressions are passed to the alert callback and logged.
+//
+// Example Usage:
+//
+//	monitor := connection.NewPlanMonitor(connection.GetConnectionManager(), 0, func(change connection.PlanChange) {
+//		pager.Notify(change.Label, change.Reasons)
+//	})
+//	_ = monitor.Watch("primary_db", "orders-by-customer", "SELECT * FROM orders WHERE customer_id = ?", 1)
+//	monitor.Start(ctx)
+//	defer monitor.Stop()
+type PlanMonitor struct {
+	factory  *ConnectionManager
+	interval time.Duration
+	alert    func(PlanChange)
+
+	mutex   sync.Mutex
+	queries map[string]*watchedQuery
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+type watchedQuery struct {
+	connection string
+	label      string
+	query      string
+	args       []interface{}
+	baseline   *QueryPlan
+}
+
+// NewPlanMonitor creates a monitor checking plans every interval (daily when interval is zero) and calling
+// alert for every regression. alert may be nil when only logging is wanted.
+func NewPlanMonitor(factory *ConnectionManager, interval time.Duration, alert func(PlanChange)) *PlanMonitor {
+	if interval <= 0 {
+		interval = defaultPlanCheckInterval
+	}
+	return &PlanMonitor{factory: factory, interval: interval, alert: alert, queries: make(map[string]*watchedQuery)}
+}
+
+// Watch registers a critical query under a label unique per monitor. args are the representative
+// placeholder values the query is explained with.
+func (m *PlanMonitor) Watch(name, label, query string, args ...interface{}) error {
+	if label == "" || query == "" {
+		return errors.New("watched query requires a label and a statement")
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.queries[label]; exists {
+		return fmt.Errorf("query %q already watched", label)
+	}
+	m.queries[label] = &watchedQuery{connection: name, label: label, query: query, args: args}
+	return nil
+}
+
+// Check explains every watched query now and returns the regressions found. Queries that cannot be
+// explained are logged and skipped.
+func (m *PlanMonitor) Check(ctx context.Context) []PlanChange {
+	m.mutex.Lock()
+	queries := make([]*watchedQuery, 0, len(m.queries))
+	for _, q := range m.queries {
+		queries = append(queries, q)
+	}
+	m.mutex.Unlock()
+	sort.Slice(queries, func(i, j int) bool { return queries[i].label < queries[j].label })
+
+	var changes []PlanChange
+	for _, q := range queries {
+		plan, err := m.factory.Explain(ctx, q.connection, q.query, q.args...)
+		if err != nil {
+			log.Printf("Plan monitor: query %q: %v", q.label, err)
+			continue
+		}
+
+		m.mutex.Lock()
+		before := q.baseline
+		q.baseline = plan
+		m.mutex.Unlock()
+		if before == nil {
+			continue
+		}
+
+		if reasons := planRegressions(before, plan); len(reasons) > 0 {
+			change := PlanChange{Connection: q.connection, Label: q.label, Before: before, After: plan, Reasons: reasons}
+			log.Printf("Plan of query %q on %q changed: %v", q.label, q.connection, reasons)
+			if m.alert != nil {
+				m.alert(change)
+			}
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// Start checks all queries immediately, to record baselines, and then every interval.
+func (m *PlanMonitor) Start(ctx context.Context) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.cancel != nil {
+		return
+	}
+	ctx, m.cancel = context.WithCancel(ctx)
+	m.done = make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			m.Check(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}(m.done)
+}
+
+// Stop ends the periodic checks and waits for a running check to return.
+func (m *PlanMonitor) Stop() {
+	m.mutex.Lock()
+	cancel, done := m.cancel, m.done
+	m.cancel, m.done = nil, nil
+	m.mutex.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// planRegressions compares two plans of the same statement table by table.
+func planRegressions(before, after *QueryPlan) []string {
+	previous := make(map[string]PlanStep, len(before.Steps))
+	for _, step := range before.Steps {
+		previous[step.Table] = step
+	}
+
+	var reasons []string
+	for _, step := range after.Steps {
+		old, ok := previous[step.Table]
+		if !ok {
+			continue
+		}
+		if step.Type == "ALL" && old.Type != "ALL" {
+			reasons = append(reasons, fmt.Sprintf("full table scan on %q (was %s access)", step.Table, old.Type))
+			continue
+		}
+		if old.Key != "" && step.Key != old.Key {
+			if step.Key == "" {
+				reasons = append(reasons, fmt.Sprintf("index %q on %q no longer used", old.Key, step.Table))
+			} else {
+				reasons = append(reasons, fmt.Sprintf("index %q on %q replaced by %q", old.Key, step.Table, step.Key))
+			}
+		}
+	}
+	return reasons
+}
@@ -0,0 +1,302 @@
+package connection
+
+import (
+	"fmt"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"reflect"
+)
+
+// callbackOperations are the GORM callback processors, in the order Callbacks lists them.
+var callbackOperations = []string{"create", "query", "update", "delete", "row", "raw"}
+
+// CallbackInfo describes one GORM callback registered on a managed connection.
+type CallbackInfo struct {
+	// Operation is the GORM processor the callback runs in: create, query, update, delete, row or raw.
+	Operation string
+
+	// Name is the callback name, e.g. "gorm:create" or "mysqlconn:otel_before_query".
+	Name string
+
+	// Plugin is the plugin that registered the callback. It is empty for GORM's own callbacks and
+	// callbacks registered directly on the *gorm.DB.
+	Plugin string
+}
+
+// pluginRegistry keeps track of the GORM plugins of one connection. It outlives reconnects, so the
+// plugins of the new pool are the same as those of the old one.
+type pluginRegistry struct {
+	// builtins are the plugins the manager installs itself on the connection.
+	builtins []gorm.Plugin
+
+	// extra are the plugins added with UsePlugin, in order.
+	extra []gorm.Plugin
+
+	// removed are the names of plugins removed with RemovePlugin. They are not installed again.
+	removed map[string]bool
+
+	// callbacks are the callbacks each installed plugin registered.
+	callbacks map[string][]CallbackInfo
+}
+
+// use installs plugin on db unless it was removed, and records the callbacks it registers.
+func (r *pluginRegistry) use(db *gorm.DB, plugin gorm.Plugin) error {
+	if r.removed[plugin.Name()] {
+		return nil
+	}
+	before := make(map[CallbackInfo]bool)
+	for _, cb := range registeredCallbacks(db) {
+		before[cb] = true
+	}
+	if err := db.Use(plugin); err != nil {
+		return err
+	}
+
+	var added []CallbackInfo
+	for _, cb := range registeredCallbacks(db) {
+		if !before[cb] {
+			cb.Plugin = plugin.Name()
+			added = append(added, cb)
+		}
+	}
+	if r.callbacks == nil {
+		r.callbacks = make(map[string][]CallbackInfo)
+	}
+	r.callbacks[plugin.Name()] = added
+	return nil
+}
+
+// installPlugins installs the built-in plugins and the plugins added with UsePlugin on a freshly
+// opened pool. The caller holds the mutex.
+func (f *ConnectionManager) installPlugins(name string, db *gorm.DB, builtins ...gorm.Plugin) (*pluginRegistry, error) {
+	registry := &pluginRegistry{builtins: builtins}
+	if previous := f.plugins[name]; previous != nil {
+		registry.extra, registry.removed = previous.extra, previous.removed
+	}
+	if err := registry.install(db); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+// install installs the built-in plugins and the plugins added with UsePlugin on db, which has none of
+// them yet.
+func (r *pluginRegistry) install(db *gorm.DB) error {
+	r.callbacks = nil
+	for _, plugin := range append(r.builtins[:len(r.builtins):len(r.builtins)], r.extra...) {
+		if err := r.use(db, plugin); err != nil {
+			return fmt.Errorf("failed to install plugin %q: %w", plugin.Name(), err)
+		}
+	}
+	return nil
+}
+
+// UsePlugin installs a GORM plugin on a managed connection. Unlike calling Use on the *gorm.DB, the
+// plugin is installed again when the connection is reconnected or replaced.
+//
+// Parameters:
+// - name: The name of the managed connection.
+// - plugin: The GORM plugin.
+//
+// Returns:
+// - error: An error if the connection does not exist, a plugin with the same name is installed or
+// the plugin fails to initialize.
+func (f *ConnectionManager) UsePlugin(name string, plugin gorm.Plugin) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	db, exists := f.connections[name]
+	if !exists {
+		return errNotFound(name, OpUsePlugin)
+	}
+	registry := f.plugins[name]
+	if registry == nil {
+		registry = &pluginRegistry{}
+	}
+	delete(registry.removed, plugin.Name())
+	if err := registry.use(db, plugin); err != nil {
+		return fmt.Errorf("failed to install plugin %q on %q: %w", plugin.Name(), name, err)
+	}
+	registry.extra = append(registry.extra, plugin)
+
+	if f.plugins == nil {
+		f.plugins = make(map[string]*pluginRegistry)
+	}
+	f.plugins[name] = registry
+	return nil
+}
+
+// Callbacks lists the GORM callbacks registered on a managed connection, with the plugin that
+// registered each of them.
+//
+// Parameters:
+// - name: The name of the managed connection.
+//
+// Returns:
+// - []CallbackInfo: The callbacks by operation (create, query, update, delete, row, raw), in registration order.
+// - error: An error if the connection does not exist.
+//
+// Example Usage:
+//
+//	callbacks, err := connection.GetConnectionManager().Callbacks("primary_db")
+//	for _, cb := range callbacks {
+//		log.Printf("%s %s (plugin %q)", cb.Operation, cb.Name, cb.Plugin)
+//	}
+func (f *ConnectionManager) Callbacks(name string) ([]CallbackInfo, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	db, exists := f.connections[name]
+	if !exists {
+		return nil, errNotFound(name, OpCallbacks)
+	}
+	owners := make(map[CallbackInfo]string)
+	if registry := f.plugins[name]; registry != nil {
+		for plugin, callbacks := range registry.callbacks {
+			for _, cb := range callbacks {
+				cb.Plugin = ""
+				owners[cb] = plugin
+			}
+		}
+	}
+
+	callbacks := registeredCallbacks(db)
+	for i := range callbacks {
+		callbacks[i].Plugin = owners[callbacks[i]]
+	}
+	return callbacks, nil
+}
+
+// RemovePlugin removes a plugin and all the callbacks it registered from a managed connection, for
+// example to disable a misbehaving plugin without a restart.
+//
+// Parameters:
+// - name: The name of the managed connection.
+// - pluginName: The name of the plugin, as returned by its Name method and listed by Callbacks.
+//
+// Returns:
+// - error: An error if the connection does not exist, the plugin is not installed or a callback cannot be removed.
+//
+// Behavior:
+// 1. GORM's callbacks cannot be changed while statements run, so the connection gets a new handle on
+// the same pool, with the remaining plugins installed on it: GetDB returns it from then on. Handles
+// obtained before, with the sessions and transactions derived from them, keep the old callbacks, and
+// so do the statements running on them. Callbacks registered on the *gorm.DB without UsePlugin are
+// not carried over.
+// 2. The plugin stays removed when the connection is reconnected or replaced, including the plugins
+// the manager installs itself (mysqlconn:otel, mysqlconn:tenancy). UsePlugin installs it again.
+//
+// Example Usage:
+//
+//	if err := connection.GetConnectionManager().RemovePlugin("primary_db", "mysqlconn:otel"); err != nil {
+//		log.Printf("Failed to remove plugin: %v", err)
+//	}
+func (f *ConnectionManager) RemovePlugin(name, pluginName string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	db, exists := f.connections[name]
+	if !exists {
+		return errNotFound(name, OpRemovePlugin)
+	}
+	registry := f.plugins[name]
+	var installed bool
+	if registry != nil {
+		_, installed = registry.callbacks[pluginName]
+	}
+	if !installed {
+		return fmt.Errorf("plugin %q is not installed on %q", pluginName, name)
+	}
+
+	next := &pluginRegistry{builtins: registry.builtins, removed: map[string]bool{pluginName: true}}
+	for removed := range registry.removed {
+		next.removed[removed] = true
+	}
+	for _, plugin := range registry.extra {
+		if plugin.Name() != pluginName {
+			next.extra = append(next.extra, plugin)
+		}
+	}
+	fresh, err := freshHandle(db)
+	if err != nil {
+		return fmt.Errorf("failed to open a new handle on %q: %w", name, err)
+	}
+	if err := next.install(fresh); err != nil {
+		return fmt.Errorf("failed to reinstall the plugins of %q: %w", name, err)
+	}
+	f.connections[name] = fresh
+	f.plugins[name] = next
+	return nil
+}
+
+// freshHandle returns a handle on the pool of db with the same settings and only GORM's own callbacks.
+// The pool, its statement hooks and its prepared statements are shared with db.
+func freshHandle(db *gorm.DB) (*gorm.DB, error) {
+	dialector, ok := db.Dialector.(*mysql.Dialector)
+	if !ok {
+		return nil, fmt.Errorf("unsupported dialector %T", db.Dialector)
+	}
+	// The server version was read when the pool was opened; the dialector settings derived from it are kept.
+	dialectorConfig := *dialector.Config
+	dialectorConfig.SkipInitializeWithVersion = true
+	config := *db.Config
+	config.Plugins, config.ClauseBuilders, config.PrepareStmt = nil, nil, false
+	fresh, err := gorm.Open(mysql.New(dialectorConfig), &config)
+	if err != nil {
+		return nil, err
+	}
+	fresh.Config.PrepareStmt = db.Config.PrepareStmt
+	fresh.ConnPool = db.ConnPool
+	fresh.Statement.ConnPool = db.ConnPool
+	return fresh, nil
+}
+
+// callbackProcessor returns the GORM callback processor of an operation.
+func callbackProcessor(db *gorm.DB, operation string) interface {
+	Remove(name string) error
+} {
+	cb := db.Callback()
+	switch operation {
+	case "create":
+		return cb.Create()
+	case "query":
+		return cb.Query()
+	case "update":
+		return cb.Update()
+	case "delete":
+		return cb.Delete()
+	case "row":
+		return cb.Row()
+	}
+	return cb.Raw()
+}
+
+// registeredCallbacks lists the callbacks registered on db. GORM does not export its callback lists,
+// so they are read through reflection; removed callbacks are already dropped from them by GORM.
+func registeredCallbacks(db *gorm.DB) []CallbackInfo {
+	var callbacks []CallbackInfo
+	for _, operation := range callbackOperations {
+		processor := reflect.ValueOf(callbackProcessor(db, operation))
+		if processor.Kind() != reflect.Pointer || processor.IsNil() {
+			continue
+		}
+		list := processor.Elem().FieldByName("callbacks")
+		if list.Kind() != reflect.Slice {
+			continue
+		}
+		seen := make(map[string]bool)
+		for i := 0; i < list.Len(); i++ {
+			entry := list.Index(i)
+			if entry.Kind() != reflect.Pointer || entry.IsNil() {
+				continue
+			}
+			name := entry.Elem().FieldByName("name")
+			if name.Kind() != reflect.String || name.String() == "" || seen[name.String()] {
+				continue
+			}
+			seen[name.String()] = true
+			callbacks = append(callbacks, CallbackInfo{Operation: operation, Name: name.String()})
+		}
+	}
+	return callbacks
+}
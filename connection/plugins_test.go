@@ -0,0 +1,145 @@
+package connection
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"sync"
+	"testing"
+)
+
+// countingPlugin counts the queries run on the connection it is installed on.
+type countingPlugin struct {
+	queries *int
+}
+
+func (countingPlugin) Name() string {
+	return "test:counting"
+}
+
+func (p countingPlugin) Initialize(db *gorm.DB) error {
+	return db.Callback().Query().Before("gorm:query").Register("test:counting_query", func(*gorm.DB) {
+		*p.queries++
+	})
+}
+
+func TestPluginsSurviveReconnect(t *testing.T) {
+	factory := newTestFactory()
+	db := newConnectorDB(t, &fakeConnector{})
+	registry, err := factory.installPlugins("plugin_db", db, otelPlugin{factory: factory, name: "plugin_db"})
+	if err != nil {
+		t.Fatalf("Failed to install plugins: %v", err)
+	}
+	factory.connections["plugin_db"] = db
+	factory.plugins = map[string]*pluginRegistry{"plugin_db": registry}
+
+	var queries int
+	if err := factory.UsePlugin("plugin_db", countingPlugin{queries: &queries}); err != nil {
+		t.Fatalf("UsePlugin failed: %v", err)
+	}
+	if err := factory.UsePlugin("missing_db", countingPlugin{queries: &queries}); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+
+	callbacks, err := factory.Callbacks("plugin_db")
+	if err != nil {
+		t.Fatalf("Callbacks failed: %v", err)
+	}
+	owners := make(map[string]string)
+	for _, cb := range callbacks {
+		owners[cb.Operation+" "+cb.Name] = cb.Plugin
+	}
+	for key, want := range map[string]string{
+		"query gorm:query":                   "",
+		"query test:counting_query":          "test:counting",
+		"query mysqlconn:otel_before_query":  otelPluginName,
+		"create mysqlconn:otel_after_create": otelPluginName,
+	} {
+		if got, ok := owners[key]; !ok || got != want {
+			t.Errorf("Callback %q: plugin %q (listed %v), want %q", key, got, ok, want)
+		}
+	}
+
+	var count int64
+	_ = db.WithContext(context.Background()).Table("users").Count(&count)
+	if queries != 1 {
+		t.Fatalf("Expected the plugin to see 1 query, got %d", queries)
+	}
+
+	if err := factory.RemovePlugin("plugin_db", otelPluginName); err != nil {
+		t.Fatalf("RemovePlugin failed: %v", err)
+	}
+	if err := factory.RemovePlugin("plugin_db", otelPluginName); err == nil {
+		t.Fatal("Expected an error removing a plugin twice, got nil")
+	}
+	callbacks, _ = factory.Callbacks("plugin_db")
+	for _, cb := range callbacks {
+		if cb.Plugin == otelPluginName || cb.Name == "mysqlconn:otel_before_query" {
+			t.Fatalf("Expected the otel plugin to be removed, found %+v", cb)
+		}
+	}
+	current := factory.connections["plugin_db"]
+	if current.Config.Plugins[otelPluginName] != nil || db.Config.Plugins[otelPluginName] == nil {
+		t.Fatal("Expected the new handle without the otel plugin and the previous one unchanged")
+	}
+	_ = current.WithContext(context.Background()).Table("users").Count(&count)
+	if queries != 2 {
+		t.Fatalf("Expected the remaining plugin on the new handle, got %d queries", queries)
+	}
+
+	// A reconnect opens a new pool: the removed plugin stays removed, the added one comes back.
+	reconnected := newConnectorDB(t, &fakeConnector{})
+	if _, err := factory.installPlugins("plugin_db", reconnected, otelPlugin{factory: factory, name: "plugin_db"}); err != nil {
+		t.Fatalf("Failed to reinstall plugins: %v", err)
+	}
+	if _, ok := reconnected.Config.Plugins[otelPluginName]; ok {
+		t.Error("Expected the removed plugin not to be reinstalled")
+	}
+	_ = reconnected.WithContext(context.Background()).Table("users").Count(&count)
+	if queries != 3 {
+		t.Errorf("Expected the added plugin to be reinstalled, got %d queries", queries)
+	}
+}
+
+func TestRemovePluginWhileQuerying(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg, started sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			for first := true; ctx.Err() == nil; first = false {
+				db, err := factory.GetDB("primary_db")
+				if err != nil {
+					t.Errorf("GetDB failed: %v", err)
+					return
+				}
+				var users []map[string]any
+				if err := db.WithContext(ctx).Table("fake_test_users").Find(&users).Error; err != nil && ctx.Err() == nil {
+					t.Errorf("Query failed: %v", err)
+				}
+				if first {
+					started.Done()
+				}
+			}
+		}()
+	}
+	started.Wait()
+	err := factory.RemovePlugin("primary_db", otelPluginName)
+	cancel()
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("RemovePlugin failed: %v", err)
+	}
+
+	callbacks, err := factory.Callbacks("primary_db")
+	if err != nil {
+		t.Fatalf("Callbacks failed: %v", err)
+	}
+	for _, cb := range callbacks {
+		if cb.Plugin == otelPluginName {
+			t.Fatalf("Expected the otel plugin to be removed, found %+v", cb)
+		}
+	}
+}
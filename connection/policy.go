@@ -0,0 +1,196 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Query policy rule names reported in PolicyViolationError.Rule.
+const (
+	PolicyRuleDenyDDL        = "deny_ddl"
+	PolicyRuleDenySelectStar = "deny_select_star"
+	PolicyRuleFingerprint    = "fingerprint_not_allowed"
+	PolicyRuleAutocommit     = "autocommit_write"
+)
+
+// ErrPolicyViolation matches every PolicyViolationError with errors.Is.
+var ErrPolicyViolation = errors.New("query policy violation")
+
+// PolicyViolationError is returned when a statement is rejected by the QueryPolicy of its connection.
+type PolicyViolationError struct {
+	// Connection is the name of the connection whose policy rejected the statement.
+	Connection string
+
+	// Rule is the violated rule, one of the PolicyRule constants.
+	Rule string
+
+	// Statement is the rejected statement.
+	Statement string
+}
+
+func (e *PolicyViolationError) Error() string {
+	return fmt.Sprintf("query policy violation on %q (%s): %s", e.Connection, e.Rule, e.Statement)
+}
+
+// Is makes errors.Is(err, ErrPolicyViolation) true for policy violations.
+func (e *PolicyViolationError) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
+
+// QueryPolicy restricts the statements that may run on a connection. It is set through
+// DBConfig.Policy and is typically used for shared connections handed to plugins, e.g. to
+// deny DDL on a production OLTP connection.
+//
+// Policies are checked on the final SQL sent to the server, so they apply to every GORM API
+// as well as Raw and Exec. Violations are logged and returned as *PolicyViolationError.
+type QueryPolicy struct {
+	// DenyDDL rejects CREATE, ALTER, DROP, TRUNCATE and RENAME statements.
+	DenyDDL bool
+
+	// DenySelectStar lists tables on which "SELECT *" projections are rejected.
+	// The entry "*" rejects SELECT * on every table.
+	DenySelectStar []string
+
+	// AllowedFingerprints, when not empty, turns the policy into an allowlist: only statements
+	// whose Fingerprint is listed may run. Use Allow to register statements by example.
+	// Note that GORM issues SAVEPOINT statements for nested transactions; allow them if needed.
+	AllowedFingerprints []string
+
+	// RequireTransactions rejects writes (INSERT, UPDATE, DELETE, REPLACE and LOAD DATA) run outside an
+	// explicit transaction, in autocommit mode. The writes of the GORM API run in a transaction by
+	// default; Exec, Raw and sessions with SkipDefaultTransaction must run in Transaction or BeginTx.
+	RequireTransactions bool
+
+	// TransactionIsolation is the isolation level of the transactions of the connection that do not set
+	// one, e.g. sql.LevelSerializable. sql.LevelDefault keeps the level of the server.
+	TransactionIsolation sql.IsolationLevel
+}
+
+// Allow adds the fingerprints of the given example statements to the allowlist and returns the policy.
+//
+// Example Usage:
+//
+//	policy := (&connection.QueryPolicy{DenyDDL: true}).Allow(
+//		"SELECT * FROM orders WHERE id = 1",
+//		"UPDATE orders SET status = 'x' WHERE id = 1",
+//	)
+func (p *QueryPolicy) Allow(statements ...string) *QueryPolicy {
+	for _, statement := range statements {
+		p.AllowedFingerprints = append(p.AllowedFingerprints, Fingerprint(statement))
+	}
+	return p
+}
+
+// ddlKeywords are the leading keywords of statements rejected by QueryPolicy.DenyDDL.
+var ddlKeywords = map[string]bool{"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "RENAME": true}
+
+// check returns the violated rule for query, or "" when the statement is allowed. inTx reports a
+// statement of an explicit transaction.
+func (p *QueryPolicy) check(query string, inTx bool, allowed map[string]bool, denyStar map[string]bool) string {
+	if p.DenyDDL && ddlKeywords[firstKeyword(query)] {
+		return PolicyRuleDenyDDL
+	}
+	if p.RequireTransactions && !inTx && isWriteStatement(query) {
+		return PolicyRuleAutocommit
+	}
+	if len(denyStar) > 0 {
+		for _, table := range selectStarTables(sqlTokens(query)) {
+			if denyStar["*"] || denyStar[table] {
+				return PolicyRuleDenySelectStar
+			}
+		}
+	}
+	if len(allowed) > 0 && !allowed[Fingerprint(query)] {
+		return PolicyRuleFingerprint
+	}
+	return ""
+}
+
+// hook compiles the policy into a statement hook for the connection.
+func (p *QueryPolicy) hook() statementHook {
+	allowed := make(map[string]bool, len(p.AllowedFingerprints))
+	for _, fp := range p.AllowedFingerprints {
+		allowed[fp] = true
+	}
+	denyStar := make(map[string]bool, len(p.DenySelectStar))
+	for _, table := range p.DenySelectStar {
+		denyStar[strings.ToLower(table)] = true
+	}
+
+	return func(ctx context.Context, stmt *hookedStatement) error {
+		rule := p.check(stmt.SQL, stmt.InTx, allowed, denyStar)
+		if rule == "" {
+			return nil
+		}
+		err := &PolicyViolationError{Connection: stmt.Conn, Rule: rule, Statement: stmt.SQL}
+		log.Printf("%v", err)
+		return err
+	}
+}
+
+// writeKeywords are the leading keywords of the statements QueryPolicy.RequireTransactions confines to
+// transactions.
+var writeKeywords = map[string]bool{"insert": true, "update": true, "delete": true, "replace": true, "load": true}
+
+// isWriteStatement reports whether query writes rows. The statement of a WITH clause is the first
+// keyword after its common table expressions.
+func isWriteStatement(query string) bool {
+	tokens := sqlTokens(query)
+	if len(tokens) == 0 {
+		return false
+	}
+	keyword := strings.ToLower(firstKeyword(query))
+	if keyword != "with" {
+		return writeKeywords[keyword]
+	}
+	depth := 0
+	for _, tok := range tokens {
+		switch {
+		case tok == "(":
+			depth++
+		case tok == ")":
+			depth--
+		case depth == 0 && (tok == "select" || writeKeywords[tok]):
+			return writeKeywords[tok]
+		}
+	}
+	return false
+}
+
+// selectStarTables returns the tables read by a statement that uses a "*" or "t.*" projection.
+// It returns nil when the statement has no star projection.
+func selectStarTables(tokens []string) []string {
+	star := false
+	for i, tok := range tokens {
+		if tok != "*" || i == 0 {
+			continue
+		}
+		switch tokens[i-1] {
+		case "select", "distinct", ",", ".":
+			star = true
+		}
+	}
+	if !star {
+		return nil
+	}
+
+	var tables []string
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i] != "from" && tokens[i] != "join" {
+			continue
+		}
+		// Take the last part of a possibly qualified name (db.table).
+		j := i + 1
+		for j+2 < len(tokens) && tokens[j+1] == "." {
+			j += 2
+		}
+		if isWordToken(tokens[j]) || strings.HasPrefix(tokens[j], "`") {
+			tables = append(tables, strings.Trim(tokens[j], "`"))
+		}
+	}
+	return tables
+}
@@ -0,0 +1,129 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestQueryPolicy(t *testing.T) {
+	db, hooks, pool := newRecordingDB(t, "prod_oltp")
+	policy := &QueryPolicy{DenyDDL: true, DenySelectStar: []string{"events"}}
+	hooks.set("policy", policy.hook())
+
+	err := db.Exec("DROP TABLE users").Error
+	var violation *PolicyViolationError
+	if !errors.As(err, &violation) || violation.Rule != PolicyRuleDenyDDL || violation.Connection != "prod_oltp" {
+		t.Fatalf("Expected a deny_ddl violation, got %v", err)
+	}
+	if !errors.Is(err, ErrPolicyViolation) {
+		t.Fatal("Expected the violation to match ErrPolicyViolation")
+	}
+
+	var rows []map[string]interface{}
+	err = db.Raw("SELECT e.* FROM shop.events e WHERE e.id > ?", 1).Scan(&rows).Error
+	if !errors.As(err, &violation) || violation.Rule != PolicyRuleDenySelectStar {
+		t.Fatalf("Expected a deny_select_star violation, got %v", err)
+	}
+	if err := db.Raw("SELECT COUNT(*) FROM events").Scan(&rows).Error; errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("COUNT(*) must not count as a star projection: %v", err)
+	}
+	if err := db.Exec("UPDATE users SET name = ? WHERE id = ?", "a", 1).Error; err != nil {
+		t.Fatalf("Unexpected error for an allowed statement: %v", err)
+	}
+	if got := pool.recorded(); len(got) != 2 {
+		t.Fatalf("Expected only allowed statements to reach the pool, got %q", got)
+	}
+}
+
+func TestQueryPolicyAllowlist(t *testing.T) {
+	db, hooks, _ := newRecordingDB(t, "plugin_db")
+	policy := (&QueryPolicy{}).Allow("UPDATE users SET name = 'x' WHERE id = 7")
+	hooks.set("policy", policy.hook())
+
+	if err := db.Exec("UPDATE users SET name = ? WHERE id = ?", "bob", 42).Error; err != nil {
+		t.Fatalf("Expected the registered fingerprint to be allowed, got %v", err)
+	}
+	err := db.Exec("DELETE FROM users WHERE id = ?", 42).Error
+	var violation *PolicyViolationError
+	if !errors.As(err, &violation) || violation.Rule != PolicyRuleFingerprint {
+		t.Fatalf("Expected a fingerprint violation, got %v", err)
+	}
+}
+
+// isolationConn is a driver connection accepting transactions and writes, recording the isolation level
+// of its transactions.
+type isolationConn struct {
+	levels *[]driver.IsolationLevel
+}
+
+func (c isolationConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c isolationConn) Close() error                        { return nil }
+func (c isolationConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c isolationConn) Commit() error                       { return nil }
+func (c isolationConn) Rollback() error                     { return nil }
+
+func (c isolationConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	*c.levels = append(*c.levels, opts.Isolation)
+	return c, nil
+}
+
+func (c isolationConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+type isolationConnector struct {
+	levels []driver.IsolationLevel
+}
+
+func (c *isolationConnector) Connect(context.Context) (driver.Conn, error) {
+	return isolationConn{levels: &c.levels}, nil
+}
+
+func (c *isolationConnector) Driver() driver.Driver { return nil }
+
+func TestQueryPolicyRequireTransactions(t *testing.T) {
+	connector := &isolationConnector{}
+	db := newConnectorDB(t, connector)
+	hooks, err := installStatementHooks("ledger", db, false)
+	if err != nil {
+		t.Fatalf("Failed to install statement hooks: %v", err)
+	}
+	policy := &QueryPolicy{RequireTransactions: true, TransactionIsolation: sql.LevelSerializable}
+	hooks.set("policy", policy.hook())
+	db.ConnPool.(*hookedConnPool).isolation = policy.TransactionIsolation
+
+	for _, query := range []string{
+		"UPDATE accounts SET balance = 0 WHERE id = 1",
+		"/* batch */ INSERT INTO accounts (id) VALUES (2)",
+		"WITH stale AS (SELECT id FROM accounts) DELETE FROM accounts WHERE id IN (SELECT id FROM stale)",
+	} {
+		var violation *PolicyViolationError
+		if err := db.Exec(query).Error; !errors.As(err, &violation) || violation.Rule != PolicyRuleAutocommit {
+			t.Errorf("Expected an autocommit_write violation for %q, got %v", query, err)
+		}
+	}
+	for _, query := range []string{"SET @a = 1", "WITH t AS (SELECT 1) SELECT * FROM accounts FOR UPDATE"} {
+		if isWriteStatement(query) {
+			t.Errorf("Expected %q not to count as a write", query)
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return tx.Exec("UPDATE accounts SET balance = 0 WHERE id = 1").Error
+	})
+	if err != nil {
+		t.Fatalf("Expected the write in a transaction to run, got %v", err)
+	}
+	err = db.Transaction(func(tx *gorm.DB) error { return nil }, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []driver.IsolationLevel{driver.IsolationLevel(sql.LevelSerializable), driver.IsolationLevel(sql.LevelReadCommitted)}
+	if len(connector.levels) != 2 || connector.levels[0] != expected[0] || connector.levels[1] != expected[1] {
+		t.Fatalf("Expected the policy isolation unless the transaction sets one, got %v", connector.levels)
+	}
+}
@@ -0,0 +1,91 @@
+package connection
+
+import (
+	"database/sql"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// poolControl holds the pool settings of an established connection and applies them to its shards:
+// reconfigure changes them on the live pool, the connection ramp raises the open limit step by step,
+// and the capacity of the priority gate follows the open limit.
+type poolControl struct {
+	mutex  sync.Mutex
+	shards []*sql.DB
+	config DBConfig
+
+	// rampOpen is the open connection limit per shard reached by the ramp, 0 when it is not ramping.
+	rampOpen int
+
+	// gate is the priority gate of DBConfig.PriorityAcquisition, or nil.
+	gate *priorityGate
+}
+
+func newPoolControl(config DBConfig) *poolControl {
+	return &poolControl{config: config}
+}
+
+// poolControlOf returns the pool control of an established connection, or nil.
+func poolControlOf(db *gorm.DB) *poolControl {
+	if hooked, ok := db.ConnPool.(*hookedConnPool); ok {
+		return hooked.control
+	}
+	return nil
+}
+
+// apply sets the pool settings of config, keeping the open limit of a running ramp when it is lower.
+func (c *poolControl) apply(config DBConfig) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.config = config
+	c.applyLocked()
+}
+
+// setGate puts the priority gate under the control, so its capacity follows the open limit.
+func (c *poolControl) setGate(gate *priorityGate) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.gate = gate
+	c.applyLocked()
+}
+
+// lifetime returns the current Lifetime and LifetimeJitter, zero for a nil control.
+func (c *poolControl) lifetime() (time.Duration, float64) {
+	if c == nil {
+		return 0, 0
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.config.Lifetime, c.config.LifetimeJitter
+}
+
+// applyLocked applies the settings to the shards and the gate. The caller holds the mutex.
+func (c *poolControl) applyLocked() {
+	open, idle := shardShare(c.config.MaxOpen, len(c.shards)), shardShare(c.config.MaxIdle, len(c.shards))
+	if c.rampOpen > 0 && open > 0 {
+		open = min(open, c.rampOpen)
+	}
+	if open > 0 {
+		idle = min(idle, open)
+	}
+	for _, shard := range c.shards {
+		shard.SetMaxOpenConns(open)
+		shard.SetMaxIdleConns(idle)
+		shard.SetConnMaxLifetime(poolLifetime(c.config))
+		shard.SetConnMaxIdleTime(c.config.IdleTime)
+	}
+	if c.gate != nil && c.config.MaxOpen > 0 {
+		c.gate.setCapacity(min(open*len(c.shards), c.config.MaxOpen))
+	}
+}
+
+// applyLivePoolSettings applies config to the pool of db through its pool control, or directly to
+// shards for pools without one.
+func applyLivePoolSettings(db *gorm.DB, shards []*sql.DB, config DBConfig) {
+	if control := poolControlOf(db); control != nil {
+		control.apply(config)
+		return
+	}
+	applyPoolSettings(shards, config)
+}
@@ -0,0 +1,67 @@
+package connection
+
+import (
+	"errors"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// erNeedReprepare is MySQL error 1615 (ER_NEED_REPREPARE): a cached prepared statement refers to a
+// table whose definition changed since it was prepared, typically after DDL.
+const erNeedReprepare = 1615
+
+// isNeedReprepare reports whether err is MySQL error 1615.
+func isNeedReprepare(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == erNeedReprepare
+}
+
+// flushPreparedStatements forgets the statements cached by pool (when it is GORM's prepared statement
+// cache, see DBConfig.PrepareStmt) that refer to a table of query, including query itself, and reports
+// whether pool caches statements. The next execution of a flushed statement prepares it again.
+func flushPreparedStatements(pool gorm.ConnPool, query string) bool {
+	prepared, ok := pool.(*gorm.PreparedStmtDB)
+	if !ok {
+		return false
+	}
+	tables := statementTables(query)
+
+	prepared.Mux.Lock()
+	defer prepared.Mux.Unlock()
+	for cached, stmt := range prepared.Stmts {
+		if cached != query && !sharesTable(statementTables(cached), tables) {
+			continue
+		}
+		delete(prepared.Stmts, cached)
+		if stmt.Stmt != nil {
+			go stmt.Close()
+		}
+	}
+	return true
+}
+
+// statementTables returns the tables a statement reads or writes: those following FROM, JOIN,
+// UPDATE and INTO.
+func statementTables(query string) []string {
+	tokens := sqlTokens(query)
+	tables := parseTableRefs(tokens).tables
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i] == "into" && isIdentifierToken(tokens[i+1]) {
+			j := i + 1
+			for j+2 < len(tokens) && tokens[j+1] == "." {
+				j += 2
+			}
+			tables = append(tables, unquoteToken(tokens[j]))
+		}
+	}
+	return tables
+}
+
+func sharesTable(a, b []string) bool {
+	for _, table := range a {
+		if containsString(b, table) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// reprepareConnector simulates statements invalidated by DDL: statements prepared before the
+// latest schema change fail with error 1615.
+type reprepareConnector struct {
+	schemaVersion atomic.Int64
+	prepares      atomic.Int64
+}
+
+func (c *reprepareConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &reprepareConn{connector: c}, nil
+}
+
+func (c *reprepareConnector) Driver() driver.Driver {
+	return nil
+}
+
+type reprepareConn struct {
+	connector *reprepareConnector
+}
+
+func (c *reprepareConn) Prepare(query string) (driver.Stmt, error) {
+	c.connector.prepares.Add(1)
+	return &reprepareStmt{connector: c.connector, version: c.connector.schemaVersion.Load()}, nil
+}
+
+func (c *reprepareConn) Close() error {
+	return nil
+}
+
+func (c *reprepareConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+type reprepareStmt struct {
+	connector *reprepareConnector
+	version   int64
+}
+
+func (s *reprepareStmt) Close() error {
+	return nil
+}
+
+func (s *reprepareStmt) NumInput() int {
+	return -1
+}
+
+func (s *reprepareStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if s.version != s.connector.schemaVersion.Load() {
+		return nil, &mysqldriver.MySQLError{Number: erNeedReprepare, Message: "Prepared statement needs to be re-prepared"}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *reprepareStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, driver.ErrSkip
+}
+
+func TestPreparedStatementRetriedAfterDDL(t *testing.T) {
+	connector := &reprepareConnector{}
+	sqlDB := sql.OpenDB(connector)
+	defer sqlDB.Close()
+
+	prepared := gorm.NewPreparedStmtDB(sqlDB)
+	pool := &hookedConnPool{pool: prepared, sqlDB: sqlDB, name: "primary_db", hooks: &hookChain{}}
+	ctx := context.Background()
+
+	if _, err := pool.ExecContext(ctx, "UPDATE users SET a = ?", 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	prepared.Stmts["SELECT * FROM orders"] = &gorm.Stmt{}
+	prepared.Stmts["SELECT * FROM users JOIN orders ON orders.user_id = users.id"] = &gorm.Stmt{}
+
+	connector.schemaVersion.Add(1) // ALTER TABLE users ...
+	if _, err := pool.ExecContext(ctx, "UPDATE users SET a = ?", 2); err != nil {
+		t.Fatalf("Expected the statement to be retried, got %v", err)
+	}
+	if got := connector.prepares.Load(); got != 2 {
+		t.Fatalf("Expected the statement to be prepared again, got %d prepares", got)
+	}
+
+	var cached []string
+	for query := range prepared.Stmts {
+		cached = append(cached, query)
+	}
+	want := []string{"SELECT * FROM orders", "UPDATE users SET a = ?"}
+	if len(cached) != 2 || !containsString(cached, want[0]) || !containsString(cached, want[1]) {
+		t.Fatalf("Expected statements on users to be flushed, got %q", cached)
+	}
+}
+
+func TestStatementTables(t *testing.T) {
+	got := statementTables("INSERT INTO `shop`.`orders` (id) SELECT id FROM carts c JOIN users u ON u.id = c.user_id")
+	if want := []string{"carts", "users", "orders"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("statementTables = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,296 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// priorityRanks orders the query classes for PriorityAcquisition, highest priority first.
+var priorityRanks = []QueryClass{QueryCritical, QueryInteractive, QueryBackground, QueryBestEffort}
+
+// rank returns the rank of class in priorityRanks.
+func (c QueryClass) rank() int {
+	for i, class := range priorityRanks {
+		if class == c {
+			return i
+		}
+	}
+	return 1
+}
+
+// AcquisitionStats describes the priority queue of a connection with DBConfig.PriorityAcquisition.
+type AcquisitionStats struct {
+	// Capacity is the number of statements and transactions admitted at once, the open connection
+	// limit of the pool (MaxOpen, or less during the connection ramp), and InUse the number admitted now.
+	Capacity int
+	InUse    int
+
+	// Waiting is the number of callers queued by class, Waits the number that had to queue since the
+	// connection was established and WaitDuration their total time in the queue.
+	Waiting      map[QueryClass]int
+	Waits        map[QueryClass]int64
+	WaitDuration map[QueryClass]time.Duration
+}
+
+// AcquisitionStats returns the state of the priority queue of a connection initialized with
+// DBConfig.PriorityAcquisition. ok is false when the connection does not exist or queues its callers
+// in the FIFO order of database/sql.
+//
+// Example Usage:
+//
+//	if stats, ok := factory.AcquisitionStats("primary_db"); ok {
+//		log.Printf("%d interactive and %d background callers waiting for a connection",
+//			stats.Waiting[connection.QueryInteractive], stats.Waiting[connection.QueryBackground])
+//	}
+func (f *ConnectionManager) AcquisitionStats(name string) (stats AcquisitionStats, ok bool) {
+	f.mutex.Lock()
+	db := f.connections[name]
+	f.mutex.Unlock()
+	if db == nil {
+		return AcquisitionStats{}, false
+	}
+	hooked, isHooked := db.ConnPool.(*hookedConnPool)
+	if !isHooked {
+		return AcquisitionStats{}, false
+	}
+	gated, isGated := hooked.pool.(*priorityPool)
+	if !isGated {
+		return AcquisitionStats{}, false
+	}
+	return gated.gate.stats(), true
+}
+
+// priorityGate admits at most capacity holders at once and hands the slots freed to the waiters of
+// the highest priority class first, in arrival order within a class.
+type priorityGate struct {
+	mutex    sync.Mutex
+	capacity int
+	inUse    int
+	queues   [4][]*priorityWaiter
+	waits    [4]int64
+	waited   [4]time.Duration
+}
+
+type priorityWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func newPriorityGate(capacity int) *priorityGate {
+	return &priorityGate{capacity: capacity}
+}
+
+// acquire waits for a slot for a caller of class, or until ctx ends.
+func (g *priorityGate) acquire(ctx context.Context, class QueryClass) error {
+	rank := class.rank()
+	g.mutex.Lock()
+	if g.inUse < g.capacity && g.waiting() == 0 {
+		g.inUse++
+		g.mutex.Unlock()
+		return nil
+	}
+	waiter := &priorityWaiter{ready: make(chan struct{})}
+	g.queues[rank] = append(g.queues[rank], waiter)
+	g.waits[rank]++
+	g.mutex.Unlock()
+
+	started := time.Now()
+	select {
+	case <-waiter.ready:
+		g.mutex.Lock()
+		g.waited[rank] += time.Since(started)
+		g.mutex.Unlock()
+		return nil
+	case <-ctx.Done():
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.waited[rank] += time.Since(started)
+	if waiter.granted {
+		// The slot was handed over as the context ended: pass it on.
+		g.releaseLocked()
+		return ctx.Err()
+	}
+	for i, queued := range g.queues[rank] {
+		if queued == waiter {
+			g.queues[rank] = append(g.queues[rank][:i], g.queues[rank][i+1:]...)
+			break
+		}
+	}
+	return ctx.Err()
+}
+
+// release frees a slot, handing it to the first waiter of the highest priority class.
+func (g *priorityGate) release() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.releaseLocked()
+}
+
+func (g *priorityGate) releaseLocked() {
+	// Over a lowered capacity, the slot is dropped instead.
+	if g.inUse <= g.capacity && g.grantLocked() {
+		return
+	}
+	g.inUse--
+}
+
+// grantLocked hands a slot to the first waiter of the highest priority class, if any. The caller
+// holds the mutex.
+func (g *priorityGate) grantLocked() bool {
+	for rank, queue := range g.queues {
+		if len(queue) > 0 {
+			waiter := queue[0]
+			g.queues[rank] = queue[1:]
+			waiter.granted = true
+			close(waiter.ready)
+			return true
+		}
+	}
+	return false
+}
+
+// setCapacity changes the number of slots, admitting waiters at once when it grows. When it shrinks,
+// the slots in use beyond it are dropped as they are released.
+func (g *priorityGate) setCapacity(capacity int) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.capacity = capacity
+	for g.inUse < g.capacity && g.grantLocked() {
+		g.inUse++
+	}
+}
+
+// waiting returns the number of queued callers. The caller holds the mutex.
+func (g *priorityGate) waiting() int {
+	n := 0
+	for _, queue := range g.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+func (g *priorityGate) stats() AcquisitionStats {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	stats := AcquisitionStats{Capacity: g.capacity, InUse: g.inUse, Waiting: make(map[QueryClass]int),
+		Waits: make(map[QueryClass]int64), WaitDuration: make(map[QueryClass]time.Duration)}
+	for rank, class := range priorityRanks {
+		stats.Waiting[class] = len(g.queues[rank])
+		stats.Waits[class] = g.waits[rank]
+		stats.WaitDuration[class] = g.waited[rank]
+	}
+	return stats
+}
+
+// priorityPool runs each statement on a connection taken from db once the gate admitted it, so the
+// gate, not database/sql, queues the callers when the pool is saturated. The connection and the slot
+// are given back when the statement is done, after its rows are closed for queries.
+type priorityPool struct {
+	db   *sql.DB
+	gate *priorityGate
+}
+
+// conn admits the caller and takes a connection from the pool.
+func (p *priorityPool) conn(ctx context.Context) (*sql.Conn, error) {
+	if err := p.gate.acquire(ctx, QueryClassOf(ctx)); err != nil {
+		return nil, err
+	}
+	conn, err := p.db.Conn(ctx)
+	if err != nil {
+		p.gate.release()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// closeWhenDone gives the connection and its slot back once the rows of the query are closed:
+// (*sql.Conn).Close waits for them.
+func (p *priorityPool) closeWhenDone(conn *sql.Conn) {
+	go func() {
+		_ = conn.Close()
+		p.gate.release()
+	}()
+}
+
+func (p *priorityPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.db.PrepareContext(ctx, query)
+}
+
+func (p *priorityPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer p.gate.release()
+	defer conn.Close()
+	return conn.ExecContext(ctx, query, args...)
+}
+
+func (p *priorityPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, query, args...)
+	p.closeWhenDone(conn)
+	return rows, err
+}
+
+func (p *priorityPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	conn, err := p.conn(ctx)
+	if err != nil {
+		return rejectedRow(ctx, p.db)
+	}
+	row := conn.QueryRowContext(ctx, query, args...)
+	p.closeWhenDone(conn)
+	return row
+}
+
+// GetDBConn implements gorm.GetDBConnector.
+func (p *priorityPool) GetDBConn() (*sql.DB, error) {
+	return p.db, nil
+}
+
+// priorityWarnings reports a PriorityAcquisition that cannot be honored.
+func priorityWarnings(c DBConfig) []ConfigWarning {
+	if !c.PriorityAcquisition {
+		return nil
+	}
+	switch {
+	case c.MaxOpen <= 0:
+		return []ConfigWarning{{Field: ConfigFieldPriorityAcquisition, Message: "PriorityAcquisition needs a bounded pool (MaxOpen); callers are queued in FIFO order"}}
+	case c.PoolShards > 1 || c.PrepareStmt:
+		return []ConfigWarning{{Field: ConfigFieldPriorityAcquisition, Message: "PriorityAcquisition is not supported with PoolShards or PrepareStmt; callers are queued in FIFO order"}}
+	}
+	return nil
+}
+
+// gated reports whether the pool of db has a priority gate.
+func gated(db *gorm.DB) bool {
+	if hooked, ok := db.ConnPool.(*hookedConnPool); ok {
+		_, ok := hooked.pool.(*priorityPool)
+		return ok
+	}
+	return false
+}
+
+// installPriorityAcquisition puts a priority gate of MaxOpen slots in front of the pool of db, when
+// the configuration allows it. With a pool control, the capacity follows the open limit of the pool,
+// e.g. during the connection ramp.
+func installPriorityAcquisition(db *gorm.DB, pool *sql.DB, config DBConfig) {
+	if !config.PriorityAcquisition || len(priorityWarnings(config)) > 0 {
+		return
+	}
+	if hooked, ok := db.ConnPool.(*hookedConnPool); ok {
+		gate := newPriorityGate(config.MaxOpen)
+		hooked.pool = &priorityPool{db: pool, gate: gate}
+		if hooked.control != nil {
+			hooked.control.setGate(gate)
+		}
+	}
+}
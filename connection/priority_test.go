@@ -0,0 +1,178 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// waitForWaiting polls the gate until n callers are queued.
+func waitForWaiting(t *testing.T, gate *priorityGate, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		gate.mutex.Lock()
+		waiting := gate.waiting()
+		gate.mutex.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Expected %d queued callers", n)
+}
+
+func TestPriorityGateOrder(t *testing.T) {
+	gate := newPriorityGate(1)
+	if err := gate.acquire(context.Background(), QueryBackground); err != nil {
+		t.Fatalf("Unexpected error acquiring a free slot: %v", err)
+	}
+
+	order := make(chan QueryClass, 4)
+	for i, class := range []QueryClass{QueryBestEffort, QueryBackground, QueryInteractive, QueryCritical} {
+		go func() {
+			if err := gate.acquire(context.Background(), class); err == nil {
+				order <- class
+				gate.release()
+			}
+		}()
+		waitForWaiting(t, gate, i+1)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := gate.acquire(ctx, QueryCritical); err != context.Canceled {
+		t.Fatalf("Expected the context error for an abandoned wait, got %v", err)
+	}
+	waitForWaiting(t, gate, 4)
+
+	gate.release()
+	for _, want := range []QueryClass{QueryCritical, QueryInteractive, QueryBackground, QueryBestEffort} {
+		if got := <-order; got != want {
+			t.Fatalf("Expected %s to be admitted next, got %s", want, got)
+		}
+	}
+	if stats := gate.stats(); stats.InUse != 0 || stats.Waits[QueryCritical] != 2 {
+		t.Fatalf("Unexpected stats after the queue drained: %+v", stats)
+	}
+}
+
+func TestPriorityGateCapacity(t *testing.T) {
+	gate := newPriorityGate(2)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := gate.acquire(ctx, QueryInteractive); err != nil {
+			t.Fatalf("Unexpected error acquiring a free slot: %v", err)
+		}
+	}
+	admitted := make(chan error, 1)
+	go func() { admitted <- gate.acquire(ctx, QueryInteractive) }()
+	waitForWaiting(t, gate, 1)
+
+	// Lowered to one slot: the first release drops its slot instead of handing it over.
+	gate.setCapacity(1)
+	gate.release()
+	if stats := gate.stats(); stats.InUse != 1 || stats.Waiting[QueryInteractive] != 1 {
+		t.Fatalf("Expected the waiter to stay queued over the lowered capacity, got %+v", stats)
+	}
+	gate.release()
+	if err := <-admitted; err != nil {
+		t.Fatalf("Expected the waiter to be admitted, got %v", err)
+	}
+
+	// Raised again: waiters are admitted at once.
+	go func() { admitted <- gate.acquire(ctx, QueryInteractive) }()
+	waitForWaiting(t, gate, 1)
+	gate.setCapacity(2)
+	if err := <-admitted; err != nil {
+		t.Fatalf("Expected the waiter to be admitted by the raised capacity, got %v", err)
+	}
+	if stats := gate.stats(); stats.InUse != 2 || stats.Capacity != 2 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestPriorityAcquisition(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	db := factory.connections["primary_db"]
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	installPriorityAcquisition(db, sqlDB, DBConfig{PriorityAcquisition: true, MaxOpen: 1})
+	stats, ok := factory.AcquisitionStats("primary_db")
+	if !ok || stats.Capacity != 1 {
+		t.Fatalf("Expected a priority queue of 1 slot, got %+v (%v)", stats, ok)
+	}
+	gate := db.ConnPool.(*hookedConnPool).pool.(*priorityPool).gate
+
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("Begin failed: %v", tx.Error)
+	}
+	done := make(chan QueryClass, 2)
+	for i, class := range []QueryClass{QueryBackground, QueryInteractive} {
+		go func() {
+			var users []fakeTestUser
+			if err := db.WithContext(WithQueryClass(context.Background(), class)).Find(&users).Error; err != nil {
+				t.Errorf("Find failed: %v", err)
+			}
+			done <- class
+		}()
+		waitForWaiting(t, gate, i+1)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if first := <-done; first != QueryInteractive {
+		t.Fatalf("Expected the interactive query to run first, got %s", first)
+	}
+	<-done
+	if stats, _ := factory.AcquisitionStats("primary_db"); stats.Waits[QueryBackground] != 1 || stats.Waits[QueryInteractive] != 1 {
+		t.Fatalf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestPriorityWarnings(t *testing.T) {
+	if w := priorityWarnings(DBConfig{PriorityAcquisition: true}); len(w) != 1 || w[0].Field != ConfigFieldPriorityAcquisition {
+		t.Fatalf("Expected a warning for an unbounded pool, got %v", w)
+	}
+	if w := priorityWarnings(DBConfig{PriorityAcquisition: true, MaxOpen: 10}); len(w) != 0 {
+		t.Fatalf("Expected no warning, got %v", w)
+	}
+}
+
+func TestPriorityAcquisitionReconfigure(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	db := factory.connections["primary_db"]
+	sqlDB, _ := db.DB()
+	config := factory.configs["primary_db"]
+	config.MaxOpen, config.PriorityAcquisition, config.ReconfigureOnMismatch = 2, true, true
+	control := newPoolControl(config)
+	control.shards = []*sql.DB{sqlDB}
+	control.apply(config)
+	db.ConnPool.(*hookedConnPool).control = control
+	installPriorityAcquisition(db, sqlDB, config)
+	factory.configs["primary_db"] = config
+
+	raised := config
+	raised.MaxOpen = 5
+	factory.mutex.Lock()
+	replace, err := factory.reconfigure("primary_db", raised)
+	factory.mutex.Unlock()
+	if replace || err != nil {
+		t.Fatalf("Expected MaxOpen to be applied live, got %v, %v", replace, err)
+	}
+	if stats, _ := factory.AcquisitionStats("primary_db"); stats.Capacity != 5 {
+		t.Fatalf("Expected the gate to follow the reconfigured MaxOpen, got %+v", stats)
+	}
+
+	unbounded := raised
+	unbounded.MaxOpen = 0
+	factory.mutex.Lock()
+	replace, err = factory.reconfigure("primary_db", unbounded)
+	factory.mutex.Unlock()
+	if !replace || err != nil {
+		t.Fatalf("Expected an unbounded pool to be established again, got %v, %v", replace, err)
+	}
+}
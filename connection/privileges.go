@@ -0,0 +1,207 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// DefaultPrivilegePolicy is the policy of AuditPrivileges for connections without DBConfig.PrivilegePolicy:
+// an application user must not hold administrative privileges or drop or grant anything.
+var DefaultPrivilegePolicy = PrivilegePolicy{
+	Forbidden: []string{"ALL PRIVILEGES", "SUPER", "DROP", "GRANT OPTION", "FILE", "PROCESS", "SHUTDOWN",
+		"RELOAD", "CREATE USER", "SYSTEM_USER", "CONNECTION_ADMIN", "SYSTEM_VARIABLES_ADMIN"},
+}
+
+// PrivilegePolicy declares the privileges the user of a connection may hold.
+type PrivilegePolicy struct {
+	// Allowed lists the privileges the user may hold, e.g. SELECT, INSERT, UPDATE, DELETE. Any other
+	// privilege, except USAGE, is excess. Empty allows every privilege not in Forbidden.
+	Allowed []string
+
+	// Forbidden lists privileges the user must not hold, e.g. DROP, SUPER. ALL PRIVILEGES holds them all.
+	Forbidden []string
+}
+
+// PrivilegeGrant is one privilege granted on one object, e.g. DROP on `app`.*.
+type PrivilegeGrant struct {
+	Privilege string
+	On        string
+}
+
+func (g PrivilegeGrant) String() string {
+	return g.Privilege + " ON " + g.On
+}
+
+// PrivilegeAudit is the result of AuditPrivileges.
+type PrivilegeAudit struct {
+	// Connection is the name of the audited connection and User the account it is authenticated as.
+	Connection string
+	User       string
+
+	// Grants are the statements returned by SHOW GRANTS.
+	Grants []string
+
+	// Excess are the granted privileges the policy does not allow. Empty when Compliant.
+	Excess []PrivilegeGrant
+
+	// Roles are the roles granted to the user. Their privileges are read with SHOW GRANTS ... USING and
+	// audited with those of the user.
+	Roles []string
+
+	// Incomplete is true when the privileges of the roles could not be read, e.g. on servers without
+	// SHOW GRANTS ... USING. An incomplete audit is never Compliant.
+	Incomplete bool
+
+	// Compliant is true when the user holds no privilege beyond the policy.
+	Compliant bool
+}
+
+// AuditPrivileges reads the privileges of the user of a managed connection and reports those beyond
+// its declared policy, for security reviews of the users behind every connection.
+//
+// Parameters:
+// - ctx: Context of the SHOW GRANTS statement.
+// - name: The name of the managed connection.
+//
+// Returns:
+// - *PrivilegeAudit: The grants of the user and the privileges exceeding the policy.
+// - error: An error if the connection does not exist or the grants cannot be read.
+//
+// Behavior:
+// 1. The policy is DBConfig.PrivilegePolicy, or DefaultPrivilegePolicy.
+// 2. A privilege is excess when it is in Forbidden, or Allowed is set and does not list it. ALL
+// PRIVILEGES exceeds any policy forbidding or not allowing a privilege.
+// 3. WITH GRANT OPTION is audited as the GRANT OPTION privilege.
+// 4. When roles are granted to the user, the grants are read again with SHOW GRANTS ... USING the roles,
+// so that the privileges of the roles are audited too. If they cannot be read the audit is Incomplete
+// and not Compliant.
+//
+// Example Usage:
+//
+//	audit, err := connection.GetConnectionManager().AuditPrivileges(ctx, "primary_db")
+//	if err == nil && !audit.Compliant {
+//		log.Printf("%s is over-privileged: %v", audit.User, audit.Excess)
+//	}
+func (f *ConnectionManager) AuditPrivileges(ctx context.Context, name string) (*PrivilegeAudit, error) {
+	db, err := f.GetDBContext(ctx, name)
+	if err != nil {
+		return nil, connError(name, OpAuditPrivileges, err)
+	}
+	policy := DefaultPrivilegePolicy
+	if config := f.GetDbConfig(name); config.PrivilegePolicy != nil {
+		policy = *config.PrivilegePolicy
+	}
+
+	audit := &PrivilegeAudit{Connection: name}
+	if err := db.Raw("SELECT CURRENT_USER()").Scan(&audit.User).Error; err != nil {
+		return nil, connError(name, OpAuditPrivileges, fmt.Errorf("failed to read the user: %w", err))
+	}
+	if err := db.Raw("SHOW GRANTS FOR CURRENT_USER()").Scan(&audit.Grants).Error; err != nil {
+		return nil, connError(name, OpAuditPrivileges, fmt.Errorf("failed to read the grants: %w", err))
+	}
+	for _, grant := range audit.Grants {
+		_, roles := parseGrant(grant)
+		audit.Roles = append(audit.Roles, roles...)
+	}
+	if len(audit.Roles) > 0 {
+		var withRoles []string
+		if err := db.Raw("SHOW GRANTS FOR CURRENT_USER() USING " + strings.Join(audit.Roles, ", ")).Scan(&withRoles).Error; err != nil {
+			log.Printf("Failed to read the privileges of the roles of %q: %v", name, err)
+			audit.Incomplete = true
+		} else {
+			audit.Grants = withRoles
+		}
+	}
+	for _, grant := range audit.Grants {
+		granted, _ := parseGrant(grant)
+		for _, g := range granted {
+			if policy.exceeds(g.Privilege) {
+				audit.Excess = append(audit.Excess, g)
+			}
+		}
+	}
+	audit.Compliant = len(audit.Excess) == 0 && !audit.Incomplete
+	return audit, nil
+}
+
+// exceeds reports whether holding privilege goes beyond the policy.
+func (p PrivilegePolicy) exceeds(privilege string) bool {
+	if privilege == "USAGE" {
+		return false
+	}
+	all := privilege == "ALL PRIVILEGES" || privilege == "ALL"
+	if all && (len(p.Allowed) > 0 || len(p.Forbidden) > 0) {
+		return true
+	}
+	for _, forbidden := range p.Forbidden {
+		if strings.EqualFold(forbidden, privilege) {
+			return true
+		}
+	}
+	if len(p.Allowed) == 0 {
+		return false
+	}
+	for _, allowed := range p.Allowed {
+		if strings.EqualFold(allowed, privilege) {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	grantPrivilegesPattern = regexp.MustCompile(`(?i)^GRANT\s+(.+?)\s+ON\s+(.+?)\s+TO\s+`)
+	grantRolesPattern      = regexp.MustCompile(`(?i)^GRANT\s+(.+?)\s+TO\s+`)
+)
+
+// parseGrant returns the privileges granted by a SHOW GRANTS statement, or the roles it grants.
+func parseGrant(grant string) ([]PrivilegeGrant, []string) {
+	if match := grantPrivilegesPattern.FindStringSubmatch(grant); match != nil {
+		on := strings.TrimSpace(match[2])
+		var granted []PrivilegeGrant
+		for _, privilege := range splitTopLevel(match[1]) {
+			// Column privileges: SELECT (`a`, `b`)
+			if i := strings.Index(privilege, "("); i >= 0 {
+				privilege = privilege[:i]
+			}
+			granted = append(granted, PrivilegeGrant{Privilege: strings.ToUpper(strings.TrimSpace(privilege)), On: on})
+		}
+		if strings.Contains(strings.ToUpper(grant), "WITH GRANT OPTION") {
+			granted = append(granted, PrivilegeGrant{Privilege: "GRANT OPTION", On: on})
+		}
+		return granted, nil
+	}
+	if match := grantRolesPattern.FindStringSubmatch(grant); match != nil {
+		var roles []string
+		for _, role := range splitTopLevel(match[1]) {
+			roles = append(roles, strings.TrimSpace(role))
+		}
+		return nil, roles
+	}
+	return nil, nil
+}
+
+// splitTopLevel splits s on the commas outside quotes and parentheses.
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"', '`':
+			i = skipQuoted(s, i, s[i])
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
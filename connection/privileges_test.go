@@ -0,0 +1,186 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// scriptedConnector opens connections answering each query with the rows returned by rows, or with
+// the error returned by fail when set.
+type scriptedConnector struct {
+	rows func(query string) (columns []string, values [][]driver.Value)
+	fail func(query string) error
+}
+
+func (c *scriptedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &scriptedConn{rows: c.rows, fail: c.fail}, nil
+}
+
+func (c *scriptedConnector) Driver() driver.Driver {
+	return nil
+}
+
+type scriptedConn struct {
+	rows func(query string) ([]string, [][]driver.Value)
+	fail func(query string) error
+}
+
+func (c *scriptedConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *scriptedConn) Close() error {
+	return nil
+}
+
+func (c *scriptedConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c *scriptedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.fail != nil {
+		if err := c.fail(query); err != nil {
+			return nil, err
+		}
+	}
+	columns, values := c.rows(query)
+	return &scriptedRows{columns: columns, values: values}, nil
+}
+
+type scriptedRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *scriptedRows) Columns() []string {
+	return r.columns
+}
+
+func (r *scriptedRows) Close() error {
+	return nil
+}
+
+func (r *scriptedRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+func TestAuditPrivileges(t *testing.T) {
+	grants := []string{
+		"GRANT USAGE ON *.* TO `app`@`%`",
+		"GRANT SELECT, INSERT, UPDATE (`status`, `note`), DROP ON `app`.* TO `app`@`%` WITH GRANT OPTION",
+		"GRANT `reporting`@`%` TO `app`@`%`",
+	}
+	connector := &scriptedConnector{rows: func(query string) ([]string, [][]driver.Value) {
+		if strings.HasPrefix(query, "SHOW GRANTS") {
+			values := make([][]driver.Value, len(grants))
+			for i, grant := range grants {
+				values[i] = []driver.Value{grant}
+			}
+			return []string{"Grants for app@%"}, values
+		}
+		return []string{"CURRENT_USER()"}, [][]driver.Value{{"app@%"}}
+	}}
+	factory := newTestFactory()
+	factory.connections["app_db"] = newConnectorDB(t, connector)
+	factory.configs["app_db"] = DBConfig{DataSourceName: "app@tcp(db:3306)/app"}
+
+	audit, err := factory.AuditPrivileges(context.Background(), "app_db")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if audit.User != "app@%" || len(audit.Grants) != 3 || audit.Compliant {
+		t.Fatalf("Unexpected audit %+v", audit)
+	}
+	if len(audit.Excess) != 2 || audit.Excess[0].String() != "DROP ON `app`.*" || audit.Excess[1].Privilege != "GRANT OPTION" {
+		t.Fatalf("Expected DROP and GRANT OPTION in excess, got %v", audit.Excess)
+	}
+	if len(audit.Roles) != 1 || audit.Roles[0] != "`reporting`@`%`" {
+		t.Fatalf("Expected the reporting role, got %v", audit.Roles)
+	}
+
+	factory.configs["app_db"] = DBConfig{DataSourceName: "app@tcp(db:3306)/app",
+		PrivilegePolicy: &PrivilegePolicy{Allowed: []string{"SELECT", "INSERT", "UPDATE", "DROP", "GRANT OPTION"}}}
+	if audit, err := factory.AuditPrivileges(context.Background(), "app_db"); err != nil || !audit.Compliant {
+		t.Fatalf("Expected a compliant audit, got %+v, %v", audit, err)
+	}
+	factory.configs["app_db"] = DBConfig{DataSourceName: "app@tcp(db:3306)/app",
+		PrivilegePolicy: &PrivilegePolicy{Allowed: []string{"SELECT"}}}
+	if audit, err := factory.AuditPrivileges(context.Background(), "app_db"); err != nil || len(audit.Excess) != 4 {
+		t.Fatalf("Expected INSERT, UPDATE, DROP and GRANT OPTION in excess, got %+v, %v", audit, err)
+	}
+
+	if _, err := factory.AuditPrivileges(context.Background(), "missing_db"); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
+
+func TestAuditPrivilegesOfRoles(t *testing.T) {
+	grants := []string{"GRANT SELECT ON `app`.* TO `app`@`%`", "GRANT `admin`@`%` TO `app`@`%`"}
+	withRoles := append(grants, "GRANT ALL PRIVILEGES ON *.* TO `app`@`%`")
+	connector := &scriptedConnector{rows: func(query string) ([]string, [][]driver.Value) {
+		shown := grants
+		if strings.Contains(query, "USING `admin`@`%`") {
+			shown = withRoles
+		}
+		if strings.HasPrefix(query, "SHOW GRANTS") {
+			values := make([][]driver.Value, len(shown))
+			for i, grant := range shown {
+				values[i] = []driver.Value{grant}
+			}
+			return []string{"Grants for app@%"}, values
+		}
+		return []string{"CURRENT_USER()"}, [][]driver.Value{{"app@%"}}
+	}}
+	factory := newTestFactory()
+	factory.connections["app_db"] = newConnectorDB(t, connector)
+	factory.configs["app_db"] = DBConfig{DataSourceName: "app@tcp(db:3306)/app"}
+
+	audit, err := factory.AuditPrivileges(context.Background(), "app_db")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if audit.Compliant || audit.Incomplete || len(audit.Excess) != 1 || audit.Excess[0].Privilege != "ALL PRIVILEGES" {
+		t.Fatalf("Expected the privileges of the role in excess, got %+v", audit)
+	}
+
+	connector.fail = func(query string) error {
+		if strings.Contains(query, "USING") {
+			return errors.New("syntax error")
+		}
+		return nil
+	}
+	factory.connections["app_db"] = newConnectorDB(t, connector)
+	audit, err = factory.AuditPrivileges(context.Background(), "app_db")
+	if err != nil || audit.Compliant || !audit.Incomplete || len(audit.Excess) != 0 {
+		t.Fatalf("Expected an incomplete, non-compliant audit, got %+v, %v", audit, err)
+	}
+}
+
+func TestPrivilegePolicyAllPrivileges(t *testing.T) {
+	if !DefaultPrivilegePolicy.exceeds("ALL PRIVILEGES") {
+		t.Fatal("Expected ALL PRIVILEGES to exceed the default policy")
+	}
+	if (PrivilegePolicy{}).exceeds("ALL PRIVILEGES") {
+		t.Fatal("Expected an empty policy to allow everything")
+	}
+}
+
+func TestParseGrantQuotedNames(t *testing.T) {
+	granted, _ := parseGrant("GRANT SELECT (`a,b`, `c`), INSERT ON `app`.`t(1)` TO `app`@`%`")
+	if len(granted) != 2 || granted[0].Privilege != "SELECT" || granted[1].Privilege != "INSERT" {
+		t.Fatalf("Expected SELECT and INSERT, got %v", granted)
+	}
+	_, roles := parseGrant("GRANT `ops,admin`@`%`, 'read(only)'@'%' TO `app`@`%`")
+	if len(roles) != 2 || roles[0] != "`ops,admin`@`%`" || roles[1] != "'read(only)'@'%'" {
+		t.Fatalf("Expected two quoted roles, got %v", roles)
+	}
+}
@@ -0,0 +1,193 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// procVariablePrefix prefixes the session variables that carry OUT and INOUT parameters.
+const procVariablePrefix = "@mysqlconn_proc_"
+
+// ProcParam is an OUT or INOUT parameter placed among the IN arguments of CallProc, for procedures
+// whose OUT parameters are not all declared last.
+type ProcParam struct {
+	value interface{}
+	dest  interface{}
+	inout bool
+}
+
+// ProcOut returns an OUT parameter scanned into dest, a pointer as accepted by sql.Rows.Scan.
+func ProcOut(dest interface{}) ProcParam {
+	return ProcParam{dest: dest}
+}
+
+// ProcInOut returns an INOUT parameter passed value and scanned into dest afterwards.
+func ProcInOut(value, dest interface{}) ProcParam {
+	return ProcParam{value: value, dest: dest, inout: true}
+}
+
+// CallProc calls a stored procedure on a managed connection and reads its OUT parameters.
+//
+// Parameters:
+// - ctx: Context bounding the call.
+// - name: The name of the managed connection.
+// - proc: The procedure name, optionally qualified with the database ("db.proc").
+// - in: The arguments before the trailing OUT parameters, in declaration order. ProcOut and ProcInOut
+// values place OUT and INOUT parameters among them.
+// - out: Destinations of the trailing OUT parameters, pointers as accepted by sql.Rows.Scan.
+// - results: Reads the result sets returned by the procedure, positioned on the first one, before the OUT
+// parameters are read. The result sets it leaves unread are discarded. Nil discards them all.
+//
+// Returns:
+// - error: An error if the connection does not exist or the call fails.
+//
+// Behavior:
+// 1. OUT and INOUT parameters are bound to session variables on one pooled connection: INOUT values are
+// set before the call and all variables are read back after it, then cleared.
+// 2. The result sets returned by the procedure are passed to results, then the remaining ones are
+// discarded: the OUT parameters are only readable once the call completed. An error of results fails
+// the call.
+// 3. The statements pass through the hooks of the connection (query policy, chaos faults, ...).
+//
+// Example Usage:
+//
+//	// CREATE PROCEDURE order_totals(IN customer INT, OUT orders INT, OUT total DECIMAL(10,2))
+//	var orders int
+//	var total float64
+//	err := connection.GetConnectionManager().CallProc(ctx, "primary_db", "order_totals",
+//		[]any{42}, []any{&orders, &total}, nil)
+//
+//	// CREATE PROCEDURE order_report(IN customer INT, OUT total DECIMAL(10,2)), selecting orders then lines
+//	var list []Order
+//	var lines []OrderLine
+//	err = connection.GetConnectionManager().CallProc(ctx, "primary_db", "order_report",
+//		[]any{42}, []any{&total}, func(sets *connection.ResultSets) error {
+//			return sets.ScanAll(&list, &lines)
+//		})
+func (f *ConnectionManager) CallProc(ctx context.Context, name, proc string, in []interface{}, out []interface{}, results func(sets *ResultSets) error) error {
+	db, err := f.GetDBContext(ctx, name)
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to retrieve database handle for %q: %w", name, err)
+	}
+	f.mutex.Lock()
+	hooks := f.hooks[name]
+	f.mutex.Unlock()
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire a connection from %q: %w", name, err)
+	}
+	defer conn.Close()
+
+	stmts := procCall(proc, in, out)
+	run := func(query string, args []interface{}, fn func(query string, args ...interface{}) error) error {
+		if hooks != nil {
+			stmt, err := hooks.run(ctx, name, query, args)
+			if err != nil {
+				return err
+			}
+			query, args = stmt.SQL, stmt.Args
+		}
+		return fn(query, args...)
+	}
+	exec := func(query string, args ...interface{}) error {
+		_, err := conn.ExecContext(ctx, query, args...)
+		return err
+	}
+
+	if len(stmts.sets) > 0 {
+		if err := run("SET "+strings.Join(stmts.sets, ", "), stmts.setArgs, exec); err != nil {
+			return fmt.Errorf("failed to set INOUT parameters of %s: %w", proc, err)
+		}
+	}
+	err = run(stmts.call, stmts.args, func(query string, args ...interface{}) error {
+		rows, err := conn.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		if results != nil {
+			if err := results(&ResultSets{db: db, rows: rows}); err != nil {
+				return err
+			}
+		}
+		for {
+			for rows.Next() {
+			}
+			if !rows.NextResultSet() {
+				break
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return fmt.Errorf("call of %s on %q failed: %w", proc, name, err)
+	}
+	if len(stmts.variables) == 0 {
+		return nil
+	}
+
+	err = run("SELECT "+strings.Join(stmts.variables, ", "), nil, func(query string, args ...interface{}) error {
+		return conn.QueryRowContext(ctx, query, args...).Scan(stmts.dests...)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read OUT parameters of %s: %w", proc, err)
+	}
+	resets := make([]string, len(stmts.variables))
+	for i, variable := range stmts.variables {
+		resets[i] = variable + " = NULL"
+	}
+	_ = run("SET "+strings.Join(resets, ", "), nil, exec)
+	return nil
+}
+
+// procStatements are the statements of one CallProc call.
+type procStatements struct {
+	// call is the CALL statement and args its placeholder arguments.
+	call string
+	args []interface{}
+
+	// variables are the session variables of the OUT and INOUT parameters, scanned into dests.
+	variables []string
+	dests     []interface{}
+
+	// sets assign the INOUT values setArgs before the call.
+	sets    []string
+	setArgs []interface{}
+}
+
+// procCall builds the statements of a CallProc call.
+func procCall(proc string, in, out []interface{}) procStatements {
+	var stmts procStatements
+	params := make([]string, 0, len(in)+len(out))
+	bind := func(dest interface{}) string {
+		variable := fmt.Sprintf("%s%d", procVariablePrefix, len(params)+1)
+		stmts.variables = append(stmts.variables, variable)
+		stmts.dests = append(stmts.dests, dest)
+		return variable
+	}
+	for _, value := range in {
+		param, ok := value.(ProcParam)
+		if !ok {
+			params = append(params, "?")
+			stmts.args = append(stmts.args, value)
+			continue
+		}
+		variable := bind(param.dest)
+		if param.inout {
+			stmts.sets = append(stmts.sets, variable+" = ?")
+			stmts.setArgs = append(stmts.setArgs, param.value)
+		}
+		params = append(params, variable)
+	}
+	for _, dest := range out {
+		params = append(params, bind(dest))
+	}
+	stmts.call = "CALL " + quoteIdentifier(proc) + "(" + strings.Join(params, ", ") + ")"
+	return stmts
+}
@@ -0,0 +1,78 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestProcCall(t *testing.T) {
+	var count, total, balance int
+	stmts := procCall("billing.order_totals",
+		[]interface{}{42, ProcInOut(100, &balance), "EUR"}, []interface{}{&count, &total})
+
+	if want := "CALL `billing`.`order_totals`(?, @mysqlconn_proc_2, ?, @mysqlconn_proc_4, @mysqlconn_proc_5)"; stmts.call != want {
+		t.Errorf("call = %q, want %q", stmts.call, want)
+	}
+	if !reflect.DeepEqual(stmts.args, []interface{}{42, "EUR"}) {
+		t.Errorf("args = %v", stmts.args)
+	}
+	if !reflect.DeepEqual(stmts.variables, []string{"@mysqlconn_proc_2", "@mysqlconn_proc_4", "@mysqlconn_proc_5"}) {
+		t.Errorf("variables = %v", stmts.variables)
+	}
+	if dests := stmts.dests; len(dests) != 3 || dests[0] != &balance || dests[1] != &count || dests[2] != &total {
+		t.Errorf("dests = %v", dests)
+	}
+	if !reflect.DeepEqual(stmts.sets, []string{"@mysqlconn_proc_2 = ?"}) || !reflect.DeepEqual(stmts.setArgs, []interface{}{100}) {
+		t.Errorf("sets = %v, %v", stmts.sets, stmts.setArgs)
+	}
+
+	stmts = procCall("cleanup", []interface{}{ProcOut(&count)}, nil)
+	if stmts.call != "CALL `cleanup`(@mysqlconn_proc_1)" || len(stmts.variables) != 1 {
+		t.Errorf("call = %q, variables = %v", stmts.call, stmts.variables)
+	}
+}
+
+func TestCallProcUnknownConnection(t *testing.T) {
+	if err := newTestFactory().CallProc(context.Background(), "missing_db", "p", nil, nil, nil); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
+
+func TestCallProcResultSets(t *testing.T) {
+	factory := newTestFactory()
+	var calls []string
+	factory.connections["primary_db"] = newConnectorDB(t, &scriptedConnector{rows: func(query string) ([]string, [][]driver.Value) {
+		calls = append(calls, query)
+		return []string{"id", "amount"}, [][]driver.Value{{int64(1), int64(10)}, {int64(2), int64(20)}}
+	}})
+	ctx := context.Background()
+
+	type order struct {
+		ID     int
+		Amount int
+	}
+	var orders []order
+	err := factory.CallProc(ctx, "primary_db", "order_report", []interface{}{42}, nil, func(sets *ResultSets) error {
+		return sets.Scan(&orders)
+	})
+	if err != nil || len(orders) != 2 || orders[1].Amount != 20 {
+		t.Fatalf("Expected the result set to be scanned, got %+v: %v", orders, err)
+	}
+	if len(calls) != 1 || calls[0] != "CALL `order_report`(?)" {
+		t.Fatalf("Unexpected statements %v", calls)
+	}
+
+	failed := errors.New("scan failed")
+	err = factory.CallProc(ctx, "primary_db", "order_report", []interface{}{42}, nil, func(sets *ResultSets) error {
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Expected the error of the result set reader, got %v", err)
+	}
+	if err := factory.CallProc(ctx, "primary_db", "order_report", []interface{}{42}, nil, nil); err != nil {
+		t.Fatalf("Expected the result sets to be discarded, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package connection
+
+import (
+	"database/sql"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"strings"
+)
+
+// serverMaxAllowedPacket is the largest max_allowed_packet a MySQL server accepts, 1 GiB.
+const serverMaxAllowedPacket = 1 << 30
+
+// protocolDSN returns the data source name of c with its protocol options applied.
+func (c DBConfig) protocolDSN() string {
+	dsn := c.DataSourceName
+	if c.Compress {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn += separator + "compress=true"
+	}
+	return dsn
+}
+
+// applyProtocolOptions sets the protocol options of c on the driver configuration.
+func (c DBConfig) applyProtocolOptions(cfg *mysqldriver.Config) {
+	if c.MaxAllowedPacket > 0 {
+		cfg.MaxAllowedPacket = c.MaxAllowedPacket
+	}
+}
+
+// protocolWarnings reports protocol options the driver cannot honor.
+func protocolWarnings(c DBConfig) []ConfigWarning {
+	var warnings []ConfigWarning
+	if c.MaxAllowedPacket > serverMaxAllowedPacket {
+		warnings = append(warnings, ConfigWarning{Field: ConfigFieldMaxAllowedPacket,
+			Message: fmt.Sprintf("MaxAllowedPacket (%d) exceeds the 1 GiB packets a MySQL server accepts", c.MaxAllowedPacket)})
+	}
+	return warnings
+}
+
+// serverProtocolWarnings checks the protocol options of c against the server: the compression
+// algorithms it allows and its max_allowed_packet.
+func serverProtocolWarnings(c DBConfig, db *gorm.DB) ([]ConfigWarning, error) {
+	var warnings []ConfigWarning
+	if c.Compress {
+		// protocol_compression_algorithms exists from MySQL 8.0.18; older servers always allow zlib.
+		var algorithms sql.NullString
+		if err := db.Raw("SELECT @@GLOBAL.protocol_compression_algorithms").Row().Scan(&algorithms); err == nil &&
+			algorithms.Valid && !containsString(strings.Split(strings.ToLower(algorithms.String), ","), "zlib") {
+			warnings = append(warnings, ConfigWarning{Field: ConfigFieldCompress,
+				Message: fmt.Sprintf("the server allows the compression algorithms %q, not zlib; connections are not compressed", algorithms.String)})
+		}
+	}
+	if c.MaxAllowedPacket > 0 {
+		var serverPacket int64
+		if err := db.Raw("SELECT @@GLOBAL.max_allowed_packet").Row().Scan(&serverPacket); err != nil {
+			return warnings, fmt.Errorf("failed to read max_allowed_packet: %w", err)
+		}
+		if int64(c.MaxAllowedPacket) > serverPacket {
+			warnings = append(warnings, ConfigWarning{Field: ConfigFieldMaxAllowedPacket,
+				Message: fmt.Sprintf("MaxAllowedPacket (%d) exceeds the server's max_allowed_packet (%d); larger statements fail", c.MaxAllowedPacket, serverPacket)})
+		}
+	}
+	return warnings, nil
+}
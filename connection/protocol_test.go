@@ -0,0 +1,36 @@
+package connection
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+func TestProtocolOptions(t *testing.T) {
+	config := DBConfig{DataSourceName: "app@tcp(analytics:3306)/events?parseTime=true", Compress: true, MaxAllowedPacket: 16 << 20}
+	cfg, err := config.driverConfig("analytics_db")
+	if err != nil || cfg.MaxAllowedPacket != 16<<20 || !strings.Contains(cfg.FormatDSN(), "compress=true") {
+		t.Fatalf("Expected compression and maxAllowedPacket to be set, got %+v: %v", cfg, err)
+	}
+	warnings := protocolWarnings(config)
+	if len(warnings) != 0 {
+		t.Fatalf("Expected no warning for supported protocol options, got %v", warnings)
+	}
+	if w := protocolWarnings(DBConfig{MaxAllowedPacket: 2 << 30}); len(w) != 1 || w[0].Field != ConfigFieldMaxAllowedPacket {
+		t.Fatalf("Expected a MaxAllowedPacket warning, got %v", w)
+	}
+
+	db := newConnectorDB(t, &scriptedConnector{rows: func(query string) ([]string, [][]driver.Value) {
+		if strings.Contains(query, "protocol_compression_algorithms") {
+			return []string{"algorithms"}, [][]driver.Value{{"zstd,uncompressed"}}
+		}
+		return []string{"max_allowed_packet"}, [][]driver.Value{{int64(4 << 20)}}
+	}})
+	warnings, err = serverProtocolWarnings(config, db)
+	if err != nil || len(warnings) != 2 || warnings[0].Field != ConfigFieldCompress || warnings[1].Field != ConfigFieldMaxAllowedPacket {
+		t.Fatalf("Expected compression and packet size warnings, got %v: %v", warnings, err)
+	}
+	if warnings, err := serverProtocolWarnings(DBConfig{}, db); err != nil || len(warnings) != 0 {
+		t.Fatalf("Expected no check without protocol options, got %v: %v", warnings, err)
+	}
+}
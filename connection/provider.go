@@ -0,0 +1,40 @@
+package connection
+
+import (
+	"context"
+	"gorm.io/gorm"
+)
+
+// DBProvider is the part of ConnectionManager that code running queries depends on. Accepting a
+// DBProvider instead of *ConnectionManager lets that code be unit tested with the fake of the mocks
+// package, without a live database.
+//
+// Example Usage:
+//
+//	type UserService struct {
+//		DB connection.DBProvider
+//	}
+//
+//	func (s *UserService) Count(ctx context.Context) (int64, error) {
+//		db, err := s.DB.GetDBContext(ctx, "primary_db")
+//		if err != nil {
+//			return 0, err
+//		}
+//		var count int64
+//		return count, db.Model(&User{}).Count(&count).Error
+//	}
+type DBProvider interface {
+	// GetDB returns the named connection, reconnecting it if it is unhealthy.
+	GetDB(name string) (*gorm.DB, error)
+
+	// GetDBContext returns the named connection bound to ctx.
+	GetDBContext(ctx context.Context, name string) (*gorm.DB, error)
+
+	// CloseConnection closes the named connection and forgets its configuration.
+	CloseConnection(name string) error
+
+	// CloseAllConnections closes every connection and returns the errors of those that did not close cleanly.
+	CloseAllConnections() []error
+}
+
+var _ DBProvider = (*ConnectionManager)(nil)
@@ -0,0 +1,149 @@
+package connection
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"golang.org/x/net/proxy"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ContextDialer opens network connections. *net.Dialer implements it.
+type ContextDialer interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// proxyNetPrefix prefixes the driver network names registered for connections with a custom dialer.
+const proxyNetPrefix = "mysqlconn-dial-"
+
+// applyDialer routes the connections of cfg through the proxy URL or dialer, registering a driver
+// network for the managed connection name. Without either, cfg is left unchanged.
+func applyDialer(cfg *mysqldriver.Config, name, proxyURL string, dialer ContextDialer) error {
+	if proxyURL == "" && dialer == nil {
+		return nil
+	}
+	if cfg.Net != "tcp" && cfg.Net != "tcp4" && cfg.Net != "tcp6" {
+		return fmt.Errorf("a proxy or dialer requires a tcp address, got %q", cfg.Net)
+	}
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	if proxyURL != "" {
+		var err error
+		if dialer, err = proxyDialer(proxyURL, dialer); err != nil {
+			return err
+		}
+	}
+
+	network := cfg.Net
+	mysqldriver.RegisterDialContext(proxyNetPrefix+name, func(ctx context.Context, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	})
+	cfg.Net = proxyNetPrefix + name
+	return nil
+}
+
+// proxyDialer returns a dialer tunnelling through the proxy at rawURL: socks5://[user:password@]host:port
+// or http://[user:password@]host:port (HTTP CONNECT). forward dials the proxy itself.
+func proxyDialer(rawURL string, forward ContextDialer) (ContextDialer, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if u.User != nil {
+			password, _ := u.User.Password()
+			auth = &proxy.Auth{User: u.User.Username(), Password: password}
+		}
+		socks, err := proxy.SOCKS5("tcp", u.Host, auth, forwardDialer{forward})
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		contextDialer, ok := socks.(proxy.ContextDialer)
+		if !ok {
+			return nil, errors.New("SOCKS5 dialer does not support contexts")
+		}
+		return contextDialer, nil
+	case "http":
+		return &httpConnectDialer{proxy: u, forward: forward}, nil
+	}
+	return nil, fmt.Errorf("unsupported proxy scheme %q (want socks5 or http)", u.Scheme)
+}
+
+// forwardDialer adapts a ContextDialer to proxy.Dialer for the SOCKS5 client.
+type forwardDialer struct {
+	ContextDialer
+}
+
+func (d forwardDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// httpConnectDialer tunnels connections through an HTTP proxy with the CONNECT method.
+type httpConnectDialer struct {
+	proxy   *url.URL
+	forward ContextDialer
+}
+
+func (d *httpConnectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.forward.DialContext(ctx, "tcp", d.proxy.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach proxy %s: %w", d.proxy.Host, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if d.proxy.User != nil {
+		password, _ := d.proxy.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(d.proxy.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT to %s failed: %w", address, err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT to %s failed: %w", address, err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT to %s failed: %s", address, resp.Status)
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	// MySQL servers speak first: the handshake may already be buffered behind the proxy response.
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn reads the bytes buffered while parsing the proxy response before the connection's own.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
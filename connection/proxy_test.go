@@ -0,0 +1,74 @@
+package connection
+
+import (
+	"bufio"
+	"context"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHTTPConnectDialer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	requests := make(chan *http.Request, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		requests <- req
+		// The greeting follows the proxy response in the same write, as a MySQL handshake can.
+		_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\ngreeting")
+	}()
+
+	dialer, err := proxyDialer("http://user:secret@"+listener.Addr().String(), &net.Dialer{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	conn, err := dialer.DialContext(context.Background(), "tcp", "db.internal:3306")
+	if err != nil {
+		t.Fatalf("Dial through proxy failed: %v", err)
+	}
+	defer conn.Close()
+
+	req := <-requests
+	if req.Method != http.MethodConnect || req.Host != "db.internal:3306" || !strings.HasPrefix(req.Header.Get("Proxy-Authorization"), "Basic ") {
+		t.Fatalf("Unexpected proxy request: %s %s %v", req.Method, req.Host, req.Header)
+	}
+	greeting := make([]byte, len("greeting"))
+	if _, err := io.ReadFull(conn, greeting); err != nil || string(greeting) != "greeting" {
+		t.Fatalf("Expected the buffered greeting, got %q: %v", greeting, err)
+	}
+}
+
+func TestApplyDialer(t *testing.T) {
+	cfg, _ := mysqldriver.ParseDSN("user:password@tcp(db:3306)/dbname")
+	if err := applyDialer(cfg, "primary_db", "socks5://proxy:1080", nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Net != proxyNetPrefix+"primary_db" {
+		t.Fatalf("Expected the proxy network, got %q", cfg.Net)
+	}
+
+	other, _ := mysqldriver.ParseDSN("user:password@tcp(db:3306)/dbname")
+	if err := applyDialer(other, "primary_db", "ftp://proxy:21", nil); err == nil {
+		t.Fatal("Expected an error for an unsupported proxy scheme, got nil")
+	}
+	unix, _ := mysqldriver.ParseDSN("user:password@unix(/tmp/mysql.sock)/dbname")
+	if err := applyDialer(unix, "local_db", "socks5://proxy:1080", nil); err == nil {
+		t.Fatal("Expected an error for a unix socket address, got nil")
+	}
+}
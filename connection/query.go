@@ -0,0 +1,41 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+)
+
+// Select runs a raw query on db bound to ctx and returns its rows as a []T. T is a struct mapped with
+// GORM's column naming, a map[string]interface{} or a scalar for single-column queries.
+//
+// Example Usage:
+//
+//	db, _ := connection.GetConnectionManager().GetDB("primary_db")
+//	users, err := connection.Select[User](ctx, db, "SELECT * FROM users WHERE active = ?", true)
+//	ids, err := connection.Select[int64](ctx, db, "SELECT id FROM users")
+func Select[T any](ctx context.Context, db *gorm.DB, query string, args ...interface{}) ([]T, error) {
+	var out []T
+	if err := db.WithContext(ctx).Raw(query, args...).Scan(&out).Error; err != nil {
+		return nil, fmt.Errorf("select failed: %w", err)
+	}
+	return out, nil
+}
+
+// Get runs a raw query on db bound to ctx and returns its first row as a T, like Select.
+// gorm.ErrRecordNotFound is returned (wrapped) when the query returns no rows.
+//
+// Example Usage:
+//
+//	count, err := connection.Get[int64](ctx, db, "SELECT COUNT(*) FROM users")
+func Get[T any](ctx context.Context, db *gorm.DB, query string, args ...interface{}) (T, error) {
+	var out T
+	result := db.WithContext(ctx).Raw(query, args...).Scan(&out)
+	if result.Error != nil {
+		return out, fmt.Errorf("get failed: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return out, fmt.Errorf("get failed: %w", gorm.ErrRecordNotFound)
+	}
+	return out, nil
+}
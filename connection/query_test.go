@@ -0,0 +1,55 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"gorm.io/gorm"
+	"testing"
+)
+
+// emptyConnector opens connections whose queries return no rows.
+type emptyConnector struct{}
+
+func (emptyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &emptyConn{}, nil
+}
+
+func (emptyConnector) Driver() driver.Driver {
+	return nil
+}
+
+type emptyConn struct {
+	fakeConn
+}
+
+func (*emptyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &fakeRows{}, nil
+}
+
+func TestSelectAndGet(t *testing.T) {
+	ctx := context.Background()
+	db := newConnectorDB(t, multiConnector{})
+
+	users, err := Select[repoTestUser](ctx, db, "SELECT id, name FROM users")
+	if err != nil || len(users) != 2 || users[1].Name != "bo" {
+		t.Fatalf("Select() = %+v, %v", users, err)
+	}
+	user, err := Get[repoTestUser](ctx, db, "SELECT id, name FROM users WHERE id = ?", 1)
+	if err != nil || user.ID != 1 || user.Name != "ana" {
+		t.Fatalf("Get() = %+v, %v", user, err)
+	}
+
+	id, err := Get[int64](ctx, newConnectorDB(t, &fakeConnector{}), "SELECT CONNECTION_ID()")
+	if err != nil || id != 1 {
+		t.Fatalf("Get[int64]() = %d, %v", id, err)
+	}
+
+	empty := newConnectorDB(t, emptyConnector{})
+	if _, err := Get[repoTestUser](ctx, empty, "SELECT id, name FROM users"); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected gorm.ErrRecordNotFound, got %v", err)
+	}
+	if users, err := Select[repoTestUser](ctx, empty, "SELECT id, name FROM users"); err != nil || len(users) != 0 {
+		t.Fatalf("Select() on no rows = %+v, %v", users, err)
+	}
+}
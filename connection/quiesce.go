@@ -0,0 +1,161 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// defaultQuiesceGrace is how long GetDB keeps admitting queries after Quiesce, unless set with SetQuiesceGrace.
+const defaultQuiesceGrace = 2 * time.Second
+
+// ErrQuiescing is returned by GetDB once the grace period of Quiesce has elapsed, until Resume.
+var ErrQuiescing = errors.New("connection manager is quiescing")
+
+// quiesceState is the quiesce in progress.
+type quiesceState struct {
+	since time.Time
+	grace time.Duration
+}
+
+// admitting reports whether queries are still admitted at now.
+func (q *quiesceState) admitting(now time.Time) bool {
+	return now.Before(q.since.Add(q.grace))
+}
+
+// QuiesceReport describes the state of the connections after Quiesce.
+type QuiesceReport struct {
+	// Ready is true when no query was in flight on any connection: traffic can be cut over.
+	Ready bool
+
+	// InFlight is the number of pooled connections still in use, by connection name. Empty when Ready.
+	InFlight map[string]int
+
+	// Waited is how long Quiesce took, grace period included.
+	Waited time.Duration
+}
+
+// SetQuiesceGrace sets how long GetDB keeps admitting queries after Quiesce is called, so requests
+// already being served can finish their remaining statements. Zero or less stops admission right away.
+func (f *ConnectionManager) SetQuiesceGrace(grace time.Duration) {
+	f.quiesceGrace.Store(&grace)
+}
+
+// Quiesce stops admitting new queries on every connection and waits for the queries in flight, to cut
+// traffic over cleanly in a blue/green deploy of the database.
+//
+// Parameters:
+// - ctx: Bounds the wait for in-flight queries. Use context.WithTimeout.
+//
+// Returns:
+// - *QuiesceReport: Whether all connections are idle and which ones still have queries in flight.
+// - error: ctx.Err() if ctx is done before the connections are idle, or an error if Resume is called
+// while waiting. The report is returned in both cases.
+//
+// Behavior:
+// 1. GetDB, GetDBContext and GetSQLDB keep returning connections during the grace period (see
+// SetQuiesceGrace, 2 seconds by default), then fail with ErrQuiescing. So do the statements run through
+// the connections obtained before, except those of transactions, which can still commit.
+// 2. Quiesce waits for the grace period, then polls the pools until no connection is in use.
+// 3. Connections stay open: Resume admits queries again without reconnecting. Calling Quiesce again
+// while quiescing keeps the original grace period.
+//
+// Example Usage:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	report, err := connection.GetConnectionManager().Quiesce(ctx)
+//	if err != nil || !report.Ready {
+//		log.Printf("cut-over not safe, still in flight: %v", report.InFlight)
+//		connection.GetConnectionManager().Resume()
+//	}
+func (f *ConnectionManager) Quiesce(ctx context.Context) (*QuiesceReport, error) {
+	grace := defaultQuiesceGrace
+	if configured := f.quiesceGrace.Load(); configured != nil {
+		grace = *configured
+	}
+	return f.quiesceWithin(ctx, grace)
+}
+
+// quiesceWithin is Quiesce with the given grace period.
+func (f *ConnectionManager) quiesceWithin(ctx context.Context, grace time.Duration) (*QuiesceReport, error) {
+	clock := f.clock()
+	start := clock.Now()
+	state := &quiesceState{since: start, grace: grace}
+	if !f.quiesce.CompareAndSwap(nil, state) {
+		state = f.quiesce.Load()
+	}
+	if state == nil {
+		return &QuiesceReport{Waited: clock.Now().Sub(start)}, errors.New("quiesce interrupted by Resume")
+	}
+	log.Printf("Quiescing database connections: new queries are refused after %v.", state.grace)
+
+	ticker := clock.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	report := &QuiesceReport{}
+	for {
+		if f.quiesce.Load() != state {
+			report.Waited = clock.Now().Sub(start)
+			return report, errors.New("quiesce interrupted by Resume")
+		}
+		if !state.admitting(clock.Now()) {
+			report.InFlight = f.inFlight()
+			if len(report.InFlight) == 0 {
+				report.Ready = true
+				report.Waited = clock.Now().Sub(start)
+				log.Printf("Database connections quiesced after %v.", report.Waited)
+				return report, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			if report.InFlight == nil {
+				report.InFlight = f.inFlight()
+			}
+			report.Waited = clock.Now().Sub(start)
+			return report, fmt.Errorf("quiesce incomplete: %w", ctx.Err())
+		case <-ticker.C():
+		}
+	}
+}
+
+// Resume admits queries again after Quiesce.
+func (f *ConnectionManager) Resume() {
+	if f.quiesce.Swap(nil) != nil {
+		log.Println("Database connections resumed.")
+	}
+}
+
+// admit returns ErrQuiescing for op once the grace period of a quiesce has elapsed.
+func (f *ConnectionManager) admit(name, op string) error {
+	if state := f.quiesce.Load(); state != nil && !state.admitting(f.clock().Now()) {
+		return &ConnError{Name: name, Op: op, Err: ErrQuiescing}
+	}
+	return nil
+}
+
+// quiesceHook returns the statement hook refusing the statements of a quiesced manager. Statements of
+// transactions are let through, so the transactions in flight can commit.
+func (f *ConnectionManager) quiesceHook() statementHook {
+	return func(ctx context.Context, stmt *hookedStatement) error {
+		if stmt.InTx {
+			return nil
+		}
+		return f.admit(stmt.Conn, OpQuery)
+	}
+}
+
+// inFlight returns the number of pooled connections in use by connection name, leaving out idle ones.
+func (f *ConnectionManager) inFlight() map[string]int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	inFlight := make(map[string]int)
+	for name, db := range f.connections {
+		if inUse := poolStats(db).InUse; inUse > 0 {
+			inFlight[name] = inUse
+		}
+	}
+	return inFlight
+}
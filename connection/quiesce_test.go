@@ -0,0 +1,137 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestQuiesceAndResume(t *testing.T) {
+	factory := newTestFactory()
+	db := newConnectorDB(t, &fakeConnector{})
+	factory.connections["primary_db"] = db
+	factory.SetQuiesceGrace(30 * time.Millisecond)
+	sqlDB, _ := db.DB()
+
+	busy, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	go func() {
+		time.Sleep(80 * time.Millisecond)
+		_ = busy.Close()
+	}()
+
+	done := make(chan struct{})
+	var report *QuiesceReport
+	go func() {
+		defer close(done)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		report, err = factory.Quiesce(ctx)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	if _, err := factory.GetDB("primary_db"); err != nil {
+		t.Fatalf("Expected queries to be admitted during the grace period, got %v", err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, err := factory.GetDB("primary_db"); !errors.Is(err, ErrQuiescing) {
+		t.Fatalf("Expected ErrQuiescing after the grace period, got %v", err)
+	}
+
+	<-done
+	if err != nil || !report.Ready || len(report.InFlight) != 0 {
+		t.Fatalf("Expected a ready report, got %+v, %v", report, err)
+	}
+
+	factory.Resume()
+	if _, err := factory.GetDB("primary_db"); err != nil {
+		t.Fatalf("Expected queries to be admitted after Resume, got %v", err)
+	}
+}
+
+func TestQuiesceDeadline(t *testing.T) {
+	factory := newTestFactory()
+	db := newConnectorDB(t, &fakeConnector{})
+	factory.connections["primary_db"] = db
+	factory.SetQuiesceGrace(0)
+	defer factory.Resume()
+	sqlDB, _ := db.DB()
+
+	busy, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	defer busy.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	report, err := factory.Quiesce(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) || report.Ready || report.InFlight["primary_db"] != 1 {
+		t.Fatalf("Expected an incomplete quiesce with one query in flight, got %+v, %v", report, err)
+	}
+}
+
+func TestQuiesceFollowsClock(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1)
+	factory := newTestFactory().WithClock(clock)
+	factory.connections["primary_db"] = newConnectorDB(t, &fakeConnector{})
+	factory.SetQuiesceGrace(time.Minute)
+	defer factory.Resume()
+
+	done := make(chan *QuiesceReport, 1)
+	go func() {
+		report, _ := factory.Quiesce(context.Background())
+		done <- report
+	}()
+	waitFor(t, "the quiesce to start", func() bool { return clock.Tickers() == 1 })
+	if _, err := factory.GetDB("primary_db"); err != nil {
+		t.Fatalf("Expected queries to be admitted until the clock passes the grace period, got %v", err)
+	}
+
+	clock.Advance(time.Minute)
+	report := <-done
+	if !report.Ready || report.Waited != time.Minute {
+		t.Fatalf("Expected a ready report after the grace period of the clock, got %+v", report)
+	}
+	if _, err := factory.GetDB("primary_db"); !errors.Is(err, ErrQuiescing) {
+		t.Fatalf("Expected ErrQuiescing, got %v", err)
+	}
+}
+
+func TestQuiesceRefusesStatements(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	factory.SetQuiesceGrace(0)
+	defer factory.Resume()
+	db, err := factory.GetDB("primary_db")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	tx := db.Begin()
+	if tx.Error != nil {
+		t.Fatalf("Begin failed: %v", tx.Error)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if report, err := factory.Quiesce(ctx); !errors.Is(err, context.DeadlineExceeded) || report.InFlight["primary_db"] != 1 {
+		t.Fatalf("Expected the transaction to keep the quiesce waiting, got %+v, %v", report, err)
+	}
+	var count int64
+	if err := db.Table("fake_test_users").Count(&count).Error; !errors.Is(err, ErrQuiescing) {
+		t.Fatalf("Expected statements of a connection obtained before to be refused, got %v", err)
+	}
+	if err := tx.Table("fake_test_users").Count(&count).Error; err != nil {
+		t.Fatalf("Expected the transaction in flight to keep running, got %v", err)
+	}
+	if err := tx.Commit().Error; err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	factory.Resume()
+	if err := db.Table("fake_test_users").Count(&count).Error; err != nil {
+		t.Fatalf("Expected statements to run after Resume, got %v", err)
+	}
+}
@@ -0,0 +1,22 @@
+package connection
+
+import (
+	"strings"
+)
+
+// quoteIdentifier quotes a MySQL identifier with backticks. A qualified name such as
+// "db.table" is quoted part by part; embedded backticks are doubled.
+func quoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = "`" + strings.ReplaceAll(part, "`", "``") + "`"
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteString quotes s as a MySQL string literal, for the few statements that do not accept
+// placeholders (e.g. INTO OUTFILE).
+func quoteString(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\x00", `\0`, "\n", `\n`, "\r", `\r`, "\x1a", `\Z`)
+	return "'" + replacer.Replace(s) + "'"
+}
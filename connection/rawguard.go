@@ -0,0 +1,147 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"runtime"
+	"strings"
+)
+
+// rawGuardPluginName is the name under which the SQL injection guard is registered in gorm.Config.Plugins.
+const rawGuardPluginName = "mysqlconn:raw_guard"
+
+// ErrSuspectedInjection is returned for Raw and Exec statements rejected by the SQL injection guard
+// (DBConfig.RawSQLGuard), and by CheckRawSQL.
+var ErrSuspectedInjection = errors.New("suspected SQL injection")
+
+// comparisonOperators are the operators after which a quoted literal is reported by CheckRawSQL.
+var comparisonOperators = []string{"<=>", "<>", "!=", "<=", ">=", "=", "<", ">", " like", " regexp"}
+
+// CheckRawSQL reports whether a raw statement looks like it was built by formatting values into the
+// SQL text instead of binding them as arguments. It is the check run by the SQL injection guard, and
+// can be used directly in tests of code that builds SQL.
+//
+// Parameters:
+// - query: The statement text as passed to Raw or Exec, with ? placeholders for the arguments.
+//
+// Returns:
+// - error: nil when no pattern matched, otherwise an error wrapping ErrSuspectedInjection naming the pattern.
+//
+// Behavior:
+// The following patterns are reported:
+// 1. A quoted string literal compared with a column (= 'x', LIKE 'x%', ...), the shape left by
+// fmt.Sprintf("... = '%s'", value). Constant literals match too: pass them as arguments.
+// 2. A comment right after a string literal ('x'-- or 'x'#), used to cut off the rest of a statement.
+// 3. A tautology in an OR condition (OR 1=1, OR 'a'='a').
+// 4. Several statements separated by semicolons.
+//
+// Example Usage:
+//
+//	query := fmt.Sprintf("SELECT * FROM users WHERE name = '%s'", name)
+//	if err := connection.CheckRawSQL(query); err != nil {
+//		log.Printf("Unsafe query: %v", err) // suspected SQL injection: quoted literal in a comparison
+//	}
+func CheckRawSQL(query string) error {
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '-' && strings.HasPrefix(query[i:], "--"), c == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 3
+			}
+		case c == '`':
+			i = skipQuoted(query, i, c)
+		case c == '\'' || c == '"':
+			end := skipQuoted(query, i, c)
+			before := strings.ToLower(strings.TrimRight(query[:i], " \t\r\n"))
+			for _, op := range comparisonOperators {
+				if strings.HasSuffix(before, op) {
+					return fmt.Errorf("%w: quoted literal in a comparison", ErrSuspectedInjection)
+				}
+			}
+			after := strings.TrimLeft(query[end+1:], " \t\r\n)")
+			if strings.HasPrefix(after, "--") || strings.HasPrefix(after, "#") || strings.HasPrefix(after, "/*") {
+				return fmt.Errorf("%w: comment after a string literal", ErrSuspectedInjection)
+			}
+			i = end
+		}
+	}
+
+	tokens := sqlTokens(query)
+	for i, tok := range tokens {
+		switch {
+		case tok == ";":
+			return fmt.Errorf("%w: multiple statements", ErrSuspectedInjection)
+		case (tok == "or" || tok == "||") && i+3 < len(tokens) && tokens[i+2] == "=" &&
+			tokens[i+1] == tokens[i+3] && (tokens[i+1] == "?" || isWordToken(tokens[i+1])):
+			return fmt.Errorf("%w: tautology in an OR condition", ErrSuspectedInjection)
+		}
+	}
+	return nil
+}
+
+// rawGuardPlugin runs CheckRawSQL on the Raw and Exec statements of a connection. It is installed by
+// the factory when DBConfig.RawSQLGuard is set. Statements built by GORM from models and conditions
+// bind their values and are not inspected.
+type rawGuardPlugin struct {
+	logOnly bool
+}
+
+func (rawGuardPlugin) Name() string {
+	return rawGuardPluginName
+}
+
+func (p rawGuardPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("mysqlconn:raw_guard_query", p.check); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("mysqlconn:raw_guard_row", p.check); err != nil {
+		return err
+	}
+	return cb.Raw().Before("gorm:raw").Register("mysqlconn:raw_guard_raw", p.check)
+}
+
+// check inspects statements whose SQL was given by the caller: GORM only builds the SQL of the
+// query and row callbacks when it is still empty.
+func (p rawGuardPlugin) check(db *gorm.DB) {
+	if db.Error != nil || db.Statement.SQL.Len() == 0 {
+		return
+	}
+	query := db.Statement.SQL.String()
+	err := CheckRawSQL(query)
+	if err == nil {
+		return
+	}
+	log.Printf("%v at %s: %s", err, rawCaller(), query)
+	if !p.logOnly {
+		_ = db.AddError(err)
+	}
+}
+
+// rawCaller returns the file and line of the first caller outside GORM and this package, the code
+// that issued the statement.
+func rawCaller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		internal := strings.HasPrefix(frame.Function, "gorm.io/") ||
+			(strings.Contains(frame.Function, "/MySQL-connection/connection.") && !strings.HasSuffix(frame.File, "_test.go"))
+		if !internal {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown caller"
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package connection
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckRawSQL(t *testing.T) {
+	suspect := []string{
+		"SELECT * FROM users WHERE name = 'alice'",
+		"SELECT * FROM users WHERE name LIKE 'ali%'",
+		"SELECT * FROM users WHERE id = 1 OR 1=1",
+		"SELECT * FROM users WHERE name = ? OR a = a",
+		"SELECT * FROM users WHERE name IN ('x')-- ' AND active = 1",
+		"DELETE FROM sessions WHERE id = ?; DROP TABLE users",
+	}
+	for _, query := range suspect {
+		if err := CheckRawSQL(query); !errors.Is(err, ErrSuspectedInjection) {
+			t.Errorf("CheckRawSQL(%q) = %v, want ErrSuspectedInjection", query, err)
+		}
+	}
+
+	safe := []string{
+		"SELECT * FROM users WHERE name = ? AND status IN (?)",
+		"SELECT id, 'constant' AS kind FROM `users` WHERE a = b OR c = ?",
+		"UPDATE users SET visits = visits + 1 WHERE id = ?;",
+		"SELECT * FROM users -- name = 'alice'\nWHERE id = ?",
+	}
+	for _, query := range safe {
+		if err := CheckRawSQL(query); err != nil {
+			t.Errorf("CheckRawSQL(%q) = %v, want nil", query, err)
+		}
+	}
+}
+
+func TestRawGuardPlugin(t *testing.T) {
+	db := newDryRunDB(t)
+	if err := db.Use(rawGuardPlugin{}); err != nil {
+		t.Fatalf("Failed to install the SQL injection guard: %v", err)
+	}
+
+	var users []repoTestUser
+	if err := db.Raw("SELECT * FROM users WHERE name = '" + "bob" + "'").Find(&users).Error; !errors.Is(err, ErrSuspectedInjection) {
+		t.Fatalf("Expected the formatted Raw query to be rejected, got %v", err)
+	}
+	if err := db.Exec("UPDATE users SET name = 'x' WHERE id = 1 OR 1=1").Error; !errors.Is(err, ErrSuspectedInjection) {
+		t.Fatalf("Expected the formatted Exec to be rejected, got %v", err)
+	}
+	if err := db.Raw("SELECT * FROM users WHERE name = ?", "bob").Find(&users).Error; err != nil {
+		t.Fatalf("Expected the parameterized Raw query to pass, got %v", err)
+	}
+	if err := db.Where("name = 'bob'").Find(&users).Error; err != nil {
+		t.Fatalf("Expected statements built by GORM not to be inspected, got %v", err)
+	}
+
+	logOnly := newDryRunDB(t)
+	if err := logOnly.Use(rawGuardPlugin{logOnly: true}); err != nil {
+		t.Fatalf("Failed to install the SQL injection guard: %v", err)
+	}
+	if err := logOnly.Exec("DELETE FROM users WHERE name = 'bob'").Error; err != nil {
+		t.Fatalf("Expected the log-only guard to let the statement run, got %v", err)
+	}
+}
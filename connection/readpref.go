@@ -0,0 +1,86 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoReplica is returned by ReaderDB for reads with ReadSecondary when no replica can serve them.
+var ErrNoReplica = errors.New("no replica available")
+
+// ReadPreference tells ReaderDB which member of a replicated connection serves a read. The modes follow
+// the read preferences of the MongoDB drivers.
+type ReadPreference int
+
+const (
+	// ReadDefault defers to the preference of the connection (ReplicaOptions.ReadPreference), and to
+	// ReadSecondaryPreferred when it has none.
+	ReadDefault ReadPreference = iota
+
+	// ReadPrimary reads from the primary only.
+	ReadPrimary
+
+	// ReadPrimaryPreferred reads from the primary, and from a replica when the primary is unavailable.
+	ReadPrimaryPreferred
+
+	// ReadSecondary reads from a replica only, failing with ErrNoReplica when none is usable.
+	ReadSecondary
+
+	// ReadSecondaryPreferred reads from a replica, and from the primary when no replica is usable.
+	ReadSecondaryPreferred
+
+	// ReadNearest reads from the member with the lowest latency, the primary or a replica.
+	ReadNearest
+)
+
+func (p ReadPreference) String() string {
+	switch p {
+	case ReadDefault:
+		return "default"
+	case ReadPrimary:
+		return "primary"
+	case ReadPrimaryPreferred:
+		return "primaryPreferred"
+	case ReadSecondary:
+		return "secondary"
+	case ReadSecondaryPreferred:
+		return "secondaryPreferred"
+	case ReadNearest:
+		return "nearest"
+	}
+	return fmt.Sprintf("ReadPreference(%d)", int(p))
+}
+
+type readPreferenceKey struct{}
+
+// WithReadPreference returns a context whose reads through ReaderDB follow pref, overriding the
+// preference of the connection.
+//
+// Example Usage:
+//
+//	// Read-your-writes after an update: the replicas may not have applied it yet.
+//	ctx = connection.WithReadPreference(ctx, connection.ReadPrimary)
+//	db, err := factory.ReaderDB(ctx, "orders")
+func WithReadPreference(ctx context.Context, pref ReadPreference) context.Context {
+	return context.WithValue(ctx, readPreferenceKey{}, pref)
+}
+
+// ReadPreferenceOf returns the read preference of ctx, ReadDefault when none is set.
+func ReadPreferenceOf(ctx context.Context) ReadPreference {
+	pref, _ := ctx.Value(readPreferenceKey{}).(ReadPreference)
+	return pref
+}
+
+// readPreference resolves the preference of a read of a replicated connection: the one of ctx, then
+// the one of the connection, then ReadSecondaryPreferred.
+func readPreference(ctx context.Context, set *replicaSet) ReadPreference {
+	pref := ReadPreferenceOf(ctx)
+	if pref == ReadDefault && set != nil {
+		pref = set.opts.ReadPreference
+	}
+	if pref == ReadDefault {
+		pref = ReadSecondaryPreferred
+	}
+	return pref
+}
@@ -0,0 +1,93 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestReaderDBReadPreference(t *testing.T) {
+	factory := newTestFactory()
+	primary := newConnectorDB(t, &fakeConnector{})
+	replica := newConnectorDB(t, &fakeConnector{})
+	factory.connections["orders"] = primary
+	factory.connections["replica_a"] = replica
+	factory.connections["replica_down"] = newConnectorDB(t, failingConnector{})
+	if err := factory.SetReplicas("orders", []string{"replica_a", "replica_down"}, ReplicaOptions{ProbeInterval: time.Hour}); err != nil {
+		t.Fatalf("SetReplicas failed: %v", err)
+	}
+	defer factory.SetReplicas("orders", nil, ReplicaOptions{})
+
+	ctx := context.Background()
+	read := func(ctx context.Context) (*gorm.DB, error) { return factory.ReaderDB(ctx, "orders") }
+	for pref, expected := range map[ReadPreference]*gorm.DB{
+		ReadDefault:            replica,
+		ReadPrimary:            primary,
+		ReadPrimaryPreferred:   primary,
+		ReadSecondary:          replica,
+		ReadSecondaryPreferred: replica,
+	} {
+		db, err := read(WithReadPreference(ctx, pref))
+		if err != nil || db.Statement.ConnPool != expected.Statement.ConnPool {
+			t.Errorf("Unexpected member for %v (err %v)", pref, err)
+		}
+	}
+
+	set := factory.replicas["orders"]
+	set.mutex.Lock()
+	set.primaryState.latency, set.replicas[0].latency = time.Millisecond, 5*time.Millisecond
+	set.mutex.Unlock()
+	if db, err := read(WithReadPreference(ctx, ReadNearest)); err != nil || db.Statement.ConnPool != primary.Statement.ConnPool {
+		t.Fatalf("Expected the nearest member to be the primary, got %v", err)
+	}
+	set.mutex.Lock()
+	set.primaryState.latency = 10 * time.Millisecond
+	set.mutex.Unlock()
+	if db, err := read(WithReadPreference(ctx, ReadNearest)); err != nil || db.Statement.ConnPool != replica.Statement.ConnPool {
+		t.Fatalf("Expected the nearest member to be the replica, got %v", err)
+	}
+
+	set.mutex.Lock()
+	set.replicas[0].healthy = false
+	set.mutex.Unlock()
+	if _, err := read(WithReadPreference(ctx, ReadSecondary)); !errors.Is(err, ErrNoReplica) {
+		t.Fatalf("Expected ErrNoReplica without a usable replica, got %v", err)
+	}
+	if db, err := read(ctx); err != nil || db.Statement.ConnPool != primary.Statement.ConnPool {
+		t.Fatalf("Expected secondaryPreferred to fall back to the primary, got %v", err)
+	}
+}
+
+func TestReaderDBConnectionReadPreference(t *testing.T) {
+	factory := newTestFactory()
+	primary := newConnectorDB(t, &fakeConnector{})
+	replica := newConnectorDB(t, &fakeConnector{})
+	factory.connections["orders"] = primary
+	factory.connections["replica_a"] = replica
+	if err := factory.SetReplicas("orders", []string{"replica_a"}, ReplicaOptions{ProbeInterval: time.Hour, ReadPreference: ReadPrimaryPreferred}); err != nil {
+		t.Fatalf("SetReplicas failed: %v", err)
+	}
+	defer factory.SetReplicas("orders", nil, ReplicaOptions{})
+
+	ctx := context.Background()
+	if db, err := factory.ReaderDB(ctx, "orders"); err != nil || db.Statement.ConnPool != primary.Statement.ConnPool {
+		t.Fatalf("Expected the default of the connection to read from the primary, got %v", err)
+	}
+	if db, err := factory.ReaderDB(WithReadPreference(ctx, ReadSecondary), "orders"); err != nil || db.Statement.ConnPool != replica.Statement.ConnPool {
+		t.Fatalf("Expected the context to override the default, got %v", err)
+	}
+
+	delete(factory.connections, "orders")
+	if db, err := factory.ReaderDB(ctx, "orders"); err != nil || db.Statement.ConnPool != replica.Statement.ConnPool {
+		t.Fatalf("Expected primaryPreferred to fall back to the replica, got %v", err)
+	}
+	if _, err := factory.ReaderDB(WithReadPreference(ctx, ReadPrimary), "orders"); !errors.Is(err, ErrConnectionNotFound) {
+		t.Fatalf("Expected the error of the primary, got %v", err)
+	}
+
+	if ReadSecondaryPreferred.String() != "secondaryPreferred" || ReadPreferenceOf(ctx) != ReadDefault {
+		t.Fatalf("Unexpected rendering %q", ReadSecondaryPreferred.String())
+	}
+}
@@ -0,0 +1,154 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"log"
+	"reflect"
+	"strings"
+)
+
+// ErrConfigMismatch is returned by InitDataSourceConnection for an existing connection name with a
+// different configuration, unless DBConfig.ReconfigureOnMismatch is set.
+var ErrConfigMismatch = errors.New("connection already exists with a different configuration")
+
+// poolFields are the DBConfig fields that can be applied to a live pool without reconnecting. The
+// session defaults and AllowLoadData are read each time they are used.
+var poolFields = []string{"MaxOpen", "MaxIdle", "Lifetime", "IdleTime", "QueryFields", "FullSaveAssociations", "DryRun", "AllowLoadData"}
+
+// configDiff returns the names of the DBConfig fields that differ between a and b, ignoring
+// ReconfigureOnMismatch. Providers and dialers are compared by identity, policies by content.
+func configDiff(a, b DBConfig) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	var diff []string
+	for i := 0; i < va.NumField(); i++ {
+		field := va.Type().Field(i)
+		if field.Name == "ReconfigureOnMismatch" {
+			continue
+		}
+		if !sameValue(va.Field(i), vb.Field(i)) {
+			diff = append(diff, field.Name)
+		}
+	}
+	return diff
+}
+
+// sameValue compares two field values without panicking on uncomparable dynamic types such as funcs.
+// Funcs and the values of interface fields are compared by identity. Pointers, such as Policy, are
+// compared by the values they point to, so a configuration built again from scratch is the same.
+func sameValue(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Func:
+		return a.Pointer() == b.Pointer()
+	case reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		a, b = a.Elem(), b.Elem()
+		switch {
+		case a.Type() != b.Type():
+			return false
+		case a.Kind() == reflect.Func:
+			return a.Pointer() == b.Pointer()
+		case a.Type().Comparable():
+			return a.Interface() == b.Interface()
+		}
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// onlyPoolFields reports whether every field of diff can be applied to a live pool.
+func onlyPoolFields(diff []string) bool {
+	for _, field := range diff {
+		if !containsString(poolFields, field) {
+			return false
+		}
+	}
+	return true
+}
+
+// reconfigure handles InitDataSourceConnection for an existing connection, with the mutex held. It
+// returns replace as true when the connection must be initialized again with the new configuration.
+// A new MaxOpen also sets the capacity of the priority gate of the pool.
+func (f *ConnectionManager) reconfigure(name string, config DBConfig) (replace bool, err error) {
+	stored := f.configs[name]
+	diff := configDiff(stored, config)
+	if len(diff) == 0 {
+		return false, nil
+	}
+	if !config.ReconfigureOnMismatch {
+		return false, fmt.Errorf("%w: %q differs in %s", ErrConfigMismatch, name, strings.Join(diff, ", "))
+	}
+	if !onlyPoolFields(diff) {
+		return true, nil
+	}
+
+	db := f.connections[name]
+	if config.MaxOpen <= 0 && gated(db) {
+		// The priority gate needs a bounded pool: without one, the connection is established again.
+		return true, nil
+	}
+	shards, err := poolShards(db)
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve database handle for %q: %w", name, err)
+	}
+	applyLivePoolSettings(db, shards, config)
+	f.configs[name] = config
+	return false, nil
+}
+
+// RotateDSN replaces the data source name of a connection without downtime, e.g. when its endpoint
+// moves or its credentials are rotated, keeping the rest of its configuration.
+//
+// Parameters:
+// - ctx: Context bounding the health check and the opening of the new pool.
+// - name: The name of the managed connection.
+// - newDSN: The new data source name.
+//
+// Returns:
+// - error: An error if the connection does not exist, newDSN is invalid, or the new pool cannot be opened
+// or fails its health check, or a *CanaryError if it fails a canary check. The connection then keeps
+// using its current pool.
+//
+// Behavior:
+//  1. A session is opened with newDSN and closed, checking the endpoint and the credentials before
+//     anything is replaced. The current pool keeps serving meanwhile.
+//  2. The new pool is opened and checked like by Connect, then the canary checks registered with
+//     AddCanaryCheck run on it and on the current pool. If one fails, the new pool is closed.
+//  3. The new pool gets the hooks and plugins of the connection and is swapped into the registry
+//     atomically: GetDB returns it from then on.
+//  4. The previous pool stops handing out connections and is closed. Statements running on it finish
+//     first; its connections close as they are returned.
+//
+// Example Usage:
+//
+//	// Called by the secret rotation webhook
+//	if err := connection.GetConnectionManager().RotateDSN(ctx, "primary_db", newDSN); err != nil {
+//		log.Printf("Keeping the current credentials of primary_db: %v", err)
+//	}
+func (f *ConnectionManager) RotateDSN(ctx context.Context, name, newDSN string) error {
+	f.mutex.Lock()
+	config, exists := f.configs[name]
+	f.mutex.Unlock()
+	if !exists {
+		return errNotFound(name, OpRotateDSN)
+	}
+	if _, err := mysqldriver.ParseDSN(newDSN); err != nil {
+		return connError(name, OpRotateDSN, fmt.Errorf("invalid data source name: %w", err))
+	}
+	config.DataSourceName = newDSN
+
+	if err := handshake(ctx, name, config); err != nil {
+		return connError(name, OpRotateDSN, fmt.Errorf("new data source failed its health check: %w", err))
+	}
+	f.mutex.Lock()
+	verify := f.canaryVerifier(name)
+	f.mutex.Unlock()
+	if _, err := f.connectVerified(ctx, name, config, true, verify); err != nil {
+		return err
+	}
+	log.Printf("Database connection %q rotated to a new data source.", name)
+	return nil
+}
@@ -0,0 +1,111 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInitDetectsConfigMismatch(t *testing.T) {
+	factory := newTestFactory()
+	db := newConnectorDB(t, &fakeConnector{})
+	provider := SecretProviderFunc(func(ctx context.Context) (*Secret, error) { return nil, nil })
+	config := DBConfig{DataSourceName: "user@tcp(db:3306)/dbname", MaxOpen: 10, Lifetime: time.Minute, PasswordProvider: provider,
+		Policy: &QueryPolicy{DenyDDL: true}}.withDefaults()
+	factory.connections["primary_db"] = db
+	factory.configs["primary_db"] = config
+
+	if err := factory.InitDataSourceConnection("primary_db", config); err != nil {
+		t.Fatalf("Expected re-initialization with the same config to succeed, got %v", err)
+	}
+	rebuilt := config
+	rebuilt.Policy = &QueryPolicy{DenyDDL: true}
+	if err := factory.InitDataSourceConnection("primary_db", rebuilt); err != nil {
+		t.Fatalf("Expected re-initialization with an identical policy to succeed, got %v", err)
+	}
+
+	changed := config
+	changed.MaxOpen = 20
+	if err := factory.InitDataSourceConnection("primary_db", changed); !errors.Is(err, ErrConfigMismatch) {
+		t.Fatalf("Expected ErrConfigMismatch, got %v", err)
+	}
+
+	changed.ReconfigureOnMismatch = true
+	if err := factory.InitDataSourceConnection("primary_db", changed); err != nil {
+		t.Fatalf("Expected pool settings to be applied, got %v", err)
+	}
+	sqlDB, _ := db.DB()
+	if got := sqlDB.Stats().MaxOpenConnections; got != 20 {
+		t.Fatalf("Expected MaxOpen 20 on the live pool, got %d", got)
+	}
+	if factory.GetDbConfig("primary_db").MaxOpen != 20 {
+		t.Fatal("Expected the stored configuration to be updated")
+	}
+}
+
+// staticSecretProvider returns the same password on every call.
+type staticSecretProvider struct {
+	password string
+}
+
+func (p *staticSecretProvider) Secret(context.Context) (*Secret, error) {
+	return NewSecret([]byte(p.password)), nil
+}
+
+func TestConfigDiff(t *testing.T) {
+	a := DBConfig{DataSourceName: "a", MaxOpen: 1, Dialer: nil}
+	b := DBConfig{DataSourceName: "b", MaxOpen: 1, ReconfigureOnMismatch: true}
+	if got := configDiff(a, b); !reflect.DeepEqual(got, []string{"DataSourceName"}) {
+		t.Fatalf("configDiff = %v", got)
+	}
+
+	// Policies built again with the same content are the same; providers are compared by identity.
+	policy := func() DBConfig {
+		return DBConfig{DataSourceName: "a", Policy: &QueryPolicy{DenyDDL: true}, PrivilegePolicy: &PrivilegePolicy{Forbidden: []string{"SUPER"}}}
+	}
+	if got := configDiff(policy(), policy()); len(got) != 0 {
+		t.Fatalf("Expected identical policies to match, got %v", got)
+	}
+	changed := policy()
+	changed.Policy.DenyDDL = false
+	if got := configDiff(policy(), changed); !reflect.DeepEqual(got, []string{"Policy"}) {
+		t.Fatalf("configDiff = %v", got)
+	}
+	provider := func() DBConfig {
+		return DBConfig{PasswordProvider: &staticSecretProvider{password: "secret"}}
+	}
+	if got := configDiff(provider(), provider()); !reflect.DeepEqual(got, []string{"PasswordProvider"}) {
+		t.Fatalf("Expected providers to be compared by identity, got %v", got)
+	}
+
+	if onlyPoolFields([]string{"DataSourceName"}) || !onlyPoolFields([]string{"MaxOpen", "IdleTime"}) {
+		t.Fatal("Unexpected onlyPoolFields result")
+	}
+}
+
+func TestRotateDSNKeepsPoolOnFailure(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	before, _ := factory.GetDB("primary_db")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := factory.RotateDSN(ctx, "missing_db", "user@tcp(127.0.0.1:1)/app"); !errors.Is(err, ErrConnectionNotFound) {
+		t.Fatalf("Expected ErrConnectionNotFound, got %v", err)
+	}
+	if err := factory.RotateDSN(ctx, "primary_db", "not a dsn"); err == nil {
+		t.Fatal("Expected an error for an invalid data source name, got nil")
+	}
+	err := factory.RotateDSN(ctx, "primary_db", "user@tcp(127.0.0.1:1)/app?timeout=1s")
+	var connErr *ConnError
+	if !errors.As(err, &connErr) || connErr.Op != OpRotateDSN {
+		t.Fatalf("Expected the health check of an unreachable data source to fail, got %v", err)
+	}
+
+	after, err := factory.GetDB("primary_db")
+	var count int64
+	if err != nil || after != before || after.Model(&fakeTestUser{}).Count(&count).Error != nil || count != 3 {
+		t.Fatalf("Expected the current pool to keep serving, got %d users: %v", count, err)
+	}
+}
@@ -0,0 +1,292 @@
+package connection
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of SetReconnectLogging.
+const (
+	defaultReconnectLogEvery = 100
+	defaultReconnectWindow   = time.Minute
+
+	// reconnectBuckets is the number of buckets the window of the recent counters is split into.
+	reconnectBuckets = 12
+)
+
+// ReconnectStats aggregates the reconnect attempts of a connection. GetDB attempts a reconnect every
+// time it finds the connection unhealthy, so during an outage Attempts grows with the request rate.
+type ReconnectStats struct {
+	// Attempts and Failures count the reconnect attempts since the process started.
+	Attempts int64
+	Failures int64
+
+	// RecentAttempts and RecentFailures count the attempts within the last Window.
+	RecentAttempts int64
+	RecentFailures int64
+	Window         time.Duration
+
+	// ConsecutiveFailures counts the failed attempts since the last successful one, and OutageStart is
+	// the time of the first of them. Both are zero when the last attempt succeeded.
+	ConsecutiveFailures int64
+	OutageStart         time.Time
+
+	// Suppressed counts the "attempting to reconnect" log lines suppressed since the last one logged.
+	Suppressed int64
+
+	// Downtime is the total duration of the downtime windows of the connection, including the current
+	// one. See DowntimeHistory.
+	Downtime time.Duration
+
+	// ReconnectTime is the total time spent in reconnect attempts, and LastReconnectDuration the time
+	// the last attempt took.
+	ReconnectTime         time.Duration
+	LastReconnectDuration time.Duration
+
+	// LastError is the error of the last failed attempt.
+	LastError error
+
+	LastFailure time.Time
+	LastSuccess time.Time
+}
+
+// reconnectTracker aggregates reconnect attempts per connection and decides which are logged.
+type reconnectTracker struct {
+	mutex  sync.Mutex
+	every  int64
+	window time.Duration
+	states map[string]*reconnectState
+
+	// outages counts the connections with an outage in progress, so the health checks of GetDB skip
+	// the mutex when every connection is up.
+	outages atomic.Int64
+}
+
+// reconnectState aggregates the attempts of one connection. storm counts the attempts since the last
+// successful one, including those still running, and decides which are logged.
+type reconnectState struct {
+	attempts, failures int64
+	recentAttempts     windowCounter
+	recentFailures     windowCounter
+	consecutive        int64
+	storm              int64
+	outageStart        time.Time
+	suppressed         int64
+	lastErr            error
+	lastFailure        time.Time
+	lastSuccess        time.Time
+
+	// downSince is the time of the failed health check opening the current downtime window, zero
+	// when the connection is up, and downFailures the reconnect attempts failed since. windows holds
+	// the last closed windows, oldest first. probe closes the window at the first successful statement.
+	downSince     time.Time
+	downFailures  int64
+	probe         *downtimeProbe
+	downtime      time.Duration
+	windows       []DowntimeWindow
+	reconnectTime time.Duration
+	lastReconnect time.Duration
+}
+
+// windowCounter counts events over a sliding window split into reconnectBuckets buckets.
+type windowCounter struct {
+	counts [reconnectBuckets]int64
+	slots  [reconnectBuckets]int64
+}
+
+func (w *windowCounter) add(now time.Time, window time.Duration) {
+	slot := now.UnixNano() / int64(window/reconnectBuckets)
+	i := slot % reconnectBuckets
+	if w.slots[i] != slot {
+		w.slots[i], w.counts[i] = slot, 0
+	}
+	w.counts[i]++
+}
+
+func (w *windowCounter) sum(now time.Time, window time.Duration) int64 {
+	current := now.UnixNano() / int64(window/reconnectBuckets)
+	var total int64
+	for i, slot := range w.slots {
+		if current-slot < reconnectBuckets {
+			total += w.counts[i]
+		}
+	}
+	return total
+}
+
+// SetReconnectLogging sets how the "attempting to reconnect" lines of an outage are logged: the first
+// attempt after a healthy period is logged, then one attempt out of every, with the number of lines
+// suppressed in between. window is the span of the recent counters of ReconnectStats.
+// Zero values keep the defaults: every 100th attempt and one minute. Call it before connections are used.
+//
+// Example Usage:
+//
+//	factory := connection.GetConnectionManager()
+//	factory.SetReconnectLogging(1000, 5*time.Minute)
+func (f *ConnectionManager) SetReconnectLogging(every int, window time.Duration) {
+	f.reconnects.mutex.Lock()
+	defer f.reconnects.mutex.Unlock()
+	f.reconnects.every = int64(every)
+	f.reconnects.window = window
+}
+
+// ReconnectStats returns the aggregated reconnect attempts of a connection. A connection never
+// reconnected has zero stats.
+//
+// Example Usage:
+//
+//	stats := connection.GetConnectionManager().ReconnectStats("primary_db")
+//	if stats.ConsecutiveFailures > 0 {
+//		log.Printf("primary_db down since %v: %d attempts in the last %v, last error: %v",
+//			stats.OutageStart, stats.RecentAttempts, stats.Window, stats.LastError)
+//	}
+func (f *ConnectionManager) ReconnectStats(name string) ReconnectStats {
+	return f.reconnects.stats(name, f.clock().Now())
+}
+
+func (t *reconnectTracker) settings() (int64, time.Duration) {
+	every, window := t.every, t.window
+	if every <= 0 {
+		every = defaultReconnectLogEvery
+	}
+	if window < reconnectBuckets {
+		window = defaultReconnectWindow
+	}
+	return every, window
+}
+
+func (t *reconnectTracker) state(name string) *reconnectState {
+	if t.states == nil {
+		t.states = make(map[string]*reconnectState)
+	}
+	st := t.states[name]
+	if st == nil {
+		st = &reconnectState{}
+		t.states[name] = st
+	}
+	return st
+}
+
+// begin records a reconnect attempt and reports whether it is logged, with the number of attempts
+// suppressed since the last logged one.
+func (t *reconnectTracker) begin(name string, now time.Time) (bool, int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	every, window := t.settings()
+	st := t.state(name)
+	st.attempts++
+	st.recentAttempts.add(now, window)
+	if st.downSince.IsZero() {
+		st.downSince = now
+	}
+	if st.probe != nil {
+		st.probe.down.Store(false)
+	}
+	if st.storm == 0 {
+		t.outages.Add(1)
+	}
+	st.storm++
+	if (st.storm-1)%every != 0 {
+		st.suppressed++
+		return false, 0
+	}
+	suppressed := st.suppressed
+	st.suppressed = 0
+	return true, suppressed
+}
+
+// end records the outcome of a reconnect attempt. After a successful attempt ending an outage, it
+// returns the number of failed attempts of the outage and its duration.
+func (t *reconnectTracker) end(name string, err error, now time.Time) (int64, time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	_, window := t.settings()
+	st := t.state(name)
+	if err != nil {
+		st.failures++
+		st.recentFailures.add(now, window)
+		if st.consecutive == 0 {
+			st.outageStart = now
+		}
+		st.consecutive++
+		if !st.downSince.IsZero() {
+			st.downFailures++
+		}
+		st.lastErr, st.lastFailure = err, now
+		return 0, 0
+	}
+	st.lastSuccess = now
+	return t.recover(st, now)
+}
+
+// recovered ends the outage of a connection whose health check succeeded without a reconnect, e.g.
+// because the driver replaced the broken connections of the pool by itself. It returns what end does.
+func (t *reconnectTracker) recovered(name string, now time.Time) (int64, time.Duration) {
+	if t.outages.Load() == 0 {
+		return 0, 0
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	st := t.states[name]
+	if st == nil || st.storm == 0 {
+		return 0, 0
+	}
+	return t.recover(st, now)
+}
+
+// recover resets the outage of st. Its downtime window stays open until the first successful
+// statement of the connection (see downtimeProbe), or closes at now for a connection without a probe.
+func (t *reconnectTracker) recover(st *reconnectState, now time.Time) (int64, time.Duration) {
+	if !st.downSince.IsZero() {
+		if st.probe != nil {
+			st.probe.down.Store(true)
+		} else {
+			st.closeWindow(now)
+		}
+	}
+	if st.storm > 0 {
+		t.outages.Add(-1)
+	}
+	failed, outage := st.consecutive, now.Sub(st.outageStart)
+	st.consecutive, st.storm, st.outageStart, st.suppressed = 0, 0, time.Time{}, 0
+	if failed == 0 {
+		return 0, 0
+	}
+	return failed, outage
+}
+
+// timed records the duration of a reconnect attempt.
+func (t *reconnectTracker) timed(name string, took time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	st := t.state(name)
+	st.reconnectTime += took
+	st.lastReconnect = took
+}
+
+func (t *reconnectTracker) stats(name string, now time.Time) ReconnectStats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	_, window := t.settings()
+	st := t.states[name]
+	if st == nil {
+		return ReconnectStats{Window: window}
+	}
+	return ReconnectStats{
+		Attempts:              st.attempts,
+		Failures:              st.failures,
+		RecentAttempts:        st.recentAttempts.sum(now, window),
+		RecentFailures:        st.recentFailures.sum(now, window),
+		Window:                window,
+		ConsecutiveFailures:   st.consecutive,
+		OutageStart:           st.outageStart,
+		Suppressed:            st.suppressed,
+		Downtime:              st.downtimeUntil(now),
+		ReconnectTime:         st.reconnectTime,
+		LastReconnectDuration: st.lastReconnect,
+		LastError:             st.lastErr,
+		LastFailure:           st.lastFailure,
+		LastSuccess:           st.lastSuccess,
+	}
+}
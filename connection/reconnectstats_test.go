@@ -0,0 +1,64 @@
+package connection
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReconnectStormLogging(t *testing.T) {
+	EnableChaos(true)
+	defer EnableChaos(false)
+	factory := newFakeFactory(t, FakeData{})
+	factory.SetReconnectLogging(3, time.Minute)
+	outage := errors.New("connection refused")
+	if err := factory.InjectChaos("primary_db", ChaosConfig{FailPing: true, ReconnectError: outage}); err != nil {
+		t.Fatalf("InjectChaos failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	for i := 0; i < 7; i++ {
+		if _, err := factory.GetDB("primary_db"); !errors.Is(err, outage) {
+			t.Fatalf("Expected the injected reconnect error, got %v", err)
+		}
+	}
+
+	if lines := strings.Count(buf.String(), "Attempting to reconnect"); lines != 3 {
+		t.Fatalf("Expected attempts 1, 4 and 7 to be logged, got %d lines:\n%s", lines, buf.String())
+	}
+	if !strings.Contains(buf.String(), "(2 similar messages suppressed)") {
+		t.Fatalf("Expected the suppressed count to be logged:\n%s", buf.String())
+	}
+	stats := factory.ReconnectStats("primary_db")
+	if stats.Attempts != 7 || stats.Failures != 7 || stats.RecentAttempts != 7 || stats.ConsecutiveFailures != 7 ||
+		!errors.Is(stats.LastError, outage) || stats.OutageStart.IsZero() {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestReconnectTrackerRecovery(t *testing.T) {
+	var tracker reconnectTracker
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		tracker.begin("primary_db", start)
+		tracker.end("primary_db", errors.New("down"), start)
+	}
+	tracker.begin("primary_db", start.Add(time.Second))
+	if failed, outage := tracker.end("primary_db", nil, start.Add(time.Second)); failed != 3 || outage != time.Second {
+		t.Fatalf("Expected an outage of 3 failures over 1s, got %d over %v", failed, outage)
+	}
+	if logged, _ := tracker.begin("primary_db", start.Add(2*time.Second)); !logged {
+		t.Fatal("Expected the first attempt after a recovery to be logged")
+	}
+
+	stats := tracker.stats("primary_db", start.Add(2*time.Minute))
+	if stats.Attempts != 5 || stats.Failures != 3 || stats.RecentAttempts != 0 || stats.ConsecutiveFailures != 0 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+}
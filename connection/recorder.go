@@ -0,0 +1,288 @@
+package connection
+
+import (
+	"bufio"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"os"
+	"sync"
+	"time"
+)
+
+// recorderHook is the name of the statement hook installed by Record.
+const recorderHook = "recorder"
+
+// defaultRecordLimit caps the statements kept by a recording unless RecordOptions.MaxStatements is set.
+const defaultRecordLimit = 10000
+
+// redactedArg replaces string and binary arguments in recordings.
+const redactedArg = "[redacted]"
+
+// RecordOptions configure a statement recording.
+type RecordOptions struct {
+	// Window stops the recording automatically after this duration. Zero records until Stop.
+	Window time.Duration
+
+	// MaxStatements caps the number of statements kept; later statements are dropped. Defaults to 10000.
+	MaxStatements int
+
+	// KeepArgs records arguments as they are. By default string and binary arguments, which may carry
+	// personal data or secrets, are replaced with "[redacted]"; numbers, booleans and times are kept so
+	// the statements still hit the same rows when replayed.
+	KeepArgs bool
+}
+
+// RecordedStatement is one statement of a recording.
+type RecordedStatement struct {
+	// Offset is the time since the start of the recording.
+	Offset time.Duration `json:"offset"`
+	SQL    string        `json:"sql"`
+	Args   []interface{} `json:"args,omitempty"`
+}
+
+// Recording captures the statements sent on a connection. It is created by Record.
+type Recording struct {
+	chain   *hookChain
+	opts    RecordOptions
+	started time.Time
+	timer   *time.Timer
+
+	mutex      sync.Mutex
+	statements []RecordedStatement
+	dropped    int
+	stopped    bool
+}
+
+// Record starts capturing, in order, the statements sent on the named connection, for reproducing
+// production bugs with Replay.
+//
+// Parameters:
+// - name: The name of the managed connection.
+// - opts: The recording window, size cap and argument redaction.
+//
+// Returns:
+// - *Recording: The running recording. Stop it, then Save it or pass its statements to Replay.
+// - error: An error if the connection does not exist or is already being recorded.
+//
+// Notes:
+// - Statements are recorded as sent to the server, after the other statement hooks rewrote them.
+// - A reconnect replaces the connection's hooks and ends the recording.
+//
+// Example Usage:
+//
+//	rec, err := connection.GetConnectionManager().Record("primary_db", connection.RecordOptions{Window: time.Minute})
+//	if err != nil {
+//		return err
+//	}
+//	time.Sleep(time.Minute)
+//	rec.Stop()
+//	err = rec.Save("/tmp/checkout-bug.jsonl")
+func (f *ConnectionManager) Record(name string, opts RecordOptions) (*Recording, error) {
+	f.mutex.Lock()
+	chain, exists := f.hooks[name]
+	f.mutex.Unlock()
+	if !exists {
+		return nil, errNotFound(name, OpRecord)
+	}
+	return startRecording(chain, opts)
+}
+
+// startRecording installs the recorder hook on chain.
+func startRecording(chain *hookChain, opts RecordOptions) (*Recording, error) {
+	if opts.MaxStatements <= 0 {
+		opts.MaxStatements = defaultRecordLimit
+	}
+	if chain.has(recorderHook) {
+		return nil, errors.New("connection is already being recorded")
+	}
+
+	r := &Recording{chain: chain, opts: opts, started: time.Now()}
+	chain.set(recorderHook, r.record)
+	if opts.Window > 0 {
+		r.timer = time.AfterFunc(opts.Window, func() { r.Stop() })
+	}
+	return r, nil
+}
+
+func (r *Recording) record(ctx context.Context, stmt *hookedStatement) error {
+	recorded := RecordedStatement{Offset: time.Since(r.started), SQL: stmt.SQL}
+	if len(stmt.Args) > 0 {
+		recorded.Args = make([]interface{}, len(stmt.Args))
+		for i, arg := range stmt.Args {
+			recorded.Args[i] = arg
+			if !r.opts.KeepArgs {
+				recorded.Args[i] = redactArg(arg)
+			}
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.stopped {
+		return nil
+	}
+	if len(r.statements) >= r.opts.MaxStatements {
+		r.dropped++
+		return nil
+	}
+	r.statements = append(r.statements, recorded)
+	return nil
+}
+
+// redactArg replaces the arguments sent to the server as strings or bytes, converted as by the driver:
+// named string types, pointers and driver.Valuer implementations such as sql.NullString included. Other
+// Valuers are recorded as their value, and arguments the driver cannot convert are replaced.
+func redactArg(arg interface{}) interface{} {
+	value, err := driver.DefaultParameterConverter.ConvertValue(arg)
+	if err != nil {
+		return redactedArg
+	}
+	switch value.(type) {
+	case string, []byte:
+		return redactedArg
+	}
+	if _, ok := arg.(driver.Valuer); ok {
+		return value
+	}
+	return arg
+}
+
+// Stop ends the recording and returns the statements captured. Calling Stop again returns the same statements.
+func (r *Recording) Stop() []RecordedStatement {
+	r.mutex.Lock()
+	if !r.stopped {
+		r.stopped = true
+		r.chain.remove(recorderHook)
+		if r.timer != nil {
+			r.timer.Stop()
+		}
+	}
+	r.mutex.Unlock()
+	return r.Statements()
+}
+
+// Statements returns the statements captured so far.
+func (r *Recording) Statements() []RecordedStatement {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]RecordedStatement(nil), r.statements...)
+}
+
+// Dropped returns the number of statements not kept because the recording was full.
+func (r *Recording) Dropped() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.dropped
+}
+
+// Save writes the statements captured so far to path, one JSON object per line.
+func (r *Recording) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, stmt := range r.Statements() {
+		if err := encoder.Encode(stmt); err != nil {
+			_ = file.Close()
+			return fmt.Errorf("failed to write recording %q: %w", path, err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write recording %q: %w", path, err)
+	}
+	return file.Close()
+}
+
+// LoadRecording reads statements written by Recording.Save. Numeric arguments are read back as
+// float64 and times as strings, which MySQL converts as needed.
+func LoadRecording(path string) ([]RecordedStatement, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var statements []RecordedStatement
+	decoder := json.NewDecoder(file)
+	for decoder.More() {
+		var stmt RecordedStatement
+		if err := decoder.Decode(&stmt); err != nil {
+			return nil, fmt.Errorf("failed to read recording %q: %w", path, err)
+		}
+		statements = append(statements, stmt)
+	}
+	return statements, nil
+}
+
+// ReplayOptions configure Replay.
+type ReplayOptions struct {
+	// KeepTiming waits between statements as long as during the recording.
+	KeepTiming bool
+
+	// StopOnError stops at the first failing statement instead of running the rest.
+	StopOnError bool
+}
+
+// ReplayError is a statement that failed during Replay.
+type ReplayError struct {
+	Index int
+	SQL   string
+	Err   error
+}
+
+func (e ReplayError) Error() string {
+	return fmt.Sprintf("statement %d (%s): %v", e.Index, e.SQL, e.Err)
+}
+
+// ReplayResult summarizes a Replay.
+type ReplayResult struct {
+	Executed int
+	Errors   []ReplayError
+}
+
+// Replay executes recorded statements, in order, on db, which should be a test database. Each statement
+// runs on its own in autocommit mode: transactions are begun and ended through the driver rather than
+// with statements, so a recording holds no BEGIN, COMMIT or ROLLBACK, and the statements of a recorded
+// transaction are replayed without it, including those of a transaction that was rolled back.
+//
+// Example Usage:
+//
+//	statements, _ := connection.LoadRecording("/tmp/checkout-bug.jsonl")
+//	db, _ := connection.GetConnectionManager().GetDB("test_db")
+//	result, err := connection.Replay(ctx, db, statements, connection.ReplayOptions{StopOnError: true})
+func Replay(ctx context.Context, db *gorm.DB, statements []RecordedStatement, opts ReplayOptions) (*ReplayResult, error) {
+	result := &ReplayResult{}
+	start := time.Now()
+	for i, stmt := range statements {
+		if opts.KeepTiming {
+			if wait := stmt.Offset - time.Since(start); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return result, ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		err := db.WithContext(ctx).Exec(stmt.SQL, stmt.Args...).Error
+		result.Executed++
+		if err != nil {
+			replayErr := ReplayError{Index: i, SQL: stmt.SQL, Err: err}
+			result.Errors = append(result.Errors, replayErr)
+			if opts.StopOnError {
+				return result, replayErr
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,88 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	db, hooks, _ := newRecordingDB(t, "primary_db")
+	rec, err := startRecording(hooks, RecordOptions{MaxStatements: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := startRecording(hooks, RecordOptions{}); err == nil {
+		t.Fatal("Expected an error starting a second recording, got nil")
+	}
+
+	db.Exec("UPDATE users SET email = ? WHERE id = ?", "a@example.com", 42)
+	db.Exec("DELETE FROM sessions WHERE user_id = ?", 42)
+	db.Exec("UPDATE users SET seen = 1")
+	statements := rec.Stop()
+	db.Exec("UPDATE users SET seen = 2")
+
+	if len(statements) != 2 || rec.Dropped() != 1 || len(rec.Stop()) != 2 {
+		t.Fatalf("Unexpected recording: %+v (dropped %d)", statements, rec.Dropped())
+	}
+	if statements[0].Args[0] != redactedArg || statements[0].Args[1] != 42 {
+		t.Fatalf("Expected the string argument redacted and the id kept, got %v", statements[0].Args)
+	}
+	if hooks.has(recorderHook) {
+		t.Fatal("Expected Stop to remove the recorder hook")
+	}
+
+	path := filepath.Join(t.TempDir(), "recording.jsonl")
+	if err := rec.Save(path); err != nil {
+		t.Fatalf("Failed to save recording: %v", err)
+	}
+	loaded, err := LoadRecording(path)
+	if err != nil || len(loaded) != 2 || loaded[1].SQL != statements[1].SQL {
+		t.Fatalf("Unexpected loaded recording %+v: %v", loaded, err)
+	}
+
+	target, _, pool := newRecordingDB(t, "test_db")
+	result, err := Replay(context.Background(), target, loaded, ReplayOptions{StopOnError: true})
+	if err != nil || result.Executed != 2 {
+		t.Fatalf("Unexpected replay result %+v: %v", result, err)
+	}
+	if got := pool.recorded(); len(got) != 2 || got[0] != statements[0].SQL {
+		t.Fatalf("Unexpected replayed statements: %q", got)
+	}
+}
+
+type recorderTestEmail string
+
+func TestRedactArg(t *testing.T) {
+	email := "a@example.com"
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		arg  interface{}
+		want interface{}
+	}{
+		{"string", "a@example.com", redactedArg},
+		{"bytes", []byte("secret"), redactedArg},
+		{"string pointer", &email, redactedArg},
+		{"named string", recorderTestEmail("a@example.com"), redactedArg},
+		{"raw JSON", json.RawMessage(`{"card":"4111"}`), redactedArg},
+		{"null string", sql.NullString{String: "a@example.com", Valid: true}, redactedArg},
+		{"int", 42, 42},
+		{"bool", true, true},
+		{"time", at, at},
+		{"null int", sql.NullInt64{Int64: 7, Valid: true}, int64(7)},
+		{"invalid null string", sql.NullString{}, nil},
+		{"nil", nil, nil},
+		{"unconvertible", struct{ Card string }{"4111"}, redactedArg},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactArg(tt.arg); got != tt.want {
+				t.Fatalf("redactArg(%#v) = %#v, want %#v", tt.arg, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Retain records that a module uses the named connection. Modules sharing a connection each call
+// Retain once and Release when they are done; the connection is closed by the last Release, so one
+// module shutting down does not close a connection another one still uses.
+//
+// Parameters:
+// - name: The name of the managed connection.
+//
+// Returns:
+// - int: The number of holders after this call.
+// - error: An error if the connection does not exist.
+//
+// Example Usage:
+//
+//	factory := connection.GetConnectionManager()
+//	_ = factory.InitDataSourceConnection("shared_db", config)
+//	_, _ = factory.Retain("shared_db") // billing module
+//	_, _ = factory.Retain("shared_db") // reporting module
+//	...
+//	_, _ = factory.Release("shared_db") // billing shuts down, the pool stays open
+//	_, _ = factory.Release("shared_db") // reporting shuts down, the pool is closed
+func (f *ConnectionManager) Retain(name string) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, exists := f.connections[name]; !exists {
+		return 0, errNotFound(name, OpRetain)
+	}
+	if f.refs == nil {
+		f.refs = make(map[string]int)
+	}
+	f.refs[name]++
+	return f.refs[name], nil
+}
+
+// Release gives up a hold taken with Retain and closes the connection when no holder is left.
+//
+// Returns:
+// - int: The number of holders left; 0 means the connection was closed.
+// - error: An error if the connection is not retained or cannot be closed.
+func (f *ConnectionManager) Release(name string) (int, error) {
+	_, op := startOperation(context.Background(), OpClose)
+	start := time.Now()
+
+	f.mutex.Lock()
+	count := f.refs[name]
+	if count == 0 {
+		f.mutex.Unlock()
+		return 0, fmt.Errorf("database connection %q is not retained", name)
+	}
+	count--
+	if count > 0 {
+		f.refs[name] = count
+		f.mutex.Unlock()
+		return count, nil
+	}
+	// The last holder closes the connection in the same critical section, so a concurrent Retain either
+	// comes first and keeps it open, or finds it closed.
+	delete(f.refs, name)
+	op = op.tagged(f.configs[name].Cluster)
+	stop, err := f.closeLocked(op, name)
+	f.mutex.Unlock()
+	if err == nil {
+		stop()
+	}
+
+	if err := f.endStep(op, name, "close", start, err); err != nil {
+		return 0, connError(name, OpClose, err)
+	}
+	return 0, nil
+}
+
+// Holders returns the number of modules retaining the named connection.
+func (f *ConnectionManager) Holders(name string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.refs[name]
+}
@@ -0,0 +1,98 @@
+package connection
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetainRelease(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["shared_db"] = newConnectorDB(t, &fakeConnector{})
+
+	if _, err := factory.Retain("missing_db"); err == nil {
+		t.Fatal("Expected an error retaining a non-existent connection, got nil")
+	}
+	for want := 1; want <= 2; want++ {
+		if got, err := factory.Retain("shared_db"); err != nil || got != want {
+			t.Fatalf("Retain = %d, %v; want %d", got, err, want)
+		}
+	}
+
+	if left, err := factory.Release("shared_db"); err != nil || left != 1 {
+		t.Fatalf("Release = %d, %v; want 1", left, err)
+	}
+	if _, err := factory.GetDB("shared_db"); err != nil {
+		t.Fatalf("Expected the connection to stay open while retained, got %v", err)
+	}
+
+	if left, err := factory.Release("shared_db"); err != nil || left != 0 {
+		t.Fatalf("Release = %d, %v; want 0", left, err)
+	}
+	if _, err := factory.GetDB("shared_db"); err == nil {
+		t.Fatal("Expected the last Release to close the connection")
+	}
+	if _, err := factory.Release("shared_db"); err == nil {
+		t.Fatal("Expected an error releasing a connection that is not retained, got nil")
+	}
+}
+
+func TestRetainReleaseRace(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		factory := newTestFactory()
+		factory.connections["shared_db"] = newConnectorDB(t, &fakeConnector{})
+		if _, err := factory.Retain("shared_db"); err != nil {
+			t.Fatalf("Retain failed: %v", err)
+		}
+
+		retained := make(chan error, 1)
+		go func() {
+			_, err := factory.Retain("shared_db")
+			retained <- err
+		}()
+		left, err := factory.Release("shared_db")
+		if err != nil {
+			t.Fatalf("Release failed: %v", err)
+		}
+		retainErr := <-retained
+
+		// Either Retain came first and the connection stays open with one holder, or it found it closed.
+		_, getErr := factory.GetDB("shared_db")
+		switch {
+		case retainErr == nil && (left != 1 || getErr != nil || factory.Holders("shared_db") != 1):
+			t.Fatalf("Expected a successful Retain to keep the connection open, got %d holders left, %v", left, getErr)
+		case retainErr != nil && (left != 0 || getErr == nil):
+			t.Fatalf("Expected a failed Retain to find the connection closed, got %d holders left, %v", left, getErr)
+		}
+		factory.CloseAllConnections()
+	}
+}
+
+func TestReleaseStopsLagMonitor(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 1)
+	factory := newTestFactory().WithClock(clock)
+	for _, name := range []string{"orders", "replica"} {
+		if err := factory.InitFake(name, heartbeatTable()); err != nil {
+			t.Fatalf("InitFake failed: %v", err)
+		}
+	}
+	defer factory.CloseAllConnections()
+	monitor, err := factory.StartLagMonitor("orders", []string{"replica"}, LagMonitorOptions{ServerID: 7})
+	if err != nil {
+		t.Fatalf("StartLagMonitor failed: %v", err)
+	}
+	if _, err := factory.Retain("orders"); err != nil {
+		t.Fatalf("Retain failed: %v", err)
+	}
+
+	if left, err := factory.Release("orders"); err != nil || left != 0 {
+		t.Fatalf("Release = %d, %v; want 0", left, err)
+	}
+	select {
+	case <-monitor.done:
+	default:
+		t.Fatal("Expected the lag monitor to stop with the last Release")
+	}
+	if clock.Tickers() != 0 || len(factory.lagMonitors) != 0 {
+		t.Fatalf("Expected no running monitor, got %d tickers and %v", clock.Tickers(), factory.lagMonitors)
+	}
+}
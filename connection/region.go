@@ -0,0 +1,157 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hemant-dhiman/MySQL-connection/constants"
+	"gorm.io/gorm"
+	"log"
+	"os"
+)
+
+type regionKey struct{}
+
+// regionalName is the name of the managed connection serving a logical database in a region.
+func regionalName(logical, region string) string {
+	return logical + "@" + region
+}
+
+// InitRegionalConnection initializes the copy of a logical database served in a region. Once a logical
+// database has regional connections, GetDB and GetDBContext accept its logical name and return the
+// connection of the caller's region, failing over to the other regions, in registration order, when the
+// local one is unhealthy and cannot be reconnected.
+//
+// Parameters:
+// - logical: The logical database name, e.g. "orders". It must not be the name of a plain connection.
+// - region: The region name, e.g. "eu-west-1".
+// - config: The configuration of the regional connection, initialized as "logical@region". Its
+// Cluster.Region defaults to region.
+//
+// Returns:
+// - error: An error if the regional connection cannot be initialized.
+//
+// Notes:
+//   - The caller's region is the one set on the context with WithRegion, else the one set with
+//     SetLocalRegion, else the MYSQLCONN_REGION environment variable.
+//   - Closing the connection "logical@region" removes the region from the logical database, and the
+//     logical database along with its last region.
+//
+// Example Usage:
+//
+//	factory := connection.GetConnectionManager()
+//	_ = factory.InitRegionalConnection("orders", "eu-west-1", euConfig)
+//	_ = factory.InitRegionalConnection("orders", "us-east-1", usConfig)
+//	factory.SetLocalRegion("eu-west-1")
+//	db, err := factory.GetDB("orders") // orders@eu-west-1, or orders@us-east-1 when it is down
+func (f *ConnectionManager) InitRegionalConnection(logical, region string, config DBConfig) error {
+	if logical == "" || region == "" {
+		return errors.New("regional connection requires a logical name and a region")
+	}
+	f.mutex.Lock()
+	_, plain := f.connections[logical]
+	f.mutex.Unlock()
+	if plain {
+		return fmt.Errorf("%q is already a plain database connection", logical)
+	}
+	if config.Cluster.Region == "" {
+		config.Cluster.Region = region
+	}
+
+	if err := f.InitDataSourceConnection(regionalName(logical, region), config); err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.regions == nil {
+		f.regions = make(map[string][]string)
+	}
+	if !containsString(f.regions[logical], region) {
+		f.regions[logical] = append(f.regions[logical], region)
+	}
+	return nil
+}
+
+// SetLocalRegion sets the region of the process for regional connections.
+func (f *ConnectionManager) SetLocalRegion(region string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.localRegion = region
+}
+
+// Regions returns the regions of a logical database in registration order.
+func (f *ConnectionManager) Regions(logical string) []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.regions[logical]...)
+}
+
+// forgetRegion removes the region of a closed regional connection from its logical database, and the
+// logical database with its last region. The caller holds the mutex.
+func (f *ConnectionManager) forgetRegion(name string) {
+	for logical, regions := range f.regions {
+		var remaining []string
+		for _, region := range regions {
+			if regionalName(logical, region) != name {
+				remaining = append(remaining, region)
+			}
+		}
+		switch {
+		case len(remaining) == 0:
+			delete(f.regions, logical)
+		case len(remaining) < len(regions):
+			f.regions[logical] = remaining
+		}
+	}
+}
+
+// WithRegion returns a context routing regional connections to region, e.g. for a request pinned to
+// the region of its user.
+func WithRegion(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, regionKey{}, region)
+}
+
+// callerRegion returns the region regional connections are routed to for ctx.
+func (f *ConnectionManager) callerRegion(ctx context.Context) string {
+	if region, _ := ctx.Value(regionKey{}).(string); region != "" {
+		return region
+	}
+	f.mutex.Lock()
+	region := f.localRegion
+	f.mutex.Unlock()
+	if region != "" {
+		return region
+	}
+	return os.Getenv(constants.ENV_MYSQLCONN_REGION)
+}
+
+// regionalDB returns the connection of the first healthy region of a logical database, starting with the caller's.
+func (f *ConnectionManager) regionalDB(ctx context.Context, logical string, regions []string) (*gorm.DB, error) {
+	local := f.callerRegion(ctx)
+	ordered := make([]string, 0, len(regions))
+	if containsString(regions, local) {
+		ordered = append(ordered, local)
+	}
+	for _, region := range regions {
+		if region != local {
+			ordered = append(ordered, region)
+		}
+	}
+
+	var errs []error
+	for _, region := range ordered {
+		db, err := f.getDB(ctx, regionalName(logical, region))
+		if err == nil {
+			if region != local && local != "" {
+				log.Printf("Database %q is served from region %q instead of %q", logical, region, local)
+			}
+			return db, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, err)
+	}
+	return nil, fmt.Errorf("no healthy region of database %q: %w", logical, errors.Join(errs...))
+}
@@ -0,0 +1,93 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+)
+
+// failingConnector cannot reach its server.
+type failingConnector struct{}
+
+func (failingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (failingConnector) Driver() driver.Driver {
+	return nil
+}
+
+// newConnectorDB returns a GORM handle over connector, whose health checks succeed or fail with it.
+func newConnectorDB(t *testing.T, connector driver.Connector) *gorm.DB {
+	t.Helper()
+	sqlDB := sql.OpenDB(connector)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	return db
+}
+
+func TestRegionalRouting(t *testing.T) {
+	factory := newTestFactory()
+	eu := newConnectorDB(t, &fakeConnector{})
+	us := newConnectorDB(t, &fakeConnector{})
+	factory.connections[regionalName("orders", "eu")] = eu
+	factory.connections[regionalName("orders", "us")] = us
+	factory.regions = map[string][]string{"orders": {"eu", "us"}}
+
+	factory.SetLocalRegion("us")
+	if db, err := factory.GetDB("orders"); err != nil || db != us {
+		t.Fatalf("Expected the local region, got %v, %v", db, err)
+	}
+	if db, err := factory.GetDBContext(WithRegion(context.Background(), "eu"), "orders"); err != nil || db.Statement.ConnPool != eu.Statement.ConnPool {
+		t.Fatalf("Expected the context region, got %v", err)
+	}
+
+	// The local region is down and has no configuration to reconnect with.
+	factory.connections[regionalName("orders", "us")] = newConnectorDB(t, failingConnector{})
+	if db, err := factory.GetDB("orders"); err != nil || db != eu {
+		t.Fatalf("Expected failover to eu, got %v, %v", db, err)
+	}
+
+	factory.connections[regionalName("orders", "eu")] = newConnectorDB(t, failingConnector{})
+	if _, err := factory.GetDB("orders"); err == nil {
+		t.Fatal("Expected an error when no region is healthy, got nil")
+	}
+}
+
+func TestCloseRegionalConnection(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections[regionalName("orders", "eu")] = newConnectorDB(t, &fakeConnector{})
+	factory.connections[regionalName("orders", "us")] = newConnectorDB(t, &fakeConnector{})
+	factory.regions = map[string][]string{"orders": {"eu", "us"}}
+
+	if err := factory.CloseConnection(regionalName("orders", "eu")); err != nil {
+		t.Fatalf("CloseConnection failed: %v", err)
+	}
+	if regions := factory.Regions("orders"); len(regions) != 1 || regions[0] != "us" {
+		t.Fatalf("Expected only us left, got %v", regions)
+	}
+	if _, err := factory.GetDB("orders"); err != nil {
+		t.Fatalf("Expected orders to be served from us, got %v", err)
+	}
+
+	if err := factory.CloseConnection(regionalName("orders", "us")); err != nil {
+		t.Fatalf("CloseConnection failed: %v", err)
+	}
+	if _, regional := factory.regions["orders"]; regional {
+		t.Fatal("Expected the logical database to be removed with its last region")
+	}
+	if _, err := factory.GetDB("orders"); !errors.Is(err, ErrConnectionNotFound) {
+		t.Fatalf("Expected ErrConnectionNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,411 @@
+package connection
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults of ReplicaOptions.
+const (
+	defaultReplicaProbeInterval = time.Second
+	defaultReplicaFastest       = 2
+
+	// replicaLatencyWeight is the weight of a new probe in the moving average of replica latency.
+	replicaLatencyWeight = 0.3
+)
+
+// ReplicaOptions configure the read routing of SetReplicas.
+type ReplicaOptions struct {
+	// ProbeInterval is how often each replica is pinged to measure its latency. Defaults to one second.
+	ProbeInterval time.Duration
+
+	// Fastest is the number of lowest-latency replicas reads are spread over. Defaults to 2.
+	Fastest int
+
+	// MaxLag skips the replicas whose replication delay, measured by the LagMonitor of the primary,
+	// exceeds it. Zero routes reads regardless of lag.
+	MaxLag time.Duration
+
+	// ReadPreference is the preference of the reads of ReaderDB whose context sets none (see
+	// WithReadPreference). Defaults to ReadSecondaryPreferred.
+	ReadPreference ReadPreference
+}
+
+// ReplicaStat is the routing state of one replica.
+type ReplicaStat struct {
+	Name    string
+	Healthy bool
+
+	// Latency is the moving average of the replica's ping latency.
+	Latency time.Duration
+
+	// Lag is the replication delay measured by the LagMonitor of the primary, valid when LagMeasured is true.
+	Lag         time.Duration
+	LagMeasured bool
+
+	// Selections is the number of reads routed to the replica, and Share its fraction of all reads
+	// routed, including those served by the primary.
+	Selections int64
+	Share      float64
+}
+
+// replicaSet routes the reads of a primary connection to its replicas.
+type replicaSet struct {
+	primary  string
+	opts     ReplicaOptions
+	cancel   context.CancelFunc
+	done     chan struct{}
+	mutex    sync.Mutex
+	replicas []*replicaState
+
+	// primaryState is the latency of the primary, for ReadNearest, and primaryReads the number of reads
+	// it served.
+	primaryState *replicaState
+	primaryReads int64
+}
+
+type replicaState struct {
+	name       string
+	healthy    bool
+	latency    time.Duration
+	lag        time.Duration
+	lagKnown   bool
+	selections int64
+}
+
+// SetReplicas declares managed connections as read replicas of a primary connection and starts measuring
+// their latency. ReaderDB then routes reads to the fastest replicas instead of round-robin.
+//
+// Parameters:
+// - primary: The name of the managed primary connection.
+// - replicas: The names of the managed replica connections. An empty list removes the replicas.
+// - opts: The probe interval and the number of fastest replicas reads are spread over.
+//
+// Returns:
+// - error: An error if a connection does not exist.
+//
+// Behavior:
+// 1. Every ProbeInterval each replica is pinged; failures mark it unhealthy, successes update a moving
+// average of its latency.
+// 2. ReaderDB picks two random replicas among the Fastest healthy ones (power of two choices) and
+// returns the one with the lower latency weighted by its connections in use.
+// 3. With MaxLag and a running LagMonitor (see StartLagMonitor), replicas lagging more than MaxLag are
+// skipped.
+// 4. Without a usable replica, reads go to the primary.
+// 5. The read preference of the context (WithReadPreference), or else opts.ReadPreference, can send
+// reads to the primary instead; the primary is also pinged every ProbeInterval for ReadNearest.
+//
+// Example Usage:
+//
+//	factory := connection.GetConnectionManager()
+//	_ = factory.SetReplicas("orders", []string{"orders_replica_a", "orders_replica_b", "orders_replica_c"}, connection.ReplicaOptions{})
+//	db, err := factory.ReaderDB(ctx, "orders")
+func (f *ConnectionManager) SetReplicas(primary string, replicas []string, opts ReplicaOptions) error {
+	if opts.ProbeInterval <= 0 {
+		opts.ProbeInterval = defaultReplicaProbeInterval
+	}
+	if opts.Fastest <= 0 {
+		opts.Fastest = defaultReplicaFastest
+	}
+
+	f.mutex.Lock()
+	for _, name := range append([]string{primary}, replicas...) {
+		if _, exists := f.connections[name]; !exists {
+			f.mutex.Unlock()
+			return errNotFound(name, OpSetReplicas)
+		}
+	}
+	previous := f.replicas[primary]
+	delete(f.replicas, primary)
+	f.mutex.Unlock()
+	if previous != nil {
+		previous.stop()
+	}
+	if len(replicas) == 0 {
+		return nil
+	}
+
+	set := &replicaSet{primary: primary, opts: opts, done: make(chan struct{}), primaryState: &replicaState{name: primary}}
+	for _, name := range replicas {
+		set.replicas = append(set.replicas, &replicaState{name: name})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	set.cancel = cancel
+	set.probe(ctx, f)
+
+	f.mutex.Lock()
+	if f.replicas == nil {
+		f.replicas = make(map[string]*replicaSet)
+	}
+	f.replicas[primary] = set
+	f.mutex.Unlock()
+
+	go func() {
+		defer close(set.done)
+		ticker := f.clock().NewTicker(opts.ProbeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				set.probe(ctx, f)
+			}
+		}
+	}()
+	return nil
+}
+
+// ReaderDB returns a connection for reads of the primary connection, bound to ctx. By default it is one
+// of the fastest usable replicas, or the primary when it has none (see SetReplicas); the read preference
+// of ctx (WithReadPreference) or of the connection (ReplicaOptions.ReadPreference) changes the choice:
+//
+//   - ReadPrimary: the primary.
+//   - ReadPrimaryPreferred: the primary, or a replica when the primary cannot be reached.
+//   - ReadSecondary: a replica, or an error wrapping ErrNoReplica.
+//   - ReadSecondaryPreferred: a replica, or the primary.
+//   - ReadNearest: the primary when its latency is below the latency of every usable replica, or a replica.
+//
+// A connection without replicas serves every preference but ReadSecondary from the primary.
+func (f *ConnectionManager) ReaderDB(ctx context.Context, primary string) (*gorm.DB, error) {
+	f.mutex.Lock()
+	set := f.replicas[primary]
+	f.mutex.Unlock()
+
+	pref := readPreference(ctx, set)
+	switch {
+	case pref == ReadPrimary, pref == ReadNearest && set.primaryNearest():
+		return f.primaryRead(ctx, set, primary)
+	case pref == ReadPrimaryPreferred:
+		db, err := f.primaryRead(ctx, set, primary)
+		if err == nil || ctx.Err() != nil {
+			return db, err
+		}
+		if replica, replicaErr := f.replicaRead(ctx, set); replica != nil || replicaErr != nil {
+			return replica, replicaErr
+		}
+		return nil, err
+	}
+
+	db, err := f.replicaRead(ctx, set)
+	if db != nil || err != nil {
+		return db, err
+	}
+	if pref == ReadSecondary {
+		return nil, connError(primary, OpReaderDB, ErrNoReplica)
+	}
+	return f.primaryRead(ctx, set, primary)
+}
+
+// primaryRead returns the primary for a read, counting it in the routing statistics of set.
+func (f *ConnectionManager) primaryRead(ctx context.Context, set *replicaSet, primary string) (*gorm.DB, error) {
+	db, err := f.GetDBContext(ctx, primary)
+	if err == nil && set != nil {
+		set.mutex.Lock()
+		set.primaryReads++
+		set.mutex.Unlock()
+	}
+	return db, err
+}
+
+// replicaRead returns a usable replica of set for a read. Both results are nil when no replica can
+// serve it; the error is the one of ctx ending.
+func (f *ConnectionManager) replicaRead(ctx context.Context, set *replicaSet) (*gorm.DB, error) {
+	if set == nil {
+		return nil, nil
+	}
+	if name := set.pick(f); name != "" {
+		db, err := f.GetDBContext(ctx, name)
+		if err == nil || ctx.Err() != nil {
+			return db, err
+		}
+	}
+	return nil, nil
+}
+
+// ReplicaStats returns the routing state of the replicas of a primary connection.
+func (f *ConnectionManager) ReplicaStats(primary string) []ReplicaStat {
+	f.mutex.Lock()
+	set := f.replicas[primary]
+	f.mutex.Unlock()
+	if set == nil {
+		return nil
+	}
+
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	total := set.primaryReads
+	for _, replica := range set.replicas {
+		total += replica.selections
+	}
+	stats := make([]ReplicaStat, 0, len(set.replicas))
+	for _, replica := range set.replicas {
+		stat := ReplicaStat{Name: replica.name, Healthy: replica.healthy, Latency: replica.latency,
+			Lag: replica.lag, LagMeasured: replica.lagKnown, Selections: replica.selections}
+		if total > 0 {
+			stat.Share = float64(replica.selections) / float64(total)
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// probe pings the primary and every replica once and updates their health and latency.
+func (s *replicaSet) probe(ctx context.Context, f *ConnectionManager) {
+	for _, replica := range append([]*replicaState{s.primaryState}, s.replicas...) {
+		f.mutex.Lock()
+		db, exists := f.connections[replica.name]
+		f.mutex.Unlock()
+
+		latency, err := time.Duration(0), errNotFound(replica.name, OpProbeReplica)
+		if exists {
+			latency, err = pingLatency(ctx, db, s.opts.ProbeInterval)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		s.mutex.Lock()
+		if err != nil {
+			if replica.healthy && replica != s.primaryState {
+				log.Printf("Replica %q of %q is unhealthy: %v", replica.name, s.primary, err)
+			}
+			replica.healthy = false
+		} else {
+			if !replica.healthy || replica.latency == 0 {
+				replica.latency = latency
+			} else {
+				replica.latency = time.Duration(replicaLatencyWeight*float64(latency) + (1-replicaLatencyWeight)*float64(replica.latency))
+			}
+			replica.healthy = true
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// pingLatency measures one ping of db.
+func pingLatency(ctx context.Context, db *gorm.DB, timeout time.Duration) (time.Duration, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// primaryNearest reports whether the primary of s is healthy and has a latency below the latency of
+// every usable replica.
+func (s *replicaSet) primaryNearest() bool {
+	if s == nil {
+		return false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.primaryState.healthy {
+		return false
+	}
+	for _, replica := range s.replicas {
+		if s.usable(replica) && replica.latency <= s.primaryState.latency {
+			return false
+		}
+	}
+	return true
+}
+
+// setLags records the lags measured by a LagMonitor. Replicas missing from lags have no known lag.
+func (s *replicaSet) setLags(lags map[string]ReplicaLag) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, replica := range s.replicas {
+		lag := lags[replica.name]
+		replica.lag, replica.lagKnown = lag.Lag, lag.Measured
+	}
+}
+
+// usable reports whether reads can be routed to a replica: it is healthy and not lagging more than MaxLag.
+func (s *replicaSet) usable(replica *replicaState) bool {
+	return replica.healthy && !(s.opts.MaxLag > 0 && replica.lagKnown && replica.lag > s.opts.MaxLag)
+}
+
+// pick chooses a replica with the power of two choices among the fastest usable replicas, or returns ""
+// when none is usable.
+func (s *replicaSet) pick(f *ConnectionManager) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var candidates []*replicaState
+	for _, replica := range s.replicas {
+		if s.usable(replica) {
+			candidates = append(candidates, replica)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].latency < candidates[j].latency })
+	if len(candidates) > s.opts.Fastest {
+		candidates = candidates[:s.opts.Fastest]
+	}
+
+	clock := f.clock()
+	chosen := candidates[randIntn(clock, len(candidates))]
+	if len(candidates) > 1 {
+		i := randIntn(clock, len(candidates))
+		j := randIntn(clock, len(candidates)-1)
+		if j >= i {
+			j++
+		}
+		a, b := candidates[i], candidates[j]
+		chosen = a
+		if f.replicaLoad(b) < f.replicaLoad(a) {
+			chosen = b
+		}
+	}
+	chosen.selections++
+	return chosen.name
+}
+
+// replicaLoad scores a replica by its latency weighted by the connections it has in use.
+func (f *ConnectionManager) replicaLoad(replica *replicaState) float64 {
+	inUse := 0
+	f.mutex.Lock()
+	db := f.connections[replica.name]
+	f.mutex.Unlock()
+	if db != nil {
+		if sqlDB, err := db.DB(); err == nil {
+			inUse = sqlDB.Stats().InUse
+		}
+	}
+	return float64(replica.latency) * float64(inUse+1)
+}
+
+// stop ends the latency probes.
+func (s *replicaSet) stop() {
+	s.cancel()
+	<-s.done
+}
+
+// replicaStats returns the stats of the replicas of every primary with replicas.
+func (f *ConnectionManager) replicaStats() []ReplicaStat {
+	f.mutex.Lock()
+	primaries := make([]string, 0, len(f.replicas))
+	for primary := range f.replicas {
+		primaries = append(primaries, primary)
+	}
+	f.mutex.Unlock()
+
+	var stats []ReplicaStat
+	for _, primary := range primaries {
+		stats = append(stats, f.ReplicaStats(primary)...)
+	}
+	return stats
+}
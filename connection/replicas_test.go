@@ -0,0 +1,64 @@
+package connection
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReaderDBRoutesToHealthyReplicas(t *testing.T) {
+	factory := newTestFactory()
+	primary := newConnectorDB(t, &fakeConnector{})
+	factory.connections["orders"] = primary
+	factory.connections["replica_a"] = newConnectorDB(t, &fakeConnector{})
+	factory.connections["replica_b"] = newConnectorDB(t, &fakeConnector{})
+	factory.connections["replica_down"] = newConnectorDB(t, failingConnector{})
+
+	if err := factory.SetReplicas("orders", []string{"replica_a", "missing"}, ReplicaOptions{}); err == nil {
+		t.Fatal("Expected an error for a non-existent replica, got nil")
+	}
+	err := factory.SetReplicas("orders", []string{"replica_a", "replica_b", "replica_down"}, ReplicaOptions{ProbeInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer factory.SetReplicas("orders", nil, ReplicaOptions{})
+
+	ctx := context.Background()
+	for i := 0; i < 50; i++ {
+		db, err := factory.ReaderDB(ctx, "orders")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if db.Statement.ConnPool == primary.Statement.ConnPool {
+			t.Fatal("Expected reads to go to a replica")
+		}
+	}
+
+	var total int64
+	for _, stat := range factory.ReplicaStats("orders") {
+		if stat.Name == "replica_down" && (stat.Healthy || stat.Selections > 0) {
+			t.Fatalf("Expected the failing replica to be skipped, got %+v", stat)
+		}
+		total += stat.Selections
+	}
+	if total != 50 {
+		t.Fatalf("Expected 50 routed reads, got %d", total)
+	}
+}
+
+func TestReaderDBFallsBackToPrimary(t *testing.T) {
+	factory := newTestFactory()
+	primary := newConnectorDB(t, &fakeConnector{})
+	factory.connections["orders"] = primary
+	factory.connections["replica_down"] = newConnectorDB(t, failingConnector{})
+
+	if err := factory.SetReplicas("orders", []string{"replica_down"}, ReplicaOptions{ProbeInterval: time.Hour}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer factory.SetReplicas("orders", nil, ReplicaOptions{})
+
+	db, err := factory.ReaderDB(context.Background(), "orders")
+	if err != nil || db.Statement.ConnPool != primary.Statement.ConnPool {
+		t.Fatalf("Expected the primary, got %v", err)
+	}
+}
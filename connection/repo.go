@@ -0,0 +1,178 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sync"
+	"time"
+)
+
+// Repo is a small typed repository over a named connection managed by ConnectionManager.
+// It covers the CRUD boilerplate that otherwise gets copy-pasted around GetDB:
+// every call resolves the connection through the factory (so health checks and
+// reconnects still apply), binds the caller's context and records per-operation metrics.
+// Operations join the transaction of their context started with BeginTx.
+//
+// T must be a GORM model with a primary key.
+type Repo[T any] struct {
+	// factory is the connection factory the repository resolves its connection from.
+	factory *ConnectionManager
+
+	// name is the connection name passed to GetDB on every operation.
+	name string
+
+	// metrics holds call counters and latencies per repository operation.
+	metrics repoMetrics
+}
+
+// RepoOpStats contains the counters recorded for a single repository operation.
+type RepoOpStats struct {
+	// Calls is the number of times the operation was invoked.
+	Calls int64
+
+	// Errors is the number of invocations that returned an error.
+	Errors int64
+
+	// TotalDuration is the accumulated wall time spent in the operation.
+	TotalDuration time.Duration
+}
+
+type repoMetrics struct {
+	mutex sync.Mutex
+	ops   map[string]RepoOpStats
+}
+
+// NewRepo creates a typed repository for model T on the connection connName of factory.
+//
+// Example Usage:
+//
+//	users := connection.NewRepo[User](connection.GetConnectionManager(), "primary_db")
+//	user, err := users.Get(ctx, 42)
+func NewRepo[T any](factory *ConnectionManager, connName string) *Repo[T] {
+	return &Repo[T]{
+		factory: factory,
+		name:    connName,
+		metrics: repoMetrics{ops: make(map[string]RepoOpStats)},
+	}
+}
+
+// Get loads the record whose primary key equals id.
+// gorm.ErrRecordNotFound is returned (wrapped) when no such record exists.
+func (r *Repo[T]) Get(ctx context.Context, id any) (*T, error) {
+	var out T
+	err := r.run(ctx, "get", func(db *gorm.DB) error {
+		return db.Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).First(&out).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// List returns up to limit records starting at offset, ordered by primary key so that
+// consecutive pages are stable.
+func (r *Repo[T]) List(ctx context.Context, offset, limit int) ([]T, error) {
+	if offset < 0 || limit <= 0 {
+		return nil, fmt.Errorf("repo %q: invalid page offset=%d limit=%d", r.name, offset, limit)
+	}
+
+	var out []T
+	err := r.run(ctx, "list", func(db *gorm.DB) error {
+		return db.Order(clause.OrderByColumn{Column: clause.PrimaryColumn}).
+			Offset(offset).Limit(limit).Find(&out).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Page returns one page of records using Paginate. An empty req.OrderBy defaults to the
+// primary key columns of T.
+func (r *Repo[T]) Page(ctx context.Context, req PageRequest) (PageResponse[T], error) {
+	var resp PageResponse[T]
+	err := r.run(ctx, "page", func(db *gorm.DB) error {
+		if len(req.OrderBy) == 0 {
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(new(T)); err != nil {
+				return err
+			}
+			for _, field := range stmt.Schema.PrimaryFields {
+				req.OrderBy = append(req.OrderBy, field.DBName)
+			}
+		}
+
+		var err error
+		resp, err = Paginate[T](ctx, db, req)
+		return err
+	})
+	return resp, err
+}
+
+// Create inserts entity. Generated fields (e.g. auto-increment keys) are written back into it.
+func (r *Repo[T]) Create(ctx context.Context, entity *T) error {
+	return r.run(ctx, "create", func(db *gorm.DB) error {
+		return db.Create(entity).Error
+	})
+}
+
+// Update saves all fields of entity, inserting it when the primary key is zero.
+func (r *Repo[T]) Update(ctx context.Context, entity *T) error {
+	return r.run(ctx, "update", func(db *gorm.DB) error {
+		// The model bound by run has a zero primary key; Save must target entity's.
+		return db.Model(entity).Save(entity).Error
+	})
+}
+
+// Delete removes the record whose primary key equals id.
+// Deleting a record that does not exist is not an error.
+func (r *Repo[T]) Delete(ctx context.Context, id any) error {
+	return r.run(ctx, "delete", func(db *gorm.DB) error {
+		return db.Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Delete(new(T)).Error
+	})
+}
+
+// Metrics returns a snapshot of the per-operation counters, keyed by operation
+// name ("get", "list", "page", "create", "update", "delete").
+func (r *Repo[T]) Metrics() map[string]RepoOpStats {
+	r.metrics.mutex.Lock()
+	defer r.metrics.mutex.Unlock()
+
+	snapshot := make(map[string]RepoOpStats, len(r.metrics.ops))
+	for op, stats := range r.metrics.ops {
+		snapshot[op] = stats
+	}
+	return snapshot
+}
+
+// run resolves the managed connection, or the transaction on it carried by ctx, binds ctx and the
+// model, executes fn and records metrics.
+func (r *Repo[T]) run(ctx context.Context, op string, fn func(db *gorm.DB) error) error {
+	start := time.Now()
+
+	db, err := r.factory.CurrentDB(ctx, r.name)
+	if err == nil {
+		err = fn(db.Model(new(T)))
+	}
+	r.metrics.record(op, time.Since(start), err)
+
+	if err != nil {
+		return fmt.Errorf("repo %q: %s failed: %w", r.name, op, err)
+	}
+	return nil
+}
+
+func (m *repoMetrics) record(op string, elapsed time.Duration, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	stats := m.ops[op]
+	stats.Calls++
+	stats.TotalDuration += elapsed
+	if err != nil {
+		stats.Errors++
+	}
+	m.ops[op] = stats
+}
@@ -0,0 +1,84 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+)
+
+type repoTestUser struct {
+	ID   uint
+	Name string
+}
+
+// newTestFactory returns an empty factory that is independent of the package singleton.
+func newTestFactory() *ConnectionManager {
+	return &ConnectionManager{
+		connections: make(map[string]*gorm.DB),
+		configs:     make(map[string]DBConfig),
+	}
+}
+
+func TestRepoUnknownConnection(t *testing.T) {
+	repo := NewRepo[repoTestUser](newTestFactory(), "missing_db")
+	ctx := context.Background()
+
+	if _, err := repo.Get(ctx, 1); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+	if err := repo.Create(ctx, &repoTestUser{Name: "a"}); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+	if _, err := repo.List(ctx, 0, 10); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+
+	metrics := repo.Metrics()
+	for _, op := range []string{"get", "create", "list"} {
+		if metrics[op].Calls != 1 || metrics[op].Errors != 1 {
+			t.Fatalf("Unexpected metrics for %q: %+v", op, metrics[op])
+		}
+	}
+	if _, ok := metrics["delete"]; ok {
+		t.Fatal("Expected no metrics for an operation that was never called")
+	}
+}
+
+func TestRepoListValidatesPage(t *testing.T) {
+	repo := NewRepo[repoTestUser](newTestFactory(), "missing_db")
+
+	_, err := repo.List(context.Background(), -1, 10)
+	if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("Expected a page validation error, got %v", err)
+	}
+	if len(repo.Metrics()) != 0 {
+		t.Fatal("Expected invalid pages to be rejected before any operation is recorded")
+	}
+}
+
+func TestGetDBContextUnknownConnection(t *testing.T) {
+	var provider DBProvider = newTestFactory()
+	if _, err := provider.GetDBContext(context.Background(), "missing_db"); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
+
+func TestRepoUpdateTargetsEntity(t *testing.T) {
+	db, _, pool := newRecordingDB(t, "users_db")
+	factory := newTestFactory()
+	factory.connections["users_db"] = db
+	repo := NewRepo[repoTestUser](factory, "users_db")
+
+	// Run as within a transaction, so the recording handle is used without a health check.
+	ctx := context.WithValue(context.Background(), ctxTxKey{"users_db"}, db)
+	if err := repo.Update(ctx, &repoTestUser{ID: 5, Name: "eve"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	statements := pool.recorded()
+	if len(statements) != 1 || !strings.HasPrefix(statements[0], "UPDATE `repo_test_users`") ||
+		!strings.Contains(statements[0], "WHERE `id` = ?") {
+		t.Fatalf("Expected an UPDATE of the entity's primary key, got %q", statements)
+	}
+}
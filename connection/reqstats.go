@@ -0,0 +1,139 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// requestStatsPluginName is the name under which the per-request statistics are registered in gorm.Config.Plugins.
+const requestStatsPluginName = "mysqlconn:request_stats"
+
+// requestStatsStartSetting is the instance setting holding the start time of a statement.
+const requestStatsStartSetting = "mysqlconn:request_stats_start"
+
+// RequestStats counts the statements issued with one request context, see WithRequestStats.
+type RequestStats struct {
+	// Queries is the number of statements, on any connection.
+	Queries int
+
+	// Errors is the number of statements that failed. Record not found is not an error.
+	Errors int
+
+	// Duration is the total time spent in statements.
+	Duration time.Duration
+
+	// ByConnection is the number of statements by connection name.
+	ByConnection map[string]int
+}
+
+type requestStatsKey struct{}
+
+// requestStatsCollector accumulates the RequestStats of one context.
+type requestStatsCollector struct {
+	mutex sync.Mutex
+	stats RequestStats
+}
+
+// WithRequestStats returns a context collecting the number and duration of the statements issued with
+// it (db.WithContext(ctx)) through GORM on managed connections. Read them with RequestStatsFrom.
+//
+// Example Usage:
+//
+//	ctx := connection.WithRequestStats(r.Context())
+//	handle(ctx)
+//	stats, _ := connection.RequestStatsFrom(ctx)
+//	log.Printf("%s: %d queries in %v", r.URL.Path, stats.Queries, stats.Duration)
+func WithRequestStats(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestStatsKey{}, &requestStatsCollector{})
+}
+
+// RequestStatsFrom returns the statistics collected so far for ctx. ok is false when ctx does not collect them.
+func RequestStatsFrom(ctx context.Context) (stats RequestStats, ok bool) {
+	collector, _ := ctx.Value(requestStatsKey{}).(*requestStatsCollector)
+	if collector == nil {
+		return RequestStats{}, false
+	}
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	stats = collector.stats
+	stats.ByConnection = make(map[string]int, len(collector.stats.ByConnection))
+	for name, n := range collector.stats.ByConnection {
+		stats.ByConnection[name] = n
+	}
+	return stats, true
+}
+
+// requestStatsPlugin records the statements of the named connection in the collector of their context.
+type requestStatsPlugin struct {
+	name string
+}
+
+func (requestStatsPlugin) Name() string {
+	return requestStatsPluginName
+}
+
+func (p requestStatsPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	processors := []struct {
+		operation     string
+		before, after func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", cb.Create().Before("*").Register, cb.Create().After("*").Register},
+		{"query", cb.Query().Before("*").Register, cb.Query().After("*").Register},
+		{"update", cb.Update().Before("*").Register, cb.Update().After("*").Register},
+		{"delete", cb.Delete().Before("*").Register, cb.Delete().After("*").Register},
+		{"row", cb.Row().Before("*").Register, cb.Row().After("*").Register},
+		{"raw", cb.Raw().Before("*").Register, cb.Raw().After("*").Register},
+	}
+	for _, proc := range processors {
+		if err := proc.before(requestStatsPluginName+"_before_"+proc.operation, p.start); err != nil {
+			return err
+		}
+		if err := proc.after(requestStatsPluginName+"_after_"+proc.operation, p.record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (requestStatsPlugin) start(db *gorm.DB) {
+	if collectorOf(db) != nil {
+		db.InstanceSet(requestStatsStartSetting, time.Now())
+	}
+}
+
+func (p requestStatsPlugin) record(db *gorm.DB) {
+	collector := collectorOf(db)
+	if collector == nil {
+		return
+	}
+	var elapsed time.Duration
+	if value, ok := db.InstanceGet(requestStatsStartSetting); ok {
+		started, _ := value.(time.Time)
+		elapsed = time.Since(started)
+	}
+
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.stats.Queries++
+	collector.stats.Duration += elapsed
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		collector.stats.Errors++
+	}
+	if collector.stats.ByConnection == nil {
+		collector.stats.ByConnection = make(map[string]int)
+	}
+	collector.stats.ByConnection[p.name]++
+}
+
+// collectorOf returns the collector of the statement context of db, or nil.
+func collectorOf(db *gorm.DB) *requestStatsCollector {
+	if db.Statement.Context == nil {
+		return nil
+	}
+	collector, _ := db.Statement.Context.Value(requestStatsKey{}).(*requestStatsCollector)
+	return collector
+}
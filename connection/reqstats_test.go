@@ -0,0 +1,33 @@
+package connection
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRequestStats(t *testing.T) {
+	db := newConnectorDB(t, &fakeConnector{})
+	if err := db.Use(requestStatsPlugin{name: "primary_db"}); err != nil {
+		t.Fatalf("Use failed: %v", err)
+	}
+	if _, ok := RequestStatsFrom(context.Background()); ok {
+		t.Fatal("Expected no statistics without WithRequestStats")
+	}
+
+	ctx := WithRequestStats(context.Background())
+	var id int64
+	for i := 0; i < 2; i++ {
+		if err := db.WithContext(ctx).Raw("SELECT CONNECTION_ID()").Scan(&id).Error; err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+	}
+	_ = db.WithContext(ctx).Exec("DELETE FROM users").Error
+	if err := db.Raw("SELECT CONNECTION_ID()").Scan(&id).Error; err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	stats, ok := RequestStatsFrom(ctx)
+	if !ok || stats.Queries != 3 || stats.Errors != 1 || stats.ByConnection["primary_db"] != 3 || stats.Duration <= 0 {
+		t.Fatalf("Unexpected statistics %+v", stats)
+	}
+}
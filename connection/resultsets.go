@@ -0,0 +1,115 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gorm.io/gorm"
+	"reflect"
+)
+
+// ResultSets iterates the result sets of a statement returning several of them, such as a procedure
+// call or a multi-statement query. It starts on the first result set.
+type ResultSets struct {
+	db   *gorm.DB
+	rows *sql.Rows
+}
+
+// QueryResultSets runs a statement returning several result sets on a managed connection.
+//
+// Parameters:
+// - ctx: Context bounding the statement and the reading of its results.
+// - name: The name of the managed connection.
+// - query: The statement, e.g. "CALL order_report(?)". Several statements separated by ";" need
+// multiStatements=true in the data source name and cannot have placeholders.
+// - args: The placeholder arguments.
+//
+// Returns:
+// - *ResultSets: The result sets, positioned on the first one. The caller must Close it.
+// - error: An error if the connection does not exist or the statement fails.
+//
+// Behavior:
+// The statement runs through GORM like Raw, so the hooks of the connection apply and its duration is
+// recorded by the metrics of RegisterOTelMetrics. It holds one pooled connection until Close.
+//
+// Example Usage:
+//
+//	sets, err := connection.GetConnectionManager().QueryResultSets(ctx, "primary_db", "CALL order_report(?)", 42)
+//	if err != nil {
+//		return err
+//	}
+//	defer sets.Close()
+//	var orders []Order
+//	var lines []OrderLine
+//	err = sets.ScanAll(&orders, &lines)
+func (f *ConnectionManager) QueryResultSets(ctx context.Context, name, query string, args ...interface{}) (*ResultSets, error) {
+	db, err := f.GetDBContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Raw(query, args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	return &ResultSets{db: db, rows: rows}, nil
+}
+
+// Scan reads every row of the current result set into dest, a pointer to a slice of structs, maps or
+// scalars, with GORM's column mapping. It does not advance to the next result set.
+func (r *ResultSets) Scan(dest interface{}) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Pointer || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("result set destination must be a pointer to a slice, got %T", dest)
+	}
+	slice = slice.Elem()
+	slice.SetLen(0)
+	for r.rows.Next() {
+		item := reflect.New(slice.Type().Elem())
+		if err := r.db.ScanRows(r.rows, item.Interface()); err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, item.Elem()))
+	}
+	return r.rows.Err()
+}
+
+// NextResultSet advances to the next result set and reports whether there is one. Unread rows of the
+// current result set are skipped.
+func (r *ResultSets) NextResultSet() bool {
+	return r.rows.NextResultSet()
+}
+
+// ScanAll reads the result sets into dests in order, one destination per result set, like Scan.
+// A nil destination skips its result set. It fails if the statement returned fewer result sets than dests.
+func (r *ResultSets) ScanAll(dests ...interface{}) error {
+	for i, dest := range dests {
+		if i > 0 && !r.rows.NextResultSet() {
+			if err := r.rows.Err(); err != nil {
+				return err
+			}
+			return fmt.Errorf("statement returned %d result sets, expected %d", i, len(dests))
+		}
+		if dest == nil {
+			continue
+		}
+		if err := r.Scan(dest); err != nil {
+			return fmt.Errorf("failed to scan result set %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Columns returns the column names of the current result set.
+func (r *ResultSets) Columns() ([]string, error) {
+	return r.rows.Columns()
+}
+
+// Err returns the error met while iterating, if any.
+func (r *ResultSets) Err() error {
+	return r.rows.Err()
+}
+
+// Close releases the pooled connection. Remaining result sets are discarded.
+func (r *ResultSets) Close() error {
+	return r.rows.Close()
+}
@@ -0,0 +1,106 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"testing"
+)
+
+// multiConnector opens connections whose queries return two result sets: users, then order totals.
+type multiConnector struct{}
+
+func (multiConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &multiConn{}, nil
+}
+
+func (multiConnector) Driver() driver.Driver {
+	return nil
+}
+
+type multiConn struct {
+	fakeConn
+}
+
+func (*multiConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &multiRows{sets: []resultSet{
+		{columns: []string{"id", "name"}, rows: [][]driver.Value{{int64(1), "ana"}, {int64(2), "bo"}}},
+		{columns: []string{"total"}, rows: [][]driver.Value{{int64(30)}}},
+	}}, nil
+}
+
+type resultSet struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+// multiRows implements driver.RowsNextResultSet over fixed result sets.
+type multiRows struct {
+	sets []resultSet
+}
+
+func (r *multiRows) Columns() []string {
+	return r.sets[0].columns
+}
+
+func (r *multiRows) Close() error {
+	return nil
+}
+
+func (r *multiRows) Next(dest []driver.Value) error {
+	if len(r.sets[0].rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.sets[0].rows[0])
+	r.sets[0].rows = r.sets[0].rows[1:]
+	return nil
+}
+
+func (r *multiRows) HasNextResultSet() bool {
+	return len(r.sets) > 1
+}
+
+func (r *multiRows) NextResultSet() error {
+	if len(r.sets) < 2 {
+		return io.EOF
+	}
+	r.sets = r.sets[1:]
+	return nil
+}
+
+func TestResultSets(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["report_db"] = newConnectorDB(t, multiConnector{})
+	ctx := context.Background()
+
+	sets, err := factory.QueryResultSets(ctx, "report_db", "CALL report(?)", 7)
+	if err != nil {
+		t.Fatalf("QueryResultSets failed: %v", err)
+	}
+	var users []repoTestUser
+	var totals []int
+	if err := sets.ScanAll(&users, &totals); err != nil {
+		t.Fatalf("ScanAll failed: %v", err)
+	}
+	_ = sets.Close()
+	if len(users) != 2 || users[1].Name != "bo" || len(totals) != 1 || totals[0] != 30 {
+		t.Fatalf("Unexpected result sets: %+v, %v", users, totals)
+	}
+
+	sets, err = factory.QueryResultSets(ctx, "report_db", "CALL report(?)", 7)
+	if err != nil {
+		t.Fatalf("QueryResultSets failed: %v", err)
+	}
+	defer sets.Close()
+	var rows []map[string]interface{}
+	if err := sets.ScanAll(nil, &rows, &totals); err == nil {
+		t.Fatal("Expected an error for a missing third result set, got nil")
+	}
+	if len(rows) != 1 || rows[0]["total"] != int64(30) {
+		t.Fatalf("Expected the second result set as maps, got %v", rows)
+	}
+
+	if _, err := factory.QueryResultSets(ctx, "missing_db", "CALL report()"); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
@@ -0,0 +1,273 @@
+package connection
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"log"
+	"sort"
+	"sync"
+)
+
+// rowsAffectedPluginName is the name under which the rows affected accounting is registered in gorm.Config.Plugins.
+const rowsAffectedPluginName = "mysqlconn:rows_affected"
+
+// ErrRowsAffectedNotTracked is returned by RowsAffectedStats for a connection without DBConfig.TrackRowsAffected.
+var ErrRowsAffectedNotTracked = errors.New("rows affected are not tracked")
+
+// rowsAffectedTop is the number of statements listed in RowsAffectedStats.Top.
+const rowsAffectedTop = 20
+
+// maxRowsAffectedStatements is the number of distinct statements accounted per connection. The rows of
+// further statements only count in the totals and in their table.
+const maxRowsAffectedStatements = 1000
+
+// RowsAffectedStats accounts the rows written by the statements of a connection with
+// DBConfig.TrackRowsAffected, see RowsAffectedStats.
+type RowsAffectedStats struct {
+	// Statements is the number of write statements that succeeded, and Rows the rows they affected.
+	Statements int64
+	Rows       int64
+
+	// Tables accounts the rows by table, largest writes first. Statements whose table cannot be told
+	// from their SQL are accounted under "".
+	Tables []TableRowsAffected
+
+	// Top lists the statements that affected the most rows in total, largest first, at most 20.
+	Top []StatementRowsAffected
+}
+
+// TableRowsAffected accounts the rows written to one table.
+type TableRowsAffected struct {
+	Table string
+
+	// Inserted, Updated and Deleted are the rows affected by INSERT (REPLACE included), UPDATE and
+	// DELETE statements. MySQL counts a row updated by INSERT ... ON DUPLICATE KEY UPDATE twice.
+	Inserted int64
+	Updated  int64
+	Deleted  int64
+
+	Statements int64
+}
+
+// StatementRowsAffected accounts the rows affected by one statement, by fingerprint.
+type StatementRowsAffected struct {
+	Fingerprint string
+	Table       string
+	Executions  int64
+	Rows        int64
+
+	// MaxRows is the most rows one execution affected, telling a runaway UPDATE or DELETE apart from a
+	// statement run often.
+	MaxRows int64
+}
+
+// rowsAffectedTracker accumulates the RowsAffectedStats of one connection.
+type rowsAffectedTracker struct {
+	mutex      sync.Mutex
+	statements int64
+	rows       int64
+	tables     map[string]*TableRowsAffected
+	top        map[string]*StatementRowsAffected
+}
+
+// RowsAffectedStats returns the rows written through a connection since it was initialized with
+// DBConfig.TrackRowsAffected, or since ResetRowsAffectedStats.
+//
+// Parameters:
+// - name: The name of the connection.
+//
+// Returns:
+// - RowsAffectedStats: The rows affected by table and by statement.
+// - error: An error if the connection does not exist or does not track the rows affected.
+//
+// Notes:
+//   - Rows are taken from the result of the statements run through GORM (Create, Save, Update, Delete,
+//     Exec), including those of transactions, whether these commit or not. Statements run through
+//     GetSQLDB are not accounted.
+//   - The table of a statement is the table of its model, or the one it writes to according to its SQL.
+//
+// Example Usage:
+//
+//	stats, err := factory.RowsAffectedStats("primary_db")
+//	if err != nil {
+//		return err
+//	}
+//	for _, statement := range stats.Top {
+//		if statement.MaxRows > 10000 {
+//			log.Printf("%s wrote %d rows at once to %s", statement.Fingerprint, statement.MaxRows, statement.Table)
+//		}
+//	}
+func (f *ConnectionManager) RowsAffectedStats(name string) (RowsAffectedStats, error) {
+	f.mutex.Lock()
+	_, exists := f.connections[name]
+	tracker := f.rowsAffected[name]
+	f.mutex.Unlock()
+	if !exists {
+		return RowsAffectedStats{}, errNotFound(name, OpRowsAffectedStats)
+	}
+	if tracker == nil {
+		return RowsAffectedStats{}, connError(name, OpRowsAffectedStats, ErrRowsAffectedNotTracked)
+	}
+	return tracker.stats(), nil
+}
+
+// ResetRowsAffectedStats clears the rows affected accounted for a connection, e.g. at the start of a
+// capacity planning period.
+func (f *ConnectionManager) ResetRowsAffectedStats(name string) {
+	f.mutex.Lock()
+	tracker := f.rowsAffected[name]
+	f.mutex.Unlock()
+	if tracker != nil {
+		tracker.reset()
+	}
+}
+
+// rowsAffectedTracker returns the tracker of the named connection, creating it. The caller holds the mutex.
+func (f *ConnectionManager) rowsAffectedTracker(name string) *rowsAffectedTracker {
+	if f.rowsAffected == nil {
+		f.rowsAffected = make(map[string]*rowsAffectedTracker)
+	}
+	tracker := f.rowsAffected[name]
+	if tracker == nil {
+		tracker = &rowsAffectedTracker{}
+		f.rowsAffected[name] = tracker
+	}
+	return tracker
+}
+
+func (t *rowsAffectedTracker) add(table, kind, fingerprint string, rows int64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.statements++
+	t.rows += rows
+
+	if t.tables == nil {
+		t.tables = make(map[string]*TableRowsAffected)
+	}
+	tableStats := t.tables[table]
+	if tableStats == nil {
+		tableStats = &TableRowsAffected{Table: table}
+		t.tables[table] = tableStats
+	}
+	tableStats.Statements++
+	switch kind {
+	case "INSERT", "REPLACE":
+		tableStats.Inserted += rows
+	case "UPDATE":
+		tableStats.Updated += rows
+	case "DELETE":
+		tableStats.Deleted += rows
+	}
+
+	if t.top == nil {
+		t.top = make(map[string]*StatementRowsAffected)
+	}
+	statement := t.top[fingerprint]
+	if statement == nil {
+		if len(t.top) >= maxRowsAffectedStatements {
+			return
+		}
+		statement = &StatementRowsAffected{Fingerprint: fingerprint, Table: table}
+		t.top[fingerprint] = statement
+	}
+	statement.Executions++
+	statement.Rows += rows
+	statement.MaxRows = max(statement.MaxRows, rows)
+}
+
+func (t *rowsAffectedTracker) stats() RowsAffectedStats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	stats := RowsAffectedStats{Statements: t.statements, Rows: t.rows}
+	for _, table := range t.tables {
+		stats.Tables = append(stats.Tables, *table)
+	}
+	sort.Slice(stats.Tables, func(i, j int) bool {
+		a, b := stats.Tables[i], stats.Tables[j]
+		if rowsA, rowsB := a.Inserted+a.Updated+a.Deleted, b.Inserted+b.Updated+b.Deleted; rowsA != rowsB {
+			return rowsA > rowsB
+		}
+		return a.Table < b.Table
+	})
+	for _, statement := range t.top {
+		stats.Top = append(stats.Top, *statement)
+	}
+	sort.Slice(stats.Top, func(i, j int) bool {
+		if stats.Top[i].Rows != stats.Top[j].Rows {
+			return stats.Top[i].Rows > stats.Top[j].Rows
+		}
+		return stats.Top[i].Fingerprint < stats.Top[j].Fingerprint
+	})
+	if len(stats.Top) > rowsAffectedTop {
+		stats.Top = stats.Top[:rowsAffectedTop]
+	}
+	return stats
+}
+
+func (t *rowsAffectedTracker) reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.statements, t.rows, t.tables, t.top = 0, 0, nil, nil
+}
+
+// rowsAffectedPlugin accounts the rows affected by the write statements of one connection.
+type rowsAffectedPlugin struct {
+	name    string
+	tracker *rowsAffectedTracker
+
+	// alert is DBConfig.RowsAffectedAlert.
+	alert int64
+}
+
+func (rowsAffectedPlugin) Name() string {
+	return rowsAffectedPluginName
+}
+
+func (p rowsAffectedPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().After("*").Register(rowsAffectedPluginName+"_create", p.record); err != nil {
+		return err
+	}
+	if err := cb.Update().After("*").Register(rowsAffectedPluginName+"_update", p.record); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("*").Register(rowsAffectedPluginName+"_delete", p.record); err != nil {
+		return err
+	}
+	return cb.Raw().After("*").Register(rowsAffectedPluginName+"_raw", p.record)
+}
+
+func (p rowsAffectedPlugin) record(db *gorm.DB) {
+	if db.Error != nil || db.DryRun {
+		return
+	}
+	query := db.Statement.SQL.String()
+	kind := firstKeyword(query)
+	switch kind {
+	case "INSERT", "REPLACE", "UPDATE", "DELETE":
+	default:
+		return
+	}
+	table := db.Statement.Table
+	if table == "" {
+		table = writtenTable(kind, query)
+	}
+	fingerprint := Fingerprint(query)
+	p.tracker.add(table, kind, fingerprint, db.RowsAffected)
+	if p.alert > 0 && db.RowsAffected > p.alert {
+		log.Printf("Statement on %q affected %d rows of %q (over %d): %s", p.name, db.RowsAffected, table, p.alert, fingerprint)
+	}
+}
+
+// writtenTable returns the table a write statement writes to, or "" when its SQL does not tell.
+func writtenTable(kind, query string) string {
+	tables := statementTables(query)
+	if len(tables) == 0 {
+		return ""
+	}
+	// The table of INSERT ... INTO comes after the tables of INSERT ... SELECT ... FROM.
+	if kind == "INSERT" || kind == "REPLACE" {
+		return tables[len(tables)-1]
+	}
+	return tables[0]
+}
@@ -0,0 +1,77 @@
+package connection
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRowsAffectedStats(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	if _, err := factory.RowsAffectedStats("primary_db"); !errors.Is(err, ErrRowsAffectedNotTracked) {
+		t.Fatalf("Expected ErrRowsAffectedNotTracked without tracking, got %v", err)
+	}
+	factory.mutex.Lock()
+	plugin := rowsAffectedPlugin{name: "primary_db", tracker: factory.rowsAffectedTracker("primary_db"), alert: 2}
+	factory.mutex.Unlock()
+	db, err := factory.GetDB("primary_db")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+	if err := db.Use(plugin); err != nil {
+		t.Fatalf("Failed to install the rows affected plugin: %v", err)
+	}
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	if err := db.Create(&fakeTestUser{Name: "dave", Email: "dave@example.com"}).Error; err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := db.Exec("UPDATE fake_test_users SET active = ? WHERE active = ?", true, false).Error; err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if err := db.Exec("DELETE FROM fake_test_users WHERE name <> ?", "alice").Error; err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	var users []fakeTestUser
+	db.Find(&users)
+
+	stats, err := factory.RowsAffectedStats("primary_db")
+	if err != nil {
+		t.Fatalf("RowsAffectedStats failed: %v", err)
+	}
+	if stats.Statements != 3 || stats.Rows != 1+2+3 {
+		t.Fatalf("Expected 3 statements affecting 6 rows, got %+v", stats)
+	}
+	if len(stats.Tables) != 1 || stats.Tables[0] != (TableRowsAffected{Table: "fake_test_users", Inserted: 1, Updated: 2, Deleted: 3, Statements: 3}) {
+		t.Fatalf("Unexpected table accounting %+v", stats.Tables)
+	}
+	if len(stats.Top) != 3 || !strings.HasPrefix(stats.Top[0].Fingerprint, "delete") || stats.Top[0].MaxRows != 3 {
+		t.Fatalf("Expected the DELETE first, got %+v", stats.Top)
+	}
+	if !strings.Contains(buf.String(), "affected 3 rows") || strings.Contains(buf.String(), "affected 2 rows") {
+		t.Fatalf("Expected only the statement over the alert to be logged:\n%s", buf.String())
+	}
+
+	factory.ResetRowsAffectedStats("primary_db")
+	if stats, _ := factory.RowsAffectedStats("primary_db"); stats.Statements != 0 || len(stats.Tables) != 0 {
+		t.Fatalf("Expected empty stats after a reset, got %+v", stats)
+	}
+}
+
+func TestWrittenTable(t *testing.T) {
+	cases := map[string]string{
+		"INSERT INTO archive (id) SELECT id FROM orders":       "archive",
+		"UPDATE `shop`.`orders` o JOIN items i ON 1 SET o.x=1": "orders",
+		"DELETE FROM sessions WHERE expires < NOW()":           "sessions",
+	}
+	for query, want := range cases {
+		if got := writtenTable(firstKeyword(query), query); got != want {
+			t.Errorf("writtenTable(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
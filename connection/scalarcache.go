@@ -0,0 +1,153 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// scalarCache caches the results of CachedScalar per connection and query, and lets concurrent
+// callers of a query missing from the cache share one execution. The zero value is ready to use.
+type scalarCache struct {
+	mutex    sync.Mutex
+	entries  map[scalarKey]scalarEntry
+	inflight map[scalarKey]*scalarCall
+}
+
+// scalarKey identifies a cached result: the connection, the query and its formatted arguments.
+type scalarKey struct {
+	name, query, args string
+}
+
+type scalarEntry struct {
+	value   float64
+	expires time.Time
+}
+
+// scalarCall is an execution of a query shared by the callers that missed the cache meanwhile.
+type scalarCall struct {
+	done  chan struct{}
+	value float64
+	err   error
+}
+
+// CachedScalar runs a query returning a single number, such as a COUNT(*) or SUM aggregate, and caches
+// the result on the connection for ttl. Concurrent callers missing the cache share one execution of the
+// query instead of all hitting the database (stampede protection).
+//
+// Parameters:
+// - ctx: Context of the query. A caller waiting for a shared execution stops waiting when ctx is done.
+// - name: The name of the managed connection.
+// - query: The query. Its first column of its first row is the result; no row or NULL is zero.
+// - args: The arguments of the query. Results are cached per connection, query and arguments.
+// - ttl: How long the result is served from the cache. Zero or less does not cache, but concurrent
+// callers still share one execution.
+//
+// Returns:
+// - float64: The result.
+// - error: An error if the connection does not exist or the query fails. Errors are not cached, and are
+// returned to all the callers sharing the failed execution.
+//
+// Notes:
+//   - The shared execution runs with the context of the caller that started it, so its cancellation
+//     fails the execution for the other callers too.
+//   - Results are dropped by InvalidateScalars and when the connection is closed.
+//
+// Example Usage:
+//
+//	factory := connection.GetConnectionManager()
+//	count, err := factory.CachedScalar(ctx, "primary_db", "SELECT COUNT(*) FROM izooto.audience WHERE site_id = ?",
+//		[]any{siteID}, 30*time.Second)
+//	if err == nil {
+//		log.Printf("audience count: %d", int64(count))
+//	}
+func (f *ConnectionManager) CachedScalar(ctx context.Context, name, query string, args []any, ttl time.Duration) (float64, error) {
+	key := scalarKey{name: name, query: query, args: formatScalarArgs(args)}
+
+	f.scalars.mutex.Lock()
+	if entry, ok := f.scalars.entries[key]; ok && time.Now().Before(entry.expires) {
+		f.scalars.mutex.Unlock()
+		return entry.value, nil
+	}
+	if call, ok := f.scalars.inflight[key]; ok {
+		f.scalars.mutex.Unlock()
+		select {
+		case <-call.done:
+			return call.value, call.err
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	call := &scalarCall{done: make(chan struct{})}
+	if f.scalars.inflight == nil {
+		f.scalars.inflight = make(map[scalarKey]*scalarCall)
+	}
+	f.scalars.inflight[key] = call
+	f.scalars.mutex.Unlock()
+
+	call.value, call.err = f.queryScalar(ctx, name, query, args)
+
+	f.scalars.mutex.Lock()
+	delete(f.scalars.inflight, key)
+	if call.err == nil && ttl > 0 {
+		f.scalars.store(key, call.value, time.Now().Add(ttl))
+	}
+	f.scalars.mutex.Unlock()
+	close(call.done)
+	return call.value, call.err
+}
+
+// InvalidateScalars drops the results cached by CachedScalar on the named connection, e.g. after a
+// write that changes them. Executions in progress are not affected.
+func (f *ConnectionManager) InvalidateScalars(name string) {
+	f.scalars.invalidate(name)
+}
+
+// queryScalar runs query on the named connection and scans its first column.
+func (f *ConnectionManager) queryScalar(ctx context.Context, name, query string, args []any) (float64, error) {
+	db, err := f.GetDBContext(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	var value sql.NullFloat64
+	if err := db.Raw(query, args...).Scan(&value).Error; err != nil {
+		return 0, connError(name, OpCachedScalar, err)
+	}
+	return value.Float64, nil
+}
+
+// store caches a result, dropping the expired ones. The caller holds the mutex.
+func (c *scalarCache) store(key scalarKey, value float64, expires time.Time) {
+	if c.entries == nil {
+		c.entries = make(map[scalarKey]scalarEntry)
+	}
+	now := time.Now()
+	for k, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = scalarEntry{value: value, expires: expires}
+}
+
+func (c *scalarCache) invalidate(name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key := range c.entries {
+		if key.name == name {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// formatScalarArgs formats query arguments into a cache key, with their types so that 1 and "1" differ.
+func formatScalarArgs(args []any) string {
+	var b strings.Builder
+	for _, arg := range args {
+		fmt.Fprintf(&b, "%T:%v\x00", arg, arg)
+	}
+	return b.String()
+}
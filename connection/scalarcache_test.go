@@ -0,0 +1,51 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCachedScalar(t *testing.T) {
+	var queries atomic.Int64
+	factory := newTestFactory()
+	factory.connections["count_db"] = newConnectorDB(t, &scriptedConnector{rows: func(query string) ([]string, [][]driver.Value) {
+		queries.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return []string{"COUNT(*)"}, [][]driver.Value{{int64(42)}}
+	}})
+	ctx := context.Background()
+	query := "SELECT COUNT(*) FROM audience WHERE site_id = ?"
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if count, err := factory.CachedScalar(ctx, "count_db", query, []any{7}, time.Minute); err != nil || count != 42 {
+				t.Errorf("Unexpected result %v: %v", count, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := queries.Load(); got != 1 {
+		t.Fatalf("Expected the concurrent callers to share one query, got %d", got)
+	}
+
+	if _, err := factory.CachedScalar(ctx, "count_db", query, []any{7}, time.Minute); err != nil || queries.Load() != 1 {
+		t.Fatalf("Expected a cached result, got %d queries: %v", queries.Load(), err)
+	}
+	if _, err := factory.CachedScalar(ctx, "count_db", query, []any{"7"}, time.Minute); err != nil || queries.Load() != 2 {
+		t.Fatalf("Expected other arguments to miss the cache, got %d queries: %v", queries.Load(), err)
+	}
+	factory.InvalidateScalars("count_db")
+	if _, err := factory.CachedScalar(ctx, "count_db", query, []any{7}, time.Minute); err != nil || queries.Load() != 3 {
+		t.Fatalf("Expected an invalidated result to be queried again, got %d queries: %v", queries.Load(), err)
+	}
+	if _, err := factory.CachedScalar(ctx, "missing_db", query, nil, time.Minute); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
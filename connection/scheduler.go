@@ -0,0 +1,325 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxJobHistory is the number of runs kept per job for introspection.
+const maxJobHistory = 50
+
+// ErrJobNotFound is returned for operations on a job name that was never registered.
+var ErrJobNotFound = errors.New("maintenance job not found")
+
+// MaintenanceJob is a set of maintenance statements (refresh summary tables, purge old rows,
+// ANALYZE TABLE, ...) run on a named connection on a schedule.
+type MaintenanceJob struct {
+	// Name identifies the job. It is also used for the GET_LOCK name, so only one instance of
+	// the job runs at a time across all replicas of the service.
+	Name string
+
+	// Connection is the name of the managed connection the statements run on.
+	Connection string
+
+	// Schedule is a five-field cron expression ("*/15 * * * *"), "@every 10m", or one of
+	// "@hourly", "@daily", "@weekly", "@monthly".
+	Schedule string
+
+	// Statements are executed in order on a single session; the first failure stops the run.
+	Statements []string
+
+	// Timeout bounds a single run. Zero means no timeout beyond the scheduler's context.
+	Timeout time.Duration
+}
+
+// JobRun records one execution (or skipped execution) of a maintenance job.
+type JobRun struct {
+	Job          string
+	Started      time.Time
+	Finished     time.Time
+	RowsAffected int64
+
+	// Skipped is true when another process held the job's lock, or the previous run was still going.
+	Skipped bool
+
+	// Err holds the failure, if any.
+	Err error
+}
+
+// JobStatus describes a registered job.
+type JobStatus struct {
+	Job     MaintenanceJob
+	NextRun time.Time
+	Running bool
+	LastRun *JobRun
+}
+
+// Scheduler runs registered maintenance jobs on their schedules.
+//
+// Behavior:
+// 1. Each job gets its own timer; Start arms all of them, Stop cancels them and waits for running jobs.
+// 2. Before running, the scheduler takes GET_LOCK('mysqlconn:job:<name>', 0) on a dedicated session.
+// If the lock is held elsewhere the run is recorded as skipped; overlapping runs in the same process
+// are skipped as well.
+// 3. The last maxJobHistory runs of each job are kept and returned by History.
+//
+// Example Usage:
+//
+//	scheduler := connection.NewScheduler(connection.GetConnectionManager())
+//	_ = scheduler.Register(connection.MaintenanceJob{
+//		Name:       "purge_sessions",
+//		Connection: "primary_db",
+//		Schedule:   "*/30 * * * *",
+//		Statements: []string{"DELETE FROM sessions WHERE expires_at < NOW() LIMIT 10000"},
+//	})
+//	scheduler.Start(ctx)
+//	defer scheduler.Stop()
+type Scheduler struct {
+	factory *ConnectionManager
+
+	mutex   sync.Mutex
+	jobs    map[string]*scheduledJob
+	runCtx  context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+}
+
+type scheduledJob struct {
+	job      MaintenanceJob
+	schedule schedule
+	nextRun  time.Time
+	running  bool
+	history  []JobRun
+}
+
+// NewScheduler creates a scheduler running jobs on connections of factory.
+func NewScheduler(factory *ConnectionManager) *Scheduler {
+	return &Scheduler{factory: factory, jobs: make(map[string]*scheduledJob)}
+}
+
+// Register adds a job. Jobs registered after Start are armed immediately.
+func (s *Scheduler) Register(job MaintenanceJob) error {
+	if job.Name == "" || job.Connection == "" || len(job.Statements) == 0 {
+		return errors.New("maintenance job requires a name, a connection and at least one statement")
+	}
+	sched, err := parseSchedule(job.Schedule)
+	if err != nil {
+		return fmt.Errorf("job %q: %w", job.Name, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("maintenance job %q already registered", job.Name)
+	}
+	sj := &scheduledJob{job: job, schedule: sched}
+	s.jobs[job.Name] = sj
+	if s.started {
+		s.arm(sj)
+	}
+	return nil
+}
+
+// Start arms all registered jobs. Jobs stop when ctx is cancelled or Stop is called.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.started {
+		return
+	}
+	s.runCtx, s.cancel = context.WithCancel(ctx)
+	s.started = true
+	for _, sj := range s.jobs {
+		s.arm(sj)
+	}
+}
+
+// Stop cancels all timers and running jobs and waits for them to return.
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	if !s.started {
+		s.mutex.Unlock()
+		return
+	}
+	s.started = false
+	s.cancel()
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+}
+
+// RunNow runs a job immediately, outside its schedule, and returns the run record.
+func (s *Scheduler) RunNow(ctx context.Context, name string) (JobRun, error) {
+	s.mutex.Lock()
+	sj, ok := s.jobs[name]
+	s.mutex.Unlock()
+	if !ok {
+		return JobRun{}, fmt.Errorf("%w: %q", ErrJobNotFound, name)
+	}
+
+	run := s.execute(ctx, sj)
+	return run, run.Err
+}
+
+// History returns the recorded runs of a job, oldest first.
+func (s *Scheduler) History(name string) ([]JobRun, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	sj, ok := s.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrJobNotFound, name)
+	}
+	return append([]JobRun(nil), sj.history...), nil
+}
+
+// Jobs returns the status of all registered jobs sorted by name.
+func (s *Scheduler) Jobs() []JobStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, sj := range s.jobs {
+		status := JobStatus{Job: sj.job, NextRun: sj.nextRun, Running: sj.running}
+		if n := len(sj.history); n > 0 {
+			last := sj.history[n-1]
+			status.LastRun = &last
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Job.Name < statuses[j].Job.Name })
+	return statuses
+}
+
+// arm starts the timer loop of a job. The caller must hold s.mutex.
+func (s *Scheduler) arm(sj *scheduledJob) {
+	ctx := s.runCtx
+	clock := s.factory.clock()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			next := sj.schedule.next(clock.Now())
+			s.mutex.Lock()
+			sj.nextRun = next
+			s.mutex.Unlock()
+			if next.IsZero() {
+				log.Printf("Maintenance job %q has no future activation; not scheduling it.", sj.job.Name)
+				return
+			}
+
+			if sleep(ctx, clock, next.Sub(clock.Now())) != nil {
+				return
+			}
+			s.execute(ctx, sj)
+		}
+	}()
+}
+
+// execute runs a job once, unless it is already running, and records the run.
+func (s *Scheduler) execute(ctx context.Context, sj *scheduledJob) JobRun {
+	run := JobRun{Job: sj.job.Name, Started: s.factory.clock().Now()}
+
+	s.mutex.Lock()
+	if sj.running {
+		run.Skipped, run.Finished = true, run.Started
+		s.record(sj, run)
+		s.mutex.Unlock()
+		return run
+	}
+	sj.running = true
+	s.mutex.Unlock()
+
+	if sj.job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, sj.job.Timeout)
+		defer cancel()
+	}
+	run.RowsAffected, run.Skipped, run.Err = runMaintenance(ctx, s.factory, sj.job)
+	run.Finished = s.factory.clock().Now()
+	if run.Err != nil {
+		log.Printf("Maintenance job %q on %q failed: %v", sj.job.Name, sj.job.Connection, run.Err)
+	}
+
+	s.mutex.Lock()
+	sj.running = false
+	s.record(sj, run)
+	s.mutex.Unlock()
+	return run
+}
+
+// record appends a run to the job history. The caller must hold s.mutex.
+func (s *Scheduler) record(sj *scheduledJob, run JobRun) {
+	sj.history = append(sj.history, run)
+	if len(sj.history) > maxJobHistory {
+		sj.history = append(sj.history[:0:0], sj.history[len(sj.history)-maxJobHistory:]...)
+	}
+}
+
+// runMaintenance executes the job statements on one session while holding the job's named lock.
+// skipped is true when the lock is held by another session.
+func runMaintenance(ctx context.Context, f *ConnectionManager, job MaintenanceJob) (rows int64, skipped bool, err error) {
+	db, err := f.GetDB(job.Connection)
+	if err != nil {
+		return 0, false, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to retrieve database handle for %q: %w", job.Connection, err)
+	}
+
+	// GET_LOCK is session scoped, so the lock and the statements share one connection.
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	defer conn.Close()
+
+	lock := jobLockName(job.Name)
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", lock).Scan(&acquired); err != nil {
+		return 0, false, fmt.Errorf("failed to acquire lock %q: %w", lock, err)
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		return 0, true, nil
+	}
+	defer func() {
+		// Release with a fresh context so a cancelled run still frees the lock.
+		_, _ = conn.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", lock)
+	}()
+
+	for i, statement := range job.Statements {
+		result, err := conn.ExecContext(ctx, statement)
+		if err != nil {
+			return rows, false, fmt.Errorf("statement %d: %w", i+1, err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			rows += n
+		}
+	}
+	return rows, false, nil
+}
+
+// jobLockName returns the GET_LOCK name of a job.
+func jobLockName(job string) string {
+	return namedLock("mysqlconn:job:", job)
+}
+
+// namedLock returns prefix+key as a GET_LOCK name, shortened to MySQL's 64 character limit.
+func namedLock(prefix, key string) string {
+	name := prefix + key
+	if len(name) <= 64 {
+		return name
+	}
+	return fmt.Sprintf("%s:%08x", name[:55], crc32.ChecksumIEEE([]byte(key)))
+}
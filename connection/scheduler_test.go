@@ -0,0 +1,91 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	base := time.Date(2024, time.March, 15, 10, 7, 30, 0, time.UTC) // a Friday
+
+	cases := map[string]time.Time{
+		"*/15 * * * *": time.Date(2024, time.March, 15, 10, 15, 0, 0, time.UTC),
+		"0 2 * * *":    time.Date(2024, time.March, 16, 2, 0, 0, 0, time.UTC),
+		"30 9 * * 1-5": time.Date(2024, time.March, 18, 9, 30, 0, 0, time.UTC),
+		"0 0 1 * *":    time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC),
+		"0 12 29 2 *":  time.Date(2028, time.February, 29, 12, 0, 0, 0, time.UTC),
+		"@hourly":      time.Date(2024, time.March, 15, 11, 0, 0, 0, time.UTC),
+		"0 0 * * 7":    time.Date(2024, time.March, 17, 0, 0, 0, 0, time.UTC),
+		"@every 90s":   base.Add(90 * time.Second),
+	}
+	for spec, want := range cases {
+		sched, err := parseSchedule(spec)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", spec, err)
+		}
+		if got := sched.next(base); !got.Equal(want) {
+			t.Errorf("%q: next after %v = %v, want %v", spec, base, got, want)
+		}
+	}
+
+	for _, spec := range []string{"", "* * * *", "61 * * * *", "*/0 * * * *", "@every 10ms", "a b c d e"} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestSchedulerRegistrationAndHistory(t *testing.T) {
+	scheduler := NewScheduler(newTestFactory())
+	job := MaintenanceJob{Name: "analyze", Connection: "missing_db", Schedule: "@daily", Statements: []string{"ANALYZE TABLE t"}}
+
+	if err := scheduler.Register(job); err != nil {
+		t.Fatalf("Failed to register job: %v", err)
+	}
+	if err := scheduler.Register(job); err == nil {
+		t.Fatal("Expected duplicate registration to fail")
+	}
+
+	run, err := scheduler.RunNow(context.Background(), "analyze")
+	if err == nil || run.Err == nil || run.Skipped {
+		t.Fatalf("Expected the run to fail on a missing connection, got %+v", run)
+	}
+	history, _ := scheduler.History("analyze")
+	if len(history) != 1 {
+		t.Fatalf("Expected one recorded run, got %d", len(history))
+	}
+	if _, err := scheduler.History("nope"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("Expected ErrJobNotFound, got %v", err)
+	}
+
+	scheduler.Start(context.Background())
+	defer scheduler.Stop()
+	time.Sleep(10 * time.Millisecond)
+	if status := scheduler.Jobs(); len(status) != 1 || status[0].NextRun.IsZero() || status[0].LastRun == nil {
+		t.Fatalf("Unexpected job status: %+v", status)
+	}
+}
+
+func TestJobLockName(t *testing.T) {
+	long := jobLockName("a-very-long-maintenance-job-name-that-exceeds-the-mysql-lock-limit")
+	if len(long) > 64 {
+		t.Fatalf("Lock name too long: %d", len(long))
+	}
+	if jobLockName("purge") != "mysqlconn:job:purge" {
+		t.Fatalf("Unexpected lock name %q", jobLockName("purge"))
+	}
+}
+
+func TestElectUnknownConnection(t *testing.T) {
+	if _, err := newTestFactory().Elect(context.Background(), "missing_db", "worker"); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+	if _, err := newTestFactory().Elect(context.Background(), "missing_db", ""); err == nil {
+		t.Fatal("Expected an error for an empty election key, got nil")
+	}
+	if got := namedLock("mysqlconn:leader:", "billing"); got != "mysqlconn:leader:billing" {
+		t.Fatalf("Unexpected lock name %q", got)
+	}
+}
@@ -0,0 +1,714 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// SQL problem kinds reported in SQLProblem.Kind.
+const (
+	SQLUnknownTable  = "unknown_table"
+	SQLUnknownColumn = "unknown_column"
+	SQLTypeMismatch  = "type_mismatch"
+	SQLServerError   = "server_error"
+)
+
+// placeholderToken stands for the "?" placeholders of a statement in the tokens read by CheckSQL, which
+// sqlTokens would otherwise not tell apart from literals.
+const placeholderToken = "$placeholder"
+
+// SQLProblem is a statement that does not match a schema.
+type SQLProblem struct {
+	// Kind is one of the SQL problem constants.
+	Kind string
+
+	// Name is the name the statement was registered under with RegisterQuery, empty for captured statements.
+	Name string
+	SQL  string
+
+	// Table and Column locate the problem when known.
+	Table  string
+	Column string
+
+	Message string
+}
+
+func (p SQLProblem) String() string {
+	source := p.Name
+	if source == "" {
+		source = p.SQL
+	}
+	return fmt.Sprintf("%s: %s: %s", p.Kind, source, p.Message)
+}
+
+// queryRegistry holds the queries registered with RegisterQuery.
+var queryRegistry struct {
+	mutex   sync.Mutex
+	queries map[string]string
+}
+
+// RegisterQuery records a named raw SQL query for CheckSQL and returns the query, so registration
+// happens where the query is declared. Registering a name again with a different query panics.
+//
+// Example Usage:
+//
+//	var activeUsersByTeam = connection.RegisterQuery("active_users_by_team",
+//		"SELECT id, name FROM users WHERE team_id = ? AND active = 1")
+//
+//	db.Raw(activeUsersByTeam, teamID).Scan(&users)
+func RegisterQuery(name, query string) string {
+	queryRegistry.mutex.Lock()
+	defer queryRegistry.mutex.Unlock()
+	if existing, ok := queryRegistry.queries[name]; ok && existing != query {
+		panic(fmt.Sprintf("connection: query %q registered twice with different SQL", name))
+	}
+	if queryRegistry.queries == nil {
+		queryRegistry.queries = make(map[string]string)
+	}
+	queryRegistry.queries[name] = query
+	return query
+}
+
+// RegisteredQueries returns the queries registered with RegisterQuery, sorted by name.
+func RegisteredQueries() []CapturedStatement {
+	queryRegistry.mutex.Lock()
+	defer queryRegistry.mutex.Unlock()
+	statements := make([]CapturedStatement, 0, len(queryRegistry.queries))
+	for name, query := range queryRegistry.queries {
+		statements = append(statements, CapturedStatement{Name: name, SQL: query})
+	}
+	sort.Slice(statements, func(i, j int) bool { return statements[i].Name < statements[j].Name })
+	return statements
+}
+
+var (
+	// columnTypePrefix matches the type at the start of a column definition, before its attributes.
+	columnTypePrefix = regexp.MustCompile(`(?i)^[a-z]+(\s+(precision|varying))?(\s*\((?:'[^']*'|[^)'])*\))?(\s+(unsigned|signed|zerofill))*`)
+
+	// tableEngine matches the ENGINE option of a CREATE TABLE statement.
+	tableEngine = regexp.MustCompile(`(?i)\bengine\s*=\s*(\w+)`)
+
+	// executableComment matches the opening of a mysqldump executable comment with its version.
+	executableComment = regexp.MustCompile(`/\*!\d*\s?`)
+)
+
+// ParseSchemaDump reads the tables of a schema dump, such as the output of mysqldump --no-data, without
+// a server, for CheckSQL.
+//
+// Parameters:
+// - dump: The dump. It is split into statements like ExecScript splits a script.
+//
+// Returns:
+// - *SchemaInfo: The tables of the CREATE TABLE statements with their columns, primary key and indexes,
+// in dump order. Size estimates and foreign keys are not read.
+// - error: An error if the dump cannot be read.
+//
+// Notes:
+//   - Column types are normalized like DriftReport normalizes them, e.g. "int(11)" is read as "int".
+//   - CREATE VIEW statements are read as tables without columns, so only the names of views are checked.
+//   - Other statements, including ALTER TABLE, are ignored.
+//
+// Example Usage:
+//
+//	file, _ := os.Open("schema.sql")
+//	defer file.Close()
+//	info, err := connection.ParseSchemaDump(file)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	for _, problem := range info.CheckSQL(connection.RegisteredQueries()) {
+//		t.Error(problem)
+//	}
+func ParseSchemaDump(dump io.Reader) (*SchemaInfo, error) {
+	statements, err := splitScript(dump)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema dump: %w", err)
+	}
+	info := &SchemaInfo{}
+	for _, statement := range statements {
+		sql := statement.SQL
+		if strings.Contains(sql, "/*!") {
+			// mysqldump wraps views in executable comments; read them as plain statements.
+			sql = strings.ReplaceAll(executableComment.ReplaceAllString(sql, ""), "*/", "")
+		}
+		if table, ok := parseCreateTable(sql); ok {
+			if existing := info.Table(table.Name); existing != nil {
+				*existing = table
+			} else {
+				info.Tables = append(info.Tables, table)
+			}
+		}
+	}
+	return info, nil
+}
+
+// parseCreateTable reads a CREATE TABLE or CREATE VIEW statement.
+func parseCreateTable(sql string) (TableInfo, bool) {
+	tokens := sqlTokens(sql)
+	if len(tokens) < 3 || tokens[0] != "create" {
+		return TableInfo{}, false
+	}
+	for i, tok := range tokens {
+		if tok == "as" || tok == "(" {
+			return TableInfo{}, false
+		}
+		if tok == "view" && i+1 < len(tokens) {
+			name := sql[strings.Index(strings.ToLower(sql), "view")+len("view"):]
+			if end := strings.Index(strings.ToLower(name), " as"); end >= 0 {
+				name = name[:end]
+			}
+			return TableInfo{Name: lastIdentifier(name)}, true
+		}
+		if tok == "table" {
+			break
+		}
+	}
+
+	open := indexOutsideQuotes(sql, '(', 0)
+	if open < 0 {
+		return TableInfo{}, false // CREATE TABLE ... LIKE
+	}
+	closing := matchingParen(sql, open)
+	table := TableInfo{Name: lastIdentifier(sql[:open])}
+	if m := tableEngine.FindStringSubmatch(sql[closing+1:]); m != nil {
+		table.Engine = m[1]
+	}
+	for _, definition := range splitTopLevel(sql[open+1 : closing]) {
+		parseTableDefinition(&table, definition)
+	}
+	return table, table.Name != "" && len(table.Columns) > 0
+}
+
+// parseTableDefinition adds a column or index definition of a CREATE TABLE statement to table.
+func parseTableDefinition(table *TableInfo, definition string) {
+	tokens := sqlTokens(definition)
+	if len(tokens) == 0 {
+		return
+	}
+	switch tokens[0] {
+	case "constraint", "primary", "unique", "key", "index", "fulltext", "spatial", "foreign", "check":
+		if containsString(tokens, "foreign") || containsString(tokens, "check") {
+			return
+		}
+		open := indexOutsideQuotes(definition, '(', 0)
+		if open < 0 {
+			return
+		}
+		index := IndexInfo{Unique: containsString(tokens, "unique") || containsString(tokens, "primary")}
+		header := definition[:open]
+		switch {
+		case containsString(tokens, "primary"):
+			index.Name = "PRIMARY"
+		case tokens[0] == "constraint" && len(tokens) > 1 && isIdentifierToken(tokens[1]) && tokens[1] != "unique":
+			index.Name, _ = leadingIdentifier(strings.TrimSpace(header)[len("constraint"):])
+		default:
+			if last := sqlTokens(header); len(last) > 0 && !tableKeyKeywords[last[len(last)-1]] {
+				index.Name = lastIdentifier(header)
+			}
+		}
+		for _, part := range splitTopLevel(definition[open+1 : matchingParen(definition, open)]) {
+			if column, _ := leadingIdentifier(part); column != "" {
+				index.Columns = append(index.Columns, column)
+			}
+		}
+		table.Indexes = append(table.Indexes, index)
+		return
+	}
+
+	name, rest := leadingIdentifier(definition)
+	if name == "" {
+		return
+	}
+	rest = strings.TrimSpace(rest)
+	columnType := columnTypePrefix.FindString(rest)
+	attributes := strings.ToLower(rest[len(columnType):])
+	column := ColumnInfo{
+		Name:     name,
+		Type:     normalizeColumnType(columnType),
+		Nullable: !strings.Contains(attributes, "not null") && !strings.Contains(attributes, "primary key"),
+	}
+	if strings.Contains(attributes, "auto_increment") {
+		column.Extra = "auto_increment"
+	}
+	table.Columns = append(table.Columns, column)
+	if strings.Contains(attributes, "primary key") {
+		table.Indexes = append(table.Indexes, IndexInfo{Name: "PRIMARY", Columns: []string{name}, Unique: true})
+	}
+}
+
+// tableKeyKeywords are the keywords that may directly precede the column list of an index definition.
+var tableKeyKeywords = map[string]bool{"primary": true, "unique": true, "key": true, "index": true, "fulltext": true, "spatial": true}
+
+// leadingIdentifier returns the identifier at the start of s, unquoted, and the text after it.
+func leadingIdentifier(s string) (string, string) {
+	s = strings.TrimLeft(s, " \t\r\n")
+	if strings.HasPrefix(s, "`") {
+		end := skipQuoted(s, 0, '`')
+		return strings.ReplaceAll(s[1:end], "``", "`"), s[end+1:]
+	}
+	end := 0
+	for end < len(s) && (isWordChar(s[end]) || isDigit(s[end])) {
+		end++
+	}
+	return s[:end], s[end:]
+}
+
+// lastIdentifier returns the last part of the possibly qualified name ending s, unquoted.
+func lastIdentifier(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasSuffix(s, "`") {
+		start := strings.LastIndex(s[:len(s)-1], "`")
+		return s[start+1 : len(s)-1]
+	}
+	if i := strings.LastIndexAny(s, " \t\r\n."); i >= 0 {
+		s = s[i+1:]
+	}
+	return s
+}
+
+// indexOutsideQuotes returns the index of the first c of s from start outside quotes, or -1.
+func indexOutsideQuotes(s string, c byte, start int) int {
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"', '`':
+			i = skipQuoted(s, i, s[i])
+		case c:
+			return i
+		}
+	}
+	return -1
+}
+
+// matchingParen returns the index of the parenthesis closing the one at open, or the end of s.
+func matchingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"', '`':
+			i = skipQuoted(s, i, s[i])
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// CheckSQL checks statements against the schema without a server, e.g. in CI before they ship.
+//
+// Parameters:
+// - statements: The statements, typically RegisteredQueries and the statements captured by DryRunDB
+// handles. The Vars of captured statements are checked against the columns they are bound to.
+//
+// Returns:
+// - []SQLProblem: The problems found, in statement order:
+//   - SQLUnknownTable for tables missing from the schema;
+//   - SQLUnknownColumn for qualified columns (alias.column) missing from their table, and for backquoted
+//     columns, as GORM writes them, missing from all the tables of the statement;
+//   - SQLTypeMismatch for arguments compared with or assigned to a column that cannot hold them: a
+//     non-numeric string for a numeric column, a boolean or a non-date string for a temporal column,
+//     a string longer than a char or varchar column.
+//
+// Notes:
+//   - The check is static and conservative: unqualified columns that are not backquoted are not checked,
+//     and a statement on a view or a table missing from the schema has its columns skipped. Use
+//     CheckSQLOnServer on a scratch database loaded with the dump for a complete check.
+//
+// Example Usage:
+//
+//	db, capture, _ := factory.DryRunDB("primary_db")
+//	NewOrderRepo(db).ArchiveBefore(ctx, cutoff)
+//	statements := append(connection.RegisteredQueries(), capture.Statements()...)
+//	for _, problem := range info.CheckSQL(statements) {
+//		t.Error(problem)
+//	}
+func (s *SchemaInfo) CheckSQL(statements []CapturedStatement) []SQLProblem {
+	var problems []SQLProblem
+	for _, statement := range statements {
+		problems = append(problems, s.checkStatement(statement)...)
+	}
+	return problems
+}
+
+// checkStatement returns the problems of one statement.
+func (s *SchemaInfo) checkStatement(statement CapturedStatement) []SQLProblem {
+	var problems []SQLProblem
+	report := func(kind, table, column, format string, args ...interface{}) {
+		problems = append(problems, SQLProblem{Kind: kind, Name: statement.Name, SQL: statement.SQL,
+			Table: table, Column: column, Message: fmt.Sprintf(format, args...)})
+	}
+
+	tokens := sqlTokens(markPlaceholders(statement.SQL))
+	refs := parseTableRefs(tokens)
+	derived := map[string]bool{"dual": true}
+	selectAliases := make(map[string]bool)
+	complete := true
+	for i := 0; i+1 < len(tokens); i++ {
+		if (tokens[i] == "from" || tokens[i] == "join") && tokens[i+1] == "(" {
+			complete = false // Derived table, whose columns are not known.
+		}
+		if i+2 < len(tokens) && tokens[i+1] == "as" && tokens[i+2] == "(" && isIdentifierToken(tokens[i]) {
+			derived[unquoteToken(tokens[i])] = true // Common table expression.
+		}
+		if tokens[i] == "as" && isIdentifierToken(tokens[i+1]) {
+			selectAliases[unquoteToken(tokens[i+1])] = true
+		}
+	}
+
+	// tables holds the schema of each table of the statement; complete is false when one is unknown.
+	tables := make(map[string]*TableInfo)
+	for _, name := range statementTables(statement.SQL) {
+		if derived[name] {
+			complete = false
+			continue
+		}
+		table := s.Table(name)
+		if table == nil {
+			if _, reported := tables[name]; !reported {
+				report(SQLUnknownTable, name, "", "unknown table %q", name)
+			}
+			tables[name] = nil
+			complete = false
+			continue
+		}
+		if table.Columns == nil {
+			complete = false
+		}
+		tables[name] = table
+	}
+
+	// qualified returns the table named or aliased by qualifier.
+	qualified := func(qualifier string) *TableInfo {
+		if table, ok := refs.aliases[qualifier]; ok {
+			return tables[table]
+		}
+		return tables[qualifier]
+	}
+
+	// resolve returns the table and column referenced by the identifier at index i.
+	resolve := func(i int) (*TableInfo, *ColumnInfo) {
+		if i < 0 || !isIdentifierToken(tokens[i]) || fingerprintKeywords[tokens[i]] {
+			return nil, nil
+		}
+		name := unquoteToken(tokens[i])
+		if i >= 2 && tokens[i-1] == "." {
+			table := qualified(unquoteToken(tokens[i-2]))
+			if table == nil {
+				return nil, nil
+			}
+			return table, table.Column(name)
+		}
+		var found *TableInfo
+		for _, table := range tables {
+			if table != nil && table.Column(name) != nil {
+				if found != nil {
+					return nil, nil // Ambiguous.
+				}
+				found = table
+			}
+		}
+		if found == nil {
+			return nil, nil
+		}
+		return found, found.Column(name)
+	}
+
+	for i, tok := range tokens {
+		if !isIdentifierToken(tok) || tok == placeholderToken || (i+1 < len(tokens) && tokens[i+1] == ".") {
+			continue
+		}
+		name := unquoteToken(tok)
+		if i >= 2 && tokens[i-1] == "." {
+			table := qualified(unquoteToken(tokens[i-2]))
+			if table != nil && table.Columns != nil && name != "*" && table.Column(name) == nil {
+				report(SQLUnknownColumn, table.Name, name, "unknown column %q in table %q", name, table.Name)
+			}
+			continue
+		}
+		if !strings.HasPrefix(tok, "`") || !complete || len(tables) == 0 || selectAliases[name] || derived[name] {
+			continue
+		}
+		if _, isTable := refs.aliases[name]; isTable {
+			continue
+		}
+		if _, isTable := tables[name]; isTable {
+			continue
+		}
+		known := false
+		for _, table := range tables {
+			if table.Column(name) != nil {
+				known = true
+				break
+			}
+		}
+		if !known {
+			report(SQLUnknownColumn, "", name, "unknown column %q", name)
+		}
+	}
+
+	// Check the arguments against the columns of their placeholders when the statement carries them.
+	args := make(map[int]int) // token index -> argument index
+	for i, tok := range tokens {
+		if tok == placeholderToken {
+			args[i] = len(args)
+		}
+	}
+	if len(args) == 0 || len(args) != len(statement.Vars) {
+		return problems
+	}
+	bind := func(placeholder, column int) {
+		table, col := resolve(column)
+		if col == nil {
+			return
+		}
+		if message := checkColumnValue(col, statement.Vars[args[placeholder]]); message != "" {
+			report(SQLTypeMismatch, table.Name, col.Name, "%s for column %q of type %s", message, col.Name, col.Type)
+		}
+	}
+	for i, tok := range tokens {
+		if tok != placeholderToken || i < 2 {
+			continue
+		}
+		switch {
+		case placeholderOperators[tokens[i-1]]:
+			bind(i, i-2)
+		case tokens[i-1] == "between":
+			bind(i, i-2)
+		case tokens[i-1] == "and" && i >= 4 && tokens[i-3] == "between":
+			bind(i, i-4)
+		case tokens[i-1] == "(" || tokens[i-1] == ",":
+			// An element of an IN list.
+			j := i - 1
+			for j >= 2 && tokens[j] == "," && tokens[j-1] == placeholderToken {
+				j -= 2
+			}
+			if tokens[j] == "(" && j >= 2 && tokens[j-1] == "in" {
+				bind(i, j-2)
+			}
+		}
+	}
+	bindInsertValues(tokens, bind)
+	return problems
+}
+
+// placeholderOperators are the operators whose placeholder operand is checked against the column on
+// their left. "=" also covers the assignments of UPDATE ... SET.
+var placeholderOperators = map[string]bool{
+	"=": true, "!=": true, "<>": true, "<": true, ">": true, "<=": true, ">=": true, "<=>": true, "like": true,
+}
+
+// bindInsertValues binds the placeholders of the VALUES rows of an INSERT or REPLACE with a column list
+// to the columns of the list.
+func bindInsertValues(tokens []string, bind func(placeholder, column int)) {
+	start := -1
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i] == "into" {
+			start = i + 1
+			break
+		}
+	}
+	if start < 0 {
+		return
+	}
+	for start+2 < len(tokens) && tokens[start+1] == "." {
+		start += 2
+	}
+	if start+1 >= len(tokens) || tokens[start+1] != "(" {
+		return
+	}
+	var columns []int
+	i := start + 2
+	for ; i < len(tokens) && tokens[i] != ")"; i++ {
+		if tokens[i] != "," {
+			columns = append(columns, i)
+		}
+	}
+	if i+1 >= len(tokens) || (tokens[i+1] != "values" && tokens[i+1] != "value") {
+		return
+	}
+	// Walk the rows: each element made of a single placeholder is bound to its column.
+	position, depth := 0, 0
+	for i += 2; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "(":
+			depth++
+			if depth == 1 {
+				position = 0
+			}
+		case ")":
+			depth--
+			if depth < 0 {
+				return
+			}
+		case ",":
+			if depth == 1 {
+				position++
+			}
+		case placeholderToken:
+			if depth == 1 && position < len(columns) && (tokens[i-1] == "(" || tokens[i-1] == ",") &&
+				i+1 < len(tokens) && (tokens[i+1] == ")" || tokens[i+1] == ",") {
+				bind(i, columns[position])
+			}
+		default:
+			if depth == 0 {
+				return // ON DUPLICATE KEY UPDATE, checked as assignments.
+			}
+		}
+	}
+}
+
+// markPlaceholders replaces the "?" placeholders of query outside quotes and comments with placeholderToken.
+func markPlaceholders(query string) string {
+	var b strings.Builder
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			end := skipQuoted(query, i, c)
+			b.WriteString(query[i : end+1])
+			i = end
+		case c == '#' || (c == '-' && strings.HasPrefix(query[i:], "--")):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end - 1
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				b.WriteString(query[i:])
+				return b.String()
+			}
+			b.WriteString(query[i : i+end+4])
+			i += end + 3
+		case c == '?':
+			b.WriteString(" " + placeholderToken + " ")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// checkColumnValue returns why value cannot be stored in column, or "" when it can or cannot be told.
+func checkColumnValue(column *ColumnInfo, value interface{}) string {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return ""
+		}
+		value = v
+	}
+	if value == nil {
+		return ""
+	}
+	text, isText := value.(string)
+	if b, ok := value.([]byte); ok {
+		text, isText = string(b), true
+	}
+
+	base, _ := leadingIdentifier(column.Type)
+	switch base {
+	case "tinyint", "smallint", "mediumint", "int", "bigint", "decimal", "numeric", "float", "double", "real":
+		if isText {
+			if _, err := strconv.ParseFloat(strings.TrimSpace(text), 64); err != nil {
+				return fmt.Sprintf("string %q is not a number", truncateForMessage(text))
+			}
+		}
+		if _, ok := value.(time.Time); ok {
+			return "time value"
+		}
+	case "date", "datetime", "timestamp", "time", "year":
+		if _, ok := value.(bool); ok {
+			return "boolean value"
+		}
+		if isText && (text == "" || !isDigit(text[0])) {
+			return fmt.Sprintf("string %q is not a date or time", truncateForMessage(text))
+		}
+	case "char", "varchar":
+		open := strings.IndexByte(column.Type, '(')
+		if !isText || open < 0 {
+			break
+		}
+		length, err := strconv.Atoi(strings.TrimSuffix(column.Type[open+1:], ")"))
+		if n := utf8.RuneCountInString(text); err == nil && n > length {
+			return fmt.Sprintf("string of %d characters", n)
+		}
+	}
+	return ""
+}
+
+// truncateForMessage shortens a value quoted in a problem message.
+func truncateForMessage(s string) string {
+	const limit = 40
+	if len(s) <= limit {
+		return s
+	}
+	return s[:limit] + "..."
+}
+
+// CheckSQLOnServer prepares statements on a managed connection, typically a scratch database loaded with
+// the schema dump through ExecScript, and reports those the server rejects. Preparing a statement runs
+// the full name resolution of the server without executing it.
+//
+// Parameters:
+// - ctx: Context bounding the whole check.
+// - name: The name of the managed connection.
+// - statements: The statements, as for CheckSQL.
+//
+// Returns:
+// - []SQLProblem: A SQLServerError problem for each statement the server failed to prepare, with the
+// server error as message.
+// - error: An error if the connection does not exist or ctx ends.
+//
+// Example Usage:
+//
+//	dump, _ := os.Open("schema.sql")
+//	defer dump.Close()
+//	if err := factory.ExecScript(ctx, "scratch_db", dump); err != nil {
+//		t.Fatal(err)
+//	}
+//	problems, err := factory.CheckSQLOnServer(ctx, "scratch_db", connection.RegisteredQueries())
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	for _, problem := range problems {
+//		t.Error(problem)
+//	}
+func (f *ConnectionManager) CheckSQLOnServer(ctx context.Context, name string, statements []CapturedStatement) ([]SQLProblem, error) {
+	db, err := f.getDB(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, connError(name, OpGet, fmt.Errorf("failed to retrieve database handle: %w", err))
+	}
+
+	var problems []SQLProblem
+	for _, statement := range statements {
+		prepared, err := sqlDB.PrepareContext(ctx, statement.SQL)
+		if err != nil {
+			if ctx.Err() != nil {
+				return problems, ctx.Err()
+			}
+			problems = append(problems, SQLProblem{Kind: SQLServerError, Name: statement.Name, SQL: statement.SQL, Message: err.Error()})
+			continue
+		}
+		_ = prepared.Close()
+	}
+	return problems, nil
+}
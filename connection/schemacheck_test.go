@@ -0,0 +1,151 @@
+package connection
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSchemaDump = `
+-- MySQL dump
+/*!40101 SET NAMES utf8mb4 */;
+DROP TABLE IF EXISTS ` + "`fake_test_users`" + `;
+CREATE TABLE ` + "`fake_test_users`" + ` (
+  ` + "`id`" + ` bigint unsigned NOT NULL AUTO_INCREMENT,
+  ` + "`name`" + ` varchar(8) NOT NULL DEFAULT '',
+  ` + "`email`" + ` varchar(191) DEFAULT NULL,
+  ` + "`active`" + ` tinyint(1) NOT NULL,
+  ` + "`role`" + ` enum('admin','user, guest') DEFAULT 'user, guest',
+  ` + "`created_at`" + ` datetime(3) DEFAULT NULL,
+  ` + "`deleted_at`" + ` datetime(3) DEFAULT NULL,
+  PRIMARY KEY (` + "`id`" + `),
+  UNIQUE KEY ` + "`uq_email`" + ` (` + "`email`" + `),
+  KEY ` + "`idx_deleted_at`" + ` (` + "`deleted_at`" + `)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+CREATE TABLE teams (id int(11) PRIMARY KEY, title varchar(50));
+/*!50001 CREATE ALGORITHM=UNDEFINED */
+/*!50001 VIEW ` + "`active_users`" + ` AS select 1 AS ` + "`id`" + ` */;
+`
+
+func TestParseSchemaDump(t *testing.T) {
+	info, err := ParseSchemaDump(strings.NewReader(testSchemaDump))
+	if err != nil {
+		t.Fatalf("ParseSchemaDump failed: %v", err)
+	}
+	if len(info.Tables) != 3 {
+		t.Fatalf("Expected 3 tables, got %+v", info.Tables)
+	}
+	users := info.Table("fake_test_users")
+	if users == nil || users.Engine != "InnoDB" || len(users.Columns) != 7 {
+		t.Fatalf("Unexpected users table %+v", users)
+	}
+	for column, expected := range map[string]string{
+		"id": "bigint unsigned", "name": "varchar(8)", "active": "tinyint(1)", "role": "enum('admin','user, guest')", "created_at": "datetime(3)",
+	} {
+		if got := users.Column(column); got == nil || got.Type != expected {
+			t.Errorf("Expected column %s of type %q, got %+v", column, expected, got)
+		}
+	}
+	if users.Column("id").Nullable || users.Column("id").Extra != "auto_increment" || !users.Column("email").Nullable {
+		t.Errorf("Unexpected column attributes %+v", users.Columns)
+	}
+	if len(users.Indexes) != 3 || users.Indexes[0].Name != "PRIMARY" || users.Indexes[1].Name != "uq_email" || !users.Indexes[1].Unique ||
+		users.Indexes[2].Columns[0] != "deleted_at" {
+		t.Errorf("Unexpected indexes %+v", users.Indexes)
+	}
+	if teams := info.Table("teams"); teams == nil || teams.Column("id").Type != "int" || teams.Indexes[0].Name != "PRIMARY" {
+		t.Errorf("Unexpected teams table %+v", teams)
+	}
+	if view := info.Table("active_users"); view == nil || view.Columns != nil {
+		t.Errorf("Expected the view without columns, got %+v", view)
+	}
+}
+
+func TestCheckSQL(t *testing.T) {
+	info, err := ParseSchemaDump(strings.NewReader(testSchemaDump))
+	if err != nil {
+		t.Fatalf("ParseSchemaDump failed: %v", err)
+	}
+
+	for _, tc := range []struct {
+		statement CapturedStatement
+		kind      string
+		column    string
+	}{
+		{statement: CapturedStatement{SQL: "SELECT u.id, t.title FROM fake_test_users u JOIN teams t ON t.id = u.id WHERE u.active = 1"}},
+		{statement: CapturedStatement{SQL: "SELECT * FROM `fake_test_users` WHERE `fake_test_users`.`deleted_at` IS NULL ORDER BY `name`"}},
+		{statement: CapturedStatement{SQL: "SELECT COUNT(*) AS `total` FROM active_users ORDER BY `total`"}},
+		{statement: CapturedStatement{SQL: "SELECT x.n FROM (SELECT `id` AS n FROM teams) AS x WHERE `n` > 1"}},
+		{statement: CapturedStatement{SQL: "SELECT id FROM users"}, kind: SQLUnknownTable},
+		{statement: CapturedStatement{SQL: "SELECT u.nmae FROM fake_test_users u"}, kind: SQLUnknownColumn, column: "nmae"},
+		{statement: CapturedStatement{SQL: "UPDATE `teams` SET `titel` = ? WHERE `id` = ?", Vars: []interface{}{"a", 1}}, kind: SQLUnknownColumn, column: "titel"},
+		{statement: CapturedStatement{SQL: "SELECT * FROM teams WHERE id = ? AND title = 'x?'", Vars: []interface{}{"abc"}}, kind: SQLTypeMismatch, column: "id"},
+		{statement: CapturedStatement{SQL: "SELECT * FROM teams WHERE id IN (?,?)", Vars: []interface{}{1, "two"}}, kind: SQLTypeMismatch, column: "id"},
+		{statement: CapturedStatement{SQL: "SELECT * FROM fake_test_users WHERE created_at BETWEEN ? AND ?", Vars: []interface{}{"2024-01-01", true}}, kind: SQLTypeMismatch, column: "created_at"},
+		{statement: CapturedStatement{SQL: "INSERT INTO `fake_test_users` (`name`,`active`,`created_at`) VALUES (?,?,?),(?,?,?)",
+			Vars: []interface{}{"alice", true, time.Now(), "bartholomew", false, time.Now()}}, kind: SQLTypeMismatch, column: "name"},
+	} {
+		problems := info.CheckSQL([]CapturedStatement{tc.statement})
+		if tc.kind == "" {
+			if len(problems) != 0 {
+				t.Errorf("Expected no problem for %q, got %v", tc.statement.SQL, problems)
+			}
+			continue
+		}
+		if len(problems) != 1 || problems[0].Kind != tc.kind || problems[0].Column != tc.column || problems[0].SQL != tc.statement.SQL {
+			t.Errorf("Expected one %s problem on %q for %q, got %v", tc.kind, tc.column, tc.statement.SQL, problems)
+		}
+	}
+}
+
+func TestRegisteredQueriesAndDryRunCheck(t *testing.T) {
+	query := RegisterQuery("schemacheck_test_bad", "SELECT emial FROM fake_test_users u WHERE u.emial = ?")
+	if RegisterQuery("schemacheck_test_bad", query) != query {
+		t.Fatal("Expected registering the same query again to return it")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected registering a name with different SQL to panic")
+			}
+		}()
+		RegisterQuery("schemacheck_test_bad", "SELECT 1")
+	}()
+
+	factory := newFakeFactory(t, fakeUsers())
+	db, capture, err := factory.DryRunDB("primary_db")
+	if err != nil {
+		t.Fatalf("DryRunDB failed: %v", err)
+	}
+	db.Where("name = ?", "alice").Find(&[]fakeTestUser{})
+	path := filepath.Join(t.TempDir(), "statements.jsonl")
+	if err := capture.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	captured, err := LoadStatements(path)
+	if err != nil || len(captured) != 1 || captured[0].Vars[0] != "alice" {
+		t.Fatalf("Unexpected loaded statements %+v, %v", captured, err)
+	}
+
+	info, _ := ParseSchemaDump(strings.NewReader(testSchemaDump))
+	var registered []CapturedStatement
+	for _, statement := range RegisteredQueries() {
+		if statement.Name == "schemacheck_test_bad" {
+			registered = append(registered, statement)
+		}
+	}
+	problems := info.CheckSQL(append(registered, captured...))
+	if len(problems) != 1 || problems[0].Name != "schemacheck_test_bad" || problems[0].Kind != SQLUnknownColumn {
+		t.Fatalf("Expected the registered query only to be reported, got %v", problems)
+	}
+
+	problems, err = factory.CheckSQLOnServer(context.Background(), "primary_db", captured)
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("Expected the captured statement to prepare, got %v, %v", problems, err)
+	}
+	if _, err := factory.CheckSQLOnServer(context.Background(), "missing_db", captured); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
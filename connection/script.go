@@ -0,0 +1,199 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// ScriptError is returned by ExecScript for the statement of a script that failed.
+type ScriptError struct {
+	// Statement is the 1-based position of the statement in the script.
+	Statement int
+
+	// Line is the line of the script on which the statement starts.
+	Line int
+
+	// SQL is the statement text, without its delimiter.
+	SQL string
+
+	// Err is the error of the statement.
+	Err error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("statement %d (line %d) failed: %v", e.Statement, e.Line, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// scriptStatement is one statement of a script with the line it starts on.
+type scriptStatement struct {
+	SQL  string
+	Line int
+}
+
+// ExecScript runs a multi-statement SQL script, such as a migration or a schema dump, on a managed connection.
+//
+// Parameters:
+// - ctx: Context bounding the whole script.
+// - name: The name of the managed connection whose data source is used.
+// - script: The script. Statements end with ";" or with the delimiter set by a DELIMITER line, as in the mysql client.
+//
+// Returns:
+// - error: A *ScriptError with the position of the first failing statement, or an error if the connection
+// does not exist, the script cannot be read or the session cannot be opened.
+//
+// Behavior:
+// 1. The script is split into statements outside quotes and comments. DELIMITER lines change the
+// delimiter, so procedure and trigger bodies containing ";" are sent as one statement.
+// 2. The statements run in order on one dedicated session opened with multiStatements enabled, so
+// session state set by the script (SET, USE) applies to the following statements and never reaches
+// the pool. The session is closed when the script ends.
+// 3. Each statement passes through the hooks of the connection (query policy, chaos faults, ...).
+// 4. The script stops at the first failing statement. Statements that ran before are not rolled back.
+//
+// Example Usage:
+//
+//	file, _ := os.Open("migrations/0042_add_triggers.sql")
+//	defer file.Close()
+//	err := connection.GetConnectionManager().ExecScript(ctx, "primary_db", file)
+//	var scriptErr *connection.ScriptError
+//	if errors.As(err, &scriptErr) {
+//		log.Printf("Migration failed at line %d: %v", scriptErr.Line, scriptErr.Err)
+//	}
+func (f *ConnectionManager) ExecScript(ctx context.Context, name string, script io.Reader) error {
+	f.mutex.Lock()
+	config, exists := f.configs[name]
+	hooks := f.hooks[name]
+	f.mutex.Unlock()
+	if !exists {
+		return errNotFound(name, OpExecScript)
+	}
+
+	statements, err := splitScript(script)
+	if err != nil {
+		return connError(name, OpExecScript, fmt.Errorf("failed to read script: %w", err))
+	}
+
+	dsnConfig, err := config.driverConfig(name)
+	if err != nil {
+		return connError(name, OpExecScript, fmt.Errorf("invalid data source name: %w", err))
+	}
+	if dsnConfig.Timeout == 0 {
+		dsnConfig.Timeout = config.ConnectTimeout
+	}
+	dsnConfig.MultiStatements = true
+	dsnConfig.ConnectionAttributes = connectionAttributes(dsnConfig.ConnectionAttributes, name, config)
+	connector, err := mysqldriver.NewConnector(dsnConfig)
+	if err != nil {
+		return connError(name, OpExecScript, fmt.Errorf("invalid data source name: %w", err))
+	}
+	sqlDB := sql.OpenDB(connector)
+	defer sqlDB.Close()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return connError(name, OpExecScript, fmt.Errorf("failed to open script session: %w", err))
+	}
+	defer conn.Close()
+
+	for i, stmt := range statements {
+		query := stmt.SQL
+		if hooks != nil {
+			hooked, err := hooks.run(ctx, name, query, nil)
+			if err != nil {
+				return connError(name, OpExecScript, &ScriptError{Statement: i + 1, Line: stmt.Line, SQL: stmt.SQL, Err: err})
+			}
+			query = hooked.SQL
+		}
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			return connError(name, OpExecScript, &ScriptError{Statement: i + 1, Line: stmt.Line, SQL: stmt.SQL, Err: err})
+		}
+	}
+	return nil
+}
+
+// splitScript splits a script into statements like the mysql client: on the current delimiter outside
+// quotes and comments, with DELIMITER lines changing the delimiter. Comments in front of a statement
+// and statements made only of comments are dropped.
+func splitScript(r io.Reader) ([]scriptStatement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	text := string(data)
+
+	var statements []scriptStatement
+	delimiter := ";"
+	start := 0
+	flush := func(end int) {
+		sql := strings.TrimSpace(text[start:end])
+		// Executable comments (/*!40101 SET ... */, as written by mysqldump) are statements.
+		if len(sqlTokens(sql)) == 0 && !strings.Contains(sql, "/*!") {
+			return
+		}
+		offset := start + strings.Index(text[start:end], sql)
+		statements = append(statements, scriptStatement{SQL: sql, Line: 1 + strings.Count(text[:offset], "\n")})
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+		case isDelimiterCommand(text[i:]) && strings.TrimSpace(text[strings.LastIndexByte(text[:i], '\n')+1:i]) == "":
+			flush(i)
+			end := strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				end = len(text) - i
+			}
+			if fields := strings.Fields(text[i : i+end]); len(fields) > 1 {
+				delimiter = fields[1]
+			}
+			i += end
+			start = i + 1
+		case c == '#' || (c == '-' && strings.HasPrefix(text[i:], "--") &&
+			(i+2 == len(text) || unicode.IsSpace(rune(text[i+2])))):
+			leading := strings.TrimSpace(text[start:i]) == ""
+			for i+1 < len(text) && text[i+1] != '\n' {
+				i++
+			}
+			if leading {
+				start = i + 1
+			}
+		case c == '/' && strings.HasPrefix(text[i:], "/*"):
+			leading := strings.TrimSpace(text[start:i]) == "" && !strings.HasPrefix(text[i:], "/*!")
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				i = len(text)
+			} else {
+				i += end + 3
+			}
+			if leading {
+				start = i + 1
+			}
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(text, i, c)
+		case strings.HasPrefix(text[i:], delimiter):
+			flush(i)
+			i += len(delimiter) - 1
+			start = i + 1
+		}
+	}
+	if start < len(text) {
+		flush(len(text))
+	}
+	return statements, nil
+}
+
+// isDelimiterCommand reports whether s starts with a mysql client DELIMITER command.
+func isDelimiterCommand(s string) bool {
+	const command = "DELIMITER"
+	return len(s) > len(command) && strings.EqualFold(s[:len(command)], command) &&
+		(s[len(command)] == ' ' || s[len(command)] == '\t')
+}
@@ -0,0 +1,59 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSplitScript(t *testing.T) {
+	script := `-- schema for the orders service
+CREATE TABLE orders (id INT PRIMARY KEY, note VARCHAR(20) DEFAULT 'a;b');
+INSERT INTO orders VALUES (1, "x;y"); # trailing comment
+/* block; comment
+   over two lines */
+DELIMITER $$
+CREATE TRIGGER orders_bi BEFORE INSERT ON orders FOR EACH ROW
+BEGIN
+  SET NEW.note = 'trigger';
+END$$
+delimiter ;
+SELECT ` + "`weird;name`" + ` FROM orders;
+-- only a comment;
+/*!40101 SET NAMES utf8 */;
+SELECT 1`
+
+	statements, err := splitScript(strings.NewReader(script))
+	if err != nil {
+		t.Fatalf("splitScript failed: %v", err)
+	}
+	want := []scriptStatement{
+		{SQL: "CREATE TABLE orders (id INT PRIMARY KEY, note VARCHAR(20) DEFAULT 'a;b')", Line: 2},
+		{SQL: `INSERT INTO orders VALUES (1, "x;y")`, Line: 3},
+		{SQL: "CREATE TRIGGER orders_bi BEFORE INSERT ON orders FOR EACH ROW\nBEGIN\n  SET NEW.note = 'trigger';\nEND", Line: 7},
+		{SQL: "SELECT `weird;name` FROM orders", Line: 12},
+		{SQL: "/*!40101 SET NAMES utf8 */", Line: 14},
+		{SQL: "SELECT 1", Line: 15},
+	}
+	if len(statements) != len(want) {
+		t.Fatalf("Expected %d statements, got %d: %q", len(want), len(statements), statements)
+	}
+	for i := range want {
+		if statements[i] != want[i] {
+			t.Errorf("Statement %d = %+v, want %+v", i+1, statements[i], want[i])
+		}
+	}
+}
+
+func TestExecScriptErrors(t *testing.T) {
+	factory := newTestFactory()
+	if err := factory.ExecScript(context.Background(), "missing_db", strings.NewReader("SELECT 1;")); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+
+	scriptErr := &ScriptError{Statement: 2, Line: 5, SQL: "DROP TABLE x", Err: ErrPolicyViolation}
+	if !errors.Is(scriptErr, ErrPolicyViolation) || !strings.Contains(scriptErr.Error(), "line 5") {
+		t.Fatalf("Unexpected script error %v", scriptErr)
+	}
+}
@@ -0,0 +1,98 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"sync"
+)
+
+// ErrSecretConsumed is returned by Secret.Use when the secret was already used and wiped.
+var ErrSecretConsumed = errors.New("secret already used")
+
+// redactedSecret is how secrets are formatted in logs and exports.
+const redactedSecret = "[redacted]"
+
+// Secret is a single-use credential. Its bytes are wiped as soon as they have been used, and it
+// formats and marshals as "[redacted]", so it never shows up in logs, configuration dumps or exports.
+type Secret struct {
+	mutex sync.Mutex
+	value []byte
+	used  bool
+}
+
+// NewSecret wraps value, taking ownership of it: the caller must not keep or reuse the slice.
+func NewSecret(value []byte) *Secret {
+	return &Secret{value: value}
+}
+
+// Use passes the secret's bytes to fn and wipes them when fn returns. fn must not retain the slice.
+func (s *Secret) Use(fn func(value []byte) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.used {
+		return ErrSecretConsumed
+	}
+	s.used = true
+	defer s.wipe()
+	return fn(s.value)
+}
+
+// Wipe zeroes the secret without using it.
+func (s *Secret) Wipe() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.used = true
+	s.wipe()
+}
+
+func (s *Secret) wipe() {
+	for i := range s.value {
+		s.value[i] = 0
+	}
+	s.value = nil
+}
+
+func (s *Secret) String() string {
+	return redactedSecret
+}
+
+func (s *Secret) GoString() string {
+	return redactedSecret
+}
+
+func (s *Secret) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + redactedSecret + `"`), nil
+}
+
+// SecretProvider supplies fresh credentials, e.g. from a vault or a token service.
+type SecretProvider interface {
+	Secret(ctx context.Context) (*Secret, error)
+}
+
+// SecretProviderFunc adapts a function to SecretProvider.
+type SecretProviderFunc func(ctx context.Context) (*Secret, error)
+
+func (fn SecretProviderFunc) Secret(ctx context.Context) (*Secret, error) {
+	return fn(ctx)
+}
+
+// applyPasswordProvider makes the driver ask provider for the password of every new physical connection.
+// The data source name must not carry a password itself.
+func applyPasswordProvider(cfg *mysqldriver.Config, provider SecretProvider) error {
+	if cfg.Passwd != "" {
+		return errors.New("data source name must not contain a password when a PasswordProvider is set")
+	}
+	return cfg.Apply(mysqldriver.BeforeConnect(func(ctx context.Context, connCfg *mysqldriver.Config) error {
+		secret, err := provider.Secret(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get database password: %w", err)
+		}
+		return secret.Use(func(value []byte) error {
+			connCfg.Passwd = string(value)
+			return nil
+		})
+	}))
+}
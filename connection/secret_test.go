@@ -0,0 +1,53 @@
+package connection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"testing"
+)
+
+func TestSecretWipedAfterUse(t *testing.T) {
+	value := []byte("s3cret")
+	secret := NewSecret(value)
+
+	if got := fmt.Sprintf("%v %s %#v", secret, secret, secret); got != "[redacted] [redacted] [redacted]" {
+		t.Fatalf("Secret not redacted when formatted: %s", got)
+	}
+	if data, _ := json.Marshal(struct{ Password *Secret }{secret}); string(data) != `{"Password":"[redacted]"}` {
+		t.Fatalf("Secret not redacted when marshaled: %s", data)
+	}
+
+	var seen string
+	if err := secret.Use(func(b []byte) error { seen = string(b); return nil }); err != nil || seen != "s3cret" {
+		t.Fatalf("Unexpected Use result %q: %v", seen, err)
+	}
+	for _, b := range value {
+		if b != 0 {
+			t.Fatalf("Expected the secret bytes to be zeroed, got %q", value)
+		}
+	}
+	if err := secret.Use(func([]byte) error { return nil }); !errors.Is(err, ErrSecretConsumed) {
+		t.Fatalf("Expected ErrSecretConsumed, got %v", err)
+	}
+}
+
+func TestApplyPasswordProvider(t *testing.T) {
+	withPassword, _ := mysqldriver.ParseDSN("user:password@tcp(127.0.0.1:3306)/dbname")
+	provider := SecretProviderFunc(func(ctx context.Context) (*Secret, error) {
+		return NewSecret([]byte("fresh")), nil
+	})
+	if err := applyPasswordProvider(withPassword, provider); err == nil {
+		t.Fatal("Expected an error for a data source name carrying a password, got nil")
+	}
+
+	cfg, _ := mysqldriver.ParseDSN("user@tcp(127.0.0.1:3306)/dbname")
+	if err := applyPasswordProvider(cfg, provider); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Passwd != "" {
+		t.Fatal("Expected the stored configuration to stay without a password")
+	}
+}
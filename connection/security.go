@@ -0,0 +1,157 @@
+package connection
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrSecurityPolicy is returned for data source names violating the security policy.
+var ErrSecurityPolicy = errors.New("security policy violation")
+
+// SecurityPolicy is the transport security every managed connection must meet. It is set process-wide
+// with SetSecurityPolicy and enforced when connections are initialized.
+type SecurityPolicy struct {
+	// RequireTLS rejects data source names without TLS, with tls=preferred (plaintext fallback) or with
+	// tls=skip-verify (no certificate verification).
+	RequireTLS bool
+
+	// MinTLSVersion is the lowest TLS version allowed, e.g. tls.VersionTLS12. TLS configurations that do
+	// not set a minimum get this one.
+	MinTLSVersion uint16
+
+	// CipherSuites are the TLS 1.0-1.2 cipher suites allowed, e.g. the FIPS-approved ones. TLS
+	// configurations that do not restrict cipher suites are restricted to these. Empty allows Go's defaults.
+	CipherSuites []uint16
+
+	// Rewrite upgrades violating data source names to meet the policy (enabling verified TLS, raising the
+	// minimum version, dropping disallowed cipher suites) instead of rejecting them.
+	Rewrite bool
+}
+
+var securityPolicy atomic.Pointer[SecurityPolicy]
+
+// SetSecurityPolicy sets the security policy enforced on connections initialized afterwards. Existing
+// connections are not affected. A zero policy removes all requirements.
+//
+// Example Usage:
+//
+//	connection.SetSecurityPolicy(connection.SecurityPolicy{
+//		RequireTLS:    true,
+//		MinTLSVersion: tls.VersionTLS12,
+//		CipherSuites:  []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+//	})
+func SetSecurityPolicy(policy SecurityPolicy) {
+	policy.CipherSuites = append([]uint16(nil), policy.CipherSuites...)
+	securityPolicy.Store(&policy)
+}
+
+// enforce checks cfg against the policy and, in rewrite mode, fixes it in place.
+func (p *SecurityPolicy) enforce(cfg *mysqldriver.Config) error {
+	var violations []string
+	violate := func(format string, args ...interface{}) {
+		violations = append(violations, fmt.Sprintf(format, args...))
+	}
+
+	if p.RequireTLS {
+		switch {
+		case cfg.TLS == nil:
+			violate("TLS is not enabled")
+			if p.Rewrite {
+				cfg.TLS = &tls.Config{}
+			}
+		case cfg.AllowFallbackToPlaintext:
+			violate("plaintext fallback is allowed")
+		case cfg.TLS.InsecureSkipVerify:
+			violate("certificate verification is disabled")
+		}
+		if p.Rewrite {
+			cfg.AllowFallbackToPlaintext = false
+			if cfg.TLS.InsecureSkipVerify {
+				cfg.TLS = cfg.TLS.Clone()
+				cfg.TLS.InsecureSkipVerify = false
+			}
+			cfg.TLSConfig = ""
+		}
+	}
+	if cfg.TLS == nil {
+		return policyResult(violations, p.Rewrite)
+	}
+
+	tlsConfig := cfg.TLS.Clone()
+	if p.MinTLSVersion != 0 {
+		switch {
+		case tlsConfig.MinVersion == 0:
+			tlsConfig.MinVersion = p.MinTLSVersion
+		case tlsConfig.MinVersion < p.MinTLSVersion:
+			violate("TLS minimum version %s is below %s", tls.VersionName(tlsConfig.MinVersion), tls.VersionName(p.MinTLSVersion))
+			tlsConfig.MinVersion = p.MinTLSVersion
+		}
+	}
+	if len(p.CipherSuites) > 0 {
+		if len(tlsConfig.CipherSuites) == 0 {
+			tlsConfig.CipherSuites = append([]uint16(nil), p.CipherSuites...)
+		} else {
+			var allowed []uint16
+			for _, suite := range tlsConfig.CipherSuites {
+				if containsSuite(p.CipherSuites, suite) {
+					allowed = append(allowed, suite)
+				} else {
+					violate("cipher suite %s is not allowed", tls.CipherSuiteName(suite))
+				}
+			}
+			if len(allowed) == 0 {
+				allowed = append([]uint16(nil), p.CipherSuites...)
+			}
+			tlsConfig.CipherSuites = allowed
+		}
+	}
+	if len(violations) == 0 || p.Rewrite {
+		cfg.TLS = tlsConfig
+	}
+	return policyResult(violations, p.Rewrite)
+}
+
+// policyResult turns violations into an error, unless they were rewritten.
+func policyResult(violations []string, rewritten bool) error {
+	if len(violations) == 0 || rewritten {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrSecurityPolicy, strings.Join(violations, "; "))
+}
+
+func containsSuite(suites []uint16, suite uint16) bool {
+	for _, s := range suites {
+		if s == suite {
+			return true
+		}
+	}
+	return false
+}
+
+// driverConfig parses the data source name of c for the named connection and applies the dialer or
+// proxy, the password provider and the security policy.
+func (c DBConfig) driverConfig(name string) (*mysqldriver.Config, error) {
+	cfg, err := mysqldriver.ParseDSN(c.protocolDSN())
+	if err != nil {
+		return nil, err
+	}
+	c.applyProtocolOptions(cfg)
+	if err := applyDialer(cfg, name, c.Proxy, c.Dialer); err != nil {
+		return nil, err
+	}
+	if c.PasswordProvider != nil {
+		if err := applyPasswordProvider(cfg, c.PasswordProvider); err != nil {
+			return nil, err
+		}
+	}
+	if policy := securityPolicy.Load(); policy != nil {
+		if err := policy.enforce(cfg); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,54 @@
+package connection
+
+import (
+	"crypto/tls"
+	"errors"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"testing"
+)
+
+func TestSecurityPolicyRejects(t *testing.T) {
+	policy := &SecurityPolicy{RequireTLS: true, MinTLSVersion: tls.VersionTLS12}
+	for _, dsn := range []string{
+		"user:password@tcp(db:3306)/dbname",
+		"user:password@tcp(db:3306)/dbname?tls=preferred",
+		"user:password@tcp(db:3306)/dbname?tls=skip-verify",
+	} {
+		cfg, err := mysqldriver.ParseDSN(dsn)
+		if err != nil {
+			t.Fatalf("ParseDSN(%q): %v", dsn, err)
+		}
+		if err := policy.enforce(cfg); !errors.Is(err, ErrSecurityPolicy) {
+			t.Errorf("%q: expected ErrSecurityPolicy, got %v", dsn, err)
+		}
+	}
+
+	cfg, _ := mysqldriver.ParseDSN("user:password@tcp(db:3306)/dbname?tls=true")
+	if err := policy.enforce(cfg); err != nil {
+		t.Fatalf("Unexpected error for a verified TLS connection: %v", err)
+	}
+	if cfg.TLS.MinVersion != tls.VersionTLS12 {
+		t.Fatalf("Expected the policy minimum version to be applied, got %x", cfg.TLS.MinVersion)
+	}
+}
+
+func TestSecurityPolicyRewrites(t *testing.T) {
+	allowed := []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}
+	policy := &SecurityPolicy{RequireTLS: true, MinTLSVersion: tls.VersionTLS13, CipherSuites: allowed, Rewrite: true}
+
+	cfg, _ := mysqldriver.ParseDSN("user:password@tcp(db:3306)/dbname?tls=preferred")
+	if err := policy.enforce(cfg); err != nil {
+		t.Fatalf("Unexpected error in rewrite mode: %v", err)
+	}
+	if cfg.TLS == nil || cfg.TLS.InsecureSkipVerify || cfg.AllowFallbackToPlaintext {
+		t.Fatalf("Expected verified TLS without fallback, got %+v", cfg.TLS)
+	}
+	if cfg.TLS.MinVersion != tls.VersionTLS13 || len(cfg.TLS.CipherSuites) != 1 || cfg.TLS.CipherSuites[0] != allowed[0] {
+		t.Fatalf("Expected the policy TLS settings, got min %x suites %v", cfg.TLS.MinVersion, cfg.TLS.CipherSuites)
+	}
+
+	plain, _ := mysqldriver.ParseDSN("user:password@tcp(db:3306)/dbname")
+	if err := policy.enforce(plain); err != nil || plain.TLS == nil {
+		t.Fatalf("Expected TLS to be enabled, got %v, %v", plain.TLS, err)
+	}
+}
@@ -0,0 +1,126 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"gorm.io/gorm"
+	"sync/atomic"
+)
+
+// shardedPool spreads the statements of one connection round-robin over several pools of the same data
+// source (DBConfig.PoolShards), so that concurrent callers contend on different database/sql pool locks.
+type shardedPool struct {
+	shards []*sql.DB
+	next   atomic.Uint64
+}
+
+// pick returns the pool of the next statement.
+func (p *shardedPool) pick() *sql.DB {
+	return p.shards[p.next.Add(1)%uint64(len(p.shards))]
+}
+
+func (p *shardedPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.pick().PrepareContext(ctx, query)
+}
+
+func (p *shardedPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.pick().ExecContext(ctx, query, args...)
+}
+
+func (p *shardedPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.pick().QueryContext(ctx, query, args...)
+}
+
+func (p *shardedPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.pick().QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx implements gorm.TxBeginner: each transaction runs on one shard.
+func (p *shardedPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return p.pick().BeginTx(ctx, opts)
+}
+
+// GetDBConn implements gorm.GetDBConnector with the first shard, which (*gorm.DB).DB() returns.
+func (p *shardedPool) GetDBConn() (*sql.DB, error) {
+	return p.shards[0], nil
+}
+
+// findShardedPool returns the sharded pool under the wrappers of pool, or nil.
+func findShardedPool(pool gorm.ConnPool) *shardedPool {
+	for {
+		switch p := pool.(type) {
+		case *shardedPool:
+			return p
+		case *hookedConnPool:
+			pool = p.pool
+		case *gorm.PreparedStmtDB:
+			pool = p.ConnPool
+		default:
+			return nil
+		}
+	}
+}
+
+// poolShards returns the pools of db: its shards, or the single pool of an unsharded connection.
+func poolShards(db *gorm.DB) ([]*sql.DB, error) {
+	if sharded := findShardedPool(db.ConnPool); sharded != nil {
+		return sharded.shards, nil
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return []*sql.DB{sqlDB}, nil
+}
+
+// closeExtraShards closes the shards of db other than the first one, which (*gorm.DB).DB() returns
+// and its callers close.
+func closeExtraShards(db *gorm.DB) {
+	if sharded := findShardedPool(db.ConnPool); sharded != nil {
+		for _, shard := range sharded.shards[1:] {
+			_ = shard.Close()
+		}
+	}
+}
+
+// poolStats returns the statistics of the pools of db, summed over its shards.
+func poolStats(db *gorm.DB) sql.DBStats {
+	shards, err := poolShards(db)
+	if err != nil {
+		return sql.DBStats{}
+	}
+	var total sql.DBStats
+	for _, shard := range shards {
+		stats := shard.Stats()
+		total.MaxOpenConnections += stats.MaxOpenConnections
+		total.OpenConnections += stats.OpenConnections
+		total.InUse += stats.InUse
+		total.Idle += stats.Idle
+		total.WaitCount += stats.WaitCount
+		total.WaitDuration += stats.WaitDuration
+		total.MaxIdleClosed += stats.MaxIdleClosed
+		total.MaxIdleTimeClosed += stats.MaxIdleTimeClosed
+		total.MaxLifetimeClosed += stats.MaxLifetimeClosed
+	}
+	return total
+}
+
+// shardShare returns the share of a pool limit of each of shards shards, rounded up. Limits that are
+// not positive are kept.
+func shardShare(n, shards int) int {
+	if n <= 0 {
+		return n
+	}
+	return (n + shards - 1) / shards
+}
+
+// applyPoolSettings applies the pool settings of config to shards, splitting MaxOpen and MaxIdle
+// evenly between them (rounding up). Negative limits are kept.
+func applyPoolSettings(shards []*sql.DB, config DBConfig) {
+	for _, shard := range shards {
+		shard.SetMaxOpenConns(shardShare(config.MaxOpen, len(shards)))
+		shard.SetMaxIdleConns(shardShare(config.MaxIdle, len(shards)))
+		shard.SetConnMaxLifetime(poolLifetime(config))
+		shard.SetConnMaxIdleTime(config.IdleTime)
+	}
+}
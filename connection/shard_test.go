@@ -0,0 +1,66 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"testing"
+)
+
+func TestShardedPool(t *testing.T) {
+	shards := make([]*sql.DB, 3)
+	for i := range shards {
+		shards[i] = sql.OpenDB(&fakeConnector{})
+	}
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: &shardedPool{shards: shards}, SkipInitializeWithVersion: true}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if _, err := installStatementHooks("hot_db", db, false); err != nil {
+		t.Fatalf("installStatementHooks failed: %v", err)
+	}
+	applyPoolSettings(shards, DBConfig{MaxOpen: 8, MaxIdle: 8})
+
+	ctx := context.Background()
+	for i := 0; i < 6; i++ {
+		var id int64
+		if err := db.WithContext(ctx).Raw("SELECT CONNECTION_ID()").Scan(&id).Error; err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+	}
+	for i, shard := range shards {
+		if stats := shard.Stats(); stats.OpenConnections != 1 || stats.MaxOpenConnections != 3 {
+			t.Errorf("Shard %d: expected one of at most 3 connections, got %+v", i, stats)
+		}
+	}
+	if stats := poolStats(db); stats.OpenConnections != 3 || stats.MaxOpenConnections != 9 {
+		t.Errorf("Expected the statistics summed over the shards, got %+v", stats)
+	}
+	if sqlDB, _ := db.DB(); sqlDB != shards[0] {
+		t.Error("Expected DB() to return the first shard")
+	}
+
+	if err := closePool(db); err != nil {
+		t.Fatalf("closePool failed: %v", err)
+	}
+	for i, shard := range shards {
+		if err := shard.Ping(); err == nil {
+			t.Errorf("Shard %d still open after closePool", i)
+		}
+	}
+}
+
+func TestPoolShardsWarnings(t *testing.T) {
+	warnings := DBConfig{MaxOpen: 2, PoolShards: 4, PrepareStmt: true}.Validate()
+	if len(warnings) != 2 || warnings[0].Field != ConfigFieldPoolShards || warnings[1].Field != ConfigFieldPoolShards {
+		t.Fatalf("Unexpected warnings %v", warnings)
+	}
+	if warnings := (DBConfig{MaxOpen: 16, PoolShards: 4}).Validate(); len(warnings) != 0 {
+		t.Fatalf("Unexpected warnings %v", warnings)
+	}
+}
@@ -0,0 +1,130 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownGrace bounds the graceful shutdown of HookSignals unless ShutdownOptions.Grace is set.
+const defaultShutdownGrace = 10 * time.Second
+
+// ShutdownOptions configure HookSignals.
+type ShutdownOptions struct {
+	// Grace bounds the whole shutdown: BeforeClose and the wait for in-flight queries. Defaults to 10 seconds.
+	Grace time.Duration
+
+	// BeforeClose runs first, while the connections still admit queries, to flush application state
+	// (queues, buffers, outboxes). Its error is logged and does not stop the shutdown.
+	BeforeClose func(ctx context.Context) error
+
+	// Signals are the signals starting the shutdown. Defaults to SIGTERM and SIGINT.
+	Signals []os.Signal
+
+	// AfterClose receives the errors of Shutdown, e.g. to exit the process with the right status.
+	AfterClose func(errs []error)
+}
+
+// Shutdown stops the manager gracefully: it stops admitting queries, waits for the queries in flight
+// until ctx is done, then closes every connection.
+//
+// Parameters:
+// - ctx: Bounds the wait for in-flight queries. Use context.WithTimeout.
+//
+// Returns:
+// - []error: The errors of the connections that did not close cleanly, preceded by ctx.Err() when
+// queries were still running at the deadline; nil after a clean shutdown.
+//
+// Behavior:
+// 1. Queries are refused at once (no grace period) and in-flight ones are waited for, as with Quiesce.
+// 2. All connections, replicas and standbys are closed as with CloseAllConnections.
+// 3. Admission is resumed afterwards, so connections initialized later can be used.
+func (f *ConnectionManager) Shutdown(ctx context.Context) []error {
+	var errs []error
+	if report, err := f.quiesceWithin(ctx, 0); err != nil && report != nil {
+		errs = append(errs, fmt.Errorf("shutdown with queries in flight %v: %w", report.InFlight, err))
+	}
+	errs = append(errs, f.CloseAllConnections()...)
+	f.Resume()
+	return errs
+}
+
+// HookSignals runs the graceful shutdown when the process receives SIGTERM or SIGINT: BeforeClose, then
+// Shutdown within the grace period, then AfterClose. A second signal during the shutdown skips the
+// remaining wait for in-flight queries.
+//
+// Parameters:
+// - opts: The grace period, the callbacks and the signals.
+//
+// Returns:
+// - <-chan struct{}: Closed once the shutdown completed, for main to wait on before exiting, or at once
+// when stop is called before any signal.
+// - func(): Stops listening for the signals. Does not interrupt a shutdown in progress.
+//
+// Example Usage:
+//
+//	done, _ := connection.GetConnectionManager().HookSignals(connection.ShutdownOptions{
+//		Grace:       15 * time.Second,
+//		BeforeClose: func(ctx context.Context) error { return outbox.Flush(ctx) },
+//	})
+//	go server.ListenAndServe()
+//	<-done
+func (f *ConnectionManager) HookSignals(opts ShutdownOptions) (<-chan struct{}, func()) {
+	if opts.Grace <= 0 {
+		opts.Grace = defaultShutdownGrace
+	}
+	if len(opts.Signals) == 0 {
+		opts.Signals = []os.Signal{syscall.SIGTERM, syscall.SIGINT}
+	}
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, opts.Signals...)
+	stopped := make(chan struct{})
+	done := make(chan struct{})
+	stop := sync.OnceFunc(func() {
+		signal.Stop(signals)
+		close(stopped)
+	})
+
+	go func() {
+		defer close(done)
+		var sig os.Signal
+		select {
+		case <-stopped:
+			return
+		case sig = <-signals:
+		}
+		log.Printf("Received %v: shutting down database connections within %v.", sig, opts.Grace)
+
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Grace)
+		defer cancel()
+		go func() {
+			select {
+			case <-signals:
+				log.Println("Received a second signal: closing database connections now.")
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		if opts.BeforeClose != nil {
+			if err := opts.BeforeClose(ctx); err != nil {
+				log.Printf("Flushing application state before shutdown failed: %v", err)
+			}
+		}
+		errs := f.Shutdown(ctx)
+		signal.Stop(signals)
+		if len(errs) > 0 {
+			log.Printf("Database connections shut down with errors: %v", errors.Join(errs...))
+		}
+		if opts.AfterClose != nil {
+			opts.AfterClose(errs)
+		}
+	}()
+	return done, stop
+}
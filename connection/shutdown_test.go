@@ -0,0 +1,88 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	factory := newTestFactory()
+	db := newConnectorDB(t, &fakeConnector{})
+	factory.connections["primary_db"] = db
+	sqlDB, _ := db.DB()
+	busy, err := sqlDB.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Conn failed: %v", err)
+	}
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		_ = busy.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if errs := factory.Shutdown(ctx); len(errs) != 0 {
+		t.Fatalf("Unexpected errors %v", errs)
+	}
+	if len(factory.connections) != 0 {
+		t.Fatalf("Expected every connection closed, got %v", factory.connections)
+	}
+	if err := factory.admit("primary_db", OpGet); err != nil {
+		t.Fatalf("Expected admission to resume after shutdown, got %v", err)
+	}
+}
+
+func TestHookSignals(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["primary_db"] = newConnectorDB(t, &fakeConnector{})
+
+	var order []string
+	var closeErrs []error
+	done, stop := factory.HookSignals(ShutdownOptions{
+		Grace:   time.Second,
+		Signals: []os.Signal{syscall.SIGUSR1},
+		BeforeClose: func(ctx context.Context) error {
+			if _, err := factory.GetDB("primary_db"); err != nil {
+				t.Errorf("Expected queries to be admitted before closing, got %v", err)
+			}
+			order = append(order, "before")
+			return errors.New("flush failed")
+		},
+		AfterClose: func(errs []error) {
+			order = append(order, "after")
+			closeErrs = errs
+		},
+	})
+	defer stop()
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatalf("Kill failed: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown did not complete")
+	}
+	if len(order) != 2 || order[0] != "before" || order[1] != "after" || len(closeErrs) != 0 {
+		t.Fatalf("Unexpected shutdown sequence %v, errors %v", order, closeErrs)
+	}
+	if _, err := factory.GetDB("primary_db"); err == nil {
+		t.Fatal("Expected the connection to be closed, got nil error")
+	}
+}
+
+func TestHookSignalsStop(t *testing.T) {
+	factory := newTestFactory()
+	done, stop := factory.HookSignals(ShutdownOptions{Signals: []os.Signal{syscall.SIGUSR2}})
+	stop()
+	stop()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected done to be closed when stopped before any signal")
+	}
+}
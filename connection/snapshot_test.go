@@ -0,0 +1,114 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDumpCommand(t *testing.T) {
+	cfg, err := mysqldriver.ParseDSN("backup:s3cret@tcp(db.internal:3307)/shop")
+	if err != nil {
+		t.Fatalf("Failed to parse DSN: %v", err)
+	}
+
+	binary, args := dumpCommand(cfg, SnapshotOptions{
+		Tables:    []string{"orders", "users"},
+		Output:    "/backups/shop.sql",
+		ExtraArgs: []string{"--hex-blob"},
+	}, "/tmp/opts.cnf")
+
+	want := []string{
+		"--defaults-extra-file=/tmp/opts.cnf",
+		"--host=db.internal", "--port=3307", "--user=backup",
+		"--single-transaction", "--quick", "--result-file=/backups/shop.sql",
+		"--hex-blob",
+		"shop", "orders", "users",
+	}
+	if binary != "mysqldump" || !reflect.DeepEqual(args, want) {
+		t.Fatalf("Unexpected mysqldump command:\n got: %s %v\nwant: mysqldump %v", binary, args, want)
+	}
+	for _, arg := range args {
+		if arg == "s3cret" || arg == "--password=s3cret" {
+			t.Fatal("Password must not appear on the command line")
+		}
+	}
+
+	binary, args = dumpCommand(cfg, SnapshotOptions{
+		Method: SnapshotMydumper,
+		Tables: []string{"orders"},
+		Output: "/backups/shop",
+	}, "/tmp/opts.cnf")
+	want = []string{
+		"--defaults-file=/tmp/opts.cnf",
+		"--host=db.internal", "--port=3307", "--user=backup",
+		"--database=shop", "--tables-list=shop.orders", "--outputdir=/backups/shop",
+	}
+	if binary != "mydumper" || !reflect.DeepEqual(args, want) {
+		t.Fatalf("Unexpected mydumper command:\n got: %s %v\nwant: mydumper %v", binary, args, want)
+	}
+}
+
+func TestQuoting(t *testing.T) {
+	if got := quoteIdentifier("shop.or`ders"); got != "`shop`.`or``ders`" {
+		t.Fatalf("Unexpected quoted identifier: %s", got)
+	}
+	if got := quoteString(`/tmp/it's\x`); got != `'/tmp/it\'s\\x'` {
+		t.Fatalf("Unexpected quoted string: %s", got)
+	}
+}
+
+func TestOptionFileValue(t *testing.T) {
+	for value, want := range map[string]string{
+		`s3cret`:     `'s3cret'`,
+		`a\b"c`:      `'a\\b"c'`,
+		`it's`:       `'it\'s'`,
+		"pässwört":   "'pässwört'",
+		"two\nlines": `'two\nlines'`,
+	} {
+		if got := optionFileValue(value); got != want {
+			t.Errorf("optionFileValue(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestSnapshotPasswordProvider(t *testing.T) {
+	dir := t.TempDir()
+	// The fake dump tool copies its option file, the first argument, to the result file.
+	tool := filepath.Join(dir, "mysqldump")
+	script := "#!/bin/sh\nfor arg; do case $arg in --result-file=*) out=${arg#--result-file=};; esac; done\ncp \"${1#--defaults-extra-file=}\" \"$out\"\n"
+	if err := os.WriteFile(tool, []byte(script), 0o700); err != nil {
+		t.Fatalf("Failed to write the dump tool: %v", err)
+	}
+
+	factory := newTestFactory()
+	factory.connections["shop"] = newConnectorDB(t, &scriptedConnector{rows: func(query string) ([]string, [][]driver.Value) {
+		return []string{"COUNT(*)"}, [][]driver.Value{{int64(2)}}
+	}})
+	provider := SecretProviderFunc(func(ctx context.Context) (*Secret, error) {
+		return NewSecret([]byte(`from"vault\`)), nil
+	})
+	factory.configs["shop"] = DBConfig{DataSourceName: "backup@tcp(db:3306)/shop", PasswordProvider: provider}
+
+	output := filepath.Join(dir, "shop.sql")
+	manifest, err := factory.Snapshot(context.Background(), "shop", SnapshotOptions{Tables: []string{"orders"}, Output: output, BinaryPath: tool})
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if len(manifest.Tables) != 1 || manifest.Tables[0].Rows != 2 {
+		t.Fatalf("Unexpected manifest %+v", manifest)
+	}
+	written, err := os.ReadFile(output)
+	if err != nil || string(written) != "[client]\npassword='from\"vault\\\\'\n" {
+		t.Fatalf("Expected the password of the provider in the option file, got %q, %v", written, err)
+	}
+
+	factory.configs["shop"] = DBConfig{DataSourceName: "backup@tcp(db:3306)/shop", Proxy: "socks5://proxy:1080"}
+	if _, err := factory.Snapshot(context.Background(), "shop", SnapshotOptions{Tables: []string{"orders"}, Output: output, BinaryPath: tool}); err == nil {
+		t.Fatal("Expected an error for a dump through a proxy, got nil")
+	}
+}
@@ -0,0 +1,121 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"gorm.io/gorm"
+	"sync"
+)
+
+// SnapshotSession is a read-only transaction holding a consistent snapshot on a dedicated connection,
+// so that a batch of report queries sees the database at one point in time. Create it with
+// ConnectionManager.SnapshotSession and Close it when the batch is done.
+type SnapshotSession struct {
+	db   *gorm.DB
+	conn *sql.Conn
+	name string
+
+	mutex  sync.Mutex
+	closed bool
+}
+
+// snapshotConnPool runs the statements of a SnapshotSession on its connection. It does not implement
+// gorm.ConnPoolBeginner, so a transaction started on the session fails instead of escaping the snapshot.
+type snapshotConnPool struct {
+	gorm.ConnPool
+	sqlDB *sql.DB
+}
+
+// GetDBConn implements gorm.GetDBConnector so (*gorm.DB).DB() returns the pool of the connection.
+func (p snapshotConnPool) GetDBConn() (*sql.DB, error) {
+	return p.sqlDB, nil
+}
+
+// SnapshotSession takes a dedicated connection from the pool of a managed connection and starts a
+// read-only REPEATABLE READ transaction WITH CONSISTENT SNAPSHOT on it.
+//
+// Parameters:
+// - ctx: Context of the session. The queries of DB run with it.
+// - name: The name of the managed connection.
+//
+// Returns:
+// - *SnapshotSession: The session. The caller must Close it, which rolls the transaction back.
+// - error: An error if the connection does not exist, no connection can be obtained or the snapshot
+// cannot be started.
+//
+// Notes:
+//   - The snapshot is taken when the session starts: every query of the session sees the data committed
+//     before that point, and none committed after, whatever the order and duration of the queries.
+//   - Statements pass through the hooks of the connection. Writes fail, as in any read-only transaction.
+//   - Long sessions hold back the purge of old row versions by InnoDB; keep report batches short.
+//
+// Example Usage:
+//
+//	session, err := connection.GetConnectionManager().SnapshotSession(ctx, "primary_db")
+//	if err != nil {
+//		return err
+//	}
+//	defer session.Close()
+//	var revenue, orders int64
+//	session.DB().Raw("SELECT SUM(total) FROM orders WHERE day = ?", day).Scan(&revenue)
+//	session.DB().Raw("SELECT COUNT(*) FROM orders WHERE day = ?", day).Scan(&orders) // same point in time
+func (f *ConnectionManager) SnapshotSession(ctx context.Context, name string) (*SnapshotSession, error) {
+	db, err := f.GetDBContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, connError(name, OpSnapshotSession, fmt.Errorf("error retrieving database handle: %w", err))
+	}
+	f.mutex.Lock()
+	hooks := f.hooks[name]
+	f.mutex.Unlock()
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, connError(name, OpSnapshotSession, err)
+	}
+	for _, query := range []string{
+		"SET TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY",
+	} {
+		if _, err := conn.ExecContext(ctx, query); err != nil {
+			_ = conn.Close()
+			return nil, connError(name, OpSnapshotSession, fmt.Errorf("failed to start the snapshot: %w", err))
+		}
+	}
+
+	var pool gorm.ConnPool = conn
+	if hooks != nil {
+		pool = &hookedConnPool{pool: conn, sqlDB: sqlDB, name: name, hooks: hooks}
+	}
+	session := db.Session(&gorm.Session{NewDB: true})
+	session.Statement.ConnPool = snapshotConnPool{ConnPool: pool, sqlDB: sqlDB}
+	return &SnapshotSession{db: session, conn: conn, name: name}, nil
+}
+
+// DB returns the handle running statements in the snapshot. It must not be used after Close.
+func (s *SnapshotSession) DB() *gorm.DB {
+	return s.db
+}
+
+// Close rolls the snapshot transaction back and returns the connection to the pool. Closing a closed
+// session does nothing.
+func (s *SnapshotSession) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	_, err := s.conn.ExecContext(context.Background(), "ROLLBACK")
+	if closeErr := s.conn.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return connError(s.name, OpSnapshotSession, fmt.Errorf("failed to end the snapshot: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"testing"
+)
+
+func TestSnapshotSession(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	session, err := factory.SnapshotSession(context.Background(), "primary_db")
+	if err != nil {
+		t.Fatalf("SnapshotSession failed: %v", err)
+	}
+
+	var count int64
+	if err := session.DB().Model(&fakeTestUser{}).Count(&count).Error; err != nil || count != 3 {
+		t.Fatalf("Expected 3 users in the snapshot, got %d: %v", count, err)
+	}
+	// The fake does not enforce READ ONLY, which shows that Close rolls the transaction back.
+	if err := session.DB().Create(&fakeTestUser{Name: "dave", Email: "dave@example.com"}).Error; err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	err = session.DB().Transaction(func(tx *gorm.DB) error { return nil })
+	if !errors.Is(err, gorm.ErrInvalidTransaction) {
+		t.Fatalf("Expected a transaction on the session to fail, got %v", err)
+	}
+
+	if err := session.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := session.Close(); err != nil {
+		t.Fatalf("Expected a second Close to do nothing, got %v", err)
+	}
+	db, _ := factory.GetDB("primary_db")
+	if db.Model(&fakeTestUser{}).Count(&count); count != 3 {
+		t.Fatalf("Expected the session to be rolled back, got %d users", count)
+	}
+
+	if _, err := factory.SnapshotSession(context.Background(), "missing_db"); !errors.Is(err, ErrConnectionNotFound) {
+		t.Fatalf("Expected ErrConnectionNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,345 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"log"
+	"sync"
+	"time"
+)
+
+// Defaults of StandbyOptions.
+const (
+	defaultStandbySize             = 2
+	defaultStandbyValidateInterval = 30 * time.Second
+)
+
+// ErrNoStandby is returned by Failover when the primary connection has no healthy standby.
+var ErrNoStandby = errors.New("no healthy standby")
+
+// StandbyOptions configure a warm standby of AddStandby.
+type StandbyOptions struct {
+	// Size is the number of connections kept open to the standby. Defaults to 2.
+	Size int
+
+	// ValidateInterval is how often a new session is opened to the standby to check that its credentials
+	// and TLS still work, and the warm connections are topped up. Defaults to 30 seconds.
+	ValidateInterval time.Duration
+}
+
+// StandbyStat is the state of one warm standby.
+type StandbyStat struct {
+	Name    string
+	Healthy bool
+
+	// LastValidated is when the standby was last checked, and Err the error of that check.
+	LastValidated time.Time
+	Err           error
+}
+
+// standby keeps the pool of a failover target warm.
+type standby struct {
+	name     string
+	config   DBConfig
+	opts     StandbyOptions
+	validate func(ctx context.Context) error
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mutex     sync.Mutex
+	healthy   bool
+	validated time.Time
+	err       error
+}
+
+// AddStandby opens a small warm pool to a failover target of a primary connection, e.g. a standby replica,
+// so that Failover switches to it without dialing, authenticating or negotiating TLS.
+//
+// Parameters:
+// - ctx: Context bounding the initial connection to the standby.
+// - primary: The name of the managed primary connection.
+// - name: The name under which the standby is registered as a managed connection.
+// - config: The configuration of the standby, used in full once it is promoted by Failover.
+// - opts: The number of warm connections and how often the standby is validated.
+//
+// Returns:
+// - error: An error if the primary does not exist or the standby cannot be connected.
+//
+// Behavior:
+// 1. Until promoted, the standby pool keeps opts.Size connections open and never expires idle ones.
+// 2. Every ValidateInterval a new session is opened and closed to check the credentials and TLS of the
+// standby, since the warm connections were authenticated when they were opened; the pool is then topped
+// up to opts.Size connections. A failed validation marks the standby unhealthy and is logged.
+// 3. The standby is a managed connection: GetDB(name) reads from it before any failover.
+//
+// Example Usage:
+//
+//	factory := connection.GetConnectionManager()
+//	err := factory.AddStandby(ctx, "orders", "orders_standby", standbyConfig, connection.StandbyOptions{Size: 2})
+//	...
+//	// when the primary fails
+//	promoted, err := factory.Failover("orders")
+func (f *ConnectionManager) AddStandby(ctx context.Context, primary, name string, config DBConfig, opts StandbyOptions) error {
+	f.mutex.Lock()
+	_, exists := f.connections[primary]
+	f.mutex.Unlock()
+	if !exists {
+		return errNotFound(primary, OpAddStandby)
+	}
+	if opts.Size <= 0 {
+		opts.Size = defaultStandbySize
+	}
+
+	warm := config
+	warm.MaxOpen, warm.MaxIdle, warm.IdleTime = opts.Size, opts.Size, 0
+	if _, err := f.ConnectContext(ctx, name, warm); err != nil {
+		return fmt.Errorf("failed to connect standby %q of %q: %w", name, primary, err)
+	}
+	f.startStandby(primary, name, config, opts, func(ctx context.Context) error {
+		return handshake(ctx, name, config)
+	})
+	return nil
+}
+
+// startStandby registers the managed connection name as a standby of primary and starts validating it.
+func (f *ConnectionManager) startStandby(primary, name string, config DBConfig, opts StandbyOptions, validate func(ctx context.Context) error) {
+	if opts.Size <= 0 {
+		opts.Size = defaultStandbySize
+	}
+	if opts.ValidateInterval <= 0 {
+		opts.ValidateInterval = defaultStandbyValidateInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &standby{name: name, config: config, opts: opts, validate: validate, cancel: cancel, done: make(chan struct{})}
+	s.check(ctx, f)
+
+	f.mutex.Lock()
+	if f.standbys == nil {
+		f.standbys = make(map[string][]*standby)
+	}
+	f.standbys[primary] = append(f.standbys[primary], s)
+	f.mutex.Unlock()
+
+	go func() {
+		defer close(s.done)
+		ticker := f.clock().NewTicker(opts.ValidateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C():
+				s.check(ctx, f)
+			}
+		}
+	}()
+}
+
+// check validates the standby and tops up its warm connections.
+func (s *standby) check(ctx context.Context, f *ConnectionManager) {
+	vctx, cancel := context.WithTimeout(ctx, s.opts.ValidateInterval)
+	defer cancel()
+	err := s.validate(vctx)
+	if err == nil {
+		f.mutex.Lock()
+		db, exists := f.connections[s.name]
+		f.mutex.Unlock()
+		if !exists {
+			err = errNotFound(s.name, OpValidateStandby)
+		} else if sqlDB, dbErr := db.DB(); dbErr != nil {
+			err = dbErr
+		} else {
+			err = warmUp(vctx, sqlDB, s.opts.Size)
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err != nil && s.healthy {
+		log.Printf("Standby %q is unhealthy: %v", s.name, err)
+	}
+	s.healthy, s.err, s.validated = err == nil, err, f.clock().Now()
+}
+
+// detachStandbys removes the standbys of the primary name, and name itself if it is a standby, and
+// returns them for their validation to be stopped. The caller holds the mutex.
+func (f *ConnectionManager) detachStandbys(name string) []*standby {
+	detached := f.standbys[name]
+	delete(f.standbys, name)
+	for primary, list := range f.standbys {
+		var remaining []*standby
+		for _, s := range list {
+			if s.name == name {
+				detached = append(detached, s)
+				continue
+			}
+			remaining = append(remaining, s)
+		}
+		f.standbys[primary] = remaining
+	}
+	return detached
+}
+
+// stop ends the validation of the standby.
+func (s *standby) stop() {
+	s.cancel()
+	<-s.done
+}
+
+// handshake opens and closes one new session with config, checking its credentials and TLS. The
+// session sends the connection attributes of the pools of name.
+func handshake(ctx context.Context, name string, config DBConfig) error {
+	dsnConfig, err := config.driverConfig(name)
+	if err != nil {
+		return err
+	}
+	dsnConfig.ConnectionAttributes = connectionAttributes(dsnConfig.ConnectionAttributes, name, config)
+	connector, err := mysqldriver.NewConnector(dsnConfig)
+	if err != nil {
+		return err
+	}
+	conn, err := connector.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// warmUp opens connections on pool until size of them are open.
+func warmUp(ctx context.Context, pool *sql.DB, size int) error {
+	missing := size - pool.Stats().OpenConnections
+	conns := make([]*sql.Conn, 0, missing)
+	defer func() {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+	for i := 0; i < missing; i++ {
+		conn, err := pool.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		conns = append(conns, conn)
+		if err := conn.PingContext(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StandbyStats returns the state of the warm standbys of a primary connection.
+func (f *ConnectionManager) StandbyStats(primary string) []StandbyStat {
+	f.mutex.Lock()
+	standbys := append([]*standby(nil), f.standbys[primary]...)
+	f.mutex.Unlock()
+
+	stats := make([]StandbyStat, 0, len(standbys))
+	for _, s := range standbys {
+		s.mutex.Lock()
+		stats = append(stats, StandbyStat{Name: s.name, Healthy: s.healthy, LastValidated: s.validated, Err: s.err})
+		s.mutex.Unlock()
+	}
+	return stats
+}
+
+// Failover promotes the first healthy standby of a primary connection: its warm pool becomes the
+// connection named primary, so GetDB(primary) returns it at once.
+//
+// Parameters:
+// - primary: The name of the managed primary connection.
+//
+// Returns:
+// - string: The name of the promoted standby.
+// - error: ErrNoStandby if no standby is healthy, or an error if the primary does not exist.
+//
+// Behavior:
+// 1. The standby pool, its hooks and plugins are registered under primary and resized to the standby
+// configuration of AddStandby, which later reconnects of primary use.
+// 2. The standby name is removed, and the standby is no longer validated.
+// 3. The previous primary pool is closed; statements running on it finish first.
+//
+// Notes:
+// - Statement metrics keep the standby name as their pool attribute until primary is next reconnected.
+func (f *ConnectionManager) Failover(primary string) (string, error) {
+	f.mutex.Lock()
+	old, exists := f.connections[primary]
+	if !exists {
+		f.mutex.Unlock()
+		return "", errNotFound(primary, OpFailover)
+	}
+	var promoted *standby
+	var remaining []*standby
+	for _, s := range f.standbys[primary] {
+		s.mutex.Lock()
+		healthy := s.healthy
+		s.mutex.Unlock()
+		_, registered := f.connections[s.name]
+		if promoted == nil && healthy && registered {
+			promoted = s
+			continue
+		}
+		remaining = append(remaining, s)
+	}
+	if promoted == nil {
+		f.mutex.Unlock()
+		return "", connError(primary, OpFailover, ErrNoStandby)
+	}
+	f.standbys[primary] = remaining
+
+	db := f.connections[promoted.name]
+	if shards, err := poolShards(db); err == nil {
+		applyLivePoolSettings(db, shards, promoted.config)
+	}
+	f.connections[primary] = db
+	if hooked, ok := db.ConnPool.(*hookedConnPool); ok {
+		hooked.downtime.Store(f.downtimeProbe(primary))
+	}
+	f.configs[primary] = promoted.config
+	f.warnings[primary] = f.warnings[promoted.name]
+	f.sessions[primary] = f.sessions[promoted.name]
+	f.hooks[primary] = f.hooks[promoted.name]
+	f.plugins[primary] = f.plugins[promoted.name]
+	if info := f.info[promoted.name]; info != nil {
+		renamed := *info
+		renamed.Name = primary
+		f.info[primary] = &renamed
+	}
+	f.forget(promoted.name)
+	delete(f.plugins, promoted.name)
+	f.mutex.Unlock()
+
+	promoted.stop()
+	if oldDB, err := old.DB(); err == nil {
+		_ = oldDB.Close()
+	}
+	closeExtraShards(old)
+	log.Printf("Database connection %q failed over to standby %q.", primary, promoted.name)
+	return promoted.name, nil
+}
+
+// RemoveStandby stops keeping a standby warm and closes its connection.
+func (f *ConnectionManager) RemoveStandby(primary, name string) error {
+	f.mutex.Lock()
+	var removed *standby
+	var remaining []*standby
+	for _, s := range f.standbys[primary] {
+		if s.name == name && removed == nil {
+			removed = s
+			continue
+		}
+		remaining = append(remaining, s)
+	}
+	f.standbys[primary] = remaining
+	f.mutex.Unlock()
+	if removed == nil {
+		return connError(primary, OpRemoveStandby, fmt.Errorf("%q is not a standby", name))
+	}
+	removed.stop()
+	return f.CloseConnection(name)
+}
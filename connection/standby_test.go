@@ -0,0 +1,108 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newStandbyTestFactory(t *testing.T) *ConnectionManager {
+	factory := newTestFactory()
+	factory.warnings = make(map[string][]ConfigWarning)
+	factory.sessions = make(map[string]*sessionTracker)
+	factory.hooks = make(map[string]*hookChain)
+	factory.plugins = make(map[string]*pluginRegistry)
+	factory.info = make(map[string]*Connection)
+	factory.connections["orders"] = newConnectorDB(t, &fakeConnector{})
+	factory.connections["orders_standby"] = newConnectorDB(t, &fakeConnector{})
+	factory.info["orders_standby"] = &Connection{Name: "orders_standby", Addr: "standby:3306"}
+	return factory
+}
+
+func TestStandbyFailover(t *testing.T) {
+	factory := newStandbyTestFactory(t)
+	standbyDB := factory.connections["orders_standby"]
+	if sqlDB, err := standbyDB.DB(); err == nil {
+		sqlDB.SetMaxIdleConns(3)
+	}
+	config := DBConfig{DataSourceName: "app@tcp(standby:3306)/orders", MaxOpen: 10, MaxIdle: 5}
+	factory.startStandby("orders", "orders_standby", config, StandbyOptions{Size: 3, ValidateInterval: time.Hour},
+		func(ctx context.Context) error { return nil })
+
+	if stats := factory.StandbyStats("orders"); len(stats) != 1 || !stats[0].Healthy || stats[0].LastValidated.IsZero() {
+		t.Fatalf("Expected a healthy standby, got %+v", stats)
+	}
+	if open := poolStats(standbyDB).OpenConnections; open != 3 {
+		t.Fatalf("Expected 3 warm connections, got %d", open)
+	}
+
+	promoted, err := factory.Failover("orders")
+	if err != nil || promoted != "orders_standby" {
+		t.Fatalf("Unexpected failover result %q, %v", promoted, err)
+	}
+	if db, err := factory.GetDB("orders"); err != nil || db.ConnPool != standbyDB.ConnPool {
+		t.Fatalf("Expected orders to use the standby pool, got %v", err)
+	}
+	if factory.GetDbConfig("orders").MaxOpen != 10 || factory.info["orders"].Name != "orders" {
+		t.Fatal("Expected the standby configuration and description under the primary name")
+	}
+	if _, err := factory.GetDB("orders_standby"); err == nil {
+		t.Fatal("Expected the standby name to be removed, got nil error")
+	}
+	if _, err := factory.Failover("orders"); !errors.Is(err, ErrNoStandby) {
+		t.Fatalf("Expected ErrNoStandby after the only standby was promoted, got %v", err)
+	}
+}
+
+func TestStandbyUnhealthy(t *testing.T) {
+	factory := newStandbyTestFactory(t)
+	factory.startStandby("orders", "orders_standby", DBConfig{}, StandbyOptions{ValidateInterval: time.Hour},
+		func(ctx context.Context) error { return errors.New("access denied") })
+
+	if stats := factory.StandbyStats("orders"); len(stats) != 1 || stats[0].Healthy || stats[0].Err == nil {
+		t.Fatalf("Expected an unhealthy standby, got %+v", stats)
+	}
+	if _, err := factory.Failover("orders"); !errors.Is(err, ErrNoStandby) {
+		t.Fatalf("Expected ErrNoStandby, got %v", err)
+	}
+	if err := factory.RemoveStandby("orders", "orders_standby"); err != nil {
+		t.Fatalf("RemoveStandby failed: %v", err)
+	}
+	if err := factory.RemoveStandby("orders", "orders_standby"); err == nil {
+		t.Fatal("Expected an error removing an unknown standby, got nil")
+	}
+}
+
+func TestCloseConnectionStopsStandbys(t *testing.T) {
+	factory := newStandbyTestFactory(t)
+	factory.connections["orders_standby2"] = newConnectorDB(t, &fakeConnector{})
+	validate := func(ctx context.Context) error { return nil }
+	factory.startStandby("orders", "orders_standby", DBConfig{}, StandbyOptions{ValidateInterval: time.Hour}, validate)
+	factory.startStandby("orders", "orders_standby2", DBConfig{}, StandbyOptions{ValidateInterval: time.Hour}, validate)
+	standby, standby2 := factory.standbys["orders"][0], factory.standbys["orders"][1]
+
+	if err := factory.CloseConnection("orders_standby"); err != nil {
+		t.Fatalf("CloseConnection failed: %v", err)
+	}
+	select {
+	case <-standby.done:
+	default:
+		t.Fatal("Expected the validation of the closed standby to stop")
+	}
+	if stats := factory.StandbyStats("orders"); len(stats) != 1 || stats[0].Name != "orders_standby2" {
+		t.Fatalf("Expected the closed standby to be removed, got %+v", stats)
+	}
+
+	if err := factory.CloseConnection("orders"); err != nil {
+		t.Fatalf("CloseConnection failed: %v", err)
+	}
+	select {
+	case <-standby2.done:
+	default:
+		t.Fatal("Expected the validation of the standbys of the closed primary to stop")
+	}
+	if stats := factory.StandbyStats("orders"); len(stats) != 0 {
+		t.Fatalf("Expected no standbys left, got %+v", stats)
+	}
+}
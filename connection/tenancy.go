@@ -0,0 +1,164 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+)
+
+// tenancyPluginName is the name under which the tenancy guard is registered in gorm.Config.Plugins.
+const tenancyPluginName = "mysqlconn:tenancy"
+
+// tenantTag is the struct tag marking the tenant column of a model: `tenant:"true"`.
+const tenantTag = "tenant"
+
+var (
+	// ErrMissingTenant is returned when a statement on a tenant-scoped model runs without a tenant in its context.
+	ErrMissingTenant = errors.New("missing tenant scope")
+
+	// ErrTenantMismatch is returned when a record being created carries a tenant other than the context's tenant.
+	ErrTenantMismatch = errors.New("record belongs to another tenant")
+)
+
+type tenantKey struct{}
+
+type tenantBypassKey struct{}
+
+// WithTenant returns a context carrying tenantID. Statements on tenant-scoped models executed with
+// this context (db.WithContext(ctx)) are restricted to that tenant.
+// Writes run with it are also frozen by MoveTenant: see ErrTenantFrozen and ErrTenantMoved.
+func WithTenant(ctx context.Context, tenantID interface{}) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant stored by WithTenant.
+func TenantFromContext(ctx context.Context) (interface{}, bool) {
+	tenantID := ctx.Value(tenantKey{})
+	return tenantID, tenantID != nil
+}
+
+// WithoutTenantScope returns a context that deliberately bypasses the tenancy guard,
+// for administrative jobs that must work across tenants.
+func WithoutTenantScope(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantBypassKey{}, true)
+}
+
+// tenancyPlugin is a GORM plugin enforcing row-level tenancy on models with a `tenant:"true"` field.
+// It is installed by the factory when DBConfig.TenantGuard is set.
+//
+// Behavior:
+// 1. Query, Row, Update and Delete statements on tagged models get "tenant_column = ?" appended with the context's tenant.
+// 2. Create statements get the tenant field filled from the context; a different non-zero value is rejected.
+// 3. Statements on tagged models without a tenant in the context fail with ErrMissingTenant, unless the
+// context was marked with WithoutTenantScope.
+// 4. Upserts (ON CONFLICT / ON DUPLICATE KEY UPDATE) on tagged models are rejected, as they could overwrite
+// another tenant's row with the same key.
+//
+// Raw SQL (Raw, Exec) is not model based and is not inspected.
+type tenancyPlugin struct{}
+
+func (tenancyPlugin) Name() string {
+	return tenancyPluginName
+}
+
+func (p tenancyPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("mysqlconn:tenancy_query", p.scope); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("mysqlconn:tenancy_row", p.scope); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("mysqlconn:tenancy_update", p.scope); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("mysqlconn:tenancy_delete", p.scope); err != nil {
+		return err
+	}
+	return cb.Create().Before("gorm:create").Register("mysqlconn:tenancy_create", p.assign)
+}
+
+// scope adds the tenant condition to query, row, update and delete statements.
+func (tenancyPlugin) scope(db *gorm.DB) {
+	field, tenantID, ok := tenantScope(db)
+	if !ok || field == nil {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: tenantID},
+	}})
+}
+
+// assign fills the tenant field of records being created.
+func (tenancyPlugin) assign(db *gorm.DB) {
+	field, tenantID, ok := tenantScope(db)
+	if !ok || field == nil {
+		return
+	}
+	if _, upsert := db.Statement.Clauses["ON CONFLICT"]; upsert {
+		_ = db.AddError(fmt.Errorf("upserts on tenant-scoped table %q are not allowed", db.Statement.Table))
+		return
+	}
+
+	assignOne := func(rv reflect.Value) {
+		current, zero := field.ValueOf(db.Statement.Context, rv)
+		if !zero && fmt.Sprint(current) != fmt.Sprint(tenantID) {
+			_ = db.AddError(fmt.Errorf("%w: %v (context tenant %v)", ErrTenantMismatch, current, tenantID))
+			return
+		}
+		if err := field.Set(db.Statement.Context, rv, tenantID); err != nil {
+			_ = db.AddError(err)
+		}
+	}
+
+	rv := db.Statement.ReflectValue
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			elem := reflect.Indirect(rv.Index(i))
+			if elem.Kind() == reflect.Struct {
+				assignOne(elem)
+			}
+		}
+	case reflect.Struct:
+		assignOne(rv)
+	}
+}
+
+// tenantScope returns the tenant field of the statement's model and the context's tenant.
+// ok is false when the statement must not be scoped (no tagged model, bypass, earlier error);
+// a missing tenant is recorded as an error on db.
+func tenantScope(db *gorm.DB) (*schema.Field, interface{}, bool) {
+	if db.Error != nil || db.Statement.Schema == nil || db.Statement.SQL.Len() > 0 {
+		return nil, nil, false
+	}
+	field := tenantField(db.Statement.Schema)
+	if field == nil {
+		return nil, nil, false
+	}
+
+	ctx := db.Statement.Context
+	if bypass, _ := ctx.Value(tenantBypassKey{}).(bool); bypass {
+		return nil, nil, false
+	}
+	tenantID, ok := TenantFromContext(ctx)
+	if !ok {
+		_ = db.AddError(fmt.Errorf("%w: table %q requires WithTenant on the context", ErrMissingTenant, db.Statement.Table))
+		return nil, nil, false
+	}
+	return field, tenantID, true
+}
+
+// tenantField returns the field tagged `tenant:"true"`, or nil.
+func tenantField(s *schema.Schema) *schema.Field {
+	for _, field := range s.Fields {
+		if field.Tag.Get(tenantTag) == "true" && field.DBName != "" {
+			return field
+		}
+	}
+	return nil
+}
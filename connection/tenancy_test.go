@@ -0,0 +1,61 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type tenancyTestInvoice struct {
+	ID       uint
+	TenantID uint `tenant:"true"`
+	Amount   int
+}
+
+type tenancyTestCountry struct {
+	Code string `gorm:"primaryKey"`
+}
+
+func TestTenancyGuard(t *testing.T) {
+	db := newDryRunDB(t)
+	if err := db.Use(tenancyPlugin{}); err != nil {
+		t.Fatalf("Failed to install tenancy plugin: %v", err)
+	}
+	ctx := WithTenant(context.Background(), uint(7))
+
+	var invoices []tenancyTestInvoice
+	stmt := db.WithContext(ctx).Where("amount > ?", 10).Find(&invoices).Statement
+	if sql := stmt.SQL.String(); !strings.Contains(sql, "`tenancy_test_invoices`.`tenant_id` = ?") {
+		t.Fatalf("Expected the tenant condition in %s", sql)
+	}
+
+	stmt = db.WithContext(ctx).Delete(&tenancyTestInvoice{ID: 3}).Statement
+	if sql := stmt.SQL.String(); !strings.Contains(sql, "`tenant_id` = ?") {
+		t.Fatalf("Expected the tenant condition in %s", sql)
+	}
+
+	invoice := tenancyTestInvoice{Amount: 5}
+	if err := db.WithContext(ctx).Create(&invoice).Error; err != nil || invoice.TenantID != 7 {
+		t.Fatalf("Expected the tenant to be assigned on create, got %d, %v", invoice.TenantID, err)
+	}
+
+	foreign := tenancyTestInvoice{TenantID: 8}
+	if err := db.WithContext(ctx).Create(&foreign).Error; !errors.Is(err, ErrTenantMismatch) {
+		t.Fatalf("Expected ErrTenantMismatch, got %v", err)
+	}
+
+	if err := db.Find(&invoices).Error; !errors.Is(err, ErrMissingTenant) {
+		t.Fatalf("Expected ErrMissingTenant without a tenant, got %v", err)
+	}
+
+	stmt = db.WithContext(WithoutTenantScope(context.Background())).Find(&invoices).Statement
+	if stmt.Error != nil || strings.Contains(stmt.SQL.String(), "tenant_id") {
+		t.Fatalf("Expected the bypass to skip scoping, got %s, %v", stmt.SQL.String(), stmt.Error)
+	}
+
+	var countries []tenancyTestCountry
+	if err := db.Find(&countries).Error; err != nil {
+		t.Fatalf("Untagged models must not require a tenant, got %v", err)
+	}
+}
@@ -0,0 +1,437 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"sync"
+	"time"
+)
+
+// Defaults of TenantMove.
+const (
+	defaultTenantColumn     = "tenant_id"
+	defaultTenantMoveBatch  = 500
+	defaultTenantFreezeWait = 5 * time.Second
+)
+
+var (
+	// ErrTenantNotAssigned is returned for a tenant that has no shard, see AssignTenant.
+	ErrTenantNotAssigned = errors.New("tenant is not assigned to a shard")
+
+	// ErrTenantMoveMismatch is returned by MoveTenant when the copied rows still differ from the source
+	// during the write freeze. The tenant stays on its source shard.
+	ErrTenantMoveMismatch = errors.New("copied tenant rows differ from the source")
+
+	// ErrTenantFrozen is returned for the writes of a tenant tagged with WithTenant while MoveTenant
+	// freezes its writes.
+	ErrTenantFrozen = errors.New("tenant writes are frozen by a move")
+
+	// ErrTenantMoved is returned for the writes of a tenant tagged with WithTenant on a shard other than
+	// its shard in the shard map, e.g. the source of a completed move.
+	ErrTenantMoved = errors.New("tenant is on another shard")
+)
+
+// tenantAdmittedKey is the context key of the tenant admitted by WithTenantDB, whose writes the freeze
+// waits for instead of refusing them.
+type tenantAdmittedKey struct{}
+
+// tenantRoute is the shard of a tenant and the gate freezing its writes during MoveTenant.
+type tenantRoute struct {
+	mutex   sync.Mutex
+	shard   string
+	active  int
+	frozen  chan struct{}
+	drained chan struct{}
+}
+
+// enter waits for the tenant to be unfrozen and returns its shard, counting the caller as active.
+func (r *tenantRoute) enter(ctx context.Context) (string, error) {
+	for {
+		r.mutex.Lock()
+		frozen := r.frozen
+		if frozen == nil {
+			r.active++
+			shard := r.shard
+			r.mutex.Unlock()
+			return shard, nil
+		}
+		r.mutex.Unlock()
+		select {
+		case <-frozen:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+func (r *tenantRoute) leave() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.active--
+	if r.active == 0 && r.drained != nil {
+		close(r.drained)
+		r.drained = nil
+	}
+}
+
+// freeze stops admitting callers and waits for the active ones to leave. On failure the route is unfrozen.
+func (r *tenantRoute) freeze(ctx context.Context) error {
+	r.mutex.Lock()
+	r.frozen = make(chan struct{})
+	if r.active == 0 {
+		r.mutex.Unlock()
+		return nil
+	}
+	r.drained = make(chan struct{})
+	drained := r.drained
+	r.mutex.Unlock()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		r.thaw("")
+		return fmt.Errorf("tenant writes still in progress: %w", ctx.Err())
+	}
+}
+
+// thaw admits callers again, on shard when it is not empty.
+func (r *tenantRoute) thaw(shard string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if shard != "" {
+		r.shard = shard
+	}
+	r.drained = nil
+	if r.frozen != nil {
+		close(r.frozen)
+		r.frozen = nil
+	}
+}
+
+// tenantMapKey is the key of a tenant in the shard map: 42 and "42" are the same tenant.
+func tenantMapKey(tenantID interface{}) string {
+	return fmt.Sprint(tenantID)
+}
+
+func (f *ConnectionManager) tenantRoute(tenantID interface{}) *tenantRoute {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.tenants[tenantMapKey(tenantID)]
+}
+
+// AssignTenant places a tenant on a shard, a managed connection, in the shard map used by
+// WithTenantDB and MoveTenant. Reassigning a tenant only changes the map; use MoveTenant to move its rows.
+func (f *ConnectionManager) AssignTenant(tenantID interface{}, shard string) {
+	f.mutex.Lock()
+	route := f.tenants[tenantMapKey(tenantID)]
+	if route == nil {
+		if f.tenants == nil {
+			f.tenants = make(map[string]*tenantRoute)
+		}
+		f.tenants[tenantMapKey(tenantID)] = &tenantRoute{shard: shard}
+		f.mutex.Unlock()
+		return
+	}
+	f.mutex.Unlock()
+	route.mutex.Lock()
+	route.shard = shard
+	route.mutex.Unlock()
+}
+
+// TenantShard returns the shard of a tenant in the shard map.
+func (f *ConnectionManager) TenantShard(tenantID interface{}) (string, error) {
+	route := f.tenantRoute(tenantID)
+	if route == nil {
+		return "", fmt.Errorf("%w: %v", ErrTenantNotAssigned, tenantID)
+	}
+	route.mutex.Lock()
+	defer route.mutex.Unlock()
+	return route.shard, nil
+}
+
+// WithTenantDB runs fn with the connection of the shard of a tenant. While MoveTenant freezes the
+// writes of the tenant, WithTenantDB waits for the move to complete (or ctx to be done) and then runs
+// fn on the new shard, so writes made through it are never lost by a move.
+//
+// Example Usage:
+//
+//	err := factory.WithTenantDB(ctx, tenantID, func(db *gorm.DB) error {
+//		return db.Create(&invoice).Error
+//	})
+func (f *ConnectionManager) WithTenantDB(ctx context.Context, tenantID interface{}, fn func(db *gorm.DB) error) error {
+	route := f.tenantRoute(tenantID)
+	if route == nil {
+		return fmt.Errorf("%w: %v", ErrTenantNotAssigned, tenantID)
+	}
+	shard, err := route.enter(ctx)
+	if err != nil {
+		return err
+	}
+	defer route.leave()
+	db, err := f.GetDBContext(context.WithValue(ctx, tenantAdmittedKey{}, tenantMapKey(tenantID)), shard)
+	if err != nil {
+		return err
+	}
+	return fn(db)
+}
+
+// tenantWriteHook returns the statement hook refusing the writes tagged with WithTenant of a tenant
+// that is frozen or on another shard.
+func (f *ConnectionManager) tenantWriteHook() statementHook {
+	return func(ctx context.Context, stmt *hookedStatement) error {
+		tenantID, ok := TenantFromContext(ctx)
+		if !ok || ctx.Value(tenantAdmittedKey{}) == tenantMapKey(tenantID) || isIdempotentRead(stmt.SQL) {
+			return nil
+		}
+		route := f.tenantRoute(tenantID)
+		if route == nil {
+			return nil
+		}
+		route.mutex.Lock()
+		shard, frozen := route.shard, route.frozen != nil
+		route.mutex.Unlock()
+		if shard != stmt.Conn {
+			return &ConnError{Name: stmt.Conn, Op: OpQuery, Err: fmt.Errorf("%w: %v is on %q", ErrTenantMoved, tenantID, shard)}
+		}
+		if frozen {
+			return &ConnError{Name: stmt.Conn, Op: OpQuery, Err: fmt.Errorf("%w: %v", ErrTenantFrozen, tenantID)}
+		}
+		return nil
+	}
+}
+
+// TenantTable is a table holding rows of tenants, moved by MoveTenant.
+type TenantTable struct {
+	Name string
+
+	// TenantColumn holds the tenant ID. Defaults to tenant_id.
+	TenantColumn string
+
+	// PrimaryKey is the single-column primary key, looked up in information_schema when empty.
+	PrimaryKey string
+}
+
+// TenantMove describes the move of a tenant between shards.
+type TenantMove struct {
+	Tenant interface{}
+
+	// To is the destination shard. The source is the shard of the tenant in the shard map.
+	To string
+
+	// Tables are the tables holding rows of the tenant. The destination must have the same schema.
+	Tables []TenantTable
+
+	// BatchSize is the number of rows copied per statement. Defaults to 500.
+	BatchSize int
+
+	// FreezeWait bounds the wait for the writes in progress through WithTenantDB when the writes of the
+	// tenant are frozen. Defaults to five seconds.
+	FreezeWait time.Duration
+
+	// KeepSource leaves the rows of the tenant on the source shard after the move.
+	KeepSource bool
+}
+
+// TenantTableMove describes the rows of one table moved by MoveTenant.
+type TenantTableMove struct {
+	Table string
+
+	// Rows is the number of rows of the tenant on the destination after the move.
+	Rows int
+
+	// Recopied is true when the table changed during the online copy and was copied again during the freeze.
+	Recopied bool
+}
+
+// TenantMoveReport describes a completed MoveTenant.
+type TenantMoveReport struct {
+	From, To string
+	Tables   []TenantTableMove
+
+	// Frozen is how long the writes of the tenant were frozen.
+	Frozen time.Duration
+
+	// Duration is how long the whole move took.
+	Duration time.Duration
+}
+
+// MoveTenant moves the rows of a tenant to another shard and flips the shard map, with a short freeze
+// of the writes of the tenant instead of downtime.
+//
+// Parameters:
+// - ctx: Context bounding the move.
+// - move: The tenant, the destination shard and the tables of the tenant.
+//
+// Returns:
+// - *TenantMoveReport: The rows moved per table and the duration of the freeze.
+// - error: An error if the tenant is not assigned, a shard does not exist, copying fails or the copy
+// cannot be verified. The tenant then stays on its source shard, whose rows are untouched.
+//
+// Behavior:
+//  1. Online copy: the rows of the tenant already on the destination (left by an earlier failed move)
+//     are deleted, then the rows are copied from the source in primary key batches. Writes continue.
+//  2. Freeze: WithTenantDB stops admitting the tenant and the writes in progress are waited for. Writes
+//     tagged with WithTenant fail with ErrTenantFrozen.
+//  3. Verify: the rows of the tenant are checksummed on both shards. Tables written to during the online
+//     copy are copied again and verified once more, within the freeze.
+//  4. Flip: the shard map points to the destination and WithTenantDB resumes there.
+//  5. Cleanup: the rows of the tenant are deleted from the source, unless KeepSource. A cleanup failure
+//     is returned along with the report: the move itself is complete.
+//
+// Important: only the writes made through WithTenantDB, or run with a context tagged with WithTenant,
+// are frozen. A statement cannot be attributed to a tenant otherwise: the writes of the tenant made with
+// GetDB and an untagged context keep reaching the source during the freeze and after the flip, and are
+// deleted by the cleanup.
+//
+// Example Usage:
+//
+//	report, err := factory.MoveTenant(ctx, connection.TenantMove{
+//		Tenant: 42,
+//		To:     "tenants_eu_2",
+//		Tables: []connection.TenantTable{{Name: "invoices"}, {Name: "invoice_lines"}},
+//	})
+//	if err == nil {
+//		log.Printf("tenant 42 moved to %s, writes frozen for %v", report.To, report.Frozen)
+//	}
+func (f *ConnectionManager) MoveTenant(ctx context.Context, move TenantMove) (*TenantMoveReport, error) {
+	start := time.Now()
+	route := f.tenantRoute(move.Tenant)
+	if route == nil {
+		return nil, fmt.Errorf("%w: %v", ErrTenantNotAssigned, move.Tenant)
+	}
+	from, _ := f.TenantShard(move.Tenant)
+	if from == move.To {
+		return nil, fmt.Errorf("tenant %v is already on %q", move.Tenant, move.To)
+	}
+	src, err := f.GetDBContext(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	dst, err := f.GetDBContext(ctx, move.To)
+	if err != nil {
+		return nil, err
+	}
+	batch := move.BatchSize
+	if batch <= 0 {
+		batch = defaultTenantMoveBatch
+	}
+	tables := make([]TenantTable, len(move.Tables))
+	for i, table := range move.Tables {
+		if table.TenantColumn == "" {
+			table.TenantColumn = defaultTenantColumn
+		}
+		if table.PrimaryKey == "" {
+			if table.PrimaryKey, err = singlePrimaryKey(src, table.Name); err != nil {
+				return nil, fmt.Errorf("failed to move %q: %w", table.Name, err)
+			}
+		}
+		tables[i] = table
+	}
+
+	report := &TenantMoveReport{From: from, To: move.To, Tables: make([]TenantTableMove, len(tables))}
+	for i, table := range tables {
+		report.Tables[i].Table = table.Name
+		if err := recopyTenantRows(src, dst, table, move.Tenant, batch); err != nil {
+			return nil, connError(move.To, OpMoveTenant, err)
+		}
+	}
+
+	freezeWait := move.FreezeWait
+	if freezeWait <= 0 {
+		freezeWait = defaultTenantFreezeWait
+	}
+	freezeCtx, cancel := context.WithTimeout(ctx, freezeWait)
+	err = route.freeze(freezeCtx)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	frozen := time.Now()
+	for i, table := range tables {
+		rows, same, err := sameTenantRows(src, dst, table, move.Tenant)
+		if err == nil && !same {
+			report.Tables[i].Recopied = true
+			if err = recopyTenantRows(src, dst, table, move.Tenant, batch); err == nil {
+				rows, same, err = sameTenantRows(src, dst, table, move.Tenant)
+			}
+		}
+		if err == nil && !same {
+			err = fmt.Errorf("%w in %q", ErrTenantMoveMismatch, table.Name)
+		}
+		if err != nil {
+			route.thaw("")
+			return nil, connError(move.To, OpMoveTenant, err)
+		}
+		report.Tables[i].Rows = rows
+	}
+	route.thaw(move.To)
+	report.Frozen = time.Since(frozen)
+	log.Printf("Tenant %v moved from %q to %q, writes frozen for %v.", move.Tenant, from, move.To, report.Frozen)
+
+	if !move.KeepSource {
+		for _, table := range tables {
+			if err := deleteTenantRows(src, table, move.Tenant); err != nil {
+				report.Duration = time.Since(start)
+				return report, connError(from, OpMoveTenant, fmt.Errorf("tenant moved, failed to clean up %q: %w", table.Name, err))
+			}
+		}
+	}
+	report.Duration = time.Since(start)
+	return report, nil
+}
+
+// recopyTenantRows replaces the rows of a tenant in table on dst with those on src, in primary key batches.
+func recopyTenantRows(src, dst *gorm.DB, table TenantTable, tenantID interface{}, batch int) error {
+	if err := deleteTenantRows(dst, table, tenantID); err != nil {
+		return err
+	}
+	quotedColumn, quotedPK := quoteIdentifier(table.TenantColumn), quoteIdentifier(table.PrimaryKey)
+	var lower interface{}
+	for {
+		query := src.Table(table.Name).Where(quotedColumn+" = ?", tenantID)
+		if lower != nil {
+			query = query.Where(quotedPK+" > ?", lower)
+		}
+		var rows []map[string]interface{}
+		if err := query.Order(quotedPK).Limit(batch).Find(&rows).Error; err != nil {
+			return fmt.Errorf("failed to read %q: %w", table.Name, err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if err := dst.Table(table.Name).Create(&rows).Error; err != nil {
+			return fmt.Errorf("failed to copy %q: %w", table.Name, err)
+		}
+		if len(rows) < batch {
+			return nil
+		}
+		lower = keyValue(rows[len(rows)-1][table.PrimaryKey])
+	}
+}
+
+// sameTenantRows compares the checksums of the rows of a tenant in table on src and dst, and returns
+// the number of rows on dst.
+func sameTenantRows(src, dst *gorm.DB, table TenantTable, tenantID interface{}) (int, bool, error) {
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s = ? ORDER BY %s",
+		quoteIdentifier(table.Name), quoteIdentifier(table.TenantColumn), quoteIdentifier(table.PrimaryKey))
+	srcSum, err := checksumRows(src, table.PrimaryKey, query, tenantID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to checksum %q on the source: %w", table.Name, err)
+	}
+	dstSum, err := checksumRows(dst, table.PrimaryKey, query, tenantID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to checksum %q on the destination: %w", table.Name, err)
+	}
+	return dstSum.rows, srcSum.rows == dstSum.rows && srcSum.sum == dstSum.sum, nil
+}
+
+func deleteTenantRows(db *gorm.DB, table TenantTable, tenantID interface{}) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", quoteIdentifier(table.Name), quoteIdentifier(table.TenantColumn))
+	if err := db.Exec(query, tenantID).Error; err != nil {
+		return fmt.Errorf("failed to delete from %q: %w", table.Name, err)
+	}
+	return nil
+}
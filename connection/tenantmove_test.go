@@ -0,0 +1,105 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+type fakeInvoice struct {
+	ID       uint
+	TenantID int
+	Amount   int
+}
+
+func TestMoveTenant(t *testing.T) {
+	factory := newFakeFactory(t, FakeData{"fake_invoices": {Rows: []map[string]any{
+		{"id": 1, "tenant_id": 7, "amount": 10},
+		{"id": 2, "tenant_id": 8, "amount": 20},
+		{"id": 3, "tenant_id": 7, "amount": 30},
+		{"id": 4, "tenant_id": 7, "amount": 40},
+	}}})
+	if err := factory.InitFake("shard_2", FakeData{"fake_invoices": {Rows: []map[string]any{
+		{"id": 3, "tenant_id": 7, "amount": 99}, // left by an earlier failed move
+	}}}); err != nil {
+		t.Fatalf("InitFake failed: %v", err)
+	}
+	factory.AssignTenant(7, "primary_db")
+	ctx := context.Background()
+
+	// A write in progress delays the freeze until it completes.
+	written := make(chan struct{})
+	go func() {
+		_ = factory.WithTenantDB(ctx, 7, func(db *gorm.DB) error {
+			time.Sleep(50 * time.Millisecond)
+			defer close(written)
+			return db.Create(&fakeInvoice{ID: 5, TenantID: 7, Amount: 50}).Error
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	report, err := factory.MoveTenant(ctx, TenantMove{Tenant: "7", To: "shard_2", BatchSize: 2,
+		Tables: []TenantTable{{Name: "fake_invoices", PrimaryKey: "id"}}})
+	<-written
+	if err != nil {
+		t.Fatalf("MoveTenant failed: %v", err)
+	}
+	if report.From != "primary_db" || len(report.Tables) != 1 || report.Tables[0].Rows != 4 || !report.Tables[0].Recopied {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	if shard, err := factory.TenantShard(7); err != nil || shard != "shard_2" {
+		t.Fatalf("Expected the tenant on shard_2, got %q: %v", shard, err)
+	}
+
+	var amounts []int
+	err = factory.WithTenantDB(ctx, 7, func(db *gorm.DB) error {
+		return db.Model(&fakeInvoice{}).Where("tenant_id = ?", 7).Order("id").Pluck("amount", &amounts).Error
+	})
+	if err != nil || len(amounts) != 4 || amounts[1] != 30 || amounts[3] != 50 {
+		t.Fatalf("Unexpected rows on the destination %v: %v", amounts, err)
+	}
+	source, _ := factory.GetDB("primary_db")
+	var left []int
+	if source.Model(&fakeInvoice{}).Order("id").Pluck("tenant_id", &left); len(left) != 1 || left[0] != 8 {
+		t.Fatalf("Expected only the other tenant on the source, got %v", left)
+	}
+
+	if _, err := factory.MoveTenant(ctx, TenantMove{Tenant: 9, To: "shard_2"}); !errors.Is(err, ErrTenantNotAssigned) {
+		t.Fatalf("Expected ErrTenantNotAssigned, got %v", err)
+	}
+}
+
+func TestTenantWritesTagged(t *testing.T) {
+	factory := newFakeFactory(t, FakeData{"fake_invoices": {}})
+	if err := factory.InitFake("shard_2", FakeData{"fake_invoices": {}}); err != nil {
+		t.Fatalf("InitFake failed: %v", err)
+	}
+	factory.AssignTenant(7, "primary_db")
+	ctx := WithTenant(context.Background(), 7)
+	source, _ := factory.GetDB("primary_db")
+	if err := source.WithContext(ctx).Create(&fakeInvoice{ID: 1, TenantID: 7}).Error; err != nil {
+		t.Fatalf("Expected a tagged write on the shard of the tenant to run, got %v", err)
+	}
+
+	route := factory.tenantRoute(7)
+	if err := route.freeze(context.Background()); err != nil {
+		t.Fatalf("freeze failed: %v", err)
+	}
+	if err := source.WithContext(ctx).Create(&fakeInvoice{ID: 2, TenantID: 7}).Error; !errors.Is(err, ErrTenantFrozen) {
+		t.Fatalf("Expected ErrTenantFrozen during the freeze, got %v", err)
+	}
+	var count int64
+	if err := source.WithContext(ctx).Model(&fakeInvoice{}).Count(&count).Error; err != nil || count != 1 {
+		t.Fatalf("Expected reads to run during the freeze, got %d: %v", count, err)
+	}
+	if err := source.Create(&fakeInvoice{ID: 3, TenantID: 8}).Error; err != nil {
+		t.Fatalf("Expected untagged writes to run, got %v", err)
+	}
+	route.thaw("shard_2")
+
+	if err := source.WithContext(ctx).Create(&fakeInvoice{ID: 4, TenantID: 7}).Error; !errors.Is(err, ErrTenantMoved) {
+		t.Fatalf("Expected ErrTenantMoved on the source after the flip, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxExecutionTimeHook returns a statement hook adding a MAX_EXECUTION_TIME optimizer hint to SELECT
+// statements, so the server aborts them after limit even when the client has given up on them.
+func maxExecutionTimeHook(limit time.Duration) statementHook {
+	ms := limit.Milliseconds()
+	if time.Duration(ms)*time.Millisecond < limit {
+		ms++ // Round up: a partial millisecond must not shorten the limit to zero.
+	}
+	return func(ctx context.Context, stmt *hookedStatement) error {
+		stmt.SQL = withMaxExecutionTime(stmt.SQL, ms)
+		return nil
+	}
+}
+
+// withMaxExecutionTime inserts /*+ MAX_EXECUTION_TIME(ms) */ after the leading SELECT keyword of query.
+// Statements that are not SELECTs, or that already carry the hint, are returned unchanged. An existing
+// optimizer hint comment is extended, since MySQL only reads the first hint comment of a query block.
+func withMaxExecutionTime(query string, ms int64) string {
+	i := 0
+	for i < len(query) {
+		switch {
+		case query[i] == ' ' || query[i] == '\t' || query[i] == '\n' || query[i] == '\r':
+			i++
+			continue
+		case strings.HasPrefix(query[i:], "/*") && !strings.HasPrefix(query[i:], "/*+"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return query
+			}
+			i += end + 4
+			continue
+		}
+		break
+	}
+	if len(query)-i < 6 || !strings.EqualFold(query[i:i+6], "select") ||
+		(len(query) > i+6 && (isWordChar(query[i+6]) || isDigit(query[i+6]))) {
+		return query
+	}
+
+	hint := fmt.Sprintf("MAX_EXECUTION_TIME(%d)", ms)
+	pos := i + 6
+	rest := strings.TrimLeft(query[pos:], " \t\r\n")
+	if strings.HasPrefix(rest, "/*+") {
+		end := strings.Index(rest, "*/")
+		if end < 0 || strings.Contains(strings.ToUpper(rest[:end]), "MAX_EXECUTION_TIME") {
+			return query
+		}
+		start := len(query) - len(rest) + 3
+		return query[:start] + " " + hint + query[start:]
+	}
+	return query[:pos] + " /*+ " + hint + " */" + query[pos:]
+}
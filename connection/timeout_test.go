@@ -0,0 +1,34 @@
+package connection
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithMaxExecutionTime(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM orders":                   "SELECT /*+ MAX_EXECUTION_TIME(1500) */ * FROM orders",
+		"  /* app */ select id from t":           "  /* app */ select /*+ MAX_EXECUTION_TIME(1500) */ id from t",
+		"SELECT /*+ BKA(t) */ id FROM t":         "SELECT /*+ MAX_EXECUTION_TIME(1500) BKA(t) */ id FROM t",
+		"SELECT /*+ MAX_EXECUTION_TIME(10) */ 1": "SELECT /*+ MAX_EXECUTION_TIME(10) */ 1",
+		"UPDATE t SET a = (SELECT 1)":            "UPDATE t SET a = (SELECT 1)",
+		"SELECTED":                               "SELECTED",
+		"INSERT INTO t SELECT * FROM s":          "INSERT INTO t SELECT * FROM s",
+	}
+	for query, want := range cases {
+		if got := withMaxExecutionTime(query, 1500); got != want {
+			t.Errorf("withMaxExecutionTime(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestMaxExecutionTimeHookRoundsUp(t *testing.T) {
+	stmt := &hookedStatement{SQL: "SELECT 1"}
+	if err := maxExecutionTimeHook(1500*time.Microsecond)(context.Background(), stmt); err != nil {
+		t.Fatalf("hook failed: %v", err)
+	}
+	if stmt.SQL != "SELECT /*+ MAX_EXECUTION_TIME(2) */ 1" {
+		t.Fatalf("Unexpected rewrite %q", stmt.SQL)
+	}
+}
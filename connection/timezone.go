@@ -0,0 +1,116 @@
+package connection
+
+import (
+	"database/sql"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// TimeZoneCheck selects how InitDataSourceConnection checks that a connection reads and writes times in UTC.
+type TimeZoneCheck int
+
+const (
+	// TimeZoneCheckOff does not check time zones.
+	TimeZoneCheckOff TimeZoneCheck = iota
+
+	// TimeZoneCheckWarn logs a configuration warning (ConfigFieldTimeZone) when the data source name does
+	// not set parseTime=true and loc=UTC, or the session time_zone of the server is not UTC.
+	TimeZoneCheckWarn
+
+	// TimeZoneCheckEnforce fails the initialization with a *ConfigError on the same conditions.
+	TimeZoneCheckEnforce
+)
+
+// timeZoneWarnings checks the time zone settings of the data source name of c.
+func timeZoneWarnings(c DBConfig) []ConfigWarning {
+	if c.TimeZoneCheck == TimeZoneCheckOff || c.DataSourceName == "" {
+		return nil
+	}
+	cfg, err := mysqldriver.ParseDSN(c.DataSourceName)
+	if err != nil {
+		return nil
+	}
+	var warnings []ConfigWarning
+	if !cfg.ParseTime {
+		warnings = append(warnings, ConfigWarning{Field: ConfigFieldTimeZone,
+			Message: "parseTime=true is not set; DATETIME and TIMESTAMP columns are read as strings"})
+	}
+	if cfg.Loc != time.UTC {
+		warnings = append(warnings, ConfigWarning{Field: ConfigFieldTimeZone,
+			Message: fmt.Sprintf("loc is %s instead of UTC; times are read in that zone", cfg.Loc)})
+	}
+	if zone, ok := cfg.Params["time_zone"]; ok {
+		if !isUTCZone(strings.Trim(zone, `'"`)) {
+			warnings = append(warnings, ConfigWarning{Field: ConfigFieldTimeZone,
+				Message: fmt.Sprintf("time_zone is set to %s instead of UTC", zone)})
+		}
+	}
+	return warnings
+}
+
+// serverTimeZoneWarnings checks that the session time_zone of the server is UTC.
+func serverTimeZoneWarnings(db *gorm.DB) ([]ConfigWarning, error) {
+	var sessionZone, systemZone sql.NullString
+	if err := db.Raw("SELECT @@SESSION.time_zone, @@GLOBAL.system_time_zone").Row().Scan(&sessionZone, &systemZone); err != nil {
+		return nil, fmt.Errorf("failed to read time_zone: %w", err)
+	}
+	zone := sessionZone.String
+	if strings.EqualFold(zone, "SYSTEM") {
+		zone = systemZone.String
+	}
+	if isUTCZone(zone) {
+		return nil, nil
+	}
+	return []ConfigWarning{{Field: ConfigFieldTimeZone,
+		Message: fmt.Sprintf("server time_zone is %s instead of UTC; set time_zone=%%27%%2B00%%3A00%%27 in the data source name", zone)}}, nil
+}
+
+// isUTCZone reports whether a MySQL time zone value means UTC.
+func isUTCZone(zone string) bool {
+	switch strings.ToUpper(zone) {
+	case "UTC", "+00:00", "-00:00", "GMT", "ETC/UTC", "Z":
+		return true
+	}
+	return false
+}
+
+// hasTimeZoneWarning reports whether warnings contain a time zone warning.
+func hasTimeZoneWarning(warnings []ConfigWarning) bool {
+	for _, warning := range warnings {
+		if warning.Field == ConfigFieldTimeZone {
+			return true
+		}
+	}
+	return false
+}
+
+// NullTimeIn returns t at the same instant in loc, e.g. to display a UTC column in a user's zone.
+func NullTimeIn(t sql.NullTime, loc *time.Location) sql.NullTime {
+	if !t.Valid {
+		return t
+	}
+	return sql.NullTime{Time: t.Time.In(loc), Valid: true}
+}
+
+// NullTimeAsZone returns t with the same wall clock reinterpreted in loc. It repairs times read with
+// the wrong loc: a DATETIME written in Europe/Paris but read as UTC is fixed with
+// NullTimeAsZone(t, paris), which moves the instant, unlike NullTimeIn.
+func NullTimeAsZone(t sql.NullTime, loc *time.Location) sql.NullTime {
+	if !t.Valid {
+		return t
+	}
+	wall := t.Time
+	return sql.NullTime{Time: time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(),
+		wall.Nanosecond(), loc), Valid: true}
+}
+
+// UTCNullTime returns t in UTC as a sql.NullTime, invalid when t is the zero time.
+func UTCNullTime(t time.Time) sql.NullTime {
+	if t.IsZero() {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
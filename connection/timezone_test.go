@@ -0,0 +1,59 @@
+package connection
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeZoneWarnings(t *testing.T) {
+	config := DBConfig{DataSourceName: "user:pass@tcp(localhost:3306)/app", TimeZoneCheck: TimeZoneCheckWarn}
+	if w := timeZoneWarnings(config); len(w) != 1 || w[0].Field != ConfigFieldTimeZone {
+		t.Fatalf("Expected a parseTime warning, got %v", w)
+	}
+	config.DataSourceName = "user:pass@tcp(localhost:3306)/app?parseTime=true&loc=Local&time_zone=%27Europe%2FParis%27"
+	if w := timeZoneWarnings(config); len(w) != 2 {
+		t.Fatalf("Expected loc and time_zone warnings, got %v", w)
+	}
+	config.DataSourceName = "user:pass@tcp(localhost:3306)/app?parseTime=true&loc=UTC&time_zone=%27%2B00%3A00%27"
+	if w := timeZoneWarnings(config); len(w) != 0 {
+		t.Fatalf("Expected no warning for a UTC config, got %v", w)
+	}
+	config.DataSourceName = "user:pass@tcp(localhost:3306)/app"
+	config.TimeZoneCheck = TimeZoneCheckOff
+	if w := timeZoneWarnings(config); len(w) != 0 {
+		t.Fatalf("Expected no warning with TimeZoneCheckOff, got %v", w)
+	}
+
+	config.TimeZoneCheck = TimeZoneCheckEnforce
+	err := newTestFactory().InitDataSourceConnection("tz_db", config)
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || !hasTimeZoneWarning(configErr.Warnings) {
+		t.Fatalf("Expected a time zone *ConfigError, got %v", err)
+	}
+}
+
+func TestNullTimeConversions(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("Europe/Paris not available:", err)
+	}
+	utc := sql.NullTime{Time: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC), Valid: true}
+
+	if in := NullTimeIn(utc, paris); !in.Valid || !in.Time.Equal(utc.Time) || in.Time.Hour() != 13 {
+		t.Fatalf("Expected the same instant at 13:00 in Paris, got %v", in)
+	}
+	if as := NullTimeAsZone(utc, paris); as.Time.Hour() != 12 || !as.Time.Equal(utc.Time.Add(-time.Hour)) {
+		t.Fatalf("Expected 12:00 Paris time, got %v", as)
+	}
+	if null := NullTimeIn(sql.NullTime{}, paris); null.Valid {
+		t.Fatalf("Expected a NULL time to stay NULL, got %v", null)
+	}
+	if UTCNullTime(time.Time{}).Valid {
+		t.Fatal("Expected the zero time to be NULL")
+	}
+	if got := UTCNullTime(utc.Time.In(paris)); got.Time.Location() != time.UTC {
+		t.Fatalf("Expected a UTC time, got %v", got.Time)
+	}
+}
@@ -0,0 +1,417 @@
+package connection
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// txStatsPluginName is the name under which the transaction accounting is registered in gorm.Config.Plugins.
+const txStatsPluginName = "mysqlconn:tx_stats"
+
+// ErrGTIDWaitTimeout is returned by WaitForGTID when the server did not apply the GTID set in time.
+var ErrGTIDWaitTimeout = errors.New("timed out waiting for GTID set")
+
+// ErrNotInTransaction is returned by OnCommit and OnRollback when tx was not provided by Transaction
+// or BeginTx.
+var ErrNotInTransaction = errors.New("not in a transaction started by Transaction or BeginTx")
+
+// ErrTxInProgress is returned by BeginTx when the context already carries a transaction on the connection.
+var ErrTxInProgress = errors.New("transaction already in progress in context")
+
+// TxOptions configures Transaction.
+type TxOptions struct {
+	// SQL sets the isolation level and read-only mode of the transaction. Nil uses the server defaults.
+	SQL *sql.TxOptions
+
+	// CaptureLastInsertID reads LAST_INSERT_ID() before committing, into TxResult.LastInsertID.
+	CaptureLastInsertID bool
+
+	// CaptureGTID reads @@GLOBAL.gtid_executed after committing, into TxResult.GTIDExecuted. The set
+	// includes the transaction, so it can be used as a consistency token (see WaitForGTID).
+	CaptureGTID bool
+}
+
+// TxResult describes a committed transaction.
+type TxResult struct {
+	// RowsAffected is the total number of rows affected by the statements of the transaction.
+	RowsAffected int64
+
+	// LastInsertID is the first id generated by the last INSERT of the transaction, with CaptureLastInsertID.
+	LastInsertID int64
+
+	// GTIDExecuted is the GTID set executed by the server after the commit, with CaptureGTID. It is empty
+	// when GTIDs are disabled on the server.
+	GTIDExecuted string
+}
+
+type txStatsKey struct{}
+
+type txHooksKey struct{}
+
+// txHooks collects the functions registered with OnCommit and OnRollback during one transaction,
+// scoped to the savepoints they were registered in.
+type txHooks struct {
+	mutex      sync.Mutex
+	hooks      []txHook
+	savepoints []txSavepoint
+}
+
+// txHook is a function registered with OnCommit (onCommit) or OnRollback. undone marks the rollback
+// functions of a savepoint that was rolled back: their work is undone whatever the outcome.
+type txHook struct {
+	fn       func()
+	onCommit bool
+	undone   bool
+}
+
+// txSavepoint is a savepoint of the transaction and the number of functions registered before it.
+type txSavepoint struct {
+	name string
+	at   int
+}
+
+// txHooksOf returns the hooks of the transaction carried by ctx, or nil.
+func txHooksOf(ctx context.Context) *txHooks {
+	if ctx == nil {
+		return nil
+	}
+	hooks, _ := ctx.Value(txHooksKey{}).(*txHooks)
+	return hooks
+}
+
+func (h *txHooks) add(fn func(), onCommit bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.hooks = append(h.hooks, txHook{fn: fn, onCommit: onCommit})
+}
+
+// statement follows the savepoints of the transaction through its successful statements. Rolling back
+// to a savepoint drops the commit functions registered since, and keeps the rollback functions to run
+// whatever the outcome.
+func (h *txHooks) statement(query string) {
+	words := strings.Fields(strings.ToUpper(query))
+	if len(words) < 2 || len(words) > 4 {
+		return
+	}
+	name := strings.Trim(words[len(words)-1], "`")
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	switch {
+	case len(words) == 2 && words[0] == "SAVEPOINT":
+		// Setting a savepoint again moves it.
+		if i := h.findSavepoint(name); i >= 0 {
+			h.savepoints = append(h.savepoints[:i], h.savepoints[i+1:]...)
+		}
+		h.savepoints = append(h.savepoints, txSavepoint{name: name, at: len(h.hooks)})
+	case words[0] == "ROLLBACK" && words[1] == "TO":
+		i := h.findSavepoint(name)
+		if i < 0 {
+			return
+		}
+		// The savepoint stays, the later ones are deleted.
+		at := h.savepoints[i].at
+		h.savepoints = h.savepoints[:i+1]
+		kept := h.hooks[:at]
+		for _, hook := range h.hooks[at:] {
+			if !hook.onCommit {
+				hook.undone = true
+				kept = append(kept, hook)
+			}
+		}
+		h.hooks = kept
+	case words[0] == "RELEASE" && words[1] == "SAVEPOINT":
+		if i := h.findSavepoint(name); i >= 0 {
+			h.savepoints = h.savepoints[:i]
+		}
+	}
+}
+
+func (h *txHooks) findSavepoint(name string) int {
+	for i := len(h.savepoints) - 1; i >= 0; i-- {
+		if h.savepoints[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// run runs the commit or rollback functions in the order they were registered, once.
+func (h *txHooks) run(committed bool) {
+	h.mutex.Lock()
+	hooks := h.hooks
+	h.hooks, h.savepoints = nil, nil
+	h.mutex.Unlock()
+	for _, hook := range hooks {
+		if hook.onCommit == committed || hook.undone {
+			hook.fn()
+		}
+	}
+}
+
+// OnCommit registers fn to run after the transaction of tx commits, e.g. to publish an event or
+// invalidate a cache only once the change is durable. fn does not run when the transaction rolls back.
+//
+// Parameters:
+// - tx: The tx passed to the body of Transaction, the tx returned by BeginTx, or one derived from
+// them such as the result of CurrentDB.
+// - fn: The function to run after the commit, in registration order: before Transaction returns, or
+// when Commit of the tx of BeginTx returns.
+//
+// Returns:
+// - error: ErrNotInTransaction if tx was not provided by Transaction or BeginTx.
+//
+// Notes:
+//   - Functions registered in a nested transaction (a savepoint) are dropped when the transaction
+//     rolls back to that savepoint, and otherwise run when the outermost transaction commits.
+//
+// Example Usage:
+//
+//	_, err := factory.Transaction(ctx, "primary_db", func(tx *gorm.DB) error {
+//		if err := tx.Create(&order).Error; err != nil {
+//			return err
+//		}
+//		return connection.OnCommit(tx, func() { events.Publish(OrderCreated{ID: order.ID}) })
+//	}, connection.TxOptions{})
+func OnCommit(tx *gorm.DB, fn func()) error {
+	hooks := txHooksOf(tx.Statement.Context)
+	if hooks == nil {
+		return ErrNotInTransaction
+	}
+	hooks.add(fn, true)
+	return nil
+}
+
+// OnRollback registers fn to run after the transaction of tx rolls back, because its body failed or
+// panicked or the commit failed. fn does not run when the transaction commits, unless it was registered
+// in a nested transaction (a savepoint) that was rolled back: it then runs whatever the outcome.
+//
+// Example Usage:
+//
+//	_ = connection.OnRollback(tx, func() { reservations.Release(orderID) })
+func OnRollback(tx *gorm.DB, fn func()) error {
+	hooks := txHooksOf(tx.Statement.Context)
+	if hooks == nil {
+		return ErrNotInTransaction
+	}
+	hooks.add(fn, false)
+	return nil
+}
+
+// txStats accumulates the rows affected by the statements of one Transaction.
+type txStats struct {
+	rows atomic.Int64
+}
+
+// Transaction runs fn in a transaction on a managed connection and describes the committed transaction.
+//
+// Parameters:
+// - ctx: Context of the transaction. fn must issue its statements on the tx it receives.
+// - name: The name of the managed connection.
+// - fn: The body of the transaction. Returning an error (or panicking) rolls the transaction back.
+// It can register functions to run after the outcome with OnCommit and OnRollback.
+// - opts: Transaction options and the consistency information to capture.
+//
+// Returns:
+// - *TxResult: Rows affected and the captured LAST_INSERT_ID and GTID set.
+// - error: An error if the connection does not exist, fn fails, the commit fails or the capture fails.
+// A capture failure after the commit returns the result along with the error: the transaction is committed.
+//
+// Example Usage:
+//
+//	result, err := connection.GetConnectionManager().Transaction(ctx, "primary_db", func(tx *gorm.DB) error {
+//		return tx.Create(&order).Error
+//	}, connection.TxOptions{CaptureGTID: true})
+//	if err == nil {
+//		w.Header().Set("X-Consistency-Token", result.GTIDExecuted)
+//	}
+func (f *ConnectionManager) Transaction(ctx context.Context, name string, fn func(tx *gorm.DB) error, opts TxOptions) (*TxResult, error) {
+	db, err := f.GetDBContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &txStats{}
+	result := &TxResult{}
+	body := func(tx *gorm.DB) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if opts.CaptureLastInsertID {
+			if err := tx.Raw("SELECT LAST_INSERT_ID()").Scan(&result.LastInsertID).Error; err != nil {
+				return fmt.Errorf("failed to read LAST_INSERT_ID(): %w", err)
+			}
+		}
+		return nil
+	}
+	hooks := &txHooks{}
+	committed := false
+	defer func() {
+		if !committed {
+			hooks.run(false)
+		}
+	}()
+	txCtx := context.WithValue(context.WithValue(ctx, txStatsKey{}, stats), txHooksKey{}, hooks)
+	if err := db.WithContext(txCtx).Transaction(body, opts.SQL); err != nil {
+		return nil, err
+	}
+	committed = true
+	hooks.run(true)
+	result.RowsAffected = stats.rows.Load()
+
+	if opts.CaptureGTID {
+		var gtid sql.NullString
+		if err := db.Raw("SELECT @@GLOBAL.gtid_executed").Scan(&gtid).Error; err != nil {
+			return result, fmt.Errorf("transaction committed, failed to read gtid_executed: %w", err)
+		}
+		result.GTIDExecuted = gtid.String
+	}
+	return result, nil
+}
+
+// WaitForGTID waits until the server of a managed connection, typically a replica, has applied a GTID
+// set such as TxResult.GTIDExecuted, so reads after it see the writes of that transaction.
+//
+// Parameters:
+// - ctx: Context bounding the wait. Its deadline is the server-side timeout; without one, the wait is unbounded.
+// - name: The name of the managed connection.
+// - gtidSet: The GTID set to wait for. An empty set returns immediately.
+//
+// Returns:
+// - error: ErrGTIDWaitTimeout if the set was not applied before the deadline, or an error if the wait fails.
+func (f *ConnectionManager) WaitForGTID(ctx context.Context, name, gtidSet string) error {
+	if gtidSet == "" {
+		return nil
+	}
+	db, err := f.GetDBContext(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	timeout := 0.0
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = math.Max(0.001, time.Until(deadline).Seconds())
+	}
+	var timedOut sql.NullInt64
+	if err := db.Raw("SELECT WAIT_FOR_EXECUTED_GTID_SET(?, ?)", gtidSet, timeout).Scan(&timedOut).Error; err != nil {
+		return fmt.Errorf("failed to wait for GTID set on %q: %w", name, err)
+	}
+	if timedOut.Int64 == 1 {
+		return fmt.Errorf("%w on %q", ErrGTIDWaitTimeout, name)
+	}
+	return nil
+}
+
+// ctxTxKey is the context key of the transaction on one connection started with BeginTx.
+type ctxTxKey struct {
+	name string
+}
+
+// BeginTx starts a transaction on a managed connection and returns a context carrying it, so that the
+// code called with that context joins the transaction through CurrentDB instead of receiving the
+// *gorm.DB as a parameter.
+//
+// Parameters:
+// - ctx: The parent context. The transaction is bound to it.
+// - name: The name of the managed connection.
+// - opts: Optional isolation level and read-only mode.
+//
+// Returns:
+// - context.Context: ctx carrying the transaction.
+// - *gorm.DB: The transaction, to Commit or Rollback once the work is done.
+// - error: ErrTxInProgress if ctx already carries a transaction on the connection, or an error if the
+// connection does not exist or the transaction cannot be started.
+//
+// Notes:
+//   - Once the transaction is committed or rolled back, statements through the returned context fail:
+//     do not use it for work after the transaction.
+//   - Repo operations use CurrentDB, so repositories join the transaction of their context.
+//   - Functions registered with OnCommit and OnRollback run when Commit or Rollback of the returned
+//     transaction returns.
+//
+// Example Usage:
+//
+//	factory := connection.GetConnectionManager()
+//	ctx, tx, err := factory.BeginTx(ctx, "primary_db")
+//	if err != nil {
+//		return err
+//	}
+//	defer tx.Rollback()
+//	if err := orders.Create(ctx, &order); err != nil { // a Repo on primary_db: runs in tx
+//		return err
+//	}
+//	if err := inventory.Reserve(ctx, order.Items); err != nil { // calls CurrentDB(ctx, "primary_db")
+//		return err
+//	}
+//	return tx.Commit().Error
+func (f *ConnectionManager) BeginTx(ctx context.Context, name string, opts ...*sql.TxOptions) (context.Context, *gorm.DB, error) {
+	if _, ok := ctx.Value(ctxTxKey{name}).(*gorm.DB); ok {
+		return nil, nil, connError(name, OpBeginTx, ErrTxInProgress)
+	}
+	db, err := f.GetDBContext(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx = context.WithValue(ctx, txHooksKey{}, &txHooks{})
+	tx := db.WithContext(ctx).Begin(opts...)
+	if tx.Error != nil {
+		return nil, nil, connError(name, OpBeginTx, tx.Error)
+	}
+	return context.WithValue(ctx, ctxTxKey{name}, tx), tx, nil
+}
+
+// CurrentDB returns the transaction on a managed connection carried by ctx (see BeginTx), or else the
+// connection like GetDBContext. Either way the result is bound to ctx.
+//
+// Example Usage:
+//
+//	func (s *InventoryStore) Reserve(ctx context.Context, items []Item) error {
+//		db, err := connection.GetConnectionManager().CurrentDB(ctx, "primary_db")
+//		if err != nil {
+//			return err
+//		}
+//		return db.Model(&Stock{}).Where("sku IN ?", skus(items)).Update("reserved", gorm.Expr("reserved + 1")).Error
+//	}
+func (f *ConnectionManager) CurrentDB(ctx context.Context, name string) (*gorm.DB, error) {
+	if tx, ok := ctx.Value(ctxTxKey{name}).(*gorm.DB); ok {
+		return tx.WithContext(ctx), nil
+	}
+	return f.GetDBContext(ctx, name)
+}
+
+// txStatsPlugin adds the rows affected by each statement run with a Transaction context to its txStats.
+type txStatsPlugin struct{}
+
+func (txStatsPlugin) Name() string {
+	return txStatsPluginName
+}
+
+func (p txStatsPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().After("*").Register(txStatsPluginName+"_create", p.record); err != nil {
+		return err
+	}
+	if err := cb.Update().After("*").Register(txStatsPluginName+"_update", p.record); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("*").Register(txStatsPluginName+"_delete", p.record); err != nil {
+		return err
+	}
+	return cb.Raw().After("*").Register(txStatsPluginName+"_raw", p.record)
+}
+
+func (txStatsPlugin) record(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Context == nil || db.RowsAffected <= 0 {
+		return
+	}
+	if stats, ok := db.Statement.Context.Value(txStatsKey{}).(*txStats); ok {
+		stats.rows.Add(db.RowsAffected)
+	}
+}
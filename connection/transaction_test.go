@@ -0,0 +1,262 @@
+package connection
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"gorm.io/gorm"
+	"strings"
+	"testing"
+)
+
+// txConnector opens connections supporting transactions, whose statements affect two rows and whose
+// queries return LAST_INSERT_ID() 41 and the GTID set "uuid:1-7".
+type txConnector struct {
+	commits, rollbacks *int
+}
+
+func (c txConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &txConn{connector: c}, nil
+}
+
+func (txConnector) Driver() driver.Driver {
+	return nil
+}
+
+type txConn struct {
+	fakeConn
+	connector txConnector
+}
+
+func (c *txConn) Begin() (driver.Tx, error) {
+	return txFake{c.connector}, nil
+}
+
+func (c *txConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return driver.RowsAffected(2), nil
+}
+
+func (c *txConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if strings.Contains(query, "gtid_executed") {
+		return &fakeRows{values: []driver.Value{"uuid:1-7"}}, nil
+	}
+	return &fakeRows{values: []driver.Value{int64(41)}}, nil
+}
+
+type txFake struct {
+	connector txConnector
+}
+
+func (t txFake) Commit() error {
+	*t.connector.commits++
+	return nil
+}
+
+func (t txFake) Rollback() error {
+	*t.connector.rollbacks++
+	return nil
+}
+
+func TestTransactionResult(t *testing.T) {
+	var commits, rollbacks int
+	db := newConnectorDB(t, txConnector{commits: &commits, rollbacks: &rollbacks})
+	if err := db.Use(txStatsPlugin{}); err != nil {
+		t.Fatalf("Use failed: %v", err)
+	}
+	factory := newTestFactory()
+	factory.connections["tx_db"] = db
+	ctx := context.Background()
+
+	result, err := factory.Transaction(ctx, "tx_db", func(tx *gorm.DB) error {
+		if err := tx.Exec("INSERT INTO orders (id) VALUES (NULL)").Error; err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE stock SET count = count - 1").Error
+	}, TxOptions{CaptureLastInsertID: true, CaptureGTID: true})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if result.RowsAffected != 4 || result.LastInsertID != 41 || result.GTIDExecuted != "uuid:1-7" || commits != 1 {
+		t.Fatalf("Unexpected result %+v after %d commits", result, commits)
+	}
+
+	// Statements outside the transaction are not counted.
+	if err := db.Exec("DELETE FROM carts").Error; err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	result, err = factory.Transaction(ctx, "tx_db", func(tx *gorm.DB) error { return nil }, TxOptions{})
+	if err != nil || result.RowsAffected != 0 || result.GTIDExecuted != "" {
+		t.Fatalf("Unexpected empty transaction result %+v, %v", result, err)
+	}
+
+	failure := errors.New("out of stock")
+	if _, err := factory.Transaction(ctx, "tx_db", func(tx *gorm.DB) error { return failure }, TxOptions{}); !errors.Is(err, failure) || rollbacks != 1 {
+		t.Fatalf("Expected a rolled back transaction, got %v after %d rollbacks", err, rollbacks)
+	}
+	if _, err := factory.Transaction(ctx, "missing_db", func(tx *gorm.DB) error { return nil }, TxOptions{}); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
+
+func TestWaitForGTID(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["replica_db"] = newConnectorDB(t, &fakeConnector{})
+	ctx := context.Background()
+
+	// fakeConn answers 1, a timeout of WAIT_FOR_EXECUTED_GTID_SET.
+	if err := factory.WaitForGTID(ctx, "replica_db", "uuid:1-7"); !errors.Is(err, ErrGTIDWaitTimeout) {
+		t.Fatalf("Expected ErrGTIDWaitTimeout, got %v", err)
+	}
+	if err := factory.WaitForGTID(ctx, "replica_db", ""); err != nil {
+		t.Fatalf("Expected no wait for an empty set, got %v", err)
+	}
+}
+
+func TestTransactionHooks(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	ctx := context.Background()
+
+	var events []string
+	record := func(event string) func() { return func() { events = append(events, event) } }
+	_, err := factory.Transaction(ctx, "primary_db", func(tx *gorm.DB) error {
+		if err := OnCommit(tx, record("commit 1")); err != nil {
+			return err
+		}
+		_ = OnRollback(tx, record("rollback"))
+		if len(events) != 0 {
+			t.Fatalf("Expected no hook to run before the outcome, got %v", events)
+		}
+		return OnCommit(tx.Model(&fakeTestUser{}), record("commit 2"))
+	}, TxOptions{})
+	if err != nil || strings.Join(events, ",") != "commit 1,commit 2" {
+		t.Fatalf("Unexpected hooks after a commit: %v, %v", events, err)
+	}
+
+	events = nil
+	failure := errors.New("failed")
+	_, err = factory.Transaction(ctx, "primary_db", func(tx *gorm.DB) error {
+		_ = OnCommit(tx, record("commit"))
+		_ = OnRollback(tx, record("rollback"))
+		return failure
+	}, TxOptions{})
+	if !errors.Is(err, failure) || strings.Join(events, ",") != "rollback" {
+		t.Fatalf("Unexpected hooks after a rollback: %v, %v", events, err)
+	}
+
+	events = nil
+	func() {
+		defer func() { _ = recover() }()
+		_, _ = factory.Transaction(ctx, "primary_db", func(tx *gorm.DB) error {
+			_ = OnRollback(tx, record("rollback"))
+			panic("boom")
+		}, TxOptions{})
+	}()
+	if strings.Join(events, ",") != "rollback" {
+		t.Fatalf("Expected the rollback hook to run after a panic, got %v", events)
+	}
+
+	db, _ := factory.GetDB("primary_db")
+	if err := OnCommit(db, record("outside")); !errors.Is(err, ErrNotInTransaction) {
+		t.Fatalf("Expected ErrNotInTransaction, got %v", err)
+	}
+}
+
+func TestBeginTxContext(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	repo := NewRepo[fakeTestUser](factory, "primary_db")
+
+	ctx, tx, err := factory.BeginTx(context.Background(), "primary_db")
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	if _, _, err := factory.BeginTx(ctx, "primary_db"); !errors.Is(err, ErrTxInProgress) {
+		t.Fatalf("Expected ErrTxInProgress, got %v", err)
+	}
+	if err := repo.Create(ctx, &fakeTestUser{Name: "dave", Email: "dave@example.com"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	db, err := factory.CurrentDB(ctx, "primary_db")
+	var count int64
+	if err != nil || db.Model(&fakeTestUser{}).Count(&count).Error != nil || count != 4 {
+		t.Fatalf("Expected the transaction to see 4 users, got %d: %v", count, err)
+	}
+	if err := tx.Rollback().Error; err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if users, err := repo.List(context.Background(), 0, 10); err != nil || len(users) != 3 {
+		t.Fatalf("Expected the insert to be rolled back, got %d users: %v", len(users), err)
+	}
+
+	ctx, tx, _ = factory.BeginTx(context.Background(), "primary_db")
+	if err := repo.Delete(ctx, 1); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := tx.Commit().Error; err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if users, err := repo.List(context.Background(), 0, 10); err != nil || len(users) != 2 {
+		t.Fatalf("Expected the delete to be committed, got %d users: %v", len(users), err)
+	}
+
+	if _, _, err := factory.BeginTx(context.Background(), "missing"); !errors.Is(err, ErrConnectionNotFound) {
+		t.Fatalf("Expected ErrConnectionNotFound, got %v", err)
+	}
+}
+
+func TestTransactionHooksSavepoints(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	ctx := context.Background()
+
+	var events []string
+	record := func(event string) func() { return func() { events = append(events, event) } }
+	failure := errors.New("failed")
+	_, err := factory.Transaction(ctx, "primary_db", func(tx *gorm.DB) error {
+		_ = OnCommit(tx, record("outer"))
+		_ = tx.Transaction(func(nested *gorm.DB) error {
+			_ = OnCommit(nested, record("undone commit"))
+			_ = OnRollback(nested, record("undone rollback"))
+			return failure
+		})
+		return tx.Transaction(func(nested *gorm.DB) error {
+			_ = OnRollback(nested, record("kept rollback"))
+			return OnCommit(nested, record("kept commit"))
+		})
+	}, TxOptions{})
+	if err != nil || strings.Join(events, ",") != "outer,undone rollback,kept commit" {
+		t.Fatalf("Unexpected hooks after a commit with a rolled back savepoint: %v, %v", events, err)
+	}
+}
+
+func TestBeginTxHooks(t *testing.T) {
+	factory := newFakeFactory(t, fakeUsers())
+	var events []string
+	record := func(event string) func() { return func() { events = append(events, event) } }
+
+	ctx, tx, err := factory.BeginTx(context.Background(), "primary_db")
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	db, _ := factory.CurrentDB(ctx, "primary_db")
+	if err := OnCommit(db, record("commit")); err != nil {
+		t.Fatalf("OnCommit failed: %v", err)
+	}
+	_ = OnRollback(tx, record("rollback"))
+	if len(events) != 0 {
+		t.Fatalf("Expected no hook before the outcome, got %v", events)
+	}
+	if err := tx.Commit().Error; err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	_ = tx.Rollback()
+	if strings.Join(events, ",") != "commit" {
+		t.Fatalf("Unexpected hooks after Commit: %v", events)
+	}
+
+	events = nil
+	_, tx, _ = factory.BeginTx(context.Background(), "primary_db")
+	_ = OnCommit(tx, record("commit"))
+	_ = OnRollback(tx, record("rollback"))
+	if err := tx.Rollback().Error; err != nil || strings.Join(events, ",") != "rollback" {
+		t.Fatalf("Unexpected hooks after Rollback: %v, %v", events, err)
+	}
+}
@@ -0,0 +1,251 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// MySQL errors on which Upsert retries a batch: ER_LOCK_DEADLOCK and ER_LOCK_WAIT_TIMEOUT.
+const (
+	erLockDeadlock    = 1213
+	erLockWaitTimeout = 1205
+)
+
+// Batching and retries of Upsert.
+const (
+	upsertBatchSize   = 500
+	upsertMaxAttempts = 3
+	upsertRetryDelay  = 50 * time.Millisecond
+)
+
+// UpsertStrategy is the handling of rows whose key already exists by Upsert.
+type UpsertStrategy int
+
+const (
+	// UpsertUpdate updates every column of the existing row except the primary key
+	// (INSERT ... ON DUPLICATE KEY UPDATE).
+	UpsertUpdate UpsertStrategy = iota
+
+	// UpsertIgnore keeps the existing row (INSERT IGNORE). Note that INSERT IGNORE also turns other
+	// errors, such as values out of range, into warnings.
+	UpsertIgnore
+)
+
+// UpsertOutcome is what Upsert did with one row.
+type UpsertOutcome int
+
+const (
+	// UpsertInserted is a row whose key did not exist.
+	UpsertInserted UpsertOutcome = iota
+
+	// UpsertUpdated is a row that updated the existing row with its key.
+	UpsertUpdated
+
+	// UpsertSkipped is a row left out because its key existed (UpsertIgnore).
+	UpsertSkipped
+)
+
+func (o UpsertOutcome) String() string {
+	switch o {
+	case UpsertInserted:
+		return "inserted"
+	case UpsertUpdated:
+		return "updated"
+	case UpsertSkipped:
+		return "skipped"
+	}
+	return fmt.Sprintf("UpsertOutcome(%d)", int(o))
+}
+
+// Upsert inserts rows into the table of their model, handling rows whose key already exists with strategy.
+//
+// Parameters:
+// - ctx: Context bounding the whole upsert.
+// - name: The name of the managed connection.
+// - rows: A slice of models (or of pointers to models), as accepted by GORM's Create.
+// - conflictCols: The columns of the unique key identifying existing rows, e.g. []string{"email"}.
+// - strategy: UpsertUpdate or UpsertIgnore.
+//
+// Returns:
+// - []UpsertOutcome: The outcome of each row, in the order of rows. On error, the outcomes of the
+// batches committed before the failing one.
+// - error: An error if the connection does not exist, rows is not a slice of models, a conflict column
+// is unknown or a batch fails.
+//
+// Behavior:
+// 1. Rows are written in batches of 500, each in its own transaction: the existing keys of the batch are
+// locked with SELECT ... FOR UPDATE, then the batch is inserted with ON DUPLICATE KEY UPDATE or INSERT IGNORE.
+// 2. A row is reported updated (or skipped with UpsertIgnore) when its key existed or appeared earlier in
+// rows. Keys are compared as exact values, so a case-insensitive collation may report a row as inserted.
+// 3. A batch failing with a deadlock or a lock wait timeout is retried up to 3 times.
+//
+// Example Usage:
+//
+//	outcomes, err := connection.GetConnectionManager().Upsert(ctx, "primary_db", customers,
+//		[]string{"email"}, connection.UpsertUpdate)
+//	for i, outcome := range outcomes {
+//		log.Printf("%s: %s", customers[i].Email, outcome)
+//	}
+func (f *ConnectionManager) Upsert(ctx context.Context, name string, rows interface{}, conflictCols []string, strategy UpsertStrategy) ([]UpsertOutcome, error) {
+	if strategy != UpsertUpdate && strategy != UpsertIgnore {
+		return nil, fmt.Errorf("invalid upsert strategy %d", strategy)
+	}
+	if len(conflictCols) == 0 {
+		return nil, errors.New("upsert needs at least one conflict column")
+	}
+	slice := reflect.Indirect(reflect.ValueOf(rows))
+	if slice.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("upsert rows must be a slice, got %T", rows)
+	}
+	db, err := f.GetDBContext(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if slice.Len() == 0 {
+		return nil, nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(rows); err != nil {
+		return nil, fmt.Errorf("failed to parse upsert model: %w", err)
+	}
+	fields := make([]*schema.Field, len(conflictCols))
+	for i, column := range conflictCols {
+		if fields[i] = stmt.Schema.LookUpField(column); fields[i] == nil || fields[i].DBName == "" {
+			return nil, fmt.Errorf("unknown conflict column %q of %s", column, stmt.Schema.Table)
+		}
+	}
+
+	outcomes := make([]UpsertOutcome, 0, slice.Len())
+	seen := make(map[string]bool)
+	for start := 0; start < slice.Len(); start += upsertBatchSize {
+		end := start + upsertBatchSize
+		if end > slice.Len() {
+			end = slice.Len()
+		}
+		batch := slice.Slice(start, end)
+		keys := make([]string, batch.Len())
+		values := make([][]interface{}, batch.Len())
+		for i := range keys {
+			values[i] = make([]interface{}, len(fields))
+			for j, field := range fields {
+				values[i][j], _ = field.ValueOf(ctx, reflect.Indirect(batch.Index(i)))
+			}
+			keys[i] = upsertKey(values[i])
+		}
+
+		var existing map[string]bool
+		for attempt := 1; ; attempt++ {
+			existing, err = upsertBatch(db, stmt.Schema.Table, fields, values, batch.Interface(), strategy)
+			if err == nil || !isLockConflict(err) || attempt == upsertMaxAttempts || ctx.Err() != nil {
+				break
+			}
+			if sleep(ctx, f.clock(), time.Duration(attempt)*upsertRetryDelay) != nil {
+				break
+			}
+		}
+		if err != nil {
+			return outcomes, fmt.Errorf("upsert of rows %d to %d into %s failed: %w", start, end-1, stmt.Schema.Table, err)
+		}
+		for key := range existing {
+			seen[key] = true
+		}
+		outcomes = append(outcomes, upsertOutcomes(keys, seen, strategy)...)
+	}
+	return outcomes, nil
+}
+
+// upsertBatch writes one batch in a transaction and returns the keys that existed before it.
+func upsertBatch(db *gorm.DB, table string, fields []*schema.Field, values [][]interface{}, batch interface{}, strategy UpsertStrategy) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		columns := make([]string, len(fields))
+		for i, field := range fields {
+			columns[i] = quoteIdentifier(field.DBName)
+		}
+		tuple := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(fields)), ", ") + ")"
+		tuples := make([]string, len(values))
+		var args []interface{}
+		for i, row := range values {
+			tuples[i] = tuple
+			args = append(args, row...)
+		}
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE (%s) IN (%s) FOR UPDATE", strings.Join(columns, ", "),
+			quoteIdentifier(table), strings.Join(columns, ", "), strings.Join(tuples, ", "))
+
+		rows, err := tx.Raw(query, args...).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			row := make([]interface{}, len(fields))
+			ptrs := make([]interface{}, len(fields))
+			for i := range row {
+				ptrs[i] = &row[i]
+			}
+			if err := rows.Scan(ptrs...); err != nil {
+				return err
+			}
+			existing[upsertKey(row)] = true
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		return upsertClauses(tx, strategy).Create(batch).Error
+	})
+	return existing, err
+}
+
+// upsertClauses adds the conflict handling of strategy to db.
+func upsertClauses(db *gorm.DB, strategy UpsertStrategy) *gorm.DB {
+	if strategy == UpsertIgnore {
+		return db.Clauses(clause.Insert{Modifier: "IGNORE"})
+	}
+	return db.Clauses(clause.OnConflict{UpdateAll: true})
+}
+
+// upsertOutcomes returns the outcome of each key of a batch given the keys existing before it, and adds
+// the keys of the batch to existing.
+func upsertOutcomes(keys []string, existing map[string]bool, strategy UpsertStrategy) []UpsertOutcome {
+	outcomes := make([]UpsertOutcome, len(keys))
+	for i, key := range keys {
+		switch {
+		case !existing[key]:
+			outcomes[i] = UpsertInserted
+		case strategy == UpsertIgnore:
+			outcomes[i] = UpsertSkipped
+		default:
+			outcomes[i] = UpsertUpdated
+		}
+		existing[key] = true
+	}
+	return outcomes
+}
+
+// upsertKey renders key column values so that model values and values scanned from the server compare equal.
+func upsertKey(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		parts[i] = fmt.Sprint(value)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// isLockConflict reports whether err is a deadlock or a lock wait timeout.
+func isLockConflict(err error) bool {
+	var mysqlErr *mysqldriver.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == erLockDeadlock || mysqlErr.Number == erLockWaitTimeout)
+}
@@ -0,0 +1,73 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type upsertTestCustomer struct {
+	ID    uint
+	Email string `gorm:"uniqueIndex"`
+	Name  string
+}
+
+func TestUpsertOutcomes(t *testing.T) {
+	existing := map[string]bool{upsertKey([]interface{}{[]byte("a@x")}): true}
+	keys := []string{
+		upsertKey([]interface{}{"a@x"}),
+		upsertKey([]interface{}{"b@x"}),
+		upsertKey([]interface{}{"b@x"}),
+	}
+	got := upsertOutcomes(keys, existing, UpsertUpdate)
+	if want := []UpsertOutcome{UpsertUpdated, UpsertInserted, UpsertUpdated}; !reflect.DeepEqual(got, want) {
+		t.Errorf("outcomes = %v, want %v", got, want)
+	}
+	got = upsertOutcomes([]string{upsertKey([]interface{}{"a@x"}), upsertKey([]interface{}{"c@x"})}, existing, UpsertIgnore)
+	if want := []UpsertOutcome{UpsertSkipped, UpsertInserted}; !reflect.DeepEqual(got, want) {
+		t.Errorf("outcomes = %v, want %v", got, want)
+	}
+	if UpsertSkipped.String() != "skipped" {
+		t.Errorf("String() = %q", UpsertSkipped.String())
+	}
+}
+
+func TestUpsertClauses(t *testing.T) {
+	db := newDryRunDB(t)
+	customers := []upsertTestCustomer{{Email: "a@x", Name: "A"}, {Email: "b@x", Name: "B"}}
+
+	sql := upsertClauses(db, UpsertUpdate).Create(&customers).Statement.SQL.String()
+	if !strings.Contains(sql, "ON DUPLICATE KEY UPDATE `email`=VALUES(`email`),`name`=VALUES(`name`)") {
+		t.Errorf("Unexpected update statement %s", sql)
+	}
+	sql = upsertClauses(newDryRunDB(t), UpsertIgnore).Create(&customers).Statement.SQL.String()
+	if !strings.HasPrefix(sql, "INSERT IGNORE INTO `upsert_test_customers`") {
+		t.Errorf("Unexpected ignore statement %s", sql)
+	}
+}
+
+func TestUpsertErrors(t *testing.T) {
+	factory := newTestFactory()
+	factory.connections["upsert_db"] = newConnectorDB(t, &fakeConnector{})
+	ctx := context.Background()
+	customers := []upsertTestCustomer{{Email: "a@x"}}
+
+	if _, err := factory.Upsert(ctx, "upsert_db", customers, []string{"missing"}, UpsertUpdate); err == nil {
+		t.Error("Expected an error for an unknown conflict column, got nil")
+	}
+	if _, err := factory.Upsert(ctx, "upsert_db", customers[0], []string{"email"}, UpsertUpdate); err == nil {
+		t.Error("Expected an error for rows that are not a slice, got nil")
+	}
+	if _, err := factory.Upsert(ctx, "upsert_db", customers, []string{"email"}, UpsertStrategy(9)); err == nil {
+		t.Error("Expected an error for an invalid strategy, got nil")
+	}
+
+	deadlock := fmt.Errorf("batch: %w", &mysqldriver.MySQLError{Number: erLockDeadlock})
+	if !isLockConflict(deadlock) || isLockConflict(errors.New("other")) {
+		t.Error("isLockConflict misclassified errors")
+	}
+}
@@ -0,0 +1,142 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"strings"
+	"time"
+)
+
+// ErrInvalidConfig matches every *ConfigError with errors.Is.
+var ErrInvalidConfig = errors.New("inconsistent connection configuration")
+
+// Fields reported in ConfigWarning.Field.
+const (
+	ConfigFieldMaxIdle    = "MaxIdle"
+	ConfigFieldIdleTime   = "IdleTime"
+	ConfigFieldLifetime   = "Lifetime"
+	ConfigFieldPoolShards = "PoolShards"
+
+	ConfigFieldLifetimeJitter  = "LifetimeJitter"
+	ConfigFieldConnectRampRate = "ConnectRampRate"
+
+	// ConfigFieldTimeZone reports the time zone settings of DataSourceName and the server (see TimeZoneCheck).
+	ConfigFieldTimeZone = "TimeZone"
+
+	// ConfigFieldCompress reports a server not allowing the zlib protocol compression of Compress.
+	ConfigFieldCompress = "Compress"
+
+	// ConfigFieldMaxAllowedPacket reports a MaxAllowedPacket above the limit of the server.
+	ConfigFieldMaxAllowedPacket = "MaxAllowedPacket"
+
+	ConfigFieldPriorityAcquisition = "PriorityAcquisition"
+)
+
+// ConfigWarning describes a pool setting that is accepted but does not behave as configured.
+type ConfigWarning struct {
+	// Field is the DBConfig field at fault, one of the ConfigField constants.
+	Field   string
+	Message string
+}
+
+func (w ConfigWarning) String() string {
+	return w.Field + ": " + w.Message
+}
+
+// ConfigError is returned by InitDataSourceConnection in strict mode (DBConfig.StrictConfig)
+// when the configuration has warnings.
+type ConfigError struct {
+	Connection string
+	Warnings   []ConfigWarning
+}
+
+func (e *ConfigError) Error() string {
+	messages := make([]string, len(e.Warnings))
+	for i, w := range e.Warnings {
+		messages[i] = w.String()
+	}
+	return fmt.Sprintf("inconsistent configuration for %q: %s", e.Connection, strings.Join(messages, "; "))
+}
+
+// Is makes errors.Is(err, ErrInvalidConfig) true for configuration errors.
+func (e *ConfigError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+// Validate checks the pool settings for combinations that database/sql accepts but does not honor.
+// Checks that need the server (wait_timeout) are made by InitDataSourceConnection.
+func (c DBConfig) Validate() []ConfigWarning {
+	var warnings []ConfigWarning
+	if c.MaxOpen > 0 && c.MaxIdle > c.MaxOpen {
+		warnings = append(warnings, ConfigWarning{Field: ConfigFieldMaxIdle,
+			Message: fmt.Sprintf("MaxIdle (%d) exceeds MaxOpen (%d); idle connections are capped at MaxOpen", c.MaxIdle, c.MaxOpen)})
+	}
+	if c.Lifetime > 0 && c.IdleTime > c.Lifetime {
+		warnings = append(warnings, ConfigWarning{Field: ConfigFieldIdleTime,
+			Message: fmt.Sprintf("IdleTime (%v) exceeds Lifetime (%v) and has no effect", c.IdleTime, c.Lifetime)})
+	}
+	if c.PoolShards > 1 && c.MaxOpen > 0 && c.MaxOpen < c.PoolShards {
+		warnings = append(warnings, ConfigWarning{Field: ConfigFieldPoolShards,
+			Message: fmt.Sprintf("PoolShards (%d) exceeds MaxOpen (%d); each shard opens at least one connection", c.PoolShards, c.MaxOpen)})
+	}
+	if c.PoolShards > 1 && c.PrepareStmt {
+		warnings = append(warnings, ConfigWarning{Field: ConfigFieldPoolShards,
+			Message: "PrepareStmt runs each cached statement on the shard it was prepared on, which defeats PoolShards"})
+	}
+	warnings = append(warnings, protocolWarnings(c)...)
+	warnings = append(warnings, priorityWarnings(c)...)
+	warnings = append(warnings, lifetimeJitterWarnings(c)...)
+	warnings = append(warnings, connectRampWarnings(c)...)
+	return append(warnings, timeZoneWarnings(c)...)
+}
+
+// validateAgainstServer checks the pool settings against the server's wait_timeout: connections the
+// pool keeps longer than that may have been closed by the server when they are next used.
+func (c DBConfig) validateAgainstServer(db *gorm.DB) ([]ConfigWarning, error) {
+	var seconds int64
+	if err := db.Raw("SELECT @@SESSION.wait_timeout").Row().Scan(&seconds); err != nil {
+		return nil, fmt.Errorf("failed to read wait_timeout: %w", err)
+	}
+	warnings := lifetimeWarnings(c, time.Duration(seconds)*time.Second)
+	if c.TimeZoneCheck != TimeZoneCheckOff {
+		zoneWarnings, err := serverTimeZoneWarnings(db)
+		if err != nil {
+			return warnings, err
+		}
+		warnings = append(warnings, zoneWarnings...)
+	}
+	protocol, err := serverProtocolWarnings(c, db)
+	return append(warnings, protocol...), err
+}
+
+// lifetimeWarnings reports a Lifetime that is unlimited or longer than the server's wait_timeout.
+func lifetimeWarnings(c DBConfig, waitTimeout time.Duration) []ConfigWarning {
+	if waitTimeout <= 0 {
+		return nil
+	}
+	switch {
+	case c.Lifetime <= 0:
+		return []ConfigWarning{{Field: ConfigFieldLifetime,
+			Message: fmt.Sprintf("Lifetime is unlimited but the server closes connections idle for wait_timeout (%v)", waitTimeout)}}
+	case c.Lifetime > waitTimeout:
+		return []ConfigWarning{{Field: ConfigFieldLifetime,
+			Message: fmt.Sprintf("Lifetime (%v) exceeds the server's wait_timeout (%v)", c.Lifetime, waitTimeout)}}
+	case poolLifetime(c) > waitTimeout:
+		return []ConfigWarning{{Field: ConfigFieldLifetime,
+			Message: fmt.Sprintf("Lifetime (%v) with LifetimeJitter (%v) reaches %v, over the server's wait_timeout (%v)",
+				c.Lifetime, c.LifetimeJitter, poolLifetime(c), waitTimeout)}}
+	}
+	return nil
+}
+
+// ConfigWarnings returns the configuration warnings found when the named connection was initialized.
+func (f *ConnectionManager) ConfigWarnings(name string) ([]ConfigWarning, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, exists := f.connections[name]; !exists {
+		return nil, errNotFound(name, OpConfigWarnings)
+	}
+	return append([]ConfigWarning(nil), f.warnings[name]...), nil
+}
@@ -0,0 +1,34 @@
+package connection
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDBConfigValidate(t *testing.T) {
+	config := DBConfig{MaxOpen: 5, MaxIdle: 10, Lifetime: time.Minute, IdleTime: time.Hour}
+	warnings := config.Validate()
+	if len(warnings) != 2 || warnings[0].Field != ConfigFieldMaxIdle || warnings[1].Field != ConfigFieldIdleTime {
+		t.Fatalf("Unexpected warnings %v", warnings)
+	}
+	if warnings := (DBConfig{MaxOpen: 10, MaxIdle: 5, Lifetime: time.Hour, IdleTime: time.Minute}).Validate(); len(warnings) != 0 {
+		t.Fatalf("Expected a consistent config to pass, got %v", warnings)
+	}
+
+	if w := lifetimeWarnings(DBConfig{Lifetime: 2 * time.Hour}, time.Hour); len(w) != 1 || w[0].Field != ConfigFieldLifetime {
+		t.Fatalf("Expected a Lifetime warning, got %v", w)
+	}
+	if w := lifetimeWarnings(DBConfig{}, time.Hour); len(w) != 1 {
+		t.Fatalf("Expected a warning for an unlimited Lifetime, got %v", w)
+	}
+	if w := lifetimeWarnings(DBConfig{Lifetime: 5 * time.Minute}, time.Hour); len(w) != 0 {
+		t.Fatalf("Expected no warning, got %v", w)
+	}
+
+	err := newTestFactory().InitDataSourceConnection("strict_db", DBConfig{MaxOpen: 1, MaxIdle: 2, StrictConfig: true})
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || !errors.Is(err, ErrInvalidConfig) || configErr.Connection != "strict_db" {
+		t.Fatalf("Expected a *ConfigError in strict mode, got %v", err)
+	}
+}
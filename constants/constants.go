@@ -3,3 +3,28 @@ package constants
 const (
 	ENV_PANEL_MYSQL_CONNECTION_STRING = "MYSQL_PANEL_CONNECTION_STRING"
 )
+
+const (
+	// ENV_MYSQLCONN_CHAOS enables fault injection (connection.InjectChaos) when set to "1" or "true".
+	ENV_MYSQLCONN_CHAOS = "MYSQLCONN_CHAOS"
+)
+
+const (
+	// ENV_MYSQLCONN_REGION is the region of the process, used to route regional connections
+	// (connection.InitRegionalConnection) unless set with SetLocalRegion or WithRegion.
+	ENV_MYSQLCONN_REGION = "MYSQLCONN_REGION"
+)
+
+const (
+	// ENV_MYSQLCONN_SERVICE is the service name sent as the "service" connection attribute.
+	ENV_MYSQLCONN_SERVICE = "MYSQLCONN_SERVICE"
+
+	// ENV_MYSQLCONN_POD is the pod name sent as the "pod" connection attribute. The host name is used when unset.
+	ENV_MYSQLCONN_POD = "MYSQLCONN_POD"
+)
+
+const (
+	// ENV_MYSQLCONN_DSN_PREFIX prefixes the environment variables read by connection.EnvDSN, e.g.
+	// MYSQLCONN_DSN_ORDERS for the connection "orders".
+	ENV_MYSQLCONN_DSN_PREFIX = "MYSQLCONN_DSN_"
+)
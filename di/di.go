@@ -0,0 +1,75 @@
+// Package di provides the connection manager to dependency injection containers: Module for
+// go.uber.org/fx and ProviderSet for github.com/google/wire. Both construct the manager from a Config,
+// close its connections on shutdown and expose the connections as *gorm.DB values. It is a module
+// of its own, so applications that do not use it do not depend on fx and wire.
+package di
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"gorm.io/gorm"
+	"sort"
+)
+
+// Config lists the connections to initialize, keyed by connection name.
+type Config struct {
+	Connections map[string]connection.DBConfig
+}
+
+// names returns the connection names of c in a stable order.
+func (c Config) names() []string {
+	names := make([]string, 0, len(c.Connections))
+	for name := range c.Connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DBs holds the connections of a Config by name, for injection where named values are not supported.
+//
+// Example Usage:
+//
+//	func NewUserRepo(dbs di.DBs) *UserRepo {
+//		return &UserRepo{db: dbs["primary_db"]}
+//	}
+type DBs map[string]*gorm.DB
+
+// NewManager initializes the connections of cfg on the shared ConnectionManager. If one fails, the
+// connections initialized before it are closed.
+func NewManager(cfg Config) (*connection.ConnectionManager, error) {
+	manager := connection.GetConnectionManager()
+	names := cfg.names()
+	for i, name := range names {
+		if err := manager.InitDataSourceConnection(name, cfg.Connections[name]); err != nil {
+			_ = closeConnections(manager, names[:i])
+			return nil, fmt.Errorf("failed to initialize database connection %q: %w", name, err)
+		}
+	}
+	return manager, nil
+}
+
+// NewDBs returns the connections of cfg.
+func NewDBs(manager *connection.ConnectionManager, cfg Config) (DBs, error) {
+	dbs := make(DBs, len(cfg.Connections))
+	for _, name := range cfg.names() {
+		db, err := manager.GetDB(name)
+		if err != nil {
+			return nil, err
+		}
+		dbs[name] = db
+	}
+	return dbs, nil
+}
+
+// closeConnections closes the named connections and joins their errors.
+func closeConnections(manager *connection.ConnectionManager, names []string) error {
+	var errs []error
+	for _, name := range names {
+		if err := manager.CloseConnection(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
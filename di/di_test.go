@@ -0,0 +1,47 @@
+package di
+
+import (
+	"context"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"go.uber.org/fx"
+	"testing"
+)
+
+func TestModule(t *testing.T) {
+	var provider connection.DBProvider
+	var dbs DBs
+	app := fx.New(fx.NopLogger, fx.Supply(Config{}), Module, fx.Populate(&provider, &dbs))
+	if err := app.Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if provider != connection.GetConnectionManager() || len(dbs) != 0 {
+		t.Fatalf("Expected the shared manager and no connections, got %v, %v", provider, dbs)
+	}
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if err := app.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+}
+
+func TestNamedDBUnknownConnection(t *testing.T) {
+	app := fx.New(fx.NopLogger, fx.Supply(Config{}), Module, NamedDB("missing_db"),
+		fx.Invoke(fx.Annotate(func(interface{}) {}, fx.ParamTags(`name:"missing_db"`))))
+	if app.Err() == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+}
+
+func TestNewManagerWithCleanup(t *testing.T) {
+	manager, cleanup, err := NewManagerWithCleanup(Config{})
+	if err != nil || manager == nil {
+		t.Fatalf("Unexpected result %v, %v", manager, err)
+	}
+	cleanup()
+
+	_, _, err = NewManagerWithCleanup(Config{Connections: map[string]connection.DBConfig{"bad_db": {DataSourceName: "not a dsn"}}})
+	if err == nil {
+		t.Fatal("Expected an error for an invalid data source name, got nil")
+	}
+}
@@ -0,0 +1,47 @@
+package di
+
+import (
+	"context"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"go.uber.org/fx"
+	"gorm.io/gorm"
+)
+
+// Module provides *connection.ConnectionManager, connection.DBProvider and DBs from a Config supplied
+// to the application, and closes the connections of the Config when the application stops.
+//
+// Example Usage:
+//
+//	fx.New(
+//		fx.Supply(di.Config{Connections: map[string]connection.DBConfig{"primary_db": primaryConfig}}),
+//		di.Module,
+//		di.NamedDB("primary_db"),
+//		fx.Invoke(fx.Annotate(func(db *gorm.DB) { ... }, fx.ParamTags(`name:"primary_db"`))),
+//	).Run()
+var Module = fx.Module("mysqlconn",
+	fx.Provide(
+		NewManager,
+		func(manager *connection.ConnectionManager) connection.DBProvider { return manager },
+		NewDBs,
+	),
+	fx.Invoke(registerShutdown),
+)
+
+// NamedDB provides each named connection as a *gorm.DB tagged name:"<name>". It is used with Module.
+func NamedDB(names ...string) fx.Option {
+	options := make([]fx.Option, len(names))
+	for i, name := range names {
+		options[i] = fx.Provide(fx.Annotate(
+			func(manager *connection.ConnectionManager) (*gorm.DB, error) { return manager.GetDB(name) },
+			fx.ResultTags(`name:"`+name+`"`),
+		))
+	}
+	return fx.Options(options...)
+}
+
+// registerShutdown closes the connections of cfg when the application stops.
+func registerShutdown(lc fx.Lifecycle, manager *connection.ConnectionManager, cfg Config) {
+	lc.Append(fx.Hook{OnStop: func(context.Context) error {
+		return closeConnections(manager, cfg.names())
+	}})
+}
@@ -0,0 +1,30 @@
+package di
+
+import (
+	"github.com/google/wire"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+)
+
+// ProviderSet provides *connection.ConnectionManager, connection.DBProvider and DBs from a Config. The
+// cleanup function returned by the generated injector closes the connections of the Config.
+//
+// Example Usage:
+//
+//	func InitializeServer(cfg di.Config) (*Server, func(), error) {
+//		wire.Build(di.ProviderSet, NewServer)
+//		return nil, nil, nil
+//	}
+var ProviderSet = wire.NewSet(
+	NewManagerWithCleanup,
+	wire.Bind(new(connection.DBProvider), new(*connection.ConnectionManager)),
+	NewDBs,
+)
+
+// NewManagerWithCleanup is NewManager with a cleanup function closing the connections of cfg.
+func NewManagerWithCleanup(cfg Config) (*connection.ConnectionManager, func(), error) {
+	manager, err := NewManager(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return manager, func() { _ = closeConnections(manager, cfg.names()) }, nil
+}
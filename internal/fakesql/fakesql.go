@@ -0,0 +1,50 @@
+// Package fakesql is the in-memory fake of MySQL behind connection.InitFake. It implements a
+// database/sql driver for the subset of SQL that GORM and the helpers of the connection package emit
+// on a single table, so unit tests of repository code run without any server.
+package fakesql
+
+import (
+	"database/sql/driver"
+	"errors"
+)
+
+// ServerVersion is the server version reported by fake connections.
+const ServerVersion = "8.0.36-fake"
+
+// MySQL errors returned by the fake, as a server would.
+const (
+	erDupEntry    = 1062 // ER_DUP_ENTRY
+	erNoSuchTable = 1146 // ER_NO_SUCH_TABLE
+)
+
+// ErrUnsupported is returned for statements outside the implemented SQL subset.
+var ErrUnsupported = errors.New("statement not supported by the fake database")
+
+// Data seeds the tables of a fake database, keyed by table name.
+type Data map[string]Table
+
+// Table is a table of a fake database.
+type Table struct {
+	// Rows are the initial rows, as column names to values. Values are converted like query arguments.
+	Rows []map[string]any
+
+	// Columns lists the columns returned by SELECT * besides those of Rows, for tables seeded empty.
+	Columns []string
+
+	// PrimaryKey is the primary key column, "id" when empty. Integer keys missing from inserted rows are
+	// generated like AUTO_INCREMENT.
+	PrimaryKey string
+
+	// Unique lists the unique keys besides the primary key, as column lists.
+	Unique [][]string
+}
+
+// NewConnector returns a connector to a new fake database named database, seeded with seed. The
+// sessions it opens share the tables.
+func NewConnector(database string, seed Data) (driver.Connector, error) {
+	store, err := newMemStore(database, seed)
+	if err != nil {
+		return nil, err
+	}
+	return &memConnector{store: store}, nil
+}
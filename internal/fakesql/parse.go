@@ -0,0 +1,1498 @@
+package fakesql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The SQL subset of the fake database: the statements GORM and the helpers of this package emit on a
+// single table. See connection.InitFake.
+
+type fakeTokenKind int
+
+const (
+	fakeTokEOF fakeTokenKind = iota
+	fakeTokIdent
+	fakeTokNumber
+	fakeTokString
+	fakeTokParam
+	fakeTokSymbol
+)
+
+type fakeToken struct {
+	kind fakeTokenKind
+	text string
+
+	// quoted reports a backquoted identifier, which is never a keyword.
+	quoted bool
+}
+
+// fakeLex splits query into tokens, dropping comments and optimizer hints.
+func fakeLex(query string) ([]fakeToken, error) {
+	var tokens []fakeToken
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += end + 4
+		case c == '#' || strings.HasPrefix(query[i:], "-- "):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			i += end
+		case c == '`':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(query); j++ {
+				if query[j] == '`' {
+					if j+1 < len(query) && query[j+1] == '`' {
+						b.WriteByte('`')
+						j++
+						continue
+					}
+					break
+				}
+				b.WriteByte(query[j])
+			}
+			if j >= len(query) {
+				return nil, fmt.Errorf("unterminated identifier")
+			}
+			tokens = append(tokens, fakeToken{kind: fakeTokIdent, text: b.String(), quoted: true})
+			i = j + 1
+		case c == '\'' || c == '"':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(query); j++ {
+				if query[j] == '\\' && j+1 < len(query) {
+					j++
+					switch query[j] {
+					case 'n':
+						b.WriteByte('\n')
+					case 't':
+						b.WriteByte('\t')
+					case 'r':
+						b.WriteByte('\r')
+					case '0':
+						b.WriteByte(0)
+					default:
+						b.WriteByte(query[j])
+					}
+					continue
+				}
+				if query[j] == c {
+					if j+1 < len(query) && query[j+1] == c {
+						b.WriteByte(c)
+						j++
+						continue
+					}
+					break
+				}
+				b.WriteByte(query[j])
+			}
+			if j >= len(query) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, fakeToken{kind: fakeTokString, text: b.String()})
+			i = j + 1
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			j := i
+			for j < len(query) && (query[j] >= '0' && query[j] <= '9' || query[j] == '.' ||
+				(query[j] == 'e' || query[j] == 'E') && j+1 < len(query) && (query[j+1] >= '0' && query[j+1] <= '9' || query[j+1] == '-' || query[j+1] == '+') ||
+				(query[j] == '-' || query[j] == '+') && j > i && (query[j-1] == 'e' || query[j-1] == 'E')) {
+				j++
+			}
+			tokens = append(tokens, fakeToken{kind: fakeTokNumber, text: query[i:j]})
+			i = j
+		case c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(query) && (query[j] == '_' || query[j] == '$' || query[j] >= 'a' && query[j] <= 'z' ||
+				query[j] >= 'A' && query[j] <= 'Z' || query[j] >= '0' && query[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, fakeToken{kind: fakeTokIdent, text: query[i:j]})
+			i = j
+		case c == '?':
+			tokens = append(tokens, fakeToken{kind: fakeTokParam, text: "?"})
+			i++
+		default:
+			symbol := query[i : i+1]
+			for _, op := range []string{"<=>", "<=", ">=", "<>", "!="} {
+				if strings.HasPrefix(query[i:], op) {
+					symbol = op
+					break
+				}
+			}
+			if !strings.Contains("=<>!(),.*+-/;%", symbol[:1]) {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+			tokens = append(tokens, fakeToken{kind: fakeTokSymbol, text: symbol})
+			i += len(symbol)
+		}
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].text == ";" && tokens[len(tokens)-1].kind == fakeTokSymbol {
+		tokens = tokens[:len(tokens)-1]
+	}
+	return append(tokens, fakeToken{kind: fakeTokEOF}), nil
+}
+
+// fakeExpr is an expression node.
+type fakeExpr interface{}
+
+type (
+	fakeLiteral struct{ value driver.Value }
+	fakeColumn  struct{ name string }
+	fakeStar    struct{}
+	fakeTuple   struct{ items []fakeExpr }
+	fakeUnary   struct {
+		op      string
+		operand fakeExpr
+	}
+	fakeBinary struct {
+		op          string
+		left, right fakeExpr
+	}
+	fakeIsNull struct {
+		operand fakeExpr
+		not     bool
+	}
+	fakeIn struct {
+		operand fakeExpr
+		list    []fakeExpr
+		not     bool
+	}
+	fakeLike struct {
+		operand, pattern fakeExpr
+		not              bool
+	}
+	fakeBetween struct {
+		operand, low, high fakeExpr
+		not                bool
+	}
+	fakeCall struct {
+		name string
+		args []fakeExpr
+	}
+	// fakeInterval is INTERVAL amount unit, an operand of date arithmetic.
+	fakeInterval struct {
+		amount fakeExpr
+		unit   time.Duration
+	}
+)
+
+var fakeIntervalUnits = map[string]time.Duration{
+	"MICROSECOND": time.Microsecond,
+	"SECOND":      time.Second,
+	"MINUTE":      time.Minute,
+	"HOUR":        time.Hour,
+	"DAY":         24 * time.Hour,
+	"WEEK":        7 * 24 * time.Hour,
+}
+
+type fakeSelectItem struct {
+	expr  fakeExpr
+	name  string
+	star  bool
+	table string
+}
+
+type fakeOrder struct {
+	expr fakeExpr
+	desc bool
+}
+
+type fakeAssign struct {
+	column string
+	expr   fakeExpr
+}
+
+type (
+	fakeSelect struct {
+		items         []fakeSelectItem
+		table         string
+		where         fakeExpr
+		orderBy       []fakeOrder
+		limit, offset fakeExpr
+	}
+	fakeInsert struct {
+		table       string
+		ignore      bool
+		columns     []string
+		rows        [][]fakeExpr
+		onDuplicate []fakeAssign
+	}
+	fakeUpdate struct {
+		table   string
+		set     []fakeAssign
+		where   fakeExpr
+		orderBy []fakeOrder
+		limit   fakeExpr
+	}
+	fakeDelete struct {
+		table   string
+		where   fakeExpr
+		orderBy []fakeOrder
+		limit   fakeExpr
+	}
+	// fakeTxStatement is BEGIN, COMMIT, ROLLBACK or a savepoint statement.
+	fakeTxStatement struct {
+		op   string
+		name string
+	}
+	// fakeNoop is a statement accepted and ignored, e.g. SET.
+	fakeNoop struct{}
+)
+
+// fakeParser parses one statement, binding its ? placeholders to args.
+type fakeParser struct {
+	tokens []fakeToken
+	pos    int
+	args   []driver.Value
+	used   int
+}
+
+func fakeParse(query string, args []driver.Value) (interface{}, error) {
+	tokens, err := fakeLex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &fakeParser{tokens: tokens, args: args}
+	stmt, err := p.statement()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != fakeTokEOF {
+		return nil, fmt.Errorf("unexpected %q", p.peek().text)
+	}
+	if p.used != len(args) {
+		return nil, fmt.Errorf("statement has %d placeholders, got %d arguments", p.used, len(args))
+	}
+	return stmt, nil
+}
+
+func (p *fakeParser) peek() fakeToken {
+	return p.tokens[p.pos]
+}
+
+// at returns the token offset tokens after the current one, or the end of the statement.
+func (p *fakeParser) at(offset int) fakeToken {
+	return p.tokens[min(p.pos+offset, len(p.tokens)-1)]
+}
+
+func (p *fakeParser) next() fakeToken {
+	t := p.tokens[p.pos]
+	if t.kind != fakeTokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// isKeyword reports whether the current token is one of keywords.
+func (p *fakeParser) isKeyword(keywords ...string) bool {
+	t := p.peek()
+	if t.kind != fakeTokIdent || t.quoted {
+		return false
+	}
+	for _, keyword := range keywords {
+		if strings.EqualFold(t.text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *fakeParser) acceptKeyword(keywords ...string) bool {
+	for i, keyword := range keywords {
+		t := p.at(i)
+		if t.kind != fakeTokIdent || t.quoted || !strings.EqualFold(t.text, keyword) {
+			return false
+		}
+	}
+	p.pos += len(keywords)
+	return true
+}
+
+func (p *fakeParser) expectKeyword(keywords ...string) error {
+	if !p.acceptKeyword(keywords...) {
+		return fmt.Errorf("expected %s, got %q", strings.Join(keywords, " "), p.peek().text)
+	}
+	return nil
+}
+
+func (p *fakeParser) acceptSymbol(symbol string) bool {
+	if t := p.peek(); t.kind == fakeTokSymbol && t.text == symbol {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *fakeParser) expectSymbol(symbol string) error {
+	if !p.acceptSymbol(symbol) {
+		return fmt.Errorf("expected %q, got %q", symbol, p.peek().text)
+	}
+	return nil
+}
+
+func (p *fakeParser) ident() (string, error) {
+	t := p.next()
+	if t.kind != fakeTokIdent {
+		return "", fmt.Errorf("expected an identifier, got %q", t.text)
+	}
+	return t.text, nil
+}
+
+// tableName parses [database.]table and returns the table.
+func (p *fakeParser) tableName() (string, error) {
+	name, err := p.ident()
+	if err != nil {
+		return "", err
+	}
+	if p.acceptSymbol(".") {
+		return p.ident()
+	}
+	return name, nil
+}
+
+// skipAlias skips a table alias; queries refer to a single table, so qualifiers are ignored.
+func (p *fakeParser) skipAlias() {
+	if p.acceptKeyword("AS") || p.peek().kind == fakeTokIdent && (p.peek().quoted || !p.isKeyword(
+		"WHERE", "ORDER", "LIMIT", "FOR", "LOCK", "SET", "GROUP", "HAVING", "JOIN", "LEFT", "RIGHT", "INNER", "CROSS", "UNION")) {
+		p.next()
+	}
+}
+
+func (p *fakeParser) statement() (interface{}, error) {
+	switch {
+	case p.acceptKeyword("SELECT"):
+		return p.selectStatement()
+	case p.acceptKeyword("INSERT"):
+		return p.insertStatement()
+	case p.acceptKeyword("UPDATE"):
+		return p.updateStatement()
+	case p.acceptKeyword("DELETE"):
+		return p.deleteStatement()
+	case p.acceptKeyword("BEGIN"):
+		return fakeTxStatement{op: "begin"}, nil
+	case p.acceptKeyword("START", "TRANSACTION"):
+		// Characteristics (WITH CONSISTENT SNAPSHOT, READ ONLY) have no effect on the fake.
+		for p.peek().kind != fakeTokEOF {
+			p.next()
+		}
+		return fakeTxStatement{op: "begin"}, nil
+	case p.acceptKeyword("COMMIT"):
+		return fakeTxStatement{op: "commit"}, nil
+	case p.acceptKeyword("ROLLBACK"):
+		if p.acceptKeyword("TO") {
+			p.acceptKeyword("SAVEPOINT")
+			name, err := p.ident()
+			return fakeTxStatement{op: "rollback_to", name: name}, err
+		}
+		return fakeTxStatement{op: "rollback"}, nil
+	case p.acceptKeyword("SAVEPOINT"):
+		name, err := p.ident()
+		return fakeTxStatement{op: "savepoint", name: name}, err
+	case p.acceptKeyword("RELEASE", "SAVEPOINT"):
+		name, err := p.ident()
+		return fakeTxStatement{op: "release", name: name}, err
+	case p.acceptKeyword("SET"), p.acceptKeyword("DO"):
+		// Session settings have no effect on the fake.
+		for p.peek().kind != fakeTokEOF {
+			if p.peek().kind == fakeTokParam {
+				p.used++
+			}
+			p.next()
+		}
+		return fakeNoop{}, nil
+	}
+	return nil, fmt.Errorf("unsupported statement %q", p.peek().text)
+}
+
+func (p *fakeParser) selectStatement() (*fakeSelect, error) {
+	p.acceptKeyword("SQL_CALC_FOUND_ROWS")
+	if p.isKeyword("DISTINCT") {
+		return nil, fmt.Errorf("unsupported DISTINCT")
+	}
+	stmt := &fakeSelect{}
+	for {
+		start := p.pos
+		item := fakeSelectItem{}
+		if p.acceptSymbol("*") {
+			item.star = true
+		} else if t := p.peek(); t.kind == fakeTokIdent && p.at(1).text == "." && p.at(2).text == "*" {
+			p.pos += 3
+			item.star, item.table = true, t.text
+		} else {
+			expr, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			item.expr = expr
+			item.name = p.itemName(start, expr)
+			if p.acceptKeyword("AS") || p.peek().kind == fakeTokIdent && (p.peek().quoted || !p.isKeyword("FROM", "WHERE", "ORDER", "LIMIT", "FOR")) {
+				if item.name, err = p.ident(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		stmt.items = append(stmt.items, item)
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+	if p.acceptKeyword("FROM") {
+		table, err := p.tableName()
+		if err != nil {
+			return nil, err
+		}
+		stmt.table = table
+		p.skipAlias()
+	}
+	if p.isKeyword("JOIN", "LEFT", "RIGHT", "INNER", "CROSS") || p.acceptSymbol(",") {
+		return nil, fmt.Errorf("unsupported join")
+	}
+	var err error
+	if stmt.where, err = p.where(); err != nil {
+		return nil, err
+	}
+	if p.isKeyword("GROUP", "HAVING", "UNION") {
+		return nil, fmt.Errorf("unsupported %s", strings.ToUpper(p.peek().text))
+	}
+	if stmt.orderBy, err = p.orderBy(); err != nil {
+		return nil, err
+	}
+	if stmt.limit, stmt.offset, err = p.limit(); err != nil {
+		return nil, err
+	}
+	// Locking clauses have no effect on the fake.
+	if p.acceptKeyword("FOR") || p.acceptKeyword("LOCK", "IN") {
+		for p.peek().kind == fakeTokIdent && !p.peek().quoted {
+			p.next()
+		}
+	}
+	return stmt, nil
+}
+
+// itemName is the column name MySQL gives an unaliased select item: the column name for columns, else
+// the text of the expression.
+func (p *fakeParser) itemName(start int, expr fakeExpr) string {
+	if column, ok := expr.(fakeColumn); ok {
+		return column.name
+	}
+	var b strings.Builder
+	for _, t := range p.tokens[start:p.pos] {
+		switch t.kind {
+		case fakeTokString:
+			b.WriteString("'" + t.text + "'")
+		default:
+			b.WriteString(t.text)
+		}
+	}
+	return b.String()
+}
+
+func (p *fakeParser) insertStatement() (*fakeInsert, error) {
+	stmt := &fakeInsert{ignore: p.acceptKeyword("IGNORE")}
+	p.acceptKeyword("INTO")
+	var err error
+	if stmt.table, err = p.tableName(); err != nil {
+		return nil, err
+	}
+	if err := p.expectSymbol("("); err != nil {
+		return nil, fmt.Errorf("unsupported INSERT without a column list")
+	}
+	for {
+		column, err := p.columnName()
+		if err != nil {
+			return nil, err
+		}
+		stmt.columns = append(stmt.columns, column)
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+	if !p.acceptKeyword("VALUES") && !p.acceptKeyword("VALUE") {
+		return nil, fmt.Errorf("unsupported INSERT without VALUES")
+	}
+	for {
+		if err := p.expectSymbol("("); err != nil {
+			return nil, err
+		}
+		row, err := p.exprList()
+		if err != nil {
+			return nil, err
+		}
+		if len(row) != len(stmt.columns) {
+			return nil, fmt.Errorf("column count does not match value count")
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		stmt.rows = append(stmt.rows, row)
+		if !p.acceptSymbol(",") {
+			break
+		}
+	}
+	if p.acceptKeyword("ON", "DUPLICATE", "KEY", "UPDATE") {
+		if stmt.onDuplicate, err = p.assignments(); err != nil {
+			return nil, err
+		}
+	}
+	return stmt, nil
+}
+
+func (p *fakeParser) updateStatement() (*fakeUpdate, error) {
+	stmt := &fakeUpdate{}
+	var err error
+	if stmt.table, err = p.tableName(); err != nil {
+		return nil, err
+	}
+	p.skipAlias()
+	if err := p.expectKeyword("SET"); err != nil {
+		return nil, err
+	}
+	if stmt.set, err = p.assignments(); err != nil {
+		return nil, err
+	}
+	if stmt.where, err = p.where(); err != nil {
+		return nil, err
+	}
+	if stmt.orderBy, err = p.orderBy(); err != nil {
+		return nil, err
+	}
+	var offset fakeExpr
+	if stmt.limit, offset, err = p.limit(); err != nil || offset != nil {
+		return nil, fmt.Errorf("unsupported OFFSET in UPDATE")
+	}
+	return stmt, nil
+}
+
+func (p *fakeParser) deleteStatement() (*fakeDelete, error) {
+	stmt := &fakeDelete{}
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	var err error
+	if stmt.table, err = p.tableName(); err != nil {
+		return nil, err
+	}
+	p.skipAlias()
+	if stmt.where, err = p.where(); err != nil {
+		return nil, err
+	}
+	if stmt.orderBy, err = p.orderBy(); err != nil {
+		return nil, err
+	}
+	var offset fakeExpr
+	if stmt.limit, offset, err = p.limit(); err != nil || offset != nil {
+		return nil, fmt.Errorf("unsupported OFFSET in DELETE")
+	}
+	return stmt, nil
+}
+
+// columnName parses [table.]column and returns the column.
+func (p *fakeParser) columnName() (string, error) {
+	name, err := p.ident()
+	if err != nil {
+		return "", err
+	}
+	for p.acceptSymbol(".") {
+		if name, err = p.ident(); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+func (p *fakeParser) assignments() ([]fakeAssign, error) {
+	var assigns []fakeAssign
+	for {
+		column, err := p.columnName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol("="); err != nil {
+			return nil, err
+		}
+		expr, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		assigns = append(assigns, fakeAssign{column: column, expr: expr})
+		if !p.acceptSymbol(",") {
+			return assigns, nil
+		}
+	}
+}
+
+func (p *fakeParser) where() (fakeExpr, error) {
+	if !p.acceptKeyword("WHERE") {
+		return nil, nil
+	}
+	return p.expr()
+}
+
+func (p *fakeParser) orderBy() ([]fakeOrder, error) {
+	if !p.acceptKeyword("ORDER", "BY") {
+		return nil, nil
+	}
+	var orders []fakeOrder
+	for {
+		expr, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		order := fakeOrder{expr: expr}
+		if p.acceptKeyword("DESC") {
+			order.desc = true
+		} else {
+			p.acceptKeyword("ASC")
+		}
+		orders = append(orders, order)
+		if !p.acceptSymbol(",") {
+			return orders, nil
+		}
+	}
+}
+
+// limit parses LIMIT n [OFFSET m] and LIMIT m, n.
+func (p *fakeParser) limit() (limit, offset fakeExpr, err error) {
+	if !p.acceptKeyword("LIMIT") {
+		return nil, nil, nil
+	}
+	if limit, err = p.primary(); err != nil {
+		return nil, nil, err
+	}
+	if p.acceptSymbol(",") {
+		offset = limit
+		limit, err = p.primary()
+	} else if p.acceptKeyword("OFFSET") {
+		offset, err = p.primary()
+	}
+	return limit, offset, err
+}
+
+func (p *fakeParser) exprList() ([]fakeExpr, error) {
+	var list []fakeExpr
+	for {
+		expr, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, expr)
+		if !p.acceptSymbol(",") {
+			return list, nil
+		}
+	}
+}
+
+func (p *fakeParser) expr() (fakeExpr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = fakeBinary{op: "OR", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *fakeParser) and() (fakeExpr, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = fakeBinary{op: "AND", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *fakeParser) not() (fakeExpr, error) {
+	if p.acceptKeyword("NOT") {
+		operand, err := p.not()
+		return fakeUnary{op: "NOT", operand: operand}, err
+	}
+	return p.comparison()
+}
+
+func (p *fakeParser) comparison() (fakeExpr, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if t := p.peek(); t.kind == fakeTokSymbol {
+			switch t.text {
+			case "=", "<>", "!=", "<", "<=", ">", ">=", "<=>":
+				p.next()
+				right, err := p.additive()
+				if err != nil {
+					return nil, err
+				}
+				left = fakeBinary{op: t.text, left: left, right: right}
+				continue
+			}
+		}
+		if p.acceptKeyword("IS") {
+			not := p.acceptKeyword("NOT")
+			if err := p.expectKeyword("NULL"); err != nil {
+				return nil, err
+			}
+			left = fakeIsNull{operand: left, not: not}
+			continue
+		}
+		not := p.isKeyword("NOT") && p.at(1).kind == fakeTokIdent && !p.at(1).quoted
+		if not {
+			p.pos++
+		}
+		switch {
+		case p.acceptKeyword("IN"):
+			if err := p.expectSymbol("("); err != nil {
+				return nil, err
+			}
+			list, err := p.exprList()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectSymbol(")"); err != nil {
+				return nil, err
+			}
+			left = fakeIn{operand: left, list: list, not: not}
+		case p.acceptKeyword("LIKE"):
+			pattern, err := p.additive()
+			if err != nil {
+				return nil, err
+			}
+			left = fakeLike{operand: left, pattern: pattern, not: not}
+		case p.acceptKeyword("BETWEEN"):
+			low, err := p.additive()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectKeyword("AND"); err != nil {
+				return nil, err
+			}
+			high, err := p.additive()
+			if err != nil {
+				return nil, err
+			}
+			left = fakeBetween{operand: left, low: low, high: high, not: not}
+		default:
+			if not {
+				p.pos--
+			}
+			return left, nil
+		}
+	}
+}
+
+func (p *fakeParser) additive() (fakeExpr, error) {
+	left, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != fakeTokSymbol || t.text != "+" && t.text != "-" {
+			return left, nil
+		}
+		p.next()
+		right, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = fakeBinary{op: t.text, left: left, right: right}
+	}
+}
+
+func (p *fakeParser) multiplicative() (fakeExpr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != fakeTokSymbol || t.text != "*" && t.text != "/" && t.text != "%" {
+			return left, nil
+		}
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = fakeBinary{op: t.text, left: left, right: right}
+	}
+}
+
+func (p *fakeParser) unary() (fakeExpr, error) {
+	if p.acceptSymbol("-") {
+		operand, err := p.unary()
+		return fakeUnary{op: "-", operand: operand}, err
+	}
+	p.acceptSymbol("+")
+	return p.primary()
+}
+
+func (p *fakeParser) primary() (fakeExpr, error) {
+	t := p.next()
+	switch t.kind {
+	case fakeTokParam:
+		if p.used >= len(p.args) {
+			return nil, fmt.Errorf("missing argument for placeholder %d", p.used+1)
+		}
+		value := p.args[p.used]
+		p.used++
+		return fakeLiteral{value: fakeNormalize(value)}, nil
+	case fakeTokNumber:
+		if n, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return fakeLiteral{value: n}, nil
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", t.text)
+		}
+		return fakeLiteral{value: f}, nil
+	case fakeTokString:
+		return fakeLiteral{value: t.text}, nil
+	case fakeTokSymbol:
+		if t.text == "*" {
+			return fakeStar{}, nil
+		}
+		if t.text != "(" {
+			break
+		}
+		list, err := p.exprList()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		if len(list) == 1 {
+			return list[0], nil
+		}
+		return fakeTuple{items: list}, nil
+	case fakeTokIdent:
+		if !t.quoted {
+			switch strings.ToUpper(t.text) {
+			case "NULL":
+				return fakeLiteral{value: nil}, nil
+			case "TRUE":
+				return fakeLiteral{value: int64(1)}, nil
+			case "FALSE":
+				return fakeLiteral{value: int64(0)}, nil
+			case "INTERVAL":
+				if p.peek().text != "(" {
+					return p.interval()
+				}
+			}
+			if p.acceptSymbol("(") {
+				call := fakeCall{name: strings.ToUpper(t.text)}
+				if !p.acceptSymbol(")") {
+					p.acceptKeyword("DISTINCT")
+					args, err := p.exprList()
+					if err != nil {
+						return nil, err
+					}
+					if err := p.expectSymbol(")"); err != nil {
+						return nil, err
+					}
+					call.args = args
+				}
+				return call, nil
+			}
+		}
+		name := t.text
+		for p.acceptSymbol(".") {
+			next, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			name = next
+		}
+		return fakeColumn{name: name}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// interval parses the amount and unit of INTERVAL amount unit.
+func (p *fakeParser) interval() (fakeExpr, error) {
+	amount, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	unit, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	d, ok := fakeIntervalUnits[strings.ToUpper(unit)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported interval unit %s", unit)
+	}
+	return fakeInterval{amount: amount, unit: d}, nil
+}
+
+// fakeNormalize converts a driver value to the representation stored by the fake: integers as int64,
+// booleans as 0 or 1, byte slices copied.
+func fakeNormalize(value driver.Value) driver.Value {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return int64(1)
+		}
+		return int64(0)
+	case []byte:
+		return append([]byte(nil), v...)
+	}
+	return value
+}
+
+// fakeEnv is the evaluation environment of an expression.
+type fakeEnv struct {
+	conn *memConn
+
+	// row is the current row, or nil outside a table.
+	row fakeRow
+
+	// group is the group aggregates are computed on, or nil outside an aggregate query.
+	group []fakeRow
+
+	// inserted is the row VALUES() refers to in ON DUPLICATE KEY UPDATE.
+	inserted fakeRow
+}
+
+var fakeAggregates = map[string]bool{"COUNT": true, "SUM": true, "MIN": true, "MAX": true, "AVG": true}
+
+func (env *fakeEnv) eval(expr fakeExpr) (driver.Value, error) {
+	switch e := expr.(type) {
+	case nil:
+		return int64(1), nil
+	case fakeLiteral:
+		return e.value, nil
+	case fakeColumn:
+		return env.row[e.name], nil
+	case fakeTuple:
+		return nil, fmt.Errorf("operand should contain 1 column")
+	case fakeUnary:
+		value, err := env.eval(e.operand)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		if e.op == "NOT" {
+			return fakeBool(!fakeTruthy(value)), nil
+		}
+		if n, ok := value.(int64); ok {
+			return -n, nil
+		}
+		return -fakeFloat(value), nil
+	case fakeBinary:
+		return env.binary(e)
+	case fakeIsNull:
+		value, err := env.eval(e.operand)
+		return fakeBool((value == nil) != e.not), err
+	case fakeIn:
+		value, err := env.eval(e.operand)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		sawNull := false
+		for _, item := range e.list {
+			candidate, err := env.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			if candidate == nil {
+				sawNull = true
+			} else if c, _ := fakeCompare(value, candidate); c == 0 {
+				return fakeBool(!e.not), nil
+			}
+		}
+		if sawNull {
+			return nil, nil
+		}
+		return fakeBool(e.not), nil
+	case fakeLike:
+		value, err := env.eval(e.operand)
+		if err != nil {
+			return nil, err
+		}
+		pattern, err := env.eval(e.pattern)
+		if err != nil || value == nil || pattern == nil {
+			return nil, err
+		}
+		return fakeBool(fakeLikePattern(fakeString(pattern)).MatchString(fakeString(value)) != e.not), nil
+	case fakeBetween:
+		value, err := env.eval(e.operand)
+		if err != nil {
+			return nil, err
+		}
+		low, err := env.eval(e.low)
+		if err != nil {
+			return nil, err
+		}
+		high, err := env.eval(e.high)
+		if err != nil || value == nil || low == nil || high == nil {
+			return nil, err
+		}
+		a, _ := fakeCompare(value, low)
+		b, _ := fakeCompare(value, high)
+		return fakeBool((a >= 0 && b <= 0) != e.not), nil
+	case fakeCall:
+		return env.call(e)
+	case fakeInterval:
+		return nil, fmt.Errorf("INTERVAL outside date arithmetic")
+	}
+	return nil, fmt.Errorf("unsupported expression %T", expr)
+}
+
+func (env *fakeEnv) binary(e fakeBinary) (driver.Value, error) {
+	if left, ok := e.left.(fakeTuple); ok {
+		right, ok := e.right.(fakeTuple)
+		if !ok || len(left.items) != len(right.items) {
+			return nil, fmt.Errorf("operand should contain %d columns", len(left.items))
+		}
+		return env.compareTuples(e.op, left.items, right.items)
+	}
+	if interval, ok := e.right.(fakeInterval); ok && (e.op == "+" || e.op == "-") {
+		return env.dateArithmetic(e.op, e.left, interval)
+	}
+	if interval, ok := e.left.(fakeInterval); ok && e.op == "+" {
+		return env.dateArithmetic(e.op, e.right, interval)
+	}
+
+	left, err := env.eval(e.left)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "AND":
+		if left != nil && !fakeTruthy(left) {
+			return int64(0), nil
+		}
+	case "OR":
+		if left != nil && fakeTruthy(left) {
+			return int64(1), nil
+		}
+	}
+	right, err := env.eval(e.right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "AND", "OR":
+		if right != nil && fakeTruthy(right) == (e.op == "OR") {
+			return fakeBool(e.op == "OR"), nil
+		}
+		if left == nil || right == nil {
+			return nil, nil
+		}
+		return fakeBool(e.op == "AND"), nil
+	case "<=>":
+		if left == nil || right == nil {
+			return fakeBool(left == nil && right == nil), nil
+		}
+		c, _ := fakeCompare(left, right)
+		return fakeBool(c == 0), nil
+	}
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	switch e.op {
+	case "+", "-", "*", "/", "%":
+		return fakeArithmetic(e.op, left, right), nil
+	}
+	c, _ := fakeCompare(left, right)
+	return fakeBool(fakeCompareOp(e.op, c)), nil
+}
+
+// dateArithmetic adds interval to, or subtracts it from, the date operand.
+func (env *fakeEnv) dateArithmetic(op string, operand fakeExpr, interval fakeInterval) (driver.Value, error) {
+	value, err := env.eval(operand)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := env.eval(interval.amount)
+	if err != nil || value == nil || amount == nil {
+		return nil, err
+	}
+	t, ok := fakeTime(value)
+	if !ok {
+		return nil, fmt.Errorf("incorrect datetime value %q", fakeString(value))
+	}
+	d := time.Duration(fakeFloat(amount) * float64(interval.unit))
+	if n, ok := amount.(int64); ok {
+		d = time.Duration(n) * interval.unit
+	}
+	if op == "-" {
+		d = -d
+	}
+	return t.Add(d), nil
+}
+
+// compareTuples compares row constructors lexicographically, e.g. (a, b) > (?, ?).
+func (env *fakeEnv) compareTuples(op string, left, right []fakeExpr) (driver.Value, error) {
+	for i := range left {
+		a, err := env.eval(left[i])
+		if err != nil {
+			return nil, err
+		}
+		b, err := env.eval(right[i])
+		if err != nil {
+			return nil, err
+		}
+		if a == nil || b == nil {
+			return nil, nil
+		}
+		c, _ := fakeCompare(a, b)
+		if c != 0 || i == len(left)-1 {
+			if (op == "=" || op == "<=>") && c != 0 {
+				return int64(0), nil
+			}
+			return fakeBool(fakeCompareOp(op, c)), nil
+		}
+	}
+	return nil, nil
+}
+
+func fakeCompareOp(op string, c int) bool {
+	switch op {
+	case "=", "<=>":
+		return c == 0
+	case "<>", "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+func (env *fakeEnv) call(e fakeCall) (driver.Value, error) {
+	if fakeAggregates[e.name] {
+		return env.aggregate(e)
+	}
+	args := make([]driver.Value, len(e.args))
+	for i, arg := range e.args {
+		if e.name == "VALUES" {
+			break
+		}
+		value, err := env.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	switch e.name {
+	case "VERSION":
+		return ServerVersion, nil
+	case "DATABASE", "SCHEMA":
+		return env.conn.store.database, nil
+	case "CONNECTION_ID":
+		return env.conn.id, nil
+	case "LAST_INSERT_ID":
+		return env.conn.lastInsertID, nil
+	case "NOW", "CURRENT_TIMESTAMP", "SYSDATE", "UTC_TIMESTAMP":
+		return time.Now().UTC().Truncate(time.Microsecond), nil
+	case "VALUES":
+		if column, ok := e.args[0].(fakeColumn); ok && len(e.args) == 1 && env.inserted != nil {
+			return env.inserted[column.name], nil
+		}
+		return nil, fmt.Errorf("VALUES() outside ON DUPLICATE KEY UPDATE")
+	case "COALESCE", "IFNULL":
+		for _, arg := range args {
+			if arg != nil {
+				return arg, nil
+			}
+		}
+		return nil, nil
+	case "CONCAT":
+		var b strings.Builder
+		for _, arg := range args {
+			if arg == nil {
+				return nil, nil
+			}
+			b.WriteString(fakeString(arg))
+		}
+		return b.String(), nil
+	case "LOWER", "UPPER", "LENGTH", "CHAR_LENGTH":
+		if len(args) != 1 || args[0] == nil {
+			return nil, nil
+		}
+		s := fakeString(args[0])
+		switch e.name {
+		case "LOWER":
+			return strings.ToLower(s), nil
+		case "UPPER":
+			return strings.ToUpper(s), nil
+		case "LENGTH":
+			return int64(len(s)), nil
+		}
+		return int64(len([]rune(s))), nil
+	}
+	return nil, fmt.Errorf("unsupported function %s", e.name)
+}
+
+func (env *fakeEnv) aggregate(e fakeCall) (driver.Value, error) {
+	if env.group == nil {
+		return nil, fmt.Errorf("invalid use of group function %s", e.name)
+	}
+	var values []driver.Value
+	for _, row := range env.group {
+		if len(e.args) == 1 {
+			if _, star := e.args[0].(fakeStar); star {
+				values = append(values, int64(1))
+				continue
+			}
+		}
+		if len(e.args) != 1 {
+			return nil, fmt.Errorf("%s expects one argument", e.name)
+		}
+		value, err := (&fakeEnv{conn: env.conn, row: row}).eval(e.args[0])
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			values = append(values, value)
+		}
+	}
+	if e.name == "COUNT" {
+		return int64(len(values)), nil
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+	result := values[0]
+	for _, value := range values[1:] {
+		c, _ := fakeCompare(value, result)
+		switch e.name {
+		case "MIN":
+			if c < 0 {
+				result = value
+			}
+		case "MAX":
+			if c > 0 {
+				result = value
+			}
+		case "SUM", "AVG":
+			result = fakeArithmetic("+", result, value)
+		}
+	}
+	if e.name == "AVG" {
+		return fakeFloat(result) / float64(len(values)), nil
+	}
+	return result, nil
+}
+
+// fakeHasAggregate reports whether expr calls an aggregate function.
+func fakeHasAggregate(expr fakeExpr) bool {
+	switch e := expr.(type) {
+	case fakeCall:
+		if fakeAggregates[e.name] {
+			return true
+		}
+		for _, arg := range e.args {
+			if fakeHasAggregate(arg) {
+				return true
+			}
+		}
+	case fakeUnary:
+		return fakeHasAggregate(e.operand)
+	case fakeBinary:
+		return fakeHasAggregate(e.left) || fakeHasAggregate(e.right)
+	}
+	return false
+}
+
+func fakeBool(b bool) driver.Value {
+	if b {
+		return int64(1)
+	}
+	return int64(0)
+}
+
+func fakeTruthy(value driver.Value) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case int64:
+		return v != 0
+	case time.Time:
+		return !v.IsZero()
+	}
+	return fakeFloat(value) != 0
+}
+
+func fakeFloat(value driver.Value) float64 {
+	switch v := value.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case string, []byte:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(fakeString(v)), 64)
+		return f
+	case time.Time:
+		return float64(v.Unix())
+	}
+	return 0
+}
+
+func fakeString(value driver.Value) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999")
+	}
+	return fmt.Sprint(value)
+}
+
+func fakeArithmetic(op string, left, right driver.Value) driver.Value {
+	a, aInt := left.(int64)
+	b, bInt := right.(int64)
+	if aInt && bInt && op != "/" {
+		switch op {
+		case "+":
+			return a + b
+		case "-":
+			return a - b
+		case "*":
+			return a * b
+		case "%":
+			if b == 0 {
+				return nil
+			}
+			return a % b
+		}
+	}
+	x, y := fakeFloat(left), fakeFloat(right)
+	switch op {
+	case "+":
+		return x + y
+	case "-":
+		return x - y
+	case "*":
+		return x * y
+	case "%":
+		if y == 0 {
+			return nil
+		}
+		return math.Mod(x, y)
+	}
+	if y == 0 {
+		return nil
+	}
+	return x / y
+}
+
+// fakeTimeLayouts are the layouts strings are compared with times in.
+var fakeTimeLayouts = []string{"2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999Z07:00", "2006-01-02"}
+
+// fakeCompare orders two non-NULL values like MySQL with a case-insensitive collation: numbers
+// numerically, strings case-insensitively, times chronologically.
+func fakeCompare(a, b driver.Value) (int, bool) {
+	if ta, ok := a.(time.Time); ok {
+		if tb, ok := fakeTime(b); ok {
+			return ta.Compare(tb), true
+		}
+	}
+	if tb, ok := b.(time.Time); ok {
+		if ta, ok := fakeTime(a); ok {
+			return ta.Compare(tb), true
+		}
+	}
+	_, aText := a.(string)
+	_, aBytes := a.([]byte)
+	_, bText := b.(string)
+	_, bBytes := b.([]byte)
+	if (aText || aBytes) && (bText || bBytes) {
+		return strings.Compare(strings.ToLower(fakeString(a)), strings.ToLower(fakeString(b))), true
+	}
+	ia, aInt := a.(int64)
+	ib, bInt := b.(int64)
+	if aInt && bInt {
+		switch {
+		case ia < ib:
+			return -1, true
+		case ia > ib:
+			return 1, true
+		}
+		return 0, true
+	}
+	x, y := fakeFloat(a), fakeFloat(b)
+	switch {
+	case x < y:
+		return -1, true
+	case x > y:
+		return 1, true
+	}
+	return 0, true
+}
+
+func fakeTime(value driver.Value) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string, []byte:
+		for _, layout := range fakeTimeLayouts {
+			if t, err := time.ParseInLocation(layout, fakeString(v), time.UTC); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// fakeLikePattern compiles a LIKE pattern, case-insensitively.
+func fakeLikePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '%':
+			b.WriteString(".*")
+		case '_':
+			b.WriteString(".")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,782 @@
+package fakesql
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// fakeRow is a row of a fake table.
+type fakeRow map[string]driver.Value
+
+// memTable is a table of the fake database. Statements never modify rows in place: they replace rows
+// and the rows slice, so snapshots only copy the table.
+type memTable struct {
+	name          string
+	columns       []string
+	rows          []fakeRow
+	keys          [][]string
+	autoIncrement int64
+}
+
+// memStore holds the tables of one fake connection.
+type memStore struct {
+	mutex    sync.Mutex
+	database string
+	tables   map[string]*memTable
+	nextID   atomic.Int64
+}
+
+func newMemStore(database string, seed Data) (*memStore, error) {
+	store := &memStore{database: database, tables: make(map[string]*memTable, len(seed))}
+	names := make([]string, 0, len(seed))
+	for name := range seed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		spec := seed[name]
+		primary := spec.PrimaryKey
+		if primary == "" {
+			primary = "id"
+		}
+		table := &memTable{name: name, keys: append([][]string{{primary}}, spec.Unique...)}
+		table.learn(spec.Columns)
+		table.learn([]string{primary})
+		for _, key := range spec.Unique {
+			table.learn(key)
+		}
+		for i, values := range spec.Rows {
+			row := make(fakeRow, len(values))
+			columns := make([]string, 0, len(values))
+			for column, value := range values {
+				converted, err := driver.DefaultParameterConverter.ConvertValue(value)
+				if err != nil {
+					return nil, fmt.Errorf("table %q row %d column %q: %w", name, i, column, err)
+				}
+				row[column] = fakeNormalize(converted)
+				columns = append(columns, column)
+			}
+			sort.Strings(columns)
+			table.learn(columns)
+			table.generateKey(row)
+			if key, conflict := table.conflict(table.rows, row, -1); conflict >= 0 {
+				return nil, fmt.Errorf("table %q row %d: %w", name, i, duplicateEntry(table, key, row))
+			}
+			table.rows = append(table.rows, row)
+		}
+		store.tables[name] = table
+	}
+	return store, nil
+}
+
+// table returns the named table or MySQL's "table doesn't exist" error.
+func (s *memStore) table(name string) (*memTable, error) {
+	if table, ok := s.tables[name]; ok {
+		return table, nil
+	}
+	return nil, &mysqldriver.MySQLError{Number: erNoSuchTable, Message: fmt.Sprintf("Table '%s.%s' doesn't exist", s.database, name)}
+}
+
+// snapshot copies the tables for a later restore.
+func (s *memStore) snapshot() map[string]*memTable {
+	tables := make(map[string]*memTable, len(s.tables))
+	for name, table := range s.tables {
+		copied := *table
+		copied.columns = append([]string(nil), table.columns...)
+		tables[name] = &copied
+	}
+	return tables
+}
+
+// learn adds columns to those returned by SELECT *.
+func (t *memTable) learn(columns []string) {
+	for _, column := range columns {
+		if !slices.Contains(t.columns, column) {
+			t.columns = append(t.columns, column)
+		}
+	}
+}
+
+// generateKey fills a missing integer primary key like AUTO_INCREMENT and returns it, or returns 0.
+func (t *memTable) generateKey(row fakeRow) int64 {
+	if len(t.keys[0]) != 1 {
+		return 0
+	}
+	column := t.keys[0][0]
+	switch value := row[column].(type) {
+	case nil:
+		t.autoIncrement++
+		row[column] = t.autoIncrement
+		return t.autoIncrement
+	case int64:
+		if value == 0 {
+			t.autoIncrement++
+			row[column] = t.autoIncrement
+			return t.autoIncrement
+		}
+		if value > t.autoIncrement {
+			t.autoIncrement = value
+		}
+	}
+	return 0
+}
+
+// conflict returns the key and the index of a row of rows, other than skip, having the same values as
+// row for a key, or -1.
+func (t *memTable) conflict(rows []fakeRow, row fakeRow, skip int) ([]string, int) {
+	for _, key := range t.keys {
+		for i, other := range rows {
+			if i != skip && sameKey(key, row, other) {
+				return key, i
+			}
+		}
+	}
+	return nil, -1
+}
+
+func sameKey(key []string, a, b fakeRow) bool {
+	for _, column := range key {
+		x, y := a[column], b[column]
+		if x == nil || y == nil {
+			return false
+		}
+		if c, _ := fakeCompare(x, y); c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// duplicateEntry is MySQL's duplicate key error for row on key.
+func duplicateEntry(t *memTable, key []string, row fakeRow) error {
+	values := make([]string, len(key))
+	for i, column := range key {
+		values[i] = fakeString(row[column])
+	}
+	keyName := "PRIMARY"
+	if !equalStrings(key, t.keys[0]) {
+		keyName = strings.Join(key, "_")
+	}
+	return &mysqldriver.MySQLError{Number: erDupEntry, Message: fmt.Sprintf("Duplicate entry '%s' for key '%s.%s'", strings.Join(values, "-"), t.name, keyName)}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameRow reports whether two rows have the same values, which decides whether an update changed a row.
+func sameRow(a, b fakeRow) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for column, x := range a {
+		y, ok := b[column]
+		if !ok || (x == nil) != (y == nil) {
+			return false
+		}
+		if x == nil {
+			continue
+		}
+		if tx, ok := x.(time.Time); ok {
+			if ty, ok := y.(time.Time); !ok || !tx.Equal(ty) {
+				return false
+			}
+			continue
+		}
+		if fmt.Sprintf("%T", x) != fmt.Sprintf("%T", y) || fakeString(x) != fakeString(y) {
+			return false
+		}
+	}
+	return true
+}
+
+// memConnector opens sessions on a fake database.
+type memConnector struct {
+	store *memStore
+}
+
+func (c *memConnector) Connect(context.Context) (driver.Conn, error) {
+	return &memConn{store: c.store, id: c.store.nextID.Add(1)}, nil
+}
+
+func (c *memConnector) Driver() driver.Driver {
+	return c
+}
+
+func (c *memConnector) Open(string) (driver.Conn, error) {
+	return c.Connect(context.Background())
+}
+
+// memConn is a session of a fake database.
+type memConn struct {
+	store *memStore
+	id    int64
+
+	// lastInsertID is the first key generated by the last insert of the session, for LAST_INSERT_ID().
+	lastInsertID int64
+
+	// savepoints holds the snapshot of the transaction in progress first, then the named savepoints.
+	savepoints []memSavepoint
+}
+
+type memSavepoint struct {
+	name   string
+	tables map[string]*memTable
+}
+
+var (
+	_ driver.ConnBeginTx      = (*memConn)(nil)
+	_ driver.QueryerContext   = (*memConn)(nil)
+	_ driver.ExecerContext    = (*memConn)(nil)
+	_ driver.Pinger           = (*memConn)(nil)
+	_ driver.StmtQueryContext = (*memStmt)(nil)
+	_ driver.StmtExecContext  = (*memStmt)(nil)
+	_ driver.Connector        = (*memConnector)(nil)
+)
+
+func (c *memConn) Prepare(query string) (driver.Stmt, error) {
+	return &memStmt{conn: c, query: query}, nil
+}
+
+func (c *memConn) Close() error {
+	return nil
+}
+
+func (c *memConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *memConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.store.mutex.Lock()
+	defer c.store.mutex.Unlock()
+	if _, err := c.transaction(fakeTxStatement{op: "begin"}); err != nil {
+		return nil, err
+	}
+	return memTx{conn: c}, nil
+}
+
+func (c *memConn) Ping(context.Context) error {
+	return nil
+}
+
+func (c *memConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := c.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &memRows{columns: result.columns, values: result.values}, nil
+}
+
+func (c *memConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// memResult is the result of a statement of a fake database.
+type memResult struct {
+	columns      []string
+	values       [][]driver.Value
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r *memResult) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r *memResult) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// run parses and executes one statement.
+func (c *memConn) run(query string, args []driver.NamedValue) (*memResult, error) {
+	stmt, err := fakeParse(query, namedValues(args))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v: %s", ErrUnsupported, err, query)
+	}
+
+	c.store.mutex.Lock()
+	defer c.store.mutex.Unlock()
+	switch s := stmt.(type) {
+	case *fakeSelect:
+		return c.query(s)
+	case *fakeInsert:
+		return c.insert(s)
+	case *fakeUpdate:
+		return c.update(s)
+	case *fakeDelete:
+		return c.delete(s)
+	case fakeTxStatement:
+		return c.transaction(s)
+	}
+	return &memResult{}, nil
+}
+
+func (c *memConn) transaction(s fakeTxStatement) (*memResult, error) {
+	find := func() (int, error) {
+		for i := len(c.savepoints) - 1; i > 0; i-- {
+			if c.savepoints[i].name == s.name {
+				return i, nil
+			}
+		}
+		return 0, &mysqldriver.MySQLError{Number: 1305, Message: fmt.Sprintf("SAVEPOINT %s does not exist", s.name)}
+	}
+	switch s.op {
+	case "begin":
+		c.savepoints = []memSavepoint{{tables: c.store.snapshot()}}
+	case "commit":
+		c.savepoints = nil
+	case "rollback":
+		if len(c.savepoints) > 0 {
+			c.store.tables = c.savepoints[0].tables
+		}
+		c.savepoints = nil
+	case "savepoint":
+		if len(c.savepoints) == 0 {
+			break
+		}
+		if i, err := find(); err == nil {
+			c.savepoints = append(c.savepoints[:i], c.savepoints[i+1:]...)
+		}
+		c.savepoints = append(c.savepoints, memSavepoint{name: s.name, tables: c.store.snapshot()})
+	case "rollback_to":
+		i, err := find()
+		if err != nil {
+			return nil, err
+		}
+		c.store.tables = c.savepoints[i].tables
+		c.savepoints[i].tables = c.store.snapshot()
+		c.savepoints = c.savepoints[:i+1]
+	case "release":
+		i, err := find()
+		if err != nil {
+			return nil, err
+		}
+		c.savepoints = c.savepoints[:i]
+	}
+	return &memResult{}, nil
+}
+
+// filter returns the indexes of the rows matching where, ordered by orderBy and cut by limit and offset.
+func (c *memConn) filter(rows []fakeRow, where fakeExpr, orderBy []fakeOrder, limit, offset fakeExpr) ([]int, error) {
+	env := &fakeEnv{conn: c}
+	var matched []int
+	for i, row := range rows {
+		env.row = row
+		value, err := env.eval(where)
+		if err != nil {
+			return nil, err
+		}
+		if fakeTruthy(value) {
+			matched = append(matched, i)
+		}
+	}
+
+	if len(orderBy) > 0 {
+		keys := make(map[int][]driver.Value, len(matched))
+		for _, i := range matched {
+			env.row = rows[i]
+			for _, order := range orderBy {
+				value, err := env.eval(order.expr)
+				if err != nil {
+					return nil, err
+				}
+				keys[i] = append(keys[i], value)
+			}
+		}
+		sort.SliceStable(matched, func(a, b int) bool {
+			ka, kb := keys[matched[a]], keys[matched[b]]
+			for k, order := range orderBy {
+				c := compareNullsFirst(ka[k], kb[k])
+				if order.desc {
+					c = -c
+				}
+				if c != 0 {
+					return c < 0
+				}
+			}
+			return false
+		})
+	}
+
+	skip, err := c.count(offset, 0)
+	if err != nil {
+		return nil, err
+	}
+	n, err := c.count(limit, -1)
+	if err != nil {
+		return nil, err
+	}
+	if skip > len(matched) {
+		skip = len(matched)
+	}
+	matched = matched[skip:]
+	if n >= 0 && n < len(matched) {
+		matched = matched[:n]
+	}
+	return matched, nil
+}
+
+// count evaluates a LIMIT or OFFSET, returning fallback when it is absent.
+func (c *memConn) count(expr fakeExpr, fallback int) (int, error) {
+	if expr == nil {
+		return fallback, nil
+	}
+	value, err := (&fakeEnv{conn: c}).eval(expr)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := value.(int64)
+	if !ok || n < 0 {
+		return 0, fmt.Errorf("%w: invalid LIMIT or OFFSET %v", ErrUnsupported, value)
+	}
+	return int(n), nil
+}
+
+// compareNullsFirst orders values with NULL before everything, as MySQL does in ascending order.
+func compareNullsFirst(a, b driver.Value) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	c, _ := fakeCompare(a, b)
+	return c
+}
+
+func (c *memConn) query(s *fakeSelect) (*memResult, error) {
+	rows := []fakeRow{{}}
+	var table *memTable
+	if s.table != "" {
+		var err error
+		if table, err = c.store.table(s.table); err != nil {
+			return nil, err
+		}
+		rows = table.rows
+	}
+
+	result := &memResult{}
+	for _, item := range s.items {
+		if !item.star {
+			result.columns = append(result.columns, item.name)
+		} else if table == nil {
+			return nil, fmt.Errorf("%w: SELECT * without a table", ErrUnsupported)
+		} else {
+			result.columns = append(result.columns, table.columns...)
+		}
+	}
+
+	aggregate := false
+	for _, item := range s.items {
+		aggregate = aggregate || fakeHasAggregate(item.expr)
+	}
+	if aggregate {
+		matched, err := c.filter(rows, s.where, nil, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		env := &fakeEnv{conn: c, row: fakeRow{}, group: []fakeRow{}}
+		for _, i := range matched {
+			env.group = append(env.group, rows[i])
+		}
+		if len(env.group) > 0 {
+			env.row = env.group[0]
+		}
+		values, err := c.project(env, s.items, table)
+		if err != nil {
+			return nil, err
+		}
+		// The single row of an aggregate query is still subject to LIMIT and OFFSET.
+		kept, err := c.filter([]fakeRow{{}}, nil, nil, s.limit, s.offset)
+		if err != nil {
+			return nil, err
+		}
+		if len(kept) > 0 {
+			result.values = [][]driver.Value{values}
+		}
+		return result, nil
+	}
+
+	matched, err := c.filter(rows, s.where, s.orderBy, s.limit, s.offset)
+	if err != nil {
+		return nil, err
+	}
+	env := &fakeEnv{conn: c}
+	for _, i := range matched {
+		env.row = rows[i]
+		values, err := c.project(env, s.items, table)
+		if err != nil {
+			return nil, err
+		}
+		result.values = append(result.values, values)
+	}
+	return result, nil
+}
+
+// project evaluates the select items on the current row of env.
+func (c *memConn) project(env *fakeEnv, items []fakeSelectItem, table *memTable) ([]driver.Value, error) {
+	var values []driver.Value
+	for _, item := range items {
+		if item.star {
+			for _, column := range table.columns {
+				values = append(values, env.row[column])
+			}
+			continue
+		}
+		value, err := env.eval(item.expr)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func (c *memConn) insert(s *fakeInsert) (*memResult, error) {
+	table, err := c.store.table(s.table)
+	if err != nil {
+		return nil, err
+	}
+	rows := append([]fakeRow(nil), table.rows...)
+	autoIncrement := table.autoIncrement
+	result := &memResult{}
+	env := &fakeEnv{conn: c}
+	for _, exprs := range s.rows {
+		row := make(fakeRow, len(s.columns))
+		for i, column := range s.columns {
+			value, err := env.eval(exprs[i])
+			if err != nil {
+				return nil, err
+			}
+			row[column] = value
+		}
+		generated := table.generateKey(row)
+
+		key, conflict := table.conflict(rows, row, -1)
+		switch {
+		case conflict < 0:
+			rows = append(rows, row)
+			result.rowsAffected++
+			if generated > 0 && result.lastInsertID == 0 {
+				result.lastInsertID = generated
+			}
+		case s.onDuplicate != nil:
+			updated, err := c.assign(rows[conflict], s.onDuplicate, row)
+			if err != nil {
+				return nil, err
+			}
+			if sameRow(rows[conflict], updated) {
+				continue
+			}
+			if key, other := table.conflict(rows, updated, conflict); other >= 0 {
+				table.autoIncrement = autoIncrement
+				return nil, duplicateEntry(table, key, updated)
+			}
+			rows[conflict] = updated
+			result.rowsAffected += 2
+		case s.ignore:
+		default:
+			table.autoIncrement = autoIncrement
+			return nil, duplicateEntry(table, key, row)
+		}
+	}
+	table.learn(s.columns)
+	table.rows = rows
+	if result.lastInsertID > 0 {
+		c.lastInsertID = result.lastInsertID
+	}
+	return result, nil
+}
+
+// assign applies assignments to a copy of row, in order, like MySQL: later assignments see the new
+// values of earlier ones.
+func (c *memConn) assign(row fakeRow, assigns []fakeAssign, inserted fakeRow) (fakeRow, error) {
+	updated := make(fakeRow, len(row))
+	for column, value := range row {
+		updated[column] = value
+	}
+	env := &fakeEnv{conn: c, row: updated, inserted: inserted}
+	for _, a := range assigns {
+		value, err := env.eval(a.expr)
+		if err != nil {
+			return nil, err
+		}
+		updated[a.column] = value
+	}
+	return updated, nil
+}
+
+func (c *memConn) update(s *fakeUpdate) (*memResult, error) {
+	table, err := c.store.table(s.table)
+	if err != nil {
+		return nil, err
+	}
+	matched, err := c.filter(table.rows, s.where, s.orderBy, s.limit, nil)
+	if err != nil {
+		return nil, err
+	}
+	rows := append([]fakeRow(nil), table.rows...)
+	result := &memResult{}
+	for _, i := range matched {
+		updated, err := c.assign(rows[i], s.set, nil)
+		if err != nil {
+			return nil, err
+		}
+		if sameRow(rows[i], updated) {
+			continue
+		}
+		if key, other := table.conflict(rows, updated, i); other >= 0 {
+			return nil, duplicateEntry(table, key, updated)
+		}
+		rows[i] = updated
+		result.rowsAffected++
+	}
+	for _, a := range s.set {
+		table.learn([]string{a.column})
+	}
+	table.rows = rows
+	return result, nil
+}
+
+func (c *memConn) delete(s *fakeDelete) (*memResult, error) {
+	table, err := c.store.table(s.table)
+	if err != nil {
+		return nil, err
+	}
+	matched, err := c.filter(table.rows, s.where, s.orderBy, s.limit, nil)
+	if err != nil {
+		return nil, err
+	}
+	deleted := make(map[int]bool, len(matched))
+	for _, i := range matched {
+		deleted[i] = true
+	}
+	rows := make([]fakeRow, 0, len(table.rows)-len(matched))
+	for i, row := range table.rows {
+		if !deleted[i] {
+			rows = append(rows, row)
+		}
+	}
+	table.rows = rows
+	return &memResult{rowsAffected: int64(len(matched))}, nil
+}
+
+// memStmt is a prepared statement of a fake database, parsed on every execution.
+type memStmt struct {
+	conn  *memConn
+	query string
+}
+
+func (s *memStmt) Close() error {
+	return nil
+}
+
+func (s *memStmt) NumInput() int {
+	return -1
+}
+
+func (s *memStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), ordinalValues(args))
+}
+
+func (s *memStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), ordinalValues(args))
+}
+
+func (s *memStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *memStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+// ordinalValues numbers args for the context methods.
+func ordinalValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+// namedValues drops the names and ordinals of args.
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+// memTx is a transaction of a fake database.
+type memTx struct {
+	conn *memConn
+}
+
+func (t memTx) Commit() error {
+	t.conn.store.mutex.Lock()
+	defer t.conn.store.mutex.Unlock()
+	_, err := t.conn.transaction(fakeTxStatement{op: "commit"})
+	return err
+}
+
+func (t memTx) Rollback() error {
+	t.conn.store.mutex.Lock()
+	defer t.conn.store.mutex.Unlock()
+	_, err := t.conn.transaction(fakeTxStatement{op: "rollback"})
+	return err
+}
+
+// memRows are the rows of a query of a fake database.
+type memRows struct {
+	columns []string
+	values  [][]driver.Value
+	pos     int
+}
+
+func (r *memRows) Columns() []string {
+	return r.columns
+}
+
+func (r *memRows) Close() error {
+	return nil
+}
+
+func (r *memRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.values) {
+		return io.EOF
+	}
+	for i, value := range r.values[r.pos] {
+		if b, ok := value.([]byte); ok {
+			value = append([]byte(nil), b...)
+		}
+		dest[i] = value
+	}
+	r.pos++
+	return nil
+}
@@ -0,0 +1,38 @@
+// Package echomw adapts the middleware package to Echo.
+package echomw
+
+import (
+	"github.com/hemant-dhiman/MySQL-connection/middleware"
+	"github.com/labstack/echo/v4"
+	"net/http"
+)
+
+// Middleware attaches the connection of each request to c.Request().Context(), see middleware.Handler.
+// Requests whose connection cannot be obtained get Options.OnError, or a 503 HTTP error.
+//
+// Example Usage:
+//
+//	e := echo.New()
+//	e.Use(echomw.Middleware(middleware.Options{Resolve: middleware.Fixed("primary_db")}))
+//	e.GET("/users", func(c echo.Context) error {
+//		db, _ := middleware.DBFromContext(c.Request().Context())
+//		...
+//	})
+func Middleware(opts middleware.Options) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			request, r, err := opts.Begin(c.Request())
+			if err != nil {
+				if opts.OnError != nil {
+					opts.OnError(c.Response(), c.Request(), err)
+					return nil
+				}
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "database unavailable").SetInternal(err)
+			}
+			defer request.Finish()
+			c.SetRequest(r)
+			c.Response().Before(func() { request.BeforeWrite(c.Response().Header()) })
+			return next(c)
+		}
+	}
+}
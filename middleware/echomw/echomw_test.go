@@ -0,0 +1,41 @@
+package echomw
+
+import (
+	"github.com/hemant-dhiman/MySQL-connection/middleware"
+	"github.com/hemant-dhiman/MySQL-connection/mocks"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	db, err := mocks.NewDryRunDB()
+	if err != nil {
+		t.Fatalf("NewDryRunDB failed: %v", err)
+	}
+	provider := mocks.NewProvider()
+	provider.Set("primary_db", db)
+
+	e := echo.New()
+	e.Use(Middleware(middleware.Options{Provider: provider, Resolve: middleware.Fixed("primary_db")}))
+	e.GET("/users", func(c echo.Context) error {
+		if _, ok := middleware.DBFromContext(c.Request().Context()); !ok {
+			t.Error("Expected a connection on the request context")
+		}
+		return c.String(http.StatusOK, "ok")
+	})
+	e.GET("/orders", func(c echo.Context) error { return nil }, Middleware(middleware.Options{Provider: provider,
+		Resolve: middleware.Fixed("missing_db")}))
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503, got %d", rec.Code)
+	}
+}
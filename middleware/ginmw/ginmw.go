@@ -0,0 +1,62 @@
+// Package ginmw adapts the middleware package to Gin.
+package ginmw
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hemant-dhiman/MySQL-connection/middleware"
+)
+
+// Middleware attaches the connection of each request to c.Request.Context(), see middleware.Handler.
+// Requests whose connection cannot be obtained are aborted with Options.OnError, or 503.
+//
+// Example Usage:
+//
+//	router := gin.New()
+//	router.Use(ginmw.Middleware(middleware.Options{Resolve: middleware.Fixed("primary_db")}))
+//	router.GET("/users", func(c *gin.Context) {
+//		db, _ := middleware.DBFromContext(c.Request.Context())
+//		...
+//	})
+func Middleware(opts middleware.Options) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		request, r, err := opts.Begin(c.Request)
+		if err != nil {
+			opts.Fail(c.Writer, c.Request, err)
+			c.Abort()
+			return
+		}
+		defer request.Finish()
+		c.Request = r
+		c.Writer = &responseWriter{ResponseWriter: c.Writer, request: request}
+		c.Next()
+	}
+}
+
+// responseWriter calls BeforeWrite before Gin writes the response header.
+type responseWriter struct {
+	gin.ResponseWriter
+	request *middleware.Request
+	wrote   bool
+}
+
+func (w *responseWriter) before() {
+	if !w.wrote && !w.ResponseWriter.Written() {
+		w.wrote = true
+		w.request.BeforeWrite(w.Header())
+	}
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	w.before()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.before()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseWriter) WriteString(s string) (int, error) {
+	w.before()
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,43 @@
+package ginmw
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hemant-dhiman/MySQL-connection/middleware"
+	"github.com/hemant-dhiman/MySQL-connection/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := mocks.NewDryRunDB()
+	if err != nil {
+		t.Fatalf("NewDryRunDB failed: %v", err)
+	}
+	provider := mocks.NewProvider()
+	provider.Set("primary_db", db)
+
+	router := gin.New()
+	router.Use(Middleware(middleware.Options{Provider: provider, Resolve: middleware.Fixed("primary_db")}))
+	router.GET("/users", func(c *gin.Context) {
+		if _, ok := middleware.DBFromContext(c.Request.Context()); !ok {
+			t.Error("Expected a connection on the request context")
+		}
+		c.String(http.StatusOK, "ok")
+	})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Fatalf("Unexpected response %d %q", rec.Code, rec.Body.String())
+	}
+
+	router = gin.New()
+	router.Use(Middleware(middleware.Options{Provider: provider, Resolve: middleware.Fixed("missing_db")}))
+	router.GET("/users", func(c *gin.Context) { t.Error("Expected the handler not to run") })
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,219 @@
+// Package middleware attaches a managed connection to each HTTP request and records the statements the
+// request issues. Handler wraps a net/http handler; the ginmw and echomw packages adapt it to Gin and Echo.
+// They form a module of their own, so applications that do not use them do not depend on Gin and Echo.
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"gorm.io/gorm"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// BudgetWarningHeader is the response header set in development mode when a request issued more
+// statements than its query budget.
+const BudgetWarningHeader = "X-Query-Budget-Exceeded"
+
+// Resolver returns the name of the connection serving a request.
+type Resolver func(r *http.Request) (string, error)
+
+// Fixed serves every request from the named connection.
+func Fixed(name string) Resolver {
+	return func(*http.Request) (string, error) { return name, nil }
+}
+
+// ByPathPrefix serves a request from the connection of the longest path prefix matching its URL path,
+// or from fallback. An empty fallback rejects unmatched requests.
+func ByPathPrefix(routes map[string]string, fallback string) Resolver {
+	prefixes := make([]string, 0, len(routes))
+	for prefix := range routes {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	return func(r *http.Request) (string, error) {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				return routes[prefix], nil
+			}
+		}
+		if fallback == "" {
+			return "", fmt.Errorf("no database connection for path %q", r.URL.Path)
+		}
+		return fallback, nil
+	}
+}
+
+// ByTenantHeader serves a request from the connection returned by connFor for the tenant in header.
+// Set Options.TenantHeader as well to scope the statements to the tenant (DBConfig.TenantGuard).
+func ByTenantHeader(header string, connFor func(tenant string) (string, error)) Resolver {
+	return func(r *http.Request) (string, error) {
+		tenant := r.Header.Get(header)
+		if tenant == "" {
+			return "", fmt.Errorf("missing tenant header %s", header)
+		}
+		return connFor(tenant)
+	}
+}
+
+// Options configures the middleware.
+type Options struct {
+	// Provider supplies the connections. Defaults to connection.GetConnectionManager().
+	Provider connection.DBProvider
+
+	// Resolve selects the connection of each request. Required.
+	Resolve Resolver
+
+	// TenantHeader, when set, puts the value of this request header on the context with connection.WithTenant.
+	TenantHeader string
+
+	// QueryBudget sets a query budget on each request context (connection.WithQueryBudget). Zero sets none.
+	QueryBudget int
+
+	// Dev sets BudgetWarningHeader on responses of requests over their query budget. Use it with
+	// DBConfig.QueryBudgetLogOnly so that such requests still complete.
+	Dev bool
+
+	// Report receives the statistics of each request once it is served, e.g. to record metrics.
+	Report func(r *http.Request, conn string, stats connection.RequestStats)
+
+	// OnError writes the response when the connection of a request cannot be resolved or obtained.
+	// Defaults to 503 Service Unavailable.
+	OnError func(w http.ResponseWriter, r *http.Request, err error)
+}
+
+type dbKey struct{}
+type connNameKey struct{}
+
+// DBFromContext returns the connection attached to a request context by the middleware.
+func DBFromContext(ctx context.Context) (*gorm.DB, bool) {
+	db, ok := ctx.Value(dbKey{}).(*gorm.DB)
+	return db, ok
+}
+
+// ConnectionName returns the name of the connection attached to a request context by the middleware.
+func ConnectionName(ctx context.Context) string {
+	name, _ := ctx.Value(connNameKey{}).(string)
+	return name
+}
+
+// Request is the state of one request between Begin and Finish, for framework adapters.
+type Request struct {
+	opts *Options
+	req  *http.Request
+	conn string
+}
+
+// Begin resolves the connection of r and returns r with a context carrying the connection, the tenant,
+// the query budget and the statistics collector. Adapters call it before the handler and Finish after it.
+func (o *Options) Begin(r *http.Request) (*Request, *http.Request, error) {
+	if o.Resolve == nil {
+		return nil, r, fmt.Errorf("middleware has no Resolve function")
+	}
+	provider := o.Provider
+	if provider == nil {
+		provider = connection.GetConnectionManager()
+	}
+	name, err := o.Resolve(r)
+	if err != nil {
+		return nil, r, err
+	}
+
+	ctx := connection.WithRequestStats(r.Context())
+	if o.TenantHeader != "" {
+		if tenant := r.Header.Get(o.TenantHeader); tenant != "" {
+			ctx = connection.WithTenant(ctx, tenant)
+		}
+	}
+	if o.QueryBudget > 0 {
+		ctx = connection.WithQueryBudget(ctx, o.QueryBudget)
+	}
+	db, err := provider.GetDBContext(ctx, name)
+	if err != nil {
+		return nil, r, err
+	}
+	ctx = context.WithValue(context.WithValue(ctx, dbKey{}, db), connNameKey{}, name)
+	r = r.WithContext(ctx)
+	return &Request{opts: o, req: r, conn: name}, r, nil
+}
+
+// BeforeWrite sets BudgetWarningHeader on h in development mode if the request is over its budget.
+// Adapters call it right before the response header is written.
+func (q *Request) BeforeWrite(h http.Header) {
+	if !q.opts.Dev {
+		return
+	}
+	if used, limit, ok := connection.QueryBudgetUsage(q.req.Context()); ok && used > limit {
+		h.Set(BudgetWarningHeader, fmt.Sprintf("%d queries, budget %d", used, limit))
+	}
+}
+
+// Finish reports the statistics of the request.
+func (q *Request) Finish() {
+	if q.opts.Report == nil {
+		return
+	}
+	stats, _ := connection.RequestStatsFrom(q.req.Context())
+	q.opts.Report(q.req, q.conn, stats)
+}
+
+// Fail writes the response of a request whose connection cannot be obtained with OnError, or 503.
+func (o *Options) Fail(w http.ResponseWriter, r *http.Request, err error) {
+	if o.OnError != nil {
+		o.OnError(w, r, err)
+		return
+	}
+	http.Error(w, "database unavailable", http.StatusServiceUnavailable)
+}
+
+// Handler returns net/http middleware attaching the connection of each request to its context.
+//
+// Example Usage:
+//
+//	mw := middleware.Handler(middleware.Options{Resolve: middleware.Fixed("primary_db"), QueryBudget: 20})
+//	http.Handle("/users", mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//		db, _ := middleware.DBFromContext(r.Context())
+//		...
+//	})))
+func Handler(opts Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			request, r, err := opts.Begin(r)
+			if err != nil {
+				opts.Fail(w, r, err)
+				return
+			}
+			defer request.Finish()
+			next.ServeHTTP(&responseWriter{ResponseWriter: w, request: request}, r)
+		})
+	}
+}
+
+// responseWriter calls BeforeWrite before the response header is written.
+type responseWriter struct {
+	http.ResponseWriter
+	request *Request
+	wrote   bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wrote {
+		w.wrote = true
+		w.request.BeforeWrite(w.Header())
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
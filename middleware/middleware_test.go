@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"github.com/hemant-dhiman/MySQL-connection/mocks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandlerAttachesConnection(t *testing.T) {
+	db, err := mocks.NewDryRunDB()
+	if err != nil {
+		t.Fatalf("NewDryRunDB failed: %v", err)
+	}
+	provider := mocks.NewProvider()
+	provider.Set("orders_db", db)
+
+	var reported string
+	opts := Options{
+		Provider:     provider,
+		Resolve:      ByPathPrefix(map[string]string{"/orders": "orders_db", "/orders/archive": "archive_db"}, ""),
+		TenantHeader: "X-Tenant",
+		QueryBudget:  10,
+		Report: func(r *http.Request, conn string, stats connection.RequestStats) {
+			reported = conn
+		},
+	}
+	handler := Handler(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got, ok := DBFromContext(r.Context()); !ok || got == nil || ConnectionName(r.Context()) != "orders_db" {
+			t.Errorf("Expected orders_db on the context, got %v", got)
+		}
+		if tenant, _ := connection.TenantFromContext(r.Context()); tenant != "acme" {
+			t.Errorf("Expected tenant acme, got %v", tenant)
+		}
+		if _, limit, ok := connection.QueryBudgetUsage(r.Context()); !ok || limit != 10 {
+			t.Errorf("Expected a budget of 10, got %d", limit)
+		}
+		if _, ok := connection.RequestStatsFrom(r.Context()); !ok {
+			t.Error("Expected the request statistics to be collected")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/42", nil)
+	req.Header.Set("X-Tenant", "acme")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || reported != "orders_db" {
+		t.Fatalf("Unexpected response %d, reported %q", rec.Code, reported)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders/archive/1", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 for an unknown connection, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 for an unrouted path, got %d", rec.Code)
+	}
+}
+
+func TestByTenantHeader(t *testing.T) {
+	resolve := ByTenantHeader("X-Tenant", func(tenant string) (string, error) {
+		if tenant == "acme" {
+			return "acme_db", nil
+		}
+		return "", errors.New("unknown tenant")
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, err := resolve(req); err == nil {
+		t.Fatal("Expected an error without a tenant header, got nil")
+	}
+	req.Header.Set("X-Tenant", "acme")
+	if name, err := resolve(req); err != nil || name != "acme_db" {
+		t.Fatalf("Expected acme_db, got %q, %v", name, err)
+	}
+}
+
+func TestBeforeWriteWithinBudget(t *testing.T) {
+	request := &Request{opts: &Options{Dev: true}, req: httptest.NewRequest(http.MethodGet, "/", nil).
+		WithContext(connection.WithQueryBudget(context.Background(), 1))}
+	header := http.Header{}
+	request.BeforeWrite(header)
+	if header.Get(BudgetWarningHeader) != "" {
+		t.Fatalf("Expected no warning within the budget, got %q", header.Get(BudgetWarningHeader))
+	}
+}
@@ -0,0 +1,133 @@
+// Package mocks provides fakes of the connection package interfaces for unit tests of code that
+// depends on them, without a live database.
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"sync"
+)
+
+// Provider is an in-memory connection.DBProvider. Connections are registered with Set, failures
+// with Fail, and the calls made are recorded for assertions.
+//
+// Example Usage:
+//
+//	db, _ := mocks.NewDryRunDB()
+//	provider := mocks.NewProvider()
+//	provider.Set("primary_db", db)
+//	service := &UserService{DB: provider}
+type Provider struct {
+	mutex  sync.Mutex
+	dbs    map[string]*gorm.DB
+	errs   map[string]error
+	calls  []string
+	closed []string
+}
+
+var _ connection.DBProvider = (*Provider)(nil)
+
+// NewProvider returns a Provider without connections.
+func NewProvider() *Provider {
+	return &Provider{dbs: make(map[string]*gorm.DB), errs: make(map[string]error)}
+}
+
+// Set registers db as the named connection and clears a failure set with Fail.
+func (p *Provider) Set(name string, db *gorm.DB) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.dbs[name] = db
+	delete(p.errs, name)
+}
+
+// Fail makes GetDB and GetDBContext return err for the named connection.
+func (p *Provider) Fail(name string, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.errs[name] = err
+}
+
+// GetDB returns the connection registered with Set.
+func (p *Provider) GetDB(name string) (*gorm.DB, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.calls = append(p.calls, name)
+
+	if err, failed := p.errs[name]; failed {
+		return nil, err
+	}
+	db, exists := p.dbs[name]
+	if !exists {
+		return nil, fmt.Errorf("database connection %q does not exist", name)
+	}
+	return db, nil
+}
+
+// GetDBContext returns the connection registered with Set, bound to ctx.
+func (p *Provider) GetDBContext(ctx context.Context, name string) (*gorm.DB, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	db, err := p.GetDB(name)
+	if err != nil {
+		return nil, err
+	}
+	return db.WithContext(ctx), nil
+}
+
+// CloseConnection forgets the named connection.
+func (p *Provider) CloseConnection(name string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, exists := p.dbs[name]; !exists {
+		return fmt.Errorf("database connection %q does not exist", name)
+	}
+	delete(p.dbs, name)
+	p.closed = append(p.closed, name)
+	return nil
+}
+
+// CloseAllConnections forgets every connection. It never fails.
+func (p *Provider) CloseAllConnections() []error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for name := range p.dbs {
+		p.closed = append(p.closed, name)
+	}
+	p.dbs = make(map[string]*gorm.DB)
+	return nil
+}
+
+// Calls returns the connection names requested through GetDB and GetDBContext, in order.
+func (p *Provider) Calls() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]string(nil), p.calls...)
+}
+
+// Closed returns the names of the connections closed so far.
+func (p *Provider) Closed() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return append([]string(nil), p.closed...)
+}
+
+// NewDryRunDB returns a MySQL GORM connection in dry run mode: statements are built, and can be read
+// from the Statement of the result, but never sent to a server.
+func NewDryRunDB() (*gorm.DB, error) {
+	return gorm.Open(mysql.New(mysql.Config{
+		DSN:                       "mock:mock@tcp(127.0.0.1:3306)/mock",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		DryRun:                 true,
+		DisableAutomaticPing:   true,
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+}
@@ -0,0 +1,60 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type mockTestUser struct {
+	ID   uint
+	Name string
+}
+
+func TestProviderGetDB(t *testing.T) {
+	db, err := NewDryRunDB()
+	if err != nil {
+		t.Fatalf("Failed to open dry run database: %v", err)
+	}
+	provider := NewProvider()
+	provider.Set("primary_db", db)
+
+	got, err := provider.GetDBContext(context.Background(), "primary_db")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stmt := got.Where("name = ?", "a").Find(&[]mockTestUser{}).Statement
+	if sql := stmt.SQL.String(); sql != "SELECT * FROM `mock_test_users` WHERE name = ?" {
+		t.Fatalf("Unexpected statement: %s", sql)
+	}
+
+	if _, err := provider.GetDB("missing_db"); err == nil {
+		t.Fatal("Expected an error for a non-existent connection, got nil")
+	}
+
+	boom := errors.New("boom")
+	provider.Fail("primary_db", boom)
+	if _, err := provider.GetDB("primary_db"); !errors.Is(err, boom) {
+		t.Fatalf("Expected the injected error, got %v", err)
+	}
+
+	if calls := provider.Calls(); len(calls) != 3 || calls[1] != "missing_db" {
+		t.Fatalf("Unexpected calls: %v", calls)
+	}
+}
+
+func TestProviderClose(t *testing.T) {
+	db, _ := NewDryRunDB()
+	provider := NewProvider()
+	provider.Set("a", db)
+
+	if err := provider.CloseConnection("a"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := provider.CloseConnection("a"); err == nil {
+		t.Fatal("Expected an error closing a closed connection, got nil")
+	}
+	if closed := provider.Closed(); len(closed) != 1 || closed[0] != "a" {
+		t.Fatalf("Unexpected closed connections: %v", closed)
+	}
+}
@@ -0,0 +1,161 @@
+// Package parquetexport adds connection.FormatParquet to the formats of connection.Export. It is a
+// module of its own, so applications that do not export Parquet do not depend on a Parquet library.
+// Import it for its side effect:
+//
+//	import _ "github.com/hemant-dhiman/MySQL-connection/parquetexport"
+//
+//	n, err := connection.GetConnectionManager().Export(ctx, "analytics", "SELECT * FROM audience", f, connection.FormatParquet)
+package parquetexport
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"github.com/parquet-go/parquet-go"
+	"io"
+	"sort"
+)
+
+// RowGroupRows is the number of rows of each row group of the file. A row group is held in memory
+// until it is complete.
+const RowGroupRows = 64 * 1024
+
+func init() {
+	connection.RegisterExportFormat(connection.FormatParquet, NewWriter)
+}
+
+// NewWriter returns an ExportWriter writing a Parquet file to w. Every column is optional, so NULL is
+// written as a null value. The columns get the Parquet type matching their MySQL type:
+//   - integer types: INT(64), or UINT(64) for unsigned types;
+//   - FLOAT and DOUBLE: DOUBLE;
+//   - binary types: BYTE_ARRAY;
+//   - DECIMAL, temporal and text types: STRING, DECIMAL keeping its exact text.
+//
+// The file is complete once Export returns.
+func NewWriter(w io.Writer) connection.ExportWriter {
+	return &writer{w: w}
+}
+
+// writer is the ExportWriter of FormatParquet.
+type writer struct {
+	w       io.Writer
+	file    *parquet.Writer
+	columns []connection.ExportColumn
+
+	// leaves maps the position of a result column to its column index in the file. Parquet orders
+	// the columns of a group by name.
+	leaves []int
+
+	// kinds are the physical types of the columns, by position in the result.
+	kinds []parquet.Kind
+
+	row    parquet.Row
+	closed bool
+}
+
+func (p *writer) Header(columns []connection.ExportColumn) error {
+	group := make(parquet.Group, len(columns))
+	for _, column := range columns {
+		if _, exists := group[column.Name]; exists {
+			return fmt.Errorf("duplicate column %q: Parquet columns need distinct names", column.Name)
+		}
+		group[column.Name] = parquet.Optional(columnNode(column.DatabaseType))
+	}
+
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	sort.Strings(names)
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		index[name] = i
+	}
+
+	p.columns = columns
+	p.leaves = make([]int, len(columns))
+	p.kinds = make([]parquet.Kind, len(columns))
+	for i, column := range columns {
+		p.leaves[i] = index[column.Name]
+		p.kinds[i] = group[column.Name].Type().Kind()
+	}
+	p.row = make(parquet.Row, len(columns))
+	p.file = parquet.NewWriter(p.w, parquet.NewSchema("export", group), parquet.MaxRowsPerRowGroup(RowGroupRows))
+	return nil
+}
+
+// columnNode returns the Parquet node of a column of the MySQL type typeName.
+func columnNode(typeName string) parquet.Node {
+	switch typeName {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR":
+		return parquet.Int(64)
+	case "UNSIGNED TINYINT", "UNSIGNED SMALLINT", "UNSIGNED MEDIUMINT", "UNSIGNED INT", "UNSIGNED BIGINT":
+		return parquet.Uint(64)
+	case "FLOAT", "DOUBLE":
+		return parquet.Leaf(parquet.DoubleType)
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "GEOMETRY":
+		return parquet.Leaf(parquet.ByteArrayType)
+	}
+	return parquet.String()
+}
+
+func (p *writer) Row(values []interface{}) error {
+	for i, v := range values {
+		value, err := columnValue(v)
+		if err != nil {
+			return fmt.Errorf("column %q: %w", p.columns[i].Name, err)
+		}
+		if value.IsNull() {
+			p.row[p.leaves[i]] = value.Level(0, 0, p.leaves[i])
+			continue
+		}
+		if value.Kind() != p.kinds[i] && p.kinds[i] == parquet.ByteArray {
+			// Text columns take any value as its text, like in CSV.
+			value = parquet.ByteArrayValue([]byte(fmt.Sprint(v)))
+		}
+		if value.Kind() != p.kinds[i] {
+			return fmt.Errorf("column %q: %v value for a %v column", p.columns[i].Name, value.Kind(), p.kinds[i])
+		}
+		p.row[p.leaves[i]] = value.Level(0, 1, p.leaves[i])
+	}
+	_, err := p.file.WriteRows([]parquet.Row{p.row})
+	return err
+}
+
+// columnValue converts a value of an Export row to a Parquet value.
+func columnValue(v interface{}) (parquet.Value, error) {
+	switch t := v.(type) {
+	case nil:
+		return parquet.NullValue(), nil
+	case int64:
+		return parquet.Int64Value(t), nil
+	case uint64:
+		return parquet.Int64Value(int64(t)), nil
+	case float64:
+		return parquet.DoubleValue(t), nil
+	case float32:
+		return parquet.DoubleValue(float64(t)), nil
+	case json.Number:
+		return parquet.ByteArrayValue([]byte(t)), nil
+	case string:
+		return parquet.ByteArrayValue([]byte(t)), nil
+	case []byte:
+		return parquet.ByteArrayValue(t), nil
+	}
+	return parquet.Value{}, fmt.Errorf("unsupported value of type %T", v)
+}
+
+// Flush does nothing: rows are written a row group at a time, every RowGroupRows rows.
+func (p *writer) Flush() error {
+	return nil
+}
+
+// Close writes the last row group and the footer of the file. An export failing before its header
+// leaves the output empty.
+func (p *writer) Close() error {
+	if p.file == nil || p.closed {
+		return nil
+	}
+	p.closed = true
+	return p.file.Close()
+}
@@ -0,0 +1,107 @@
+package parquetexport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"github.com/parquet-go/parquet-go"
+	"io"
+	"testing"
+)
+
+// readRows opens the Parquet file in b and returns its column names and rows.
+func readRows(t *testing.T, b []byte) ([]string, []parquet.Row) {
+	t.Helper()
+	file, err := parquet.OpenFile(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("Failed to open the Parquet file: %v", err)
+	}
+	var names []string
+	for _, field := range file.Schema().Fields() {
+		names = append(names, field.Name())
+	}
+	reader := parquet.NewReader(file)
+	defer reader.Close()
+	rows := make([]parquet.Row, file.NumRows())
+	if n, err := reader.ReadRows(rows); n != len(rows) || (err != nil && err != io.EOF) {
+		t.Fatalf("ReadRows = %d, %v; want %d rows", n, err, len(rows))
+	}
+	return names, rows
+}
+
+func TestWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := NewWriter(&out)
+	columns := []connection.ExportColumn{{Name: "id", DatabaseType: "BIGINT"}, {Name: "amount", DatabaseType: "DECIMAL"},
+		{Name: "score", DatabaseType: "DOUBLE"}, {Name: "payload", DatabaseType: "BLOB"}, {Name: "deleted_at", DatabaseType: "DATETIME"}}
+	if err := w.Header(columns); err != nil {
+		t.Fatalf("Header failed: %v", err)
+	}
+	rows := [][]interface{}{
+		{int64(7), json.Number("10.50"), 0.5, []byte{0x01, 0x02}, "2024-01-01 00:00:00"},
+		{int64(8), nil, nil, nil, nil},
+	}
+	for _, row := range rows {
+		if err := w.Row(row); err != nil {
+			t.Fatalf("Row failed: %v", err)
+		}
+	}
+	if err := w.Row([]interface{}{"seven", nil, nil, nil, nil}); err == nil {
+		t.Fatal("Expected an error for a text value in an integer column, got nil")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	names, got := readRows(t, out.Bytes())
+	want := []string{"amount", "deleted_at", "id", "payload", "score"}
+	if len(names) != len(want) {
+		t.Fatalf("Columns = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("Columns = %v, want %v", names, want)
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(got))
+	}
+	first := got[0]
+	if first[2].Int64() != 7 || string(first[0].ByteArray()) != "10.50" || first[4].Double() != 0.5 ||
+		!bytes.Equal(first[3].ByteArray(), []byte{0x01, 0x02}) || string(first[1].ByteArray()) != "2024-01-01 00:00:00" {
+		t.Fatalf("Unexpected first row %v", first)
+	}
+	second := got[1]
+	if second[2].Int64() != 8 || !second[0].IsNull() || !second[1].IsNull() || !second[3].IsNull() || !second[4].IsNull() {
+		t.Fatalf("Unexpected second row %v", second)
+	}
+}
+
+func TestWriterDuplicateColumns(t *testing.T) {
+	w := NewWriter(io.Discard)
+	if err := w.Header([]connection.ExportColumn{{Name: "id"}, {Name: "id"}}); err == nil {
+		t.Fatal("Expected an error for duplicate column names, got nil")
+	}
+}
+
+func TestExportParquet(t *testing.T) {
+	factory := connection.GetConnectionManager()
+	err := factory.InitFake("parquet_test_analytics", connection.FakeData{"audience": {
+		Columns: []string{"id", "name"},
+		Rows:    []map[string]any{{"id": 1, "name": "alice"}, {"id": 2, "name": "bob"}},
+	}})
+	if err != nil {
+		t.Fatalf("InitFake failed: %v", err)
+	}
+	defer factory.CloseConnection("parquet_test_analytics")
+
+	var out bytes.Buffer
+	n, err := factory.Export(context.Background(), "parquet_test_analytics", "SELECT id, name FROM audience", &out, connection.FormatParquet)
+	if err != nil || n != 2 {
+		t.Fatalf("Export = %d, %v; want 2 rows", n, err)
+	}
+	if _, rows := readRows(t, out.Bytes()); len(rows) != 2 {
+		t.Fatalf("Expected 2 rows in the file, got %d", len(rows))
+	}
+}
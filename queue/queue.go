@@ -0,0 +1,429 @@
+/*
+Package queue implements a simple MySQL-backed job queue on top of the connections managed by the
+connection package, for services that need background jobs without introducing Redis or Kafka.
+
+Features:
+  - Enqueue jobs with an opaque payload, optionally delayed.
+  - Claim jobs concurrently from many workers with SELECT ... FOR UPDATE SKIP LOCKED (MySQL 8.0+).
+  - Leases: a claimed job that is neither completed nor failed before its lease expires is claimed again,
+    or dead-lettered when that was its last attempt.
+  - Retries with exponential backoff and a dead-letter state after MaxAttempts failures.
+
+Usage Example:
+
+	q := queue.New(connection.GetConnectionManager(), "primary_db", queue.Options{})
+	if err := q.EnsureSchema(ctx); err != nil {
+		log.Fatalf("Failed to create queue table: %v", err)
+	}
+
+	_, err := q.Enqueue(ctx, "emails", []byte(`{"to":"a@example.com"}`))
+
+	err = q.Work(ctx, "emails", func(ctx context.Context, job *queue.Job) error {
+		return sendEmail(ctx, job.Payload)
+	}, queue.WorkOptions{})
+*/
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"gorm.io/gorm"
+	"log"
+	"regexp"
+	"time"
+)
+
+const (
+	defaultTable       = "mysqlconn_jobs"
+	defaultMaxAttempts = 5
+	defaultLease       = 5 * time.Minute
+	maxBackoff         = time.Hour
+)
+
+// ErrLeaseLost is returned by Complete and Fail when the job's lease expired and the job was
+// claimed again by another worker (or the job no longer exists).
+var ErrLeaseLost = errors.New("job lease lost")
+
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Options configures a Queue. Zero values select the defaults.
+type Options struct {
+	// Table is the name of the jobs table. Defaults to "mysqlconn_jobs".
+	Table string
+
+	// MaxAttempts is the number of attempts before a job is dead-lettered. Defaults to 5.
+	MaxAttempts int
+
+	// Lease is how long a claimed job stays invisible to other workers. Defaults to 5 minutes.
+	Lease time.Duration
+
+	// Backoff returns the delay before retrying a job that failed its attempt-th attempt.
+	// Defaults to exponential backoff (2^attempt seconds, capped at one hour).
+	Backoff func(attempt int) time.Duration
+}
+
+// Job is a claimed job.
+type Job struct {
+	ID          uint64
+	Queue       string
+	Payload     []byte
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	CreatedAt   time.Time
+	LastError   string
+}
+
+// Queue is a job queue stored in a table of a managed connection.
+type Queue struct {
+	factory *connection.ConnectionManager
+	conn    string
+	opts    Options
+}
+
+// New creates a queue stored on the connection connName of factory.
+func New(factory *connection.ConnectionManager, connName string, opts Options) *Queue {
+	if opts.Table == "" {
+		opts.Table = defaultTable
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = defaultLease
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = ExponentialBackoff
+	}
+	return &Queue{factory: factory, conn: connName, opts: opts}
+}
+
+// ExponentialBackoff waits 2^attempt seconds, capped at one hour.
+func ExponentialBackoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt > 12 {
+		return maxBackoff
+	}
+	delay := time.Duration(1<<uint(attempt)) * time.Second
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
+
+// EnsureSchema creates the jobs table if it does not exist.
+func (q *Queue) EnsureSchema(ctx context.Context) error {
+	db, err := q.db(ctx)
+	if err != nil {
+		return err
+	}
+	return db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` ("+
+		"`id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,"+
+		"`queue` VARCHAR(191) NOT NULL,"+
+		"`payload` LONGBLOB NOT NULL,"+
+		"`status` ENUM('pending','running','dead') NOT NULL DEFAULT 'pending',"+
+		"`attempts` INT NOT NULL DEFAULT 0,"+
+		"`max_attempts` INT NOT NULL,"+
+		"`run_at` DATETIME(6) NOT NULL,"+
+		"`locked_until` DATETIME(6) NULL,"+
+		"`last_error` TEXT NULL,"+
+		"`created_at` DATETIME(6) NOT NULL,"+
+		"`updated_at` DATETIME(6) NOT NULL,"+
+		"KEY `idx_claim` (`queue`, `status`, `run_at`))", q.opts.Table)).Error
+}
+
+// Enqueue adds a job to queue that is ready to run immediately and returns its id.
+func (q *Queue) Enqueue(ctx context.Context, queue string, payload []byte) (uint64, error) {
+	return q.EnqueueIn(ctx, queue, payload, 0)
+}
+
+// EnqueueIn adds a job to queue that becomes ready after delay and returns its id.
+func (q *Queue) EnqueueIn(ctx context.Context, queue string, payload []byte, delay time.Duration) (uint64, error) {
+	db, err := q.db(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if payload == nil {
+		payload = []byte{}
+	}
+
+	var id uint64
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Server time is used throughout so that clock skew between workers does not matter.
+		err := tx.Exec(fmt.Sprintf("INSERT INTO `%s` (`queue`, `payload`, `status`, `attempts`, `max_attempts`, `run_at`, `created_at`, `updated_at`) "+
+			"VALUES (?, ?, 'pending', 0, ?, NOW(6) + INTERVAL ? MICROSECOND, NOW(6), NOW(6))", q.opts.Table),
+			queue, payload, q.opts.MaxAttempts, delay.Microseconds()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Raw("SELECT LAST_INSERT_ID()").Scan(&id).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue job on %q: %w", queue, err)
+	}
+	return id, nil
+}
+
+// Claim leases up to limit ready jobs of queue. Jobs whose previous lease expired are claimed again,
+// counting an attempt, unless they already used MaxAttempts attempts: their worker crashed or hung on
+// each of them, so they are dead-lettered instead. Rows locked by concurrent claimers are skipped
+// rather than waited for.
+func (q *Queue) Claim(ctx context.Context, queue string, limit int) ([]*Job, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("claim limit must be positive, got %d", limit)
+	}
+	db, err := q.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*Job
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var rows []jobRow
+		err := tx.Raw(fmt.Sprintf("SELECT `id`, `queue`, `payload`, `status`, `attempts`, `max_attempts`, `run_at`, `created_at`, `last_error` FROM `%s` "+
+			"WHERE `queue` = ? AND ((`status` = 'pending' AND `run_at` <= NOW(6)) OR (`status` = 'running' AND `locked_until` < NOW(6))) "+
+			"ORDER BY `run_at`, `id` LIMIT ? FOR UPDATE SKIP LOCKED", q.opts.Table), queue, limit).Scan(&rows).Error
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+
+		claimed, exhausted := splitExhausted(rows)
+		if len(exhausted) > 0 {
+			err := tx.Exec(fmt.Sprintf("UPDATE `%s` SET `status` = 'dead', `locked_until` = NULL, "+
+				"`last_error` = CONCAT('lease expired after ', `attempts`, ' attempt(s)'), `updated_at` = NOW(6) WHERE `id` IN ?", q.opts.Table),
+				exhausted).Error
+			if err != nil {
+				return err
+			}
+			log.Printf("Queue %q: dead-lettered %d job(s) whose lease expired on their last attempt", queue, len(exhausted))
+		}
+		if len(claimed) == 0 {
+			return nil
+		}
+
+		ids := make([]uint64, len(claimed))
+		for i, row := range claimed {
+			ids[i] = row.ID
+			jobs = append(jobs, row.job())
+			jobs[i].Attempts++
+		}
+		return tx.Exec(fmt.Sprintf("UPDATE `%s` SET `status` = 'running', `attempts` = `attempts` + 1, "+
+			"`locked_until` = NOW(6) + INTERVAL ? MICROSECOND, `updated_at` = NOW(6) WHERE `id` IN ?", q.opts.Table),
+			q.opts.Lease.Microseconds(), ids).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs on %q: %w", queue, err)
+	}
+	return jobs, nil
+}
+
+// splitExhausted separates the rows to claim from the ids of the jobs whose lease expired on their
+// last attempt.
+func splitExhausted(rows []jobRow) ([]jobRow, []uint64) {
+	var claimed []jobRow
+	var exhausted []uint64
+	for _, row := range rows {
+		if row.Status == "running" && row.Attempts >= row.MaxAttempts {
+			exhausted = append(exhausted, row.ID)
+			continue
+		}
+		claimed = append(claimed, row)
+	}
+	return claimed, exhausted
+}
+
+// Complete removes a successfully processed job.
+func (q *Queue) Complete(ctx context.Context, job *Job) error {
+	db, err := q.db(ctx)
+	if err != nil {
+		return err
+	}
+	// The attempt counter fences out workers whose lease expired and was taken over.
+	result := db.Exec(fmt.Sprintf("DELETE FROM `%s` WHERE `id` = ? AND `status` = 'running' AND `attempts` = ?", q.opts.Table),
+		job.ID, job.Attempts)
+	return leaseResult(result, job)
+}
+
+// Extend renews the lease of a claimed job for another Lease from now, for handlers running longer
+// than Lease.
+func (q *Queue) Extend(ctx context.Context, job *Job) error {
+	db, err := q.db(ctx)
+	if err != nil {
+		return err
+	}
+	result := db.Exec(fmt.Sprintf("UPDATE `%s` SET `locked_until` = NOW(6) + INTERVAL ? MICROSECOND, `updated_at` = NOW(6) "+
+		"WHERE `id` = ? AND `status` = 'running' AND `attempts` = ?", q.opts.Table),
+		q.opts.Lease.Microseconds(), job.ID, job.Attempts)
+	return leaseResult(result, job)
+}
+
+// Fail records a failed attempt. The job is retried after the backoff delay, or dead-lettered
+// once it has used MaxAttempts attempts.
+func (q *Queue) Fail(ctx context.Context, job *Job, cause error) error {
+	db, err := q.db(ctx)
+	if err != nil {
+		return err
+	}
+
+	message := ""
+	if cause != nil {
+		message = cause.Error()
+	}
+	status := "pending"
+	if job.Attempts >= job.MaxAttempts {
+		status = "dead"
+	}
+	result := db.Exec(fmt.Sprintf("UPDATE `%s` SET `status` = ?, `last_error` = ?, `locked_until` = NULL, "+
+		"`run_at` = NOW(6) + INTERVAL ? MICROSECOND, `updated_at` = NOW(6) "+
+		"WHERE `id` = ? AND `status` = 'running' AND `attempts` = ?", q.opts.Table),
+		status, message, q.opts.Backoff(job.Attempts).Microseconds(), job.ID, job.Attempts)
+	return leaseResult(result, job)
+}
+
+// DeadLetters returns up to limit dead-lettered jobs of queue, oldest first.
+func (q *Queue) DeadLetters(ctx context.Context, queue string, limit int) ([]*Job, error) {
+	db, err := q.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []jobRow
+	err = db.Raw(fmt.Sprintf("SELECT `id`, `queue`, `payload`, `attempts`, `max_attempts`, `run_at`, `created_at`, `last_error` FROM `%s` "+
+		"WHERE `queue` = ? AND `status` = 'dead' ORDER BY `id` LIMIT ?", q.opts.Table), queue, limit).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dead letters of %q: %w", queue, err)
+	}
+	jobs := make([]*Job, len(rows))
+	for i, row := range rows {
+		jobs[i] = row.job()
+	}
+	return jobs, nil
+}
+
+// Requeue moves a dead-lettered job back to pending with a fresh attempt budget.
+func (q *Queue) Requeue(ctx context.Context, id uint64) error {
+	db, err := q.db(ctx)
+	if err != nil {
+		return err
+	}
+	result := db.Exec(fmt.Sprintf("UPDATE `%s` SET `status` = 'pending', `attempts` = 0, `run_at` = NOW(6), `updated_at` = NOW(6) "+
+		"WHERE `id` = ? AND `status` = 'dead'", q.opts.Table), id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("job %d is not dead-lettered", id)
+	}
+	return nil
+}
+
+// WorkOptions configures Work.
+type WorkOptions struct {
+	// PollInterval is the wait between claims when the queue is empty. Defaults to one second.
+	PollInterval time.Duration
+
+	// BatchSize is the number of jobs claimed at once. Defaults to 10. The jobs of a batch are
+	// handled one after another, and the lease of each is extended right before its handler runs,
+	// so each handler gets a full Lease. A job waiting in the batch longer than Lease may have been
+	// claimed by another worker meanwhile: it is skipped.
+	BatchSize int
+}
+
+// Work claims and processes jobs of queue until ctx is cancelled. A nil error from handler
+// completes the job; an error fails it (retry or dead letter). Work returns ctx.Err().
+func (q *Queue) Work(ctx context.Context, queue string, handler func(ctx context.Context, job *Job) error, opts WorkOptions) error {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 10
+	}
+
+	for {
+		jobs, err := q.Claim(ctx, queue, opts.BatchSize)
+		if err != nil {
+			log.Printf("Queue %q: %v", queue, err)
+		}
+		for i, job := range jobs {
+			// The first lease just started; the others ran while the previous jobs were handled.
+			if i > 0 {
+				if err := q.Extend(ctx, job); err != nil {
+					log.Printf("Queue %q: skipping job %d: %v", queue, job.ID, err)
+					continue
+				}
+			}
+			if err := handler(ctx, job); err != nil {
+				err = q.Fail(ctx, job, err)
+				if err != nil {
+					log.Printf("Queue %q: failed to record failure of job %d: %v", queue, job.ID, err)
+				}
+			} else if err := q.Complete(ctx, job); err != nil {
+				log.Printf("Queue %q: failed to complete job %d: %v", queue, job.ID, err)
+			}
+		}
+
+		if len(jobs) == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(opts.PollInterval):
+			}
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// db resolves the managed connection and validates the table name.
+func (q *Queue) db(ctx context.Context) (*gorm.DB, error) {
+	if !tableNamePattern.MatchString(q.opts.Table) {
+		return nil, fmt.Errorf("invalid queue table name %q", q.opts.Table)
+	}
+	db, err := q.factory.GetDB(q.conn)
+	if err != nil {
+		return nil, err
+	}
+	return db.WithContext(ctx), nil
+}
+
+func leaseResult(result *gorm.DB, job *Job) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: job %d attempt %d", ErrLeaseLost, job.ID, job.Attempts)
+	}
+	return nil
+}
+
+type jobRow struct {
+	ID          uint64
+	Queue       string
+	Payload     []byte
+	Status      string
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	CreatedAt   time.Time
+	LastError   *string
+}
+
+func (r jobRow) job() *Job {
+	job := &Job{
+		ID:          r.ID,
+		Queue:       r.Queue,
+		Payload:     r.Payload,
+		Attempts:    r.Attempts,
+		MaxAttempts: r.MaxAttempts,
+		RunAt:       r.RunAt,
+		CreatedAt:   r.CreatedAt,
+	}
+	if r.LastError != nil {
+		job.LastError = *r.LastError
+	}
+	return job
+}
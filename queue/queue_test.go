@@ -0,0 +1,229 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"github.com/hemant-dhiman/MySQL-connection/connection"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newFakeQueue returns a queue on a fake connection named name, with an empty jobs table.
+func newFakeQueue(t *testing.T, name string, opts Options) *Queue {
+	t.Helper()
+	factory := connection.GetConnectionManager()
+	err := factory.InitFake(name, connection.FakeData{defaultTable: {
+		Columns: []string{"id", "queue", "payload", "status", "attempts", "max_attempts", "run_at", "locked_until",
+			"last_error", "created_at", "updated_at"},
+	}})
+	if err != nil {
+		t.Fatalf("InitFake failed: %v", err)
+	}
+	t.Cleanup(func() { factory.CloseConnection(name) })
+	return New(factory, name, opts)
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	cases := map[int]time.Duration{0: 2 * time.Second, 1: 2 * time.Second, 3: 8 * time.Second, 11: 2048 * time.Second, 12: time.Hour, 40: time.Hour}
+	for attempt, want := range cases {
+		if got := ExponentialBackoff(attempt); got != want {
+			t.Errorf("ExponentialBackoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestQueueDefaultsAndValidation(t *testing.T) {
+	q := New(connection.GetConnectionManager(), "missing_db", Options{})
+	if q.opts.Table != defaultTable || q.opts.MaxAttempts != defaultMaxAttempts || q.opts.Lease != defaultLease {
+		t.Fatalf("Unexpected defaults: %+v", q.opts)
+	}
+
+	bad := New(connection.GetConnectionManager(), "missing_db", Options{Table: "jobs; DROP TABLE x"})
+	if _, err := bad.Enqueue(context.Background(), "q", nil); err == nil {
+		t.Fatal("Expected an invalid table name to be rejected")
+	}
+	if _, err := q.Claim(context.Background(), "q", 0); err == nil {
+		t.Fatal("Expected a non-positive claim limit to be rejected")
+	}
+}
+
+func TestSplitExhausted(t *testing.T) {
+	rows := []jobRow{
+		{ID: 1, Status: "pending", Attempts: 5, MaxAttempts: 5},
+		{ID: 2, Status: "running", Attempts: 2, MaxAttempts: 5},
+		{ID: 3, Status: "running", Attempts: 5, MaxAttempts: 5},
+		{ID: 4, Status: "running", Attempts: 7, MaxAttempts: 5},
+	}
+	claimed, exhausted := splitExhausted(rows)
+	if len(claimed) != 2 || claimed[0].ID != 1 || claimed[1].ID != 2 {
+		t.Fatalf("Expected jobs 1 and 2 claimed, got %+v", claimed)
+	}
+	if len(exhausted) != 2 || exhausted[0] != 3 || exhausted[1] != 4 {
+		t.Fatalf("Expected jobs 3 and 4 dead-lettered, got %v", exhausted)
+	}
+}
+
+func TestEnqueueAndClaim(t *testing.T) {
+	ctx := context.Background()
+	q := newFakeQueue(t, "queue_test_claim", Options{})
+
+	first, err := q.Enqueue(ctx, "emails", []byte("a"))
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	second, err := q.Enqueue(ctx, "emails", []byte("b"))
+	if err != nil || second <= first {
+		t.Fatalf("Enqueue = %d, %v; want an id after %d", second, err, first)
+	}
+	if _, err := q.EnqueueIn(ctx, "emails", []byte("later"), time.Hour); err != nil {
+		t.Fatalf("EnqueueIn failed: %v", err)
+	}
+	if _, err := q.Enqueue(ctx, "sms", []byte("other queue")); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+
+	jobs, err := q.Claim(ctx, "emails", 10)
+	if err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != first || jobs[1].ID != second {
+		t.Fatalf("Expected jobs %d and %d claimed, got %+v", first, second, jobs)
+	}
+	if string(jobs[0].Payload) != "a" || jobs[0].Attempts != 1 || jobs[0].MaxAttempts != defaultMaxAttempts || jobs[0].Queue != "emails" {
+		t.Fatalf("Unexpected claimed job %+v", jobs[0])
+	}
+
+	if again, err := q.Claim(ctx, "emails", 10); err != nil || len(again) != 0 {
+		t.Fatalf("Claim of leased jobs = %+v, %v; want none", again, err)
+	}
+}
+
+func TestCompleteFencedByAttempt(t *testing.T) {
+	ctx := context.Background()
+	q := newFakeQueue(t, "queue_test_fencing", Options{Lease: time.Millisecond})
+
+	if _, err := q.Enqueue(ctx, "emails", nil); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	stale, err := q.Claim(ctx, "emails", 1)
+	if err != nil || len(stale) != 1 {
+		t.Fatalf("Claim = %+v, %v; want one job", stale, err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	fresh, err := q.Claim(ctx, "emails", 1)
+	if err != nil || len(fresh) != 1 || fresh[0].Attempts != 2 {
+		t.Fatalf("Claim after the lease expired = %+v, %v; want the job on its second attempt", fresh, err)
+	}
+
+	if err := q.Complete(ctx, stale[0]); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("Complete with an expired lease = %v, want ErrLeaseLost", err)
+	}
+	if err := q.Fail(ctx, stale[0], errors.New("boom")); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("Fail with an expired lease = %v, want ErrLeaseLost", err)
+	}
+	if err := q.Complete(ctx, fresh[0]); err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if err := q.Complete(ctx, fresh[0]); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("Complete of a completed job = %v, want ErrLeaseLost", err)
+	}
+}
+
+func TestFailRetriesThenDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	q := newFakeQueue(t, "queue_test_retry", Options{MaxAttempts: 2, Backoff: func(int) time.Duration { return 0 }})
+
+	id, err := q.Enqueue(ctx, "emails", nil)
+	if err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	for attempt := 1; attempt <= 2; attempt++ {
+		jobs, err := q.Claim(ctx, "emails", 1)
+		if err != nil || len(jobs) != 1 || jobs[0].Attempts != attempt {
+			t.Fatalf("Claim = %+v, %v; want the job on attempt %d", jobs, err, attempt)
+		}
+		if err := q.Fail(ctx, jobs[0], errors.New("smtp unavailable")); err != nil {
+			t.Fatalf("Fail failed: %v", err)
+		}
+	}
+	if jobs, err := q.Claim(ctx, "emails", 1); err != nil || len(jobs) != 0 {
+		t.Fatalf("Claim of a dead-lettered job = %+v, %v; want none", jobs, err)
+	}
+
+	dead, err := q.DeadLetters(ctx, "emails", 10)
+	if err != nil || len(dead) != 1 || dead[0].ID != id || dead[0].LastError != "smtp unavailable" {
+		t.Fatalf("DeadLetters = %+v, %v; want job %d with its last error", dead, err, id)
+	}
+
+	if err := q.Requeue(ctx, id); err != nil {
+		t.Fatalf("Requeue failed: %v", err)
+	}
+	if err := q.Requeue(ctx, id); err == nil {
+		t.Fatal("Expected Requeue of a pending job to fail")
+	}
+	if jobs, err := q.Claim(ctx, "emails", 1); err != nil || len(jobs) != 1 || jobs[0].Attempts != 1 {
+		t.Fatalf("Claim after Requeue = %+v, %v; want the job on its first attempt", jobs, err)
+	}
+}
+
+func TestExpiredLastLeaseDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	q := newFakeQueue(t, "queue_test_expired", Options{MaxAttempts: 1, Lease: time.Millisecond})
+
+	if _, err := q.Enqueue(ctx, "emails", nil); err != nil {
+		t.Fatalf("Enqueue failed: %v", err)
+	}
+	if jobs, err := q.Claim(ctx, "emails", 1); err != nil || len(jobs) != 1 {
+		t.Fatalf("Claim = %+v, %v; want one job", jobs, err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if jobs, err := q.Claim(ctx, "emails", 1); err != nil || len(jobs) != 0 {
+		t.Fatalf("Claim after the last lease expired = %+v, %v; want none", jobs, err)
+	}
+	dead, err := q.DeadLetters(ctx, "emails", 10)
+	if err != nil || len(dead) != 1 || !strings.Contains(dead[0].LastError, "lease expired after 1 attempt") {
+		t.Fatalf("DeadLetters = %+v, %v; want the job dead-lettered for its expired lease", dead, err)
+	}
+}
+
+func TestWorkExtendsLeaseOfEachJob(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	lease := 200 * time.Millisecond
+	q := newFakeQueue(t, "queue_test_work", Options{Lease: lease})
+
+	for i := 0; i < 3; i++ {
+		if _, err := q.Enqueue(ctx, "emails", nil); err != nil {
+			t.Fatalf("Enqueue failed: %v", err)
+		}
+	}
+	db, err := connection.GetConnectionManager().GetDB("queue_test_work")
+	if err != nil {
+		t.Fatalf("GetDB failed: %v", err)
+	}
+
+	var handled []uint64
+	err = q.Work(ctx, "emails", func(ctx context.Context, job *Job) error {
+		handled = append(handled, job.ID)
+		var lockedUntil time.Time
+		if err := db.Raw("SELECT `locked_until` FROM `mysqlconn_jobs` WHERE `id` = ?", job.ID).Scan(&lockedUntil).Error; err != nil {
+			return err
+		}
+		if remaining := time.Until(lockedUntil); remaining < lease/2 {
+			t.Errorf("Job %d handled with %v of its lease left, want about %v", job.ID, remaining, lease)
+		}
+		if len(handled) == 3 {
+			cancel()
+		}
+		// Each job takes most of a lease, so the third starts after the lease of its claim expired.
+		time.Sleep(lease * 3 / 4)
+		return nil
+	}, WorkOptions{BatchSize: 3, PollInterval: time.Millisecond})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Work = %v, want context.Canceled", err)
+	}
+	if len(handled) != 3 {
+		t.Fatalf("Expected 3 jobs handled, got %v", handled)
+	}
+}